@@ -7,16 +7,47 @@ import (
 	"path/filepath"
 	"strings"
 
+	"golite.dev/mvp/internal/amd64"
+	"golite.dev/mvp/internal/ast"
 	"golite.dev/mvp/internal/codegen"
-	"golite.dev/mvp/internal/lexer"
-	"golite.dev/mvp/internal/parser"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/llvm"
+	"golite.dev/mvp/internal/wasm"
 )
 
+// backend generates code for one target of the build command.
+type backend interface {
+	Generate(program *ast.Program) string
+	Errors() []*diagnostics.Diagnostic
+}
+
+// binaryBackend is a backend whose output is binary, such as a WebAssembly
+// module, and whose Generate returns a readable text form of it.
+type binaryBackend interface {
+	backend
+	Binary() []byte
+}
+
+// targets are the backends of the build command by target name, with the
+// extension of the files they write. Binary backends also write their text
+// form, with the extension text, next to the output.
+var targets = map[string]struct {
+	new       func() backend
+	ext, text string
+}{
+	"c":     {func() backend { return codegen.New() }, ".c", ""},
+	"llvm":  {func() backend { return llvm.New() }, ".ll", ""},
+	"wasm":  {func() backend { return wasm.New() }, ".wasm", ".wat"},
+	"amd64": {func() backend { return amd64.New() }, ".s", ""},
+}
+
 func handleBuildCommand() {
 	buildCmd := flag.NewFlagSet("build", flag.ExitOnError)
-	outputFile := buildCmd.String("o", "", "Output file name for the C code.")
+	outputFile := buildCmd.String("o", "", "Output file name for the generated code.")
+	targetName := buildCmd.String("target", "c", "What to generate: c (C source), llvm (LLVM IR), wasm (a WASI WebAssembly module, with its text format next to it) or amd64 (x86-64 Linux assembly for as and ld).")
 
 	// Correctly parse flags from the arguments that follow the "build" command.
+	errorFormat := addErrorFormatFlag(buildCmd)
 	buildCmd.Parse(os.Args[2:])
 
 	// After parsing, check for the required positional argument (the source file).
@@ -25,6 +56,11 @@ func handleBuildCommand() {
 		os.Exit(1)
 	}
 	filePath := buildCmd.Arg(0) // This is the first non-flag argument.
+	target, ok := targets[*targetName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown target %q: use c, llvm, wasm or amd64.\n", *targetName)
+		os.Exit(1)
+	}
 
 	input, err := os.ReadFile(filePath)
 	if err != nil {
@@ -36,23 +72,30 @@ func handleBuildCommand() {
 	if *outputFile == "" {
 		baseName := filepath.Base(filePath)
 		ext := filepath.Ext(baseName)
-		*outputFile = strings.TrimSuffix(baseName, ext) + ".c"
+		*outputFile = strings.TrimSuffix(baseName, ext) + target.ext
 	}
 
-	l := lexer.New(string(input))
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		printErrors(os.Stderr, "parser errors", p.Errors())
+	program, sourceName, source := loadProgram(*errorFormat, filePath, input)
+
+	generator := target.new()
+	code := generator.Generate(program)
+	if len(generator.Errors()) != 0 {
+		reportDiagnostics(os.Stderr, *errorFormat, sourceName, source, generator.Errors())
 		os.Exit(1)
 	}
 
-	generator := codegen.New()
-	cCode := generator.Generate(program)
-
-	err = os.WriteFile(*outputFile, []byte(cCode), 0644)
+	output := []byte(code)
+	if binary, ok := generator.(binaryBackend); ok {
+		output = binary.Binary()
+		textFile := strings.TrimSuffix(*outputFile, filepath.Ext(*outputFile)) + target.text
+		if err := os.WriteFile(textFile, []byte(code), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing generated code to file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	err = os.WriteFile(*outputFile, output, 0644)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing C code to file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error writing generated code to file: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"golite.dev/mvp/internal/semantics"
+)
+
+// handleCheckCommand type checks a program without running it and reports
+// warnings about code that may never terminate. It fails only on errors.
+func handleCheckCommand() {
+	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+	errorFormat := addErrorFormatFlag(checkCmd)
+	checkCmd.Parse(os.Args[2:])
+
+	filePath, input := readSource(checkCmd)
+
+	program := parseProgram(*errorFormat, filePath, string(input))
+
+	checker := semantics.New()
+	checker.Check(program)
+	if len(checker.Errors()) != 0 {
+		reportDiagnostics(os.Stderr, *errorFormat, filePath, string(input), checker.Errors())
+		os.Exit(1)
+	}
+
+	if warnings := semantics.CheckTermination(program); len(warnings) != 0 {
+		reportDiagnostics(os.Stderr, *errorFormat, filePath, string(input), warnings)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golite.dev/mvp/internal/glc"
+	"golite.dev/mvp/internal/optimizer"
+	"golite.dev/mvp/internal/semantics"
+)
+
+// handleCompileCommand parses, checks and optimizes a program and writes it
+// to a .glc file, which run and build load without parsing it again.
+func handleCompileCommand() {
+	compileCmd := flag.NewFlagSet("compile", flag.ExitOnError)
+	outputFile := compileCmd.String("o", "", "Output file name for the compiled program.")
+	constFold := compileCmd.Bool("const-fold", false, "Enable constant folding.")
+	dce := compileCmd.Bool("dce", false, "Enable dead code elimination.")
+	errorFormat := addErrorFormatFlag(compileCmd)
+	compileCmd.Parse(os.Args[2:])
+
+	if compileCmd.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: golite compile [flags] <file>")
+		os.Exit(1)
+	}
+	filePath, input := readSource(compileCmd)
+
+	if *outputFile == "" {
+		baseName := filepath.Base(filePath)
+		*outputFile = strings.TrimSuffix(baseName, filepath.Ext(baseName)) + ".glc"
+	}
+
+	program := parseProgram(*errorFormat, filePath, string(input))
+
+	checker := semantics.New()
+	checker.Check(program)
+	if len(checker.Errors()) != 0 {
+		reportDiagnostics(os.Stderr, *errorFormat, filePath, string(input), checker.Errors())
+		os.Exit(1)
+	}
+
+	// As with the optimize command, no flags enable every pass.
+	var enabledPasses optimizer.Pass
+	if *constFold {
+		enabledPasses |= optimizer.ConstantFolding
+	}
+	if *dce {
+		enabledPasses |= optimizer.DeadCodeElimination
+	}
+	if !*constFold && !*dce {
+		enabledPasses = optimizer.AllPasses
+	}
+	program = optimizer.Optimize(program, optimizer.Config{EnabledPasses: enabledPasses})
+
+	data := glc.Encode(&glc.File{Compiler: compilerVersion(), Source: filePath, Program: program})
+	if err := os.WriteFile(*outputFile, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing compiled program to file: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Successfully compiled '%s' to '%s'.\n", filePath, *outputFile)
+}
+
+// handleDumpCommand prints a listing of a .glc file.
+func handleDumpCommand() {
+	dumpCmd := flag.NewFlagSet("dump", flag.ExitOnError)
+	dumpCmd.Parse(os.Args[2:])
+
+	if dumpCmd.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: golite dump <file.glc>")
+		os.Exit(1)
+	}
+	filePath, input := readSource(dumpCmd)
+
+	f, err := glc.Decode(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %s\n", filePath, err)
+		os.Exit(1)
+	}
+	glc.Dump(os.Stdout, f)
+}
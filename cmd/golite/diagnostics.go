@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/glc"
+	"golite.dev/mvp/internal/lexer"
+	"golite.dev/mvp/internal/parser"
+	"golite.dev/mvp/internal/semantics"
+)
+
+// addErrorFormatFlag registers the --error-format flag shared by every
+// command that reports diagnostics.
+func addErrorFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("error-format", "human", "Diagnostic output format: human or json.")
+}
+
+// reportDiagnostics writes diags to out in the requested format.
+func reportDiagnostics(out io.Writer, format, filename, source string, diags []*diagnostics.Diagnostic) {
+	switch format {
+	case "json":
+		if err := diagnostics.WriteJSON(out, filename, diags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing diagnostics: %v\n", err)
+		}
+	default:
+		diagnostics.NewRenderer(filename, source).RenderAll(out, diags)
+	}
+}
+
+// readSource reads the program named by the first positional argument of fs,
+// falling back to stdin when there is none.
+func readSource(fs *flag.FlagSet) (filename string, input []byte) {
+	var err error
+	if fs.NArg() > 0 {
+		filename = fs.Arg(0)
+		input, err = os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file %s: %s\n", filename, err)
+			os.Exit(1)
+		}
+		return filename, input
+	}
+	input, err = io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading from stdin: %s\n", err)
+		os.Exit(1)
+	}
+	return "<stdin>", input
+}
+
+// parseProgram parses source and returns the program. If there are syntax
+// errors it also type checks the statements that could be parsed, reports
+// both kinds of error in source order and exits.
+func parseProgram(format, filename, source string) *ast.Program {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		return program
+	}
+
+	checker := semantics.New()
+	checker.Check(program)
+	diags := append(p.Errors(), checker.Errors()...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].Span.Start.Offset < diags[j].Span.Start.Offset
+	})
+	reportDiagnostics(os.Stderr, format, filename, source, diags)
+	os.Exit(1)
+	return nil
+}
+
+// loadProgram returns the program in input, which is either source text,
+// parsed as by parseProgram, or a .glc file written by golite compile. It
+// also returns the file name and source text that diagnostics should refer
+// to: for a .glc file, the name of the source file it was compiled from and
+// no text, since the file does not keep it.
+func loadProgram(format, filename string, input []byte) (*ast.Program, string, string) {
+	if !glc.IsGLC(input) {
+		return parseProgram(format, filename, string(input)), filename, string(input)
+	}
+	f, err := glc.Decode(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s: %s\n", filename, err)
+		os.Exit(1)
+	}
+	return f.Program, f.Source, ""
+}
@@ -5,6 +5,14 @@ import (
 	"os"
 )
 
+// version is the version of golite, which .glc files record. Release builds
+// set it with -ldflags "-X main.version=...".
+var version = "dev"
+
+func compilerVersion() string {
+	return "golite " + version
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "Usage: golite <command> [arguments]")
@@ -17,8 +25,14 @@ func main() {
 		handleParseCommand()
 	case "run":
 		handleRunCommand()
+	case "check":
+		handleCheckCommand()
 	case "optimize":
 		handleOptimizeCommand()
+	case "compile":
+		handleCompileCommand()
+	case "dump":
+		handleDumpCommand()
 	case "build":
 		handleBuildCommand()
 	case "profile":
@@ -17,6 +17,7 @@ func handleOptimizeCommand() {
 	dce := optCmd.Bool("dce", false, "Enable dead code elimination.")
 
 	// The first arg is the command name, so we parse from the 2nd arg onwards.
+	errorFormat := addErrorFormatFlag(optCmd)
 	optCmd.Parse(os.Args[2:])
 
 	if optCmd.NArg() < 1 {
@@ -35,7 +36,7 @@ func handleOptimizeCommand() {
 	p := parser.New(l)
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
-		printErrors(os.Stderr, "parser errors", p.Errors())
+		reportDiagnostics(os.Stderr, *errorFormat, filePath, string(input), p.Errors())
 		os.Exit(1)
 	}
 
@@ -1,65 +1,57 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io"
 	"os"
 
+	"golite.dev/mvp/internal/compiler"
+	"golite.dev/mvp/internal/diagnostics"
 	"golite.dev/mvp/internal/evaluator"
-	"golite.dev/mvp/internal/lexer"
 	"golite.dev/mvp/internal/object"
-	"golite.dev/mvp/internal/parser"
 	"golite.dev/mvp/internal/semantics"
+	"golite.dev/mvp/internal/vm"
 )
 
 func handleRunCommand() {
-	var input []byte
-	var err error
+	runCmd := flag.NewFlagSet("run", flag.ExitOnError)
+	engine := runCmd.String("engine", "eval", "Execution engine: eval, the tree-walking evaluator, or vm, the bytecode virtual machine.")
+	errorFormat := addErrorFormatFlag(runCmd)
+	runCmd.Parse(os.Args[2:])
+	if *engine != "eval" && *engine != "vm" {
+		fmt.Fprintf(os.Stderr, "Unknown engine: %s\n", *engine)
+		os.Exit(1)
+	}
+
+	filePath, input := readSource(runCmd)
+
+	program, filePath, source := loadProgram(*errorFormat, filePath, input)
 
-	if len(os.Args) > 2 {
-		filePath := os.Args[2]
-		input, err = os.ReadFile(filePath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading file %s: %s\n", filePath, err)
+	var err *object.Error
+	if *engine == "vm" {
+		// The compiler checks the program itself, since it needs the types.
+		c := compiler.New()
+		bytecode := c.Compile(program)
+		if len(c.Errors()) != 0 {
+			reportDiagnostics(os.Stderr, *errorFormat, filePath, source, c.Errors())
 			os.Exit(1)
 		}
+		err = vm.New(bytecode).Run()
 	} else {
-		input, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading from stdin: %s\n", err)
+		checker := semantics.New()
+		checker.Check(program)
+		if len(checker.Errors()) != 0 {
+			reportDiagnostics(os.Stderr, *errorFormat, filePath, source, checker.Errors())
 			os.Exit(1)
 		}
+		env := object.NewEnvironment()
+		if evaluated, ok := evaluator.Eval(program, env).(*object.Error); ok {
+			err = evaluated
+		}
 	}
-
-	l := lexer.New(string(input))
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		printErrors(os.Stderr, "parser errors", p.Errors())
-		os.Exit(1)
-	}
-
-	checker := semantics.New()
-	checker.Check(program)
-	if len(checker.Errors()) != 0 {
-		printErrors(os.Stderr, "semantic errors", checker.Errors())
-		os.Exit(1)
-	}
-
-	env := object.NewEnvironment()
-	evaluated := evaluator.Eval(program, env)
-	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
-		errors := []string{evaluated.Inspect()}
-		printErrors(os.Stderr, "runtime error", errors)
+	if err != nil {
+		diags := []*diagnostics.Diagnostic{err.Diagnostic()}
+		reportDiagnostics(os.Stderr, *errorFormat, filePath, source, diags)
 		os.Exit(1)
 	}
 }
-
-func printErrors(out io.Writer, errorType string, errors []string) {
-	fmt.Fprintf(out, "Encountered %s:\n", errorType)
-	for _, msg := range errors {
-		fmt.Fprintln(out, "\t"+msg)
-	}
-}
-
-// LINES: 66
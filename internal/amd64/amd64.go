@@ -0,0 +1,118 @@
+// Package amd64 generates x86-64 assembly in GNU assembler syntax from
+// programs, for Linux and without a C library, so that as and ld are all
+// it takes to build an executable:
+//
+//	as -o fib.o fib.s && ld -o fib fib.o
+//
+// Programs are lowered to package ir, which brings its own runtime for
+// printing, and from there to instructions over virtual registers, which a
+// linear scan register allocator maps to machine registers and stack
+// slots. Functions follow the System V calling convention; the program
+// starts at _start, which calls main and then exits, and talks to the
+// kernel with the write and exit_group system calls.
+package amd64
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/ir"
+	"golite.dev/mvp/internal/semantics"
+)
+
+// Generator is the x86-64 code generator.
+type Generator struct {
+	errors []*diagnostics.Diagnostic
+}
+
+// New creates a new x86-64 code generator.
+func New() *Generator {
+	return &Generator{}
+}
+
+// Errors returns the diagnostics for programs the x86-64 backend cannot
+// lower.
+func (g *Generator) Errors() []*diagnostics.Diagnostic {
+	return g.errors
+}
+
+// Generate takes an AST program and returns an equivalent assembly file.
+// The program is type-checked and lowered to package ir first; if either
+// fails, Generate returns "" and Errors reports why.
+func (g *Generator) Generate(program *ast.Program) string {
+	checker := semantics.New()
+	checker.Check(program)
+	if len(checker.Errors()) > 0 {
+		g.errors = append(g.errors, checker.Errors()...)
+		return ""
+	}
+	prog, errs := ir.Lower(program, checker, "x86-64", append(ir.RuntimeNames(), "_start")...)
+	if len(errs) > 0 {
+		g.errors = append(g.errors, errs...)
+		return ""
+	}
+	ir.AddRuntime(prog)
+
+	var out strings.Builder
+	out.WriteString("\t.text\n")
+	out.WriteString("\t.globl _start\n")
+	out.WriteString("_start:\n")
+	fmt.Fprintf(&out, "\tcall %s\n", prog.Main.Name)
+	out.WriteString("\tmovl $231, %eax\n")
+	out.WriteString("\txorl %edi, %edi\n")
+	out.WriteString("\tsyscall\n\n")
+
+	labels := 0
+	for _, fn := range prog.Functions {
+		c := lower(fn, &labels)
+		labels++
+		e := &emitter{out: &out, c: c, alloc: allocate(c), ret: fmt.Sprintf(".L%d", labels)}
+		e.function()
+	}
+
+	out.WriteString("\t.section .rodata\n")
+	out.WriteString("\t.balign 16\n")
+	out.WriteString(".Lsign:\n\t.quad 0x8000000000000000, 0\n\n")
+
+	out.WriteString("\t.data\n")
+	for _, d := range prog.Data {
+		if len(d.Init) == 0 {
+			continue
+		}
+		fmt.Fprintf(&out, "\t.balign 8\n%s:\n", dataSymbol(d, 0))
+		out.WriteString(bytes(d.Init))
+		if rest := d.Size - len(d.Init); rest > 0 {
+			fmt.Fprintf(&out, "\t.zero %d\n", rest)
+		}
+	}
+	out.WriteString("\n\t.bss\n")
+	for _, d := range prog.Data {
+		if len(d.Init) == 0 {
+			fmt.Fprintf(&out, "\t.balign 8\n%s:\n\t.zero %d\n", dataSymbol(d, 0), d.Size)
+		}
+	}
+	for _, v := range prog.Globals {
+		fmt.Fprintf(&out, "\t.balign 8\n%s:\n\t.zero 8\n", v.Name)
+	}
+	out.WriteString("\n\t.section .note.GNU-stack,\"\",@progbits\n")
+	return out.String()
+}
+
+// bytes writes data as .byte directives, 16 to a line.
+func bytes(data []byte) string {
+	var out strings.Builder
+	for i := 0; i < len(data); i += 16 {
+		line := data[i:min(i+16, len(data))]
+		out.WriteString("\t.byte ")
+		for j, b := range line {
+			if j > 0 {
+				out.WriteString(", ")
+			}
+			fmt.Fprintf(&out, "%d", b)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,595 @@
+package amd64
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ir"
+)
+
+var (
+	intArgRegs   = []string{"rdi", "rsi", "rdx", "rcx", "r8", "r9"}
+	floatArgRegs = []string{"xmm0", "xmm1", "xmm2", "xmm3", "xmm4", "xmm5", "xmm6", "xmm7"}
+)
+
+// byteRegs names the low bytes of the registers that can hold a value
+// being stored.
+var byteRegs = map[string]string{
+	"rax": "al", "rbx": "bl", "rsi": "sil", "rdi": "dil", "r8": "r8b", "r9": "r9b",
+	"r10": "r10b", "r12": "r12b", "r13": "r13b", "r14": "r14b", "r15": "r15b",
+}
+
+// emitter writes the assembly of one function.
+type emitter struct {
+	out   *strings.Builder
+	c     *code
+	alloc *allocation
+	ret   string // the label of the epilogue
+}
+
+func (e *emitter) ins(format string, args ...interface{}) {
+	e.out.WriteString("\t")
+	fmt.Fprintf(e.out, format, args...)
+	e.out.WriteString("\n")
+}
+
+func (e *emitter) loc(v val) location { return e.alloc.locs[v.reg] }
+
+func (e *emitter) inReg(v val) bool { return v.reg != none && e.loc(v).reg != "" }
+
+func (e *emitter) isFloat(v val) bool { return v.reg != none && e.c.floats[v.reg] }
+
+func fits32(v int64) bool { return v == int64(int32(v)) }
+
+// operand returns v as an operand: an immediate, a register or a stack
+// slot. Immediates that do not fit in 32 bits are loaded into scratch
+// first, since instructions other than movabsq take 32 bits at most.
+func (e *emitter) operand(v val, scratch string) string {
+	if v.reg != none {
+		return e.loc(v).String()
+	}
+	if fits32(v.imm) {
+		return fmt.Sprintf("$%d", v.imm)
+	}
+	e.ins("movabsq $%d, %%%s", v.imm, scratch)
+	return "%" + scratch
+}
+
+// reg returns v in a register: its own, or scratch.
+func (e *emitter) reg(v val, scratch string) string {
+	if e.inReg(v) {
+		return e.loc(v).String()
+	}
+	e.load(v, scratch)
+	return "%" + scratch
+}
+
+// load moves the int v into the register r.
+func (e *emitter) load(v val, r string) {
+	switch {
+	case v.reg == none && fits32(v.imm):
+		e.ins("movq $%d, %%%s", v.imm, r)
+	case v.reg == none:
+		e.ins("movabsq $%d, %%%s", v.imm, r)
+	case e.loc(v).reg != r:
+		e.ins("movq %s, %%%s", e.loc(v), r)
+	}
+}
+
+// store moves the int in register r to the register dst.
+func (e *emitter) store(r string, dst int) {
+	if loc := e.alloc.locs[dst]; loc.reg != r {
+		e.ins("movq %%%s, %s", r, loc)
+	}
+}
+
+// fload moves the float v into the register r.
+func (e *emitter) fload(v val, r string) {
+	switch loc := e.loc(v); {
+	case loc.reg == r:
+	case loc.reg != "":
+		e.ins("movapd %s, %%%s", loc, r)
+	default:
+		e.ins("movsd %s, %%%s", loc, r)
+	}
+}
+
+// fstore moves the float in register r to the register dst.
+func (e *emitter) fstore(r string, dst int) {
+	switch loc := e.alloc.locs[dst]; {
+	case loc.reg == r:
+	case loc.reg != "":
+		e.ins("movapd %%%s, %s", r, loc)
+	default:
+		e.ins("movsd %%%s, %s", r, loc)
+	}
+}
+
+// target returns the register to compute the value of dst in: its own,
+// unless it is on the stack or holds b, which the computation still
+// needs, and scratch otherwise.
+func (e *emitter) target(dst int, b val, scratch string) string {
+	loc := e.alloc.locs[dst]
+	if loc.reg == "" || b.reg != none && e.loc(b) == loc {
+		return scratch
+	}
+	return loc.reg
+}
+
+func (e *emitter) function() {
+	fn, a := e.c.fn, e.alloc
+	fmt.Fprintf(e.out, "%s:\n", fn.Name)
+	e.ins("pushq %%rbp")
+	e.ins("movq %%rsp, %%rbp")
+	for _, r := range a.saved {
+		e.ins("pushq %%%s", r)
+	}
+	// Keep the stack aligned to 16 bytes at calls.
+	if frame := 8 * (a.slots + (len(a.saved)+a.slots)%2); frame > 0 {
+		e.ins("subq $%d, %%rsp", frame)
+	}
+	for _, in := range e.c.insts {
+		e.instruction(in)
+	}
+	e.out.WriteString(e.ret + ":\n")
+	if len(a.saved) > 0 {
+		e.ins("leaq %d(%%rbp), %%rsp", -8*len(a.saved))
+		for i := len(a.saved) - 1; i >= 0; i-- {
+			e.ins("popq %%%s", a.saved[i])
+		}
+	} else {
+		e.ins("movq %%rbp, %%rsp")
+	}
+	e.ins("popq %%rbp")
+	e.ins("ret")
+	e.out.WriteString("\n")
+}
+
+func (e *emitter) instruction(in *inst) {
+	switch in.kind {
+	case kEntry:
+		e.entry(in)
+	case kMove:
+		if e.c.floats[in.dst] {
+			e.parallelMove([]move{{dst: e.alloc.locs[in.dst], src: in.a, float: true}})
+		} else {
+			e.parallelMove([]move{{dst: e.alloc.locs[in.dst], src: in.a}})
+		}
+	case kFloatConst:
+		r := e.target(in.dst, in.b, "xmm15")
+		if in.imm == 0 {
+			e.ins("xorpd %%%s, %%%s", r, r)
+		} else {
+			e.ins("movabsq $%d, %%r11", in.imm)
+			e.ins("movq %%r11, %%%s", r)
+		}
+		e.fstore(r, in.dst)
+	case kBinary:
+		if e.c.floats[in.dst] {
+			e.floatBinary(in)
+		} else {
+			e.intBinary(in)
+		}
+	case kCompare:
+		if e.isFloat(in.a) {
+			e.floatCompare(in)
+		} else {
+			e.intCompare(in)
+		}
+	case kNeg:
+		if e.c.floats[in.dst] {
+			r := e.target(in.dst, in.b, "xmm15")
+			e.fload(in.a, r)
+			e.ins("xorpd .Lsign(%%rip), %%%s", r)
+			e.fstore(r, in.dst)
+		} else {
+			r := e.target(in.dst, in.b, "r11")
+			e.load(in.a, r)
+			e.ins("negq %%%s", r)
+			e.store(r, in.dst)
+		}
+	case kNot:
+		r := e.target(in.dst, in.b, "r11")
+		e.load(in.a, r)
+		e.ins("xorq $1, %%%s", r)
+		e.store(r, in.dst)
+	case kConvert:
+		if e.c.floats[in.dst] {
+			r := e.target(in.dst, in.b, "xmm15")
+			src := e.reg(in.a, "r11")
+			// cvtsi2sd only writes the low half of r; clearing it first
+			// spares waiting for whatever wrote the rest.
+			e.ins("xorpd %%%s, %%%s", r, r)
+			e.ins("cvtsi2sdq %s, %%%s", src, r)
+			e.fstore(r, in.dst)
+		} else {
+			e.ins("cvttsd2siq %s, %%r11", e.loc(in.a))
+			e.store("r11", in.dst)
+		}
+	case kBits:
+		e.ins("movq %s, %%r11", e.loc(in.a))
+		e.store("r11", in.dst)
+	case kLoad:
+		addr := e.reg(in.a, "r11")
+		if in.size == 1 {
+			e.ins("movzbq (%s), %%r11", addr)
+		} else {
+			e.ins("movq (%s), %%r11", addr)
+		}
+		e.store("r11", in.dst)
+	case kStore:
+		e.storeMemory(in)
+	case kAddr:
+		r := e.target(in.dst, in.b, "r11")
+		e.ins("leaq %s(%%rip), %%%s", dataSymbol(in.data, in.imm), r)
+		e.store(r, in.dst)
+	case kGlobalLoad:
+		if e.c.floats[in.dst] {
+			r := e.target(in.dst, in.b, "xmm15")
+			e.ins("movsd %s(%%rip), %%%s", in.global.Name, r)
+			e.fstore(r, in.dst)
+		} else {
+			r := e.target(in.dst, in.b, "r11")
+			e.ins("movq %s(%%rip), %%%s", in.global.Name, r)
+			e.store(r, in.dst)
+		}
+	case kGlobalStore:
+		if e.isFloat(in.a) {
+			if !e.inReg(in.a) {
+				e.fload(in.a, "xmm15")
+				e.ins("movsd %%xmm15, %s(%%rip)", in.global.Name)
+			} else {
+				e.ins("movsd %s, %s(%%rip)", e.loc(in.a), in.global.Name)
+			}
+		} else if in.a.reg == none && fits32(in.a.imm) {
+			e.ins("movq $%d, %s(%%rip)", in.a.imm, in.global.Name)
+		} else {
+			e.ins("movq %s, %s(%%rip)", e.reg(in.a, "r11"), in.global.Name)
+		}
+	case kCall:
+		e.call(in)
+	case kWrite:
+		e.parallelMove([]move{
+			{dst: location{reg: "rdi"}, src: imm(in.imm)},
+			{dst: location{reg: "rsi"}, src: in.a},
+			{dst: location{reg: "rdx"}, src: in.b},
+		})
+		e.ins("movl $1, %%eax")
+		e.ins("syscall")
+	case kExit:
+		e.ins("movl $231, %%eax")
+		e.ins("movl $%d, %%edi", in.imm)
+		e.ins("syscall")
+	case kLabel:
+		fmt.Fprintf(e.out, ".L%d:\n", in.label)
+	case kJump:
+		e.ins("jmp .L%d", in.label)
+	case kJumpZero:
+		if in.a.reg == none {
+			if in.a.imm == 0 {
+				e.ins("jmp .L%d", in.label)
+			}
+			return
+		}
+		e.ins("cmpq $0, %s", e.loc(in.a))
+		e.ins("je .L%d", in.label)
+	case kReturn:
+		if in.size == 1 {
+			if e.isFloat(in.a) {
+				e.fload(in.a, "xmm0")
+			} else {
+				e.load(in.a, "rax")
+			}
+		}
+		e.ins("jmp %s", e.ret)
+	}
+}
+
+var intOps = map[ir.Op]string{
+	ir.Add: "addq", ir.Sub: "subq", ir.Mul: "imulq", ir.And: "andq", ir.Or: "orq", ir.Xor: "xorq",
+	ir.Shl: "shlq", ir.Shr: "sarq",
+}
+
+var floatOps = map[ir.Op]string{
+	ir.Add: "addsd", ir.Sub: "subsd", ir.Mul: "mulsd", ir.Div: "divsd",
+}
+
+func commutative(op ir.Op) bool {
+	switch op {
+	case ir.Add, ir.Mul, ir.And, ir.Or, ir.Xor:
+		return true
+	}
+	return false
+}
+
+// intBinary emits an int operator. The runtime has already replaced the
+// divisions and shifts that can stop the program, so divisors are neither
+// 0 nor -1 and shift counts are from 0 to 63.
+func (e *emitter) intBinary(in *inst) {
+	a, b := in.a, in.b
+	switch in.op {
+	case ir.Div, ir.Rem:
+		e.load(a, "rax")
+		e.ins("cqto")
+		e.ins("idivq %s", e.reg(b, "r11"))
+		if in.op == ir.Div {
+			e.store("rax", in.dst)
+		} else {
+			e.store("rdx", in.dst)
+		}
+		return
+	case ir.Shl, ir.Shr:
+		if b.reg == none {
+			r := e.target(in.dst, b, "r11")
+			e.load(a, r)
+			e.ins("%s $%d, %%%s", intOps[in.op], b.imm, r)
+			e.store(r, in.dst)
+			return
+		}
+		e.load(b, "rcx")
+		r := e.target(in.dst, imm(0), "r11")
+		e.load(a, r)
+		e.ins("%s %%cl, %%%s", intOps[in.op], r)
+		e.store(r, in.dst)
+		return
+	}
+	if commutative(in.op) && b.reg != none && e.loc(b) == e.alloc.locs[in.dst] && e.loc(b).reg != "" {
+		a, b = b, a
+	}
+	r := e.target(in.dst, b, "r11")
+	e.load(a, r)
+	e.ins("%s %s, %%%s", intOps[in.op], e.operand(b, "rax"), r)
+	e.store(r, in.dst)
+}
+
+func (e *emitter) floatBinary(in *inst) {
+	a, b := in.a, in.b
+	if commutative(in.op) && e.loc(b) == e.alloc.locs[in.dst] && e.loc(b).reg != "" {
+		a, b = b, a
+	}
+	r := e.target(in.dst, b, "xmm15")
+	e.fload(a, r)
+	e.ins("%s %s, %%%s", floatOps[in.op], e.loc(b), r)
+	e.fstore(r, in.dst)
+}
+
+var conditions = map[ir.Op]string{
+	ir.Eq: "e", ir.Ne: "ne", ir.Lt: "l", ir.Le: "le", ir.Gt: "g", ir.Ge: "ge",
+}
+
+func (e *emitter) intCompare(in *inst) {
+	// cmpq takes at most one operand in memory, and no constant first.
+	var a string
+	if in.a.reg == none || !e.inReg(in.a) && in.b.reg != none && !e.inReg(in.b) {
+		a = e.reg(in.a, "r11")
+	} else {
+		a = e.loc(in.a).String()
+	}
+	e.ins("cmpq %s, %s", e.operand(in.b, "rax"), a)
+	e.ins("set%s %%r11b", conditions[in.op])
+	e.ins("movzbq %%r11b, %%r11")
+	e.store("r11", in.dst)
+}
+
+// floatCompare compares floats with ucomisd, which sets the carry, zero
+// and parity flags if either is NaN. The comparisons are arranged so that
+// NaNs make all but != false: x > y and x >= y test above and above or
+// equal, which need the carry flag clear, and equality tests parity.
+func (e *emitter) floatCompare(in *inst) {
+	x, y := in.a, in.b
+	cond := map[ir.Op]string{ir.Eq: "e", ir.Ne: "ne", ir.Lt: "a", ir.Le: "ae", ir.Gt: "a", ir.Ge: "ae"}[in.op]
+	if in.op == ir.Gt || in.op == ir.Ge {
+		x, y = y, x
+	}
+	// ucomisd x, y compares y with x, and y must be in a register.
+	if e.inReg(y) {
+		e.ins("ucomisd %s, %s", e.loc(x), e.loc(y))
+	} else {
+		e.fload(y, "xmm15")
+		e.ins("ucomisd %s, %%xmm15", e.loc(x))
+	}
+	e.ins("set%s %%r11b", cond)
+	switch in.op {
+	case ir.Eq:
+		e.ins("setnp %%al")
+		e.ins("andb %%al, %%r11b")
+	case ir.Ne:
+		e.ins("setp %%al")
+		e.ins("orb %%al, %%r11b")
+	}
+	e.ins("movzbq %%r11b, %%r11")
+	e.store("r11", in.dst)
+}
+
+func (e *emitter) storeMemory(in *inst) {
+	addr := e.reg(in.a, "r11")
+	if in.b.reg == none && fits32(in.b.imm) {
+		if in.size == 1 {
+			e.ins("movb $%d, (%s)", uint8(in.b.imm), addr)
+		} else {
+			e.ins("movq $%d, (%s)", in.b.imm, addr)
+		}
+		return
+	}
+	value := strings.TrimPrefix(e.reg(in.b, "rax"), "%")
+	if in.size == 1 {
+		e.ins("movb %%%s, (%s)", byteRegs[value], addr)
+	} else {
+		e.ins("movq %%%s, (%s)", value, addr)
+	}
+}
+
+// entry moves the parameters from where the caller put them, by the
+// System V calling convention, to their registers: the first six ints and
+// eight floats in registers, and the rest on the stack, above the return
+// address.
+func (e *emitter) entry(in *inst) {
+	var moves []move
+	ints, floats, stack := 0, 0, 0
+	for _, p := range in.args {
+		dst := e.loc(p)
+		float := e.c.floats[p.reg]
+		var src location
+		switch {
+		case float && floats < len(floatArgRegs):
+			src = location{reg: floatArgRegs[floats]}
+			floats++
+		case !float && ints < len(intArgRegs):
+			src = location{reg: intArgRegs[ints]}
+			ints++
+		default:
+			src = location{mem: fmt.Sprintf("%d(%%rbp)", 16+8*stack)}
+			stack++
+		}
+		moves = append(moves, move{dst: dst, srcLoc: &src, float: float})
+	}
+	e.parallelMove(moves)
+}
+
+// call calls a function by the System V calling convention. The stack
+// arguments are pushed first, in reverse, so that the arguments still in
+// registers can be moved into theirs last.
+func (e *emitter) call(in *inst) {
+	var moves []move
+	var stack []val
+	ints, floats := 0, 0
+	for _, arg := range in.args {
+		float := e.isFloat(arg)
+		switch {
+		case float && floats < len(floatArgRegs):
+			moves = append(moves, move{dst: location{reg: floatArgRegs[floats]}, src: arg, float: true})
+			floats++
+		case !float && ints < len(intArgRegs):
+			moves = append(moves, move{dst: location{reg: intArgRegs[ints]}, src: arg})
+			ints++
+		default:
+			stack = append(stack, arg)
+		}
+	}
+	pushed := len(stack) + len(stack)%2
+	if len(stack)%2 == 1 {
+		e.ins("subq $8, %%rsp")
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if arg := stack[i]; e.isFloat(arg) {
+			e.fload(arg, "xmm15")
+			e.ins("subq $8, %%rsp")
+			e.ins("movsd %%xmm15, (%%rsp)")
+		} else {
+			e.ins("pushq %s", e.operand(arg, "r11"))
+		}
+	}
+	e.parallelMove(moves)
+	e.ins("call %s", in.fn.Name)
+	if pushed > 0 {
+		e.ins("addq $%d, %%rsp", 8*pushed)
+	}
+	if in.dst != none {
+		if e.c.floats[in.dst] {
+			e.fstore("xmm0", in.dst)
+		} else {
+			e.store("rax", in.dst)
+		}
+	}
+}
+
+// move is one of a set of moves that happen at once. The source is a
+// virtual register or constant, or srcLoc if it is set.
+type move struct {
+	dst    location
+	src    val
+	srcLoc *location
+	float  bool
+}
+
+func (e *emitter) source(m move) (location, bool) {
+	if m.srcLoc != nil {
+		return *m.srcLoc, true
+	}
+	if m.src.reg == none {
+		return location{}, false
+	}
+	return e.loc(m.src), true
+}
+
+// parallelMove emits moves so that every destination ends up with what its
+// source held before any of them, although some destinations are sources
+// of others. A move goes once its destination is no other's source; when
+// only cycles remain, one destination is saved in %r11 first, which breaks
+// its cycle. Float registers never form cycles, since parameters and
+// arguments are in registers that are not allocated.
+func (e *emitter) parallelMove(moves []move) {
+	var pending []move
+	for _, m := range moves {
+		if src, ok := e.source(m); !ok || src != m.dst {
+			pending = append(pending, m)
+		}
+	}
+	for len(pending) > 0 {
+		ready := -1
+		for i, m := range pending {
+			if !e.isSource(pending, i, m.dst) {
+				ready = i
+				break
+			}
+		}
+		if ready < 0 {
+			dst := pending[0].dst
+			e.ins("movq %s, %%r11", dst)
+			for i := range pending {
+				if src, ok := e.source(pending[i]); ok && src == dst {
+					pending[i].srcLoc = &location{reg: "r11"}
+				}
+			}
+			continue
+		}
+		e.moveOne(pending[ready])
+		pending = append(pending[:ready], pending[ready+1:]...)
+	}
+}
+
+// isSource reports whether loc is the source of a pending move other than
+// the i'th.
+func (e *emitter) isSource(pending []move, i int, loc location) bool {
+	for j, m := range pending {
+		if src, ok := e.source(m); j != i && ok && src == loc {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *emitter) moveOne(m move) {
+	src, ok := e.source(m)
+	switch {
+	case !ok && m.dst.reg != "":
+		e.load(m.src, m.dst.reg)
+	case !ok && fits32(m.src.imm):
+		e.ins("movq $%d, %s", m.src.imm, m.dst)
+	case !ok:
+		e.ins("movabsq $%d, %%rax", m.src.imm)
+		e.ins("movq %%rax, %s", m.dst)
+	case m.float && src.reg != "" && m.dst.reg != "":
+		e.ins("movapd %s, %s", src, m.dst)
+	case m.float && (src.reg != "" || m.dst.reg != ""):
+		e.ins("movsd %s, %s", src, m.dst)
+	case m.float:
+		e.ins("movsd %s, %%xmm15", src)
+		e.ins("movsd %%xmm15, %s", m.dst)
+	case src.reg != "" || m.dst.reg != "":
+		e.ins("movq %s, %s", src, m.dst)
+	default:
+		e.ins("movq %s, %%rax", src)
+		e.ins("movq %%rax, %s", m.dst)
+	}
+}
+
+// dataSymbol returns the symbol of d plus offset. Functions and globals
+// only have a dot before the number that makes their name unique, so the
+// symbols of data cannot clash with theirs.
+func dataSymbol(d *ir.Data, offset int64) string {
+	if offset != 0 {
+		return fmt.Sprintf("data.%s+%d", d.Name, offset)
+	}
+	return "data." + d.Name
+}
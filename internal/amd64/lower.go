@@ -0,0 +1,320 @@
+package amd64
+
+import (
+	"math"
+
+	"golite.dev/mvp/internal/ir"
+)
+
+// The functions of a program are lowered to a list of instructions close
+// to the machine's, over an unlimited number of virtual registers, which
+// the register allocator then maps to machine registers and stack slots.
+// Each variable of the function gets a virtual register of its own, and
+// each intermediate value a fresh one.
+
+// kind is the kind of an instruction.
+type kind int
+
+const (
+	kEntry       kind = iota // defines the parameters, args, from where the caller put them
+	kMove                    // dst = a
+	kFloatConst              // dst = the float with bits imm
+	kBinary                  // dst = a op b, on ints or floats by the class of dst
+	kCompare                 // dst = a op b, a bool, comparing ints or floats by the class of a
+	kNeg                     // dst = -a
+	kNot                     // dst = !a
+	kConvert                 // dst = a converted to the class of dst
+	kBits                    // dst = the bits of the float a
+	kLoad                    // dst = the size bytes at address a
+	kStore                   // the size bytes at address a = b
+	kAddr                    // dst = the address of data, plus imm
+	kGlobalLoad              // dst = global
+	kGlobalStore             // global = a
+	kCall                    // dst = fn(args...), or just the call if dst is none
+	kWrite                   // write(imm, a, b)
+	kExit                    // exit_group(imm)
+	kLabel                   // label:
+	kJump                    // goto label
+	kJumpZero                // if a == 0, goto label
+	kReturn                  // return, with a if size is 1
+)
+
+// none is the register of instructions that define nothing.
+const none = -1
+
+// val is an operand: a virtual register, or the constant imm if reg is
+// none. Constants are ints or bools.
+type val struct {
+	reg int
+	imm int64
+}
+
+func imm(v int64) val { return val{reg: none, imm: v} }
+
+type inst struct {
+	kind   kind
+	op     ir.Op
+	dst    int
+	a, b   val
+	args   []val
+	size   int
+	label  int
+	imm    int64
+	fn     *ir.Function
+	global *ir.Var
+	data   *ir.Data
+}
+
+// uses returns the virtual registers that in reads.
+func (in *inst) uses() []int {
+	var regs []int
+	for _, v := range append([]val{in.a, in.b}, in.args...) {
+		if v.reg != none {
+			regs = append(regs, v.reg)
+		}
+	}
+	return regs
+}
+
+// defs returns the virtual registers that in writes.
+func (in *inst) defs() []int {
+	if in.kind == kEntry {
+		var regs []int
+		for _, v := range in.args {
+			regs = append(regs, v.reg)
+		}
+		return regs
+	}
+	if in.dst != none {
+		return []int{in.dst}
+	}
+	return nil
+}
+
+// isCall reports whether in clobbers the registers that calls do.
+func (in *inst) isCall() bool {
+	return in.kind == kCall || in.kind == kWrite
+}
+
+// code is a lowered function.
+type code struct {
+	fn     *ir.Function
+	insts  []*inst
+	floats []bool // the class of each virtual register: float or not
+}
+
+// lowerer lowers one function.
+type lowerer struct {
+	code   *code
+	vars   map[*ir.Var]int
+	labels *int // the number of the last label of the program
+	loops  []loopLabels
+}
+
+// loopLabels are the targets of break and continue in a loop.
+type loopLabels struct {
+	post, end int
+}
+
+func lower(fn *ir.Function, labels *int) *code {
+	l := &lowerer{code: &code{fn: fn}, vars: map[*ir.Var]int{}, labels: labels}
+	entry := &inst{kind: kEntry, dst: none, a: imm(0), b: imm(0)}
+	for _, p := range fn.Params {
+		entry.args = append(entry.args, val{reg: l.variable(p)})
+	}
+	l.emit(entry)
+	for _, v := range fn.Locals {
+		l.variable(v)
+	}
+	l.statements(fn.Body)
+	return l.code
+}
+
+func (l *lowerer) variable(v *ir.Var) int {
+	r := l.newReg(v.Type == ir.Float)
+	l.vars[v] = r
+	return r
+}
+
+func (l *lowerer) newReg(float bool) int {
+	l.code.floats = append(l.code.floats, float)
+	return len(l.code.floats) - 1
+}
+
+func (l *lowerer) newLabel() int {
+	*l.labels++
+	return *l.labels
+}
+
+func (l *lowerer) emit(in *inst) {
+	l.code.insts = append(l.code.insts, in)
+}
+
+// op emits an instruction that defines a new register from a and b.
+func (l *lowerer) op(k kind, o ir.Op, float bool, a, b val) val {
+	dst := l.newReg(float)
+	l.emit(&inst{kind: k, op: o, dst: dst, a: a, b: b})
+	return val{reg: dst}
+}
+
+func (l *lowerer) jump(k kind, label int, a val) {
+	l.emit(&inst{kind: k, dst: none, a: a, b: imm(0), label: label})
+}
+
+func (l *lowerer) label(label int) {
+	l.emit(&inst{kind: kLabel, dst: none, a: imm(0), b: imm(0), label: label})
+}
+
+func (l *lowerer) statements(stmts []ir.Stmt) {
+	for _, stmt := range stmts {
+		l.statement(stmt)
+	}
+}
+
+func (l *lowerer) statement(stmt ir.Stmt) {
+	switch s := stmt.(type) {
+	case *ir.Assign:
+		value := l.expression(s.Value)
+		if s.Var.Global {
+			l.emit(&inst{kind: kGlobalStore, dst: none, a: value, b: imm(0), global: s.Var})
+		} else {
+			l.emit(&inst{kind: kMove, dst: l.vars[s.Var], a: value, b: imm(0)})
+		}
+	case *ir.Eval:
+		l.expression(s.X)
+	case *ir.If:
+		elseLabel, end := l.newLabel(), l.newLabel()
+		l.jump(kJumpZero, elseLabel, l.expression(s.Cond))
+		l.statements(s.Then)
+		l.jump(kJump, end, imm(0))
+		l.label(elseLabel)
+		l.statements(s.Else)
+		l.label(end)
+	case *ir.Loop:
+		top, post, end := l.newLabel(), l.newLabel(), l.newLabel()
+		l.label(top)
+		if s.Cond != nil {
+			l.jump(kJumpZero, end, l.expression(s.Cond))
+		}
+		l.loops = append(l.loops, loopLabels{post: post, end: end})
+		l.statements(s.Body)
+		l.loops = l.loops[:len(l.loops)-1]
+		l.label(post)
+		l.statements(s.Post)
+		l.jump(kJump, top, imm(0))
+		l.label(end)
+	case *ir.Break:
+		l.jump(kJump, l.loops[len(l.loops)-1].end, imm(0))
+	case *ir.Continue:
+		l.jump(kJump, l.loops[len(l.loops)-1].post, imm(0))
+	case *ir.Return:
+		value := imm(0)
+		if s.Value != nil {
+			value = l.expression(s.Value)
+		}
+		l.emit(&inst{kind: kReturn, dst: none, a: value, b: imm(0), size: btoi(s.Value != nil)})
+	case *ir.Poke:
+		addr := l.expression(s.Addr)
+		value := l.expression(s.Value)
+		l.emit(&inst{kind: kStore, dst: none, a: addr, b: value, size: s.Size})
+	case *ir.Write:
+		addr := l.expression(s.Addr)
+		n := l.expression(s.Len)
+		l.emit(&inst{kind: kWrite, dst: none, a: addr, b: n, imm: int64(s.FD)})
+	case *ir.Exit:
+		l.emit(&inst{kind: kExit, dst: none, a: imm(0), b: imm(0), imm: int64(s.Code)})
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// expression emits the code that computes e and returns where its value
+// is. The value of a local variable is its own register, which is safe to
+// read later in the expression, since nothing in an expression assigns to
+// local variables.
+func (l *lowerer) expression(e ir.Expr) val {
+	switch e := e.(type) {
+	case *ir.Const:
+		if e.T == ir.Float {
+			dst := l.newReg(true)
+			l.emit(&inst{kind: kFloatConst, dst: dst, a: imm(0), b: imm(0), imm: int64(math.Float64bits(e.Float))})
+			return val{reg: dst}
+		}
+		return imm(e.Int)
+	case *ir.Load:
+		if e.Var.Global {
+			dst := l.newReg(e.Var.Type == ir.Float)
+			l.emit(&inst{kind: kGlobalLoad, dst: dst, a: imm(0), b: imm(0), global: e.Var})
+			return val{reg: dst}
+		}
+		return val{reg: l.vars[e.Var]}
+	case *ir.Unary:
+		x := l.expression(e.X)
+		if e.Op == ir.Not {
+			return l.op(kNot, e.Op, false, x, imm(0))
+		}
+		return l.op(kNeg, e.Op, e.Type() == ir.Float, x, imm(0))
+	case *ir.Binary:
+		switch e.Op {
+		case ir.LogAnd, ir.LogOr:
+			return l.logical(e)
+		}
+		x := l.expression(e.X)
+		y := l.expression(e.Y)
+		if e.Op.IsComparison() {
+			return l.op(kCompare, e.Op, false, x, y)
+		}
+		return l.op(kBinary, e.Op, e.Type() == ir.Float, x, y)
+	case *ir.Call:
+		in := &inst{kind: kCall, dst: none, a: imm(0), b: imm(0), fn: e.Func}
+		for _, arg := range e.Args {
+			in.args = append(in.args, l.expression(arg))
+		}
+		if e.Func.Result != ir.Void {
+			in.dst = l.newReg(e.Func.Result == ir.Float)
+		}
+		l.emit(in)
+		return val{reg: in.dst}
+	case *ir.Convert:
+		x := l.expression(e.X)
+		if e.To == e.X.Type() {
+			return x
+		}
+		return l.op(kConvert, 0, e.To == ir.Float, x, imm(0))
+	case *ir.Addr:
+		dst := l.newReg(false)
+		l.emit(&inst{kind: kAddr, dst: dst, a: imm(0), b: imm(0), data: e.Data, imm: e.Offset})
+		return val{reg: dst}
+	case *ir.Peek:
+		dst := l.newReg(false)
+		l.emit(&inst{kind: kLoad, dst: dst, a: l.expression(e.Addr), b: imm(0), size: e.Size})
+		return val{reg: dst}
+	case *ir.Bits:
+		return l.op(kBits, 0, false, l.expression(e.X), imm(0))
+	}
+	return imm(0)
+}
+
+// logical lowers && and || to a register that holds X and is overwritten
+// with Y if X does not decide the result.
+func (l *lowerer) logical(e *ir.Binary) val {
+	dst := l.newReg(false)
+	end := l.newLabel()
+	l.emit(&inst{kind: kMove, dst: dst, a: l.expression(e.X), b: imm(0)})
+	if e.Op == ir.LogAnd {
+		l.jump(kJumpZero, end, val{reg: dst})
+	} else {
+		skip := l.newLabel()
+		l.jump(kJumpZero, skip, val{reg: dst})
+		l.jump(kJump, end, imm(0))
+		l.label(skip)
+	}
+	l.emit(&inst{kind: kMove, dst: dst, a: l.expression(e.Y), b: imm(0)})
+	l.label(end)
+	return val{reg: dst}
+}
@@ -0,0 +1,308 @@
+package amd64
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The register allocator is the linear scan of Poletto and Sarkar ("Linear
+// Scan Register Allocation", 1999). Each virtual register is live over one
+// interval of instruction positions, from the first to the last at which
+// liveness analysis finds it live, and the intervals are visited in order
+// of their start. A register freed when an interval ends goes to the next
+// one, and when none is free, the interval that ends last is spilled to a
+// stack slot.
+//
+// Every register but %rbx and %r12 to %r15 is lost across calls, so
+// intervals that span one only get those, and floats that span one are
+// always spilled. %rax, %rcx, %rdx, %r11 and %xmm15 are never allocated:
+// division, shifts and the code that moves values around need them.
+
+var (
+	callerSaved = []string{"rsi", "rdi", "r8", "r9", "r10"}
+	calleeSaved = []string{"rbx", "r12", "r13", "r14", "r15"}
+	floatRegs   = []string{"xmm8", "xmm9", "xmm10", "xmm11", "xmm12", "xmm13", "xmm14"}
+)
+
+// location is where a virtual register lives: a machine register, or a
+// stack slot at an address relative to %rbp.
+type location struct {
+	reg string // without the %
+	mem string
+}
+
+func (l location) String() string {
+	if l.reg != "" {
+		return "%" + l.reg
+	}
+	return l.mem
+}
+
+// interval is the range of positions over which a virtual register is
+// live.
+type interval struct {
+	reg        int
+	start, end int
+	float      bool
+	spansCall  bool
+	assigned   string // the machine register, or "" if spilled
+}
+
+// allocation is where the virtual registers of a function live.
+type allocation struct {
+	locs  []location // by virtual register; unused ones have none
+	saved []string   // the callee-saved registers the function uses
+	slots int        // the number of stack slots
+}
+
+func allocate(c *code) *allocation {
+	intervals := liveIntervals(c)
+	var calls []int
+	for i, in := range c.insts {
+		if in.isCall() {
+			calls = append(calls, i)
+		}
+	}
+	for _, iv := range intervals {
+		// Arguments are read before a call and results written after it,
+		// so only registers live at both sides of one must survive it.
+		j := sort.SearchInts(calls, iv.start+1)
+		iv.spansCall = j < len(calls) && calls[j] < iv.end
+	}
+
+	var active []*interval // sorted by end
+	inUse := map[string]bool{}
+	var spilled []*interval
+	for _, cur := range intervals {
+		// Expire the intervals that ended before this one starts.
+		n := 0
+		for _, iv := range active {
+			if iv.end < cur.start {
+				delete(inUse, iv.assigned)
+			} else {
+				active[n] = iv
+				n++
+			}
+		}
+		active = active[:n]
+
+		candidates := candidateRegs(cur)
+		for _, r := range candidates {
+			if !inUse[r] {
+				cur.assigned = r
+				break
+			}
+		}
+		if cur.assigned == "" {
+			// Spill whichever of this interval and the active ones holding a
+			// register it could use ends last.
+			var victim *interval
+			for _, iv := range active {
+				if contains(candidates, iv.assigned) && (victim == nil || iv.end > victim.end) {
+					victim = iv
+				}
+			}
+			if victim == nil || victim.end <= cur.end {
+				spilled = append(spilled, cur)
+				continue
+			}
+			cur.assigned, victim.assigned = victim.assigned, ""
+			spilled = append(spilled, victim)
+			active = remove(active, victim)
+		}
+		inUse[cur.assigned] = true
+		i := sort.Search(len(active), func(i int) bool { return active[i].end > cur.end })
+		active = append(active[:i], append([]*interval{cur}, active[i:]...)...)
+	}
+
+	a := &allocation{locs: make([]location, len(c.floats))}
+	for _, iv := range intervals {
+		if iv.assigned != "" {
+			a.locs[iv.reg] = location{reg: iv.assigned}
+			if contains(calleeSaved, iv.assigned) && !contains(a.saved, iv.assigned) {
+				a.saved = append(a.saved, iv.assigned)
+			}
+		}
+	}
+	sort.Slice(a.saved, func(i, j int) bool { return index(calleeSaved, a.saved[i]) < index(calleeSaved, a.saved[j]) })
+	// Slots go below the saved registers, which the prologue pushes.
+	for _, iv := range spilled {
+		a.slots++
+		a.locs[iv.reg] = location{mem: fmt.Sprintf("%d(%%rbp)", -8*(len(a.saved)+a.slots))}
+	}
+	return a
+}
+
+func candidateRegs(iv *interval) []string {
+	switch {
+	case iv.float && iv.spansCall:
+		return nil
+	case iv.float:
+		return floatRegs
+	case iv.spansCall:
+		return calleeSaved
+	default:
+		// Prefer registers that need not be saved.
+		return append(append([]string{}, callerSaved...), calleeSaved...)
+	}
+}
+
+func contains(regs []string, r string) bool { return index(regs, r) >= 0 }
+
+func index(regs []string, r string) int {
+	for i, s := range regs {
+		if s == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func remove(intervals []*interval, iv *interval) []*interval {
+	for i, x := range intervals {
+		if x == iv {
+			return append(intervals[:i], intervals[i+1:]...)
+		}
+	}
+	return intervals
+}
+
+// block is a basic block: the instructions from first to last.
+type block struct {
+	first, last int
+	succs       []int
+	use, def    bitset
+	in, out     bitset
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset    { return make(bitset, (n+63)/64) }
+func (s bitset) has(i int) bool { return s[i/64]&(1<<(i%64)) != 0 }
+func (s bitset) add(i int)      { s[i/64] |= 1 << (i % 64) }
+func (s bitset) each(f func(i int)) {
+	for w, bits := range s {
+		for b := 0; bits != 0; b++ {
+			if bits&1 != 0 {
+				f(w*64 + b)
+			}
+			bits >>= 1
+		}
+	}
+}
+
+// liveIntervals computes the live interval of each virtual register that
+// is defined or used, in order of their start. A register live into or
+// out of a block is live at its first or last instruction, which stretches
+// the intervals of registers live around a loop over the whole loop.
+func liveIntervals(c *code) []*interval {
+	blocks := basicBlocks(c)
+	n := len(c.floats)
+	for _, b := range blocks {
+		b.use, b.def, b.in, b.out = newBitset(n), newBitset(n), newBitset(n), newBitset(n)
+		for _, in := range c.insts[b.first : b.last+1] {
+			for _, r := range in.uses() {
+				if !b.def.has(r) {
+					b.use.add(r)
+				}
+			}
+			for _, r := range in.defs() {
+				b.def.add(r)
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(blocks) - 1; i >= 0; i-- {
+			b := blocks[i]
+			for _, s := range b.succs {
+				for w, bits := range blocks[s].in {
+					b.out[w] |= bits
+				}
+			}
+			for w := range b.in {
+				in := b.use[w] | b.out[w]&^b.def[w]
+				if in != b.in[w] {
+					b.in[w], changed = in, true
+				}
+			}
+		}
+	}
+
+	byReg := make([]*interval, n)
+	extend := func(r, pos int) {
+		iv := byReg[r]
+		if iv == nil {
+			byReg[r] = &interval{reg: r, start: pos, end: pos, float: c.floats[r]}
+			return
+		}
+		if pos < iv.start {
+			iv.start = pos
+		}
+		if pos > iv.end {
+			iv.end = pos
+		}
+	}
+	for _, b := range blocks {
+		b.in.each(func(r int) { extend(r, b.first) })
+		b.out.each(func(r int) { extend(r, b.last) })
+	}
+	for i, in := range c.insts {
+		for _, r := range append(in.uses(), in.defs()...) {
+			extend(r, i)
+		}
+	}
+
+	var intervals []*interval
+	for _, iv := range byReg {
+		if iv != nil {
+			intervals = append(intervals, iv)
+		}
+	}
+	sort.SliceStable(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+	return intervals
+}
+
+// basicBlocks splits the instructions of c into basic blocks. A block
+// starts at a label or after a jump, return or exit, and ends before the
+// next label or at the next jump, return or exit.
+func basicBlocks(c *code) []*block {
+	var blocks []*block
+	labels := map[int]int{}
+	start := 0
+	for i, in := range c.insts {
+		if in.kind == kLabel && i > start {
+			blocks = append(blocks, &block{first: start, last: i - 1})
+			start = i
+		}
+		if in.kind == kLabel {
+			labels[in.label] = len(blocks)
+		}
+		switch in.kind {
+		case kJump, kJumpZero, kReturn, kExit:
+			blocks = append(blocks, &block{first: start, last: i})
+			start = i + 1
+		}
+	}
+	if start < len(c.insts) {
+		blocks = append(blocks, &block{first: start, last: len(c.insts) - 1})
+	}
+	for i, b := range blocks {
+		last := c.insts[b.last]
+		switch last.kind {
+		case kJump:
+			b.succs = []int{labels[last.label]}
+		case kJumpZero:
+			b.succs = []int{labels[last.label]}
+			if i+1 < len(blocks) {
+				b.succs = append(b.succs, i+1)
+			}
+		case kReturn, kExit:
+		default:
+			if i+1 < len(blocks) {
+				b.succs = []int{i + 1}
+			}
+		}
+	}
+	return blocks
+}
@@ -10,6 +10,14 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() lexer.Position // position of the first character of the node
+	End() lexer.Position // position immediately after the node
+}
+
+// after returns the position immediately following the single-character
+// token at p, such as a closing brace or parenthesis.
+func after(p lexer.Position) lexer.Position {
+	return lexer.Position{Offset: p.Offset + 1, Line: p.Line, Column: p.Column + 1}
 }
 
 type Statement interface {
@@ -33,6 +41,20 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() lexer.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return lexer.Position{}
+}
+
+func (p *Program) End() lexer.Position {
+	if n := len(p.Statements); n > 0 {
+		return p.Statements[n-1].End()
+	}
+	return lexer.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
@@ -41,19 +63,57 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// LetStatement declares a variable, either as let x = v or in the short
+// form x := v. Several names, as in q, r := divmod(a, b), declare one
+// variable for each value; v, ok := m[k] declares ok as well and reports in
+// it whether the key was present in the map.
 type LetStatement struct {
-	Token lexer.Token // the 'let' token
+	Token lexer.Token // the 'let' or ':=' token
 	Name  *Identifier
+	Rest  []*Identifier // the names after the first, if any
+	Type  TypeExpr      // nil if unannotated
 	Value Expression
 }
 
+// Names returns every name the statement declares, in order.
+func (ls *LetStatement) Names() []*Identifier {
+	return append([]*Identifier{ls.Name}, ls.Rest...)
+}
+
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() lexer.Position {
+	if ls.Token.Type == lexer.DEFINE && ls.Name != nil {
+		return ls.Name.Pos()
+	}
+	return ls.Token.Pos
+}
+func (ls *LetStatement) End() lexer.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
-	out.WriteString(ls.TokenLiteral() + " ")
+	if ls.Token.Type != lexer.DEFINE {
+		out.WriteString(ls.TokenLiteral() + " ")
+	}
 	out.WriteString(ls.Name.String())
-	out.WriteString(" = ")
+	for _, name := range ls.Rest {
+		out.WriteString(", " + name.String())
+	}
+	if ls.Type != nil {
+		out.WriteString(" " + ls.Type.String())
+	}
+	if ls.Token.Type == lexer.DEFINE {
+		out.WriteString(" := ")
+	} else {
+		out.WriteString(" = ")
+	}
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
 	}
@@ -68,6 +128,13 @@ type PrintStatement struct {
 
 func (ps *PrintStatement) statementNode()       {}
 func (ps *PrintStatement) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PrintStatement) Pos() lexer.Position  { return ps.Token.Pos }
+func (ps *PrintStatement) End() lexer.Position {
+	if ps.Expression != nil {
+		return ps.Expression.End()
+	}
+	return ps.Token.End
+}
 func (ps *PrintStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ps.TokenLiteral() + " ")
@@ -78,6 +145,211 @@ func (ps *PrintStatement) String() string {
 	return out.String()
 }
 
+// DeferStatement defers a call until the enclosing function returns, or
+// at the top level until the program ends. The function and its arguments
+// are evaluated when the defer statement runs.
+type DeferStatement struct {
+	Token lexer.Token // the 'defer' token
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) Pos() lexer.Position  { return ds.Token.Pos }
+func (ds *DeferStatement) End() lexer.Position {
+	if ds.Call != nil {
+		return ds.Call.End()
+	}
+	return ds.Token.End
+}
+func (ds *DeferStatement) String() string {
+	if ds.Call == nil {
+		return "defer ;"
+	}
+	return "defer " + ds.Call.String() + ";"
+}
+
+type ReturnStatement struct {
+	Token       lexer.Token // the 'return' token
+	ReturnValue Expression  // nil for a bare return
+}
+
+func (rs *ReturnStatement) statementNode()       {}
+func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() lexer.Position  { return rs.Token.Pos }
+func (rs *ReturnStatement) End() lexer.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
+func (rs *ReturnStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(rs.TokenLiteral())
+	if rs.ReturnValue != nil {
+		out.WriteString(" ")
+		out.WriteString(rs.ReturnValue.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+type ForStatement struct {
+	Token     lexer.Token // the 'for' token
+	Init      Statement   // nil unless the loop has three clauses
+	Condition Expression  // nil for an infinite loop
+	Post      Statement   // nil unless the loop has three clauses
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() lexer.Position  { return fs.Token.Pos }
+func (fs *ForStatement) End() lexer.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for ")
+	if fs.Init != nil || fs.Post != nil {
+		if fs.Init != nil {
+			out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+		}
+		out.WriteString("; ")
+		if fs.Condition != nil {
+			out.WriteString(fs.Condition.String())
+		}
+		out.WriteString("; ")
+		if fs.Post != nil {
+			out.WriteString(strings.TrimSuffix(fs.Post.String(), ";"))
+		}
+		out.WriteString(" ")
+	} else if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+		out.WriteString(" ")
+	}
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token lexer.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() lexer.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() lexer.Position  { return bs.Token.End }
+func (bs *BreakStatement) String() string       { return "break;" }
+
+type ContinueStatement struct {
+	Token lexer.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() lexer.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() lexer.Position  { return cs.Token.End }
+func (cs *ContinueStatement) String() string       { return "continue;" }
+
+// TypeStatement declares a named type, e.g. type Point struct { x, y int }.
+type TypeStatement struct {
+	Token lexer.Token // the 'type' token
+	Name  *Identifier
+	Type  *StructType
+}
+
+func (ts *TypeStatement) statementNode()       {}
+func (ts *TypeStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TypeStatement) Pos() lexer.Position  { return ts.Token.Pos }
+func (ts *TypeStatement) End() lexer.Position {
+	if ts.Type != nil {
+		return ts.Type.End()
+	}
+	return ts.Token.End
+}
+func (ts *TypeStatement) String() string {
+	return "type " + ts.Name.String() + " " + ts.Type.String() + ";"
+}
+
+// MethodStatement declares a method, e.g. func (p *Point) Move(dx int) { ... }.
+// The receiver is the first parameter of Function, so a method can be checked
+// and run as a function that takes its receiver as an argument.
+type MethodStatement struct {
+	Token    lexer.Token // the 'func' token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (ms *MethodStatement) statementNode()       {}
+func (ms *MethodStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MethodStatement) Pos() lexer.Position  { return ms.Token.Pos }
+func (ms *MethodStatement) End() lexer.Position  { return ms.Function.End() }
+func (ms *MethodStatement) String() string {
+	fl := ms.Function
+	var out bytes.Buffer
+	out.WriteString("func (")
+	out.WriteString(formatParameters(fl.Parameters[:1], fl.ParamTypes[:1]))
+	out.WriteString(") " + ms.Name.String() + "(")
+	out.WriteString(formatParameters(fl.Parameters[1:], fl.ParamTypes[1:]))
+	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString(fl.ReturnType.String() + " ")
+	}
+	out.WriteString(fl.Body.String())
+	return out.String()
+}
+
+// Receiver returns the receiver of the method and its type, which is a
+// NamedType or a PointerType to one.
+func (ms *MethodStatement) Receiver() (*Identifier, TypeExpr) {
+	return ms.Function.Parameters[0], ms.Function.ParamTypes[0]
+}
+
+type AssignStatement struct {
+	Token    lexer.Token // the assignment operator token, e.g. = or +=
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (as *AssignStatement) statementNode()       {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) Pos() lexer.Position  { return as.Target.Pos() }
+func (as *AssignStatement) End() lexer.Position {
+	if as.Value != nil {
+		return as.Value.End()
+	}
+	return as.Token.End
+}
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(as.Target.String())
+	out.WriteString(" " + as.Operator + " ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+type IncDecStatement struct {
+	Token    lexer.Token // the ++ or -- token
+	Target   Expression
+	Operator string
+}
+
+func (ids *IncDecStatement) statementNode()       {}
+func (ids *IncDecStatement) TokenLiteral() string { return ids.Token.Literal }
+func (ids *IncDecStatement) Pos() lexer.Position  { return ids.Target.Pos() }
+func (ids *IncDecStatement) End() lexer.Position  { return ids.Token.End }
+func (ids *IncDecStatement) String() string {
+	return ids.Target.String() + ids.Operator + ";"
+}
+
 type ExpressionStatement struct {
 	Token      lexer.Token // the first token of the expression
 	Expression Expression
@@ -85,6 +357,13 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() lexer.Position  { return es.Token.Pos }
+func (es *ExpressionStatement) End() lexer.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -99,6 +378,8 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() lexer.Position  { return i.Token.Pos }
+func (i *Identifier) End() lexer.Position  { return i.Token.End }
 func (i *Identifier) String() string       { return i.Value }
 
 type IntegerLiteral struct {
@@ -108,8 +389,34 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() lexer.Position  { return il.Token.Pos }
+func (il *IntegerLiteral) End() lexer.Position  { return il.Token.End }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Token lexer.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() lexer.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() lexer.Position  { return fl.Token.End }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+// StringLiteral is a double-quoted string. Token.Literal holds the source
+// text, quotes and escapes included; Value holds the decoded string.
+type StringLiteral struct {
+	Token lexer.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() lexer.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() lexer.Position  { return sl.Token.End }
+func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+
 type Boolean struct {
 	Token lexer.Token
 	Value bool
@@ -117,6 +424,8 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() lexer.Position  { return b.Token.Pos }
+func (b *Boolean) End() lexer.Position  { return b.Token.End }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type PrefixExpression struct {
@@ -127,6 +436,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() lexer.Position  { return pe.Token.Pos }
+func (pe *PrefixExpression) End() lexer.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -145,6 +461,18 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() lexer.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *InfixExpression) End() lexer.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -164,6 +492,19 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() lexer.Position  { return ie.Token.Pos }
+func (ie *IfExpression) End() lexer.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	if ie.Condition != nil {
+		return ie.Condition.End()
+	}
+	return ie.Token.End
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("if")
@@ -182,32 +523,66 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      lexer.Token // The 'func' token
 	Parameters []*Identifier
+	ParamTypes []TypeExpr // parallel to Parameters; nil entries are unannotated
+	ReturnType TypeExpr   // nil if unannotated
 	Body       *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() lexer.Position  { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() lexer.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
-	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(formatParameters(fl.Parameters, fl.ParamTypes))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString(fl.ReturnType.String())
+		out.WriteString(" ")
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+// formatParameters formats a parameter list, with the type of each
+// annotated parameter after its name.
+func formatParameters(params []*Identifier, types []TypeExpr) string {
+	out := []string{}
+	for i, p := range params {
+		if i < len(types) && types[i] != nil {
+			out = append(out, p.String()+" "+types[i].String())
+		} else {
+			out = append(out, p.String())
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
 type BlockStatement struct {
 	Token      lexer.Token // the { token
 	Statements []Statement
+	Rbrace     lexer.Position // position of the closing }
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() lexer.Position  { return bs.Token.Pos }
+func (bs *BlockStatement) End() lexer.Position {
+	if bs.Rbrace.IsValid() {
+		return after(bs.Rbrace)
+	}
+	if n := len(bs.Statements); n > 0 {
+		return bs.Statements[n-1].End()
+	}
+	return bs.Token.End
+}
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString("{ ")
@@ -222,10 +597,23 @@ type CallExpression struct {
 	Token     lexer.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen    lexer.Position // position of the closing )
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() lexer.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+func (ce *CallExpression) End() lexer.Position {
+	if ce.Rparen.IsValid() {
+		return after(ce.Rparen)
+	}
+	return ce.Token.End
+}
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 	args := []string{}
@@ -238,3 +626,204 @@ func (ce *CallExpression) String() string {
 	out.WriteString(")")
 	return out.String()
 }
+
+// ArrayLiteral is a slice literal such as [1, 2, 3].
+type ArrayLiteral struct {
+	Token    lexer.Token // the '[' token
+	Elements []Expression
+	Rbracket lexer.Position // position of the closing ]
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() lexer.Position  { return al.Token.Pos }
+func (al *ArrayLiteral) End() lexer.Position {
+	if al.Rbracket.IsValid() {
+		return after(al.Rbracket)
+	}
+	return al.Token.End
+}
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapLiteral is a map composite literal such as map[string]int{"a": 1}.
+type MapLiteral struct {
+	Token  lexer.Token // the 'map' token
+	Type   *MapType
+	Keys   []Expression
+	Values []Expression   // Values[i] is the value for Keys[i]
+	Rbrace lexer.Position // position of the closing }
+}
+
+func (ml *MapLiteral) expressionNode()      {}
+func (ml *MapLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MapLiteral) Pos() lexer.Position  { return ml.Token.Pos }
+func (ml *MapLiteral) End() lexer.Position {
+	if ml.Rbrace.IsValid() {
+		return after(ml.Rbrace)
+	}
+	return ml.Token.End
+}
+func (ml *MapLiteral) String() string {
+	pairs := []string{}
+	for i, key := range ml.Keys {
+		pairs = append(pairs, key.String()+": "+ml.Values[i].String())
+	}
+	return ml.Type.String() + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// StructLiteral is a struct composite literal such as Point{x: 1, y: 2} or
+// Point{1, 2}.
+type StructLiteral struct {
+	Token  lexer.Token // the '{' token
+	Type   *NamedType
+	Fields []*Identifier // the field names, or nil if the values are in field order
+	Values []Expression
+	Rbrace lexer.Position // position of the closing }
+}
+
+func (sl *StructLiteral) expressionNode()      {}
+func (sl *StructLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StructLiteral) Pos() lexer.Position  { return sl.Type.Pos() }
+func (sl *StructLiteral) End() lexer.Position {
+	if sl.Rbrace.IsValid() {
+		return after(sl.Rbrace)
+	}
+	return sl.Token.End
+}
+func (sl *StructLiteral) String() string {
+	elements := []string{}
+	for i, value := range sl.Values {
+		if sl.Fields != nil {
+			elements = append(elements, sl.Fields[i].String()+": "+value.String())
+		} else {
+			elements = append(elements, value.String())
+		}
+	}
+	return sl.Type.String() + "{" + strings.Join(elements, ", ") + "}"
+}
+
+// IndexExpression is an element access such as a[i] or m[k].
+type IndexExpression struct {
+	Token    lexer.Token // the '[' token
+	Left     Expression
+	Index    Expression
+	Rbracket lexer.Position // position of the closing ]
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() lexer.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() lexer.Position {
+	if ie.Rbracket.IsValid() {
+		return after(ie.Rbracket)
+	}
+	return ie.Token.End
+}
+func (ie *IndexExpression) String() string {
+	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+}
+
+// SliceExpression is a slicing operation such as a[lo:hi]. Either bound may
+// be omitted.
+type SliceExpression struct {
+	Token    lexer.Token // the '[' token
+	Left     Expression
+	Low      Expression // nil if omitted
+	High     Expression // nil if omitted
+	Rbracket lexer.Position
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() lexer.Position  { return se.Left.Pos() }
+func (se *SliceExpression) End() lexer.Position {
+	if se.Rbracket.IsValid() {
+		return after(se.Rbracket)
+	}
+	return se.Token.End
+}
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+// SelectorExpression selects a field or method, as in p.x or p.Move.
+type SelectorExpression struct {
+	Token lexer.Token // the '.' token
+	X     Expression
+	Sel   *Identifier
+}
+
+func (se *SelectorExpression) expressionNode()      {}
+func (se *SelectorExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectorExpression) Pos() lexer.Position  { return se.X.Pos() }
+func (se *SelectorExpression) End() lexer.Position {
+	if se.Sel != nil {
+		return se.Sel.End()
+	}
+	return se.Token.End
+}
+func (se *SelectorExpression) String() string { return se.X.String() + "." + se.Sel.String() }
+
+// TupleExpression is a list of expressions separated by commas, such as the
+// values in return q, r or the targets and values in a, b = b, a. It only
+// appears where several values are allowed.
+type TupleExpression struct {
+	Token    lexer.Token // the first ',' token
+	Elements []Expression
+}
+
+func (te *TupleExpression) expressionNode()      {}
+func (te *TupleExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TupleExpression) Pos() lexer.Position  { return te.Elements[0].Pos() }
+func (te *TupleExpression) End() lexer.Position  { return te.Elements[len(te.Elements)-1].End() }
+func (te *TupleExpression) String() string {
+	elements := []string{}
+	for _, el := range te.Elements {
+		elements = append(elements, el.String())
+	}
+	return strings.Join(elements, ", ")
+}
+
+// BadStatement stands for a statement that could not be parsed. It covers
+// the tokens the parser skipped to recover from the error.
+type BadStatement struct {
+	Token  lexer.Token    // the first token of the statement
+	EndPos lexer.Position // position immediately after the skipped tokens
+}
+
+func (bs *BadStatement) statementNode()       {}
+func (bs *BadStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BadStatement) Pos() lexer.Position  { return bs.Token.Pos }
+func (bs *BadStatement) End() lexer.Position  { return bs.EndPos }
+func (bs *BadStatement) String() string       { return "<bad statement>" }
+
+// BadExpression stands for an expression that could not be parsed, so that
+// the statement containing it can still be checked.
+type BadExpression struct {
+	Token  lexer.Token    // the first token of the expression
+	EndPos lexer.Position // position immediately after the skipped tokens
+}
+
+func (be *BadExpression) expressionNode()      {}
+func (be *BadExpression) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpression) Pos() lexer.Position  { return be.Token.Pos }
+func (be *BadExpression) End() lexer.Position  { return be.EndPos }
+func (be *BadExpression) String() string       { return "<bad expression>" }
@@ -0,0 +1,165 @@
+package ast
+
+// Inspect traverses an AST in depth-first order without modifying it. It
+// calls f for each node; if f returns false, the children of that node are
+// skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Statements {
+			Inspect(stmt, f)
+		}
+	case *BlockStatement:
+		for _, stmt := range n.Statements {
+			Inspect(stmt, f)
+		}
+	case *LetStatement:
+		if n.Name != nil {
+			Inspect(n.Name, f)
+		}
+		for _, name := range n.Rest {
+			Inspect(name, f)
+		}
+		inspectType(n.Type, f)
+		inspectExpression(n.Value, f)
+	case *TypeStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Type, f)
+	case *MethodStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Function, f)
+	case *ReturnStatement:
+		inspectExpression(n.ReturnValue, f)
+	case *AssignStatement:
+		inspectExpression(n.Target, f)
+		inspectExpression(n.Value, f)
+	case *IncDecStatement:
+		inspectExpression(n.Target, f)
+	case *ForStatement:
+		inspectStatement(n.Init, f)
+		inspectExpression(n.Condition, f)
+		inspectStatement(n.Post, f)
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+	case *PrintStatement:
+		inspectExpression(n.Expression, f)
+	case *DeferStatement:
+		if n.Call != nil {
+			Inspect(n.Call, f)
+		}
+	case *ExpressionStatement:
+		inspectExpression(n.Expression, f)
+	case *PrefixExpression:
+		inspectExpression(n.Right, f)
+	case *InfixExpression:
+		inspectExpression(n.Left, f)
+		inspectExpression(n.Right, f)
+	case *IfExpression:
+		inspectExpression(n.Condition, f)
+		if n.Consequence != nil {
+			Inspect(n.Consequence, f)
+		}
+		if n.Alternative != nil {
+			Inspect(n.Alternative, f)
+		}
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			Inspect(param, f)
+			if i < len(n.ParamTypes) {
+				inspectType(n.ParamTypes[i], f)
+			}
+		}
+		inspectType(n.ReturnType, f)
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+	case *CallExpression:
+		inspectExpression(n.Function, f)
+		for _, arg := range n.Arguments {
+			inspectExpression(arg, f)
+		}
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *MapLiteral:
+		Inspect(n.Type, f)
+		for i, key := range n.Keys {
+			Inspect(key, f)
+			Inspect(n.Values[i], f)
+		}
+	case *StructLiteral:
+		Inspect(n.Type, f)
+		for i, value := range n.Values {
+			if n.Fields != nil {
+				Inspect(n.Fields[i], f)
+			}
+			Inspect(value, f)
+		}
+	case *SelectorExpression:
+		Inspect(n.X, f)
+		Inspect(n.Sel, f)
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *SliceExpression:
+		Inspect(n.Left, f)
+		inspectExpression(n.Low, f)
+		inspectExpression(n.High, f)
+	case *TupleExpression:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *SliceType:
+		Inspect(n.Elem, f)
+	case *MapType:
+		Inspect(n.Key, f)
+		Inspect(n.Value, f)
+	case *StructType:
+		for _, field := range n.Fields {
+			for _, name := range field.Names {
+				Inspect(name, f)
+			}
+			Inspect(field.Type, f)
+		}
+	case *PointerType:
+		Inspect(n.Elem, f)
+	case *FuncType:
+		for _, param := range n.Params {
+			Inspect(param, f)
+		}
+		inspectType(n.Result, f)
+	case *TupleType:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	// Literals, identifiers, branch statements and error nodes have no children.
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *BreakStatement, *ContinueStatement, *NamedType,
+		*BadStatement, *BadExpression:
+	}
+}
+
+// inspectExpression and inspectStatement skip optional children that are
+// absent, such as the init clause of a condition-only loop.
+func inspectExpression(e Expression, f func(Node) bool) {
+	if e != nil {
+		Inspect(e, f)
+	}
+}
+
+func inspectStatement(s Statement, f func(Node) bool) {
+	if s != nil {
+		Inspect(s, f)
+	}
+}
+
+func inspectType(t TypeExpr, f func(Node) bool) {
+	if t != nil {
+		Inspect(t, f)
+	}
+}
@@ -33,8 +33,34 @@ func Modify(node Node, visitor Visitor) Node {
 		n.Statements = filterNilStatements(n.Statements)
 	case *LetStatement:
 		n.Value = Modify(n.Value, visitor).(Expression)
+	case *MethodStatement:
+		n.Function = Modify(n.Function, visitor).(*FunctionLiteral)
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			n.ReturnValue = Modify(n.ReturnValue, visitor).(Expression)
+		}
+	case *ForStatement:
+		if n.Init != nil {
+			n.Init, _ = Modify(n.Init, visitor).(Statement)
+		}
+		if n.Condition != nil {
+			n.Condition = Modify(n.Condition, visitor).(Expression)
+		}
+		if n.Post != nil {
+			n.Post, _ = Modify(n.Post, visitor).(Statement)
+		}
+		n.Body = Modify(n.Body, visitor).(*BlockStatement)
+	case *AssignStatement:
+		n.Target = Modify(n.Target, visitor).(Expression)
+		n.Value = Modify(n.Value, visitor).(Expression)
+	case *IncDecStatement:
+		n.Target = Modify(n.Target, visitor).(Expression)
 	case *PrintStatement:
 		n.Expression = Modify(n.Expression, visitor).(Expression)
+	case *DeferStatement:
+		if n.Call != nil {
+			n.Call, _ = Modify(n.Call, visitor).(*CallExpression)
+		}
 	case *ExpressionStatement:
 		n.Expression = Modify(n.Expression, visitor).(Expression)
 	case *PrefixExpression:
@@ -58,8 +84,40 @@ func Modify(node Node, visitor Visitor) Node {
 		for i, arg := range n.Arguments {
 			n.Arguments[i] = Modify(arg, visitor).(Expression)
 		}
-	// Literals and identifiers have no children to modify, so we do nothing.
-	case *Identifier, *IntegerLiteral, *Boolean:
+	case *ArrayLiteral:
+		for i, el := range n.Elements {
+			n.Elements[i] = Modify(el, visitor).(Expression)
+		}
+	case *MapLiteral:
+		for i, key := range n.Keys {
+			n.Keys[i] = Modify(key, visitor).(Expression)
+			n.Values[i] = Modify(n.Values[i], visitor).(Expression)
+		}
+	case *StructLiteral:
+		for i, value := range n.Values {
+			n.Values[i] = Modify(value, visitor).(Expression)
+		}
+	case *TupleExpression:
+		for i, el := range n.Elements {
+			n.Elements[i] = Modify(el, visitor).(Expression)
+		}
+	case *SelectorExpression:
+		n.X = Modify(n.X, visitor).(Expression)
+	case *IndexExpression:
+		n.Left = Modify(n.Left, visitor).(Expression)
+		n.Index = Modify(n.Index, visitor).(Expression)
+	case *SliceExpression:
+		n.Left = Modify(n.Left, visitor).(Expression)
+		if n.Low != nil {
+			n.Low = Modify(n.Low, visitor).(Expression)
+		}
+		if n.High != nil {
+			n.High = Modify(n.High, visitor).(Expression)
+		}
+	// Literals, identifiers, branch statements, type declarations and error nodes have no
+	// children to modify.
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean, *BreakStatement, *ContinueStatement,
+		*TypeStatement, *BadStatement, *BadExpression:
 		// No children to traverse
 	}
 
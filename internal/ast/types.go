@@ -0,0 +1,176 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"golite.dev/mvp/internal/lexer"
+)
+
+// TypeExpr is a type annotation, such as the int in `func(n int) int`.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// NamedType refers to a type by name, e.g. int or bool.
+type NamedType struct {
+	Token lexer.Token // the IDENT token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) Pos() lexer.Position  { return nt.Token.Pos }
+func (nt *NamedType) End() lexer.Position  { return nt.Token.End }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// FuncType is the type of a function, e.g. func(int, int) bool.
+type FuncType struct {
+	Token  lexer.Token // the 'func' token
+	Params []TypeExpr
+	Result TypeExpr // nil if the function produces no value
+	Rparen lexer.Position
+}
+
+func (ft *FuncType) typeNode()            {}
+func (ft *FuncType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FuncType) Pos() lexer.Position  { return ft.Token.Pos }
+func (ft *FuncType) End() lexer.Position {
+	if ft.Result != nil {
+		return ft.Result.End()
+	}
+	if ft.Rparen.IsValid() {
+		return after(ft.Rparen)
+	}
+	return ft.Token.End
+}
+func (ft *FuncType) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ft.Params {
+		params = append(params, p.String())
+	}
+	out.WriteString("func(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if ft.Result != nil {
+		out.WriteString(" ")
+		out.WriteString(ft.Result.String())
+	}
+	return out.String()
+}
+
+// SliceType is the type of a slice, e.g. []int.
+type SliceType struct {
+	Token lexer.Token // the '[' token
+	Elem  TypeExpr
+}
+
+func (st *SliceType) typeNode()            {}
+func (st *SliceType) TokenLiteral() string { return st.Token.Literal }
+func (st *SliceType) Pos() lexer.Position  { return st.Token.Pos }
+func (st *SliceType) End() lexer.Position {
+	if st.Elem != nil {
+		return st.Elem.End()
+	}
+	return st.Token.End
+}
+func (st *SliceType) String() string { return "[]" + st.Elem.String() }
+
+// MapType is the type of a map, e.g. map[string]int.
+type MapType struct {
+	Token lexer.Token // the 'map' token
+	Key   TypeExpr
+	Value TypeExpr
+}
+
+func (mt *MapType) typeNode()            {}
+func (mt *MapType) TokenLiteral() string { return mt.Token.Literal }
+func (mt *MapType) Pos() lexer.Position  { return mt.Token.Pos }
+func (mt *MapType) End() lexer.Position {
+	if mt.Value != nil {
+		return mt.Value.End()
+	}
+	return mt.Token.End
+}
+func (mt *MapType) String() string { return "map[" + mt.Key.String() + "]" + mt.Value.String() }
+
+// StructType is the type of a struct, e.g. struct { x, y int }.
+type StructType struct {
+	Token  lexer.Token // the 'struct' token
+	Fields []*Field
+	Rbrace lexer.Position // position of the closing }
+}
+
+// Field declares one or more struct fields of the same type.
+type Field struct {
+	Names []*Identifier
+	Type  TypeExpr
+}
+
+func (st *StructType) typeNode()            {}
+func (st *StructType) TokenLiteral() string { return st.Token.Literal }
+func (st *StructType) Pos() lexer.Position  { return st.Token.Pos }
+func (st *StructType) End() lexer.Position {
+	if st.Rbrace.IsValid() {
+		return after(st.Rbrace)
+	}
+	return st.Token.End
+}
+func (st *StructType) String() string {
+	fields := []string{}
+	for _, f := range st.Fields {
+		names := []string{}
+		for _, name := range f.Names {
+			names = append(names, name.String())
+		}
+		fields = append(fields, strings.Join(names, ", ")+" "+f.Type.String())
+	}
+	if len(fields) == 0 {
+		return "struct {}"
+	}
+	return "struct { " + strings.Join(fields, "; ") + " }"
+}
+
+// PointerType is the type of a pointer, e.g. *Point.
+type PointerType struct {
+	Token lexer.Token // the '*' token
+	Elem  TypeExpr
+}
+
+func (pt *PointerType) typeNode()            {}
+func (pt *PointerType) TokenLiteral() string { return pt.Token.Literal }
+func (pt *PointerType) Pos() lexer.Position  { return pt.Token.Pos }
+func (pt *PointerType) End() lexer.Position {
+	if pt.Elem != nil {
+		return pt.Elem.End()
+	}
+	return pt.Token.End
+}
+func (pt *PointerType) String() string { return "*" + pt.Elem.String() }
+
+// TupleType is the result type of a function that returns several values,
+// e.g. the (int, bool) in func(s string) (int, bool).
+type TupleType struct {
+	Lparen lexer.Token // the '(' token
+	Elems  []TypeExpr
+	Rparen lexer.Position
+}
+
+func (tt *TupleType) typeNode()            {}
+func (tt *TupleType) TokenLiteral() string { return tt.Lparen.Literal }
+func (tt *TupleType) Pos() lexer.Position  { return tt.Lparen.Pos }
+func (tt *TupleType) End() lexer.Position {
+	if tt.Rparen.IsValid() {
+		return after(tt.Rparen)
+	}
+	return tt.Lparen.End
+}
+func (tt *TupleType) String() string {
+	elems := []string{}
+	for _, e := range tt.Elems {
+		elems = append(elems, e.String())
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
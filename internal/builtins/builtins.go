@@ -0,0 +1,205 @@
+// Package builtins defines the predeclared functions of GoLite, such as len
+// and println. Each builtin is registered once, with everything the
+// compiler needs to know about it: how the checker types a call, how the
+// evaluator runs it and how the C backend lowers it.
+package builtins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/types"
+)
+
+// Builtin is a predeclared function. Builtins may accept arguments of
+// several types, so each call to one is typed on its own, by Check, and
+// they cannot be used as values.
+type Builtin struct {
+	// Name is the name the builtin is called by. Qualified names, such as
+	// errors.New, are called through the name of a package.
+	Name string
+	// Signature describes the arguments and result, for diagnostics.
+	Signature string
+
+	// Check returns the type of call, whose arguments have the types args,
+	// and reports misuse to c.
+	Check func(c Checker, call *ast.CallExpression, args []types.Type) types.Type
+	// Eval runs the builtin in the evaluator. It returns nil if the builtin
+	// has no result.
+	Eval object.BuiltinFunction
+	// Lower emits call as a C expression.
+	Lower func(g Generator, call *ast.CallExpression)
+}
+
+// Checker is what the type rules of builtins need from the type checker.
+type Checker interface {
+	// NewVar returns a type not known yet.
+	NewVar() types.Type
+	// Unify makes a and b the same type and reports whether it could.
+	Unify(a, b types.Type) bool
+	// Errorf reports an error at node.
+	Errorf(code diagnostics.Code, node ast.Node, format string, args ...interface{}) *diagnostics.Diagnostic
+	// RequireSequence requires arg, of type t, to be a string, slice or
+	// map. If t is not known yet, the choice waits until the end of the
+	// enclosing function.
+	RequireSequence(arg ast.Expression, t types.Type, builtin string)
+}
+
+// Generator is what the C lowerings of builtins need from the C code
+// generator.
+type Generator interface {
+	// Write emits C code.
+	Write(code string)
+	// Expression emits expr as a C expression.
+	Expression(expr ast.Expression)
+	// TypeOf returns the type the checker gave expr.
+	TypeOf(expr ast.Expression) types.Type
+	// CType returns the C type of values of type t.
+	CType(t types.Type) string
+	// CString quotes s as a C string literal.
+	CString(s string) string
+	// Position returns the position of node as C arguments, for runtime
+	// errors.
+	Position(node ast.Node) string
+	// WriteValue returns a C statement that writes value, of type t, to
+	// stdout the way print does, without the trailing newline.
+	WriteValue(value string, t types.Type) string
+	// Require emits the named part of the C runtime, such as "string",
+	// ahead of the program.
+	Require(part string)
+	// Helper returns the name of a C function that define returns the
+	// code of, given its name, which is derived from base. The function is
+	// emitted once, the first time key is used.
+	Helper(base, key string, define func(name string) string) string
+	// Unsupported reports that the C backend cannot lower node.
+	Unsupported(node ast.Node, format string, args ...interface{})
+}
+
+var registry = map[string]*Builtin{}
+
+// register adds b to the builtins.
+func register(b *Builtin) {
+	if _, ok := registry[b.Name]; ok {
+		panic("builtin " + b.Name + " registered twice")
+	}
+	registry[b.Name] = b
+}
+
+// Lookup returns the builtin called name.
+func Lookup(name string) (*Builtin, bool) {
+	b, ok := registry[name]
+	return b, ok
+}
+
+// Predeclared returns the builtins called by a plain name, which every
+// program can use unless a let shadows them, sorted by name.
+func Predeclared() []*Builtin {
+	var all []*Builtin
+	for name, b := range registry {
+		if !strings.Contains(name, ".") {
+			all = append(all, b)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// wantArgs reports a call of b with other than n arguments, and returns
+// false if it has.
+func wantArgs(c Checker, call *ast.CallExpression, b string, n int) bool {
+	if len(call.Arguments) == n {
+		return true
+	}
+	c.Errorf(diagnostics.WrongArgumentCount, call, "wrong number of arguments in call to %s: want %d, got %d", b, n, len(call.Arguments)).
+		WithNote("%s", registry[b].Signature)
+	return false
+}
+
+// wantAtLeast reports a call of b with fewer than n arguments, and returns
+// false if it has.
+func wantAtLeast(c Checker, call *ast.CallExpression, b string, n int) bool {
+	if len(call.Arguments) >= n {
+		return true
+	}
+	c.Errorf(diagnostics.WrongArgumentCount, call, "not enough arguments in call to %s: want at least %d, got %d", b, n, len(call.Arguments)).
+		WithNote("%s", registry[b].Signature)
+	return false
+}
+
+// wantType requires the i'th argument of a call of b, of type t, to have
+// type want.
+func wantType(c Checker, call *ast.CallExpression, b string, i int, t, want types.Type) bool {
+	if t == types.Invalid || c.Unify(t, want) {
+		return true
+	}
+	arg := call.Arguments[i]
+	c.Errorf(diagnostics.TypeMismatch, arg, "cannot use %s (type %s) as %s in argument to %s", arg.String(), t, want, b)
+	return false
+}
+
+// defaultInt makes t, if it is not known yet, an int.
+func defaultInt(c Checker, t types.Type) types.Type {
+	if _, ok := types.Prune(t).(*types.Var); ok {
+		c.Unify(t, types.Int)
+	}
+	return types.Prune(t)
+}
+
+func isNumeric(t types.Type) bool {
+	t = types.Prune(t)
+	return t == types.Int || t == types.Float
+}
+
+// newError returns a runtime error for the evaluator.
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// wrongArgs returns the evaluator's error for a call with the wrong number
+// of arguments.
+func wrongArgs(want, got int) *object.Error {
+	return newError("wrong number of arguments: want=%d, got=%d", want, got)
+}
+
+// genArgs emits the arguments of call, separated by commas.
+func genArgs(g Generator, args []ast.Expression) {
+	for i, arg := range args {
+		if i > 0 {
+			g.Write(", ")
+		}
+		g.Expression(arg)
+	}
+}
+
+// helperParams returns the parameters a0, a1, ... of a C helper function
+// taking values of types ts.
+func helperParams(g Generator, ts []types.Type) string {
+	if len(ts) == 0 {
+		return "void"
+	}
+	params := make([]string, len(ts))
+	for i, t := range ts {
+		ctype := g.CType(t)
+		if !strings.HasSuffix(ctype, "*") {
+			ctype += " "
+		}
+		params[i] = fmt.Sprintf("%sa%d", ctype, i)
+	}
+	return strings.Join(params, ", ")
+}
+
+// argTypes returns the types of args, and a key telling them apart for
+// Generator.Helper.
+func argTypes(g Generator, args []ast.Expression) ([]types.Type, string) {
+	ts := make([]types.Type, len(args))
+	names := make([]string, len(args))
+	for i, arg := range args {
+		ts[i] = g.TypeOf(arg)
+		names[i] = types.Resolve(ts[i]).String()
+	}
+	return ts, strings.Join(names, ", ")
+}
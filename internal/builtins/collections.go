@@ -0,0 +1,203 @@
+package builtins
+
+import (
+	"fmt"
+	"math/rand"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/types"
+)
+
+func init() {
+	register(&Builtin{
+		Name:      "len",
+		Signature: "func len(v string | []T | map[K]V) int",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if wantArgs(c, call, "len", 1) {
+				c.RequireSequence(call.Arguments[0], args[0], "len")
+			}
+			return types.Int
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgs(1, len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Slice:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Map:
+				return &object.Integer{Value: int64(len(arg.Pairs))}
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			if _, ok := types.Prune(g.TypeOf(call.Arguments[0])).(*types.Map); ok {
+				g.Write("golite_map_len(")
+				g.Expression(call.Arguments[0])
+				g.Write(")")
+				return
+			}
+			g.Write("(")
+			g.Expression(call.Arguments[0])
+			g.Write(").len")
+		},
+	})
+
+	register(&Builtin{
+		Name:      "append",
+		Signature: "func append(s []T, elems ...T) []T",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantAtLeast(c, call, "append", 1) || args[0] == types.Invalid {
+				return types.Invalid
+			}
+			elem := c.NewVar()
+			slice := &types.Slice{Elem: elem}
+			if !c.Unify(args[0], slice) {
+				c.Errorf(diagnostics.InvalidBuiltinCall, call.Arguments[0], "invalid argument: %s (type %s) is not a slice",
+					call.Arguments[0].String(), args[0])
+				return types.Invalid
+			}
+			for i := range call.Arguments[1:] {
+				wantType(c, call, "append", i+1, args[i+1], elem)
+			}
+			return slice
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments: want at least 1, got 0")
+			}
+			slice, ok := args[0].(*object.Slice)
+			if !ok {
+				return newError("first argument to `append` must be SLICE, got %s", args[0].Type())
+			}
+			return &object.Slice{Elements: appendElements(slice.Elements, args[1:])}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			if len(call.Arguments) == 1 {
+				g.Expression(call.Arguments[0])
+				return
+			}
+			g.Require("slice")
+			elem := "int64_t"
+			if s, ok := types.Prune(g.TypeOf(call.Arguments[0])).(*types.Slice); ok {
+				elem = g.CType(s.Elem)
+			}
+			g.Write("golite_append(")
+			g.Expression(call.Arguments[0])
+			g.Write(fmt.Sprintf(", sizeof(%s), %d, (%s[]){ ", elem, len(call.Arguments)-1, elem))
+			genArgs(g, call.Arguments[1:])
+			g.Write(" })")
+		},
+	})
+
+	register(&Builtin{
+		Name:      "delete",
+		Signature: "func delete(m map[K]V, key K)",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantArgs(c, call, "delete", 2) {
+				return types.Void
+			}
+			if m := requireMap(c, call.Arguments[0], args[0]); m != nil {
+				wantType(c, call, "delete", 1, args[1], m.Key)
+			}
+			return types.Void
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return wrongArgs(2, len(args))
+			}
+			m, ok := args[0].(*object.Map)
+			if !ok {
+				return newError("first argument to `delete` must be MAP, got %s", args[0].Type())
+			}
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("unusable as map key: %s", args[1].Type())
+			}
+			delete(m.Pairs, key.HashKey())
+			return nil
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			m, _ := types.Prune(g.TypeOf(call.Arguments[0])).(*types.Map)
+			g.Write("golite_map_delete(")
+			g.Expression(call.Arguments[0])
+			g.Write(", (" + g.CType(m.Key) + "[]){ ")
+			g.Expression(call.Arguments[1])
+			g.Write(" })")
+		},
+	})
+
+	register(&Builtin{
+		Name:      "keys",
+		Signature: "func keys(m map[K]V) []K",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantArgs(c, call, "keys", 1) {
+				return types.Invalid
+			}
+			m := requireMap(c, call.Arguments[0], args[0])
+			if m == nil {
+				return types.Invalid
+			}
+			return &types.Slice{Elem: m.Key}
+		},
+		// keys returns the keys of a map in random order, so that programs
+		// cannot come to depend on one, as with Go's map iteration.
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgs(1, len(args))
+			}
+			m, ok := args[0].(*object.Map)
+			if !ok {
+				return newError("argument to `keys` must be MAP, got %s", args[0].Type())
+			}
+			keys := make([]object.Object, 0, len(m.Pairs))
+			for _, pair := range m.Pairs {
+				keys = append(keys, pair.Key)
+			}
+			rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+			return &object.Slice{Elements: keys}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Write("golite_map_keys(")
+			g.Expression(call.Arguments[0])
+			g.Write(")")
+		},
+	})
+}
+
+// requireMap checks that the argument arg, of type t, is a map and returns
+// its type, or nil after reporting an error.
+func requireMap(c Checker, arg ast.Expression, t types.Type) *types.Map {
+	if t == types.Invalid {
+		return nil
+	}
+	m := &types.Map{Key: c.NewVar(), Value: c.NewVar()}
+	if !c.Unify(t, m) {
+		c.Errorf(diagnostics.InvalidBuiltinCall, arg, "invalid argument: %s (type %s) is not a map", arg.String(), t)
+		return nil
+	}
+	return m
+}
+
+// appendElements appends elems to s, reusing its array when it has room.
+// Otherwise the elements move to a new array with double the capacity, or
+// just enough if that is more. The C runtime grows slices the same way, so
+// both backends agree on when appending to a slice affects others sharing
+// its array.
+func appendElements(s, elems []object.Object) []object.Object {
+	if n := len(s) + len(elems); n > cap(s) {
+		newCap := 2 * cap(s)
+		if newCap < n {
+			newCap = n
+		}
+		grown := make([]object.Object, len(s), newCap)
+		copy(grown, s)
+		s = grown
+	}
+	return append(s, elems...)
+}
@@ -0,0 +1,173 @@
+package builtins
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/types"
+)
+
+func init() {
+	register(&Builtin{
+		Name:      "errors.New",
+		Signature: "func errors.New(text string) error",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if wantArgs(c, call, "errors.New", 1) {
+				wantType(c, call, "errors.New", 0, args[0], types.String)
+			}
+			return types.Error
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgs(1, len(args))
+			}
+			msg, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `errors.New` must be STRING, got %s", args[0].Type())
+			}
+			return object.NewErrorValue(msg.Value)
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Require("errorValue")
+			g.Write("golite_error_new(")
+			g.Expression(call.Arguments[0])
+			g.Write(")")
+		},
+	})
+
+	// panic(v) stops the function and unwinds the stack, running deferred
+	// calls, until one of them recovers. v is a message or an error.
+	register(&Builtin{
+		Name:      "panic",
+		Signature: "func panic(v string | error)",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantArgs(c, call, "panic", 1) {
+				return types.Void
+			}
+			if _, ok := types.Prune(args[0]).(*types.Var); ok {
+				c.Unify(args[0], types.String)
+			}
+			if t := types.Prune(args[0]); t != types.String && t != types.Error && t != types.Invalid {
+				c.Errorf(diagnostics.InvalidBuiltinCall, call.Arguments[0], "cannot use %s (type %s) as string or error in argument to panic",
+					call.Arguments[0].String(), args[0])
+			}
+			return types.Void
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgs(1, len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Error{Message: arg.Value, Value: object.NewErrorValue(arg.Value)}
+			case *object.ErrorValue:
+				if arg.Message != nil {
+					return &object.Error{Message: *arg.Message, Value: arg}
+				}
+			case *object.Nil:
+			default:
+				return newError("argument to `panic` must be STRING or ERROR_VALUE, got %s", args[0].Type())
+			}
+			return newError("panic called with nil argument")
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Write("golite_panic(")
+			if types.Prune(g.TypeOf(call.Arguments[0])) == types.String {
+				g.Require("errorValue")
+				g.Write("golite_error_new(")
+				g.Expression(call.Arguments[0])
+				g.Write(")")
+			} else {
+				g.Expression(call.Arguments[0])
+			}
+			g.Write(", " + g.Position(call) + ")")
+		},
+	})
+
+	// recover() stops a panic when called by a deferred function, and
+	// returns it as an error, or nil if there is none.
+	register(&Builtin{
+		Name:      "recover",
+		Signature: "func recover() error",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			wantArgs(c, call, "recover", 0)
+			return types.Error
+		},
+		// The evaluator runs the calls of recover made by deferred
+		// functions itself. Deferring recover does not stop a panic.
+		Eval: func(args ...object.Object) object.Object {
+			return &object.Nil{}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Write("golite_recover()")
+		},
+	})
+
+	// assert(cond, msg) is a runtime error unless cond holds. The message
+	// is optional.
+	register(&Builtin{
+		Name:      "assert",
+		Signature: "func assert(cond bool, msg ...string)",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if n := len(call.Arguments); n != 1 && n != 2 {
+				c.Errorf(diagnostics.WrongArgumentCount, call, "wrong number of arguments in call to assert: want 1 or 2, got %d", n).
+					WithNote("%s", registry["assert"].Signature)
+				return types.Void
+			}
+			wantType(c, call, "assert", 0, args[0], types.Bool)
+			if len(args) == 2 {
+				wantType(c, call, "assert", 1, args[1], types.String)
+			}
+			return types.Void
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments: want 1 or 2, got %d", len(args))
+			}
+			cond, ok := args[0].(*object.Boolean)
+			if !ok {
+				return newError("first argument to `assert` must be BOOLEAN, got %s", args[0].Type())
+			}
+			if cond.Value {
+				return nil
+			}
+			if len(args) == 1 {
+				return newError("assertion failed")
+			}
+			msg, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `assert` must be STRING, got %s", args[1].Type())
+			}
+			return newError("assertion failed: %s", msg.Value)
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Require("error")
+			if len(call.Arguments) == 1 {
+				name := g.Helper("golite_assert", "assert", func(name string) string {
+					return "static void " + name + `(bool cond, int line, int column) {
+    if (!cond) {
+        golite_runtime_error(line, column, "assertion failed");
+    }
+}
+`
+				})
+				g.Write(name + "(")
+				g.Expression(call.Arguments[0])
+				g.Write(", " + g.Position(call) + ")")
+				return
+			}
+			g.Require("string")
+			name := g.Helper("golite_assert_message", "assert message", func(name string) string {
+				return "static void " + name + `(bool cond, golite_string msg, int line, int column) {
+    if (!cond) {
+        golite_runtime_error(line, column, "assertion failed: %.*s", (int)msg.len, msg.data);
+    }
+}
+`
+			})
+			g.Write(name + "(")
+			genArgs(g, call.Arguments)
+			g.Write(", " + g.Position(call) + ")")
+		},
+	})
+}
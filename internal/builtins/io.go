@@ -0,0 +1,393 @@
+package builtins
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/types"
+)
+
+// stdin buffers the standard input read by input.
+var stdin = bufio.NewReader(os.Stdin)
+
+func init() {
+	// println(a, b, ...) prints its arguments the way print does, separated
+	// by spaces, and then a newline.
+	register(&Builtin{
+		Name:      "println",
+		Signature: "func println(args ...any)",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			return types.Void
+		},
+		Eval: func(args ...object.Object) object.Object {
+			values := make([]string, len(args))
+			for i, arg := range args {
+				values[i] = arg.Inspect()
+			}
+			fmt.Fprintln(os.Stdout, strings.Join(values, " "))
+			return nil
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			if len(call.Arguments) == 0 {
+				g.Write("putchar('\\n')")
+				return
+			}
+			ts, key := argTypes(g, call.Arguments)
+			name := g.Helper("golite_println", "println "+key, func(name string) string {
+				var body strings.Builder
+				for i, t := range ts {
+					if i > 0 {
+						body.WriteString("    putchar(' ');\n")
+					}
+					body.WriteString("    " + g.WriteValue(fmt.Sprintf("a%d", i), t) + "\n")
+				}
+				return "static void " + name + "(" + helperParams(g, ts) + ") {\n" + body.String() + "    putchar('\\n');\n}\n"
+			})
+			g.Write(name + "(")
+			genArgs(g, call.Arguments)
+			g.Write(")")
+		},
+	})
+
+	register(&Builtin{
+		Name:      "printf",
+		Signature: "func printf(format string, args ...any)",
+		Check:     checkPrintf,
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments: want at least 1, got 0")
+			}
+			format, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `printf` must be STRING, got %s", args[0].Type())
+			}
+			out, err := sprintf(format.Value, args[1:])
+			if err != nil {
+				return newError("%s", err)
+			}
+			fmt.Fprint(os.Stdout, out)
+			return nil
+		},
+		Lower: lowerPrintf,
+	})
+
+	// input() reads a line from the standard input and returns it without
+	// the newline, or "" at the end of the input.
+	register(&Builtin{
+		Name:      "input",
+		Signature: "func input() string",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			wantArgs(c, call, "input", 0)
+			return types.String
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return wrongArgs(0, len(args))
+			}
+			line, _ := stdin.ReadString('\n')
+			return &object.String{Value: strings.TrimSuffix(line, "\n")}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			g.Require("string")
+			g.Require("error")
+			name := g.Helper("golite_input", "input", func(name string) string {
+				return "static golite_string " + name + `(void) {
+    size_t cap = 64, len = 0;
+    char *data = golite_alloc(cap);
+    int ch;
+    fflush(stdout);
+    while ((ch = getchar()) != EOF && ch != '\n') {
+        if (len == cap) {
+            char *grown = golite_alloc(2 * cap);
+            memcpy(grown, data, len);
+            free(data);
+            data = grown;
+            cap *= 2;
+        }
+        data[len++] = (char)ch;
+    }
+    return (golite_string){ data, (int64_t)len };
+}
+`
+			})
+			g.Write(name + "()")
+		},
+	})
+}
+
+// A directive is a piece of a printf format: literal text, or a verb with
+// its flags, width and precision, as written.
+type directive struct {
+	text string // the directive as written, or the text with %% unescaped
+	verb byte   // 0 for literal text
+	spec string // flags, width and precision of a verb
+}
+
+// flags returns the flags of a verb, which come before its width.
+func (d directive) flags() string {
+	return d.spec[:len(d.spec)-len(strings.TrimLeft(d.spec, "-+ 0"))]
+}
+
+// width returns the width of a verb, or 0 if it has none.
+func (d directive) width() int {
+	digits := strings.TrimLeft(d.spec, "-+ 0")
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits = digits[:i]
+	}
+	width, _ := strconv.Atoi(digits)
+	return width
+}
+
+// parseFormat splits a printf format into directives. The verbs are %v,
+// which prints a value the way print does, %d, %f, %s, %t and %%. Flags
+// and a width are allowed with %d, %f and %s, and a precision with %f.
+func parseFormat(format string) ([]directive, error) {
+	var ds []directive
+	var text strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			text.WriteByte(format[i])
+			continue
+		}
+		start := i
+		for i++; i < len(format) && strings.IndexByte("-+ 0", format[i]) >= 0; i++ {
+		}
+		for ; i < len(format) && '0' <= format[i] && format[i] <= '9'; i++ {
+		}
+		precision := false
+		if i < len(format) && format[i] == '.' {
+			precision = true
+			for i++; i < len(format) && '0' <= format[i] && format[i] <= '9'; i++ {
+			}
+		}
+		if i == len(format) {
+			return nil, fmt.Errorf("printf format %s is missing a verb at the end", format[start:])
+		}
+		d := directive{text: format[start : i+1], verb: format[i], spec: format[start+1 : i]}
+		switch d.verb {
+		case '%':
+			if d.spec != "" {
+				return nil, fmt.Errorf("printf format %s has unknown verb %%", d.text)
+			}
+			text.WriteByte('%')
+			continue
+		case 'd', 's', 'f':
+			if precision && d.verb != 'f' {
+				return nil, fmt.Errorf("printf format %s: precision is only allowed with %%f", d.text)
+			}
+		case 'v', 't':
+			if d.spec != "" {
+				return nil, fmt.Errorf("printf format %s: flags and width are not allowed with %%%c", d.text, d.verb)
+			}
+		default:
+			return nil, fmt.Errorf("printf format %s has unknown verb %c", d.text, d.verb)
+		}
+		if text.Len() > 0 {
+			ds = append(ds, directive{text: text.String()})
+			text.Reset()
+		}
+		ds = append(ds, d)
+	}
+	if text.Len() > 0 {
+		ds = append(ds, directive{text: text.String()})
+	}
+	return ds, nil
+}
+
+// verbs returns the directives of ds that consume an argument.
+func verbs(ds []directive) []directive {
+	var vs []directive
+	for _, d := range ds {
+		if d.verb != 0 {
+			vs = append(vs, d)
+		}
+	}
+	return vs
+}
+
+// verbType returns the type of argument that verb prints, or nil if it
+// prints any value.
+func verbType(verb byte) types.Type {
+	switch verb {
+	case 'd':
+		return types.Int
+	case 'f':
+		return types.Float
+	case 's':
+		return types.String
+	case 't':
+		return types.Bool
+	}
+	return nil
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// checkPrintf types a call of printf. A literal format is checked against
+// the arguments, as go vet does; %s also prints errors.
+func checkPrintf(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+	if !wantAtLeast(c, call, "printf", 1) || !wantType(c, call, "printf", 0, args[0], types.String) {
+		return types.Void
+	}
+	lit, ok := call.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		return types.Void
+	}
+	ds, err := parseFormat(lit.Value)
+	if err != nil {
+		c.Errorf(diagnostics.InvalidBuiltinCall, lit, "%s", err)
+		return types.Void
+	}
+	vs := verbs(ds)
+	for i, d := range vs {
+		if i+1 >= len(args) {
+			c.Errorf(diagnostics.WrongArgumentCount, call, "printf format %s reads arg #%d, but call has %s", d.text, i+1, plural(len(args)-1, "arg"))
+			return types.Void
+		}
+		want := verbType(d.verb)
+		if want == nil || args[i+1] == types.Invalid {
+			continue
+		}
+		if _, ok := types.Prune(args[i+1]).(*types.Var); ok {
+			c.Unify(args[i+1], want)
+		}
+		t := types.Prune(args[i+1])
+		if t != want && !(d.verb == 's' && t == types.Error) {
+			c.Errorf(diagnostics.TypeMismatch, call.Arguments[i+1], "printf format %s has arg %s of wrong type %s", d.text, call.Arguments[i+1].String(), t)
+		}
+	}
+	if len(args)-1 > len(vs) {
+		c.Errorf(diagnostics.WrongArgumentCount, call, "printf call needs %s but has %s", plural(len(vs), "arg"), plural(len(args)-1, "arg"))
+	}
+	return types.Void
+}
+
+// sprintf formats args according to format, for the evaluator.
+func sprintf(format string, args []object.Object) (string, error) {
+	ds, err := parseFormat(format)
+	if err != nil {
+		return "", err
+	}
+	if vs := verbs(ds); len(vs) != len(args) {
+		return "", fmt.Errorf("printf call needs %s but has %s", plural(len(vs), "arg"), plural(len(args), "arg"))
+	}
+	var out strings.Builder
+	i := 0
+	for _, d := range ds {
+		if d.verb == 0 {
+			out.WriteString(d.text)
+			continue
+		}
+		arg := args[i]
+		i++
+		if d.verb == 'v' {
+			out.WriteString(arg.Inspect())
+			continue
+		}
+		var value interface{}
+		switch arg := arg.(type) {
+		case *object.Integer:
+			value = arg.Value
+		case *object.Float:
+			value = arg.Value
+		case *object.String:
+			value = arg.Value
+		case *object.Boolean:
+			value = arg.Value
+		case *object.ErrorValue, *object.Nil:
+			value = arg.Inspect()
+		}
+		if !verbAccepts(d.verb, arg) {
+			return "", fmt.Errorf("printf format %s has arg of wrong type %s", d.text, arg.Type())
+		}
+		fmt.Fprintf(&out, d.text, value)
+	}
+	return out.String(), nil
+}
+
+// verbAccepts reports whether verb can print arg.
+func verbAccepts(verb byte, arg object.Object) bool {
+	switch arg.(type) {
+	case *object.Integer:
+		return verb == 'd'
+	case *object.Float:
+		return verb == 'f'
+	case *object.String, *object.ErrorValue, *object.Nil:
+		return verb == 's'
+	case *object.Boolean:
+		return verb == 't'
+	}
+	return false
+}
+
+// lowerPrintf emits a call of printf as a call of a C function made for
+// its format and argument types. C's printf prints the integers and the
+// finite floats; strings, whose width counts runes, and infinities and NaN
+// go through the printf part of the runtime.
+func lowerPrintf(g Generator, call *ast.CallExpression) {
+	lit, ok := call.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		g.Unsupported(call.Arguments[0], "the C backend needs the format of printf to be a string literal, not %s", call.Arguments[0].String())
+		g.Write("(void)0")
+		return
+	}
+	ds, _ := parseFormat(lit.Value)
+	for _, d := range ds {
+		if d.verb == 's' || d.verb == 'f' {
+			g.Require("printf")
+			break
+		}
+	}
+	ts, key := argTypes(g, call.Arguments[1:])
+	name := g.Helper("golite_printf", "printf "+strconv.Quote(lit.Value)+" "+key, func(name string) string {
+		var body strings.Builder
+		i := 0
+		for _, d := range ds {
+			if d.verb == 0 {
+				fmt.Fprintf(&body, "    fwrite(%s, 1, %d, stdout);\n", g.CString(d.text), len(d.text))
+				continue
+			}
+			a := fmt.Sprintf("a%d", i)
+			t := ts[i]
+			i++
+			flags, width := d.flags(), d.width()
+			left := strings.Contains(flags, "-")
+			switch d.verb {
+			case 'd':
+				fmt.Fprintf(&body, "    printf(\"%%%slld\", (long long)%s);\n", d.spec, a)
+			case 'f':
+				// Go never pads infinities and NaN with zeros.
+				plus, space := strings.Contains(flags, "+"), strings.Contains(flags, " ")
+				fmt.Fprintf(&body, "    if (isfinite(%s)) {\n        printf(\"%%%sf\", %s);\n    } else {\n", a, d.spec, a)
+				fmt.Fprintf(&body, "        const char *s = golite_nonfinite(%s, %t, %t);\n", a, plus, space)
+				fmt.Fprintf(&body, "        golite_write_padded(s, strlen(s), %d, %t, false);\n    }\n", width, left)
+			case 's':
+				pad := fmt.Sprintf("%d, %t, %t", width, left, strings.Contains(flags, "0"))
+				if types.Prune(t) == types.Error {
+					fmt.Fprintf(&body, "    if (%s != NULL) {\n        golite_write_padded(%s->message.data, %s->message.len, %s);\n    } else {\n        golite_write_padded(\"<nil>\", 5, %s);\n    }\n", a, a, a, pad, pad)
+				} else {
+					fmt.Fprintf(&body, "    golite_write_padded(%s.data, %s.len, %s);\n", a, a, pad)
+				}
+			case 't':
+				fmt.Fprintf(&body, "    fputs(%s ? \"true\" : \"false\", stdout);\n", a)
+			case 'v':
+				body.WriteString("    " + g.WriteValue(a, t) + "\n")
+			}
+		}
+		return "static void " + name + "(" + helperParams(g, ts) + ") {\n" + body.String() + "}\n"
+	})
+	g.Write(name + "(")
+	genArgs(g, call.Arguments[1:])
+	g.Write(")")
+}
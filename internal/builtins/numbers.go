@@ -0,0 +1,222 @@
+package builtins
+
+import (
+	"math"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/types"
+)
+
+func init() {
+	// int and float convert between the numeric types.
+	for _, name := range []string{"int", "float"} {
+		name := name
+		result, _ := types.Lookup(name)
+		register(&Builtin{
+			Name:      name,
+			Signature: "func " + name + "(x int | float) " + name,
+			Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+				if len(call.Arguments) != 1 {
+					c.Errorf(diagnostics.WrongArgumentCount, call, "wrong number of arguments in conversion to %s: want 1, got %d", name, len(call.Arguments))
+					return result
+				}
+				if t := defaultInt(c, args[0]); !isNumeric(t) && t != types.Invalid {
+					c.Errorf(diagnostics.InvalidBuiltinCall, call.Arguments[0], "cannot convert %s (type %s) to %s",
+						call.Arguments[0].String(), args[0], name)
+				}
+				return result
+			},
+			Eval: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return wrongArgs(1, len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.Integer:
+					if name == "float" {
+						return &object.Float{Value: float64(arg.Value)}
+					}
+					return arg
+				case *object.Float:
+					if name == "int" {
+						return &object.Integer{Value: int64(arg.Value)}
+					}
+					return arg
+				default:
+					return newError("cannot convert %s to %s", args[0].Type(), name)
+				}
+			},
+			Lower: func(g Generator, call *ast.CallExpression) {
+				g.Write("((" + g.CType(result) + ")(")
+				g.Expression(call.Arguments[0])
+				g.Write("))")
+			},
+		})
+	}
+
+	register(&Builtin{
+		Name:      "abs",
+		Signature: "func abs(x T) T, for T int or float",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantArgs(c, call, "abs", 1) {
+				return types.Invalid
+			}
+			t := defaultInt(c, args[0])
+			if !isNumeric(t) {
+				if t != types.Invalid {
+					c.Errorf(diagnostics.InvalidBuiltinCall, call.Arguments[0], "invalid argument: %s (type %s) for abs", call.Arguments[0].String(), t)
+				}
+				return types.Invalid
+			}
+			return t
+		},
+		// The absolute value of the most negative int wraps around to
+		// itself, as negating it does.
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgs(1, len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.Value < 0 {
+					return &object.Integer{Value: -arg.Value}
+				}
+				return arg
+			case *object.Float:
+				return &object.Float{Value: math.Abs(arg.Value)}
+			default:
+				return newError("argument to `abs` must be INTEGER or FLOAT, got %s", args[0].Type())
+			}
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			var name string
+			if types.Prune(g.TypeOf(call.Arguments[0])) == types.Float {
+				name = g.Helper("golite_abs_float", "abs float", func(name string) string {
+					// Adding zero turns -0.0 into 0.0.
+					return "static double " + name + "(double x) {\n    return x < 0 ? -x : x + 0.0;\n}\n"
+				})
+			} else {
+				name = g.Helper("golite_abs_int", "abs int", func(name string) string {
+					return "static int64_t " + name + "(int64_t x) {\n    return x < 0 ? (int64_t)(0 - (uint64_t)x) : x;\n}\n"
+				})
+			}
+			g.Write(name + "(")
+			g.Expression(call.Arguments[0])
+			g.Write(")")
+		},
+	})
+
+	registerOrdered("min", -1)
+	registerOrdered("max", 1)
+}
+
+// registerOrdered registers min or max, which return the least or greatest
+// of their arguments: the first argument x for which comparing x with every
+// other argument gives sign, or 0.
+func registerOrdered(name string, sign int) {
+	op := "<"
+	if sign > 0 {
+		op = ">"
+	}
+	register(&Builtin{
+		Name:      name,
+		Signature: "func " + name + "(x T, y ...T) T, for T int, float or string",
+		Check: func(c Checker, call *ast.CallExpression, args []types.Type) types.Type {
+			if !wantAtLeast(c, call, name, 1) {
+				return types.Invalid
+			}
+			t := args[0]
+			for i := range call.Arguments[1:] {
+				if !wantType(c, call, name, i+1, args[i+1], t) {
+					return types.Invalid
+				}
+			}
+			t = defaultInt(c, t)
+			if !isNumeric(t) && t != types.String {
+				if t != types.Invalid {
+					c.Errorf(diagnostics.InvalidBuiltinCall, call.Arguments[0], "invalid argument: %s (type %s) cannot be ordered", call.Arguments[0].String(), t)
+				}
+				return types.Invalid
+			}
+			return t
+		},
+		Eval: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments: want at least 1, got 0")
+			}
+			result := args[0]
+			for _, arg := range args[1:] {
+				cmp, ok := compare(arg, result)
+				if !ok {
+					return newError("arguments to `%s` must be INTEGER, FLOAT or STRING of one type, got %s and %s", name, result.Type(), arg.Type())
+				}
+				if cmp == sign {
+					result = arg
+				}
+			}
+			return result
+		},
+		Lower: func(g Generator, call *ast.CallExpression) {
+			t := types.Prune(g.TypeOf(call))
+			ctype := g.CType(t)
+			if t == types.String {
+				g.Require("string")
+			}
+			helper := g.Helper("golite_"+name+"_"+t.String(), name+" "+t.String(), func(helper string) string {
+				if t != types.String {
+					return "static " + ctype + " " + helper + "(" + ctype + " a, " + ctype + " b) {\n    return b " + op + " a ? b : a;\n}\n"
+				}
+				return "static golite_string " + helper + `(golite_string a, golite_string b) {
+    int64_t n = a.len < b.len ? a.len : b.len;
+    int c = n > 0 ? memcmp(b.data, a.data, n) : 0;
+    if (c == 0) {
+        c = (b.len > a.len) - (b.len < a.len);
+    }
+    return c ` + op + ` 0 ? b : a;
+}
+`
+			})
+			// min(a, b, c) is min(min(a, b), c).
+			for range call.Arguments[1:] {
+				g.Write(helper + "(")
+			}
+			g.Expression(call.Arguments[0])
+			for _, arg := range call.Arguments[1:] {
+				g.Write(", ")
+				g.Expression(arg)
+				g.Write(")")
+			}
+		},
+	})
+}
+
+// compare returns -1, 0 or 1 as x is less than, equal to or greater than y,
+// and false if they are not both ints, floats or strings.
+func compare(x, y object.Object) (int, bool) {
+	switch x := x.(type) {
+	case *object.Integer:
+		if y, ok := y.(*object.Integer); ok {
+			return order(x.Value < y.Value, x.Value > y.Value), true
+		}
+	case *object.Float:
+		if y, ok := y.(*object.Float); ok {
+			return order(x.Value < y.Value, x.Value > y.Value), true
+		}
+	case *object.String:
+		if y, ok := y.(*object.String); ok {
+			return order(x.Value < y.Value, x.Value > y.Value), true
+		}
+	}
+	return 0, false
+}
+
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
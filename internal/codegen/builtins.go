@@ -0,0 +1,90 @@
+package codegen
+
+import (
+	"fmt"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/types"
+)
+
+// genBuiltinCall emits a call to a builtin, which lowers itself (see
+// package builtins).
+func (c *CGen) genBuiltinCall(ce *ast.CallExpression, b *types.Builtin) {
+	builtin, ok := builtins.Lookup(b.Name)
+	if !ok {
+		c.errorf(ce, "the C backend does not support the builtin %s", b.Name)
+		c.out.WriteString("0")
+		return
+	}
+	builtin.Lower(builtinGen{c}, ce)
+}
+
+// builtinParts are the parts of the C runtime that builtins may require,
+// by name.
+var builtinParts = map[string]*runtimePart{
+	"error":      errorRuntime,
+	"string":     stringRuntime,
+	"float":      floatRuntime,
+	"printf":     printfRuntime,
+	"slice":      sliceRuntime,
+	"map":        mapRuntime,
+	"errorValue": errorValueRuntime,
+}
+
+// builtinGen gives the lowerings of builtins access to the generator.
+type builtinGen struct {
+	c *CGen
+}
+
+func (g builtinGen) Write(code string) {
+	g.c.out.WriteString(code)
+}
+
+func (g builtinGen) Expression(expr ast.Expression) {
+	g.c.genExpression(expr)
+}
+
+func (g builtinGen) TypeOf(expr ast.Expression) types.Type {
+	return g.c.typeOf(expr)
+}
+
+func (g builtinGen) CType(t types.Type) string {
+	return g.c.cType(t)
+}
+
+func (g builtinGen) CString(s string) string {
+	return cStringLiteral(s)
+}
+
+func (g builtinGen) Position(node ast.Node) string {
+	return position(node)
+}
+
+func (g builtinGen) WriteValue(value string, t types.Type) string {
+	return g.c.writeStatement(value, t)
+}
+
+func (g builtinGen) Require(part string) {
+	p, ok := builtinParts[part]
+	if !ok {
+		panic("unknown runtime part " + part)
+	}
+	g.c.require(p)
+}
+
+func (g builtinGen) Helper(base, key string, define func(name string) string) string {
+	if name, ok := g.c.lowered[key]; ok {
+		return name
+	}
+	name := g.c.uniqueName(base)
+	g.c.lowered[key] = name
+	// Defining the helper may define others it uses, which must come first.
+	code := define(name)
+	g.c.helpers.WriteString(code)
+	return name
+}
+
+func (g builtinGen) Unsupported(node ast.Node, format string, args ...interface{}) {
+	g.c.errorf(node, "%s", fmt.Sprintf(format, args...))
+}
@@ -2,15 +2,44 @@ package codegen
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
 	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/semantics"
+	"golite.dev/mvp/internal/types"
 )
 
 // CGen is the C code generator.
 type CGen struct {
-	builder strings.Builder
+	out      *strings.Builder // body of the function currently being generated
+	typedefs strings.Builder  // struct types
+	decls    strings.Builder  // file-scope variables and environment structs
+	protos   strings.Builder  // function prototypes
+	funcs    strings.Builder  // function definitions
+	errors   []*diagnostics.Diagnostic
+
+	helpers strings.Builder   // functions specialized to a type, such as slice printers
+	writers map[string]string // names of the slice printers by slice type
+	lowered map[string]string // names of the helpers of builtins by key
+
+	checker *semantics.Checker // source of the static type of every expression
+	runtime []*runtimePart     // support code the program needs
+
+	fileScope *scope // names visible from every C function
+	scope     *scope // innermost scope at the current point of generation
+	fn        *funcInfo
+
+	used     map[string]bool // file-scope C identifiers already in use
+	hoisted  map[string]bool // top-level variables that live at file scope
+	topFuncs map[*ast.FunctionLiteral]*funcInfo
+	methods  map[string]*funcInfo // C functions for methods, by Type.Method
+	structs  map[*types.Struct]string
+	tuples   map[string]string // C structs for multiple results, by fields
+	onHeap   map[string]bool   // variables whose address is taken
+	tracing  bool              // functions record their calls (see usesPanics)
 }
 
 // New creates a new C code generator.
@@ -18,85 +47,664 @@ func New() *CGen {
 	return &CGen{}
 }
 
+// Errors returns the diagnostics for constructs the C backend cannot lower.
+func (c *CGen) Errors() []*diagnostics.Diagnostic {
+	return c.errors
+}
+
 // Generate takes an AST program and returns a string of equivalent C code.
+// The program is type-checked first, since C variables and functions need
+// the types the checker infers; if checking fails, Generate returns "" and
+// Errors reports why.
 func (c *CGen) Generate(program *ast.Program) string {
-	c.builder.WriteString("#include <stdio.h>\n")
-	c.builder.WriteString("#include <stdint.h>\n")
-	c.builder.WriteString("#include <stdbool.h>\n\n")
-	c.builder.WriteString("int main() {\n")
+	c.checker = semantics.New()
+	c.checker.Check(program)
+	if len(c.checker.Errors()) > 0 {
+		c.errors = append(c.errors, c.checker.Errors()...)
+		return ""
+	}
 
+	c.used = map[string]bool{"main": true}
+	c.writers = map[string]string{}
+	c.lowered = map[string]string{}
+	c.hoisted = map[string]bool{}
+	c.topFuncs = map[*ast.FunctionLiteral]*funcInfo{}
+	c.methods = map[string]*funcInfo{}
+	c.structs = map[*types.Struct]string{}
+	c.tuples = map[string]string{}
+	c.onHeap = c.addressTaken(program)
+	c.fileScope = newScope(nil)
+	c.scope = newScope(c.fileScope)
+	c.tracing = c.usesPanics(program)
+	c.declareTopLevel(program)
+
+	var main strings.Builder
+	c.out = &main
+	defers := c.tracing && hasDefer(program.Statements)
+	if c.tracing {
+		c.require(panicRuntime)
+		main.WriteString(c.tracedPrologue("main", "", defers))
+	}
 	for _, stmt := range program.Statements {
 		c.genStatement(stmt, 1)
 	}
+	if c.tracing {
+		main.WriteString(tracedEpilogue("main", "", defers))
+	}
 
-	c.builder.WriteString("    return 0;\n")
-	c.builder.WriteString("}\n")
-	return c.builder.String()
+	var out strings.Builder
+	out.WriteString("#include <stdio.h>\n")
+	out.WriteString("#include <stdint.h>\n")
+	out.WriteString("#include <stdbool.h>\n")
+	included := map[string]bool{}
+	for _, part := range c.runtime {
+		for _, header := range part.includes {
+			if !included[header] {
+				included[header] = true
+				out.WriteString("#include <" + header + ">\n")
+			}
+		}
+	}
+	out.WriteString("\n")
+	for _, part := range c.runtime {
+		out.WriteString(part.code)
+		out.WriteString("\n")
+	}
+	if c.typedefs.Len() > 0 {
+		out.WriteString(c.typedefs.String())
+		out.WriteString("\n")
+	}
+	out.WriteString(c.helpers.String())
+	if c.decls.Len() > 0 {
+		out.WriteString(c.decls.String())
+		out.WriteString("\n")
+	}
+	if c.protos.Len() > 0 {
+		out.WriteString(c.protos.String())
+		out.WriteString("\n")
+	}
+	out.WriteString(c.funcs.String())
+	out.WriteString("int main() {\n")
+	out.WriteString(main.String())
+	out.WriteString("    return 0;\n")
+	out.WriteString("}\n")
+	return out.String()
+}
+
+func (c *CGen) errorf(node ast.Node, format string, args ...interface{}) {
+	c.errors = append(c.errors, diagnostics.Errorf(diagnostics.Unsupported, diagnostics.SpanOf(node), format, args...))
+}
+
+// cType returns the C type used for values of type t. Type variables the
+// checker left unbound belong to values whose type never mattered, so any
+// type will do for them.
+func (c *CGen) cType(t types.Type) string {
+	switch t := types.Prune(t).(type) {
+	case *types.Struct:
+		return c.structType(t)
+	case *types.Pointer:
+		return c.cType(t.Elem) + " *"
+	case *types.Tuple:
+		return c.tupleType(t)
+	case *types.Slice:
+		c.require(sliceRuntime)
+		return "golite_slice"
+	case *types.Map:
+		c.require(mapRuntime)
+		return "golite_map *"
+	}
+	switch types.Prune(t) {
+	case types.Bool:
+		return "bool"
+	case types.Float:
+		return "double"
+	case types.String:
+		c.require(stringRuntime)
+		return "golite_string"
+	case types.Error:
+		c.require(errorValueRuntime)
+		return "golite_error *"
+	case types.Void:
+		return "void"
+	default:
+		return "int64_t"
+	}
+}
+
+func (c *CGen) typeOf(expr ast.Expression) types.Type {
+	if t := c.checker.TypeOf(expr); t != nil {
+		return t
+	}
+	return types.Int
 }
 
 func (c *CGen) writeIndent(level int) {
-	c.builder.WriteString(strings.Repeat("    ", level))
+	c.out.WriteString(strings.Repeat("    ", level))
+}
+
+func (c *CGen) pushScope() {
+	c.scope = newScope(c.scope)
+}
+
+func (c *CGen) popScope() {
+	c.scope = c.scope.outer
 }
 
 func (c *CGen) genStatement(stmt ast.Statement, level int) {
 	switch s := stmt.(type) {
 	case *ast.LetStatement:
-		c.writeIndent(level)
-		// Assuming all variables are 64-bit integers for now.
-		c.builder.WriteString("int64_t ")
-		c.builder.WriteString(s.Name.Value)
-		c.builder.WriteString(" = ")
-		c.genExpression(s.Value)
-		c.builder.WriteString(";\n")
+		c.genLet(s, level)
+	case *ast.MethodStatement:
+		if fi := c.topFuncs[s.Function]; fi != nil {
+			c.genFunction(fi, newScope(c.fileScope))
+		}
 	case *ast.PrintStatement:
 		c.writeIndent(level)
-		c.builder.WriteString("printf(\"%lld\\n\", (long long)(")
+		if writer, ok := c.writer(c.typeOf(s.Expression)); ok {
+			c.out.WriteString(writer + "(")
+			c.genExpression(s.Expression)
+			c.out.WriteString(");\n")
+			c.writeIndent(level)
+			c.out.WriteString("putchar('\\n');\n")
+			return
+		}
+		switch c.typeOf(s.Expression) {
+		case types.String:
+			c.out.WriteString("golite_print_string(")
+			c.genExpression(s.Expression)
+			c.out.WriteString(");\n")
+			return
+		case types.Float:
+			c.require(floatRuntime)
+			c.out.WriteString("golite_print_float(")
+			c.genExpression(s.Expression)
+			c.out.WriteString(");\n")
+			return
+		case types.Bool:
+			c.out.WriteString("printf(\"%s\\n\", (")
+			c.genExpression(s.Expression)
+			c.out.WriteString(") ? \"true\" : \"false\");\n")
+			return
+		case types.Error:
+			c.out.WriteString("golite_print_error(")
+			c.genExpression(s.Expression)
+			c.out.WriteString(");\n")
+			return
+		}
+		c.out.WriteString("printf(\"%lld\\n\", (long long)(")
 		c.genExpression(s.Expression)
-		c.builder.WriteString("));\n")
+		c.out.WriteString("));\n")
+	case *ast.ReturnStatement:
+		c.genReturn(s.ReturnValue, level)
+	case *ast.DeferStatement:
+		c.genDefer(s, level)
+	case *ast.AssignStatement, *ast.IncDecStatement:
+		if isMultiValue(s) {
+			c.genAssignValues(s.(*ast.AssignStatement), level)
+			return
+		}
+		c.writeIndent(level)
+		c.genSimpleStatement(s)
+		c.out.WriteString(";\n")
+	case *ast.ForStatement:
+		c.genFor(s, level)
+	case *ast.BreakStatement:
+		c.writeIndent(level)
+		c.out.WriteString("break;\n")
+	case *ast.ContinueStatement:
+		c.writeIndent(level)
+		c.out.WriteString("continue;\n")
 	case *ast.ExpressionStatement:
 		c.writeIndent(level)
+		if ie, ok := s.Expression.(*ast.IfExpression); ok {
+			// Emit if statements at the right nesting depth instead of
+			// through genExpression, which has no notion of indentation.
+			c.genIf(ie, level)
+			c.out.WriteString("\n")
+			return
+		}
 		c.genExpression(s.Expression)
-		c.builder.WriteString(";\n")
+		c.out.WriteString(";\n")
+	}
+}
+
+func (c *CGen) genLet(s *ast.LetStatement, level int) {
+	name := s.Name.Value
+
+	if isMultiValue(s) {
+		c.genLetValues(s, level)
+		return
+	}
+	if name == "_" {
+		c.writeIndent(level)
+		c.genDiscard(s.Value)
+		c.out.WriteString(";\n")
+		return
+	}
+
+	if lit, ok := s.Value.(*ast.FunctionLiteral); ok {
+		if fi := c.topFuncs[lit]; fi != nil {
+			c.genTopLevelFunction(name, fi)
+		} else {
+			c.genNestedFunction(name, lit, level)
+		}
+		return
+	}
+
+	// Top-level variables used by functions live at file scope, so main
+	// only assigns their initial value.
+	if c.isHoisted(name) {
+		b := c.hoist(name, c.cType(c.typeOf(s.Value)))
+		c.writeIndent(level)
+		c.out.WriteString(b.cexpr + " = ")
+		c.genExpression(s.Value)
+		c.out.WriteString(";\n")
+		return
+	}
+
+	// Redeclaring a name in the same scope rebinds it, as in the evaluator.
+	if b, ok := c.scope.vars[name]; ok && b.fn == nil {
+		c.writeIndent(level)
+		c.out.WriteString(b.cexpr + " = ")
+		c.genExpression(s.Value)
+		c.out.WriteString(";\n")
+		return
+	}
+
+	c.writeIndent(level)
+	c.genSimpleStatement(s)
+	c.out.WriteString(";\n")
+}
+
+func (c *CGen) isHoisted(name string) bool {
+	return c.fn == nil && c.scope.outer == c.fileScope && c.hoisted[name]
+}
+
+// hoist returns the file-scope variable for the top-level variable name,
+// declaring it on first use.
+func (c *CGen) hoist(name, ctype string) *binding {
+	b := c.fileScope.resolve(name)
+	if b == nil || b.fn != nil {
+		cname := c.uniqueName(cIdent(name))
+		c.decls.WriteString(ctype + " " + cname + ";\n")
+		b = &binding{cexpr: cname, ptr: "&" + cname, ctype: ctype, fileScope: true}
+		c.fileScope.define(name, b)
+	}
+	return b
+}
+
+// declare returns the C variable for a let-declared name of type t whose
+// initial value is assigned separately, declaring it if necessary.
+func (c *CGen) declare(name string, t types.Type, level int) *binding {
+	ctype := c.cType(t)
+	if c.isHoisted(name) {
+		return c.hoist(name, ctype)
+	}
+	if b, ok := c.scope.vars[name]; ok && b.fn == nil {
+		return b
+	}
+	cname := cIdent(name)
+	c.writeIndent(level)
+	if c.livesOnHeap(name, t) {
+		c.require(structRuntime)
+		c.out.WriteString(ctype + " *" + cname + " = golite_new(sizeof(" + ctype + "), &(" + ctype + "){0});\n")
+		b := heapVar(cname, ctype)
+		c.scope.define(name, b)
+		return b
+	}
+	c.out.WriteString(ctype + " " + cname + ";\n")
+	b := &binding{cexpr: cname, ptr: "&" + cname, ctype: ctype}
+	c.scope.define(name, b)
+	return b
+}
+
+// genSimpleStatement emits a statement from a for clause, without the
+// indentation and trailing semicolon of a full statement.
+func (c *CGen) genSimpleStatement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if s.Name.Value == "_" {
+			c.genDiscard(s.Value)
+			return
+		}
+		cname := cIdent(s.Name.Value)
+		ctype := c.cType(c.typeOf(s.Value))
+		if c.livesOnHeap(s.Name.Value, c.typeOf(s.Value)) {
+			c.require(structRuntime)
+			c.out.WriteString(ctype + " *" + cname + " = golite_new(sizeof(" + ctype + "), (" + ctype + "[]){ ")
+			c.genExpression(s.Value)
+			c.out.WriteString(" })")
+			c.scope.define(s.Name.Value, heapVar(cname, ctype))
+			return
+		}
+		c.out.WriteString(ctype + " ")
+		c.out.WriteString(cname)
+		c.out.WriteString(" = ")
+		c.genExpression(s.Value)
+		c.scope.define(s.Name.Value, &binding{cexpr: cname, ptr: "&" + cname, ctype: ctype})
+	case *ast.AssignStatement:
+		if isBlank(s.Target) {
+			c.genDiscard(s.Value)
+			return
+		}
+		if ie, ok := s.Target.(*ast.IndexExpression); ok && c.isMap(ie.Left) {
+			c.genMapAssign(s, ie)
+			return
+		}
+		op := strings.TrimSuffix(s.Operator, "=")
+		if c.isIntArithmetic(op, c.typeOf(s.Target)) {
+			c.genExpression(s.Target)
+			c.out.WriteString(" = ")
+			c.genIntArithmetic(op, s, func() { c.genExpression(s.Target) }, func() { c.genExpression(s.Value) })
+			return
+		}
+		if s.Operator == "+=" && c.typeOf(s.Target) == types.String {
+			c.genExpression(s.Target)
+			c.out.WriteString(" = golite_string_concat(")
+			c.genExpression(s.Target)
+			c.out.WriteString(", ")
+			c.genExpression(s.Value)
+			c.out.WriteString(")")
+			return
+		}
+		c.genExpression(s.Target)
+		c.out.WriteString(" " + s.Operator + " ")
+		c.genExpression(s.Value)
+	case *ast.IncDecStatement:
+		if ie, ok := s.Target.(*ast.IndexExpression); ok && c.isMap(ie.Left) {
+			c.genMapAssign(s, ie)
+			return
+		}
+		if c.isIntArithmetic(s.Operator[:1], c.typeOf(s.Target)) {
+			c.genExpression(s.Target)
+			c.out.WriteString(" = ")
+			c.genIntArithmetic(s.Operator[:1], s, func() { c.genExpression(s.Target) }, func() { c.out.WriteString("1") })
+			return
+		}
+		c.genExpression(s.Target)
+		c.out.WriteString(s.Operator)
+	case *ast.ExpressionStatement:
+		c.genExpression(s.Expression)
+	}
+}
+
+func (c *CGen) genFor(fs *ast.ForStatement, level int) {
+	c.pushScope()
+	defer c.popScope()
+
+	// The clauses of a C for loop cannot declare or assign several values
+	// at once, so such a loop goes in a block of its own that declares the
+	// variables, or the temporary holding the values, beforehand.
+	init, postValues := fs.Init, ""
+	if isMultiValue(fs.Init) || isMultiValue(fs.Post) {
+		c.writeIndent(level)
+		c.out.WriteString("{\n")
+		defer func(level int) {
+			c.writeIndent(level)
+			c.out.WriteString("}\n")
+		}(level)
+		level++
+		if isMultiValue(init) {
+			c.genStatement(init, level)
+			init = nil
+		}
+		if as, ok := fs.Post.(*ast.AssignStatement); ok && isMultiValue(as) {
+			postValues = c.uniqueName("golite_values")
+			c.writeIndent(level)
+			c.out.WriteString(c.cType(c.typeOf(as.Value)) + " " + postValues + ";\n")
+		}
+	}
+
+	c.writeIndent(level)
+	switch {
+	case init == nil && fs.Post == nil && fs.Condition != nil:
+		c.out.WriteString("while (")
+		c.genExpression(fs.Condition)
+		c.out.WriteString(") {\n")
+	default:
+		c.out.WriteString("for (")
+		if init != nil {
+			c.genSimpleStatement(init)
+		}
+		c.out.WriteString("; ")
+		if fs.Condition != nil {
+			c.genExpression(fs.Condition)
+		}
+		c.out.WriteString("; ")
+		switch {
+		case postValues != "":
+			c.genAssignValuesExpr(fs.Post.(*ast.AssignStatement), postValues)
+		case fs.Post != nil:
+			c.genSimpleStatement(fs.Post)
+		}
+		c.out.WriteString(") {\n")
+	}
+	c.genBlock(fs.Body, level)
+	c.writeIndent(level)
+	c.out.WriteString("}\n")
+}
+
+func (c *CGen) genIf(ie *ast.IfExpression, level int) {
+	c.out.WriteString("if (")
+	c.genExpression(ie.Condition)
+	c.out.WriteString(") {\n")
+	c.genBlock(ie.Consequence, level)
+	c.writeIndent(level)
+	c.out.WriteString("}")
+	if ie.Alternative != nil {
+		c.out.WriteString(" else {\n")
+		c.genBlock(ie.Alternative, level)
+		c.writeIndent(level)
+		c.out.WriteString("}")
 	}
 }
 
 func (c *CGen) genExpression(expr ast.Expression) {
 	switch e := expr.(type) {
 	case *ast.IntegerLiteral:
-		c.builder.WriteString(strconv.FormatInt(e.Value, 10))
+		c.out.WriteString(cIntLiteral(e.Value))
+	case *ast.FloatLiteral:
+		c.out.WriteString(cFloatLiteral(e.Value))
+	case *ast.StringLiteral:
+		c.require(stringRuntime)
+		c.out.WriteString("((golite_string){ " + cStringLiteral(e.Value) + ", " + strconv.Itoa(len(e.Value)) + " })")
 	case *ast.Boolean:
-		c.builder.WriteString(strconv.FormatBool(e.Value))
+		c.out.WriteString(strconv.FormatBool(e.Value))
 	case *ast.Identifier:
-		c.builder.WriteString(e.Value)
+		b := c.scope.resolve(e.Value)
+		switch {
+		case b == nil && e.Value == "nil":
+			c.out.WriteString("NULL")
+		case b == nil:
+			c.out.WriteString(cIdent(e.Value))
+		case b.fn != nil:
+			c.errorf(e, "the C backend does not support function values; %s can only be called", e.Value)
+			c.out.WriteString("0")
+		default:
+			c.out.WriteString(b.cexpr)
+		}
+	case *ast.PrefixExpression:
+		if e.Operator == "&" {
+			c.genAddress(e.Right)
+			return
+		}
+		if lit, ok := e.Right.(*ast.IntegerLiteral); ok && e.Operator == "-" {
+			c.out.WriteString(cIntLiteral(-lit.Value))
+			return
+		}
+		if e.Operator == "-" && c.isIntArithmetic("-", c.typeOf(e)) {
+			c.genIntArithmetic("-", e, func() { c.out.WriteString("0") }, func() { c.genExpression(e.Right) })
+			return
+		}
+		c.out.WriteString("(")
+		c.out.WriteString(e.Operator)
+		c.genExpression(e.Right)
+		c.out.WriteString(")")
 	case *ast.InfixExpression:
-		c.builder.WriteString("(")
+		if c.typeOf(e.Left) == types.String {
+			c.genStringInfix(e)
+			return
+		}
+		if c.isIntArithmetic(e.Operator, c.typeOf(e)) {
+			c.genIntArithmetic(e.Operator, e, func() { c.genExpression(e.Left) }, func() { c.genExpression(e.Right) })
+			return
+		}
+		c.out.WriteString("(")
 		c.genExpression(e.Left)
-		c.builder.WriteString(" ")
-		// Note: C uses `&&` and `||` for logical ops, but we haven't added those yet.
-		// GoLite `==` maps to C `==`, etc.
-		c.builder.WriteString(e.Operator)
-		c.builder.WriteString(" ")
+		c.out.WriteString(" ")
+		// The remaining operators, the short-circuiting && and || included,
+		// have the same meaning in C as in GoLite.
+		c.out.WriteString(e.Operator)
+		c.out.WriteString(" ")
 		c.genExpression(e.Right)
-		c.builder.WriteString(")")
-	case *ast.IfExpression:
-		c.builder.WriteString("if (")
-		c.genExpression(e.Condition)
-		c.builder.WriteString(") {\n")
-		c.genBlock(e.Consequence, 1)
-		c.writeIndent(1)
-		c.builder.WriteString("}")
-		if e.Alternative != nil {
-			c.builder.WriteString(" else {\n")
-			c.genBlock(e.Alternative, 1)
-			c.writeIndent(1)
-			c.builder.WriteString("}")
+		c.out.WriteString(")")
+	case *ast.CallExpression:
+		c.genCall(e)
+	case *ast.ArrayLiteral:
+		c.genArrayLiteral(e)
+	case *ast.MapLiteral:
+		c.genMapLiteral(e)
+	case *ast.IndexExpression:
+		if m, ok := c.mapType(e.Left); ok {
+			c.genMapIndex(e, m)
+			return
 		}
+		c.genIndex(e)
+	case *ast.SliceExpression:
+		c.genSliceExpression(e)
+	case *ast.StructLiteral:
+		c.genStructLiteral(e)
+	case *ast.SelectorExpression:
+		c.genSelector(e)
+	case *ast.TupleExpression:
+		c.genTupleExpression(e)
+	case *ast.FunctionLiteral:
+		c.errorf(e, "the C backend does not support function values; bind the function with let or call it directly")
+		c.out.WriteString("0")
+	case *ast.IfExpression:
+		c.errorf(e, "the C backend does not support if expressions used as values")
+		c.out.WriteString("0")
 	default:
-		panic(fmt.Sprintf("unhandled expression type in C codegen: %T", e))
+		c.errorf(expr, "unhandled expression type in C codegen: %T", e)
+		c.out.WriteString("0")
+	}
+}
+
+func (c *CGen) genStringInfix(e *ast.InfixExpression) {
+	switch e.Operator {
+	case "+":
+		c.out.WriteString("golite_string_concat(")
+	case "==":
+		c.out.WriteString("golite_string_eq(")
+	case "!=":
+		c.out.WriteString("!golite_string_eq(")
+	}
+	c.genExpression(e.Left)
+	c.out.WriteString(", ")
+	c.genExpression(e.Right)
+	c.out.WriteString(")")
+}
+
+// isIntArithmetic reports whether op is an arithmetic operator on integers
+// of type t whose C operator does not do what the GoLite one does, which
+// genIntArithmetic emits instead.
+func (c *CGen) isIntArithmetic(op string, t types.Type) bool {
+	switch op {
+	case "+", "-", "*", "/", "%", "<<", ">>":
+		return types.Prune(t) == types.Int
+	}
+	return false
+}
+
+// genIntArithmetic emits an integer operation the way Go does it; left and
+// right emit the operands. Signed overflow is undefined in C, so +, - and *
+// are done on unsigned integers, which wrap around. Division and shifts go
+// through the runtime, which stops the program at node on a zero divisor
+// or a negative shift amount.
+func (c *CGen) genIntArithmetic(op string, node ast.Node, left, right func()) {
+	switch op {
+	case "+", "-", "*":
+		c.out.WriteString("((int64_t)((uint64_t)(")
+		left()
+		c.out.WriteString(") " + op + " (uint64_t)(")
+		right()
+		c.out.WriteString(")))")
+		return
+	case "/":
+		c.require(divisionRuntime)
+		c.out.WriteString("golite_div(")
+	case "%":
+		c.require(divisionRuntime)
+		c.out.WriteString("golite_mod(")
+	case "<<":
+		c.require(shiftRuntime)
+		c.out.WriteString("golite_shl(")
+	case ">>":
+		c.require(shiftRuntime)
+		c.out.WriteString("golite_shr(")
+	}
+	left()
+	c.out.WriteString(", ")
+	right()
+	c.out.WriteString(", " + position(node) + ")")
+}
+
+// cIntLiteral formats v as a C integer constant. Constants are written
+// with INT64_C, since a bare one is an int, which is too narrow for shifts
+// such as 1 << 40. Constant folding leaves negative literals in the tree,
+// which are parenthesized so that a prefix operator before them cannot
+// merge with their sign into -- or ++. The most negative integer has no
+// literal in C, since its magnitude does not fit, so it is written as
+// INT64_MIN.
+func cIntLiteral(v int64) string {
+	switch {
+	case v == math.MinInt64:
+		return "INT64_MIN"
+	case v < 0:
+		return "(INT64_C(" + strconv.FormatInt(v, 10) + "))"
+	}
+	return "INT64_C(" + strconv.FormatInt(v, 10) + ")"
+}
+
+// cFloatLiteral formats v as a C double constant, parenthesized if it is
+// negative as cIntLiteral does.
+func cFloatLiteral(v float64) string {
+	literal := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	if math.Signbit(v) {
+		return "(" + literal + ")"
+	}
+	return literal
+}
+
+// cStringLiteral quotes s as a C string literal. Bytes outside printable
+// ASCII are written as three-digit octal escapes, which unlike \x escapes
+// cannot run into a following digit.
+func cStringLiteral(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '"' || ch == '\\' || ch == '?':
+			// ? is escaped so that no trigraph can form.
+			out.WriteByte('\\')
+			out.WriteByte(ch)
+		case ch < 0x20 || ch >= 0x7f:
+			fmt.Fprintf(&out, "\\%03o", ch)
+		default:
+			out.WriteByte(ch)
+		}
 	}
+	out.WriteByte('"')
+	return out.String()
 }
 
 func (c *CGen) genBlock(block *ast.BlockStatement, level int) {
+	c.pushScope()
+	defer c.popScope()
 	for _, stmt := range block.Statements {
 		c.genStatement(stmt, level+1)
 	}
@@ -0,0 +1,508 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// funcInfo describes a GoLite function literal lowered to a C function.
+type funcInfo struct {
+	cname   string
+	name    string // name in stack traces
+	lit     *ast.FunctionLiteral
+	sig     *types.Signature
+	envType string // C struct type holding captured variables, "" if none
+}
+
+func (fi *funcInfo) hasEnv() bool { return fi.envType != "" }
+
+// capture is one variable or function environment copied into a closure's
+// environment struct.
+type capture struct {
+	field string
+	ctype string
+	init  string
+}
+
+// declareTopLevel registers the functions bound by top-level let statements,
+// and the methods, so that they can be called before their definition, and
+// moves top-level
+// variables that functions refer to out of main and into file scope.
+func (c *CGen) declareTopLevel(program *ast.Program) {
+	topVars := map[string]bool{}
+	var lits []*ast.FunctionLiteral
+	for _, stmt := range program.Statements {
+		if ms, ok := stmt.(*ast.MethodStatement); ok {
+			lits = append(lits, ms.Function)
+			c.declareMethod(ms)
+			continue
+		}
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		if lit, ok := let.Value.(*ast.FunctionLiteral); ok {
+			lits = append(lits, lit)
+			if c.fileScope.resolve(let.Name.Value) == nil {
+				fi := c.newFuncInfo(cIdent(let.Name.Value), lit)
+				fi.name = let.Name.Value
+				c.fileScope.define(let.Name.Value, &binding{fn: fi, fileScope: true})
+				c.topFuncs[lit] = fi
+			}
+			continue
+		}
+		for _, name := range let.Names() {
+			topVars[name.Value] = true
+		}
+	}
+
+	for _, lit := range lits {
+		for _, name := range freeVariables(lit) {
+			if topVars[name] {
+				c.hoisted[name] = true
+			}
+		}
+	}
+}
+
+func (c *CGen) newFuncInfo(base string, lit *ast.FunctionLiteral) *funcInfo {
+	sig, _ := c.typeOf(lit).(*types.Signature)
+	return &funcInfo{cname: c.uniqueName(base), lit: lit, sig: sig}
+}
+
+// returnsValue reports whether the C function for fi returns a value.
+func (fi *funcInfo) returnsValue() bool {
+	return fi.sig == nil || types.Prune(fi.sig.Result) != types.Void
+}
+
+// uniqueName returns a file-scope C identifier derived from base that has
+// not been handed out before.
+func (c *CGen) uniqueName(base string) string {
+	name := base
+	for i := 2; c.used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	c.used[name] = true
+	return name
+}
+
+// genTopLevelFunction emits the C function for a top-level let-bound
+// function literal.
+func (c *CGen) genTopLevelFunction(name string, fi *funcInfo) {
+	c.fileScope.define(name, &binding{fn: fi, fileScope: true})
+	c.genFunction(fi, newScope(c.fileScope))
+}
+
+// liftFunction lambda-lifts a nested function literal to a file-scope C
+// function. Variables it captures from enclosing functions are passed by
+// address through an environment struct, so assignments inside the closure
+// remain visible to its parent. It returns the lifted function together with
+// the captures needed to build its environment; self names the literal when
+// it is let-bound so that it can call itself.
+func (c *CGen) liftFunction(self string, lit *ast.FunctionLiteral) (*funcInfo, []capture) {
+	base := "lambda"
+	if self != "" {
+		base = self
+	}
+	if c.fn != nil {
+		base = c.fn.cname + "__" + base
+	}
+	fi := c.newFuncInfo(base, lit)
+	fi.name = "func literal"
+	if self != "" {
+		fi.name = self
+	}
+
+	captured := newScope(c.fileScope)
+	var captures []capture
+	for _, name := range freeVariables(lit) {
+		if name == self {
+			continue
+		}
+		b := c.scope.resolve(name)
+		if b == nil || b.fileScope {
+			continue
+		}
+		field := cIdent(name)
+		switch {
+		case b.fn != nil && b.fn.hasEnv():
+			captures = append(captures, capture{field: field, ctype: b.fn.envType + " *", init: b.envExpr})
+			captured.define(name, &binding{fn: b.fn, envExpr: "env->" + field})
+		case b.fn != nil:
+			captured.define(name, &binding{fn: b.fn})
+		default:
+			captures = append(captures, capture{field: field, ctype: b.ctype + " *", init: b.ptr})
+			captured.define(name, &binding{
+				cexpr: "(*env->" + field + ")",
+				ptr:   "env->" + field,
+				ctype: b.ctype,
+			})
+		}
+	}
+
+	if len(captures) > 0 {
+		fi.envType = "struct " + fi.cname + "_env"
+		c.decls.WriteString(fi.envType + " {\n")
+		for _, cp := range captures {
+			c.decls.WriteString("    " + cp.ctype + cp.field + ";\n")
+		}
+		c.decls.WriteString("};\n")
+	}
+
+	if self != "" {
+		if fi.hasEnv() {
+			captured.define(self, &binding{fn: fi, envExpr: "env"})
+		} else {
+			captured.define(self, &binding{fn: fi})
+		}
+	}
+
+	c.genFunction(fi, newScope(captured))
+	return fi, captures
+}
+
+// genNestedFunction lifts a let-bound function literal inside a function or
+// a block of main, declaring its environment in the current C scope.
+func (c *CGen) genNestedFunction(name string, lit *ast.FunctionLiteral, level int) {
+	fi, captures := c.liftFunction(name, lit)
+	b := &binding{fn: fi}
+	if fi.hasEnv() {
+		envVar := fi.cname + "_env"
+		c.writeIndent(level)
+		c.out.WriteString(fi.envType + " " + envVar + " = { " + captureInits(captures) + " };\n")
+		b.envExpr = "&" + envVar
+	}
+	c.scope.define(name, b)
+}
+
+func captureInits(captures []capture) string {
+	inits := make([]string, len(captures))
+	for i, cp := range captures {
+		inits[i] = cp.init
+	}
+	return strings.Join(inits, ", ")
+}
+
+// genFunction emits the prototype and definition of fi, generating its body
+// in a fresh scope nested in outer. In a traced program (see usesPanics) the
+// function also takes the position of its call, for stack traces.
+func (c *CGen) genFunction(fi *funcInfo, outer *scope) {
+	savedOut, savedFn, savedScope := c.out, c.fn, c.scope
+	defer func() { c.out, c.fn, c.scope = savedOut, savedFn, savedScope }()
+
+	var body strings.Builder
+	c.out = &body
+	c.fn = fi
+	c.scope = outer
+
+	var params []string
+	if c.tracing {
+		params = append(params, "int golite_line", "int golite_column")
+	}
+	if fi.hasEnv() {
+		params = append(params, fi.envType+" *env")
+	}
+	for i, p := range fi.lit.Parameters {
+		name := cIdent(p.Value)
+		ctype := c.paramType(fi, i)
+		if fi.sig != nil && c.livesOnHeap(p.Value, fi.sig.Params[i]) {
+			// The argument is copied to the heap like any variable whose
+			// address is taken.
+			c.require(structRuntime)
+			arg := "golite_arg_" + name
+			c.writeIndent(1)
+			c.out.WriteString(ctype + " *" + name + " = golite_new(sizeof(" + ctype + "), &" + arg + ");\n")
+			c.scope.define(p.Value, heapVar(name, ctype))
+			params = append(params, ctype+" "+arg)
+			continue
+		}
+		c.scope.define(p.Value, &binding{cexpr: name, ptr: "&" + name, ctype: ctype})
+		params = append(params, ctype+" "+name)
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+
+	result := "int64_t"
+	if fi.sig != nil {
+		result = c.cType(fi.sig.Result)
+	}
+	signature := fmt.Sprintf("%s %s(%s)", result, fi.cname, strings.Join(params, ", "))
+	if c.topFuncs[fi.lit] == nil {
+		signature = "static " + signature
+	}
+	c.protos.WriteString(signature + ";\n")
+
+	c.genFunctionBody(fi.lit.Body)
+
+	c.funcs.WriteString(signature + " {\n")
+	if c.tracing {
+		defers := hasDefer(fi.lit.Body.Statements)
+		c.funcs.WriteString(c.tracedPrologue(fi.name, result, defers))
+		c.funcs.WriteString(body.String())
+		c.funcs.WriteString(tracedEpilogue(fi.name, result, defers))
+	} else {
+		c.funcs.WriteString(body.String())
+	}
+	c.funcs.WriteString("}\n\n")
+}
+
+// paramType returns the C type of the i'th parameter of fi.
+func (c *CGen) paramType(fi *funcInfo, i int) string {
+	if fi.sig == nil {
+		return "int64_t"
+	}
+	return c.cType(fi.sig.Params[i])
+}
+
+// genFunctionBody emits the statements of a function body. As in the
+// evaluator, a trailing expression statement is the function's result.
+func (c *CGen) genFunctionBody(body *ast.BlockStatement) {
+	stmts := body.Statements
+	if !c.fn.returnsValue() {
+		for _, stmt := range stmts {
+			c.genStatement(stmt, 1)
+		}
+		return
+	}
+	for i, stmt := range stmts {
+		if i == len(stmts)-1 {
+			c.genTail(stmt, 1)
+		} else {
+			c.genStatement(stmt, 1)
+		}
+	}
+	if c.tracing {
+		// golite_result starts out zero.
+		return
+	}
+	if len(stmts) == 0 {
+		c.writeIndent(1)
+		c.out.WriteString("return 0;\n")
+		return
+	}
+	if !endsWithReturn(stmts[len(stmts)-1]) {
+		c.writeIndent(1)
+		c.out.WriteString("return 0;\n")
+	}
+}
+
+// endsWithReturn reports whether genTail emits an unconditional return for
+// stmt.
+func endsWithReturn(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		if s.Expression == nil {
+			return false
+		}
+		_, isIf := s.Expression.(*ast.IfExpression)
+		return !isIf
+	}
+	return false
+}
+
+// genTail emits a statement in tail position of a function body, turning an
+// expression statement into a return of its value.
+func (c *CGen) genTail(stmt ast.Statement, level int) {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok || es.Expression == nil {
+		c.genStatement(stmt, level)
+		return
+	}
+	if ie, ok := es.Expression.(*ast.IfExpression); ok {
+		c.writeIndent(level)
+		c.out.WriteString("if (")
+		c.genExpression(ie.Condition)
+		c.out.WriteString(") {\n")
+		c.genTailBlock(ie.Consequence, level)
+		c.writeIndent(level)
+		c.out.WriteString("}")
+		if ie.Alternative != nil {
+			c.out.WriteString(" else {\n")
+			c.genTailBlock(ie.Alternative, level)
+			c.writeIndent(level)
+			c.out.WriteString("}")
+		}
+		c.out.WriteString("\n")
+		return
+	}
+	if c.typeOf(es.Expression) == types.Void {
+		// A call of panic has no value to return.
+		c.genStatement(stmt, level)
+		return
+	}
+	c.genReturn(es.Expression, level)
+}
+
+func (c *CGen) genTailBlock(block *ast.BlockStatement, level int) {
+	c.pushScope()
+	defer c.popScope()
+	for i, stmt := range block.Statements {
+		if i == len(block.Statements)-1 {
+			c.genTail(stmt, level+1)
+		} else {
+			c.genStatement(stmt, level+1)
+		}
+	}
+}
+
+// genCall emits a call to a let-bound or immediately invoked function.
+func (c *CGen) genCall(ce *ast.CallExpression) {
+	if b, ok := c.typeOf(ce.Function).(*types.Builtin); ok {
+		c.genBuiltinCall(ce, b)
+		return
+	}
+
+	var fi *funcInfo
+	var env string
+
+	switch fn := ce.Function.(type) {
+	case *ast.Identifier:
+		if b := c.scope.resolve(fn.Value); b != nil && b.fn != nil {
+			fi, env = b.fn, b.envExpr
+		}
+	case *ast.SelectorExpression:
+		if m, fi := c.method(fn); fi != nil {
+			c.genMethodCall(ce, fn, m, fi)
+			return
+		}
+		if c.typeOf(fn.X) == types.Error {
+			c.genErrorMethod(ce, fn)
+			return
+		}
+	case *ast.FunctionLiteral:
+		var captures []capture
+		fi, captures = c.liftFunction("", fn)
+		if fi.hasEnv() {
+			// A compound literal keeps the environment alive for the call.
+			env = "&(" + fi.envType + "){ " + captureInits(captures) + " }"
+		}
+	}
+
+	if fi == nil {
+		c.errorf(ce.Function, "the C backend can only call let-bound or immediately invoked functions, not %s", ce.Function.String())
+		c.out.WriteString("0")
+		return
+	}
+
+	c.out.WriteString(fi.cname + "(")
+	first := true
+	if c.tracing {
+		c.out.WriteString(position(ce))
+		first = false
+	}
+	if fi.hasEnv() {
+		if !first {
+			c.out.WriteString(", ")
+		}
+		c.out.WriteString(env)
+		first = false
+	}
+	for _, arg := range ce.Arguments {
+		if !first {
+			c.out.WriteString(", ")
+		}
+		first = false
+		c.genExpression(arg)
+	}
+	c.out.WriteString(")")
+}
+
+// freeVariables returns the names used in lit that are not bound by lit
+// itself, in order of first use.
+func freeVariables(lit *ast.FunctionLiteral) []string {
+	fv := &freeVarCollector{seen: map[string]bool{}}
+	fv.function(lit)
+	return fv.free
+}
+
+type freeVarCollector struct {
+	scopes []map[string]bool
+	free   []string
+	seen   map[string]bool
+}
+
+func (fv *freeVarCollector) push()            { fv.scopes = append(fv.scopes, map[string]bool{}) }
+func (fv *freeVarCollector) pop()             { fv.scopes = fv.scopes[:len(fv.scopes)-1] }
+func (fv *freeVarCollector) bind(name string) { fv.scopes[len(fv.scopes)-1][name] = true }
+
+func (fv *freeVarCollector) bound(name string) bool {
+	for _, s := range fv.scopes {
+		if s[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (fv *freeVarCollector) function(lit *ast.FunctionLiteral) {
+	fv.push()
+	for _, p := range lit.Parameters {
+		fv.bind(p.Value)
+	}
+	fv.walk(lit.Body)
+	fv.pop()
+}
+
+func (fv *freeVarCollector) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			if !fv.bound(n.Value) && !fv.seen[n.Value] {
+				fv.seen[n.Value] = true
+				fv.free = append(fv.free, n.Value)
+			}
+		case *ast.LetStatement:
+			// Function literals may refer to themselves.
+			if _, ok := n.Value.(*ast.FunctionLiteral); ok {
+				fv.bind(n.Name.Value)
+			}
+			if n.Value != nil {
+				fv.walk(n.Value)
+			}
+			for _, name := range n.Names() {
+				fv.bind(name.Value)
+			}
+			return false
+		case *ast.FunctionLiteral:
+			fv.function(n)
+			return false
+		case *ast.SelectorExpression:
+			// The selected name is a field or method, not a variable.
+			fv.walk(n.X)
+			return false
+		case *ast.StructLiteral:
+			for _, value := range n.Values {
+				fv.walk(value)
+			}
+			return false
+		case *ast.BlockStatement:
+			fv.push()
+			for _, stmt := range n.Statements {
+				fv.walk(stmt)
+			}
+			fv.pop()
+			return false
+		case *ast.ForStatement:
+			fv.push()
+			if n.Init != nil {
+				fv.walk(n.Init)
+			}
+			if n.Condition != nil {
+				fv.walk(n.Condition)
+			}
+			if n.Post != nil {
+				fv.walk(n.Post)
+			}
+			fv.walk(n.Body)
+			fv.pop()
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,143 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// mapType returns the type of expr if it is a map.
+func (c *CGen) mapType(expr ast.Expression) (*types.Map, bool) {
+	m, ok := types.Prune(c.typeOf(expr)).(*types.Map)
+	return m, ok
+}
+
+func (c *CGen) isMap(expr ast.Expression) bool {
+	_, ok := c.mapType(expr)
+	return ok
+}
+
+// keyOps returns the C golite_key_ops that hash, compare and order keys of
+// type t.
+func (c *CGen) keyOps(t types.Type) string {
+	if types.Prune(t) == types.String {
+		c.require(stringKeyRuntime)
+	}
+	return "&golite_" + types.Prune(t).String() + "_key"
+}
+
+// genMapKey emits the address of a temporary holding the value of key.
+func (c *CGen) genMapKey(m *types.Map, key ast.Expression) {
+	c.out.WriteString("(" + c.cType(m.Key) + "[]){ ")
+	c.genExpression(key)
+	c.out.WriteString(" }")
+}
+
+func (c *CGen) genMapLiteral(ml *ast.MapLiteral) {
+	m, _ := c.mapType(ml)
+	keyType, valueType := c.cType(m.Key), c.cType(m.Value)
+	table := fmt.Sprintf("golite_map_new(sizeof(%s), sizeof(%s), %s)", keyType, valueType, c.keyOps(m.Key))
+	if len(ml.Keys) == 0 {
+		c.out.WriteString(table)
+		return
+	}
+	fmt.Fprintf(c.out, "golite_map_of(%s, %d, ", table, len(ml.Keys))
+	c.genElements(keyType, ml.Keys)
+	c.out.WriteString(", ")
+	c.genElements(valueType, ml.Values)
+	c.out.WriteString(")")
+}
+
+// genMapIndex emits m[k], which is the zero value if k is missing.
+func (c *CGen) genMapIndex(ie *ast.IndexExpression, m *types.Map) {
+	valueType := c.cType(m.Value)
+	c.out.WriteString("(*(" + valueType + " *)golite_map_index(")
+	c.genExpression(ie.Left)
+	c.out.WriteString(", ")
+	c.genMapKey(m, ie.Index)
+	c.out.WriteString(", &(" + valueType + "){0}))")
+}
+
+// genMapStore emits an assignment to m[k]; value emits the new value. The
+// value is computed before the entry is stored, since storing may move the
+// table.
+func (c *CGen) genMapStore(ie *ast.IndexExpression, value func()) {
+	m, _ := c.mapType(ie.Left)
+	c.out.WriteString("golite_map_store(")
+	c.genExpression(ie.Left)
+	c.out.WriteString(", ")
+	c.genMapKey(m, ie.Index)
+	c.out.WriteString(", (" + c.cType(m.Value) + "[]){ ")
+	value()
+	c.out.WriteString(" }, " + position(ie) + ")")
+}
+
+// genMapAssign emits an assignment or increment whose target is a map
+// entry.
+func (c *CGen) genMapAssign(stmt ast.Statement, ie *ast.IndexExpression) {
+	switch s := stmt.(type) {
+	case *ast.AssignStatement:
+		c.genMapStore(ie, func() {
+			switch {
+			case s.Operator == "=":
+				c.genExpression(s.Value)
+			case s.Operator == "+=" && c.typeOf(ie) == types.String:
+				c.out.WriteString("golite_string_concat(")
+				c.genExpression(ie)
+				c.out.WriteString(", ")
+				c.genExpression(s.Value)
+				c.out.WriteString(")")
+			case c.isIntArithmetic(strings.TrimSuffix(s.Operator, "="), c.typeOf(ie)):
+				c.genIntArithmetic(strings.TrimSuffix(s.Operator, "="), s, func() { c.genExpression(ie) }, func() { c.genExpression(s.Value) })
+			default:
+				c.genExpression(ie)
+				c.out.WriteString(" " + strings.TrimSuffix(s.Operator, "=") + " ")
+				c.genExpression(s.Value)
+			}
+		})
+	case *ast.IncDecStatement:
+		c.genMapStore(ie, func() {
+			if c.isIntArithmetic(s.Operator[:1], c.typeOf(ie)) {
+				c.genIntArithmetic(s.Operator[:1], s, func() { c.genExpression(ie) }, func() { c.out.WriteString("1") })
+				return
+			}
+			c.genExpression(ie)
+			c.out.WriteString(" " + s.Operator[:1] + " 1")
+		})
+	}
+}
+
+// mapWriter returns the name of a C function that writes maps of type t
+// the way print does, e.g. map[a:1 b:2], generating it on first use.
+func (c *CGen) mapWriter(t *types.Map) string {
+	key := types.Resolve(t).String()
+	if name, ok := c.writers[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("golite_write_map_%d", len(c.writers)+1)
+	c.writers[key] = name
+
+	keyType, valueType := c.cType(t.Key), c.cType(t.Value)
+	c.keyOps(t.Key)
+	writeKey := c.writeStatement("k", t.Key)
+	writeValue := c.writeStatement("(*("+valueType+" *)golite_map_get(m, &k))", t.Value)
+	fmt.Fprintf(&c.helpers, `static void %s(golite_map *m) {
+    golite_slice keys = golite_map_sorted_keys(m);
+    fputs("map[", stdout);
+    for (int64_t i = 0; i < keys.len; i++) {
+        %s k = ((%s *)keys.data)[i];
+        if (i > 0) {
+            putchar(' ');
+        }
+        %s
+        putchar(':');
+        %s
+    }
+    putchar(']');
+}
+
+`, name, keyType, keyType, writeKey, writeValue)
+	return name
+}
@@ -0,0 +1,218 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// usesPanics reports whether a program defers calls or calls panic or
+// recover. Only such programs are traced: every C function then takes the
+// position of its call and records itself for stack traces (see
+// panicRuntime), and runtime errors become panics that can be recovered.
+func (c *CGen) usesPanics(program *ast.Program) bool {
+	found := false
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DeferStatement:
+			found = true
+		case *ast.CallExpression:
+			if b, ok := c.typeOf(n.Function).(*types.Builtin); ok && (b.Name == "panic" || b.Name == "recover") {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// hasDefer reports whether stmts defer a call, not counting the bodies of
+// the function literals among them.
+func hasDefer(stmts []ast.Statement) bool {
+	found := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.DeferStatement:
+				found = true
+			case *ast.FunctionLiteral:
+				return false
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// tracedPrologue returns the statements that start a traced function body,
+// or main if name is "main". Returns jump to golite_return at the end of
+// the body, so that the function always leaves the stack of calls and runs
+// its deferred calls. golite_result is volatile in a function with deferred
+// calls, since a panic in one of them jumps back into the function after it
+// is set.
+func (c *CGen) tracedPrologue(name, result string, defers bool) string {
+	var out strings.Builder
+	if result != "void" && result != "" {
+		if defers {
+			result += " volatile"
+		}
+		out.WriteString("    " + result + " golite_result = { 0 };\n")
+	}
+	if defers {
+		out.WriteString("    golite_frame golite_current;\n")
+	}
+	if name == "main" {
+		out.WriteString("    golite_start();\n")
+	} else {
+		out.WriteString("    golite_enter(" + cStringLiteral(name) + ", golite_line, golite_column);\n")
+	}
+	if defers {
+		out.WriteString("    golite_push_frame(&golite_current);\n")
+		out.WriteString("    if (setjmp(golite_current.jump) != 0) {\n")
+		out.WriteString("        goto golite_return;\n")
+		out.WriteString("    }\n")
+	}
+	return out.String()
+}
+
+// tracedEpilogue returns the statements that end a traced function body.
+func tracedEpilogue(name, result string, defers bool) string {
+	var out strings.Builder
+	if name != "main" || defers {
+		out.WriteString("golite_return:\n")
+	}
+	if defers {
+		out.WriteString("    golite_pop_frame(&golite_current);\n")
+	}
+	if name != "main" {
+		out.WriteString("    golite_leave();\n")
+	}
+	if result != "void" && result != "" {
+		out.WriteString("    return golite_result;\n")
+	}
+	return out.String()
+}
+
+// genReturn emits a return of value, or a bare return if value is nil.
+func (c *CGen) genReturn(value ast.Expression, level int) {
+	c.writeIndent(level)
+	if !c.tracing {
+		if value == nil {
+			c.out.WriteString("return;\n")
+			return
+		}
+		c.out.WriteString("return ")
+		c.genExpression(value)
+		c.out.WriteString(";\n")
+		return
+	}
+	if value == nil {
+		c.out.WriteString("goto golite_return;\n")
+		return
+	}
+	c.out.WriteString("{\n")
+	c.writeIndent(level + 1)
+	c.out.WriteString("golite_result = ")
+	c.genExpression(value)
+	c.out.WriteString(";\n")
+	c.writeIndent(level + 1)
+	c.out.WriteString("goto golite_return;\n")
+	c.writeIndent(level)
+	c.out.WriteString("}\n")
+}
+
+// genDefer emits a defer statement. The function's environment, receiver
+// and arguments are evaluated now and stored in a record of a C struct
+// made for the statement, along with a C function that makes the call from
+// the record, which golite_pop_frame calls. Records are allocated on the
+// heap, so that a defer statement in a loop can defer several calls.
+func (c *CGen) genDefer(ds *ast.DeferStatement, level int) {
+	ce := ds.Call
+	var fi *funcInfo
+	var env string
+	var values []func()
+
+	switch fn := ce.Function.(type) {
+	case *ast.Identifier:
+		if b := c.scope.resolve(fn.Value); b != nil && b.fn != nil {
+			fi = b.fn
+			if fi.hasEnv() {
+				env = "*" + b.envExpr
+			}
+		}
+	case *ast.FunctionLiteral:
+		var captures []capture
+		fi, captures = c.liftFunction("", fn)
+		if fi.hasEnv() {
+			env = "(" + fi.envType + "){ " + captureInits(captures) + " }"
+		}
+	case *ast.SelectorExpression:
+		var m *types.Method
+		if m, fi = c.method(fn); fi != nil {
+			values = append(values, func() { c.genReceiver(fn, m) })
+		}
+	}
+	if fi == nil {
+		c.errorf(ce.Function, "the C backend can only defer calls of let-bound functions, methods and function literals, not %s", ce.Function.String())
+		return
+	}
+	for _, arg := range ce.Arguments {
+		arg := arg
+		values = append(values, func() { c.genExpression(arg) })
+	}
+
+	base := "main"
+	if c.fn != nil {
+		base = c.fn.cname
+	}
+	name := c.uniqueName(base + "_defer")
+	args := []string{position(ce)}
+	c.decls.WriteString("struct " + name + " {\n    golite_deferred deferred;\n")
+	if fi.hasEnv() {
+		c.decls.WriteString("    " + fi.envType + " env;\n")
+		args = append(args, "&call->env")
+	}
+	for i := range values {
+		fmt.Fprintf(&c.decls, "    %s a%d;\n", c.paramType(fi, i), i)
+		args = append(args, fmt.Sprintf("call->a%d", i))
+	}
+	c.decls.WriteString("};\n")
+
+	c.protos.WriteString("static void " + name + "_run(golite_deferred *d);\n")
+	c.funcs.WriteString("static void " + name + "_run(golite_deferred *d) {\n")
+	if len(args) > 1 {
+		c.funcs.WriteString("    struct " + name + " *call = (struct " + name + " *)d;\n")
+	} else {
+		c.funcs.WriteString("    (void)d;\n")
+	}
+	c.funcs.WriteString("    " + fi.cname + "(" + strings.Join(args, ", ") + ");\n}\n\n")
+
+	c.writeIndent(level)
+	c.out.WriteString("{\n")
+	c.writeIndent(level + 1)
+	c.out.WriteString("struct " + name + " *golite_call = golite_alloc(sizeof *golite_call);\n")
+	if fi.hasEnv() {
+		c.writeIndent(level + 1)
+		c.out.WriteString("golite_call->env = " + env + ";\n")
+	}
+	for i, value := range values {
+		c.writeIndent(level + 1)
+		fmt.Fprintf(c.out, "golite_call->a%d = ", i)
+		value()
+		c.out.WriteString(";\n")
+	}
+	c.writeIndent(level + 1)
+	c.out.WriteString("golite_defer(&golite_call->deferred, " + name + "_run);\n")
+	c.writeIndent(level)
+	c.out.WriteString("}\n")
+}
+
+// genErrorMethod emits err.Error(), checking that err is not nil.
+func (c *CGen) genErrorMethod(ce *ast.CallExpression, se *ast.SelectorExpression) {
+	c.require(errorValueRuntime)
+	c.out.WriteString("golite_error_message(")
+	c.genExpression(se.X)
+	c.out.WriteString(", " + position(ce) + ")")
+}
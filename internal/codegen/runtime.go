@@ -0,0 +1,813 @@
+package codegen
+
+// runtimePart is C support code that is only emitted for programs that
+// need it.
+type runtimePart struct {
+	includes []string
+	deps     []*runtimePart // parts this code uses
+	code     string
+}
+
+// require arranges for part, and the parts it depends on, to be emitted
+// ahead of the generated code.
+func (c *CGen) require(part *runtimePart) {
+	for _, p := range c.runtime {
+		if p == part {
+			return
+		}
+	}
+	for _, dep := range part.deps {
+		c.require(dep)
+	}
+	c.runtime = append(c.runtime, part)
+}
+
+// errorRuntime reports runtime errors, such as an index out of range, and
+// stops the program. The position is that of the failing expression. A
+// program that can recover from panics turns them into panics instead,
+// through golite_on_runtime_error.
+var errorRuntime = &runtimePart{
+	includes: []string{"stdarg.h", "stdlib.h"},
+	code: `static void (*golite_on_runtime_error)(int line, int column, const char *message);
+
+static void golite_runtime_error(int line, int column, const char *format, ...) {
+    char message[256];
+    va_list args;
+    va_start(args, format);
+    vsnprintf(message, sizeof message, format, args);
+    va_end(args);
+    if (golite_on_runtime_error != NULL) {
+        golite_on_runtime_error(line, column, message);
+    }
+    fflush(stdout);
+    fprintf(stderr, "runtime error at %d:%d: %s\n", line, column, message);
+    exit(1);
+}
+
+static void *golite_alloc(size_t size) {
+    void *p = malloc(size);
+    if (p == NULL && size > 0) {
+        fputs("out of memory\n", stderr);
+        exit(1);
+    }
+    return p;
+}
+`,
+}
+
+// stringRuntime implements GoLite strings in C. A string is an immutable
+// pointer and length pair; literals point at static storage and the results
+// of concatenation live on the heap. Strings are never freed.
+var stringRuntime = &runtimePart{
+	includes: []string{"stdlib.h", "string.h"},
+	code: `typedef struct {
+    const char *data;
+    int64_t len;
+} golite_string;
+
+static golite_string golite_string_concat(golite_string a, golite_string b) {
+    char *data = malloc(a.len + b.len + 1);
+    if (data == NULL) {
+        fputs("out of memory\n", stderr);
+        exit(1);
+    }
+    memcpy(data, a.data, a.len);
+    memcpy(data + a.len, b.data, b.len);
+    data[a.len + b.len] = '\0';
+    return (golite_string){ data, a.len + b.len };
+}
+
+static bool golite_string_eq(golite_string a, golite_string b) {
+    return a.len == b.len && memcmp(a.data, b.data, a.len) == 0;
+}
+
+static void golite_print_string(golite_string s) {
+    fwrite(s.data, 1, s.len, stdout);
+    putchar('\n');
+}
+`,
+}
+
+// floatRuntime prints floats exactly as the evaluator does, which follows
+// Go's strconv.FormatFloat(v, 'g', -1, 64): the fewest digits that read
+// back as v, in exponent form when the exponent is below -4 or at least 6.
+var floatRuntime = &runtimePart{
+	includes: []string{"math.h", "stdlib.h", "string.h"},
+	code: `static void golite_write_float(double v) {
+    if (isnan(v)) {
+        fputs("NaN", stdout);
+        return;
+    }
+    if (isinf(v)) {
+        fputs(v > 0 ? "+Inf" : "-Inf", stdout);
+        return;
+    }
+    char buf[32];
+    int digits;
+    for (digits = 1; digits < 17; digits++) {
+        snprintf(buf, sizeof buf, "%.*e", digits - 1, v);
+        if (strtod(buf, NULL) == v) {
+            break;
+        }
+    }
+    snprintf(buf, sizeof buf, "%.*e", digits - 1, v);
+    int exp = atoi(strchr(buf, 'e') + 1);
+    if (exp < -4 || exp >= 6) {
+        fputs(buf, stdout);
+    } else {
+        printf("%.*g", digits > exp + 1 ? digits : exp + 1, v);
+    }
+}
+
+static void golite_print_float(double v) {
+    golite_write_float(v);
+    putchar('\n');
+}
+`,
+}
+
+// printfRuntime implements what C's printf does differently from Go's for
+// the verbs of printf: a width counts runes rather than bytes, strings may
+// hold NUL bytes, and %f prints infinities and NaN the way Go does.
+var printfRuntime = &runtimePart{
+	includes: []string{"math.h", "string.h"},
+	code: `static int64_t golite_rune_count(const char *data, int64_t len) {
+    const unsigned char *s = (const unsigned char *)data;
+    int64_t runes = 0;
+    for (int64_t i = 0; i < len; runes++) {
+        unsigned char b = s[i];
+        int n = b < 0x80 ? 1 : b >= 0xC2 && b <= 0xDF ? 2 : b >= 0xE0 && b <= 0xEF ? 3 : b >= 0xF0 && b <= 0xF4 ? 4 : 1;
+        unsigned char lo = b == 0xE0 ? 0xA0 : b == 0xF0 ? 0x90 : 0x80;
+        unsigned char hi = b == 0xED ? 0x9F : b == 0xF4 ? 0x8F : 0xBF;
+        int j = 1;
+        for (; j < n && i + j < len; j++) {
+            unsigned char c = s[i + j];
+            if (c < (j == 1 ? lo : 0x80) || c > (j == 1 ? hi : 0xBF)) {
+                break;
+            }
+        }
+        /* An invalid sequence counts as one rune per byte, as in Go. */
+        i += j == n ? n : 1;
+    }
+    return runes;
+}
+
+static void golite_write_padded(const char *data, int64_t len, int64_t width, bool left, bool zero) {
+    int64_t pad = width - golite_rune_count(data, len);
+    for (; !left && pad > 0; pad--) {
+        putchar(zero ? '0' : ' ');
+    }
+    fwrite(data, 1, len, stdout);
+    for (; pad > 0; pad--) {
+        putchar(' ');
+    }
+}
+
+static const char *golite_nonfinite(double v, bool plus, bool space) {
+    if (isnan(v)) {
+        return plus ? "+NaN" : space ? " NaN" : "NaN";
+    }
+    if (v < 0) {
+        return "-Inf";
+    }
+    return space && !plus ? " Inf" : "+Inf";
+}
+`,
+}
+
+// sliceRuntime implements GoLite slices in C. A slice is a window of len
+// elements onto an array with room for cap, which other slices may share.
+// Element types vary, so the functions take the size of an element and
+// callers cast the data pointer. Appending grows arrays the way the
+// evaluator does, so that both agree on when slices stop sharing.
+var sliceRuntime = &runtimePart{
+	includes: []string{"string.h"},
+	deps:     []*runtimePart{errorRuntime},
+	code: `typedef struct {
+    void *data;
+    int64_t len;
+    int64_t cap;
+} golite_slice;
+
+static golite_slice golite_slice_of(size_t size, int64_t n, const void *elems) {
+    golite_slice s = { golite_alloc(n * size), n, n };
+    memcpy(s.data, elems, n * size);
+    return s;
+}
+
+static void *golite_index(golite_slice s, int64_t i, size_t size, int line, int column) {
+    if (i < 0) {
+        golite_runtime_error(line, column, "index out of range [%lld]", (long long)i);
+    }
+    if (i >= s.len) {
+        golite_runtime_error(line, column, "index out of range [%lld] with length %lld", (long long)i, (long long)s.len);
+    }
+    return (char *)s.data + i * size;
+}
+
+static golite_slice golite_slice_slice(golite_slice s, int64_t lo, int64_t hi, bool has_high, size_t size, int line, int column) {
+    if (!has_high) {
+        hi = s.len;
+    }
+    if (hi < 0 || hi > s.cap) {
+        golite_runtime_error(line, column, "slice bounds out of range [:%lld] with capacity %lld", (long long)hi, (long long)s.cap);
+    }
+    if (lo < 0 || lo > hi) {
+        golite_runtime_error(line, column, "slice bounds out of range [%lld:%lld]", (long long)lo, (long long)hi);
+    }
+    if (s.data != NULL) {
+        s.data = (char *)s.data + lo * size;
+    }
+    s.len = hi - lo;
+    s.cap -= lo;
+    return s;
+}
+
+static golite_slice golite_append(golite_slice s, size_t size, int64_t n, const void *elems) {
+    if (s.len + n > s.cap) {
+        int64_t cap = 2 * s.cap;
+        if (cap < s.len + n) {
+            cap = s.len + n;
+        }
+        void *data = golite_alloc(cap * size);
+        if (s.len > 0) {
+            memcpy(data, s.data, s.len * size);
+        }
+        s.data = data;
+        s.cap = cap;
+    }
+    memcpy((char *)s.data + s.len * size, elems, n * size);
+    s.len += n;
+    return s;
+}
+`,
+}
+
+// stringSliceRuntime slices strings. Unlike a slice, a string has no spare
+// capacity, so the bounds are checked against its length.
+var stringSliceRuntime = &runtimePart{
+	deps: []*runtimePart{stringRuntime, errorRuntime},
+	code: `static golite_string golite_string_slice(golite_string s, int64_t lo, int64_t hi, bool has_high, int line, int column) {
+    if (!has_high) {
+        hi = s.len;
+    }
+    if (hi < 0 || hi > s.len) {
+        golite_runtime_error(line, column, "slice bounds out of range [:%lld] with length %lld", (long long)hi, (long long)s.len);
+    }
+    if (lo < 0 || lo > hi) {
+        golite_runtime_error(line, column, "slice bounds out of range [%lld:%lld]", (long long)lo, (long long)hi);
+    }
+    return (golite_string){ s.data + lo, hi - lo };
+}
+`,
+}
+
+// mapRuntime implements GoLite maps in C as open-addressing hash tables with
+// linear probing. A map value is a pointer to its table, so maps are shared
+// by reference as in Go, and NULL is the nil map. Like slices, the
+// functions take keys and values by address, and a table records the size
+// of each and how to hash, compare and order its keys.
+var mapRuntime = &runtimePart{
+	includes: []string{"stdlib.h", "string.h", "time.h"},
+	deps:     []*runtimePart{errorRuntime, sliceRuntime},
+	code: `typedef struct {
+    uint64_t (*hash)(const void *key);
+    bool (*equal)(const void *a, const void *b);
+    int (*compare)(const void *a, const void *b);
+} golite_key_ops;
+
+enum { GOLITE_SLOT_EMPTY, GOLITE_SLOT_FULL, GOLITE_SLOT_DELETED };
+
+typedef struct {
+    int64_t len;  /* entries in the map */
+    int64_t used; /* slots that are not empty, including deleted entries */
+    int64_t cap;  /* number of slots, a power of two */
+    size_t key_size;
+    size_t value_size;
+    const golite_key_ops *ops;
+    unsigned char *states;
+    char *keys;
+    char *values;
+} golite_map;
+
+static uint64_t golite_hash_bits(uint64_t x) {
+    x ^= x >> 30;
+    x *= 0xbf58476d1ce4e5b9ULL;
+    x ^= x >> 27;
+    x *= 0x94d049bb133111ebULL;
+    return x ^ (x >> 31);
+}
+
+static uint64_t golite_hash_int(const void *key) {
+    return golite_hash_bits((uint64_t)*(const int64_t *)key);
+}
+
+static bool golite_equal_int(const void *a, const void *b) {
+    return *(const int64_t *)a == *(const int64_t *)b;
+}
+
+static int golite_compare_int(const void *a, const void *b) {
+    int64_t x = *(const int64_t *)a, y = *(const int64_t *)b;
+    return (x > y) - (x < y);
+}
+
+static uint64_t golite_hash_float(const void *key) {
+    double x = *(const double *)key;
+    uint64_t bits;
+    if (x == 0) {
+        x = 0; /* -0 and +0 are the same key */
+    }
+    memcpy(&bits, &x, sizeof bits);
+    return golite_hash_bits(bits);
+}
+
+static bool golite_equal_float(const void *a, const void *b) {
+    return *(const double *)a == *(const double *)b;
+}
+
+static int golite_compare_float(const void *a, const void *b) {
+    double x = *(const double *)a, y = *(const double *)b;
+    return (x > y) - (x < y);
+}
+
+static uint64_t golite_hash_bool(const void *key) {
+    return *(const bool *)key;
+}
+
+static bool golite_equal_bool(const void *a, const void *b) {
+    return *(const bool *)a == *(const bool *)b;
+}
+
+static int golite_compare_bool(const void *a, const void *b) {
+    return (int)*(const bool *)a - (int)*(const bool *)b;
+}
+
+static const golite_key_ops golite_int_key = { golite_hash_int, golite_equal_int, golite_compare_int };
+static const golite_key_ops golite_float_key = { golite_hash_float, golite_equal_float, golite_compare_float };
+static const golite_key_ops golite_bool_key = { golite_hash_bool, golite_equal_bool, golite_compare_bool };
+
+static void golite_map_alloc(golite_map *m, int64_t cap) {
+    m->len = 0;
+    m->used = 0;
+    m->cap = cap;
+    m->states = golite_alloc(cap);
+    memset(m->states, GOLITE_SLOT_EMPTY, cap);
+    m->keys = golite_alloc(cap * m->key_size);
+    m->values = golite_alloc(cap * m->value_size);
+}
+
+static golite_map *golite_map_new(size_t key_size, size_t value_size, const golite_key_ops *ops) {
+    golite_map *m = golite_alloc(sizeof *m);
+    m->key_size = key_size;
+    m->value_size = value_size;
+    m->ops = ops;
+    golite_map_alloc(m, 8);
+    return m;
+}
+
+/* golite_map_slot returns the slot holding key or, if there is none, the
+   slot where it would be inserted. There is always an empty slot, so the
+   search ends. */
+static int64_t golite_map_slot(golite_map *m, const void *key, bool *found) {
+    uint64_t mask = (uint64_t)m->cap - 1;
+    uint64_t i = m->ops->hash(key) & mask;
+    int64_t free_slot = -1;
+    for (;;) {
+        if (m->states[i] == GOLITE_SLOT_EMPTY) {
+            *found = false;
+            return free_slot >= 0 ? free_slot : (int64_t)i;
+        }
+        if (m->states[i] == GOLITE_SLOT_DELETED) {
+            if (free_slot < 0) {
+                free_slot = i;
+            }
+        } else if (m->ops->equal(m->keys + i * m->key_size, key)) {
+            *found = true;
+            return i;
+        }
+        i = (i + 1) & mask;
+    }
+}
+
+/* golite_map_put stores an entry in slot i. As in Go, the key is stored
+   even if an equal one was there, which matters for -0 and +0. */
+static void golite_map_put(golite_map *m, int64_t i, bool found, const void *key, const void *value) {
+    if (!found) {
+        if (m->states[i] == GOLITE_SLOT_EMPTY) {
+            m->used++;
+        }
+        m->states[i] = GOLITE_SLOT_FULL;
+        m->len++;
+    }
+    memcpy(m->keys + i * m->key_size, key, m->key_size);
+    memcpy(m->values + i * m->value_size, value, m->value_size);
+}
+
+/* golite_map_resize moves the entries of m to a table with cap slots,
+   dropping deleted ones. */
+static void golite_map_resize(golite_map *m, int64_t cap) {
+    golite_map old = *m;
+    golite_map_alloc(m, cap);
+    for (int64_t i = 0; i < old.cap; i++) {
+        if (old.states[i] == GOLITE_SLOT_FULL) {
+            const void *key = old.keys + i * old.key_size;
+            bool found;
+            int64_t slot = golite_map_slot(m, key, &found);
+            golite_map_put(m, slot, found, key, old.values + i * old.value_size);
+        }
+    }
+    free(old.states);
+    free(old.keys);
+    free(old.values);
+}
+
+static void golite_map_store(golite_map *m, const void *key, const void *value, int line, int column) {
+    if (m == NULL) {
+        golite_runtime_error(line, column, "assignment to entry in nil map");
+    }
+    bool found;
+    int64_t i = golite_map_slot(m, key, &found);
+    if (!found && (m->used + 1) * 4 > m->cap * 3) {
+        golite_map_resize(m, (m->len + 1) * 2 > m->cap ? m->cap * 2 : m->cap);
+        i = golite_map_slot(m, key, &found);
+    }
+    golite_map_put(m, i, found, key, value);
+}
+
+static golite_map *golite_map_of(golite_map *m, int64_t n, const void *keys, const void *values) {
+    for (int64_t i = 0; i < n; i++) {
+        golite_map_store(m, (const char *)keys + i * m->key_size, (const char *)values + i * m->value_size, 0, 0);
+    }
+    return m;
+}
+
+static void *golite_map_get(golite_map *m, const void *key) {
+    if (m == NULL || m->len == 0) {
+        return NULL;
+    }
+    bool found;
+    int64_t i = golite_map_slot(m, key, &found);
+    return found ? m->values + i * m->value_size : NULL;
+}
+
+/* golite_map_index returns the address of the value for key, or zero if
+   the key is missing. */
+static void *golite_map_index(golite_map *m, const void *key, void *zero) {
+    void *value = golite_map_get(m, key);
+    return value != NULL ? value : zero;
+}
+
+/* golite_map_lookup copies the value for key to value, or zeroes it if the
+   key is missing, and reports whether the key was present. */
+static bool golite_map_lookup(golite_map *m, const void *key, void *value, size_t value_size) {
+    void *found = golite_map_get(m, key);
+    if (found == NULL) {
+        memset(value, 0, value_size);
+        return false;
+    }
+    memcpy(value, found, value_size);
+    return true;
+}
+
+static void golite_map_delete(golite_map *m, const void *key) {
+    if (m == NULL || m->len == 0) {
+        return;
+    }
+    bool found;
+    int64_t i = golite_map_slot(m, key, &found);
+    if (found) {
+        m->states[i] = GOLITE_SLOT_DELETED;
+        m->len--;
+    }
+}
+
+static int64_t golite_map_len(golite_map *m) {
+    return m == NULL ? 0 : m->len;
+}
+
+/* golite_map_collect returns the keys of m, in slot order starting from
+   slot start. */
+static golite_slice golite_map_collect(golite_map *m, int64_t start) {
+    if (m == NULL || m->len == 0) {
+        return (golite_slice){ NULL, 0, 0 };
+    }
+    golite_slice keys = { golite_alloc(m->len * m->key_size), m->len, m->len };
+    int64_t n = 0;
+    for (int64_t j = 0; j < m->cap; j++) {
+        int64_t i = (start + j) & (m->cap - 1);
+        if (m->states[i] == GOLITE_SLOT_FULL) {
+            memcpy((char *)keys.data + n * m->key_size, m->keys + i * m->key_size, m->key_size);
+            n++;
+        }
+    }
+    return keys;
+}
+
+/* golite_map_keys returns the keys of m starting from a random slot, so
+   that programs cannot come to depend on their order. */
+static golite_slice golite_map_keys(golite_map *m) {
+    static bool seeded;
+    if (!seeded) {
+        srand((unsigned)time(NULL));
+        seeded = true;
+    }
+    return golite_map_collect(m, ((int64_t)rand() << 16) ^ rand());
+}
+
+/* golite_map_sorted_keys returns the keys of m in ascending order, the
+   order in which print shows them. */
+static golite_slice golite_map_sorted_keys(golite_map *m) {
+    golite_slice keys = golite_map_collect(m, 0);
+    if (keys.len > 1) {
+        qsort(keys.data, keys.len, m->key_size, m->ops->compare);
+    }
+    return keys;
+}
+`,
+}
+
+// stringKeyRuntime lets strings be map keys.
+var stringKeyRuntime = &runtimePart{
+	deps: []*runtimePart{mapRuntime, stringRuntime},
+	code: `static uint64_t golite_hash_string(const void *key) {
+    const golite_string *s = key;
+    uint64_t h = 14695981039346656037ULL;
+    for (int64_t i = 0; i < s->len; i++) {
+        h ^= (unsigned char)s->data[i];
+        h *= 1099511628211ULL;
+    }
+    return h;
+}
+
+static bool golite_equal_string(const void *a, const void *b) {
+    return golite_string_eq(*(const golite_string *)a, *(const golite_string *)b);
+}
+
+static int golite_compare_string(const void *a, const void *b) {
+    const golite_string *x = a, *y = b;
+    int64_t n = x->len < y->len ? x->len : y->len;
+    int c = n > 0 ? memcmp(x->data, y->data, n) : 0;
+    if (c != 0) {
+        return c;
+    }
+    return (x->len > y->len) - (x->len < y->len);
+}
+
+static const golite_key_ops golite_string_key = { golite_hash_string, golite_equal_string, golite_compare_string };
+`,
+}
+
+// structRuntime supports pointers to structs. golite_new copies a struct to
+// the heap, where it is never freed, and golite_deref stops the program
+// before it follows a nil pointer.
+var structRuntime = &runtimePart{
+	includes: []string{"string.h"},
+	deps:     []*runtimePart{errorRuntime},
+	code: `static void *golite_new(size_t size, const void *value) {
+    void *p = golite_alloc(size);
+    memcpy(p, value, size);
+    return p;
+}
+
+static void *golite_deref(void *p, int line, int column) {
+    if (p == NULL) {
+        golite_runtime_error(line, column, "invalid memory address or nil pointer dereference");
+    }
+    return p;
+}
+`,
+}
+
+// divisionRuntime divides integers, stopping the program on a zero divisor
+// as the evaluator does instead of leaving the result undefined. Dividing
+// the most negative integer by -1 wraps around, as in Go.
+var divisionRuntime = &runtimePart{
+	deps: []*runtimePart{errorRuntime},
+	code: `static int64_t golite_div(int64_t a, int64_t b, int line, int column) {
+    if (b == 0) {
+        golite_runtime_error(line, column, "integer divide by zero");
+    }
+    if (b == -1) {
+        return (int64_t)(0 - (uint64_t)a);
+    }
+    return a / b;
+}
+
+static int64_t golite_mod(int64_t a, int64_t b, int line, int column) {
+    if (b == 0) {
+        golite_runtime_error(line, column, "integer divide by zero");
+    }
+    if (b == -1) {
+        return 0;
+    }
+    return a % b;
+}
+`,
+}
+
+// shiftRuntime shifts integers as Go does: a negative shift amount stops
+// the program, and shifting by 64 or more, which is undefined in C, shifts
+// every bit out, leaving 0, or -1 for a right shift of a negative integer.
+var shiftRuntime = &runtimePart{
+	deps: []*runtimePart{errorRuntime},
+	code: `static int64_t golite_shl(int64_t a, int64_t b, int line, int column) {
+    if (b < 0) {
+        golite_runtime_error(line, column, "negative shift amount: %lld", (long long)b);
+    }
+    if (b >= 64) {
+        return 0;
+    }
+    return (int64_t)((uint64_t)a << b);
+}
+
+static int64_t golite_shr(int64_t a, int64_t b, int line, int column) {
+    if (b < 0) {
+        golite_runtime_error(line, column, "negative shift amount: %lld", (long long)b);
+    }
+    if (b >= 64) {
+        return a < 0 ? -1 : 0;
+    }
+    return a >> b;
+}
+`,
+}
+
+// errorValueRuntime implements GoLite errors in C. An error is a pointer to
+// its message, which errors.New allocates on the heap, so that errors with
+// the same message are still told apart; nil is NULL.
+var errorValueRuntime = &runtimePart{
+	deps: []*runtimePart{errorRuntime, stringRuntime},
+	code: `typedef struct {
+    golite_string message;
+} golite_error;
+
+static golite_error *golite_error_new(golite_string message) {
+    golite_error *e = golite_alloc(sizeof *e);
+    e->message = message;
+    return e;
+}
+
+static golite_string golite_error_message(golite_error *e, int line, int column) {
+    if (e == NULL) {
+        golite_runtime_error(line, column, "invalid memory address or nil pointer dereference");
+    }
+    return e->message;
+}
+
+static void golite_write_error(golite_error *e) {
+    if (e == NULL) {
+        fputs("<nil>", stdout);
+        return;
+    }
+    fwrite(e->message.data, 1, e->message.len, stdout);
+}
+
+static void golite_print_error(golite_error *e) {
+    golite_write_error(e);
+    putchar('\n');
+}
+`,
+}
+
+// panicRuntime implements panic, defer and recover. Every function records
+// itself on a stack of calls, which a panic copies for its stack trace. A
+// function with deferred calls pushes a frame whose jmp_buf a panic jumps
+// to; golite_pop_frame then runs the deferred calls, the most recent first,
+// and carries on unwinding unless one of them recovered. recover only works
+// in a deferred call, which runs one call deeper than the frame.
+var panicRuntime = &runtimePart{
+	includes: []string{"setjmp.h", "string.h"},
+	deps:     []*runtimePart{errorRuntime, errorValueRuntime},
+	code: `typedef struct golite_deferred {
+    struct golite_deferred *next;
+    void (*run)(struct golite_deferred *d);
+} golite_deferred;
+
+typedef struct golite_frame {
+    jmp_buf jump;
+    struct golite_frame *outer;
+    golite_deferred *deferred;
+    int depth;
+    int recover_depth;
+} golite_frame;
+
+typedef struct {
+    const char *function;
+    int line;
+    int column;
+} golite_activation;
+
+static golite_activation *golite_stack;
+static int golite_depth, golite_stack_size;
+static golite_frame *golite_frames;
+static int golite_recover_depth = -1;
+
+static struct {
+    golite_error *value;
+    golite_activation *trace;
+    int depth;
+} golite_panicking;
+
+static void golite_enter(const char *function, int line, int column) {
+    if (golite_depth > 0) {
+        golite_stack[golite_depth - 1].line = line;
+        golite_stack[golite_depth - 1].column = column;
+    }
+    if (golite_depth == golite_stack_size) {
+        golite_stack_size = golite_stack_size > 0 ? 2 * golite_stack_size : 64;
+        golite_stack = realloc(golite_stack, golite_stack_size * sizeof *golite_stack);
+        if (golite_stack == NULL) {
+            fputs("out of memory\n", stderr);
+            exit(1);
+        }
+    }
+    golite_stack[golite_depth++] = (golite_activation){ function, 0, 0 };
+}
+
+static void golite_leave(void) {
+    golite_depth--;
+}
+
+static void golite_push_frame(golite_frame *frame) {
+    frame->outer = golite_frames;
+    frame->deferred = NULL;
+    frame->depth = golite_depth;
+    frame->recover_depth = golite_recover_depth;
+    golite_frames = frame;
+}
+
+static void golite_defer(golite_deferred *d, void (*run)(golite_deferred *d)) {
+    d->run = run;
+    d->next = golite_frames->deferred;
+    golite_frames->deferred = d;
+}
+
+static void golite_unwind(void) {
+    if (golite_frames != NULL) {
+        longjmp(golite_frames->jump, 1);
+    }
+    fflush(stdout);
+    fprintf(stderr, "panic: %.*s\n", (int)golite_panicking.value->message.len, golite_panicking.value->message.data);
+    for (int i = golite_panicking.depth - 1; i >= 0; i--) {
+        golite_activation *a = &golite_panicking.trace[i];
+        fprintf(stderr, "    in %s at %d:%d\n", a->function, a->line, a->column);
+    }
+    exit(1);
+}
+
+static void golite_raise(golite_error *value, int line, int column) {
+    golite_stack[golite_depth - 1].line = line;
+    golite_stack[golite_depth - 1].column = column;
+    golite_panicking.value = value;
+    golite_panicking.trace = golite_alloc(golite_depth * sizeof *golite_stack);
+    memcpy(golite_panicking.trace, golite_stack, golite_depth * sizeof *golite_stack);
+    golite_panicking.depth = golite_depth;
+    golite_unwind();
+}
+
+static void golite_runtime_panic(int line, int column, const char *message) {
+    static const char prefix[] = "runtime error: ";
+    size_t n = strlen(message);
+    char *data = golite_alloc(sizeof prefix - 1 + n);
+    memcpy(data, prefix, sizeof prefix - 1);
+    memcpy(data + sizeof prefix - 1, message, n);
+    golite_raise(golite_error_new((golite_string){ data, (int64_t)(sizeof prefix - 1 + n) }), line, column);
+}
+
+static void golite_panic(golite_error *value, int line, int column) {
+    if (value == NULL) {
+        golite_runtime_panic(line, column, "panic called with nil argument");
+    }
+    golite_raise(value, line, column);
+}
+
+static golite_error *golite_recover(void) {
+    golite_error *value = golite_panicking.value;
+    if (value == NULL || golite_depth != golite_recover_depth) {
+        return NULL;
+    }
+    golite_panicking.value = NULL;
+    return value;
+}
+
+static void golite_pop_frame(golite_frame *frame) {
+    golite_depth = frame->depth;
+    golite_recover_depth = frame->depth + 1;
+    while (frame->deferred != NULL) {
+        golite_deferred *d = frame->deferred;
+        frame->deferred = d->next;
+        d->run(d);
+    }
+    golite_recover_depth = frame->recover_depth;
+    golite_frames = frame->outer;
+    if (golite_panicking.value != NULL) {
+        golite_unwind();
+    }
+}
+
+static void golite_start(void) {
+    golite_on_runtime_error = golite_runtime_panic;
+    golite_enter("main", 0, 0);
+}
+`,
+}
@@ -0,0 +1,61 @@
+package codegen
+
+import "strings"
+
+// binding describes how a GoLite name is reached from the C code currently
+// being generated.
+type binding struct {
+	cexpr string // C expression naming the variable, e.g. "x" or "(*env->x)"
+	ptr   string // C expression for the variable's address, e.g. "&x" or "env->x"
+	ctype string // C type of the variable
+
+	fn      *funcInfo // set when the name is bound to a known function
+	envExpr string    // pointer to fn's environment, if fn captures variables
+
+	fileScope bool // reachable from every function without capturing
+}
+
+type scope struct {
+	vars  map[string]*binding
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]*binding), outer: outer}
+}
+
+func (s *scope) define(name string, b *binding) {
+	s.vars[name] = b
+}
+
+func (s *scope) resolve(name string) *binding {
+	for sc := s; sc != nil; sc = sc.outer {
+		if b, ok := sc.vars[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// reservedNames are C keywords and names used by the generated runtime that
+// GoLite identifiers must not collide with.
+var reservedNames = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true,
+	"continue": true, "default": true, "do": true, "double": true, "else": true,
+	"enum": true, "extern": true, "float": true, "for": true, "goto": true,
+	"if": true, "inline": true, "int": true, "long": true, "register": true,
+	"restrict": true, "return": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "struct": true, "switch": true,
+	"typedef": true, "union": true, "unsigned": true, "void": true,
+	"volatile": true, "while": true, "bool": true, "main": true, "printf": true, "env": true,
+	"int64_t": true, "NULL": true,
+}
+
+// cIdent maps a GoLite identifier to a C identifier. Names starting with
+// golite_ are reserved for the runtime.
+func cIdent(name string) string {
+	if reservedNames[name] || strings.HasPrefix(name, "golite_") {
+		return name + "_"
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+// elemType returns the C type of the elements of slices of type t.
+func (c *CGen) elemType(t types.Type) string {
+	if s, ok := types.Prune(t).(*types.Slice); ok {
+		return c.cType(s.Elem)
+	}
+	return "int64_t"
+}
+
+// position returns the line and column of node as C arguments, for runtime
+// errors.
+func position(node ast.Node) string {
+	pos := diagnostics.SpanOf(node).Start
+	return strconv.Itoa(pos.Line) + ", " + strconv.Itoa(pos.Column)
+}
+
+func (c *CGen) genArrayLiteral(al *ast.ArrayLiteral) {
+	c.require(sliceRuntime)
+	if len(al.Elements) == 0 {
+		c.out.WriteString("((golite_slice){ NULL, 0, 0 })")
+		return
+	}
+	elem := c.elemType(c.typeOf(al))
+	fmt.Fprintf(c.out, "golite_slice_of(sizeof(%s), %d, ", elem, len(al.Elements))
+	c.genElements(elem, al.Elements)
+	c.out.WriteString(")")
+}
+
+// genElements emits the values of elems as a C array of type elem[].
+func (c *CGen) genElements(elem string, elems []ast.Expression) {
+	c.out.WriteString("(" + elem + "[]){ ")
+	for i, el := range elems {
+		if i > 0 {
+			c.out.WriteString(", ")
+		}
+		c.genExpression(el)
+	}
+	c.out.WriteString(" }")
+}
+
+// genIndex emits a[i] as a dereferenced, bounds-checked pointer to the
+// element, which is also valid as the target of an assignment.
+func (c *CGen) genIndex(ie *ast.IndexExpression) {
+	c.require(sliceRuntime)
+	elem := c.elemType(c.typeOf(ie.Left))
+	c.out.WriteString("(*(" + elem + " *)golite_index(")
+	c.genExpression(ie.Left)
+	c.out.WriteString(", ")
+	c.genExpression(ie.Index)
+	c.out.WriteString(", sizeof(" + elem + "), " + position(ie) + "))")
+}
+
+func (c *CGen) genSliceExpression(se *ast.SliceExpression) {
+	leftType := c.typeOf(se.Left)
+	if leftType == types.String {
+		c.require(stringSliceRuntime)
+		c.out.WriteString("golite_string_slice(")
+	} else {
+		c.require(sliceRuntime)
+		c.out.WriteString("golite_slice_slice(")
+	}
+	c.genExpression(se.Left)
+	c.out.WriteString(", ")
+	if se.Low != nil {
+		c.genExpression(se.Low)
+	} else {
+		c.out.WriteString("0")
+	}
+	c.out.WriteString(", ")
+	if se.High != nil {
+		c.genExpression(se.High)
+		c.out.WriteString(", true")
+	} else {
+		c.out.WriteString("0, false")
+	}
+	if leftType != types.String {
+		c.out.WriteString(", sizeof(" + c.elemType(leftType) + ")")
+	}
+	c.out.WriteString(", " + position(se) + ")")
+}
+
+// writeStatement returns a C statement that writes value, of type t, to
+// stdout the way print does, without the trailing newline.
+func (c *CGen) writeStatement(value string, t types.Type) string {
+	if _, ok := types.Prune(t).(*types.Pointer); ok {
+		return "(" + value + ") != NULL ? printf(\"%p\", (void *)(" + value + ")) : fputs(\"<nil>\", stdout);"
+	}
+	if writer, ok := c.writer(t); ok {
+		return writer + "(" + value + ");"
+	}
+	switch t := types.Prune(t).(type) {
+	case *types.Basic:
+		switch t {
+		case types.Bool:
+			return "fputs((" + value + ") ? \"true\" : \"false\", stdout);"
+		case types.Float:
+			c.require(floatRuntime)
+			return "golite_write_float(" + value + ");"
+		case types.String:
+			return "fwrite((" + value + ").data, 1, (" + value + ").len, stdout);"
+		case types.Error:
+			return "golite_write_error(" + value + ");"
+		}
+	}
+	return "printf(\"%lld\", (long long)(" + value + "));"
+}
+
+// writer returns the name of the C function that writes values of type t,
+// if t is a slice, map, struct or pointer type.
+func (c *CGen) writer(t types.Type) (string, bool) {
+	switch t := types.Prune(t).(type) {
+	case *types.Slice:
+		return c.sliceWriter(t), true
+	case *types.Map:
+		return c.mapWriter(t), true
+	case *types.Struct:
+		return c.structWriter(t), true
+	case *types.Pointer:
+		return c.pointerWriter(t), true
+	}
+	return "", false
+}
+
+// sliceWriter returns the name of a C function that writes slices of type t
+// the way print does, e.g. [1 2 3], generating it on first use.
+func (c *CGen) sliceWriter(t *types.Slice) string {
+	key := types.Resolve(t).String()
+	if name, ok := c.writers[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("golite_write_slice_%d", len(c.writers)+1)
+	c.writers[key] = name
+
+	elem := c.cType(t.Elem)
+	write := c.writeStatement("(("+elem+" *)s.data)[i]", t.Elem)
+	fmt.Fprintf(&c.helpers, `static void %s(golite_slice s) {
+    putchar('[');
+    for (int64_t i = 0; i < s.len; i++) {
+        if (i > 0) {
+            putchar(' ');
+        }
+        %s
+    }
+    putchar(']');
+}
+
+`, name, write)
+	return name
+}
@@ -0,0 +1,292 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// structType returns the name of the C struct for s, emitting its typedef on
+// first use. Structs held by value in a field are defined first, since C
+// needs their size; those only pointed to need just the forward typedef.
+func (c *CGen) structType(s *types.Struct) string {
+	if name, ok := c.structs[s]; ok {
+		return name
+	}
+	name := c.uniqueName(cIdent(s.Name))
+	c.structs[s] = name
+	c.typedefs.WriteString("typedef struct " + name + " " + name + ";\n")
+
+	var def strings.Builder
+	def.WriteString("struct " + name + " {\n")
+	for _, f := range s.Fields {
+		def.WriteString("    " + c.cType(f.Type) + " " + cIdent(f.Name) + ";\n")
+	}
+	if len(s.Fields) == 0 {
+		// C does not allow empty structs.
+		def.WriteString("    char golite_unused;\n")
+	}
+	def.WriteString("};\n")
+	c.typedefs.WriteString(def.String())
+	return name
+}
+
+// receiverStruct returns the struct type of t, or of the struct t points to.
+func receiverStruct(t types.Type) (*types.Struct, bool) {
+	t = types.Prune(t)
+	if p, ok := t.(*types.Pointer); ok {
+		t = types.Prune(p.Elem)
+	}
+	s, ok := t.(*types.Struct)
+	return s, ok
+}
+
+func methodKey(s *types.Struct, name string) string {
+	return s.Name + "." + name
+}
+
+// declareMethod registers the C function for a method, which takes the
+// receiver as its first argument, so that it can be called anywhere.
+func (c *CGen) declareMethod(ms *ast.MethodStatement) {
+	fi := c.newFuncInfo("", ms.Function)
+	if fi.sig == nil {
+		return
+	}
+	s, ok := receiverStruct(fi.sig.Params[0])
+	if !ok {
+		return
+	}
+	fi.cname = c.uniqueName(cIdent(s.Name) + "_" + ms.Name.Value)
+	fi.name = s.Name + "." + ms.Name.Value
+	c.methods[methodKey(s, ms.Name.Value)] = fi
+	c.topFuncs[ms.Function] = fi
+}
+
+// method returns the method that se names and the C function for it, if se
+// selects a method rather than a field.
+func (c *CGen) method(se *ast.SelectorExpression) (*types.Method, *funcInfo) {
+	s, ok := receiverStruct(c.typeOf(se.X))
+	if !ok {
+		return nil, nil
+	}
+	m, ok := s.Methods[se.Sel.Value]
+	if !ok {
+		return nil, nil
+	}
+	return m, c.methods[methodKey(s, se.Sel.Value)]
+}
+
+func (c *CGen) isPointer(expr ast.Expression) bool {
+	_, ok := types.Prune(c.typeOf(expr)).(*types.Pointer)
+	return ok
+}
+
+// genMethodCall emits x.m(args) as a call with the receiver first: the
+// address of x for a pointer receiver and a copy of x for a value receiver.
+// A nil pointer is caught at the call, as in the evaluator.
+func (c *CGen) genMethodCall(ce *ast.CallExpression, se *ast.SelectorExpression, m *types.Method, fi *funcInfo) {
+	c.out.WriteString(fi.cname + "(")
+	if c.tracing {
+		c.out.WriteString(position(ce) + ", ")
+	}
+	c.genReceiver(se, m)
+	for _, arg := range ce.Arguments {
+		c.out.WriteString(", ")
+		c.genExpression(arg)
+	}
+	c.out.WriteString(")")
+}
+
+// genReceiver emits the receiver that x.m passes to the method m.
+func (c *CGen) genReceiver(se *ast.SelectorExpression, m *types.Method) {
+	switch {
+	case m.PointerReceiver && c.isPointer(se.X):
+		c.genDeref(se.X, se)
+	case m.PointerReceiver:
+		c.genAddress(se.X)
+	case c.isPointer(se.X):
+		c.out.WriteString("(*")
+		c.genDeref(se.X, se)
+		c.out.WriteString(")")
+	default:
+		c.genExpression(se.X)
+	}
+}
+
+// genDeref emits the pointer x, checked not to be nil where node uses it.
+func (c *CGen) genDeref(x ast.Expression, node ast.Node) {
+	c.require(structRuntime)
+	c.out.WriteString("((" + c.cType(c.typeOf(x)) + ")golite_deref(")
+	c.genExpression(x)
+	c.out.WriteString(", " + position(node) + "))")
+}
+
+func (c *CGen) genSelector(se *ast.SelectorExpression) {
+	field := cIdent(se.Sel.Value)
+	if c.isPointer(se.X) {
+		c.out.WriteString("(")
+		c.genDeref(se.X, se)
+		c.out.WriteString("->" + field + ")")
+		return
+	}
+	c.out.WriteString("(")
+	c.genExpression(se.X)
+	c.out.WriteString(")." + field)
+}
+
+// genStructLiteral emits a compound literal with designated initializers,
+// which leave the omitted fields zero.
+func (c *CGen) genStructLiteral(sl *ast.StructLiteral) {
+	s, _ := c.typeOf(sl).(*types.Struct)
+	if s == nil || len(sl.Values) == 0 {
+		c.out.WriteString("((" + c.cType(c.typeOf(sl)) + "){ 0 })")
+		return
+	}
+	c.out.WriteString("((" + c.structType(s) + "){ ")
+	for i, value := range sl.Values {
+		if i > 0 {
+			c.out.WriteString(", ")
+		}
+		name := s.Fields[i].Name
+		if sl.Fields != nil {
+			name = sl.Fields[i].Value
+		}
+		c.out.WriteString("." + cIdent(name) + " = ")
+		c.genExpression(value)
+	}
+	c.out.WriteString(" })")
+}
+
+// genAddress emits &x. Variables whose address is taken live on the heap
+// (see addressTaken), as do the copies made for &T{...}, so the pointer
+// stays valid for as long as it is used.
+func (c *CGen) genAddress(x ast.Expression) {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		if b := c.scope.resolve(x.Value); b != nil && b.fn == nil {
+			c.out.WriteString(b.ptr)
+			return
+		}
+	case *ast.StructLiteral:
+		c.require(structRuntime)
+		ctype := c.cType(c.typeOf(x))
+		c.out.WriteString("((" + ctype + " *)golite_new(sizeof(" + ctype + "), (" + ctype + "[]){ ")
+		c.genExpression(x)
+		c.out.WriteString(" }))")
+		return
+	}
+	c.out.WriteString("(&")
+	c.genExpression(x)
+	c.out.WriteString(")")
+}
+
+// addressTaken returns the names of the variables whose address a program
+// takes, with & or by calling a pointer method. C would otherwise keep them
+// in a block that may end while the pointer is in use, and would reuse the
+// same storage on every iteration of a loop, so they are allocated on the
+// heap instead. Names are not resolved, so any variable with such a name is
+// moved, which is safe if wasteful.
+func (c *CGen) addressTaken(program *ast.Program) map[string]bool {
+	taken := map[string]bool{}
+	ast.Inspect(program, func(n ast.Node) bool {
+		var x ast.Expression
+		switch n := n.(type) {
+		case *ast.PrefixExpression:
+			if n.Operator == "&" {
+				x = n.Right
+			}
+		case *ast.CallExpression:
+			if se, ok := n.Function.(*ast.SelectorExpression); ok {
+				if m, _ := c.method(se); m != nil && m.PointerReceiver {
+					x = se.X
+				}
+			}
+		}
+		if name := c.addressRoot(x); name != "" {
+			taken[name] = true
+		}
+		return true
+	})
+	return taken
+}
+
+// addressRoot returns the variable that holds the storage x denotes, or ""
+// if x is stored elsewhere, such as in a slice or behind a pointer.
+func (c *CGen) addressRoot(x ast.Expression) string {
+	if x == nil || c.isPointer(x) {
+		return ""
+	}
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return x.Value
+	case *ast.SelectorExpression:
+		return c.addressRoot(x.X)
+	}
+	return ""
+}
+
+// livesOnHeap reports whether the variable name, of type t, is allocated on
+// the heap. Only structs can have their address taken.
+func (c *CGen) livesOnHeap(name string, t types.Type) bool {
+	_, ok := types.Prune(t).(*types.Struct)
+	return ok && c.onHeap[name]
+}
+
+// heapVar returns the binding of a variable that lives on the heap, behind
+// the C pointer cname.
+func heapVar(cname, ctype string) *binding {
+	return &binding{cexpr: "(*" + cname + ")", ptr: cname, ctype: ctype}
+}
+
+// structWriter returns the name of a C function that writes structs of type
+// t the way print does, e.g. {1 2}, generating it on first use.
+func (c *CGen) structWriter(t *types.Struct) string {
+	key := t.String()
+	if name, ok := c.writers[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("golite_write_struct_%d", len(c.writers)+1)
+	c.writers[key] = name
+
+	var fields strings.Builder
+	for i, f := range t.Fields {
+		if i > 0 {
+			fields.WriteString("    putchar(' ');\n")
+		}
+		fields.WriteString("    " + c.writeStatement("v."+cIdent(f.Name), f.Type) + "\n")
+	}
+	fmt.Fprintf(&c.helpers, `static void %s(%s v) {
+    putchar('{');
+%s    putchar('}');
+}
+
+`, name, c.structType(t), fields.String())
+	return name
+}
+
+// pointerWriter returns the name of a C function that writes pointers of
+// type t the way print does, e.g. &{1 2}, generating it on first use.
+// Pointers inside other values are written as addresses instead, as in Go.
+func (c *CGen) pointerWriter(t *types.Pointer) string {
+	key := types.Resolve(t).String()
+	if name, ok := c.writers[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("golite_write_pointer_%d", len(c.writers)+1)
+	c.writers[key] = name
+
+	write := c.writeStatement("*p", t.Elem)
+	fmt.Fprintf(&c.helpers, `static void %s(%s p) {
+    if (p == NULL) {
+        fputs("<nil>", stdout);
+        return;
+    }
+    putchar('&');
+    %s
+}
+
+`, name, c.cType(t), write)
+	return name
+}
@@ -0,0 +1,188 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// tupleType returns the name of the C struct that holds the values of a
+// function returning several of them, emitting its typedef on first use.
+// Its fields are named v0, v1, and so on. Tuples are told apart by their C
+// fields, so that a call of a generic function, whose result has types the
+// checker left unbound, and the function itself agree on the struct.
+func (c *CGen) tupleType(t *types.Tuple) string {
+	var fields strings.Builder
+	for i, e := range t.Elems {
+		fmt.Fprintf(&fields, "    %s v%d;\n", c.cType(e), i)
+	}
+	key := fields.String()
+	if name, ok := c.tuples[key]; ok {
+		return name
+	}
+	name := c.uniqueName(fmt.Sprintf("golite_tuple_%d", len(c.tuples)+1))
+	c.tuples[key] = name
+	c.typedefs.WriteString("typedef struct {\n" + fields.String() + "} " + name + ";\n")
+	return name
+}
+
+func (c *CGen) genTupleExpression(te *ast.TupleExpression) {
+	c.out.WriteString("((" + c.cType(c.typeOf(te)) + "){ ")
+	for i, el := range te.Elements {
+		if i > 0 {
+			c.out.WriteString(", ")
+		}
+		c.genExpression(el)
+	}
+	c.out.WriteString(" })")
+}
+
+func isBlank(expr ast.Expression) bool {
+	ident, ok := expr.(*ast.Identifier)
+	return ok && ident.Value == "_"
+}
+
+func isTuple(expr ast.Expression) bool {
+	_, ok := expr.(*ast.TupleExpression)
+	return ok
+}
+
+// isMultiValue reports whether stmt declares or assigns several values.
+func isMultiValue(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		return len(s.Rest) > 0 || isTuple(s.Value)
+	case *ast.AssignStatement:
+		return isTuple(s.Target) || isTuple(s.Value)
+	}
+	return false
+}
+
+// genValues emits temporaries holding the n values that value provides and
+// returns C expressions for them. Holding them first means that, as in the
+// evaluator, every value is computed before any variable changes.
+func (c *CGen) genValues(value ast.Expression, n int, level int) []string {
+	if ie, ok := value.(*ast.IndexExpression); ok && n == 2 {
+		if m, ok := c.mapType(ie.Left); ok {
+			v, found := c.uniqueName("golite_value"), c.uniqueName("golite_found")
+			ctype := c.cType(m.Value)
+			c.writeIndent(level)
+			c.out.WriteString(ctype + " " + v + ";\n")
+			c.writeIndent(level)
+			c.out.WriteString("bool " + found + " = golite_map_lookup(")
+			c.genExpression(ie.Left)
+			c.out.WriteString(", ")
+			c.genMapKey(m, ie.Index)
+			c.out.WriteString(", &" + v + ", sizeof(" + ctype + "));\n")
+			return []string{v, found}
+		}
+	}
+	tmp := c.uniqueName("golite_values")
+	c.writeIndent(level)
+	c.out.WriteString(c.cType(c.typeOf(value)) + " " + tmp + " = ")
+	c.genExpression(value)
+	c.out.WriteString(";\n")
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("%s.v%d", tmp, i)
+	}
+	return values
+}
+
+// valueTypes returns the types of the n values that value provides.
+func (c *CGen) valueTypes(value ast.Expression, n int) []types.Type {
+	if ie, ok := value.(*ast.IndexExpression); ok && n == 2 {
+		if m, ok := c.mapType(ie.Left); ok {
+			return []types.Type{m.Value, types.Bool}
+		}
+	}
+	if tuple, ok := c.typeOf(value).(*types.Tuple); ok && len(tuple.Elems) == n {
+		return tuple.Elems
+	}
+	return []types.Type{c.typeOf(value)}
+}
+
+// genLetValues emits a let statement that declares several variables, as
+// in q, r := divmod(a, b) or v, ok := m[k].
+func (c *CGen) genLetValues(s *ast.LetStatement, level int) {
+	names := s.Names()
+	elemTypes := c.valueTypes(s.Value, len(names))
+	values := c.genValues(s.Value, len(names), level)
+	for i, name := range names {
+		if name.Value == "_" {
+			continue
+		}
+		b := c.declare(name.Value, elemTypes[i], level)
+		c.writeIndent(level)
+		c.out.WriteString(b.cexpr + " = " + values[i] + ";\n")
+	}
+}
+
+// genAssignValues emits an assignment to several targets, as in
+// a, b = b, a, in a block of its own that holds the values.
+func (c *CGen) genAssignValues(s *ast.AssignStatement, level int) {
+	targets := []ast.Expression{s.Target}
+	if te, ok := s.Target.(*ast.TupleExpression); ok {
+		targets = te.Elements
+	}
+	c.writeIndent(level)
+	c.out.WriteString("{\n")
+	values := c.genValues(s.Value, len(targets), level+1)
+	for i, target := range targets {
+		if isBlank(target) {
+			continue
+		}
+		c.writeIndent(level + 1)
+		c.genStore(target, values[i])
+		c.out.WriteString(";\n")
+	}
+	c.writeIndent(level)
+	c.out.WriteString("}\n")
+}
+
+// genAssignValuesExpr emits an assignment to several targets as a single
+// expression, for the post clause of a for loop. tmp is a variable declared
+// before the loop that holds the values.
+func (c *CGen) genAssignValuesExpr(s *ast.AssignStatement, tmp string) {
+	te, ok := s.Target.(*ast.TupleExpression)
+	if !ok || !c.isTupleValue(s.Value) {
+		c.errorf(s, "the C backend does not support comma-ok assignments in for clauses")
+		return
+	}
+	c.out.WriteString(tmp + " = ")
+	c.genExpression(s.Value)
+	for i, target := range te.Elements {
+		if isBlank(target) {
+			continue
+		}
+		c.out.WriteString(", ")
+		c.genStore(target, fmt.Sprintf("%s.v%d", tmp, i))
+	}
+}
+
+// isTupleValue reports whether expr is a list of values or a call that
+// returns several values.
+func (c *CGen) isTupleValue(expr ast.Expression) bool {
+	_, ok := c.typeOf(expr).(*types.Tuple)
+	return ok
+}
+
+// genStore emits an assignment of the C expression value to target.
+func (c *CGen) genStore(target ast.Expression, value string) {
+	if ie, ok := target.(*ast.IndexExpression); ok && c.isMap(ie.Left) {
+		c.genMapStore(ie, func() { c.out.WriteString(value) })
+		return
+	}
+	c.genExpression(target)
+	c.out.WriteString(" = " + value)
+}
+
+// genDiscard emits value, which is assigned to the blank identifier, for
+// its effects alone.
+func (c *CGen) genDiscard(value ast.Expression) {
+	c.out.WriteString("(void)(")
+	c.genExpression(value)
+	c.out.WriteString(")")
+}
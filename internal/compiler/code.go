@@ -0,0 +1,247 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Instructions is a sequence of encoded instructions: an opcode byte
+// followed by its operands, which are big-endian.
+type Instructions []byte
+
+// String disassembles the instructions, one per line, with their offsets.
+func (ins Instructions) String() string {
+	var out strings.Builder
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, formatInstruction(def, operands))
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func formatInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d", len(operands), len(def.OperandWidths))
+	}
+	parts := []string{def.Name}
+	for _, op := range operands {
+		parts = append(parts, fmt.Sprint(op))
+	}
+	return strings.Join(parts, " ")
+}
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // push constant [index]
+	OpPop
+	OpDup
+	OpSwap // exchange the two values on top of the stack
+	OpTrue
+	OpFalse
+	OpNull
+	OpNil
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+	OpMinus
+	OpNot
+	OpIncrement
+	OpDecrement
+
+	OpJump          // jump to [offset]
+	OpJumpNotTruthy // pop the condition and jump to [offset] if it is false or null
+	OpJumpIfFalse   // jump to [offset] if the top is false, keeping it, or else pop it
+	OpJumpIfTrue    // jump to [offset] if the top is true, keeping it, or else pop it
+
+	OpGetGlobal // push global [index]
+	OpSetGlobal // pop into global [index]
+	OpGetLocal  // push local [slot]
+	OpSetLocal  // pop into local [slot]
+	OpNewCell   // pop a value into a new cell, stored in local [slot]
+	OpGetCell   // push the value in the cell in local [slot]
+	OpSetCell   // pop into the cell in local [slot]
+	OpLoadCell  // push the cell in local [slot] itself, to capture it
+	OpGetFree   // push the value of free variable [index]
+	OpSetFree   // pop into free variable [index]
+	OpLoadFree  // push the cell of free variable [index] itself
+	OpGetBuiltin
+	OpClosure // make a closure of function constant [index] over [n] cells
+
+	OpCall       // call with [argc] arguments
+	OpMethod     // replace a struct, pointer or error with its method [name], selected by [selector], and the receiver
+	OpCallMethod // call what OpMethod pushed with [argc] arguments
+	OpDefer      // defer a call with [argc] arguments, after OpMethod if [method] is 1
+	OpRecover
+	OpReturn
+
+	OpArray  // make a slice of the top [n] values
+	OpMap    // make a map with zero value [zero] of the top [n] key-value pairs
+	OpStruct // push the zero value of struct type [index]
+	OpInitField
+	OpIndex
+	OpIndexOk // push the value that a map holds for a key and whether it is there
+	OpSetIndex
+	OpSlice    // slice with the bounds in [flags]: 1 for low, 2 for high
+	OpField    // replace a struct or pointer with its field [name], selected by [selector]
+	OpSetField // pop a struct or pointer and store the value below it in its field [name]
+	OpAddress
+	OpCopy // copy the struct on top of the stack
+	OpTuple
+	OpUnpack // replace a tuple of [n] values with its elements, the first on top
+	OpPrint
+)
+
+// Definition describes an opcode for encoding and disassembly.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {"OpConstant", []int{2}},
+	OpPop:           {"OpPop", nil},
+	OpDup:           {"OpDup", nil},
+	OpSwap:          {"OpSwap", nil},
+	OpTrue:          {"OpTrue", nil},
+	OpFalse:         {"OpFalse", nil},
+	OpNull:          {"OpNull", nil},
+	OpNil:           {"OpNil", nil},
+	OpAdd:           {"OpAdd", nil},
+	OpSub:           {"OpSub", nil},
+	OpMul:           {"OpMul", nil},
+	OpDiv:           {"OpDiv", nil},
+	OpMod:           {"OpMod", nil},
+	OpBitAnd:        {"OpBitAnd", nil},
+	OpBitOr:         {"OpBitOr", nil},
+	OpBitXor:        {"OpBitXor", nil},
+	OpShl:           {"OpShl", nil},
+	OpShr:           {"OpShr", nil},
+	OpEqual:         {"OpEqual", nil},
+	OpNotEqual:      {"OpNotEqual", nil},
+	OpLess:          {"OpLess", nil},
+	OpLessEqual:     {"OpLessEqual", nil},
+	OpGreater:       {"OpGreater", nil},
+	OpGreaterEqual:  {"OpGreaterEqual", nil},
+	OpMinus:         {"OpMinus", nil},
+	OpNot:           {"OpNot", nil},
+	OpIncrement:     {"OpIncrement", nil},
+	OpDecrement:     {"OpDecrement", nil},
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJumpIfFalse:   {"OpJumpIfFalse", []int{2}},
+	OpJumpIfTrue:    {"OpJumpIfTrue", []int{2}},
+	OpGetGlobal:     {"OpGetGlobal", []int{2}},
+	OpSetGlobal:     {"OpSetGlobal", []int{2}},
+	OpGetLocal:      {"OpGetLocal", []int{2}},
+	OpSetLocal:      {"OpSetLocal", []int{2}},
+	OpNewCell:       {"OpNewCell", []int{2}},
+	OpGetCell:       {"OpGetCell", []int{2}},
+	OpSetCell:       {"OpSetCell", []int{2}},
+	OpLoadCell:      {"OpLoadCell", []int{2}},
+	OpGetFree:       {"OpGetFree", []int{1}},
+	OpSetFree:       {"OpSetFree", []int{1}},
+	OpLoadFree:      {"OpLoadFree", []int{1}},
+	OpGetBuiltin:    {"OpGetBuiltin", []int{1}},
+	OpClosure:       {"OpClosure", []int{2, 1}},
+	OpCall:          {"OpCall", []int{1}},
+	OpMethod:        {"OpMethod", []int{2, 2}},
+	OpCallMethod:    {"OpCallMethod", []int{1}},
+	OpDefer:         {"OpDefer", []int{1, 1}},
+	OpRecover:       {"OpRecover", nil},
+	OpReturn:        {"OpReturn", nil},
+	OpArray:         {"OpArray", []int{2}},
+	OpMap:           {"OpMap", []int{2, 2}},
+	OpStruct:        {"OpStruct", []int{2}},
+	OpInitField:     {"OpInitField", []int{1}},
+	OpIndex:         {"OpIndex", nil},
+	OpIndexOk:       {"OpIndexOk", nil},
+	OpSetIndex:      {"OpSetIndex", nil},
+	OpSlice:         {"OpSlice", []int{1}},
+	OpField:         {"OpField", []int{2, 2}},
+	OpSetField:      {"OpSetField", []int{2, 2}},
+	OpAddress:       {"OpAddress", nil},
+	OpCopy:          {"OpCopy", nil},
+	OpTuple:         {"OpTuple", []int{1}},
+	OpUnpack:        {"OpUnpack", []int{1}},
+	OpPrint:         {"OpPrint", nil},
+}
+
+// Lookup returns the definition of op.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes an instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+	ins := make([]byte, length)
+	ins[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(ins[offset:], uint16(o))
+		case 1:
+			ins[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+	return ins
+}
+
+// ReadOperands decodes the operands of an instruction defined by def,
+// returning them and the number of bytes they take.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
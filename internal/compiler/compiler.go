@@ -0,0 +1,976 @@
+// Package compiler translates a checked program into bytecode for the
+// virtual machine in package vm. Variables are resolved while compiling,
+// to slots in the frame of a function, globals, or cells that closures
+// capture, so that the machine never looks a name up at run time.
+package compiler
+
+import (
+	"math"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/semantics"
+	"golite.dev/mvp/internal/types"
+)
+
+// Bytecode is a compiled program.
+type Bytecode struct {
+	Main      *Function
+	Constants []interface{} // int64, float64, string or *Function
+	Globals   []string      // the names of the global variables, by index
+	Builtins  []string      // the names of the builtins OpGetBuiltin loads, by index
+	Types     []*StructType
+	Zeros     []*Zero // the values of the keys missing from maps, by the index OpMap takes
+}
+
+// Function is a compiled function literal, method or main program.
+type Function struct {
+	Instructions Instructions
+	NumParams    int
+	NumLocals    int   // including the parameters
+	MaxStack     int   // the most values the function pushes above its locals
+	Result       *Zero // returned when a deferred call recovers from a panic
+	Lines        []Line
+	// Callees names the function that each call instruction calls, by its
+	// offset, for stack traces.
+	Callees map[int]string
+}
+
+// Line maps the instructions from Offset up to the next Line to the node
+// they were compiled from, for runtime errors.
+type Line struct {
+	Offset int
+	Span   diagnostics.Span
+}
+
+// SpanAt returns the span of the node that the instruction at offset was
+// compiled from.
+func (f *Function) SpanAt(offset int) diagnostics.Span {
+	var span diagnostics.Span
+	for _, line := range f.Lines {
+		if line.Offset > offset {
+			break
+		}
+		span = line.Span
+	}
+	return span
+}
+
+// StructType is a struct type declared by a type statement.
+type StructType struct {
+	Name    string
+	Decl    *ast.StructType
+	Fields  []string
+	Zeros   []*Zero // the zero value of each field
+	Methods map[string]*Method
+}
+
+// Method is a method of a struct type, compiled as a function that takes
+// the receiver as its first argument.
+type Method struct {
+	Function        *Function
+	PointerReceiver bool
+}
+
+type ZeroKind int
+
+const (
+	ZeroNull ZeroKind = iota
+	ZeroInt
+	ZeroFloat
+	ZeroBool
+	ZeroString
+	ZeroError
+	ZeroStruct  // of Types[Type]
+	ZeroPointer // the nil pointer
+	ZeroSlice
+	ZeroMap // a nil map whose missing keys read as Elems[0]
+	ZeroTuple
+)
+
+// Zero describes the zero value of a type, which the machine builds anew
+// each time it needs one.
+type Zero struct {
+	Kind  ZeroKind
+	Type  int
+	Elems []*Zero
+}
+
+// Compiler compiles a program to bytecode.
+type Compiler struct {
+	errors  []*diagnostics.Diagnostic
+	checker *semantics.Checker // source of the static types that decide where structs are copied
+
+	constants []interface{}
+	constant  map[interface{}]int
+	globals   map[string]*Symbol
+	bytecode  *Bytecode
+	builtins  map[string]int
+	structs   map[string]int
+
+	fn *funcState
+}
+
+// funcState is the state of the function being compiled.
+type funcState struct {
+	outer    *funcState
+	fn       *Function
+	scope    *scope
+	captured map[string]bool
+	free     []*Symbol // the variables of outer that the function captures
+	freeSyms map[string]*Symbol
+	loops    []*loop
+	lastSpan diagnostics.Span
+	depth    int // the values on the stack, counting those of every branch
+}
+
+// loop holds the jumps of the break and continue statements of a for
+// statement, to be patched once their targets are known.
+type loop struct {
+	breaks    []int
+	continues []int
+}
+
+// New creates a new compiler.
+func New() *Compiler {
+	return &Compiler{}
+}
+
+// Errors returns the diagnostics of the program that kept it from compiling.
+func (c *Compiler) Errors() []*diagnostics.Diagnostic {
+	return c.errors
+}
+
+// Compile type-checks program, since the compiler needs to know where
+// structs are copied, and compiles it. If checking or compiling fails,
+// Compile returns nil and Errors reports why.
+func (c *Compiler) Compile(program *ast.Program) *Bytecode {
+	c.checker = semantics.New()
+	c.checker.Check(program)
+	if len(c.checker.Errors()) > 0 {
+		c.errors = append(c.errors, c.checker.Errors()...)
+		return nil
+	}
+
+	c.bytecode = &Bytecode{}
+	c.constant = map[interface{}]int{}
+	c.globals = map[string]*Symbol{}
+	c.builtins = map[string]int{}
+	c.structs = map[string]int{}
+
+	main := &funcState{fn: &Function{Callees: map[int]string{}}, captured: capturedNames(program)}
+	c.fn = main
+	for _, name := range declaredNames(program) {
+		c.globals[name] = &Symbol{Name: name, Scope: GlobalScope, Index: len(c.bytecode.Globals)}
+		c.bytecode.Globals = append(c.bytecode.Globals, name)
+	}
+	c.declareTypes(program)
+
+	for _, stmt := range program.Statements {
+		c.compileStatement(stmt)
+	}
+	c.emit(OpNull)
+	c.emit(OpReturn)
+
+	if len(c.constants) > math.MaxUint16+1 || len(c.bytecode.Globals) > math.MaxUint16+1 {
+		c.errors = append(c.errors, diagnostics.Errorf(diagnostics.Unsupported, diagnostics.SpanOf(program),
+			"program too large for the bytecode compiler"))
+	}
+	if len(c.errors) > 0 {
+		return nil
+	}
+	c.bytecode.Main = main.fn
+	c.bytecode.Constants = c.constants
+	return c.bytecode
+}
+
+func (c *Compiler) errorf(node ast.Node, code diagnostics.Code, format string, args ...interface{}) {
+	c.errors = append(c.errors, diagnostics.Errorf(code, diagnostics.SpanOf(node), format, args...))
+}
+
+// declareTypes compiles the struct types and methods of program before the
+// statements, since they can be used before their declarations.
+func (c *Compiler) declareTypes(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		if ts, ok := stmt.(*ast.TypeStatement); ok {
+			def := &StructType{Name: ts.Name.Value, Decl: ts.Type, Methods: map[string]*Method{}}
+			for _, field := range ts.Type.Fields {
+				for _, name := range field.Names {
+					def.Fields = append(def.Fields, name.Value)
+				}
+			}
+			c.structs[def.Name] = len(c.bytecode.Types)
+			c.bytecode.Types = append(c.bytecode.Types, def)
+		}
+	}
+	for _, def := range c.bytecode.Types {
+		for _, field := range def.Decl.Fields {
+			for range field.Names {
+				def.Zeros = append(def.Zeros, c.zero(field.Type))
+			}
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		ms, ok := stmt.(*ast.MethodStatement)
+		if !ok {
+			continue
+		}
+		_, recvType := ms.Receiver()
+		pt, pointer := recvType.(*ast.PointerType)
+		if pointer {
+			recvType = pt.Elem
+		}
+		nt, ok := recvType.(*ast.NamedType)
+		if !ok {
+			continue
+		}
+		if index, ok := c.structs[nt.Name]; ok {
+			fn, _ := c.compileFunction(ms.Function)
+			c.bytecode.Types[index].Methods[ms.Name.Value] = &Method{Function: fn, PointerReceiver: pointer}
+		}
+	}
+}
+
+// zero describes the zero value of type t.
+func (c *Compiler) zero(t ast.TypeExpr) *Zero {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		switch t.Name {
+		case "int":
+			return &Zero{Kind: ZeroInt}
+		case "float":
+			return &Zero{Kind: ZeroFloat}
+		case "bool":
+			return &Zero{Kind: ZeroBool}
+		case "string":
+			return &Zero{Kind: ZeroString}
+		case "error":
+			return &Zero{Kind: ZeroError}
+		}
+		if index, ok := c.structs[t.Name]; ok {
+			return &Zero{Kind: ZeroStruct, Type: index}
+		}
+	case *ast.PointerType:
+		return &Zero{Kind: ZeroPointer}
+	case *ast.SliceType:
+		return &Zero{Kind: ZeroSlice}
+	case *ast.MapType:
+		return &Zero{Kind: ZeroMap, Elems: []*Zero{c.zero(t.Value)}}
+	case *ast.TupleType:
+		tuple := &Zero{Kind: ZeroTuple}
+		for _, e := range t.Elems {
+			tuple.Elems = append(tuple.Elems, c.zero(e))
+		}
+		return tuple
+	}
+	return &Zero{Kind: ZeroNull}
+}
+
+// emit appends an instruction to the current function and returns its
+// offset.
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	pos := len(c.fn.fn.Instructions)
+	c.fn.fn.Instructions = append(c.fn.fn.Instructions, Make(op, operands...)...)
+	c.fn.depth += stackEffect(op, operands)
+	if c.fn.depth > c.fn.fn.MaxStack {
+		c.fn.fn.MaxStack = c.fn.depth
+	}
+	return pos
+}
+
+// stackEffect returns how many values an instruction adds to the stack,
+// or removes if negative. Since the code of both branches of an if counts,
+// the depth it adds up to overestimates what the function needs, which is
+// safe.
+func stackEffect(op Opcode, operands []int) int {
+	switch op {
+	case OpConstant, OpDup, OpTrue, OpFalse, OpNull, OpNil, OpGetGlobal, OpGetLocal, OpGetCell,
+		OpLoadCell, OpGetFree, OpLoadFree, OpGetBuiltin, OpMethod, OpRecover, OpStruct:
+		return 1
+	case OpPop, OpAdd, OpSub, OpMul, OpDiv, OpMod, OpBitAnd, OpBitOr, OpBitXor, OpShl, OpShr,
+		OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual,
+		OpJumpNotTruthy, OpJumpIfFalse, OpJumpIfTrue, OpSetGlobal, OpSetLocal, OpNewCell,
+		OpSetCell, OpSetFree, OpReturn, OpInitField, OpIndex, OpPrint:
+		return -1
+	case OpSetField:
+		return -2
+	case OpSetIndex:
+		return -3
+	case OpClosure:
+		return 1 - operands[1]
+	case OpCall:
+		return -operands[0]
+	case OpCallMethod:
+		return -operands[0] - 1
+	case OpDefer:
+		return -operands[0] - 1 - operands[1]
+	case OpArray, OpTuple:
+		return 1 - operands[0]
+	case OpMap:
+		return 1 - 2*operands[1]
+	case OpSlice:
+		return -(operands[0] & 1) - (operands[0] >> 1)
+	case OpUnpack:
+		return operands[0] - 1
+	}
+	return 0
+}
+
+// emitAt emits an instruction that can fail, recording node as the place
+// to report it.
+func (c *Compiler) emitAt(node ast.Node, op Opcode, operands ...int) int {
+	span := diagnostics.SpanOf(node)
+	if span != c.fn.lastSpan || len(c.fn.fn.Lines) == 0 {
+		c.fn.fn.Lines = append(c.fn.fn.Lines, Line{Offset: len(c.fn.fn.Instructions), Span: span})
+		c.fn.lastSpan = span
+	}
+	return c.emit(op, operands...)
+}
+
+// patch sets the target of the jump at offset to the current offset.
+func (c *Compiler) patch(offset int) {
+	target := len(c.fn.fn.Instructions)
+	if target > math.MaxUint16 {
+		c.errors = append(c.errors, diagnostics.Errorf(diagnostics.Unsupported, diagnostics.Span{}, "function too large for the bytecode compiler"))
+		return
+	}
+	ins := c.fn.fn.Instructions
+	ins[offset+1] = byte(target >> 8)
+	ins[offset+2] = byte(target)
+}
+
+// addConstant returns the index of a constant, adding it to the pool
+// unless an equal one is already there.
+func (c *Compiler) addConstant(value interface{}) int {
+	key := value
+	if f, ok := value.(float64); ok {
+		// -0.0 and 0.0 are equal as map keys, but not as constants.
+		key = [1]uint64{math.Float64bits(f)}
+	}
+	if _, ok := value.(*Function); !ok {
+		if index, ok := c.constant[key]; ok {
+			return index
+		}
+		c.constant[key] = len(c.constants)
+	}
+	c.constants = append(c.constants, value)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) builtin(name string) int {
+	if index, ok := c.builtins[name]; ok {
+		return index
+	}
+	c.builtins[name] = len(c.bytecode.Builtins)
+	c.bytecode.Builtins = append(c.bytecode.Builtins, name)
+	return c.builtins[name]
+}
+
+// enterScope begins the scope of a function body or for statement, making
+// the cells of the boxed variables that its statements declare.
+func (c *Compiler) enterScope(nodes ...ast.Node) {
+	c.fn.scope = newScope(c.fn.scope)
+	for _, name := range declaredNames(nodes...) {
+		if c.fn.captured[name] {
+			sym := c.newLocal(name)
+			c.fn.scope.pending[name] = sym
+			c.emit(OpNull)
+			c.emit(OpNewCell, sym.Index)
+		}
+	}
+}
+
+func (c *Compiler) leaveScope() {
+	c.fn.scope = c.fn.scope.outer
+}
+
+func (c *Compiler) newLocal(name string) *Symbol {
+	sym := &Symbol{Name: name, Scope: LocalScope, Index: c.fn.fn.NumLocals, Boxed: c.fn.captured[name]}
+	c.fn.fn.NumLocals++
+	return sym
+}
+
+// define declares name in the innermost scope, unless it is declared there
+// already, and returns its symbol.
+func (c *Compiler) define(name string) *Symbol {
+	if c.fn.scope == nil {
+		return c.globals[name]
+	}
+	if sym, ok := c.fn.scope.symbols[name]; ok {
+		return sym
+	}
+	sym, ok := c.fn.scope.pending[name]
+	if !ok {
+		sym = c.newLocal(name)
+		if sym.Boxed {
+			c.emit(OpNull)
+			c.emit(OpNewCell, sym.Index)
+		}
+	}
+	c.fn.scope.symbols[name] = sym
+	return sym
+}
+
+// resolve returns the variable that name denotes in the current function,
+// or nil if it is a builtin or undefined.
+func (c *Compiler) resolve(name string) *Symbol {
+	return c.resolveIn(c.fn, name)
+}
+
+func (c *Compiler) resolveIn(fs *funcState, name string) *Symbol {
+	for s := fs.scope; s != nil; s = s.outer {
+		if sym, ok := s.symbols[name]; ok {
+			return sym
+		}
+	}
+	if fs.outer == nil {
+		return c.globals[name]
+	}
+	if sym, ok := fs.freeSyms[name]; ok {
+		return sym
+	}
+	sym := c.resolveIn(fs.outer, name)
+	if sym == nil || sym.Scope == GlobalScope {
+		return sym
+	}
+	free := &Symbol{Name: name, Scope: FreeScope, Index: len(fs.free), Boxed: true}
+	fs.free = append(fs.free, sym)
+	fs.freeSyms[name] = free
+	return free
+}
+
+// load pushes the value of sym.
+func (c *Compiler) load(node ast.Node, sym *Symbol) {
+	switch {
+	case sym.Scope == GlobalScope:
+		c.emitAt(node, OpGetGlobal, sym.Index)
+	case sym.Scope == FreeScope:
+		c.emit(OpGetFree, sym.Index)
+	case sym.Boxed:
+		c.emit(OpGetCell, sym.Index)
+	default:
+		c.emit(OpGetLocal, sym.Index)
+	}
+}
+
+// store pops the value on top of the stack into sym.
+func (c *Compiler) store(sym *Symbol) {
+	switch {
+	case sym.Scope == GlobalScope:
+		c.emit(OpSetGlobal, sym.Index)
+	case sym.Scope == FreeScope:
+		c.emit(OpSetFree, sym.Index)
+	case sym.Boxed:
+		c.emit(OpSetCell, sym.Index)
+	default:
+		c.emit(OpSetLocal, sym.Index)
+	}
+}
+
+// compileFunction compiles a function literal in a new function state
+// nested in the current one, and returns it with the variables it
+// captures.
+func (c *Compiler) compileFunction(fl *ast.FunctionLiteral) (*Function, []*Symbol) {
+	fs := &funcState{
+		outer:    c.fn,
+		fn:       &Function{NumParams: len(fl.Parameters), Result: c.zero(fl.ReturnType), Callees: map[int]string{}},
+		captured: capturedNames(fl.Body),
+		freeSyms: map[string]*Symbol{},
+	}
+	c.fn = fs
+	fs.scope = newScope(nil)
+	for _, param := range fl.Parameters {
+		sym := c.newLocal(param.Value)
+		fs.scope.symbols[param.Value] = sym
+		if sym.Boxed {
+			c.emit(OpGetLocal, sym.Index)
+			c.emit(OpNewCell, sym.Index)
+		}
+	}
+	c.enterScope(fl.Body)
+	c.compileBlock(fl.Body, true)
+	c.emit(OpReturn)
+	c.fn = fs.outer
+	if len(fs.fn.Instructions) > math.MaxUint16 {
+		c.errorf(fl, diagnostics.Unsupported, "function too large for the bytecode compiler")
+	}
+	return fs.fn, fs.free
+}
+
+// compileBlock compiles the statements of block. If value is set, the code
+// leaves the value of the block on the stack, which is the value of its
+// last statement, as in the evaluator.
+func (c *Compiler) compileBlock(block *ast.BlockStatement, value bool) {
+	if block == nil || len(block.Statements) == 0 {
+		if value {
+			c.emit(OpNull)
+		}
+		return
+	}
+	last := len(block.Statements) - 1
+	for _, stmt := range block.Statements[:last] {
+		c.compileStatement(stmt)
+	}
+	if !value {
+		c.compileStatement(block.Statements[last])
+		return
+	}
+	switch stmt := block.Statements[last].(type) {
+	case *ast.ExpressionStatement:
+		c.compileExpression(stmt.Expression)
+	case *ast.PrintStatement:
+		c.compileExpression(stmt.Expression)
+		c.emit(OpDup)
+		c.emit(OpPrint)
+	case *ast.ReturnStatement:
+		c.compileStatement(stmt)
+	default:
+		c.compileStatement(stmt)
+		c.emit(OpNull)
+	}
+}
+
+func (c *Compiler) compileStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+			c.compileIf(ie, false)
+			return
+		}
+		c.compileExpression(stmt.Expression)
+		c.emit(OpPop)
+	case *ast.PrintStatement:
+		c.compileExpression(stmt.Expression)
+		c.emit(OpPrint)
+	case *ast.LetStatement:
+		c.compileLet(stmt)
+	case *ast.ReturnStatement:
+		switch value := stmt.ReturnValue.(type) {
+		case nil:
+			c.emit(OpNull)
+		default:
+			c.compileExpression(value)
+		}
+		c.emit(OpReturn)
+	case *ast.AssignStatement:
+		c.compileAssign(stmt)
+	case *ast.IncDecStatement:
+		c.compileExpression(stmt.Target)
+		if stmt.Operator == "++" {
+			c.emitAt(stmt, OpIncrement)
+		} else {
+			c.emitAt(stmt, OpDecrement)
+		}
+		c.assign(stmt.Target, stmt)
+	case *ast.ForStatement:
+		c.compileFor(stmt)
+	case *ast.BreakStatement:
+		if n := len(c.fn.loops); n > 0 {
+			l := c.fn.loops[n-1]
+			l.breaks = append(l.breaks, c.emit(OpJump, 0))
+		}
+	case *ast.ContinueStatement:
+		if n := len(c.fn.loops); n > 0 {
+			l := c.fn.loops[n-1]
+			l.continues = append(l.continues, c.emit(OpJump, 0))
+		}
+	case *ast.DeferStatement:
+		method := c.compileCallee(stmt.Call, stmt)
+		for _, arg := range stmt.Call.Arguments {
+			c.compileExpression(arg)
+		}
+		flag := 0
+		if method {
+			flag = 1
+		}
+		pos := c.emitAt(stmt.Call, OpDefer, len(stmt.Call.Arguments), flag)
+		c.fn.fn.Callees[pos] = calleeName(stmt.Call)
+	case *ast.BlockStatement:
+		c.compileBlock(stmt, false)
+	case *ast.TypeStatement, *ast.MethodStatement:
+		// Compiled by declareTypes before the program.
+	}
+}
+
+func (c *Compiler) compileLet(ls *ast.LetStatement) {
+	if len(ls.Rest) > 0 {
+		names := ls.Names()
+		c.compileValues(ls.Value, len(names), ls)
+		for _, name := range names {
+			if name.Value == "_" {
+				c.emit(OpPop)
+				continue
+			}
+			c.store(c.define(name.Value))
+		}
+		return
+	}
+	if ls.Name.Value == "_" {
+		c.compileExpression(ls.Value)
+		c.emit(OpPop)
+		return
+	}
+	// A function literal can call itself by the name it is bound to, so
+	// the variable must exist for the closure to capture it.
+	if _, ok := ls.Value.(*ast.FunctionLiteral); ok {
+		sym := c.define(ls.Name.Value)
+		c.compileExpression(ls.Value)
+		c.store(sym)
+		return
+	}
+	c.compileExpression(ls.Value)
+	c.store(c.define(ls.Name.Value))
+}
+
+// compileValues pushes the n values that value provides to n variables,
+// the first on top: the elements of a tuple, or the value and found flag
+// of a map index.
+func (c *Compiler) compileValues(value ast.Expression, n int, stmt ast.Node) {
+	if ie, ok := value.(*ast.IndexExpression); ok && n == 2 {
+		c.compileExpression(ie.Left)
+		c.compileExpression(ie.Index)
+		c.emitAt(ie, OpIndexOk)
+		return
+	}
+	c.compileExpression(value)
+	c.emitAt(stmt, OpUnpack, n)
+}
+
+func (c *Compiler) compileAssign(as *ast.AssignStatement) {
+	if te, ok := as.Target.(*ast.TupleExpression); ok {
+		c.compileValues(as.Value, len(te.Elements), as)
+		for _, target := range te.Elements {
+			c.assign(target, as)
+		}
+		return
+	}
+	c.compileExpression(as.Value)
+	if as.Operator != "=" {
+		c.compileExpression(as.Target)
+		c.emit(OpSwap)
+		c.emitAt(as, binaryOps[as.Operator[:len(as.Operator)-1]])
+	}
+	c.assign(as.Target, as)
+}
+
+// assign pops the value on top of the stack into the location target
+// denotes. Errors in doing so are reported at stmt.
+func (c *Compiler) assign(target ast.Expression, stmt ast.Node) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if target.Value == "_" {
+			c.emit(OpPop)
+			return
+		}
+		sym := c.resolve(target.Value)
+		if sym == nil {
+			c.errorf(target, diagnostics.UndefinedName, "identifier not found: %s", target.Value)
+			return
+		}
+		c.store(sym)
+	case *ast.SelectorExpression:
+		c.compileRef(target.X)
+		c.emitAt(stmt, OpSetField, c.addConstant(target.Sel.Value), c.addConstant(target.String()))
+	case *ast.IndexExpression:
+		c.compileExpression(target.Left)
+		c.compileExpression(target.Index)
+		c.emitAt(stmt, OpSetIndex)
+	default:
+		c.errorf(target, diagnostics.InvalidAssignTarget, "cannot assign to %s", target.String())
+	}
+}
+
+func (c *Compiler) compileFor(fs *ast.ForStatement) {
+	c.enterScope(fs)
+	defer c.leaveScope()
+	if fs.Init != nil {
+		c.compileStatement(fs.Init)
+	}
+
+	start := len(c.fn.fn.Instructions)
+	exit := -1
+	if fs.Condition != nil {
+		c.compileExpression(fs.Condition)
+		exit = c.emit(OpJumpNotTruthy, 0)
+	}
+
+	l := &loop{}
+	c.fn.loops = append(c.fn.loops, l)
+	c.compileBlock(fs.Body, false)
+	c.fn.loops = c.fn.loops[:len(c.fn.loops)-1]
+
+	for _, pos := range l.continues {
+		c.patch(pos)
+	}
+	if fs.Post != nil {
+		c.compileStatement(fs.Post)
+	}
+	c.emit(OpJump, start)
+	if exit >= 0 {
+		c.patch(exit)
+	}
+	for _, pos := range l.breaks {
+		c.patch(pos)
+	}
+}
+
+// compileIf compiles an if expression, which leaves its value on the stack
+// if value is set.
+func (c *Compiler) compileIf(ie *ast.IfExpression, value bool) {
+	c.compileExpression(ie.Condition)
+	skip := c.emit(OpJumpNotTruthy, 0)
+	c.compileBlock(ie.Consequence, value)
+	if ie.Alternative == nil && !value {
+		c.patch(skip)
+		return
+	}
+	end := c.emit(OpJump, 0)
+	c.patch(skip)
+	if ie.Alternative != nil {
+		c.compileBlock(ie.Alternative, value)
+	} else {
+		c.emit(OpNull)
+	}
+	c.patch(end)
+}
+
+var binaryOps = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"%":  OpMod,
+	"&":  OpBitAnd,
+	"|":  OpBitOr,
+	"^":  OpBitXor,
+	"<<": OpShl,
+	">>": OpShr,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	"<=": OpLessEqual,
+	">":  OpGreater,
+	">=": OpGreaterEqual,
+}
+
+func (c *Compiler) compileExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(OpConstant, c.addConstant(expr.Value))
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.addConstant(expr.Value))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.addConstant(expr.Value))
+	case *ast.Boolean:
+		if expr.Value {
+			c.emit(OpTrue)
+		} else {
+			c.emit(OpFalse)
+		}
+	case *ast.Identifier:
+		c.compileRef(expr)
+		c.copyStruct(expr)
+	case *ast.PrefixExpression:
+		switch expr.Operator {
+		case "&":
+			c.compileRef(expr.Right)
+			c.emitAt(expr, OpAddress)
+		case "!":
+			c.compileExpression(expr.Right)
+			c.emit(OpNot)
+		default:
+			c.compileExpression(expr.Right)
+			c.emitAt(expr, OpMinus)
+		}
+	case *ast.InfixExpression:
+		c.compileExpression(expr.Left)
+		switch expr.Operator {
+		case "&&", "||":
+			// The right operand only runs if the left one does not
+			// decide the result.
+			op := OpJumpIfFalse
+			if expr.Operator == "||" {
+				op = OpJumpIfTrue
+			}
+			end := c.emit(op, 0)
+			c.compileExpression(expr.Right)
+			c.patch(end)
+		default:
+			c.compileExpression(expr.Right)
+			op, ok := binaryOps[expr.Operator]
+			if !ok {
+				c.errorf(expr, diagnostics.UnknownOperator, "unknown operator: %s", expr.Operator)
+				return
+			}
+			c.emitAt(expr, op)
+		}
+	case *ast.IfExpression:
+		c.compileIf(expr, true)
+	case *ast.FunctionLiteral:
+		fn, free := c.compileFunction(expr)
+		for _, sym := range free {
+			if sym.Scope == FreeScope {
+				c.emit(OpLoadFree, sym.Index)
+			} else {
+				c.emit(OpLoadCell, sym.Index)
+			}
+		}
+		c.emit(OpClosure, c.addConstant(fn), len(free))
+	case *ast.CallExpression:
+		c.compileCall(expr)
+	case *ast.ArrayLiteral:
+		for _, el := range expr.Elements {
+			c.compileExpression(el)
+		}
+		c.emit(OpArray, len(expr.Elements))
+	case *ast.MapLiteral:
+		for i, key := range expr.Keys {
+			c.compileExpression(key)
+			c.compileExpression(expr.Values[i])
+		}
+		c.bytecode.Zeros = append(c.bytecode.Zeros, c.zero(expr.Type.Value))
+		c.emitAt(expr, OpMap, len(c.bytecode.Zeros)-1, len(expr.Keys))
+	case *ast.StructLiteral:
+		c.compileStructLiteral(expr)
+	case *ast.SelectorExpression, *ast.IndexExpression:
+		c.compileRef(expr)
+		c.copyStruct(expr)
+	case *ast.SliceExpression:
+		c.compileExpression(expr.Left)
+		flags := 0
+		if expr.Low != nil {
+			c.compileExpression(expr.Low)
+			flags |= 1
+		}
+		if expr.High != nil {
+			c.compileExpression(expr.High)
+			flags |= 2
+		}
+		c.emitAt(expr, OpSlice, flags)
+	case *ast.TupleExpression:
+		for _, el := range expr.Elements {
+			c.compileExpression(el)
+		}
+		c.emit(OpTuple, len(expr.Elements))
+	default:
+		c.errorf(expr, diagnostics.Unsupported, "the bytecode compiler does not support %s", expr.String())
+	}
+}
+
+// compileRef pushes the value expr denotes without copying the struct it
+// may be, so that its fields can be assigned, its address taken or its
+// methods called.
+func (c *Compiler) compileRef(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		if sym := c.resolve(expr.Value); sym != nil {
+			c.load(expr, sym)
+			return
+		}
+		if _, ok := builtins.Lookup(expr.Value); ok {
+			c.emit(OpGetBuiltin, c.builtin(expr.Value))
+			return
+		}
+		if expr.Value == "nil" {
+			c.emit(OpNil)
+			return
+		}
+		c.errorf(expr, diagnostics.UndefinedName, "identifier not found: %s", expr.Value)
+	case *ast.SelectorExpression:
+		c.compileRef(expr.X)
+		c.emitAt(expr, OpField, c.addConstant(expr.Sel.Value), c.addConstant(expr.String()))
+	case *ast.IndexExpression:
+		c.compileExpression(expr.Left)
+		c.compileExpression(expr.Index)
+		c.emitAt(expr, OpIndex)
+	default:
+		c.compileExpression(expr)
+	}
+}
+
+// copyStruct copies the value on top of the stack, which expr read from a
+// variable, field or element, unless its type shows it is not a struct.
+func (c *Compiler) copyStruct(expr ast.Expression) {
+	switch types.Prune(c.checker.TypeOf(expr)).(type) {
+	case *types.Struct, *types.Var, nil:
+		c.emit(OpCopy)
+	}
+}
+
+func (c *Compiler) compileStructLiteral(sl *ast.StructLiteral) {
+	index, ok := c.structs[sl.Type.Name]
+	if !ok {
+		c.errorf(sl, diagnostics.UndefinedType, "undefined type: %s", sl.Type.Name)
+		return
+	}
+	def := c.bytecode.Types[index]
+	c.emit(OpStruct, index)
+	for i, value := range sl.Values {
+		c.compileExpression(value)
+		field := i
+		if sl.Fields != nil {
+			field = -1
+			for j, name := range def.Fields {
+				if name == sl.Fields[i].Value {
+					field = j
+				}
+			}
+		}
+		if field < 0 || field >= len(def.Fields) {
+			c.errorf(value, diagnostics.UndefinedField, "unknown field in struct literal of type %s", def.Name)
+			return
+		}
+		c.emit(OpInitField, field)
+	}
+}
+
+func (c *Compiler) compileCall(ce *ast.CallExpression) {
+	// recover() stops a panic only when a deferred function calls it
+	// directly, so the call compiles to its own instruction.
+	if ident, ok := ce.Function.(*ast.Identifier); ok && ident.Value == "recover" && c.resolve("recover") == nil {
+		c.emitAt(ce, OpRecover)
+		return
+	}
+	method := c.compileCallee(ce, ce)
+	for _, arg := range ce.Arguments {
+		c.compileExpression(arg)
+	}
+	var pos int
+	if method {
+		pos = c.emitAt(ce, OpCallMethod, len(ce.Arguments))
+	} else {
+		pos = c.emitAt(ce, OpCall, len(ce.Arguments))
+	}
+	c.fn.fn.Callees[pos] = calleeName(ce)
+}
+
+// compileCallee pushes the function that ce calls. It reports whether it
+// is a method, in which case OpMethod pushed the receiver as well. Errors
+// in finding the method are reported at node.
+func (c *Compiler) compileCallee(ce *ast.CallExpression, node ast.Node) bool {
+	se, ok := ce.Function.(*ast.SelectorExpression)
+	if !ok {
+		c.compileExpression(ce.Function)
+		return false
+	}
+	if pkg, ok := se.X.(*ast.Identifier); ok && c.resolve(pkg.Value) == nil {
+		name := pkg.Value + "." + se.Sel.Value
+		if _, ok := builtins.Lookup(name); ok {
+			c.emit(OpGetBuiltin, c.builtin(name))
+			return false
+		}
+	}
+	c.compileRef(se.X)
+	c.emitAt(node, OpMethod, c.addConstant(se.Sel.Value), c.addConstant(se.String()))
+	return true
+}
+
+// calleeName names the function that ce calls in stack traces.
+func calleeName(ce *ast.CallExpression) string {
+	if _, ok := ce.Function.(*ast.FunctionLiteral); ok {
+		return "func literal"
+	}
+	return ce.Function.String()
+}
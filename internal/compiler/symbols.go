@@ -0,0 +1,94 @@
+package compiler
+
+import "golite.dev/mvp/internal/ast"
+
+type SymbolScope int
+
+const (
+	GlobalScope SymbolScope = iota
+	LocalScope
+	FreeScope
+	BuiltinScope
+)
+
+// Symbol is a variable resolved to where it lives at run time: a global,
+// a slot in the frame of the function, or a cell that a closure captured.
+// A boxed local lives in a cell in its slot, because a closure may capture
+// it.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+	Boxed bool
+}
+
+// scope holds the variables declared by the body of a function or by a for
+// statement, which are the only constructs that begin a scope in GoLite.
+type scope struct {
+	outer   *scope
+	symbols map[string]*Symbol
+	// pending holds the boxed variables the scope declares that do not
+	// exist yet. Their cells are made when the scope is entered, so that a
+	// let repeated by a loop reuses the same variable, as in the evaluator.
+	pending map[string]*Symbol
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, symbols: map[string]*Symbol{}, pending: map[string]*Symbol{}}
+}
+
+// declaredNames returns the names that the statements declare in their own
+// scope, leaving out those of nested for statements and function literals.
+func declaredNames(nodes ...ast.Node) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		root := node
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ForStatement:
+				return n == root
+			case *ast.FunctionLiteral:
+				return false
+			case *ast.LetStatement:
+				for _, name := range n.Names() {
+					if !seen[name.Value] && name.Value != "_" {
+						seen[name.Value] = true
+						names = append(names, name.Value)
+					}
+				}
+			}
+			return true
+		})
+	}
+	return names
+}
+
+// capturedNames returns the names used inside the function literals nested
+// in the statements, which are the variables closures may capture. This
+// overestimates, as a name used in a closure may denote a variable of its
+// own.
+func capturedNames(nodes ...ast.Node) map[string]bool {
+	names := map[string]bool{}
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			if fl, ok := n.(*ast.FunctionLiteral); ok {
+				ast.Inspect(fl.Body, func(n ast.Node) bool {
+					if ident, ok := n.(*ast.Identifier); ok {
+						names[ident.Value] = true
+					}
+					return true
+				})
+				return false
+			}
+			return true
+		})
+	}
+	return names
+}
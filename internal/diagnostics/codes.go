@@ -0,0 +1,57 @@
+package diagnostics
+
+// Code identifies a class of diagnostic. Codes are stable so that tooling can
+// match on them instead of on message text.
+type Code string
+
+// Parser diagnostics.
+const (
+	UnexpectedToken Code = "E0001"
+	MissingPrefix   Code = "E0002"
+	InvalidInteger  Code = "E0003"
+	InvalidString   Code = "E0004"
+	IllegalToken    Code = "E0005"
+	InvalidFloat    Code = "E0006"
+)
+
+// Semantic diagnostics.
+const (
+	UndefinedName    Code = "E0100"
+	TypeMismatch     Code = "E0101"
+	UnknownOperator  Code = "E0102"
+	NonBoolCondition Code = "E0103"
+	NotAFunction     Code = "E0104"
+
+	ReturnOutsideFunction Code = "E0105"
+	MissingReturn         Code = "E0106"
+	BranchOutsideLoop     Code = "E0107"
+	InvalidAssignTarget   Code = "E0108"
+	WrongArgumentCount    Code = "E0109"
+	UndefinedType         Code = "E0110"
+	InvalidBuiltinCall    Code = "E0111"
+	InvalidIndex          Code = "E0112"
+	InvalidMapKey         Code = "E0113"
+	DuplicateKey          Code = "E0114"
+	AssignmentMismatch    Code = "E0115"
+	UndefinedField        Code = "E0116"
+	InvalidDeclaration    Code = "E0117"
+	NotAddressable        Code = "E0118"
+)
+
+// Code generation diagnostics.
+const (
+	Unsupported Code = "E0300"
+)
+
+// Runtime diagnostics.
+const (
+	RuntimeError Code = "E0200"
+	Panic        Code = "E0201"
+)
+
+// Analysis diagnostics. These are warnings: the program may still run.
+const (
+	InfiniteRecursion   Code = "W0400"
+	UnprovenTermination Code = "W0401"
+	InfiniteLoop        Code = "W0402"
+)
@@ -0,0 +1,105 @@
+package diagnostics
+
+import (
+	"fmt"
+
+	"golite.dev/mvp/internal/lexer"
+)
+
+// Severity classifies how serious a diagnostic is.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Span is a half-open range [Start, End) of source text.
+type Span struct {
+	Start lexer.Position
+	End   lexer.Position
+}
+
+// Node is anything that covers a range of source text, such as an ast.Node.
+type Node interface {
+	Pos() lexer.Position
+	End() lexer.Position
+}
+
+// SpanOf returns the span covered by node.
+func SpanOf(node Node) Span {
+	return Span{Start: node.Pos(), End: node.End()}
+}
+
+// SpanOfToken returns the span covered by tok.
+func SpanOfToken(tok lexer.Token) Span {
+	return Span{Start: tok.Pos, End: tok.End}
+}
+
+// Label attaches a message to a secondary span of a diagnostic.
+type Label struct {
+	Span    Span
+	Message string
+}
+
+// Diagnostic is a single message produced by the parser, the semantic checker
+// or the evaluator.
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	Span     Span     // primary location
+	Labels   []Label  // secondary locations
+	Notes    []string // additional free-form remarks
+}
+
+// Errorf creates an error diagnostic.
+func Errorf(code Code, span Span, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Severity: Error, Code: code, Span: span, Message: fmt.Sprintf(format, args...)}
+}
+
+// Warningf creates a warning diagnostic.
+func Warningf(code Code, span Span, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Severity: Warning, Code: code, Span: span, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithLabel adds a secondary label and returns d for chaining.
+func (d *Diagnostic) WithLabel(span Span, format string, args ...interface{}) *Diagnostic {
+	d.Labels = append(d.Labels, Label{Span: span, Message: fmt.Sprintf(format, args...)})
+	return d
+}
+
+// WithNote adds a note and returns d for chaining.
+func (d *Diagnostic) WithNote(format string, args ...interface{}) *Diagnostic {
+	d.Notes = append(d.Notes, fmt.Sprintf(format, args...))
+	return d
+}
+
+// Error implements the error interface with a compact one-line form.
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
+
+// HasErrors reports whether any diagnostic in diags is an error.
+func HasErrors(diags []*Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
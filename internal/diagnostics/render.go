@@ -0,0 +1,245 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golite.dev/mvp/internal/lexer"
+)
+
+const tabWidth = 4
+
+// Renderer formats diagnostics as rustc-style source snippets with carets
+// under the offending text.
+type Renderer struct {
+	Filename string
+	lines    []string
+}
+
+// NewRenderer creates a renderer for diagnostics produced from source. If
+// source is empty, as when a program was loaded from a .glc file, the
+// diagnostics are rendered with their locations but without snippets.
+func NewRenderer(filename, source string) *Renderer {
+	if source == "" {
+		return &Renderer{Filename: filename}
+	}
+	return &Renderer{Filename: filename, lines: strings.Split(source, "\n")}
+}
+
+// annotation is one underlined span within a rendered snippet.
+type annotation struct {
+	span    Span
+	message string
+	primary bool
+}
+
+// RenderAll writes every diagnostic in diags to w, separated by blank lines.
+func (r *Renderer) RenderAll(w io.Writer, diags []*Diagnostic) {
+	for i, d := range diags {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		r.Render(w, d)
+	}
+}
+
+// Render writes a single diagnostic to w.
+func (r *Renderer) Render(w io.Writer, d *Diagnostic) {
+	if d.Code != "" {
+		fmt.Fprintf(w, "%s[%s]: %s\n", d.Severity, d.Code, d.Message)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", d.Severity, d.Message)
+	}
+
+	if !d.Span.Start.IsValid() {
+		fmt.Fprintf(w, " --> %s\n", r.Filename)
+		r.renderNotes(w, d, 1)
+		return
+	}
+	if r.lines == nil {
+		fmt.Fprintf(w, " --> %s:%d:%d\n", r.Filename, d.Span.Start.Line, d.Span.Start.Column)
+		r.renderNotes(w, d, 1)
+		return
+	}
+
+	annotations := []annotation{{span: d.Span, primary: true}}
+	for _, l := range d.Labels {
+		if l.Span.Start.IsValid() {
+			annotations = append(annotations, annotation{span: l.Span, message: l.Message})
+		}
+	}
+
+	byLine := map[int][]annotation{}
+	var lineNumbers []int
+	maxLine := 0
+	for _, a := range annotations {
+		line := a.span.Start.Line
+		if _, seen := byLine[line]; !seen {
+			lineNumbers = append(lineNumbers, line)
+		}
+		byLine[line] = append(byLine[line], a)
+		if line > maxLine {
+			maxLine = line
+		}
+	}
+	sort.Ints(lineNumbers)
+
+	gutter := len(strconv.Itoa(maxLine))
+	pad := strings.Repeat(" ", gutter)
+
+	fmt.Fprintf(w, "%s--> %s:%d:%d\n", pad, r.Filename, d.Span.Start.Line, d.Span.Start.Column)
+	fmt.Fprintf(w, "%s |\n", pad)
+	for i, line := range lineNumbers {
+		if i > 0 && line > lineNumbers[i-1]+1 {
+			fmt.Fprintf(w, "%s...\n", pad)
+		}
+		text := r.line(line)
+		fmt.Fprintf(w, "%*d | %s\n", gutter, line, expandTabs(text))
+		for _, a := range byLine[line] {
+			start, end := r.columns(text, a.span)
+			mark := "-"
+			if a.primary {
+				mark = "^"
+			}
+			underline := strings.Repeat(" ", start) + strings.Repeat(mark, end-start)
+			if a.message != "" {
+				underline += " " + a.message
+			}
+			fmt.Fprintf(w, "%s | %s\n", pad, underline)
+		}
+	}
+	r.renderNotes(w, d, gutter)
+}
+
+func (r *Renderer) renderNotes(w io.Writer, d *Diagnostic, gutter int) {
+	pad := strings.Repeat(" ", gutter)
+	for _, n := range d.Notes {
+		fmt.Fprintf(w, "%s = note: %s\n", pad, n)
+	}
+}
+
+func (r *Renderer) line(n int) string {
+	if n < 1 || n > len(r.lines) {
+		return ""
+	}
+	return strings.TrimRight(r.lines[n-1], "\r")
+}
+
+// columns converts span into a [start, end) range of visual columns on the
+// line containing span.Start, clamping spans that run past the end of the
+// line and widening empty spans to a single column.
+func (r *Renderer) columns(text string, span Span) (int, int) {
+	startCol := span.Start.Column - 1
+	endCol := len(text)
+	if span.End.Line == span.Start.Line && span.End.Column-1 < endCol {
+		endCol = span.End.Column - 1
+	}
+	start := visualColumn(text, startCol)
+	end := visualColumn(text, endCol)
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// visualColumn returns the display column of byte offset col in text once
+// tabs have been expanded. Every other character, however many bytes it
+// takes in UTF-8, is one column wide.
+func visualColumn(text string, col int) int {
+	if col > len(text) {
+		col = len(text)
+	}
+	visual := 0
+	for i := 0; i < col; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\t' {
+			visual += tabWidth - visual%tabWidth
+		} else {
+			visual++
+		}
+		i += size
+	}
+	return visual
+}
+
+func expandTabs(text string) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	var b strings.Builder
+	visual := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\t' {
+			n := tabWidth - visual%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			visual += n
+		} else {
+			b.WriteString(text[i : i+size])
+			visual++
+		}
+		i += size
+	}
+	return b.String()
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+type jsonSpan struct {
+	File  string       `json:"file"`
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonLabel struct {
+	Span    jsonSpan `json:"span"`
+	Message string   `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	Severity string      `json:"severity"`
+	Code     Code        `json:"code,omitempty"`
+	Message  string      `json:"message"`
+	Span     jsonSpan    `json:"span"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Notes    []string    `json:"notes,omitempty"`
+}
+
+// WriteJSON writes diags to w as JSON, one object per line, so that CI
+// tooling can stream and annotate them.
+func WriteJSON(w io.Writer, filename string, diags []*Diagnostic) error {
+	enc := json.NewEncoder(w)
+	for _, d := range diags {
+		jd := jsonDiagnostic{
+			Severity: d.Severity.String(),
+			Code:     d.Code,
+			Message:  d.Message,
+			Span:     toJSONSpan(filename, d.Span),
+			Notes:    d.Notes,
+		}
+		for _, l := range d.Labels {
+			jd.Labels = append(jd.Labels, jsonLabel{Span: toJSONSpan(filename, l.Span), Message: l.Message})
+		}
+		if err := enc.Encode(jd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toJSONSpan(filename string, s Span) jsonSpan {
+	return jsonSpan{File: filename, Start: toJSONPosition(s.Start), End: toJSONPosition(s.End)}
+}
+
+func toJSONPosition(p lexer.Position) jsonPosition {
+	return jsonPosition{Line: p.Line, Column: p.Column, Offset: p.Offset}
+}
@@ -0,0 +1,67 @@
+package evaluator
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/object"
+)
+
+// qualifiedBuiltin returns the builtin that se names, unless a variable in
+// env shadows the name of its package.
+func qualifiedBuiltin(se *ast.SelectorExpression, env *object.Environment) (*object.Builtin, bool) {
+	pkg, ok := se.X.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := env.Get(pkg.Value); ok {
+		return nil, false
+	}
+	b, ok := builtins.Lookup(pkg.Value + "." + se.Sel.Value)
+	if !ok {
+		return nil, false
+	}
+	return &object.Builtin{Fn: b.Eval}, true
+}
+
+// errorMethod returns the method of the error e that se selects. Error is
+// the only one.
+func errorMethod(e *object.ErrorValue, se *ast.SelectorExpression) object.Object {
+	if se.Sel.Value != "Error" {
+		return newError("%s undefined (type error has no field or method %s)", se.String(), se.Sel.Value)
+	}
+	if e.Message == nil {
+		return newError("invalid memory address or nil pointer dereference")
+	}
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return &object.String{Value: *e.Message}
+	}}
+}
+
+// reference returns what identifies a pointer or an error. Two of them are
+// equal if they refer to the same struct or error, or are both nil.
+func reference(obj object.Object) (interface{}, bool) {
+	switch obj := obj.(type) {
+	case *object.Pointer:
+		if obj.Target != nil {
+			return obj.Target, true
+		}
+		return nil, true
+	case *object.ErrorValue:
+		if obj.Message != nil {
+			return obj.Message, true
+		}
+		return nil, true
+	case *object.Nil:
+		return nil, true
+	}
+	return nil, false
+}
+
+// evalReferenceEquality compares two pointers or errors, or either with
+// nil.
+func evalReferenceEquality(operator string, left, right interface{}) object.Object {
+	if operator == "==" {
+		return nativeBoolToBooleanObject(left == right)
+	}
+	return nativeBoolToBooleanObject(left != right)
+}
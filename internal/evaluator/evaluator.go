@@ -3,18 +3,34 @@ package evaluator
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/diagnostics"
 	"golite.dev/mvp/internal/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
+	NIL      = &object.Nil{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	// The innermost node that produced an error is the most precise location
+	// we have for it, so only attach a span if none has been set yet.
+	if err, ok := result.(*object.Error); ok && !err.Span.Start.IsValid() && node != nil {
+		err.Span = diagnostics.SpanOf(node)
+	}
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -24,11 +40,35 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.LetStatement:
+		if len(node.Rest) > 0 {
+			return evalLetValues(node, env)
+		}
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		define(env, node.Name.Value, val)
+	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
+		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.DeferStatement:
+		return evalDeferStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.AssignStatement:
+		return evalAssignStatement(node, env)
+	case *ast.IncDecStatement:
+		return evalIncDecStatement(node, env)
 	case *ast.PrintStatement:
 		val := Eval(node.Expression, env)
 		if !isError(val) {
@@ -39,9 +79,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.TypeStatement, *ast.MethodStatement:
+		// Declared by evalProgram before the program runs.
+		return nil
 	case *ast.PrefixExpression:
+		if node.Operator == "&" {
+			ref := evalStructRef(node.Right, env)
+			if isError(ref) {
+				return ref
+			}
+			return &object.Pointer{Target: ref.(*object.Struct)}
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -52,6 +106,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(left) {
 			return left
 		}
+		// && and || evaluate their right operand only when the left one
+		// does not decide the result.
+		if b, ok := left.(*object.Boolean); ok && (node.Operator == "&&" && !b.Value || node.Operator == "||" && b.Value) {
+			return left
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
@@ -59,14 +118,40 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		// A literal's capacity is its length, as in Go and the C runtime.
+		return &object.Slice{Elements: elements[:len(elements):len(elements)]}
+	case *ast.MapLiteral:
+		return evalMapLiteral(node, env)
+	case *ast.StructLiteral:
+		return evalStructLiteral(node, env)
+	case *ast.SelectorExpression:
+		return copyValue(evalSelectorExpression(node, env))
+	case *ast.IndexExpression:
+		return copyValue(evalIndexExpression(node, env))
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+	case *ast.TupleExpression:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Tuple{Elements: elements}
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return copyValue(evalIdentifier(node, env))
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Result: node.ReturnType, Env: env, Body: body}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if isBuiltinCall(node, "recover", env) {
+			return evalRecover(env)
+		}
+		function, recv := evalCallee(node, env)
 		if isError(function) {
 			return function
 		}
@@ -74,12 +159,40 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		result := applyFunction(function, append(recv, args...), env.Frame())
+		// An error located inside the function, rather than in calling it,
+		// unwound a call that belongs in its stack trace.
+		if err, ok := result.(*object.Error); ok && err.Span.Start.IsValid() {
+			if _, ok := function.(*object.Function); ok {
+				err.Stack = append(err.Stack, object.StackFrame{Function: calleeName(node), Call: diagnostics.SpanOf(node)})
+			}
+		}
+		return result
 	}
 	return nil
 }
 
+// evalProgram runs the statements of program and then the calls they
+// deferred, which may recover from a panic.
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	declareTypes(program, env)
+	frame := &object.Frame{}
+	env.SetFrame(frame)
+	result := evalStatements(program, env)
+	if err, ok := result.(*object.Error); ok {
+		frame.Panic = err
+	}
+	runDeferred(frame)
+	if frame.Panic != nil {
+		return frame.Panic
+	}
+	if isError(result) {
+		return nil
+	}
+	return result
+}
+
+func evalStatements(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
@@ -100,7 +213,8 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		result = Eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -140,19 +254,33 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	if operator == "==" || operator == "!=" {
+		l, lok := reference(left)
+		r, rok := reference(right)
+		if lok && rok {
+			return evalReferenceEquality(operator, l, r)
+		}
+	}
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == object.FLOAT_OBJ && right.Type() == object.FLOAT_OBJ:
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
 		return evalBooleanInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -170,12 +298,65 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return &object.Integer{Value: leftVal - rightVal}
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
+	case "/", "%":
+		if rightVal == 0 {
+			return newError("integer divide by zero")
+		}
+		if operator == "/" {
+			return &object.Integer{Value: leftVal / rightVal}
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift amount: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << rightVal}
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Float).Value
+	rightVal := right.(*object.Float).Value
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return &object.Float{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -189,6 +370,25 @@ func evalBooleanInfixExpression(operator string, left, right object.Object) obje
 	leftVal := left.(*object.Boolean).Value
 	rightVal := right.(*object.Boolean).Value
 	switch operator {
+	case "&&":
+		return nativeBoolToBooleanObject(leftVal && rightVal)
+	case "||":
+		return nativeBoolToBooleanObject(leftVal || rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -212,6 +412,218 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+func evalAssignStatement(as *ast.AssignStatement, env *object.Environment) object.Object {
+	if te, ok := as.Target.(*ast.TupleExpression); ok {
+		return evalAssignValues(te, as.Value, env)
+	}
+	val := Eval(as.Value, env)
+	if isError(val) {
+		return val
+	}
+	if as.Operator != "=" {
+		current := Eval(as.Target, env)
+		if isError(current) {
+			return current
+		}
+		val = evalInfixExpression(strings.TrimSuffix(as.Operator, "="), current, val)
+		if isError(val) {
+			return val
+		}
+	}
+	return assign(as.Target, val, env)
+}
+
+func evalIncDecStatement(ids *ast.IncDecStatement, env *object.Environment) object.Object {
+	current := Eval(ids.Target, env)
+	if isError(current) {
+		return current
+	}
+	var one object.Object = &object.Integer{Value: 1}
+	if current.Type() == object.FLOAT_OBJ {
+		one = &object.Float{Value: 1}
+	}
+	val := evalInfixExpression(ids.Operator[:1], current, one)
+	if isError(val) {
+		return val
+	}
+	return assign(ids.Target, val, env)
+}
+
+// assign stores val into the location denoted by target.
+func assign(target ast.Expression, val object.Object, env *object.Environment) object.Object {
+	if se, ok := target.(*ast.SelectorExpression); ok {
+		return assignField(se, val, env)
+	}
+	if ie, ok := target.(*ast.IndexExpression); ok {
+		left := Eval(ie.Left, env)
+		if isError(left) {
+			return left
+		}
+		if m, ok := left.(*object.Map); ok {
+			return assignMapEntry(m, ie.Index, val, env)
+		}
+		slice, index := evalIndexOperands(left, ie.Index, env)
+		if isError(slice) {
+			return slice
+		}
+		if isError(index) {
+			return index
+		}
+		elements := slice.(*object.Slice).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return indexError(i, len(elements))
+		}
+		elements[i] = val
+		return nil
+	}
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		return newError("cannot assign to %s", target.String())
+	}
+	if ident.Value == "_" {
+		return nil
+	}
+	if !env.Assign(ident.Value, val) {
+		return newError("identifier not found: " + ident.Value)
+	}
+	return nil
+}
+
+func evalIndexExpression(ie *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(ie.Left, env)
+	if isError(left) {
+		return left
+	}
+	if m, ok := left.(*object.Map); ok {
+		val, _ := evalMapLookup(m, ie.Index, env)
+		return val
+	}
+	slice, index := evalIndexOperands(left, ie.Index, env)
+	if isError(slice) {
+		return slice
+	}
+	if isError(index) {
+		return index
+	}
+	elements := slice.(*object.Slice).Elements
+	i := index.(*object.Integer).Value
+	if i < 0 || i >= int64(len(elements)) {
+		return indexError(i, len(elements))
+	}
+	return elements[i]
+}
+
+// evalIndexOperands evaluates the index into slice, returning an error in
+// place of either if it is not of the right type.
+func evalIndexOperands(slice object.Object, indexNode ast.Expression, env *object.Environment) (object.Object, object.Object) {
+	if slice.Type() != object.SLICE_OBJ {
+		return newError("index operator not supported: %s", slice.Type()), nil
+	}
+	index := Eval(indexNode, env)
+	if !isError(index) && index.Type() != object.INTEGER_OBJ {
+		index = newError("index must be INTEGER, got %s", index.Type())
+	}
+	return slice, index
+}
+
+func indexError(i int64, length int) *object.Error {
+	if i < 0 {
+		return newError("index out of range [%d]", i)
+	}
+	return newError("index out of range [%d] with length %d", i, length)
+}
+
+// evalSliceExpression evaluates s[lo:hi]. As in Go, the result of slicing a
+// slice shares its array, and hi may reach into the capacity beyond its
+// length.
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var length, limit int
+	limitName := "capacity"
+	switch left := left.(type) {
+	case *object.Slice:
+		length, limit = len(left.Elements), cap(left.Elements)
+	case *object.String:
+		length, limit = len(left.Value), len(left.Value)
+		limitName = "length"
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	bounds := [2]int64{0, int64(length)}
+	for i, bound := range []ast.Expression{se.Low, se.High} {
+		if bound == nil {
+			continue
+		}
+		val := Eval(bound, env)
+		if isError(val) {
+			return val
+		}
+		n, ok := val.(*object.Integer)
+		if !ok {
+			return newError("slice index must be INTEGER, got %s", val.Type())
+		}
+		bounds[i] = n.Value
+	}
+
+	lo, hi := bounds[0], bounds[1]
+	switch {
+	case hi < 0 || hi > int64(limit):
+		return newError("slice bounds out of range [:%d] with %s %d", hi, limitName, limit)
+	case lo < 0 || lo > hi:
+		return newError("slice bounds out of range [%d:%d]", lo, hi)
+	}
+
+	if s, ok := left.(*object.String); ok {
+		return &object.String{Value: s.Value[lo:hi]}
+	}
+	return &object.Slice{Elements: left.(*object.Slice).Elements[lo:hi]}
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+
+	if fs.Init != nil {
+		if init := Eval(fs.Init, loopEnv); isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := Eval(fs.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				break
+			}
+		}
+
+		result := Eval(fs.Body, loopEnv)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return nil
+			}
+		}
+
+		if fs.Post != nil {
+			if post := Eval(fs.Post, loopEnv); isError(post) {
+				return post
+			}
+		}
+	}
+	return nil
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -229,6 +641,12 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
+	if b, ok := builtins.Lookup(node.Value); ok {
+		return &object.Builtin{Fn: b.Eval}
+	}
+	if node.Value == "nil" {
+		return NIL
+	}
 	return newError("identifier not found: " + node.Value)
 }
 
@@ -244,29 +662,17 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		return newError("not a function: %s", fn.Type())
-	}
-
-	if len(function.Parameters) != len(args) {
-		return newError("wrong number of arguments: want=%d, got=%d",
-			len(function.Parameters), len(args))
+// applyFunction calls fn with args from a call made in the frame caller.
+func applyFunction(fn object.Object, args []object.Object, caller *object.Frame) object.Object {
+	depth := 0
+	if caller != nil {
+		depth = caller.Depth
 	}
-
-	extendedEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
-
-	if returnValue, ok := evaluated.(*object.ReturnValue); ok {
-		return returnValue.Value
-	}
-
-	return evaluated
+	return callFunction(fn, args, &object.Frame{Depth: depth + 1})
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+func extendFunctionEnv(fn *object.Function, args []object.Object, frame *object.Frame) *object.Environment {
+	env := object.NewFrameEnvironment(fn.Env, frame)
 	for paramIdx, param := range fn.Parameters {
 		env.Set(param.Value, args[paramIdx])
 	}
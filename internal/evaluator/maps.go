@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/object"
+)
+
+// zeroValue returns the value of type t that a lookup of a missing map key
+// or an omitted field in a struct literal produces, and that a function
+// returns when a deferred call recovers from a panic.
+func zeroValue(t ast.TypeExpr, env *object.Environment) object.Object {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		switch t.Name {
+		case "int":
+			return &object.Integer{Value: 0}
+		case "float":
+			return &object.Float{Value: 0}
+		case "bool":
+			return FALSE
+		case "string":
+			return &object.String{Value: ""}
+		case "error":
+			return &object.ErrorValue{}
+		}
+		if def, ok := lookupStruct(t, env); ok {
+			return zeroStruct(def, env)
+		}
+	case *ast.PointerType:
+		return &object.Pointer{}
+	case *ast.SliceType:
+		return &object.Slice{}
+	case *ast.MapType:
+		return &object.Map{Zero: zeroValue(t.Value, env)}
+	case *ast.TupleType:
+		tuple := &object.Tuple{}
+		for _, e := range t.Elems {
+			tuple.Elements = append(tuple.Elements, zeroValue(e, env))
+		}
+		return tuple
+	}
+	return NULL
+}
+
+func evalMapLiteral(ml *ast.MapLiteral, env *object.Environment) object.Object {
+	m := &object.Map{
+		Pairs: make(map[object.HashKey]object.MapPair, len(ml.Keys)),
+		Zero:  zeroValue(ml.Type.Value, env),
+	}
+	for i, keyNode := range ml.Keys {
+		key, hashKey := evalMapKey(keyNode, env)
+		if isError(key) {
+			return key
+		}
+		val := Eval(ml.Values[i], env)
+		if isError(val) {
+			return val
+		}
+		m.Pairs[hashKey] = object.MapPair{Key: key, Value: val}
+	}
+	return m
+}
+
+// evalMapKey evaluates a map key, returning an error in its place if the
+// value cannot be a key.
+func evalMapKey(node ast.Expression, env *object.Environment) (object.Object, object.HashKey) {
+	key := Eval(node, env)
+	if isError(key) {
+		return key, object.HashKey{}
+	}
+	hashable, ok := key.(object.Hashable)
+	if !ok {
+		return newError("unusable as map key: %s", key.Type()), object.HashKey{}
+	}
+	return key, hashable.HashKey()
+}
+
+// evalMapLookup evaluates m[index], returning the value, or the zero value
+// if the key is missing, and whether it was present.
+func evalMapLookup(m *object.Map, index ast.Expression, env *object.Environment) (object.Object, bool) {
+	key, hashKey := evalMapKey(index, env)
+	if isError(key) {
+		return key, false
+	}
+	pair, ok := m.Pairs[hashKey]
+	if !ok {
+		return m.Zero, false
+	}
+	return pair.Value, true
+}
+
+// evalCommaOk evaluates v, ok := m[k] to v and ok.
+func evalCommaOk(ie *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(ie.Left, env)
+	if isError(left) {
+		return left
+	}
+	m, ok := left.(*object.Map)
+	if !ok {
+		return newError("assignment mismatch: 2 variables but 1 value")
+	}
+	val, found := evalMapLookup(m, ie.Index, env)
+	if isError(val) {
+		return val
+	}
+	return &object.Tuple{Elements: []object.Object{copyValue(val), nativeBoolToBooleanObject(found)}}
+}
+
+func assignMapEntry(m *object.Map, index ast.Expression, val object.Object, env *object.Environment) object.Object {
+	key, hashKey := evalMapKey(index, env)
+	if isError(key) {
+		return key
+	}
+	if m.Pairs == nil {
+		return newError("assignment to entry in nil map")
+	}
+	m.Pairs[hashKey] = object.MapPair{Key: key, Value: val}
+	return nil
+}
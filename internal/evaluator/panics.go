@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+)
+
+// maxFrames is the most calls that can be in progress at once. Every call
+// nests calls of Eval on the Go stack, whose overflow kills the process,
+// so a runaway recursion stops here with a runtime error instead.
+const maxFrames = 10000
+
+// callFunction calls fn with args. frame holds the state of the call that
+// its deferred calls need. Once the body has finished, normally or with a
+// panic, the deferred calls run; if one of them recovers from the panic,
+// the function returns the zero value of its result.
+func callFunction(fn object.Object, args []object.Object, frame *object.Frame) object.Object {
+	if builtin, ok := fn.(*object.Builtin); ok {
+		if result := builtin.Fn(args...); result != nil {
+			return result
+		}
+		return NULL
+	}
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return newError("not a function: %s", fn.Type())
+	}
+
+	if frame.Depth >= maxFrames {
+		return newError("stack overflow")
+	}
+	if len(function.Parameters) != len(args) {
+		return newError("wrong number of arguments: want=%d, got=%d",
+			len(function.Parameters), len(args))
+	}
+
+	extendedEnv := extendFunctionEnv(function, args, frame)
+	evaluated := Eval(function.Body, extendedEnv)
+	if err, ok := evaluated.(*object.Error); ok {
+		frame.Panic = err
+	}
+	runDeferred(frame)
+	if frame.Panic != nil {
+		return frame.Panic
+	}
+	if isError(evaluated) {
+		return zeroValue(function.Result, function.Env)
+	}
+
+	if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+
+	return evaluated
+}
+
+// runDeferred runs the calls deferred in frame, the most recent first. A
+// deferred call that panics replaces the panic unwinding through the frame,
+// if there is one.
+func runDeferred(frame *object.Frame) {
+	for len(frame.Deferred) > 0 {
+		last := len(frame.Deferred) - 1
+		d := frame.Deferred[last]
+		frame.Deferred = frame.Deferred[:last]
+
+		result := callFunction(d.Function, d.Args, &object.Frame{Deferring: frame, Depth: frame.Depth + 1})
+		if err, ok := result.(*object.Error); ok {
+			if !err.Span.Start.IsValid() {
+				err.Span = d.Call
+			}
+			if _, ok := d.Function.(*object.Function); ok {
+				err.Stack = append(err.Stack, object.StackFrame{Function: d.Name, Call: d.Call})
+			}
+			frame.Panic = err
+		}
+	}
+}
+
+// evalDeferStatement evaluates the function and arguments of a deferred
+// call, which runs when the enclosing function returns.
+func evalDeferStatement(ds *ast.DeferStatement, env *object.Environment) object.Object {
+	frame := env.Frame()
+	if frame == nil {
+		return newError("defer outside function")
+	}
+	function, recv := evalCallee(ds.Call, env)
+	if isError(function) {
+		return function
+	}
+	args := evalExpressions(ds.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	frame.Deferred = append(frame.Deferred, &object.DeferredCall{
+		Function: function,
+		Args:     append(recv, args...),
+		Name:     calleeName(ds.Call),
+		Call:     diagnostics.SpanOf(ds.Call),
+	})
+	return nil
+}
+
+// evalRecover stops the panic unwinding through the function whose
+// deferred call is running in env and returns it as an error. recover only
+// does so when called by the deferred function itself; otherwise, or if
+// there is no panic, it returns nil.
+func evalRecover(env *object.Environment) object.Object {
+	frame := env.Frame()
+	if frame == nil || frame.Deferring == nil || frame.Deferring.Panic == nil {
+		return NIL
+	}
+	err := frame.Deferring.Panic
+	frame.Deferring.Panic = nil
+	if err.Value != nil {
+		return err.Value
+	}
+	return object.NewErrorValue("runtime error: " + err.Message)
+}
+
+// calleeName names the function that ce calls in stack traces.
+func calleeName(ce *ast.CallExpression) string {
+	if _, ok := ce.Function.(*ast.FunctionLiteral); ok {
+		return "func literal"
+	}
+	return ce.Function.String()
+}
+
+// isBuiltinCall reports whether ce calls the builtin called name, which no
+// variable in env shadows.
+func isBuiltinCall(ce *ast.CallExpression, name string, env *object.Environment) bool {
+	ident, ok := ce.Function.(*ast.Identifier)
+	if !ok || ident.Value != name {
+		return false
+	}
+	_, shadowed := env.Get(name)
+	return !shadowed
+}
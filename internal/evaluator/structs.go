@@ -0,0 +1,208 @@
+package evaluator
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/object"
+)
+
+// typeKey is the name under which the struct type called name is stored in
+// the environment. No identifier can have it, so types do not clash with
+// values of the same name.
+func typeKey(name string) string {
+	return "type " + name
+}
+
+// declareTypes defines the struct types and methods of a program before it
+// runs, so that they can be used before the declarations that introduce
+// them, as at the top level of a Go package.
+func declareTypes(program *ast.Program, env *object.Environment) {
+	for _, stmt := range program.Statements {
+		ts, ok := stmt.(*ast.TypeStatement)
+		if !ok {
+			continue
+		}
+		def := &object.StructType{Name: ts.Name.Value, Decl: ts.Type, Methods: map[string]*object.Method{}}
+		for _, field := range ts.Type.Fields {
+			for _, name := range field.Names {
+				def.Fields = append(def.Fields, name.Value)
+			}
+		}
+		env.Set(typeKey(def.Name), def)
+	}
+
+	for _, stmt := range program.Statements {
+		ms, ok := stmt.(*ast.MethodStatement)
+		if !ok {
+			continue
+		}
+		_, recvType := ms.Receiver()
+		pt, pointer := recvType.(*ast.PointerType)
+		if pointer {
+			recvType = pt.Elem
+		}
+		if def, ok := lookupStruct(recvType, env); ok {
+			fn := &object.Function{Parameters: ms.Function.Parameters, Result: ms.Function.ReturnType, Body: ms.Function.Body, Env: env}
+			def.Methods[ms.Name.Value] = &object.Method{Function: fn, PointerReceiver: pointer}
+		}
+	}
+}
+
+// lookupStruct returns the struct type that t names.
+func lookupStruct(t ast.TypeExpr, env *object.Environment) (*object.StructType, bool) {
+	nt, ok := t.(*ast.NamedType)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(typeKey(nt.Name))
+	if !ok {
+		return nil, false
+	}
+	def, ok := obj.(*object.StructType)
+	return def, ok
+}
+
+// zeroStruct returns a struct of type def with every field set to its zero
+// value.
+func zeroStruct(def *object.StructType, env *object.Environment) *object.Struct {
+	s := &object.Struct{StructType: def}
+	for _, field := range def.Decl.Fields {
+		for range field.Names {
+			s.Fields = append(s.Fields, zeroValue(field.Type, env))
+		}
+	}
+	return s
+}
+
+func evalStructLiteral(sl *ast.StructLiteral, env *object.Environment) object.Object {
+	def, ok := lookupStruct(sl.Type, env)
+	if !ok {
+		return newError("undefined type: %s", sl.Type.Name)
+	}
+	s := zeroStruct(def, env)
+	for i, valueNode := range sl.Values {
+		val := Eval(valueNode, env)
+		if isError(val) {
+			return val
+		}
+		index := i
+		if sl.Fields != nil {
+			if index = def.FieldIndex(sl.Fields[i].Value); index < 0 {
+				return newError("unknown field %s in struct literal of type %s", sl.Fields[i].Value, def.Name)
+			}
+		}
+		if index >= len(s.Fields) {
+			return newError("too many values in struct literal of type %s", def.Name)
+		}
+		s.Fields[index] = val
+	}
+	return s
+}
+
+// copyValue returns a copy of obj if it is a struct, which must not be
+// shared once it is stored somewhere else, and obj itself otherwise.
+func copyValue(obj object.Object) object.Object {
+	if s, ok := obj.(*object.Struct); ok {
+		return s.Copy()
+	}
+	return obj
+}
+
+// evalStructRef evaluates expr, which denotes a struct or a pointer to one,
+// to the struct itself rather than to a copy of it, so that its fields can
+// be assigned or its address taken.
+func evalStructRef(expr ast.Expression, env *object.Environment) object.Object {
+	return structRef(evalRef(expr, env))
+}
+
+// evalRef evaluates expr without copying the struct it may denote.
+func evalRef(expr ast.Expression, env *object.Environment) object.Object {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return evalIdentifier(expr, env)
+	case *ast.SelectorExpression:
+		return evalSelectorExpression(expr, env)
+	case *ast.IndexExpression:
+		return evalIndexExpression(expr, env)
+	}
+	return Eval(expr, env)
+}
+
+// structRef returns the struct that obj is or points to.
+func structRef(obj object.Object) object.Object {
+	switch o := obj.(type) {
+	case *object.Error, *object.Struct:
+		return o
+	case *object.Pointer:
+		if o.Target == nil {
+			return newError("invalid memory address or nil pointer dereference")
+		}
+		return o.Target
+	case *object.Nil:
+		return newError("invalid memory address or nil pointer dereference")
+	}
+	return newError("%s is not a struct", obj.Type())
+}
+
+// evalSelectorExpression evaluates x.f to the field itself, without copying
+// it.
+func evalSelectorExpression(se *ast.SelectorExpression, env *object.Environment) object.Object {
+	ref := evalStructRef(se.X, env)
+	if isError(ref) {
+		return ref
+	}
+	s := ref.(*object.Struct)
+	i := s.StructType.FieldIndex(se.Sel.Value)
+	if i < 0 {
+		return newError("%s undefined (type %s has no field %s)", se.String(), s.StructType.Name, se.Sel.Value)
+	}
+	return s.Fields[i]
+}
+
+func assignField(se *ast.SelectorExpression, val object.Object, env *object.Environment) object.Object {
+	ref := evalStructRef(se.X, env)
+	if isError(ref) {
+		return ref
+	}
+	s := ref.(*object.Struct)
+	i := s.StructType.FieldIndex(se.Sel.Value)
+	if i < 0 {
+		return newError("%s undefined (type %s has no field %s)", se.String(), s.StructType.Name, se.Sel.Value)
+	}
+	s.Fields[i] = val
+	return nil
+}
+
+// evalCallee evaluates the function called by ce together with the
+// arguments that precede the explicit ones: the receiver, if it calls a
+// method. A pointer receiver points to the struct the method is called on,
+// while a value receiver gets a copy of it.
+func evalCallee(ce *ast.CallExpression, env *object.Environment) (object.Object, []object.Object) {
+	se, ok := ce.Function.(*ast.SelectorExpression)
+	if !ok {
+		return Eval(ce.Function, env), nil
+	}
+	if b, ok := qualifiedBuiltin(se, env); ok {
+		return b, nil
+	}
+	obj := evalRef(se.X, env)
+	if e, ok := obj.(*object.ErrorValue); ok {
+		return errorMethod(e, se), nil
+	}
+	ref := structRef(obj)
+	if isError(ref) {
+		return ref, nil
+	}
+	s := ref.(*object.Struct)
+	m, ok := s.StructType.Methods[se.Sel.Value]
+	if !ok {
+		i := s.StructType.FieldIndex(se.Sel.Value)
+		if i < 0 {
+			return newError("%s undefined (type %s has no field or method %s)", se.String(), s.StructType.Name, se.Sel.Value), nil
+		}
+		return s.Fields[i], nil
+	}
+	if m.PointerReceiver {
+		return m.Function, []object.Object{&object.Pointer{Target: s}}
+	}
+	return m.Function, []object.Object{s.Copy()}
+}
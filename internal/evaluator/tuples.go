@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/object"
+)
+
+// define binds name to val in env. The blank identifier _ discards val.
+func define(env *object.Environment, name string, val object.Object) {
+	if name != "_" {
+		env.Set(name, val)
+	}
+}
+
+// evalValues evaluates value to the n values it provides to n variables:
+// the elements of a tuple, or the value and found flag of a map index.
+func evalValues(value ast.Expression, n int, env *object.Environment) object.Object {
+	if ie, ok := value.(*ast.IndexExpression); ok && n == 2 {
+		return evalCommaOk(ie, env)
+	}
+	val := Eval(value, env)
+	if isError(val) {
+		return val
+	}
+	tuple, ok := val.(*object.Tuple)
+	if !ok || len(tuple.Elements) != n {
+		count := 1
+		if ok {
+			count = len(tuple.Elements)
+		}
+		return newError("assignment mismatch: %d variables but %d values", n, count)
+	}
+	return tuple
+}
+
+// evalLetValues evaluates a let statement that declares several variables,
+// as in q, r := divmod(a, b).
+func evalLetValues(ls *ast.LetStatement, env *object.Environment) object.Object {
+	names := ls.Names()
+	val := evalValues(ls.Value, len(names), env)
+	if isError(val) {
+		return val
+	}
+	for i, name := range names {
+		define(env, name.Value, val.(*object.Tuple).Elements[i])
+	}
+	return nil
+}
+
+// evalAssignValues evaluates an assignment to several targets, as in
+// a, b = b, a. Every value is computed before any target is assigned.
+func evalAssignValues(targets *ast.TupleExpression, value ast.Expression, env *object.Environment) object.Object {
+	val := evalValues(value, len(targets.Elements), env)
+	if isError(val) {
+		return val
+	}
+	for i, target := range targets.Elements {
+		if err := assign(target, val.(*object.Tuple).Elements[i], env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+package glc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/lexer"
+)
+
+// decoder reads a file. The first error is kept and later reads return
+// zero values, so that the error is checked once at the end.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+
+	strings   []string
+	constants []Constant
+	lines     []Line
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size <= 0 || n > math.MaxInt32 {
+		d.fail("bad integer at offset %d", d.pos)
+		return 0
+	}
+	d.pos += size
+	return int(n)
+}
+
+// count reads the length of a list, each element of which takes at least
+// a byte, so that a bad length cannot make the decoder allocate too much.
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > len(d.data)-d.pos {
+		d.fail("bad length %d at offset %d", n, d.pos)
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos >= len(d.data) {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+// string reads a string stored in place, as in the header.
+func (d *decoder) string() string {
+	n := d.count()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.data[d.pos : d.pos+n])
+	d.pos += n
+	return s
+}
+
+// stringRef reads the index of a string in the string table.
+func (d *decoder) stringRef() string {
+	i := d.uint()
+	if i >= len(d.strings) {
+		d.fail("string index %d out of range", i)
+		return ""
+	}
+	return d.strings[i]
+}
+
+func (d *decoder) constant(kind ConstantKind) Constant {
+	i := d.uint()
+	if i >= len(d.constants) {
+		d.fail("constant index %d out of range", i)
+		return Constant{Kind: kind}
+	}
+	if c := d.constants[i]; c.Kind == kind {
+		return c
+	}
+	d.fail("constant %d has the wrong kind", i)
+	return Constant{Kind: kind}
+}
+
+func (d *decoder) tables() {
+	d.strings = make([]string, d.count())
+	for i := range d.strings {
+		d.strings[i] = d.string()
+	}
+
+	d.constants = make([]Constant, d.count())
+	for i := range d.constants {
+		c := Constant{Kind: ConstantKind(d.byte())}
+		switch c.Kind {
+		case IntConstant:
+			if d.err != nil {
+				break
+			}
+			n, size := binary.Varint(d.data[d.pos:])
+			if size <= 0 {
+				d.fail("bad integer at offset %d", d.pos)
+				break
+			}
+			c.Int = n
+			d.pos += size
+		case FloatConstant:
+			if d.err != nil {
+				break
+			}
+			if len(d.data)-d.pos < 8 {
+				d.fail("unexpected end of data")
+				break
+			}
+			c.Float = math.Float64frombits(binary.BigEndian.Uint64(d.data[d.pos:]))
+			d.pos += 8
+		case StringConstant:
+			c.String = d.stringRef()
+		default:
+			d.fail("bad constant kind %d", c.Kind)
+		}
+		d.constants[i] = c
+	}
+
+	d.lines = make([]Line, d.count())
+	line, offset := 0, 0
+	for i := range d.lines {
+		line += d.uint()
+		offset += d.uint()
+		d.lines[i] = Line{Line: line, Offset: offset}
+	}
+}
+
+// position reads a position, finding its line and column in the line
+// table.
+func (d *decoder) position() lexer.Position {
+	n := d.uint()
+	if n == 0 {
+		return lexer.Position{}
+	}
+	offset := n - 1
+	i := sort.Search(len(d.lines), func(i int) bool { return d.lines[i].Offset > offset }) - 1
+	if i < 0 {
+		d.fail("position %d is before the first line", offset)
+		return lexer.Position{}
+	}
+	return lexer.Position{Offset: offset, Line: d.lines[i].Line, Column: offset - d.lines[i].Offset + 1}
+}
+
+func (d *decoder) token() lexer.Token {
+	t := lexer.Token{Type: lexer.TokenType(d.stringRef()), Literal: d.stringRef()}
+	t.Pos = d.position()
+	t.End = d.position()
+	return t
+}
+
+func (d *decoder) statements() []ast.Statement {
+	stmts := make([]ast.Statement, d.count())
+	for i := range stmts {
+		stmts[i] = d.statement()
+	}
+	return stmts
+}
+
+func (d *decoder) expressions() []ast.Expression {
+	exprs := make([]ast.Expression, d.count())
+	for i := range exprs {
+		exprs[i] = d.expression()
+	}
+	return exprs
+}
+
+func (d *decoder) identifiers() []*ast.Identifier {
+	present := d.bool()
+	n := d.count()
+	if !present {
+		return nil
+	}
+	idents := make([]*ast.Identifier, n)
+	for i := range idents {
+		idents[i] = d.identifier()
+	}
+	return idents
+}
+
+func (d *decoder) typeExprs() []ast.TypeExpr {
+	types := make([]ast.TypeExpr, d.count())
+	for i := range types {
+		types[i] = d.typeExpr()
+	}
+	return types
+}
+
+// paramTypes reads the types of the parameters of a function literal,
+// which are nil for the parameters whose type is inferred.
+func (d *decoder) paramTypes() []ast.TypeExpr {
+	types := make([]ast.TypeExpr, d.count())
+	for i := range types {
+		types[i] = d.optionalTypeExpr()
+	}
+	return types
+}
+
+// The functions below read a node that must be of a certain kind. The
+// tree cannot do without most children, so a nil one makes the file
+// corrupt; the optional ones are read by the functions that say so.
+
+func (d *decoder) statement() ast.Statement {
+	stmt := d.optionalStatement()
+	if stmt == nil {
+		d.missing("statement")
+	}
+	return stmt
+}
+
+func (d *decoder) optionalStatement() ast.Statement {
+	if n := d.node(); n != nil {
+		if stmt, ok := n.(ast.Statement); ok {
+			return stmt
+		}
+		d.fail("expected a statement, got %T", n)
+	}
+	return nil
+}
+
+func (d *decoder) expression() ast.Expression {
+	expr := d.optionalExpression()
+	if expr == nil {
+		d.missing("expression")
+	}
+	return expr
+}
+
+func (d *decoder) optionalExpression() ast.Expression {
+	if n := d.node(); n != nil {
+		if expr, ok := n.(ast.Expression); ok {
+			return expr
+		}
+		d.fail("expected an expression, got %T", n)
+	}
+	return nil
+}
+
+func (d *decoder) typeExpr() ast.TypeExpr {
+	t := d.optionalTypeExpr()
+	if t == nil {
+		d.missing("type")
+	}
+	return t
+}
+
+func (d *decoder) optionalTypeExpr() ast.TypeExpr {
+	if n := d.node(); n != nil {
+		if t, ok := n.(ast.TypeExpr); ok {
+			return t
+		}
+		d.fail("expected a type, got %T", n)
+	}
+	return nil
+}
+
+func (d *decoder) identifier() *ast.Identifier {
+	n, _ := d.expect(tagIdentifier, "identifier").(*ast.Identifier)
+	return n
+}
+
+func (d *decoder) block() *ast.BlockStatement {
+	n, _ := d.expect(tagBlock, "block").(*ast.BlockStatement)
+	return n
+}
+
+func (d *decoder) optionalBlock() *ast.BlockStatement {
+	n, _ := d.optional(tagBlock).(*ast.BlockStatement)
+	return n
+}
+
+// expect reads a node that must have the given tag; what names it in the
+// error if it is nil.
+func (d *decoder) expect(tag byte, what string) ast.Node {
+	n := d.optional(tag)
+	if n == nil {
+		d.missing(what)
+	}
+	return n
+}
+
+// optional reads a node that must have the given tag, or be nil.
+func (d *decoder) optional(tag byte) ast.Node {
+	if d.err == nil && d.pos < len(d.data) && d.data[d.pos] != tagNil && d.data[d.pos] != tag {
+		d.fail("unexpected node tag %d at offset %d", d.data[d.pos], d.pos)
+		return nil
+	}
+	return d.node()
+}
+
+// missing reports a nil node, which ended just before the current offset,
+// where the tree needs a what.
+func (d *decoder) missing(what string) {
+	d.fail("missing %s at offset %d", what, d.pos-1)
+}
+
+// node reads a node, mirroring encoder.node. It returns nil for a nil node
+// and once reading has failed.
+func (d *decoder) node() ast.Node {
+	tag := d.byte()
+	if d.err != nil {
+		return nil
+	}
+	switch tag {
+	case tagNil:
+		return nil
+	case tagLet:
+		return &ast.LetStatement{Token: d.token(), Name: d.identifier(), Rest: d.identifiers(), Type: d.optionalTypeExpr(), Value: d.expression()}
+	case tagPrint:
+		return &ast.PrintStatement{Token: d.token(), Expression: d.expression()}
+	case tagDefer:
+		n := &ast.DeferStatement{Token: d.token()}
+		n.Call, _ = d.expect(tagCall, "call").(*ast.CallExpression)
+		return n
+	case tagReturn:
+		return &ast.ReturnStatement{Token: d.token(), ReturnValue: d.optionalExpression()}
+	case tagFor:
+		return &ast.ForStatement{Token: d.token(), Init: d.optionalStatement(), Condition: d.optionalExpression(), Post: d.optionalStatement(), Body: d.block()}
+	case tagBreak:
+		return &ast.BreakStatement{Token: d.token()}
+	case tagContinue:
+		return &ast.ContinueStatement{Token: d.token()}
+	case tagType:
+		n := &ast.TypeStatement{Token: d.token(), Name: d.identifier()}
+		n.Type, _ = d.expect(tagStructType, "struct type").(*ast.StructType)
+		return n
+	case tagMethod:
+		n := &ast.MethodStatement{Token: d.token(), Name: d.identifier()}
+		n.Function, _ = d.expect(tagFunction, "function").(*ast.FunctionLiteral)
+		if n.Function == nil || len(n.Function.Parameters) == 0 || len(n.Function.ParamTypes) == 0 {
+			d.fail("method %s has no receiver", n.Name)
+		}
+		return n
+	case tagAssign:
+		return &ast.AssignStatement{Token: d.token(), Target: d.expression(), Operator: d.stringRef(), Value: d.expression()}
+	case tagIncDec:
+		return &ast.IncDecStatement{Token: d.token(), Target: d.expression(), Operator: d.stringRef()}
+	case tagExpression:
+		return &ast.ExpressionStatement{Token: d.token(), Expression: d.expression()}
+	case tagBlock:
+		return &ast.BlockStatement{Token: d.token(), Statements: d.statements(), Rbrace: d.position()}
+	case tagBadStatement:
+		return &ast.BadStatement{Token: d.token(), EndPos: d.position()}
+	case tagIdentifier:
+		return &ast.Identifier{Token: d.token(), Value: d.stringRef()}
+	case tagInteger:
+		return &ast.IntegerLiteral{Token: d.token(), Value: d.constant(IntConstant).Int}
+	case tagFloat:
+		return &ast.FloatLiteral{Token: d.token(), Value: d.constant(FloatConstant).Float}
+	case tagString:
+		return &ast.StringLiteral{Token: d.token(), Value: d.constant(StringConstant).String}
+	case tagBoolean:
+		return &ast.Boolean{Token: d.token(), Value: d.bool()}
+	case tagPrefix:
+		return &ast.PrefixExpression{Token: d.token(), Operator: d.stringRef(), Right: d.expression()}
+	case tagInfix:
+		return &ast.InfixExpression{Token: d.token(), Left: d.expression(), Operator: d.stringRef(), Right: d.expression()}
+	case tagIf:
+		return &ast.IfExpression{Token: d.token(), Condition: d.expression(), Consequence: d.block(), Alternative: d.optionalBlock()}
+	case tagFunction:
+		n := &ast.FunctionLiteral{Token: d.token(), Parameters: d.identifiers(), ParamTypes: d.paramTypes(), ReturnType: d.optionalTypeExpr(), Body: d.block()}
+		if len(n.ParamTypes) != len(n.Parameters) {
+			d.fail("function has %d parameters but %d parameter types", len(n.Parameters), len(n.ParamTypes))
+		}
+		return n
+	case tagCall:
+		return &ast.CallExpression{Token: d.token(), Function: d.expression(), Arguments: d.expressions(), Rparen: d.position()}
+	case tagArray:
+		return &ast.ArrayLiteral{Token: d.token(), Elements: d.expressions(), Rbracket: d.position()}
+	case tagMap:
+		n := &ast.MapLiteral{Token: d.token()}
+		n.Type, _ = d.expect(tagMapType, "map type").(*ast.MapType)
+		n.Keys = d.expressions()
+		n.Values = d.expressions()
+		n.Rbrace = d.position()
+		if len(n.Keys) != len(n.Values) {
+			d.fail("map literal has %d keys but %d values", len(n.Keys), len(n.Values))
+		}
+		return n
+	case tagStruct:
+		n := &ast.StructLiteral{Token: d.token()}
+		n.Type, _ = d.expect(tagNamedType, "type name").(*ast.NamedType)
+		n.Fields = d.identifiers()
+		n.Values = d.expressions()
+		n.Rbrace = d.position()
+		if n.Fields != nil && len(n.Fields) != len(n.Values) {
+			d.fail("struct literal has %d fields but %d values", len(n.Fields), len(n.Values))
+		}
+		return n
+	case tagIndex:
+		return &ast.IndexExpression{Token: d.token(), Left: d.expression(), Index: d.expression(), Rbracket: d.position()}
+	case tagSlice:
+		return &ast.SliceExpression{Token: d.token(), Left: d.expression(), Low: d.optionalExpression(), High: d.optionalExpression(), Rbracket: d.position()}
+	case tagSelector:
+		return &ast.SelectorExpression{Token: d.token(), X: d.expression(), Sel: d.identifier()}
+	case tagTuple:
+		n := &ast.TupleExpression{Token: d.token(), Elements: d.expressions()}
+		if len(n.Elements) == 0 {
+			d.fail("empty tuple")
+		}
+		return n
+	case tagBadExpression:
+		return &ast.BadExpression{Token: d.token(), EndPos: d.position()}
+	case tagNamedType:
+		return &ast.NamedType{Token: d.token(), Name: d.stringRef()}
+	case tagFuncType:
+		return &ast.FuncType{Token: d.token(), Params: d.typeExprs(), Result: d.optionalTypeExpr(), Rparen: d.position()}
+	case tagSliceType:
+		return &ast.SliceType{Token: d.token(), Elem: d.typeExpr()}
+	case tagMapType:
+		return &ast.MapType{Token: d.token(), Key: d.typeExpr(), Value: d.typeExpr()}
+	case tagStructType:
+		n := &ast.StructType{Token: d.token()}
+		n.Fields = make([]*ast.Field, d.count())
+		for i := range n.Fields {
+			n.Fields[i] = &ast.Field{Names: d.identifiers(), Type: d.typeExpr()}
+		}
+		n.Rbrace = d.position()
+		return n
+	case tagPointerType:
+		return &ast.PointerType{Token: d.token(), Elem: d.typeExpr()}
+	case tagTupleType:
+		return &ast.TupleType{Lparen: d.token(), Elems: d.typeExprs(), Rparen: d.position()}
+	}
+	d.fail("unknown node tag %d at offset %d", tag, d.pos-1)
+	return nil
+}
@@ -0,0 +1,93 @@
+package glc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/lexer"
+)
+
+// Dump writes a readable listing of f: its header, its tables and its
+// syntax tree, one node per line with its position.
+func Dump(w io.Writer, f *File) {
+	fmt.Fprintf(w, "format:   %d\n", Version)
+	fmt.Fprintf(w, "compiler: %s\n", f.Compiler)
+	fmt.Fprintf(w, "source:   %s\n", f.Source)
+	fmt.Fprintf(w, "checksum: %08x\n", f.Checksum)
+
+	fmt.Fprintf(w, "\nstrings (%d):\n", len(f.Strings))
+	for i, s := range f.Strings {
+		fmt.Fprintf(w, "  %4d  %q\n", i, s)
+	}
+	fmt.Fprintf(w, "\nconstants (%d):\n", len(f.Constants))
+	for i, c := range f.Constants {
+		fmt.Fprintf(w, "  %4d  %s\n", i, c.format())
+	}
+	fmt.Fprintf(w, "\nlines (%d):\n", len(f.Lines))
+	for _, l := range f.Lines {
+		fmt.Fprintf(w, "  %4d  offset %d\n", l.Line, l.Offset)
+	}
+
+	fmt.Fprintf(w, "\nprogram (%d statements):\n", len(f.Program.Statements))
+	for _, stmt := range f.Program.Statements {
+		dumpNode(w, stmt, 1)
+	}
+}
+
+func dumpNode(w io.Writer, n ast.Node, depth int) {
+	fmt.Fprintf(w, "%-9s%s%s\n", formatPosition(n.Pos()), strings.Repeat("  ", depth), describe(n))
+	if _, ok := n.(ast.TypeExpr); ok {
+		// Types are short enough to list whole.
+		return
+	}
+	for _, child := range children(n) {
+		dumpNode(w, child, depth+1)
+	}
+}
+
+func formatPosition(p lexer.Position) string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// children returns the nodes directly below n.
+func children(n ast.Node) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(n, func(child ast.Node) bool {
+		if child == n {
+			return true
+		}
+		nodes = append(nodes, child)
+		return false
+	})
+	return nodes
+}
+
+// describe names the type of a node, with what it holds that its children
+// do not show.
+func describe(n ast.Node) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+	switch n := n.(type) {
+	case *ast.LetStatement:
+		return name + " " + n.Token.Literal
+	case *ast.AssignStatement:
+		return name + " " + n.Operator
+	case *ast.IncDecStatement:
+		return name + " " + n.Operator
+	case *ast.PrefixExpression:
+		return name + " " + n.Operator
+	case *ast.InfixExpression:
+		return name + " " + n.Operator
+	case *ast.Identifier:
+		return name + " " + n.Value
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return name + " " + n.TokenLiteral()
+	case ast.TypeExpr:
+		return name + " " + n.String()
+	}
+	return name
+}
@@ -0,0 +1,374 @@
+package glc
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sort"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/lexer"
+)
+
+// encoder writes the syntax tree, collecting the tables it refers to,
+// which are written before it.
+type encoder struct {
+	buf       []byte
+	strings   []string
+	stringIDs map[string]int
+	constants []Constant
+	// constantIDs is keyed by the bits of floats, since -0.0 and 0.0 are
+	// equal but distinct constants.
+	constantIDs map[constantKey]int
+	lineStarts  map[int]int // the offset at which each line starts
+}
+
+type constantKey struct {
+	kind ConstantKind
+	bits uint64
+	s    string
+}
+
+func newEncoder() *encoder {
+	return &encoder{stringIDs: map[string]int{}, constantIDs: map[constantKey]int{}, lineStarts: map[int]int{}}
+}
+
+func (e *encoder) appendTables(out []byte) []byte {
+	// The constants refer to strings, so both tables are complete by now.
+	out = binary.AppendUvarint(out, uint64(len(e.strings)))
+	for _, s := range e.strings {
+		out = appendString(out, s)
+	}
+
+	out = binary.AppendUvarint(out, uint64(len(e.constants)))
+	for _, c := range e.constants {
+		out = append(out, byte(c.Kind))
+		switch c.Kind {
+		case IntConstant:
+			out = binary.AppendVarint(out, c.Int)
+		case FloatConstant:
+			out = binary.BigEndian.AppendUint64(out, math.Float64bits(c.Float))
+		case StringConstant:
+			out = binary.AppendUvarint(out, uint64(e.stringIDs[c.String]))
+		}
+	}
+
+	lines := make([]int, 0, len(e.lineStarts))
+	for line := range e.lineStarts {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	out = binary.AppendUvarint(out, uint64(len(lines)))
+	prevLine, prevOffset := 0, 0
+	for _, line := range lines {
+		out = binary.AppendUvarint(out, uint64(line-prevLine))
+		out = binary.AppendUvarint(out, uint64(e.lineStarts[line]-prevOffset))
+		prevLine, prevOffset = line, e.lineStarts[line]
+	}
+	return out
+}
+
+func (e *encoder) uint(n int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(n))
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// string writes the index of s in the string table.
+func (e *encoder) string(s string) {
+	e.uint(e.intern(s))
+}
+
+func (e *encoder) intern(s string) int {
+	id, ok := e.stringIDs[s]
+	if !ok {
+		id = len(e.strings)
+		e.stringIDs[s] = id
+		e.strings = append(e.strings, s)
+	}
+	return id
+}
+
+// constant writes the index of c in the constant pool.
+func (e *encoder) constant(c Constant) {
+	key := constantKey{kind: c.Kind, bits: uint64(c.Int), s: c.String}
+	if c.Kind == FloatConstant {
+		key.bits = math.Float64bits(c.Float)
+	}
+	id, ok := e.constantIDs[key]
+	if !ok {
+		if c.Kind == StringConstant {
+			// The string table is written first, so it must be complete
+			// once the tree is.
+			e.intern(c.String)
+		}
+		id = len(e.constants)
+		e.constantIDs[key] = id
+		e.constants = append(e.constants, c)
+	}
+	e.uint(id)
+}
+
+func (e *encoder) position(p lexer.Position) {
+	if !p.IsValid() {
+		e.uint(0)
+		return
+	}
+	if _, ok := e.lineStarts[p.Line]; !ok {
+		e.lineStarts[p.Line] = p.Offset - (p.Column - 1)
+	}
+	e.uint(p.Offset + 1)
+}
+
+// token writes a token without its comments, which only tools working on
+// source need.
+func (e *encoder) token(t lexer.Token) {
+	e.string(string(t.Type))
+	e.string(t.Literal)
+	e.position(t.Pos)
+	e.position(t.End)
+}
+
+func (e *encoder) statements(stmts []ast.Statement) {
+	e.uint(len(stmts))
+	for _, stmt := range stmts {
+		e.node(stmt)
+	}
+}
+
+func (e *encoder) expressions(exprs []ast.Expression) {
+	e.uint(len(exprs))
+	for _, expr := range exprs {
+		e.node(expr)
+	}
+}
+
+func (e *encoder) identifiers(idents []*ast.Identifier) {
+	e.bool(idents != nil)
+	e.uint(len(idents))
+	for _, ident := range idents {
+		e.node(ident)
+	}
+}
+
+func (e *encoder) typeExprs(types []ast.TypeExpr) {
+	e.uint(len(types))
+	for _, t := range types {
+		e.node(t)
+	}
+}
+
+// node writes a node, or a nil one. Optional fields such as the name of a
+// let statement hold nil pointers, which are not nil as nodes.
+func (e *encoder) node(n ast.Node) {
+	if n == nil || reflect.ValueOf(n).IsNil() {
+		e.buf = append(e.buf, tagNil)
+		return
+	}
+	switch n := n.(type) {
+	case *ast.LetStatement:
+		e.buf = append(e.buf, tagLet)
+		e.token(n.Token)
+		e.node(n.Name)
+		e.identifiers(n.Rest)
+		e.node(n.Type)
+		e.node(n.Value)
+	case *ast.PrintStatement:
+		e.buf = append(e.buf, tagPrint)
+		e.token(n.Token)
+		e.node(n.Expression)
+	case *ast.DeferStatement:
+		e.buf = append(e.buf, tagDefer)
+		e.token(n.Token)
+		e.node(n.Call)
+	case *ast.ReturnStatement:
+		e.buf = append(e.buf, tagReturn)
+		e.token(n.Token)
+		e.node(n.ReturnValue)
+	case *ast.ForStatement:
+		e.buf = append(e.buf, tagFor)
+		e.token(n.Token)
+		e.node(n.Init)
+		e.node(n.Condition)
+		e.node(n.Post)
+		e.node(n.Body)
+	case *ast.BreakStatement:
+		e.buf = append(e.buf, tagBreak)
+		e.token(n.Token)
+	case *ast.ContinueStatement:
+		e.buf = append(e.buf, tagContinue)
+		e.token(n.Token)
+	case *ast.TypeStatement:
+		e.buf = append(e.buf, tagType)
+		e.token(n.Token)
+		e.node(n.Name)
+		e.node(n.Type)
+	case *ast.MethodStatement:
+		e.buf = append(e.buf, tagMethod)
+		e.token(n.Token)
+		e.node(n.Name)
+		e.node(n.Function)
+	case *ast.AssignStatement:
+		e.buf = append(e.buf, tagAssign)
+		e.token(n.Token)
+		e.node(n.Target)
+		e.string(n.Operator)
+		e.node(n.Value)
+	case *ast.IncDecStatement:
+		e.buf = append(e.buf, tagIncDec)
+		e.token(n.Token)
+		e.node(n.Target)
+		e.string(n.Operator)
+	case *ast.ExpressionStatement:
+		e.buf = append(e.buf, tagExpression)
+		e.token(n.Token)
+		e.node(n.Expression)
+	case *ast.BlockStatement:
+		e.buf = append(e.buf, tagBlock)
+		e.token(n.Token)
+		e.statements(n.Statements)
+		e.position(n.Rbrace)
+	case *ast.BadStatement:
+		e.buf = append(e.buf, tagBadStatement)
+		e.token(n.Token)
+		e.position(n.EndPos)
+	case *ast.Identifier:
+		e.buf = append(e.buf, tagIdentifier)
+		e.token(n.Token)
+		e.string(n.Value)
+	case *ast.IntegerLiteral:
+		e.buf = append(e.buf, tagInteger)
+		e.token(n.Token)
+		e.constant(Constant{Kind: IntConstant, Int: n.Value})
+	case *ast.FloatLiteral:
+		e.buf = append(e.buf, tagFloat)
+		e.token(n.Token)
+		e.constant(Constant{Kind: FloatConstant, Float: n.Value})
+	case *ast.StringLiteral:
+		e.buf = append(e.buf, tagString)
+		e.token(n.Token)
+		e.constant(Constant{Kind: StringConstant, String: n.Value})
+	case *ast.Boolean:
+		e.buf = append(e.buf, tagBoolean)
+		e.token(n.Token)
+		e.bool(n.Value)
+	case *ast.PrefixExpression:
+		e.buf = append(e.buf, tagPrefix)
+		e.token(n.Token)
+		e.string(n.Operator)
+		e.node(n.Right)
+	case *ast.InfixExpression:
+		e.buf = append(e.buf, tagInfix)
+		e.token(n.Token)
+		e.node(n.Left)
+		e.string(n.Operator)
+		e.node(n.Right)
+	case *ast.IfExpression:
+		e.buf = append(e.buf, tagIf)
+		e.token(n.Token)
+		e.node(n.Condition)
+		e.node(n.Consequence)
+		e.node(n.Alternative)
+	case *ast.FunctionLiteral:
+		e.buf = append(e.buf, tagFunction)
+		e.token(n.Token)
+		e.identifiers(n.Parameters)
+		e.typeExprs(n.ParamTypes)
+		e.node(n.ReturnType)
+		e.node(n.Body)
+	case *ast.CallExpression:
+		e.buf = append(e.buf, tagCall)
+		e.token(n.Token)
+		e.node(n.Function)
+		e.expressions(n.Arguments)
+		e.position(n.Rparen)
+	case *ast.ArrayLiteral:
+		e.buf = append(e.buf, tagArray)
+		e.token(n.Token)
+		e.expressions(n.Elements)
+		e.position(n.Rbracket)
+	case *ast.MapLiteral:
+		e.buf = append(e.buf, tagMap)
+		e.token(n.Token)
+		e.node(n.Type)
+		e.expressions(n.Keys)
+		e.expressions(n.Values)
+		e.position(n.Rbrace)
+	case *ast.StructLiteral:
+		e.buf = append(e.buf, tagStruct)
+		e.token(n.Token)
+		e.node(n.Type)
+		e.identifiers(n.Fields)
+		e.expressions(n.Values)
+		e.position(n.Rbrace)
+	case *ast.IndexExpression:
+		e.buf = append(e.buf, tagIndex)
+		e.token(n.Token)
+		e.node(n.Left)
+		e.node(n.Index)
+		e.position(n.Rbracket)
+	case *ast.SliceExpression:
+		e.buf = append(e.buf, tagSlice)
+		e.token(n.Token)
+		e.node(n.Left)
+		e.node(n.Low)
+		e.node(n.High)
+		e.position(n.Rbracket)
+	case *ast.SelectorExpression:
+		e.buf = append(e.buf, tagSelector)
+		e.token(n.Token)
+		e.node(n.X)
+		e.node(n.Sel)
+	case *ast.TupleExpression:
+		e.buf = append(e.buf, tagTuple)
+		e.token(n.Token)
+		e.expressions(n.Elements)
+	case *ast.BadExpression:
+		e.buf = append(e.buf, tagBadExpression)
+		e.token(n.Token)
+		e.position(n.EndPos)
+	case *ast.NamedType:
+		e.buf = append(e.buf, tagNamedType)
+		e.token(n.Token)
+		e.string(n.Name)
+	case *ast.FuncType:
+		e.buf = append(e.buf, tagFuncType)
+		e.token(n.Token)
+		e.typeExprs(n.Params)
+		e.node(n.Result)
+		e.position(n.Rparen)
+	case *ast.SliceType:
+		e.buf = append(e.buf, tagSliceType)
+		e.token(n.Token)
+		e.node(n.Elem)
+	case *ast.MapType:
+		e.buf = append(e.buf, tagMapType)
+		e.token(n.Token)
+		e.node(n.Key)
+		e.node(n.Value)
+	case *ast.StructType:
+		e.buf = append(e.buf, tagStructType)
+		e.token(n.Token)
+		e.uint(len(n.Fields))
+		for _, field := range n.Fields {
+			e.identifiers(field.Names)
+			e.node(field.Type)
+		}
+		e.position(n.Rbrace)
+	case *ast.PointerType:
+		e.buf = append(e.buf, tagPointerType)
+		e.token(n.Token)
+		e.node(n.Elem)
+	case *ast.TupleType:
+		e.buf = append(e.buf, tagTupleType)
+		e.token(n.Lparen)
+		e.typeExprs(n.Elems)
+		e.position(n.Rparen)
+	}
+}
@@ -0,0 +1,189 @@
+// Package glc reads and writes .glc files, which hold a parsed, checked and
+// optimized program so that it can be run or built again without lexing or
+// parsing its source.
+//
+// A file is laid out as follows. Integers are unsigned varints unless noted,
+// and a string is its length followed by its bytes.
+//
+//	magic      "GLC\x00"
+//	version    the format version, Version
+//	compiler   string: the version of golite that wrote the file
+//	source     string: the name of the source file, for diagnostics
+//	strings    count, then each string: identifiers, operators and the
+//	           types and literals of tokens, each stored once
+//	constants  count, then each constant: a kind byte and an int64 varint,
+//	           the 8 bytes of a float64 or the index of a string
+//	lines      count, then for each line that a position refers to, the
+//	           differences of its number and start offset from the line
+//	           before
+//	program    the syntax tree, each node a tag byte followed by its token
+//	           and its fields in declaration order
+//	checksum   4 bytes: the big-endian CRC-32 of everything before it
+//
+// A position is stored as its offset plus one, or 0 if it is not valid; its
+// line and column are found again from the line table.
+package glc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"golite.dev/mvp/internal/ast"
+)
+
+// Version is the version of the format. Files of other versions are
+// rejected, since their syntax trees may be encoded differently.
+const Version = 1
+
+var magic = []byte("GLC\x00")
+
+// File is the content of a .glc file.
+type File struct {
+	Compiler string // the version of golite that wrote the file
+	Source   string // the name of the source file
+	Program  *ast.Program
+
+	// The tables of the file, filled in by Decode for listings. Encode
+	// builds them from Program and ignores these.
+	Strings   []string
+	Constants []Constant
+	Lines     []Line
+	Checksum  uint32
+}
+
+type ConstantKind byte
+
+const (
+	IntConstant ConstantKind = iota + 1
+	FloatConstant
+	StringConstant
+)
+
+// Constant is the value of an integer, float or string literal.
+type Constant struct {
+	Kind   ConstantKind
+	Int    int64
+	Float  float64
+	String string
+}
+
+func (c Constant) format() string {
+	switch c.Kind {
+	case IntConstant:
+		return fmt.Sprintf("int     %d", c.Int)
+	case FloatConstant:
+		return fmt.Sprintf("float   %g", c.Float)
+	default:
+		return fmt.Sprintf("string  %q", c.String)
+	}
+}
+
+// Line is an entry of the line table: the offset at which a line starts.
+type Line struct {
+	Line   int
+	Offset int
+}
+
+// IsGLC reports whether data begins like a .glc file rather than source
+// text.
+func IsGLC(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encode returns the encoding of f.
+func Encode(f *File) []byte {
+	e := newEncoder()
+	e.statements(f.Program.Statements)
+
+	out := append([]byte{}, magic...)
+	out = binary.AppendUvarint(out, Version)
+	out = appendString(out, f.Compiler)
+	out = appendString(out, f.Source)
+	out = e.appendTables(out)
+	out = append(out, e.buf...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out))
+}
+
+// Decode decodes a .glc file.
+func Decode(data []byte) (*File, error) {
+	if !IsGLC(data) {
+		return nil, errors.New("not a .glc file")
+	}
+	if len(data) < len(magic)+4 {
+		return nil, errors.New("truncated .glc file")
+	}
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New("corrupt .glc file: checksum mismatch")
+	}
+
+	d := &decoder{data: body, pos: len(magic)}
+	if version := d.uint(); version != Version && d.err == nil {
+		return nil, fmt.Errorf("unsupported .glc format version %d (this golite reads version %d)", version, Version)
+	}
+	f := &File{Checksum: sum}
+	f.Compiler = d.string()
+	f.Source = d.string()
+	d.tables()
+	f.Program = &ast.Program{Statements: d.statements()}
+	if d.err == nil && d.pos != len(d.data) {
+		d.fail("trailing data after the program")
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("corrupt .glc file: %s", d.err)
+	}
+	f.Strings, f.Constants, f.Lines = d.strings, d.constants, d.lines
+	return f, nil
+}
+
+func appendString(out []byte, s string) []byte {
+	out = binary.AppendUvarint(out, uint64(len(s)))
+	return append(out, s...)
+}
+
+// Tags identify the type of each node. 0 stands for a nil node.
+const (
+	tagNil byte = iota
+	tagLet
+	tagPrint
+	tagDefer
+	tagReturn
+	tagFor
+	tagBreak
+	tagContinue
+	tagType
+	tagMethod
+	tagAssign
+	tagIncDec
+	tagExpression
+	tagBlock
+	tagBadStatement
+	tagIdentifier
+	tagInteger
+	tagFloat
+	tagString
+	tagBoolean
+	tagPrefix
+	tagInfix
+	tagIf
+	tagFunction
+	tagCall
+	tagArray
+	tagMap
+	tagStruct
+	tagIndex
+	tagSlice
+	tagSelector
+	tagTuple
+	tagBadExpression
+	tagNamedType
+	tagFuncType
+	tagSliceType
+	tagMapType
+	tagStructType
+	tagPointerType
+	tagTupleType
+)
@@ -0,0 +1,285 @@
+// Package ir is the intermediate representation that the native backends
+// generate code from. Lower translates a checked program into it, resolving
+// every name to a variable or a function and every operator to one that
+// works on a single type, and reports the constructs the native backends do
+// not support: they compile programs over ints, floats and bools that print
+// those and string literals, with top-level and nested functions that do not
+// capture the variables of other functions.
+//
+// Control flow stays structured, as in the source, since WebAssembly needs
+// it that way; the other backends lower it to jumps.
+package ir
+
+// Type is the type of a value. The only strings are constants, which can
+// be stored in variables and printed.
+type Type int
+
+const (
+	Void Type = iota
+	Int
+	Float
+	Bool
+	String
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	default:
+		return "void"
+	}
+}
+
+// Program is a lowered program. Main runs the top-level statements.
+type Program struct {
+	Globals   []*Var
+	Functions []*Function // Main last, or followed by the runtime
+	Main      *Function
+	Strings   []string // the string constants, by Const.Index
+	Data      []*Data  // static memory, used by the runtime
+}
+
+// Function is a function literal, or the main program.
+type Function struct {
+	// Name is unique among the functions and globals of the program. It is
+	// the name the function was bound to, or "lambda", and "main" for the
+	// main program.
+	Name   string
+	Params []*Var
+	Result Type
+	Locals []*Var // excluding the parameters
+	Body   []Stmt // every path through it ends in a Return
+}
+
+// Var is a variable: a parameter or local of a function, or a global, which
+// is a variable declared by the top-level statements of the program outside
+// any loop. Globals start out zero.
+type Var struct {
+	Name   string // unique among the variables of its function, or the globals
+	Type   Type
+	Global bool
+}
+
+// Stmt is a statement.
+type Stmt interface{ stmt() }
+
+// Assign stores Value in Var.
+type Assign struct {
+	Var   *Var
+	Value Expr
+}
+
+// Print prints Value and a newline.
+type Print struct {
+	Value Expr
+}
+
+// Eval evaluates X for its effects and discards its value, if any.
+type Eval struct {
+	X Expr
+}
+
+// If runs Then if Cond is true and Else, which may be empty, otherwise.
+type If struct {
+	Cond Expr
+	Then []Stmt
+	Else []Stmt
+}
+
+// Loop runs Body and then Post for as long as Cond is true, or forever if
+// Cond is nil. Continue skips to Post.
+type Loop struct {
+	Cond Expr
+	Body []Stmt
+	Post []Stmt
+}
+
+// Break leaves the innermost loop.
+type Break struct{}
+
+// Continue starts the next iteration of the innermost loop.
+type Continue struct{}
+
+// Return returns from the function, with Value unless it has no result.
+type Return struct {
+	Value Expr
+}
+
+func (*Assign) stmt()   {}
+func (*Print) stmt()    {}
+func (*Eval) stmt()     {}
+func (*If) stmt()       {}
+func (*Loop) stmt()     {}
+func (*Break) stmt()    {}
+func (*Continue) stmt() {}
+func (*Return) stmt()   {}
+
+// Expr is an expression.
+type Expr interface {
+	Type() Type
+}
+
+// Const is a constant. Bools are 0 or 1 in Int, and strings are the
+// Index'th of Program.Strings.
+type Const struct {
+	T     Type
+	Int   int64
+	Float float64
+	Index int
+}
+
+// Load is the value of a variable.
+type Load struct {
+	Var *Var
+}
+
+// Op is an operator.
+type Op int
+
+const (
+	Add Op = iota
+	Sub
+	Mul
+	Div // stops the program if an int divisor is zero
+	Rem // of ints; stops the program if the divisor is zero
+	And
+	Or
+	Xor
+	Shl // stops the program if the count is negative
+	Shr // stops the program if the count is negative
+	Eq
+	Ne
+	Lt
+	Le
+	Gt
+	Ge
+	LogAnd // evaluates Y only if X is true
+	LogOr  // evaluates Y only if X is false
+	Neg
+	Not
+)
+
+var opNames = [...]string{
+	Add: "+", Sub: "-", Mul: "*", Div: "/", Rem: "%", And: "&", Or: "|", Xor: "^",
+	Shl: "<<", Shr: ">>", Eq: "==", Ne: "!=", Lt: "<", Le: "<=", Gt: ">", Ge: ">=",
+	LogAnd: "&&", LogOr: "||", Neg: "-", Not: "!",
+}
+
+func (op Op) String() string { return opNames[op] }
+
+// IsComparison reports whether op compares its operands, giving a bool.
+func (op Op) IsComparison() bool { return op >= Eq && op <= Ge }
+
+// Unary is Neg of an int or float, or Not of a bool.
+type Unary struct {
+	Op Op
+	X  Expr
+}
+
+// Binary applies Op to operands of the same type. The shifts and Div and
+// Rem of ints can stop the program with a runtime error, which is reported
+// at Line and Column.
+type Binary struct {
+	Op           Op
+	X, Y         Expr
+	Line, Column int
+}
+
+// Call calls a function.
+type Call struct {
+	Func *Function
+	Args []Expr
+}
+
+// Convert converts an int to a float or a float to an int, truncating it.
+type Convert struct {
+	To Type
+	X  Expr
+}
+
+func (c *Const) Type() Type   { return c.T }
+func (l *Load) Type() Type    { return l.Var.Type }
+func (u *Unary) Type() Type   { return u.X.Type() }
+func (c *Call) Type() Type    { return c.Func.Result }
+func (c *Convert) Type() Type { return c.To }
+
+func (b *Binary) Type() Type {
+	if b.Op.IsComparison() {
+		return Bool
+	}
+	return b.X.Type()
+}
+
+// Traps reports whether b can stop the program with a runtime error.
+func (b *Binary) Traps() bool {
+	switch b.Op {
+	case Div, Rem:
+		return b.X.Type() == Int
+	case Shl, Shr:
+		return true
+	}
+	return false
+}
+
+// The nodes below only appear in the runtime that AddRuntime adds, for
+// backends without a C library. They work on memory, where an Int is an
+// address.
+
+// Data is a static area of memory of Size bytes that starts out holding
+// Init, followed by zeros. Backends place it at an address that is a
+// multiple of 8.
+type Data struct {
+	Name string // unique among the data
+	Size int
+	Init []byte
+}
+
+// Poke stores the low Size bytes, 1 or 8, of Value at Addr.
+type Poke struct {
+	Addr, Value Expr
+	Size        int
+}
+
+// Write writes Len bytes from Addr to the file descriptor FD, 1 for the
+// standard output or 2 for the standard error.
+type Write struct {
+	FD        int
+	Addr, Len Expr
+}
+
+// Exit ends the program with status Code.
+type Exit struct {
+	Code int
+}
+
+func (*Poke) stmt()  {}
+func (*Write) stmt() {}
+func (*Exit) stmt()  {}
+
+// Addr is the address of Data plus Offset.
+type Addr struct {
+	Data   *Data
+	Offset int64
+}
+
+// Peek loads Size bytes, 1 or 8, from Addr. A byte is zero-extended.
+type Peek struct {
+	Addr Expr
+	Size int
+}
+
+// Bits is the IEEE 754 representation of a float, as an int.
+type Bits struct {
+	X Expr
+}
+
+func (a *Addr) Type() Type { return Int }
+func (p *Peek) Type() Type { return Int }
+func (b *Bits) Type() Type { return Int }
@@ -0,0 +1,593 @@
+package ir
+
+import (
+	"fmt"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/semantics"
+	"golite.dev/mvp/internal/types"
+)
+
+// Lower translates a program that checker has checked without errors.
+// backend names the backend in diagnostics, such as "LLVM"; reserved lists
+// the symbols the backend defines itself, which functions and globals are
+// renamed to avoid. The diagnostics report the constructs that have no IR,
+// and the program is only usable if there are none.
+func Lower(program *ast.Program, checker *semantics.Checker, backend string, reserved ...string) (*Program, []*diagnostics.Diagnostic) {
+	l := &lowerer{
+		backend: backend,
+		checker: checker,
+		prog:    &Program{},
+		used:    map[string]bool{"main": true},
+		strings: map[string]int{},
+		funcs:   map[*ast.FunctionLiteral]*Function{},
+	}
+	for _, name := range reserved {
+		l.used[name] = true
+	}
+	l.prog.Main = &Function{Name: "main", Result: Void}
+	l.fn = l.prog.Main
+	l.scope = &scope{names: map[string]*binding{}, fn: l.fn, global: true}
+
+	// Top-level functions can be called before their definition.
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Rest == nil {
+			if lit, ok := let.Value.(*ast.FunctionLiteral); ok && l.scope.names[let.Name.Value] == nil {
+				l.scope.names[let.Name.Value] = &binding{fn: l.declare(let.Name.Value, lit)}
+			}
+		}
+	}
+
+	l.prog.Main.Body = l.block(program.Statements, Void)
+	l.prog.Functions = append(l.prog.Functions, l.prog.Main)
+	return l.prog, l.errors
+}
+
+type lowerer struct {
+	backend string
+	checker *semantics.Checker
+	prog    *Program
+	errors  []*diagnostics.Diagnostic
+
+	fn      *Function // the function being lowered
+	scope   *scope
+	used    map[string]bool // the names of functions and globals
+	strings map[string]int  // the indexes of the string constants
+	funcs   map[*ast.FunctionLiteral]*Function
+}
+
+// scope holds the names declared by a function body or a for statement.
+// If blocks declare their names in the enclosing scope, as in the
+// evaluator.
+type scope struct {
+	outer  *scope
+	names  map[string]*binding
+	fn     *Function
+	global bool // declares globals: the top-level scope of the program
+}
+
+// binding is what a name stands for: a variable or a function.
+type binding struct {
+	v  *Var
+	fn *Function
+}
+
+func (s *scope) resolve(name string) (*binding, *scope) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b, s
+		}
+	}
+	return nil, nil
+}
+
+func (l *lowerer) errorf(node ast.Node, format string, args ...interface{}) {
+	message := fmt.Sprintf("the %s backend does not support ", l.backend) + fmt.Sprintf(format, args...)
+	l.errors = append(l.errors, diagnostics.Errorf(diagnostics.Unsupported, diagnostics.SpanOf(node), "%s", message))
+}
+
+// uniqueName returns a name derived from base that no function or global
+// has yet.
+func (l *lowerer) uniqueName(base string) string {
+	name := base
+	for i := 2; l.used[name]; i++ {
+		name = fmt.Sprintf("%s.%d", base, i)
+	}
+	l.used[name] = true
+	return name
+}
+
+func (l *lowerer) typeOf(expr ast.Expression) types.Type {
+	return l.checker.TypeOf(expr)
+}
+
+// irType returns the IR type of values of type t. Type variables the
+// checker left unbound belong to values whose type never mattered, so any
+// type will do for them.
+func irType(t types.Type) (Type, bool) {
+	switch t := types.Prune(t).(type) {
+	case *types.Var:
+		return Int, true
+	case *types.Basic:
+		switch t {
+		case types.Int:
+			return Int, true
+		case types.Float:
+			return Float, true
+		case types.Bool:
+			return Bool, true
+		case types.String:
+			return String, true
+		case types.Void:
+			return Void, true
+		}
+	}
+	return Void, false
+}
+
+func (l *lowerer) stringConst(s string) *Const {
+	index, ok := l.strings[s]
+	if !ok {
+		index = len(l.prog.Strings)
+		l.strings[s] = index
+		l.prog.Strings = append(l.prog.Strings, s)
+	}
+	return &Const{T: String, Index: index}
+}
+
+func (l *lowerer) zero(t Type) Expr {
+	if t == String {
+		return l.stringConst("")
+	}
+	return &Const{T: t}
+}
+
+// declare creates the function for lit, with its signature, to be lowered
+// by define.
+func (l *lowerer) declare(name string, lit *ast.FunctionLiteral) *Function {
+	fn := &Function{Name: l.uniqueName(name)}
+	l.funcs[lit] = fn
+
+	sig, _ := l.typeOf(lit).(*types.Signature)
+	if sig == nil {
+		return fn
+	}
+	result, ok := irType(sig.Result)
+	if !ok {
+		l.errorf(lit, "functions returning %s", types.Resolve(sig.Result))
+	}
+	fn.Result = result
+	for i, p := range lit.Parameters {
+		t, ok := irType(sig.Params[i])
+		if !ok {
+			l.errorf(p, "parameters of type %s", types.Resolve(sig.Params[i]))
+		}
+		fn.Params = append(fn.Params, &Var{Name: p.Value, Type: t})
+	}
+	return fn
+}
+
+// define lowers the body of the function declared for lit. Its parameters
+// and locals are resolved in a scope of its own whose outer scope is the
+// current one, so that the function sees the globals and functions
+// declared so far.
+func (l *lowerer) define(fn *Function, lit *ast.FunctionLiteral) {
+	savedFn, savedScope := l.fn, l.scope
+	defer func() { l.fn, l.scope = savedFn, savedScope }()
+
+	l.fn = fn
+	l.scope = &scope{outer: l.scope, names: map[string]*binding{}, fn: fn}
+	for _, p := range fn.Params {
+		l.scope.names[p.Name] = &binding{v: p}
+	}
+	fn.Body = l.block(lit.Body.Statements, fn.Result)
+	l.prog.Functions = append(l.prog.Functions, fn)
+}
+
+// block lowers the statements of a function body. If result is not Void,
+// a trailing expression statement is the function's result, as in the
+// evaluator, and a body that ends without one returns the zero value.
+func (l *lowerer) block(stmts []ast.Statement, result Type) []Stmt {
+	var out []Stmt
+	for i, stmt := range stmts {
+		if i == len(stmts)-1 && result != Void {
+			out = l.tail(out, stmt, result)
+		} else {
+			out = l.statement(out, stmt)
+		}
+	}
+	if !terminates(out) {
+		if result == Void {
+			out = append(out, &Return{})
+		} else {
+			out = append(out, &Return{Value: l.zero(result)})
+		}
+	}
+	return out
+}
+
+// tail lowers a statement in tail position of a function with a result,
+// turning an expression statement into a return of its value.
+func (l *lowerer) tail(out []Stmt, stmt ast.Statement, result Type) []Stmt {
+	if terminates(out) {
+		return out
+	}
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok || es.Expression == nil {
+		return l.statement(out, stmt)
+	}
+	if ie, ok := es.Expression.(*ast.IfExpression); ok {
+		s := &If{Cond: l.expression(ie.Condition)}
+		s.Then = l.tailBlock(ie.Consequence, result)
+		if ie.Alternative != nil {
+			s.Else = l.tailBlock(ie.Alternative, result)
+		}
+		return append(out, s)
+	}
+	if t, ok := irType(l.typeOf(es.Expression)); ok && t == Void {
+		return l.statement(out, stmt)
+	}
+	return append(out, &Return{Value: l.expression(es.Expression)})
+}
+
+func (l *lowerer) tailBlock(block *ast.BlockStatement, result Type) []Stmt {
+	var out []Stmt
+	for i, stmt := range block.Statements {
+		if i == len(block.Statements)-1 {
+			out = l.tail(out, stmt, result)
+		} else {
+			out = l.statement(out, stmt)
+		}
+	}
+	return out
+}
+
+// terminates reports whether stmts end in a statement that control never
+// passes.
+func terminates(stmts []Stmt) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch stmts[len(stmts)-1].(type) {
+	case *Return, *Break, *Continue:
+		return true
+	}
+	return false
+}
+
+// statements lowers a block of an if or for statement, leaving out the
+// statements after one that control never passes.
+func (l *lowerer) statements(stmts []ast.Statement) []Stmt {
+	var out []Stmt
+	for _, stmt := range stmts {
+		if terminates(out) {
+			break
+		}
+		out = l.statement(out, stmt)
+	}
+	return out
+}
+
+// statement appends the lowering of stmt to out.
+func (l *lowerer) statement(out []Stmt, stmt ast.Statement) []Stmt {
+	if terminates(out) {
+		return out
+	}
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		return l.let(out, s)
+	case *ast.AssignStatement:
+		return l.assign(out, s)
+	case *ast.IncDecStatement:
+		op := Add
+		if s.Operator == "--" {
+			op = Sub
+		}
+		v := l.variable(s.Target)
+		if v == nil {
+			return out
+		}
+		return append(out, &Assign{Var: v, Value: l.binary(op, &Load{Var: v}, &Const{T: v.Type, Int: 1, Float: 1}, s)})
+	case *ast.PrintStatement:
+		value := l.expression(s.Expression)
+		if value.Type() == Void {
+			l.errorf(s.Expression, "printing %s", s.Expression.String())
+			return out
+		}
+		return append(out, &Print{Value: value})
+	case *ast.ReturnStatement:
+		if s.ReturnValue == nil {
+			return append(out, &Return{})
+		}
+		if _, ok := s.ReturnValue.(*ast.TupleExpression); ok {
+			l.errorf(s.ReturnValue, "multiple return values")
+			return out
+		}
+		return append(out, &Return{Value: l.expression(s.ReturnValue)})
+	case *ast.ForStatement:
+		return l.loop(out, s)
+	case *ast.BreakStatement:
+		return append(out, &Break{})
+	case *ast.ContinueStatement:
+		return append(out, &Continue{})
+	case *ast.ExpressionStatement:
+		if s.Expression == nil {
+			return out
+		}
+		if ie, ok := s.Expression.(*ast.IfExpression); ok {
+			st := &If{Cond: l.expression(ie.Condition), Then: l.statements(ie.Consequence.Statements)}
+			if ie.Alternative != nil {
+				st.Else = l.statements(ie.Alternative.Statements)
+			}
+			return append(out, st)
+		}
+		return append(out, &Eval{X: l.expression(s.Expression)})
+	case *ast.DeferStatement:
+		l.errorf(s, "defer")
+	case *ast.TypeStatement:
+		l.errorf(s, "type declarations")
+	case *ast.MethodStatement:
+		l.errorf(s, "methods")
+	default:
+		l.errorf(stmt, "%T statements", stmt)
+	}
+	return out
+}
+
+func (l *lowerer) let(out []Stmt, s *ast.LetStatement) []Stmt {
+	if s.Rest != nil {
+		l.errorf(s, "declaring several variables at once")
+		return out
+	}
+	name := s.Name.Value
+	if lit, ok := s.Value.(*ast.FunctionLiteral); ok {
+		fn := l.funcs[lit]
+		if fn == nil {
+			fn = l.declare(name, lit)
+		}
+		// The function is in scope in its own body, so that it can call
+		// itself.
+		l.scope.names[name] = &binding{fn: fn}
+		l.define(fn, lit)
+		return out
+	}
+
+	value := l.expression(s.Value)
+	if name == "_" {
+		return append(out, &Eval{X: value})
+	}
+	if value.Type() == Void {
+		l.errorf(s.Value, "using %s as a value", s.Value.String())
+		return out
+	}
+	// Redeclaring a name in the same scope rebinds it, as in the evaluator.
+	if b, ok := l.scope.names[name]; !ok || b.v == nil || b.v.Type != value.Type() {
+		l.scope.names[name] = &binding{v: l.newVar(name, value.Type())}
+	}
+	return append(out, &Assign{Var: l.scope.names[name].v, Value: value})
+}
+
+// newVar creates a variable in the current scope: a global in the top-level
+// scope of the program and a local of the current function otherwise.
+func (l *lowerer) newVar(name string, t Type) *Var {
+	if l.scope.global {
+		v := &Var{Name: l.uniqueName(name), Type: t, Global: true}
+		l.prog.Globals = append(l.prog.Globals, v)
+		return v
+	}
+	base := name
+	for i := 2; l.hasLocal(name); i++ {
+		name = fmt.Sprintf("%s.%d", base, i)
+	}
+	v := &Var{Name: name, Type: t}
+	l.fn.Locals = append(l.fn.Locals, v)
+	return v
+}
+
+func (l *lowerer) hasLocal(name string) bool {
+	for _, v := range l.fn.Params {
+		if v.Name == name {
+			return true
+		}
+	}
+	for _, v := range l.fn.Locals {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *lowerer) assign(out []Stmt, s *ast.AssignStatement) []Stmt {
+	if _, ok := s.Target.(*ast.TupleExpression); ok {
+		l.errorf(s, "assigning several values at once")
+		return out
+	}
+	if ident, ok := s.Target.(*ast.Identifier); ok && ident.Value == "_" {
+		return append(out, &Eval{X: l.expression(s.Value)})
+	}
+	v := l.variable(s.Target)
+	if v == nil {
+		return out
+	}
+	value := l.expression(s.Value)
+	if s.Operator != "=" {
+		op, ok := binaryOps[strings.TrimSuffix(s.Operator, "=")]
+		if !ok {
+			l.errorf(s, "the %s operator", s.Operator)
+			return out
+		}
+		value = l.binary(op, &Load{Var: v}, value, s)
+	}
+	return append(out, &Assign{Var: v, Value: value})
+}
+
+// variable returns the variable that target, the target of an assignment,
+// names.
+func (l *lowerer) variable(target ast.Expression) *Var {
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		l.errorf(target, "assigning to %s", target.String())
+		return nil
+	}
+	load, ok := l.identifier(ident).(*Load)
+	if !ok {
+		return nil
+	}
+	return load.Var
+}
+
+func (l *lowerer) loop(out []Stmt, fs *ast.ForStatement) []Stmt {
+	l.scope = &scope{outer: l.scope, names: map[string]*binding{}, fn: l.fn}
+	defer func() { l.scope = l.scope.outer }()
+
+	if fs.Init != nil {
+		out = l.statement(out, fs.Init)
+	}
+	loop := &Loop{}
+	if fs.Condition != nil {
+		loop.Cond = l.expression(fs.Condition)
+	}
+	loop.Body = l.statements(fs.Body.Statements)
+	if fs.Post != nil {
+		loop.Post = l.statement(nil, fs.Post)
+	}
+	return append(out, loop)
+}
+
+var binaryOps = map[string]Op{
+	"+": Add, "-": Sub, "*": Mul, "/": Div, "%": Rem, "&": And, "|": Or, "^": Xor,
+	"<<": Shl, ">>": Shr, "==": Eq, "!=": Ne, "<": Lt, "<=": Le, ">": Gt, ">=": Ge,
+	"&&": LogAnd, "||": LogOr,
+}
+
+// binary builds a binary expression, which reports runtime errors at the
+// position of node.
+func (l *lowerer) binary(op Op, x, y Expr, node ast.Node) *Binary {
+	pos := diagnostics.SpanOf(node).Start
+	return &Binary{Op: op, X: x, Y: y, Line: pos.Line, Column: pos.Column}
+}
+
+func (l *lowerer) expression(expr ast.Expression) Expr {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return &Const{T: Int, Int: e.Value}
+	case *ast.FloatLiteral:
+		return &Const{T: Float, Float: e.Value}
+	case *ast.Boolean:
+		if e.Value {
+			return &Const{T: Bool, Int: 1}
+		}
+		return &Const{T: Bool}
+	case *ast.StringLiteral:
+		return l.stringConst(e.Value)
+	case *ast.Identifier:
+		return l.identifier(e)
+	case *ast.PrefixExpression:
+		x := l.expression(e.Right)
+		switch {
+		case e.Operator == "-" && (x.Type() == Int || x.Type() == Float):
+			return &Unary{Op: Neg, X: x}
+		case e.Operator == "!" && x.Type() == Bool:
+			return &Unary{Op: Not, X: x}
+		}
+		l.errorf(e, "the %s operator on %s", e.Operator, types.Resolve(l.typeOf(e.Right)))
+		return l.zero(x.Type())
+	case *ast.InfixExpression:
+		x, y := l.expression(e.Left), l.expression(e.Right)
+		op, ok := binaryOps[e.Operator]
+		if !ok || x.Type() == String || x.Type() == Void {
+			l.errorf(e, "the %s operator on %s", e.Operator, types.Resolve(l.typeOf(e.Left)))
+			return &Const{T: Int}
+		}
+		return l.binary(op, x, y, e)
+	case *ast.CallExpression:
+		return l.call(e)
+	case *ast.FunctionLiteral:
+		l.errorf(e, "function values; bind the function with let or call it directly")
+	case *ast.IfExpression:
+		l.errorf(e, "if expressions used as values")
+	case *ast.ArrayLiteral, *ast.SliceExpression:
+		l.errorf(e, "slices")
+	case *ast.MapLiteral:
+		l.errorf(e, "maps")
+	case *ast.StructLiteral, *ast.SelectorExpression:
+		l.errorf(e, "structs")
+	case *ast.IndexExpression:
+		l.errorf(e, "indexing %s", types.Resolve(l.typeOf(e.Left)))
+	case *ast.TupleExpression:
+		l.errorf(e, "multiple values")
+	default:
+		l.errorf(expr, "%s", expr.String())
+	}
+	return &Const{T: Int}
+}
+
+func (l *lowerer) identifier(ident *ast.Identifier) Expr {
+	b, s := l.scope.resolve(ident.Value)
+	switch {
+	case b == nil && ident.Value == "nil":
+		l.errorf(ident, "error values")
+	case b == nil:
+		l.errorf(ident, "%s", ident.Value)
+	case b.fn != nil:
+		l.errorf(ident, "function values; %s can only be called", ident.Value)
+	case !b.v.Global && s.fn != l.fn:
+		l.errorf(ident, "closures; %s belongs to an enclosing function", ident.Value)
+	default:
+		return &Load{Var: b.v}
+	}
+	return &Const{T: Int}
+}
+
+func (l *lowerer) call(ce *ast.CallExpression) Expr {
+	if b, ok := l.typeOf(ce.Function).(*types.Builtin); ok {
+		return l.builtinCall(ce, b)
+	}
+
+	var fn *Function
+	switch callee := ce.Function.(type) {
+	case *ast.Identifier:
+		if b, _ := l.scope.resolve(callee.Value); b != nil && b.fn != nil {
+			fn = b.fn
+		}
+	case *ast.FunctionLiteral:
+		fn = l.declare("lambda", callee)
+		l.define(fn, callee)
+	}
+	if fn == nil {
+		l.errorf(ce.Function, "calling %s", ce.Function.String())
+		return &Const{T: Int}
+	}
+
+	call := &Call{Func: fn}
+	for i, arg := range ce.Arguments {
+		a := l.expression(arg)
+		if i < len(fn.Params) && fn.Params[i].Type != a.Type() {
+			l.errorf(arg, "generic functions; %s is called with different types", fn.Name)
+			return &Const{T: Int}
+		}
+		call.Args = append(call.Args, a)
+	}
+	if t, ok := irType(l.typeOf(ce)); ok && t != fn.Result {
+		l.errorf(ce, "generic functions; %s is called with different types", fn.Name)
+	}
+	return call
+}
+
+// builtinCall lowers the conversions int and float, the only builtins the
+// native backends support.
+func (l *lowerer) builtinCall(ce *ast.CallExpression, b *types.Builtin) Expr {
+	to, ok := map[string]Type{"int": Int, "float": Float}[b.Name]
+	if !ok || len(ce.Arguments) != 1 {
+		l.errorf(ce, "the %s builtin", b.Name)
+		return &Const{T: Int}
+	}
+	x := l.expression(ce.Arguments[0])
+	if x.Type() == to {
+		return x
+	}
+	return &Convert{To: to, X: x}
+}
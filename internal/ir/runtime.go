@@ -0,0 +1,645 @@
+package ir
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AddRuntime adds to p the runtime that backends without a C library need,
+// written in IR so that they compile it like the program, and lowers what
+// relies on it to calls: printing, the operators that can stop the program
+// with a runtime error, and string constants, which become the addresses
+// of their length, in 8 bytes, followed by their bytes.
+//
+// The standard output is buffered. The buffer is flushed when main returns
+// and before a runtime error is reported, so that what the program printed
+// comes first.
+func AddRuntime(p *Program) {
+	rt := newRuntime(p)
+	for _, s := range p.Strings {
+		init := binary.LittleEndian.AppendUint64(nil, uint64(len(s)))
+		rt.strings = append(rt.strings, rt.data(fmt.Sprintf("str.%d", len(rt.strings)), 8+len(s), append(init, s...)))
+	}
+	for _, fn := range p.Functions {
+		if !rt.own[fn] {
+			fn.Body = rt.statements(fn.Body, fn == p.Main)
+		}
+	}
+}
+
+// RuntimeNames returns the names of the functions and globals that
+// AddRuntime adds, which the program's own must avoid.
+func RuntimeNames() []string {
+	p := &Program{}
+	newRuntime(p)
+	var names []string
+	for _, fn := range p.Functions {
+		names = append(names, fn.Name)
+	}
+	for _, v := range p.Globals {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+const (
+	outSize = 4096 // the size of the output buffer
+	numSize = 32   // the size of the buffer integers are formatted in
+	limbs   = 40   // the 32-bit limbs of a big integer, enough for any float
+)
+
+type runtime struct {
+	p       *Program
+	own     map[*Function]bool
+	strings []*Data // the data of the string constants, by Const.Index
+	texts   int     // the number of texts of the runtime's own
+
+	out, num, digits      *Data
+	r, s, mplus, mminus   *Data // the big integers that printing a float needs
+	high                  *Data
+	outLen                *Var
+	flush, emit, emitByte *Function
+	writeInt, emitInt     *Function
+	formatInt             *Function
+	errorAt               *Function
+	printInt, printFloat  *Function
+	printBool, printStr   *Function
+	div, rem, shl, shr    *Function
+}
+
+func newRuntime(p *Program) *runtime {
+	rt := &runtime{p: p, own: map[*Function]bool{}}
+	rt.out = rt.data("golite_out", outSize, nil)
+	rt.num = rt.data("golite_num", numSize, nil)
+	rt.digits = rt.data("golite_digits", numSize, nil)
+	rt.outLen = &Var{Name: "golite_out_len", Type: Int, Global: true}
+	p.Globals = append(p.Globals, rt.outLen)
+
+	rt.output()
+	rt.integers()
+	rt.errors()
+	rt.floats()
+	return rt
+}
+
+func (rt *runtime) data(name string, size int, init []byte) *Data {
+	d := &Data{Name: name, Size: size, Init: init}
+	rt.p.Data = append(rt.p.Data, d)
+	return d
+}
+
+// text returns the address and length of s, stored once per call.
+func (rt *runtime) text(s string) (Expr, Expr) {
+	d := rt.data(fmt.Sprintf("golite_text.%d", rt.texts), len(s), []byte(s))
+	rt.texts++
+	return &Addr{Data: d}, num(int64(len(s)))
+}
+
+func (rt *runtime) function(name string, result Type, params ...*Var) *Function {
+	fn := &Function{Name: name, Result: result, Params: params}
+	rt.p.Functions = append(rt.p.Functions, fn)
+	rt.own[fn] = true
+	return fn
+}
+
+// The helpers below build the runtime's IR.
+
+func param(name string, t Type) *Var { return &Var{Name: name, Type: t} }
+
+func local(fn *Function, name string, t Type) *Var {
+	v := &Var{Name: name, Type: t}
+	fn.Locals = append(fn.Locals, v)
+	return v
+}
+
+func num(v int64) Expr                      { return &Const{T: Int, Int: v} }
+func get(v *Var) Expr                       { return &Load{Var: v} }
+func op(o Op, x, y Expr) Expr               { return &Binary{Op: o, X: x, Y: y} }
+func not(x Expr) Expr                       { return &Unary{Op: Not, X: x} }
+func set(v *Var, value Expr) Stmt           { return &Assign{Var: v, Value: value} }
+func add(v *Var, n int64) Stmt              { return set(v, op(Add, get(v), num(n))) }
+func call(fn *Function, args ...Expr) *Call { return &Call{Func: fn, Args: args} }
+func do(fn *Function, args ...Expr) Stmt    { return &Eval{X: call(fn, args...)} }
+func ret(value Expr) Stmt                   { return &Return{Value: value} }
+func when(cond Expr, then ...Stmt) *If      { return &If{Cond: cond, Then: then} }
+func while(cond Expr, body ...Stmt) Stmt    { return &Loop{Cond: cond, Body: body} }
+func peek(addr Expr) Expr                   { return &Peek{Addr: addr, Size: 8} }
+func peekByte(addr Expr) Expr               { return &Peek{Addr: addr, Size: 1} }
+func poke(addr, value Expr) Stmt            { return &Poke{Addr: addr, Value: value, Size: 8} }
+func pokeByte(addr, value Expr) Stmt        { return &Poke{Addr: addr, Value: value, Size: 1} }
+func addr(d *Data) Expr                     { return &Addr{Data: d} }
+
+// limb returns the address of the i'th limb of the big integer at a.
+func limb(a, i Expr) Expr { return op(Add, a, op(Mul, i, num(8))) }
+
+// output builds the functions that buffer the standard output.
+func (rt *runtime) output() {
+	rt.flush = rt.function("golite_flush", Void)
+	rt.flush.Body = []Stmt{
+		when(op(Gt, get(rt.outLen), num(0)),
+			&Write{FD: 1, Addr: addr(rt.out), Len: get(rt.outLen)},
+			set(rt.outLen, num(0))),
+		&Return{},
+	}
+
+	a, n := param("a", Int), param("n", Int)
+	rt.emit = rt.function("golite_emit", Void, a, n)
+	i := local(rt.emit, "i", Int)
+	rt.emit.Body = []Stmt{
+		when(op(Gt, op(Add, get(rt.outLen), get(n)), num(outSize)), do(rt.flush)),
+		when(op(Gt, get(n), num(outSize)),
+			&Write{FD: 1, Addr: get(a), Len: get(n)},
+			&Return{}),
+		set(i, num(0)),
+		while(op(Lt, get(i), get(n)),
+			pokeByte(op(Add, addr(rt.out), op(Add, get(rt.outLen), get(i))), peekByte(op(Add, get(a), get(i)))),
+			add(i, 1)),
+		set(rt.outLen, op(Add, get(rt.outLen), get(n))),
+		&Return{},
+	}
+
+	b := param("b", Int)
+	rt.emitByte = rt.function("golite_emit_byte", Void, b)
+	rt.emitByte.Body = []Stmt{
+		when(op(Eq, get(rt.outLen), num(outSize)), do(rt.flush)),
+		pokeByte(op(Add, addr(rt.out), get(rt.outLen)), get(b)),
+		add(rt.outLen, 1),
+		&Return{},
+	}
+}
+
+// integers builds the functions that print ints, bools and strings.
+func (rt *runtime) integers() {
+	// formatInt writes n in decimal at the end of the num buffer and
+	// returns where it starts. It works on negative numbers, so that the
+	// smallest int has a magnitude too.
+	n := param("n", Int)
+	rt.formatInt = rt.function("golite_format_int", Int, n)
+	p, neg := local(rt.formatInt, "p", Int), local(rt.formatInt, "neg", Bool)
+	end := op(Add, addr(rt.num), num(numSize))
+	rt.formatInt.Body = []Stmt{
+		set(p, end),
+		set(neg, op(Lt, get(n), num(0))),
+		when(not(get(neg)), set(n, op(Sub, num(0), get(n)))),
+		while(nil,
+			add(p, -1),
+			pokeByte(get(p), op(Sub, num('0'), op(Rem, get(n), num(10)))),
+			set(n, op(Div, get(n), num(10))),
+			when(op(Eq, get(n), num(0)), &Break{})),
+		when(get(neg), add(p, -1), pokeByte(get(p), num('-'))),
+		ret(get(p)),
+	}
+
+	n = param("n", Int)
+	rt.emitInt = rt.function("golite_emit_int", Void, n)
+	p = local(rt.emitInt, "p", Int)
+	rt.emitInt.Body = []Stmt{
+		set(p, call(rt.formatInt, get(n))),
+		do(rt.emit, get(p), op(Sub, end, get(p))),
+		&Return{},
+	}
+
+	// writeInt writes n to the standard error, unbuffered.
+	n = param("n", Int)
+	rt.writeInt = rt.function("golite_write_int", Void, n)
+	p = local(rt.writeInt, "p", Int)
+	rt.writeInt.Body = []Stmt{
+		set(p, call(rt.formatInt, get(n))),
+		&Write{FD: 2, Addr: get(p), Len: op(Sub, end, get(p))},
+		&Return{},
+	}
+
+	n = param("n", Int)
+	rt.printInt = rt.function("golite_print_int", Void, n)
+	rt.printInt.Body = []Stmt{
+		do(rt.emitInt, get(n)),
+		do(rt.emitByte, num('\n')),
+		&Return{},
+	}
+
+	b := param("b", Bool)
+	rt.printBool = rt.function("golite_print_bool", Void, b)
+	yes, yesLen := rt.text("true")
+	no, noLen := rt.text("false")
+	rt.printBool.Body = []Stmt{
+		&If{Cond: get(b), Then: []Stmt{do(rt.emit, yes, yesLen)}, Else: []Stmt{do(rt.emit, no, noLen)}},
+		do(rt.emitByte, num('\n')),
+		&Return{},
+	}
+
+	s := param("s", String)
+	rt.printStr = rt.function("golite_print_string", Void, s)
+	rt.printStr.Body = []Stmt{
+		do(rt.emit, op(Add, get(s), num(8)), peek(get(s))),
+		do(rt.emitByte, num('\n')),
+		&Return{},
+	}
+}
+
+// errors builds the operators that stop the program with a runtime error.
+// Dividing by -1 wraps around instead of trapping, and shift counts of 64
+// or more shift every bit out, as in Go.
+func (rt *runtime) errors() {
+	line, column := param("line", Int), param("column", Int)
+	rt.errorAt = rt.function("golite_error_at", Void, line, column)
+	prefix, prefixLen := rt.text("runtime error at ")
+	colon, _ := rt.text(":")
+	sep, sepLen := rt.text(": ")
+	rt.errorAt.Body = []Stmt{
+		do(rt.flush),
+		&Write{FD: 2, Addr: prefix, Len: prefixLen},
+		do(rt.writeInt, get(line)),
+		&Write{FD: 2, Addr: colon, Len: num(1)},
+		do(rt.writeInt, get(column)),
+		&Write{FD: 2, Addr: sep, Len: sepLen},
+		&Return{},
+	}
+
+	divide, divideLen := rt.text("integer divide by zero\n")
+	shift, shiftLen := rt.text("negative shift amount: ")
+	newline, _ := rt.text("\n")
+	checkDivide := func(b, line, column *Var) Stmt {
+		return when(op(Eq, get(b), num(0)),
+			do(rt.errorAt, get(line), get(column)),
+			&Write{FD: 2, Addr: divide, Len: divideLen},
+			&Exit{Code: 1})
+	}
+	checkShift := func(n, line, column *Var) Stmt {
+		return when(op(Lt, get(n), num(0)),
+			do(rt.errorAt, get(line), get(column)),
+			&Write{FD: 2, Addr: shift, Len: shiftLen},
+			do(rt.writeInt, get(n)),
+			&Write{FD: 2, Addr: newline, Len: num(1)},
+			&Exit{Code: 1})
+	}
+
+	for _, name := range []string{"golite_div", "golite_mod"} {
+		a, b := param("a", Int), param("b", Int)
+		line, column := param("line", Int), param("column", Int)
+		fn := rt.function(name, Int, a, b, line, column)
+		result, minus := op(Div, get(a), get(b)), op(Sub, num(0), get(a))
+		if name == "golite_mod" {
+			result, minus = op(Rem, get(a), get(b)), num(0)
+			rt.rem = fn
+		} else {
+			rt.div = fn
+		}
+		fn.Body = []Stmt{
+			checkDivide(b, line, column),
+			when(op(Eq, get(b), num(-1)), ret(minus)),
+			ret(result),
+		}
+	}
+
+	a, n := param("a", Int), param("n", Int)
+	line, column = param("line", Int), param("column", Int)
+	rt.shl = rt.function("golite_shl", Int, a, n, line, column)
+	rt.shl.Body = []Stmt{
+		checkShift(n, line, column),
+		when(op(Ge, get(n), num(64)), ret(num(0))),
+		ret(op(Shl, get(a), get(n))),
+	}
+
+	a, n = param("a", Int), param("n", Int)
+	line, column = param("line", Int), param("column", Int)
+	rt.shr = rt.function("golite_shr", Int, a, n, line, column)
+	rt.shr.Body = []Stmt{
+		checkShift(n, line, column),
+		when(op(Ge, get(n), num(64)), set(n, num(63))),
+		ret(op(Shr, get(a), get(n))),
+	}
+}
+
+// floats builds the printing of floats. It prints what strconv.FormatFloat
+// does with format 'g' and the shortest precision, using the algorithm of
+// Burger and Dybvig ("Printing Floating-Point Numbers Quickly and
+// Accurately", 1996) on big integers: r/s is the value being printed,
+// scaled into [0.1, 1), and mplus/s and mminus/s are the distances to the
+// midpoints between it and its neighbouring floats. Digits are generated
+// until the number they form is closer to the value than to its
+// neighbours.
+func (rt *runtime) floats() {
+	big := func(name string) *Data { return rt.data(name, limbs*8, nil) }
+	rt.r, rt.s = big("golite_big_r"), big("golite_big_s")
+	rt.mplus, rt.mminus = big("golite_big_mplus"), big("golite_big_mminus")
+	rt.high = big("golite_big_high")
+
+	// assign sets the big integer at a to v, which must not be negative.
+	a, v := param("a", Int), param("v", Int)
+	assign := rt.function("golite_big_set", Void, a, v)
+	i := local(assign, "i", Int)
+	assign.Body = []Stmt{
+		set(i, num(0)),
+		while(op(Lt, get(i), num(limbs)), poke(limb(get(a), get(i)), num(0)), add(i, 1)),
+		poke(limb(get(a), num(0)), op(And, get(v), num(0xffffffff))),
+		poke(limb(get(a), num(1)), op(Shr, get(v), num(32))),
+		&Return{},
+	}
+
+	// mul multiplies the big integer at a by m, which is less than 2^31.
+	a, m := param("a", Int), param("m", Int)
+	mul := rt.function("golite_big_mul", Void, a, m)
+	i, t, carry := local(mul, "i", Int), local(mul, "t", Int), local(mul, "carry", Int)
+	mul.Body = []Stmt{
+		set(i, num(0)),
+		set(carry, num(0)),
+		while(op(Lt, get(i), num(limbs)),
+			set(t, op(Add, op(Mul, peek(limb(get(a), get(i))), get(m)), get(carry))),
+			poke(limb(get(a), get(i)), op(And, get(t), num(0xffffffff))),
+			set(carry, op(Shr, get(t), num(32))),
+			add(i, 1)),
+		&Return{},
+	}
+
+	// shl multiplies the big integer at a by 2^n.
+	a, n := param("a", Int), param("n", Int)
+	shl := rt.function("golite_big_shl", Void, a, n)
+	shl.Body = []Stmt{
+		while(op(Ge, get(n), num(30)), do(mul, get(a), num(1<<30)), add(n, -30)),
+		do(mul, get(a), op(Shl, num(1), get(n))),
+		&Return{},
+	}
+
+	// pow10 multiplies the big integer at a by 10^k.
+	a, k := param("a", Int), param("k", Int)
+	pow10 := rt.function("golite_big_pow10", Void, a, k)
+	m = local(pow10, "m", Int)
+	pow10.Body = []Stmt{
+		while(op(Ge, get(k), num(9)), do(mul, get(a), num(1e9)), add(k, -9)),
+		set(m, num(1)),
+		while(op(Gt, get(k), num(0)), set(m, op(Mul, get(m), num(10))), add(k, -1)),
+		do(mul, get(a), get(m)),
+		&Return{},
+	}
+
+	// sum sets the big integer at d to the sum of those at a and b.
+	d, a, b := param("d", Int), param("a", Int), param("b", Int)
+	sum := rt.function("golite_big_add", Void, d, a, b)
+	i, t, carry = local(sum, "i", Int), local(sum, "t", Int), local(sum, "carry", Int)
+	sum.Body = []Stmt{
+		set(i, num(0)),
+		set(carry, num(0)),
+		while(op(Lt, get(i), num(limbs)),
+			set(t, op(Add, op(Add, peek(limb(get(a), get(i))), peek(limb(get(b), get(i)))), get(carry))),
+			poke(limb(get(d), get(i)), op(And, get(t), num(0xffffffff))),
+			set(carry, op(Shr, get(t), num(32))),
+			add(i, 1)),
+		&Return{},
+	}
+
+	// sub subtracts the big integer at b from the one at a, which must be
+	// at least as large.
+	a, b = param("a", Int), param("b", Int)
+	sub := rt.function("golite_big_sub", Void, a, b)
+	i, t, borrow := local(sub, "i", Int), local(sub, "t", Int), local(sub, "borrow", Int)
+	sub.Body = []Stmt{
+		set(i, num(0)),
+		set(borrow, num(0)),
+		while(op(Lt, get(i), num(limbs)),
+			set(t, op(Sub, op(Sub, peek(limb(get(a), get(i))), peek(limb(get(b), get(i)))), get(borrow))),
+			set(borrow, num(0)),
+			when(op(Lt, get(t), num(0)), add(t, 1<<32), set(borrow, num(1))),
+			poke(limb(get(a), get(i)), get(t)),
+			add(i, 1)),
+		&Return{},
+	}
+
+	// cmp returns -1, 0 or 1 as the big integer at a is less than, equal
+	// to or greater than the one at b.
+	a, b = param("a", Int), param("b", Int)
+	cmp := rt.function("golite_big_cmp", Int, a, b)
+	i, x, y := local(cmp, "i", Int), local(cmp, "x", Int), local(cmp, "y", Int)
+	cmp.Body = []Stmt{
+		set(i, num(limbs-1)),
+		while(op(Ge, get(i), num(0)),
+			set(x, peek(limb(get(a), get(i)))),
+			set(y, peek(limb(get(b), get(i)))),
+			when(op(Lt, get(x), get(y)), ret(num(-1))),
+			when(op(Gt, get(x), get(y)), ret(num(1))),
+			add(i, -1)),
+		ret(num(0)),
+	}
+
+	v = param("v", Float)
+	emit := rt.function("golite_emit_float", Void, v)
+	local := func(name string, t Type) *Var { return local(emit, name, t) }
+	bits, exp, frac := local("bits", Int), local("exp", Int), local("frac", Int)
+	f, e, even := local("f", Int), local("e", Int), local("even", Bool)
+	k, c, digit, nd := local("k", Int), local("c", Int), local("digit", Int), local("nd", Int)
+	low, up, j := local("low", Bool), local("up", Bool), local("j", Int)
+	r, s, mplus, mminus, high := addr(rt.r), addr(rt.s), addr(rt.mplus), addr(rt.mminus), addr(rt.high)
+	nan, nanLen := rt.text("NaN")
+	inf, infLen := rt.text("+Inf")
+	negInf, negInfLen := rt.text("-Inf")
+	hidden := num(1 << 52)
+	// rounds reports whether c, a comparison with a bound of the interval
+	// of numbers that read back as the value, puts a number on the bound
+	// inside it: bounds belong to the interval of floats with an even
+	// mantissa, which reading rounds halfway cases to.
+	rounds := func(c Expr, sign int64) Expr {
+		return op(LogOr, op(Eq, c, num(sign)), op(LogAnd, op(Eq, c, num(0)), get(even)))
+	}
+	emitByte := func(b Expr) Stmt { return do(rt.emitByte, b) }
+	emitZeros := func(from, to Expr) []Stmt {
+		return []Stmt{set(j, from), while(op(Lt, get(j), to), emitByte(num('0')), add(j, 1))}
+	}
+	digits := addr(rt.digits)
+
+	emit.Body = []Stmt{
+		set(bits, &Bits{X: get(v)}),
+		set(exp, op(And, op(Shr, get(bits), num(52)), num(0x7ff))),
+		set(frac, op(And, get(bits), num(1<<52-1))),
+		when(op(Eq, get(exp), num(0x7ff)),
+			when(op(Ne, get(frac), num(0)), do(rt.emit, nan, nanLen), &Return{}),
+			&If{Cond: op(Lt, get(bits), num(0)),
+				Then: []Stmt{do(rt.emit, negInf, negInfLen)},
+				Else: []Stmt{do(rt.emit, inf, infLen)}},
+			&Return{}),
+		when(op(Lt, get(bits), num(0)), emitByte(num('-'))),
+		when(op(LogAnd, op(Eq, get(exp), num(0)), op(Eq, get(frac), num(0))), emitByte(num('0')), &Return{}),
+
+		// The value is f*2^e.
+		set(f, get(frac)),
+		set(e, num(-1074)),
+		when(op(Ne, get(exp), num(0)), set(f, op(Add, get(frac), hidden)), set(e, op(Sub, get(exp), num(1075)))),
+		set(even, op(Eq, op(And, get(f), num(1)), num(0))),
+
+		// The gap to the next float down is half the gap to the next one
+		// up when f is the smallest mantissa of a binade.
+		&If{Cond: op(Ge, get(e), num(0)),
+			Then: []Stmt{&If{Cond: op(Ne, get(f), hidden),
+				Then: []Stmt{
+					do(assign, r, get(f)), do(shl, r, op(Add, get(e), num(1))), do(assign, s, num(2)),
+					do(assign, mplus, num(1)), do(shl, mplus, get(e)), do(assign, mminus, num(1)), do(shl, mminus, get(e)),
+				},
+				Else: []Stmt{
+					do(assign, r, get(f)), do(shl, r, op(Add, get(e), num(2))), do(assign, s, num(4)),
+					do(assign, mplus, num(1)), do(shl, mplus, op(Add, get(e), num(1))), do(assign, mminus, num(1)), do(shl, mminus, get(e)),
+				}}},
+			Else: []Stmt{&If{Cond: op(LogOr, op(Le, get(exp), num(1)), op(Ne, get(f), hidden)),
+				Then: []Stmt{
+					do(assign, r, op(Mul, get(f), num(2))), do(assign, s, num(1)), do(shl, s, op(Sub, num(1), get(e))),
+					do(assign, mplus, num(1)), do(assign, mminus, num(1)),
+				},
+				Else: []Stmt{
+					do(assign, r, op(Mul, get(f), num(4))), do(assign, s, num(1)), do(shl, s, op(Sub, num(2), get(e))),
+					do(assign, mplus, num(2)), do(assign, mminus, num(1)),
+				}}}},
+
+		// Estimate the decimal exponent k from the binary one, scale r/s
+		// by 10^-k, and then correct k until the value plus mplus/s is
+		// below 1 but not below 0.1.
+		set(k, &Convert{To: Int, X: op(Mul, &Convert{To: Float, X: op(Add, get(e), num(52))}, &Const{T: Float, Float: 0.30102999566398114})}),
+		&If{Cond: op(Ge, get(k), num(0)),
+			Then: []Stmt{do(pow10, s, get(k))},
+			Else: []Stmt{
+				do(pow10, r, op(Sub, num(0), get(k))),
+				do(pow10, mplus, op(Sub, num(0), get(k))),
+				do(pow10, mminus, op(Sub, num(0), get(k))),
+			}},
+		while(nil,
+			do(sum, high, r, mplus),
+			when(not(rounds(call(cmp, high, s), 1)), &Break{}),
+			do(mul, s, num(10)),
+			add(k, 1)),
+		while(nil,
+			do(sum, high, r, mplus),
+			do(mul, high, num(10)),
+			when(rounds(call(cmp, high, s), 1), &Break{}),
+			do(mul, r, num(10)), do(mul, mplus, num(10)), do(mul, mminus, num(10)),
+			add(k, -1)),
+
+		// Generate digits until the number they form reads back as the
+		// value, rounding the last one to the nearer of the two that do.
+		set(nd, num(0)),
+		while(nil,
+			do(mul, r, num(10)), do(mul, mplus, num(10)), do(mul, mminus, num(10)),
+			set(digit, num(0)),
+			while(op(Ge, call(cmp, r, s), num(0)), do(sub, r, s), add(digit, 1)),
+			set(low, rounds(call(cmp, r, mminus), -1)),
+			do(sum, high, r, mplus),
+			set(up, rounds(call(cmp, high, s), 1)),
+			&If{Cond: op(LogAnd, get(low), get(up)),
+				Then: []Stmt{
+					// Halfway between two digits, pick the even one.
+					do(sum, high, r, r),
+					set(c, call(cmp, high, s)),
+					when(op(LogOr, op(Gt, get(c), num(0)), op(LogAnd, op(Eq, get(c), num(0)), op(Eq, op(And, get(digit), num(1)), num(1)))), add(digit, 1)),
+				},
+				Else: []Stmt{when(get(up), add(digit, 1))}},
+			pokeByte(op(Add, digits, get(nd)), op(Add, num('0'), get(digit))),
+			add(nd, 1),
+			when(op(LogOr, get(low), get(up)), &Break{})),
+
+		// The value is 0.digits * 10^k. Format it as %e if its exponent is
+		// less than -4 or at least 6, and as %f otherwise.
+		set(c, op(Sub, get(k), num(1))),
+		&If{Cond: op(LogOr, op(Lt, get(c), num(-4)), op(Ge, get(c), num(6))),
+			Then: []Stmt{
+				emitByte(peekByte(digits)),
+				when(op(Gt, get(nd), num(1)), emitByte(num('.')), do(rt.emit, op(Add, digits, num(1)), op(Sub, get(nd), num(1)))),
+				emitByte(num('e')),
+				&If{Cond: op(Lt, get(c), num(0)),
+					Then: []Stmt{emitByte(num('-')), set(c, op(Sub, num(0), get(c)))},
+					Else: []Stmt{emitByte(num('+'))}},
+				when(op(Lt, get(c), num(10)), emitByte(num('0'))),
+				do(rt.emitInt, get(c)),
+			},
+			Else: []Stmt{&If{Cond: op(Le, get(k), num(0)),
+				Then: append(append([]Stmt{emitByte(num('0')), emitByte(num('.'))},
+					emitZeros(get(k), num(0))...),
+					do(rt.emit, digits, get(nd))),
+				Else: []Stmt{&If{Cond: op(Ge, get(k), get(nd)),
+					Then: append([]Stmt{do(rt.emit, digits, get(nd))}, emitZeros(get(nd), get(k))...),
+					Else: []Stmt{
+						do(rt.emit, digits, get(k)),
+						emitByte(num('.')),
+						do(rt.emit, op(Add, digits, get(k)), op(Sub, get(nd), get(k))),
+					}}}}}},
+		&Return{},
+	}
+
+	rt.printFloat = rt.function("golite_print_float", Void, param("v", Float))
+	rt.printFloat.Body = []Stmt{
+		do(emit, get(rt.printFloat.Params[0])),
+		do(rt.emitByte, num('\n')),
+		&Return{},
+	}
+}
+
+// statements lowers printing and the other statements that rely on the
+// runtime in stmts. In main, returning flushes the output first.
+func (rt *runtime) statements(stmts []Stmt, main bool) []Stmt {
+	var out []Stmt
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *Print:
+			fn := map[Type]*Function{Int: rt.printInt, Float: rt.printFloat, Bool: rt.printBool, String: rt.printStr}[s.Value.Type()]
+			out = append(out, do(fn, rt.expression(s.Value)))
+		case *Assign:
+			out = append(out, &Assign{Var: s.Var, Value: rt.expression(s.Value)})
+		case *Eval:
+			out = append(out, &Eval{X: rt.expression(s.X)})
+		case *If:
+			out = append(out, &If{Cond: rt.expression(s.Cond), Then: rt.statements(s.Then, main), Else: rt.statements(s.Else, main)})
+		case *Loop:
+			loop := &Loop{Body: rt.statements(s.Body, main), Post: rt.statements(s.Post, main)}
+			if s.Cond != nil {
+				loop.Cond = rt.expression(s.Cond)
+			}
+			out = append(out, loop)
+		case *Return:
+			if main {
+				out = append(out, do(rt.flush))
+			}
+			if s.Value != nil {
+				out = append(out, &Return{Value: rt.expression(s.Value)})
+			} else {
+				out = append(out, s)
+			}
+		default:
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (rt *runtime) expression(e Expr) Expr {
+	switch e := e.(type) {
+	case *Const:
+		if e.T == String {
+			return &Addr{Data: rt.strings[e.Index]}
+		}
+	case *Unary:
+		return &Unary{Op: e.Op, X: rt.expression(e.X)}
+	case *Binary:
+		x, y := rt.expression(e.X), rt.expression(e.Y)
+		if e.Traps() && !safe(e.Op, y) {
+			fn := map[Op]*Function{Div: rt.div, Rem: rt.rem, Shl: rt.shl, Shr: rt.shr}[e.Op]
+			return call(fn, x, y, num(int64(e.Line)), num(int64(e.Column)))
+		}
+		return &Binary{Op: e.Op, X: x, Y: y, Line: e.Line, Column: e.Column}
+	case *Call:
+		call := &Call{Func: e.Func}
+		for _, arg := range e.Args {
+			call.Args = append(call.Args, rt.expression(arg))
+		}
+		return call
+	case *Convert:
+		return &Convert{To: e.To, X: rt.expression(e.X)}
+	}
+	return e
+}
+
+// safe reports whether an operator that can stop the program cannot do so
+// with y, a constant, as its right operand; it then needs no check.
+func safe(o Op, y Expr) bool {
+	c, ok := y.(*Const)
+	if !ok {
+		return false
+	}
+	if o == Div || o == Rem {
+		return c.Int != 0 && c.Int != -1
+	}
+	return c.Int >= 0 && c.Int < 64
+}
@@ -1,12 +1,30 @@
 package lexer
 
-import "unicode"
+import (
+	"strings"
+	"unicode"
+)
 
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character of the token
+	End     Position // position immediately after the token
+
+	// Comments holds the comments between the previous token and this one,
+	// when the lexer was created by NewWithComments.
+	Comments []Comment
+}
+
+// Comment is a // line comment or a /* */ block comment. Comments are
+// trivia: the parser ignores them, but tools such as a formatter can recover
+// them from the tokens they precede.
+type Comment struct {
+	Text string   // the comment including its delimiters, without the newline ending a line comment
+	Pos  Position // position of the first character of the comment
+	End  Position // position immediately after the comment
 }
 
 const (
@@ -16,8 +34,8 @@ const (
 	// Identifiers + literals
 	IDENT  = "IDENT"
 	INT    = "INT"
-	FLOAT  = "FLOAT"  // Optional
-	STRING = "STRING" // Optional
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
 
 	// Operators
 	ASSIGN   = "="
@@ -26,39 +44,80 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
 	EQ       = "=="
 	NOT_EQ   = "!="
 
+	// Logical operators
+	AND = "&&"
+	OR  = "||"
+
+	// Bitwise operators
+	AMPERSAND = "&"
+	PIPE      = "|"
+	CARET     = "^"
+	SHL       = "<<"
+	SHR       = ">>"
+
+	// Assignment operators
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	DEFINE          = ":="
+	INC             = "++"
+	DEC             = "--"
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 
 	// Keywords
-	FUNC   = "FUNC"
-	LET    = "LET"
-	TRUE   = "TRUE"
-	FALSE  = "FALSE"
-	IF     = "IF"
-	ELSE   = "ELSE"
-	RETURN = "RETURN"
-	PRINT  = "PRINT"
+	FUNC     = "FUNC"
+	LET      = "LET"
+	TRUE     = "TRUE"
+	FALSE    = "FALSE"
+	IF       = "IF"
+	ELSE     = "ELSE"
+	RETURN   = "RETURN"
+	PRINT    = "PRINT"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	MAP      = "MAP"
+	TYPE     = "TYPE"
+	STRUCT   = "STRUCT"
+	DEFER    = "DEFER"
 )
 
 var keywords = map[string]TokenType{
-	"func":   FUNC,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"print":  PRINT,
+	"func":     FUNC,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"print":    PRINT,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"map":      MAP,
+	"type":     TYPE,
+	"struct":   STRUCT,
+	"defer":    DEFER,
 }
 
 type Lexer struct {
@@ -66,15 +125,34 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column locate ch in the input.
+	line   int
+	column int
+
+	keepComments bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// NewWithComments returns a lexer that attaches the comments it skips to the
+// Comments field of the following token. Comments at the end of the input
+// are attached to the EOF token.
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.keepComments = true
+	return l
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // NUL character
 	} else {
@@ -82,6 +160,12 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
+	l.column++
+}
+
+// pos returns the position of the current character.
+func (l *Lexer) pos() Position {
+	return Position{Offset: l.position, Line: l.line, Column: l.column}
 }
 
 func (l *Lexer) peekChar() byte {
@@ -92,9 +176,17 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) NextToken() Token {
-	var tok Token
+	comments := l.skipWhitespaceAndComments()
+	tok := l.nextToken()
+	if l.keepComments {
+		tok.Comments = comments
+	}
+	return tok
+}
 
-	l.skipWhitespace()
+func (l *Lexer) nextToken() Token {
+	var tok Token
+	start := l.pos()
 
 	switch l.ch {
 	case '=':
@@ -107,9 +199,23 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(PLUS, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(PLUS_ASSIGN)
+		case '+':
+			tok = l.twoCharToken(INC)
+		default:
+			tok = newToken(PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(MINUS, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(MINUS_ASSIGN)
+		case '-':
+			tok = l.twoCharToken(DEC)
+		default:
+			tok = newToken(MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -120,13 +226,61 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(SLASH, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(SLASH_ASSIGN)
+		case '*':
+			// skipWhitespaceAndComments leaves only block comments that
+			// are never closed.
+			tok = Token{Type: ILLEGAL, Literal: l.input[l.position:]}
+			for l.ch != 0 {
+				l.readChar()
+			}
+			tok.Pos, tok.End = start, l.pos()
+			return tok
+		default:
+			tok = newToken(SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(PERCENT, l.ch)
 	case '<':
-		tok = newToken(LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(LT_EQ)
+		case '<':
+			tok = l.twoCharToken(SHL)
+		default:
+			tok = newToken(LT, l.ch)
+		}
 	case '>':
-		tok = newToken(GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken(GT_EQ)
+		case '>':
+			tok = l.twoCharToken(SHR)
+		default:
+			tok = newToken(GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.twoCharToken(AND)
+		} else {
+			tok = newToken(AMPERSAND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.twoCharToken(OR)
+		} else {
+			tok = newToken(PIPE, l.ch)
+		}
+	case '^':
+		tok = newToken(CARET, l.ch)
 	case ';':
 		tok = newToken(SEMICOLON, l.ch)
 	case '(':
@@ -135,36 +289,103 @@ func (l *Lexer) NextToken() Token {
 		tok = newToken(RPAREN, l.ch)
 	case ',':
 		tok = newToken(COMMA, l.ch)
+	case ':':
+		if l.peekChar() == '=' {
+			tok = l.twoCharToken(DEFINE)
+		} else {
+			tok = newToken(COLON, l.ch)
+		}
+	case '.':
+		tok = newToken(DOT, l.ch)
+	case '[':
+		tok = newToken(LBRACKET, l.ch)
+	case ']':
+		tok = newToken(RBRACKET, l.ch)
 	case '{':
 		tok = newToken(LBRACE, l.ch)
 	case '}':
 		tok = newToken(RBRACE, l.ch)
+	case '"':
+		literal, ok := l.readString()
+		tok = Token{Type: STRING, Literal: literal}
+		if !ok {
+			tok.Type = ILLEGAL
+		}
+		tok.Pos, tok.End = start, l.pos()
+		return tok
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
+		tok.Pos, tok.End = start, start
+		return tok
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = lookupIdent(tok.Literal)
+			tok.Pos, tok.End = start, l.pos()
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = INT
 			tok.Literal = l.readNumber()
+			if isFloatLiteral(tok.Literal) {
+				tok.Type = FLOAT
+			}
+			tok.Pos, tok.End = start, l.pos()
 			return tok
 		} else {
 			tok = newToken(ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
+	tok.Pos, tok.End = start, l.pos()
 	return tok
 }
 
+// twoCharToken consumes the current character and the next one as a single
+// token of type t.
+func (l *Lexer) twoCharToken(t TokenType) Token {
+	ch := l.ch
+	l.readChar()
+	return Token{Type: t, Literal: string(ch) + string(l.ch)}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
+// skipWhitespaceAndComments skips to the start of the next token and returns
+// the comments on the way. A block comment without its closing */ is left
+// for NextToken to report.
+func (l *Lexer) skipWhitespaceAndComments() []Comment {
+	var comments []Comment
+	for {
+		l.skipWhitespace()
+		if l.ch != '/' {
+			return comments
+		}
+		start := l.pos()
+		switch l.peekChar() {
+		case '/':
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case '*':
+			end := strings.Index(l.input[l.position+2:], "*/")
+			if end < 0 {
+				return comments
+			}
+			for l.position < start.Offset+2+end+2 {
+				l.readChar()
+			}
+		default:
+			return comments
+		}
+		comments = append(comments, Comment{Text: l.input[start.Offset:l.position], Pos: start, End: l.pos()})
+	}
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) || isDigit(l.ch) {
@@ -173,18 +394,75 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// readString reads a double-quoted string literal, returning its source
+// text including the quotes. Escape sequences are left for the parser to
+// interpret. It reports false if the literal is not terminated on the same
+// line.
+func (l *Lexer) readString() (string, bool) {
+	position := l.position
+	for {
+		l.readChar()
+		switch l.ch {
+		case '\\':
+			if p := l.peekChar(); p != '\n' && p != 0 {
+				l.readChar()
+			}
+		case '"':
+			l.readChar()
+			return l.input[position:l.position], true
+		case '\n', 0:
+			return l.input[position:l.position], false
+		}
+	}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || unicode.IsLetter(rune(ch))
 }
 
+// readNumber reads an integer or floating-point literal. A fraction needs
+// at least one digit after the dot and an exponent needs at least one digit,
+// so in `1.x` or `1e` the number ends before the dot or the e.
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isDigit(l.ch) {
+	l.readDigits()
+	if l.ch == '.' && isDigit(l.peekChar()) {
 		l.readChar()
+		l.readDigits()
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if (next == '+' || next == '-') && l.readPosition+1 < len(l.input) {
+			next = l.input[l.readPosition+1]
+			if isDigit(next) {
+				l.readChar()
+			}
+		}
+		if isDigit(next) {
+			l.readChar()
+			l.readDigits()
+		}
 	}
 	return l.input[position:l.position]
 }
 
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) {
+		l.readChar()
+	}
+}
+
+// isFloatLiteral reports whether a literal read by readNumber has a fraction
+// or an exponent.
+func isFloatLiteral(literal string) bool {
+	for i := 0; i < len(literal); i++ {
+		if !isDigit(literal[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
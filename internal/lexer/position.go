@@ -0,0 +1,24 @@
+package lexer
+
+import "fmt"
+
+// Position describes a location in the source text.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in bytes, starting at 1
+}
+
+// IsValid reports whether the position refers to a location in the source.
+// Tokens synthesized by later passes (e.g. the optimizer) may carry the zero
+// Position.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
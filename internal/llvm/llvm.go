@@ -0,0 +1,461 @@
+// Package llvm generates textual LLVM IR (.ll files) from programs, for
+// llc or clang to compile to native code, or lli to run.
+//
+// Variables live in stack slots made by alloca in the entry block of their
+// function and are read and written with loads and stores, the way clang
+// emits code before optimization; LLVM's mem2reg pass promotes them to SSA
+// registers. The output uses typed pointers, which LLVM 14 requires.
+package llvm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/ir"
+	"golite.dev/mvp/internal/semantics"
+)
+
+// Generator is the LLVM IR code generator.
+type Generator struct {
+	errors  []*diagnostics.Diagnostic
+	runtime []*runtimePart // support code the program needs
+
+	prog   *ir.Program
+	out    *strings.Builder // the function being generated
+	fn     *ir.Function
+	temps  int // the number of the last temporary of fn
+	labels int // the number of the last group of labels of fn
+	// block is the label of the block being generated, or "" after a
+	// terminator, when the code that follows is unreachable.
+	block string
+	loops []loopLabels
+}
+
+// loopLabels are the targets of break and continue in a loop.
+type loopLabels struct {
+	post, end string
+}
+
+// New creates a new LLVM IR code generator.
+func New() *Generator {
+	return &Generator{}
+}
+
+// Errors returns the diagnostics for programs the LLVM backend cannot
+// lower.
+func (g *Generator) Errors() []*diagnostics.Diagnostic {
+	return g.errors
+}
+
+// Generate takes an AST program and returns an equivalent LLVM module. The
+// program is type-checked and lowered to package ir first; if either
+// fails, Generate returns "" and Errors reports why.
+func (g *Generator) Generate(program *ast.Program) string {
+	checker := semantics.New()
+	checker.Check(program)
+	if len(checker.Errors()) > 0 {
+		g.errors = append(g.errors, checker.Errors()...)
+		return ""
+	}
+	prog, errs := ir.Lower(program, checker, "LLVM", Reserved()...)
+	if len(errs) > 0 {
+		g.errors = append(g.errors, errs...)
+		return ""
+	}
+	g.prog = prog
+
+	var funcs strings.Builder
+	for _, fn := range prog.Functions {
+		g.function(&funcs, fn)
+	}
+
+	var out strings.Builder
+	out.WriteString("; ModuleID = 'golite'\nsource_filename = \"golite\"\n\n")
+	out.WriteString("%golite.string = type { i8*, i64 }\n\n")
+	for i, s := range prog.Strings {
+		fmt.Fprintf(&out, "@.str.%d = private unnamed_addr constant [%d x i8] c\"%s\"\n", i, len(s), escape(s))
+	}
+	for _, v := range prog.Globals {
+		fmt.Fprintf(&out, "@%s = internal global %s %s\n", v.Name, llType(v.Type), zero(v.Type))
+	}
+	if len(prog.Strings) > 0 || len(prog.Globals) > 0 {
+		out.WriteString("\n")
+	}
+	for _, part := range g.runtime {
+		out.WriteString(part.code)
+		out.WriteString("\n")
+	}
+	out.WriteString(funcs.String())
+
+	declared := map[string]bool{}
+	for _, part := range g.runtime {
+		for _, name := range part.declares {
+			declared[name] = true
+		}
+	}
+	var declares []string
+	for name := range declared {
+		declares = append(declares, libc[name])
+	}
+	sort.Strings(declares)
+	for _, d := range declares {
+		out.WriteString(d + "\n")
+	}
+	return out.String()
+}
+
+// llType returns the LLVM type of values of type t.
+func llType(t ir.Type) string {
+	switch t {
+	case ir.Int:
+		return "i64"
+	case ir.Float:
+		return "double"
+	case ir.Bool:
+		return "i1"
+	case ir.String:
+		return "%golite.string"
+	default:
+		return "void"
+	}
+}
+
+func zero(t ir.Type) string {
+	switch t {
+	case ir.Float:
+		return "0.0"
+	case ir.Bool:
+		return "false"
+	case ir.String:
+		return "zeroinitializer"
+	default:
+		return "0"
+	}
+}
+
+// escape quotes s for an LLVM c"..." constant. Bytes outside printable
+// ASCII are written as two hex digits.
+func escape(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch < 0x20 || ch >= 0x7f || ch == '"' || ch == '\\' {
+			fmt.Fprintf(&out, "\\%02X", ch)
+		} else {
+			out.WriteByte(ch)
+		}
+	}
+	return out.String()
+}
+
+// floatLiteral formats v as an LLVM double constant. Decimal constants
+// must be exact, so values that have no short exact form are written as
+// the hexadecimal bits of the double.
+func floatLiteral(v float64) string {
+	s := strconv.FormatFloat(v, 'e', 6, 64)
+	if back, err := strconv.ParseFloat(s, 64); err == nil && back == v && math.Signbit(back) == math.Signbit(v) {
+		return s
+	}
+	return fmt.Sprintf("0x%016X", math.Float64bits(v))
+}
+
+// function emits fn. Parameters arrive as the numbered values %0, %1, ...
+// and are stored to their slots, named like every variable slot after the
+// variable with .addr appended; temporaries are %t.1, %t.2, ... and labels
+// carry the number of the statement they belong to, so none of these
+// names can collide.
+func (g *Generator) function(out *strings.Builder, fn *ir.Function) {
+	g.out, g.fn, g.temps, g.labels, g.block = out, fn, 0, 0, ""
+
+	var params []string
+	for i, p := range fn.Params {
+		params = append(params, fmt.Sprintf("%s %%%d", llType(p.Type), i))
+	}
+	if fn == g.prog.Main {
+		out.WriteString("define i32 @main() {\n")
+	} else {
+		fmt.Fprintf(out, "define internal %s @%s(%s) {\n", llType(fn.Result), fn.Name, strings.Join(params, ", "))
+	}
+	g.label("entry")
+	for _, v := range append(append([]*ir.Var{}, fn.Params...), fn.Locals...) {
+		g.emit("%%%s.addr = alloca %s", v.Name, llType(v.Type))
+	}
+	for i, p := range fn.Params {
+		g.emit("store %s %%%d, %s* %%%s.addr", llType(p.Type), i, llType(p.Type), p.Name)
+	}
+	g.statements(fn.Body)
+	out.WriteString("}\n\n")
+}
+
+func (g *Generator) emit(format string, args ...interface{}) {
+	if g.block == "" {
+		// Code after a return, break or continue needs a block of its own,
+		// which nothing jumps to.
+		g.label(g.newLabels("dead")[0])
+	}
+	g.out.WriteString("  ")
+	fmt.Fprintf(g.out, format, args...)
+	g.out.WriteString("\n")
+}
+
+// terminate emits the instruction that ends the current block.
+func (g *Generator) terminate(format string, args ...interface{}) {
+	g.emit(format, args...)
+	g.block = ""
+}
+
+// label starts a block.
+func (g *Generator) label(name string) {
+	if g.block != "" {
+		g.terminate("br label %%%s", name)
+	}
+	g.out.WriteString(name + ":\n")
+	g.block = name
+}
+
+// newLabels returns labels for the blocks of one statement or expression,
+// made unique with a number.
+func (g *Generator) newLabels(names ...string) []string {
+	g.labels++
+	labels := make([]string, len(names))
+	for i, name := range names {
+		labels[i] = fmt.Sprintf("%s.%d", name, g.labels)
+	}
+	return labels
+}
+
+func (g *Generator) temp() string {
+	g.temps++
+	return fmt.Sprintf("%%t.%d", g.temps)
+}
+
+// slot returns the pointer to the storage of v.
+func slot(v *ir.Var) string {
+	if v.Global {
+		return "@" + v.Name
+	}
+	return "%" + v.Name + ".addr"
+}
+
+func (g *Generator) statements(stmts []ir.Stmt) {
+	for _, stmt := range stmts {
+		g.statement(stmt)
+	}
+}
+
+func (g *Generator) statement(stmt ir.Stmt) {
+	switch s := stmt.(type) {
+	case *ir.Assign:
+		value := g.expression(s.Value)
+		g.emit("store %s %s, %s* %s", llType(s.Var.Type), value, llType(s.Var.Type), slot(s.Var))
+	case *ir.Print:
+		g.print(s.Value)
+	case *ir.Eval:
+		g.expression(s.X)
+	case *ir.If:
+		cond := g.expression(s.Cond)
+		l := g.newLabels("if.then", "if.else", "if.end")
+		if len(s.Else) == 0 {
+			l[1] = l[2]
+		}
+		g.terminate("br i1 %s, label %%%s, label %%%s", cond, l[0], l[1])
+		g.label(l[0])
+		g.statements(s.Then)
+		if len(s.Else) > 0 {
+			g.branch(l[2])
+			g.label(l[1])
+			g.statements(s.Else)
+		}
+		g.label(l[2])
+	case *ir.Loop:
+		l := g.newLabels("loop.cond", "loop.body", "loop.post", "loop.end")
+		g.label(l[0])
+		if s.Cond != nil {
+			cond := g.expression(s.Cond)
+			g.terminate("br i1 %s, label %%%s, label %%%s", cond, l[1], l[3])
+		}
+		g.label(l[1])
+		g.loops = append(g.loops, loopLabels{post: l[2], end: l[3]})
+		g.statements(s.Body)
+		g.loops = g.loops[:len(g.loops)-1]
+		g.label(l[2])
+		g.statements(s.Post)
+		g.branch(l[0])
+		g.label(l[3])
+	case *ir.Break:
+		g.branch(g.loops[len(g.loops)-1].end)
+	case *ir.Continue:
+		g.branch(g.loops[len(g.loops)-1].post)
+	case *ir.Return:
+		switch {
+		case g.fn == g.prog.Main:
+			g.terminate("ret i32 0")
+		case s.Value == nil:
+			g.terminate("ret void")
+		default:
+			value := g.expression(s.Value)
+			g.terminate("ret %s %s", llType(s.Value.Type()), value)
+		}
+	}
+}
+
+// branch jumps to label, unless the current block has ended already.
+func (g *Generator) branch(label string) {
+	if g.block != "" {
+		g.terminate("br label %%%s", label)
+	}
+}
+
+func (g *Generator) print(value ir.Expr) {
+	v := g.expression(value)
+	switch value.Type() {
+	case ir.Int:
+		g.require(printIntRuntime)
+		g.emit("call void @golite_print_int(i64 %s)", v)
+	case ir.Float:
+		g.require(printFloatRuntime)
+		g.emit("call void @golite_print_float(double %s)", v)
+	case ir.Bool:
+		g.require(printBoolRuntime)
+		g.emit("call void @golite_print_bool(i1 %s)", v)
+	case ir.String:
+		g.require(printStringRuntime)
+		g.emit("call void @golite_print_string(%%golite.string %s)", v)
+	}
+}
+
+// expression emits the code that computes e and returns its value: a
+// temporary or a constant.
+func (g *Generator) expression(e ir.Expr) string {
+	switch e := e.(type) {
+	case *ir.Const:
+		return g.constant(e)
+	case *ir.Load:
+		t := g.temp()
+		g.emit("%s = load %s, %s* %s", t, llType(e.Var.Type), llType(e.Var.Type), slot(e.Var))
+		return t
+	case *ir.Unary:
+		x := g.expression(e.X)
+		t := g.temp()
+		switch {
+		case e.Op == ir.Not:
+			g.emit("%s = xor i1 %s, true", t, x)
+		case e.X.Type() == ir.Float:
+			g.emit("%s = fneg double %s", t, x)
+		default:
+			g.emit("%s = sub i64 0, %s", t, x)
+		}
+		return t
+	case *ir.Binary:
+		if e.Op == ir.LogAnd || e.Op == ir.LogOr {
+			return g.logical(e)
+		}
+		x := g.expression(e.X)
+		y := g.expression(e.Y)
+		return g.binary(e, x, y)
+	case *ir.Call:
+		var args []string
+		for _, arg := range e.Args {
+			v := g.expression(arg)
+			args = append(args, llType(arg.Type())+" "+v)
+		}
+		if e.Func.Result == ir.Void {
+			g.emit("call void @%s(%s)", e.Func.Name, strings.Join(args, ", "))
+			return ""
+		}
+		t := g.temp()
+		g.emit("%s = call %s @%s(%s)", t, llType(e.Func.Result), e.Func.Name, strings.Join(args, ", "))
+		return t
+	case *ir.Convert:
+		x := g.expression(e.X)
+		t := g.temp()
+		if e.To == ir.Float {
+			g.emit("%s = sitofp i64 %s to double", t, x)
+		} else {
+			g.emit("%s = fptosi double %s to i64", t, x)
+		}
+		return t
+	}
+	return ""
+}
+
+func (g *Generator) constant(c *ir.Const) string {
+	switch c.T {
+	case ir.Float:
+		return floatLiteral(c.Float)
+	case ir.Bool:
+		if c.Int != 0 {
+			return "true"
+		}
+		return "false"
+	case ir.String:
+		n := len(g.prog.Strings[c.Index])
+		return fmt.Sprintf("{ i8* getelementptr inbounds ([%d x i8], [%d x i8]* @.str.%d, i64 0, i64 0), i64 %d }", n, n, c.Index, n)
+	default:
+		return strconv.FormatInt(c.Int, 10)
+	}
+}
+
+var intOps = map[ir.Op]string{
+	ir.Add: "add", ir.Sub: "sub", ir.Mul: "mul", ir.And: "and", ir.Or: "or", ir.Xor: "xor",
+	ir.Eq: "icmp eq", ir.Ne: "icmp ne", ir.Lt: "icmp slt", ir.Le: "icmp sle", ir.Gt: "icmp sgt", ir.Ge: "icmp sge",
+}
+
+var floatOps = map[ir.Op]string{
+	ir.Add: "fadd", ir.Sub: "fsub", ir.Mul: "fmul", ir.Div: "fdiv",
+	ir.Eq: "fcmp oeq", ir.Ne: "fcmp une", ir.Lt: "fcmp olt", ir.Le: "fcmp ole", ir.Gt: "fcmp ogt", ir.Ge: "fcmp oge",
+}
+
+// trapping names the runtime functions for the operators that can stop
+// the program.
+var trapping = map[ir.Op]string{
+	ir.Div: "golite_div", ir.Rem: "golite_mod", ir.Shl: "golite_shl", ir.Shr: "golite_shr",
+}
+
+func (g *Generator) binary(e *ir.Binary, x, y string) string {
+	t := g.temp()
+	operand := e.X.Type()
+	switch {
+	case e.Traps():
+		if e.Op == ir.Shl || e.Op == ir.Shr {
+			g.require(shiftRuntime)
+		} else {
+			g.require(divisionRuntime)
+		}
+		g.emit("%s = call i64 @%s(i64 %s, i64 %s, i32 %d, i32 %d)", t, trapping[e.Op], x, y, e.Line, e.Column)
+	case operand == ir.Float:
+		g.emit("%s = %s double %s, %s", t, floatOps[e.Op], x, y)
+	default:
+		g.emit("%s = %s %s %s, %s", t, intOps[e.Op], llType(operand), x, y)
+	}
+	return t
+}
+
+// logical emits && and ||, which only evaluate their right operand if the
+// left one does not decide the result. A phi picks the result from the
+// block that decided it.
+func (g *Generator) logical(e *ir.Binary) string {
+	l := g.newLabels("logic.rhs", "logic.end")
+	x := g.expression(e.X)
+	from := g.block
+	short := "false"
+	if e.Op == ir.LogAnd {
+		g.terminate("br i1 %s, label %%%s, label %%%s", x, l[0], l[1])
+	} else {
+		short = "true"
+		g.terminate("br i1 %s, label %%%s, label %%%s", x, l[1], l[0])
+	}
+	g.label(l[0])
+	y := g.expression(e.Y)
+	rhs := g.block
+	g.branch(l[1])
+	g.label(l[1])
+	t := g.temp()
+	g.emit("%s = phi i1 [ %s, %%%s ], [ %s, %%%s ]", t, short, from, y, rhs)
+	return t
+}
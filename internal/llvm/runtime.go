@@ -0,0 +1,279 @@
+package llvm
+
+// runtimePart is LLVM IR support code that is only emitted for programs
+// that need it. The runtime prints through the C library, which every
+// program is linked with anyway.
+type runtimePart struct {
+	declares []string       // the C library functions the code calls
+	deps     []*runtimePart // parts this code uses
+	code     string
+}
+
+// require arranges for part, and the parts it depends on, to be emitted
+// ahead of the generated code.
+func (g *Generator) require(part *runtimePart) {
+	for _, p := range g.runtime {
+		if p == part {
+			return
+		}
+	}
+	for _, dep := range part.deps {
+		g.require(dep)
+	}
+	g.runtime = append(g.runtime, part)
+}
+
+// libc declares the C library functions that runtime parts call, by name.
+var libc = map[string]string{
+	"atoi":     "declare i32 @atoi(i8*)",
+	"dprintf":  "declare i32 @dprintf(i32, i8*, ...)",
+	"exit":     "declare void @exit(i32) noreturn",
+	"fflush":   "declare i32 @fflush(i8*)",
+	"printf":   "declare i32 @printf(i8*, ...)",
+	"putchar":  "declare i32 @putchar(i32)",
+	"puts":     "declare i32 @puts(i8*)",
+	"snprintf": "declare i32 @snprintf(i8*, i64, i8*, ...)",
+	"strchr":   "declare i8* @strchr(i8*, i32)",
+	"strtod":   "declare double @strtod(i8*, i8**)",
+}
+
+// Reserved lists the global names the generated code defines besides the
+// program's own, which functions and globals of the program must avoid.
+func Reserved() []string {
+	names := []string{
+		"golite_print_int", "golite_print_float", "golite_print_bool", "golite_print_string",
+		"golite_runtime_error", "golite_div", "golite_mod", "golite_shl", "golite_shr",
+	}
+	for name := range libc {
+		names = append(names, name)
+	}
+	return names
+}
+
+var printIntRuntime = &runtimePart{
+	declares: []string{"printf"},
+	code: `@.fmt.int = private unnamed_addr constant [6 x i8] c"%lld\0A\00"
+
+define internal void @golite_print_int(i64 %n) {
+entry:
+  %r = call i32 (i8*, ...) @printf(i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.fmt.int, i64 0, i64 0), i64 %n)
+  ret void
+}
+`,
+}
+
+var printBoolRuntime = &runtimePart{
+	declares: []string{"puts"},
+	code: `@.str.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.str.false = private unnamed_addr constant [6 x i8] c"false\00"
+
+define internal void @golite_print_bool(i1 %b) {
+entry:
+  %s = select i1 %b, i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.true, i64 0, i64 0), i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.str.false, i64 0, i64 0)
+  %r = call i32 @puts(i8* %s)
+  ret void
+}
+`,
+}
+
+// printStringRuntime writes strings a byte at a time, since they may hold
+// NUL bytes.
+var printStringRuntime = &runtimePart{
+	declares: []string{"putchar"},
+	code: `define internal void @golite_print_string(%golite.string %s) {
+entry:
+  %data = extractvalue %golite.string %s, 0
+  %len = extractvalue %golite.string %s, 1
+  %i.addr = alloca i64
+  store i64 0, i64* %i.addr
+  br label %loop
+loop:
+  %i = load i64, i64* %i.addr
+  %more = icmp slt i64 %i, %len
+  br i1 %more, label %body, label %done
+body:
+  %p = getelementptr inbounds i8, i8* %data, i64 %i
+  %c = load i8, i8* %p
+  %ci = zext i8 %c to i32
+  %r = call i32 @putchar(i32 %ci)
+  %next = add i64 %i, 1
+  store i64 %next, i64* %i.addr
+  br label %loop
+done:
+  %nl = call i32 @putchar(i32 10)
+  ret void
+}
+`,
+}
+
+// printFloatRuntime prints floats the way the evaluator does: with the
+// fewest digits that read back as the same float, in exponent form for
+// very large and very small magnitudes.
+var printFloatRuntime = &runtimePart{
+	declares: []string{"atoi", "printf", "puts", "snprintf", "strchr", "strtod"},
+	code: `@.fmt.e = private unnamed_addr constant [5 x i8] c"%.*e\00"
+@.fmt.g = private unnamed_addr constant [6 x i8] c"%.*g\0A\00"
+@.str.nan = private unnamed_addr constant [4 x i8] c"NaN\00"
+@.str.inf = private unnamed_addr constant [5 x i8] c"+Inf\00"
+@.str.neginf = private unnamed_addr constant [5 x i8] c"-Inf\00"
+
+define internal void @golite_print_float(double %v) {
+entry:
+  %buf = alloca [32 x i8]
+  %digits.addr = alloca i32
+  %p = getelementptr inbounds [32 x i8], [32 x i8]* %buf, i64 0, i64 0
+  %isnan = fcmp uno double %v, %v
+  br i1 %isnan, label %nan, label %notnan
+nan:
+  %r.nan = call i32 @puts(i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0))
+  ret void
+notnan:
+  %isinf = fcmp oeq double %v, 0x7FF0000000000000
+  br i1 %isinf, label %inf, label %notinf
+inf:
+  %r.inf = call i32 @puts(i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.inf, i64 0, i64 0))
+  ret void
+notinf:
+  %isneginf = fcmp oeq double %v, 0xFFF0000000000000
+  br i1 %isneginf, label %neginf, label %finite
+neginf:
+  %r.neginf = call i32 @puts(i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.neginf, i64 0, i64 0))
+  ret void
+finite:
+  store i32 1, i32* %digits.addr
+  br label %try
+try:
+  %digits = load i32, i32* %digits.addr
+  %precision = sub i32 %digits, 1
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* %p, i64 32, i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.fmt.e, i64 0, i64 0), i32 %precision, double %v)
+  %back = call double @strtod(i8* %p, i8** null)
+  %same = fcmp oeq double %back, %v
+  %most = icmp sge i32 %digits, 17
+  %found = or i1 %same, %most
+  br i1 %found, label %format, label %next
+next:
+  %more = add i32 %digits, 1
+  store i32 %more, i32* %digits.addr
+  br label %try
+format:
+  %e = call i8* @strchr(i8* %p, i32 101)
+  %ep = getelementptr inbounds i8, i8* %e, i64 1
+  %exp = call i32 @atoi(i8* %ep)
+  %small = icmp slt i32 %exp, -4
+  %large = icmp sge i32 %exp, 6
+  %exponent = or i1 %small, %large
+  br i1 %exponent, label %scientific, label %plain
+scientific:
+  %r.e = call i32 @puts(i8* %p)
+  ret void
+plain:
+  %whole = add i32 %exp, 1
+  %wider = icmp sgt i32 %digits, %whole
+  %width = select i1 %wider, i32 %digits, i32 %whole
+  %r.g = call i32 (i8*, ...) @printf(i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.fmt.g, i64 0, i64 0), i32 %width, double %v)
+  ret void
+}
+`,
+}
+
+// errorRuntime reports runtime errors, such as a division by zero, and
+// stops the program. The position is that of the failing expression, and
+// message is a printf format for arg.
+var errorRuntime = &runtimePart{
+	declares: []string{"dprintf", "exit", "fflush"},
+	code: `@.fmt.error = private unnamed_addr constant [25 x i8] c"runtime error at %d:%d: \00"
+@.fmt.newline = private unnamed_addr constant [2 x i8] c"\0A\00"
+
+define internal void @golite_runtime_error(i32 %line, i32 %column, i8* %message, i64 %arg) noreturn {
+entry:
+  %f = call i32 @fflush(i8* null)
+  %r1 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* getelementptr inbounds ([25 x i8], [25 x i8]* @.fmt.error, i64 0, i64 0), i32 %line, i32 %column)
+  %r2 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* %message, i64 %arg)
+  %r3 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* getelementptr inbounds ([2 x i8], [2 x i8]* @.fmt.newline, i64 0, i64 0))
+  call void @exit(i32 1)
+  unreachable
+}
+`,
+}
+
+// divisionRuntime divides ints, stopping the program if the divisor is
+// zero. Dividing by -1 wraps around instead of trapping, as in Go.
+var divisionRuntime = &runtimePart{
+	deps: []*runtimePart{errorRuntime},
+	code: `@.str.divide = private unnamed_addr constant [23 x i8] c"integer divide by zero\00"
+
+define internal i64 @golite_div(i64 %a, i64 %b, i32 %line, i32 %column) {
+entry:
+  %zero = icmp eq i64 %b, 0
+  br i1 %zero, label %fail, label %nonzero
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([23 x i8], [23 x i8]* @.str.divide, i64 0, i64 0), i64 0)
+  unreachable
+nonzero:
+  %minus = icmp eq i64 %b, -1
+  br i1 %minus, label %negate, label %divide
+negate:
+  %n = sub i64 0, %a
+  ret i64 %n
+divide:
+  %q = sdiv i64 %a, %b
+  ret i64 %q
+}
+
+define internal i64 @golite_mod(i64 %a, i64 %b, i32 %line, i32 %column) {
+entry:
+  %zero = icmp eq i64 %b, 0
+  br i1 %zero, label %fail, label %nonzero
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([23 x i8], [23 x i8]* @.str.divide, i64 0, i64 0), i64 0)
+  unreachable
+nonzero:
+  %minus = icmp eq i64 %b, -1
+  br i1 %minus, label %done, label %divide
+done:
+  ret i64 0
+divide:
+  %r = srem i64 %a, %b
+  ret i64 %r
+}
+`,
+}
+
+// shiftRuntime shifts ints as Go does: counts of 64 or more shift every
+// bit out, and negative counts stop the program.
+var shiftRuntime = &runtimePart{
+	deps: []*runtimePart{errorRuntime},
+	code: `@.str.shift = private unnamed_addr constant [28 x i8] c"negative shift amount: %lld\00"
+
+define internal i64 @golite_shl(i64 %a, i64 %n, i32 %line, i32 %column) {
+entry:
+  %negative = icmp slt i64 %n, 0
+  br i1 %negative, label %fail, label %valid
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([28 x i8], [28 x i8]* @.str.shift, i64 0, i64 0), i64 %n)
+  unreachable
+valid:
+  %wide = icmp sge i64 %n, 64
+  br i1 %wide, label %out, label %shift
+out:
+  ret i64 0
+shift:
+  %r = shl i64 %a, %n
+  ret i64 %r
+}
+
+define internal i64 @golite_shr(i64 %a, i64 %n, i32 %line, i32 %column) {
+entry:
+  %negative = icmp slt i64 %n, 0
+  br i1 %negative, label %fail, label %valid
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([28 x i8], [28 x i8]* @.str.shift, i64 0, i64 0), i64 %n)
+  unreachable
+valid:
+  %wide = icmp sge i64 %n, 64
+  %count = select i1 %wide, i64 63, i64 %n
+  %r = ashr i64 %a, %count
+  ret i64 %r
+}
+`,
+}
@@ -3,20 +3,36 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
 )
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
+	STRING_OBJ       = "STRING"
+	SLICE_OBJ        = "SLICE"
+	MAP_OBJ          = "MAP"
+	STRUCT_OBJ       = "STRUCT"
+	POINTER_OBJ      = "POINTER"
+	TUPLE_OBJ        = "TUPLE"
+	ERROR_VALUE_OBJ  = "ERROR_VALUE"
+	NIL_OBJ          = "NIL"
+	TYPE_OBJ         = "TYPE"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
+	BUILTIN_OBJ      = "BUILTIN"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 )
 
 type Object interface {
@@ -31,6 +47,16 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Inspect formats the float the way Go's fmt.Println does: the shortest
+// representation that reads back as the same value.
+func (f *Float) Inspect() string { return strconv.FormatFloat(f.Value, 'g', -1, 64) }
+
 type Boolean struct {
 	Value bool
 }
@@ -38,6 +64,223 @@ type Boolean struct {
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+// Slice is a window onto an array of elements that other slices may share,
+// as in Go. The length and capacity of Elements are those of the slice.
+type Slice struct {
+	Elements []Object
+}
+
+func (s *Slice) Type() ObjectType { return SLICE_OBJ }
+
+// Inspect formats the slice the way Go's fmt.Println does, e.g. [1 2 3].
+func (s *Slice) Inspect() string {
+	elements := make([]string, len(s.Elements))
+	for i, el := range s.Elements {
+		elements[i] = inspectElement(el)
+	}
+	return "[" + strings.Join(elements, " ") + "]"
+}
+
+// HashKey identifies a map key by value: keys that are equal have equal
+// HashKeys, so they can index a Go map. Value holds an int64, float64, bool
+// or string, which Go compares the way GoLite does, including for NaN.
+type HashKey struct {
+	Type  ObjectType
+	Value interface{}
+}
+
+// Hashable is implemented by the objects that can be map keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey { return HashKey{Type: i.Type(), Value: i.Value} }
+func (f *Float) HashKey() HashKey   { return HashKey{Type: f.Type(), Value: f.Value} }
+func (b *Boolean) HashKey() HashKey { return HashKey{Type: b.Type(), Value: b.Value} }
+func (s *String) HashKey() HashKey  { return HashKey{Type: s.Type(), Value: s.Value} }
+
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map is a reference to a hash table, as in Go. A nil map has nil Pairs:
+// it reads as empty but cannot be assigned to.
+type Map struct {
+	Pairs map[HashKey]MapPair
+	Zero  Object // the value of keys that are not in the map
+}
+
+func (m *Map) Type() ObjectType { return MAP_OBJ }
+
+// Inspect formats the map the way Go's fmt.Println does, with the keys in
+// ascending order, e.g. map[a:1 b:2].
+func (m *Map) Inspect() string {
+	pairs := make([]MapPair, 0, len(m.Pairs))
+	for _, pair := range m.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return lessKey(pairs[i].Key, pairs[j].Key) })
+
+	elements := make([]string, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key.Inspect() + ":" + inspectElement(pair.Value)
+	}
+	return "map[" + strings.Join(elements, " ") + "]"
+}
+
+// lessKey orders map keys of the same type: numbers by value, strings
+// bytewise and false before true.
+func lessKey(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *Float:
+		return a.Value < b.(*Float).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	}
+	return false
+}
+
+// StructType is a struct type declared by a type statement.
+type StructType struct {
+	Name    string
+	Decl    *ast.StructType
+	Fields  []string // the field names, in order
+	Methods map[string]*Method
+}
+
+func (t *StructType) Type() ObjectType { return TYPE_OBJ }
+func (t *StructType) Inspect() string  { return "type " + t.Name }
+
+// FieldIndex returns the index of the named field, or -1 if there is none.
+func (t *StructType) FieldIndex(name string) int {
+	for i, field := range t.Fields {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Method is a method of a struct type, a function that takes the receiver
+// as its first argument.
+type Method struct {
+	Function        *Function
+	PointerReceiver bool // the receiver is a *Pointer rather than a copy of the struct
+}
+
+// Struct is a struct value. As in Go, structs are values rather than
+// references: the evaluator copies one whenever it is read from a variable,
+// field or element, so that no two variables share a struct.
+type Struct struct {
+	StructType *StructType
+	Fields     []Object
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
+
+// Inspect formats the struct the way Go's fmt.Println does, e.g. {1 2}.
+func (s *Struct) Inspect() string {
+	fields := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		fields[i] = inspectElement(f)
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// Copy returns a copy of the struct, including the structs in its fields.
+func (s *Struct) Copy() *Struct {
+	fields := make([]Object, len(s.Fields))
+	for i, f := range s.Fields {
+		if inner, ok := f.(*Struct); ok {
+			f = inner.Copy()
+		}
+		fields[i] = f
+	}
+	return &Struct{StructType: s.StructType, Fields: fields}
+}
+
+// Pointer points to a struct. The nil pointer has a nil Target.
+type Pointer struct {
+	Target *Struct
+}
+
+func (p *Pointer) Type() ObjectType { return POINTER_OBJ }
+
+// Inspect formats the pointer the way Go's fmt.Println does: as & followed
+// by the struct it points to, or <nil>.
+func (p *Pointer) Inspect() string {
+	if p.Target == nil {
+		return "<nil>"
+	}
+	return "&" + p.Target.Inspect()
+}
+
+// inspectElement formats a value held in a slice, map or struct. As with
+// Go's fmt.Println, a pointer there shows as an address rather than as what
+// it points to, which also keeps cyclic structures finite.
+func inspectElement(obj Object) string {
+	if p, ok := obj.(*Pointer); ok && p.Target != nil {
+		return fmt.Sprintf("%p", p.Target)
+	}
+	return obj.Inspect()
+}
+
+// Tuple holds the values of a function that returns several of them. It
+// only exists until they are assigned to variables.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJ }
+func (t *Tuple) Inspect() string {
+	elements := make([]string, len(t.Elements))
+	for i, el := range t.Elements {
+		elements[i] = el.Inspect()
+	}
+	return "(" + strings.Join(elements, ", ") + ")"
+}
+
+// ErrorValue is a value of the error type, such as one made by errors.New.
+// As in Go, errors are equal only if they are the same error, so each holds
+// a pointer to its message. The nil error has a nil Message.
+type ErrorValue struct {
+	Message *string
+}
+
+func NewErrorValue(message string) *ErrorValue {
+	return &ErrorValue{Message: &message}
+}
+
+func (e *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+
+// Inspect formats the error the way Go's fmt.Println does: its message, or
+// <nil>.
+func (e *ErrorValue) Inspect() string {
+	if e.Message == nil {
+		return "<nil>"
+	}
+	return *e.Message
+}
+
+// Nil is the value of the predeclared nil, which stands for the nil error
+// or the nil pointer.
+type Nil struct{}
+
+func (n *Nil) Type() ObjectType { return NIL_OBJ }
+func (n *Nil) Inspect() string  { return "<nil>" }
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
@@ -50,16 +293,94 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break and Continue signal a branch out of the innermost loop body, in the
+// same way ReturnValue signals a return out of a function body.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Error is a panic unwinding the stack: a runtime error, or a call of
+// panic with Value as its argument.
 type Error struct {
 	Message string
+	Span    diagnostics.Span // location of the node that failed, if known
+	Value   *ErrorValue      // the value passed to panic, nil for a runtime error
+	Stack   []StackFrame     // the function calls the panic has left, innermost first
+}
+
+// StackFrame is a call of a function that a panic unwound.
+type StackFrame struct {
+	Function string           // the name of the function called
+	Call     diagnostics.Span // the location of the call
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// tracedFrames is how many calls a stack trace shows at each end. The
+// calls between them, as a runaway recursion leaves thousands of, are
+// counted rather than listed.
+const tracedFrames = 10
+
+// Diagnostic converts the runtime error or panic into a diagnostic. Notes
+// trace the functions it unwound, from the one that failed to main.
+func (e *Error) Diagnostic() *diagnostics.Diagnostic {
+	var d *diagnostics.Diagnostic
+	if e.Value != nil {
+		d = diagnostics.Errorf(diagnostics.Panic, e.Span, "panic: %s", e.Message)
+	} else {
+		d = diagnostics.Errorf(diagnostics.RuntimeError, e.Span, "%s", e.Message)
+	}
+	if len(e.Stack) == 0 {
+		return d
+	}
+	elided := len(e.Stack) - 2*tracedFrames
+	at := e.Span
+	for i, frame := range e.Stack {
+		switch {
+		case elided < 2 || i < tracedFrames || i >= tracedFrames+elided:
+			d.WithNote("in %s at %s", frame.Function, at.Start)
+		case i == tracedFrames:
+			d.WithNote("... %d more calls ...", elided)
+		}
+		at = frame.Call
+	}
+	return d.WithNote("in main at %s", at.Start)
+}
+
+// Frame is the state of a function call, or of the program as a whole,
+// that its deferred calls need.
+type Frame struct {
+	Deferred []*DeferredCall
+	// Panic is the panic unwinding through the call while its deferred
+	// calls run, until one of them recovers.
+	Panic *Error
+	// Deferring is the frame whose deferred calls include this call, so
+	// that recover can stop the panic unwinding through it.
+	Deferring *Frame
+	// Depth is the number of calls in progress, counting this one.
+	Depth int
+}
+
+// DeferredCall is a call postponed by a defer statement, with its function
+// and arguments already evaluated.
+type DeferredCall struct {
+	Function Object
+	Args     []Object
+	Name     string           // the name of the function, for stack traces
+	Call     diagnostics.Span // the location of the call
+}
+
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+	frame *Frame // the call this environment belongs to, if it begins one
 }
 
 func NewEnvironment() *Environment {
@@ -73,6 +394,30 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return env
 }
 
+// NewFrameEnvironment returns the environment of a function call, which
+// holds the call's frame.
+func NewFrameEnvironment(outer *Environment, frame *Frame) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.frame = frame
+	return env
+}
+
+// Frame returns the frame of the innermost call that e belongs to, or nil
+// if there is none.
+func (e *Environment) Frame() *Frame {
+	for ; e != nil; e = e.outer {
+		if e.frame != nil {
+			return e.frame
+		}
+	}
+	return nil
+}
+
+// SetFrame makes e begin a call with the given frame.
+func (e *Environment) SetFrame(frame *Frame) {
+	e.frame = frame
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -86,8 +431,23 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Assign updates an existing binding in the innermost environment that
+// defines name, rather than shadowing it in e. It reports whether a binding
+// was found.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
+	Result     ast.TypeExpr // the declared result type, nil if unannotated
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -109,3 +469,12 @@ func (f *Function) Inspect() string {
 
 	return out.String()
 }
+
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
@@ -2,6 +2,9 @@ package optimizer
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"golite.dev/mvp/internal/ast"
 	"golite.dev/mvp/internal/lexer"
@@ -34,16 +37,22 @@ func constantFolding(node ast.Node) ast.Node {
 		return node
 	}
 
-	left, leftOk := inf.Left.(*ast.IntegerLiteral)
-	right, rightOk := inf.Right.(*ast.IntegerLiteral)
-
-	if !leftOk || !rightOk {
-		return node // Not an infix expression on two integers
+	switch left := inf.Left.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := inf.Right.(*ast.IntegerLiteral); ok {
+			return foldIntegers(inf, left.Value, right.Value)
+		}
+	case *ast.FloatLiteral:
+		if right, ok := inf.Right.(*ast.FloatLiteral); ok {
+			return foldFloats(inf, left.Value, right.Value)
+		}
+	case *ast.Boolean:
+		return foldLogical(inf, left)
 	}
+	return node // Not an infix expression on two literals of the same type
+}
 
-	leftVal := left.Value
-	rightVal := right.Value
-
+func foldIntegers(inf *ast.InfixExpression, leftVal, rightVal int64) ast.Node {
 	var newValue int64
 	switch inf.Operator {
 	case "+":
@@ -55,12 +64,33 @@ func constantFolding(node ast.Node) ast.Node {
 	case "/":
 		if rightVal == 0 {
 			// Cannot fold division by zero, leave it for runtime error.
-			return node
+			return inf
 		}
 		newValue = leftVal / rightVal
+	case "%":
+		if rightVal == 0 {
+			return inf
+		}
+		newValue = leftVal % rightVal
+	case "&":
+		newValue = leftVal & rightVal
+	case "|":
+		newValue = leftVal | rightVal
+	case "^":
+		newValue = leftVal ^ rightVal
+	case "<<", ">>":
+		if rightVal < 0 {
+			// A negative shift amount is a runtime error.
+			return inf
+		}
+		if inf.Operator == "<<" {
+			newValue = leftVal << rightVal
+		} else {
+			newValue = leftVal >> rightVal
+		}
 	default:
 		// Not a foldable operator
-		return node
+		return inf
 	}
 
 	// Create a new IntegerLiteral with BOTH the value and the text literal.
@@ -69,10 +99,60 @@ func constantFolding(node ast.Node) ast.Node {
 		Token: lexer.Token{
 			Type:    lexer.INT,
 			Literal: fmt.Sprintf("%d", newValue), // This was the missing piece
+			Pos:     inf.Pos(),
+			End:     inf.End(),
 		},
 	}
 }
 
+func foldFloats(inf *ast.InfixExpression, leftVal, rightVal float64) ast.Node {
+	var newValue float64
+	switch inf.Operator {
+	case "+":
+		newValue = leftVal + rightVal
+	case "-":
+		newValue = leftVal - rightVal
+	case "*":
+		newValue = leftVal * rightVal
+	case "/":
+		newValue = leftVal / rightVal
+	default:
+		return inf
+	}
+	// Infinities and NaN have no literal syntax, so leave them to runtime.
+	if math.IsInf(newValue, 0) || math.IsNaN(newValue) {
+		return inf
+	}
+
+	literal := strconv.FormatFloat(newValue, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		// Keep the literal a float literal when it has an integral value.
+		literal += ".0"
+	}
+	return &ast.FloatLiteral{
+		Value: newValue,
+		Token: lexer.Token{
+			Type:    lexer.FLOAT,
+			Literal: literal,
+			Pos:     inf.Pos(),
+			End:     inf.End(),
+		},
+	}
+}
+
+// foldLogical simplifies && and || whose left operand is a boolean literal.
+// Either the literal decides the result, in which case the right operand
+// would never be evaluated, or the result is the right operand itself.
+func foldLogical(inf *ast.InfixExpression, left *ast.Boolean) ast.Node {
+	switch {
+	case inf.Operator == "&&" && !left.Value, inf.Operator == "||" && left.Value:
+		return left
+	case inf.Operator == "&&", inf.Operator == "||":
+		return inf.Right
+	}
+	return inf
+}
+
 // deadCodeElimination is a visitor that removes unreachable code by emptying dead branches.
 func deadCodeElimination(node ast.Node) ast.Node {
 	ifExp, ok := node.(*ast.IfExpression)
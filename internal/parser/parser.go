@@ -3,32 +3,50 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
 	"golite.dev/mvp/internal/lexer"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
-	SUM         // +
-	PRODUCT     // *
-	PREFIX      // -X or !X
+	SUM         // + or |
+	PRODUCT     // * or <<
+	PREFIX      // -X, !X or &X
 	CALL        // myFunction(X)
+	INDEX       // array[index], x.field or T{...}
 )
 
 var precedences = map[lexer.TokenType]int{
-	lexer.EQ:       EQUALS,
-	lexer.NOT_EQ:   EQUALS,
-	lexer.LT:       LESSGREATER,
-	lexer.GT:       LESSGREATER,
-	lexer.PLUS:     SUM,
-	lexer.MINUS:    SUM,
-	lexer.SLASH:    PRODUCT,
-	lexer.ASTERISK: PRODUCT,
-	lexer.LPAREN:   CALL,
+	lexer.OR:        LOGICAL_OR,
+	lexer.AND:       LOGICAL_AND,
+	lexer.EQ:        EQUALS,
+	lexer.NOT_EQ:    EQUALS,
+	lexer.LT:        LESSGREATER,
+	lexer.GT:        LESSGREATER,
+	lexer.LT_EQ:     LESSGREATER,
+	lexer.GT_EQ:     LESSGREATER,
+	lexer.PLUS:      SUM,
+	lexer.MINUS:     SUM,
+	lexer.PIPE:      SUM,
+	lexer.CARET:     SUM,
+	lexer.SLASH:     PRODUCT,
+	lexer.ASTERISK:  PRODUCT,
+	lexer.PERCENT:   PRODUCT,
+	lexer.AMPERSAND: PRODUCT,
+	lexer.SHL:       PRODUCT,
+	lexer.SHR:       PRODUCT,
+	lexer.LPAREN:    CALL,
+	lexer.LBRACKET:  INDEX,
+	lexer.DOT:       INDEX,
+	lexer.LBRACE:    INDEX,
 }
 
 type (
@@ -38,31 +56,51 @@ type (
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []*diagnostics.Diagnostic
 
 	curToken  lexer.Token
 	peekToken lexer.Token
 
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
+
+	// panicking is set by a syntax error and cleared once the parser has
+	// resynchronized at the end of the statement containing it. No errors
+	// are reported in between.
+	panicking bool
+	// reported holds the errors reported so far, so that none is reported
+	// twice.
+	reported map[reportedError]bool
+
+	// exprLev is negative in the header of an if or for statement, where
+	// a { after a name opens the body rather than a struct literal, as in
+	// Go. Parentheses and brackets raise it, so that (T{}) is a literal.
+	exprLev int
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:        l,
+		errors:   []*diagnostics.Diagnostic{},
+		reported: map[reportedError]bool{},
 	}
 
 	p.prefixParseFns = make(map[lexer.TokenType]prefixParseFn)
 	p.registerPrefix(lexer.IDENT, p.parseIdentifier)
 	p.registerPrefix(lexer.INT, p.parseIntegerLiteral)
+	p.registerPrefix(lexer.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(lexer.STRING, p.parseStringLiteral)
+	p.registerPrefix(lexer.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(lexer.BANG, p.parsePrefixExpression)
 	p.registerPrefix(lexer.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(lexer.AMPERSAND, p.parsePrefixExpression)
 	p.registerPrefix(lexer.TRUE, p.parseBoolean)
 	p.registerPrefix(lexer.FALSE, p.parseBoolean)
 	p.registerPrefix(lexer.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(lexer.IF, p.parseIfExpression)
 	p.registerPrefix(lexer.FUNC, p.parseFunctionLiteral)
+	p.registerPrefix(lexer.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(lexer.MAP, p.parseMapLiteral)
 
 	p.infixParseFns = make(map[lexer.TokenType]infixParseFn)
 	p.registerInfix(lexer.PLUS, p.parseInfixExpression)
@@ -73,7 +111,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(lexer.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(lexer.LT, p.parseInfixExpression)
 	p.registerInfix(lexer.GT, p.parseInfixExpression)
+	p.registerInfix(lexer.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(lexer.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(lexer.PERCENT, p.parseInfixExpression)
+	p.registerInfix(lexer.AND, p.parseInfixExpression)
+	p.registerInfix(lexer.OR, p.parseInfixExpression)
+	p.registerInfix(lexer.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(lexer.PIPE, p.parseInfixExpression)
+	p.registerInfix(lexer.CARET, p.parseInfixExpression)
+	p.registerInfix(lexer.SHL, p.parseInfixExpression)
+	p.registerInfix(lexer.SHR, p.parseInfixExpression)
 	p.registerInfix(lexer.LPAREN, p.parseCallExpression)
+	p.registerInfix(lexer.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(lexer.DOT, p.parseSelectorExpression)
+	p.registerInfix(lexer.LBRACE, p.parseStructLiteral)
 
 	p.nextToken()
 	p.nextToken()
@@ -81,7 +132,7 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() []*diagnostics.Diagnostic {
 	return p.errors
 }
 
@@ -95,7 +146,7 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != lexer.EOF {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementRecovering()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -105,12 +156,99 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// statementStart lists the tokens that begin a statement, where parsing can
+// resume after a syntax error.
+var statementStart = map[lexer.TokenType]bool{
+	lexer.LET:      true,
+	lexer.PRINT:    true,
+	lexer.RETURN:   true,
+	lexer.FOR:      true,
+	lexer.IF:       true,
+	lexer.BREAK:    true,
+	lexer.CONTINUE: true,
+	lexer.TYPE:     true,
+	lexer.DEFER:    true,
+}
+
+// parseStatementRecovering parses a statement and, if it contains a syntax
+// error, skips the rest of it. A statement too broken to be built at all is
+// replaced by an ast.BadStatement covering the skipped tokens.
+func (p *Parser) parseStatementRecovering() ast.Statement {
+	start := p.curToken
+	p.panicking = false
+	stmt := p.parseStatement()
+	if !p.panicking {
+		return stmt
+	}
+	p.synchronize()
+	if stmt == nil {
+		return &ast.BadStatement{Token: start, EndPos: p.curToken.End}
+	}
+	return stmt
+}
+
+// synchronize advances to the last token of the statement in which a syntax
+// error occurred: a semicolon, or the token before a closing brace or a
+// keyword that starts a statement. Blocks are skipped as a whole, so that
+// the statements in them are not mistaken for the ones that follow. The
+// parser then leaves panic mode.
+func (p *Parser) synchronize() {
+	defer func() { p.panicking = false }()
+	depth := 0
+	for !p.curTokenIs(lexer.EOF) {
+		switch p.curToken.Type {
+		case lexer.LBRACE:
+			depth++
+		case lexer.RBRACE:
+			depth--
+		}
+		if depth < 0 {
+			// The error was at the end of the enclosing block.
+			return
+		}
+		if depth == 0 {
+			if p.curTokenIs(lexer.SEMICOLON) || p.peekTokenIs(lexer.RBRACE) || p.peekTokenIs(lexer.EOF) || statementStart[p.peekToken.Type] {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case lexer.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case lexer.PRINT:
 		return p.parsePrintStatement()
+	case lexer.RETURN:
+		return p.parseReturnStatement()
+	case lexer.DEFER:
+		return p.parseDeferStatement()
+	case lexer.FOR:
+		return p.parseForStatement()
+	case lexer.TYPE:
+		return p.parseTypeStatement()
+	case lexer.FUNC:
+		if p.peekTokenIs(lexer.LPAREN) {
+			return p.parseFuncStatement()
+		}
+		return p.parseExpressionStatement()
+	case lexer.BREAK:
+		stmt := &ast.BreakStatement{Token: p.curToken}
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	case lexer.CONTINUE:
+		stmt := &ast.ContinueStatement{Token: p.curToken}
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -125,11 +263,27 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(lexer.COMMA) {
+		for p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			stmt.Rest = append(stmt.Rest, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		}
+	} else if p.peekStartsType() {
+		p.nextToken()
+		stmt.Type = p.parseType()
+		if stmt.Type == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(lexer.ASSIGN) {
 		return nil
 	}
 	p.nextToken()
-	stmt.Value = p.parseExpression(LOWEST)
+	stmt.Value = p.parseValues()
 
 	if p.peekTokenIs(lexer.SEMICOLON) {
 		p.nextToken()
@@ -148,25 +302,250 @@ func (p *Parser) parsePrintStatement() *ast.PrintStatement {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{Token: p.curToken}
-	stmt.Expression = p.parseExpression(LOWEST)
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+	p.nextToken()
+	start := p.curToken
+	expr := p.parseExpression(LOWEST)
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		if _, bad := expr.(*ast.BadExpression); !bad && expr != nil {
+			p.errorf(diagnostics.UnexpectedToken, start, "expression in defer must be function call")
+		}
+		return stmt
+	}
+	stmt.Call = call
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	stmt := &ast.ReturnStatement{Token: p.curToken}
+
+	// A bare return has nothing before the end of the statement or block.
+	if p.peekTokenIs(lexer.SEMICOLON) || p.peekTokenIs(lexer.RBRACE) || p.peekTokenIs(lexer.EOF) {
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
+	p.nextToken()
+	stmt.ReturnValue = p.parseValues()
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseForStatement parses the three forms of loop:
+//
+//	for { ... }
+//	for cond { ... }
+//	for init; cond; post { ... }
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+	defer func(lev int) { p.exprLev = lev }(p.exprLev)
+	p.exprLev = -1
+
+	if p.peekTokenIs(lexer.LBRACE) {
+		p.nextToken()
+		stmt.Body = p.parseBlockStatement()
+		return stmt
+	}
+
+	p.nextToken()
+	if !p.curTokenIs(lexer.SEMICOLON) {
+		init := p.parseSimpleStatement()
+		if init == nil {
+			return nil
+		}
+		// Simple statements consume their trailing semicolon, so a loop
+		// without one is the condition-only form.
+		if !p.curTokenIs(lexer.SEMICOLON) {
+			cond, ok := init.(*ast.ExpressionStatement)
+			if !ok {
+				p.errorf(diagnostics.UnexpectedToken, p.curToken, "expected for loop condition, got %s", init.TokenLiteral())
+				return nil
+			}
+			stmt.Condition = cond.Expression
+			if !p.expectPeek(lexer.LBRACE) {
+				return nil
+			}
+			stmt.Body = p.parseBlockStatement()
+			return stmt
+		}
+		stmt.Init = init
+	}
+
+	if !p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(lexer.SEMICOLON) {
+		return nil
+	}
+
+	if !p.peekTokenIs(lexer.LBRACE) {
+		p.nextToken()
+		stmt.Post = p.parseSimpleStatement()
+		if stmt.Post == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+// parseSimpleStatement parses a statement that may appear in the clauses of a
+// for loop.
+func (p *Parser) parseSimpleStatement() ast.Statement {
+	if p.curTokenIs(lexer.LET) {
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	}
+	return p.parseExpressionStatement()
+}
+
+var assignOperators = map[lexer.TokenType]bool{
+	lexer.ASSIGN:          true,
+	lexer.PLUS_ASSIGN:     true,
+	lexer.MINUS_ASSIGN:    true,
+	lexer.ASTERISK_ASSIGN: true,
+	lexer.SLASH_ASSIGN:    true,
+}
+
+// parseExpressionStatement parses an expression used as a statement, or an
+// assignment or increment whose target is that expression, or a short
+// variable declaration.
+func (p *Parser) parseExpressionStatement() ast.Statement {
+	firstToken := p.curToken
+	return p.completeExpressionStatement(firstToken, p.parseExpression(LOWEST))
+}
+
+// completeExpressionStatement parses the rest of a statement starting with
+// expression, which has been parsed from firstToken on.
+func (p *Parser) completeExpressionStatement(firstToken lexer.Token, expression ast.Expression) ast.Statement {
+	if expression != nil && p.peekTokenIs(lexer.COMMA) {
+		return p.parseListStatement(p.moreValues(expression).(*ast.TupleExpression))
+	}
+	if name, ok := expression.(*ast.Identifier); ok && p.peekTokenIs(lexer.DEFINE) {
+		return p.parseShortVarDecl(name, nil)
+	}
+
+	var stmt ast.Statement
+	switch {
+	case expression != nil && assignOperators[p.peekToken.Type]:
+		p.nextToken()
+		assign := &ast.AssignStatement{Token: p.curToken, Target: expression, Operator: p.curToken.Literal}
+		p.nextToken()
+		if assign.Operator == "=" {
+			assign.Value = p.parseValues()
+		} else {
+			assign.Value = p.parseExpression(LOWEST)
+		}
+		stmt = assign
+	case expression != nil && (p.peekTokenIs(lexer.INC) || p.peekTokenIs(lexer.DEC)):
+		p.nextToken()
+		stmt = &ast.IncDecStatement{Token: p.curToken, Target: expression, Operator: p.curToken.Literal}
+	default:
+		stmt = &ast.ExpressionStatement{Token: firstToken, Expression: expression}
+	}
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
 
+// parseListStatement parses the rest of a statement that starts with a list
+// of expressions: a short variable declaration such as q, r := divmod(a, b)
+// if they are all names, or an assignment such as a, b = b, a.
+func (p *Parser) parseListStatement(targets *ast.TupleExpression) ast.Statement {
+	if p.peekTokenIs(lexer.DEFINE) {
+		var names []*ast.Identifier
+		for _, target := range targets.Elements {
+			name, ok := target.(*ast.Identifier)
+			if !ok {
+				p.errorf(diagnostics.UnexpectedToken, p.peekToken, "non-name %s on left side of :=", target.String())
+				return nil
+			}
+			names = append(names, name)
+		}
+		return p.parseShortVarDecl(names[0], names[1:])
+	}
+	if !p.expectPeek(lexer.ASSIGN) {
+		return nil
+	}
+	assign := &ast.AssignStatement{Token: p.curToken, Target: targets, Operator: p.curToken.Literal}
+	p.nextToken()
+	assign.Value = p.parseValues()
 	if p.peekTokenIs(lexer.SEMICOLON) {
 		p.nextToken()
 	}
+	return assign
+}
 
+// parseShortVarDecl parses the rest of x := v or q, r := divmod(a, b),
+// whose names have been parsed already. It is equivalent to a let
+// statement.
+func (p *Parser) parseShortVarDecl(name *ast.Identifier, rest []*ast.Identifier) ast.Statement {
+	stmt := &ast.LetStatement{Name: name, Rest: rest}
+	if !p.expectPeek(lexer.DEFINE) {
+		return nil
+	}
+	stmt.Token = p.curToken
+	p.nextToken()
+	stmt.Value = p.parseValues()
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
 	return stmt
 }
 
+// parseValues parses one expression, or several separated by commas, which
+// make a TupleExpression.
+func (p *Parser) parseValues() ast.Expression {
+	return p.moreValues(p.parseExpression(LOWEST))
+}
+
+// moreValues parses the expressions that follow first, if a comma does.
+func (p *Parser) moreValues(first ast.Expression) ast.Expression {
+	if !p.peekTokenIs(lexer.COMMA) {
+		return first
+	}
+	tuple := &ast.TupleExpression{Token: p.peekToken, Elements: []ast.Expression{first}}
+	for p.peekTokenIs(lexer.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+	}
+	return tuple
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return p.badExpression(p.curToken)
 	}
-	leftExp := prefix()
+	return p.continueExpression(prefix(), precedence)
+}
 
+// continueExpression parses the operators following leftExp that bind more
+// tightly than precedence.
+func (p *Parser) continueExpression(leftExp ast.Expression, precedence int) ast.Expression {
 	for !p.peekTokenIs(lexer.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
@@ -188,14 +567,49 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.errorf(diagnostics.InvalidInteger, p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return p.badExpression(p.curToken)
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorf(diagnostics.InvalidFloat, p.curToken, "could not parse %q as float", p.curToken.Literal)
+		return p.badExpression(p.curToken)
 	}
 	lit.Value = value
 	return lit
 }
 
+// parseStringLiteral decodes a string literal. Escape sequences follow Go:
+// \n, \t, \\, \", \xhh, \uhhhh and so on.
+func (p *Parser) parseStringLiteral() ast.Expression {
+	value, err := strconv.Unquote(p.curToken.Literal)
+	if err != nil {
+		p.errorf(diagnostics.InvalidString, p.curToken, "invalid escape sequence in string literal %s", p.curToken.Literal)
+		return p.badExpression(p.curToken)
+	}
+	return &ast.StringLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseIllegal() ast.Expression {
+	if strings.HasPrefix(p.curToken.Literal, "\"") {
+		p.errorf(diagnostics.InvalidString, p.curToken, "string literal not terminated")
+		return p.badExpression(p.curToken)
+	}
+	if strings.HasPrefix(p.curToken.Literal, "/*") {
+		p.errorf(diagnostics.IllegalToken, p.curToken, "comment not terminated")
+		return p.badExpression(p.curToken)
+	}
+	p.errorf(diagnostics.IllegalToken, p.curToken, "illegal character %q", p.curToken.Literal)
+	return p.badExpression(p.curToken)
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(lexer.TRUE)}
 }
@@ -223,10 +637,13 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curToken
+	p.exprLev++
+	defer func() { p.exprLev-- }()
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
 	if !p.expectPeek(lexer.RPAREN) {
-		return nil
+		return p.badExpression(lparen)
 	}
 	return exp
 }
@@ -234,19 +651,16 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 func (p *Parser) parseIfExpression() ast.Expression {
 	expression := &ast.IfExpression{Token: p.curToken}
 
-	if !p.expectPeek(lexer.LPAREN) {
-		return nil
-	}
-
+	// Parentheses around the condition are optional, as in Go; when present
+	// they are parsed as a grouped expression.
 	p.nextToken()
+	outer := p.exprLev
+	p.exprLev = -1
 	expression.Condition = p.parseExpression(LOWEST)
-
-	if !p.expectPeek(lexer.RPAREN) {
-		return nil
-	}
+	p.exprLev = outer
 
 	if !p.expectPeek(lexer.LBRACE) {
-		return nil
+		return p.badExpression(expression.Token)
 	}
 
 	expression.Consequence = p.parseBlockStatement()
@@ -254,7 +668,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(lexer.ELSE) {
 		p.nextToken()
 		if !p.expectPeek(lexer.LBRACE) {
-			return nil
+			return p.badExpression(expression.Token)
 		}
 		expression.Alternative = p.parseBlockStatement()
 	}
@@ -264,70 +678,445 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
+	defer func(lev int) { p.exprLev = lev }(p.exprLev)
+	p.exprLev = 0
 	block.Statements = []ast.Statement{}
 	p.nextToken()
 
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseStatementRecovering()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	if p.curTokenIs(lexer.EOF) {
+		// Blocks enclosing this one end here too, but one error says it.
+		p.errorf(diagnostics.UnexpectedToken, p.curToken, "expected }, got end of file").
+			WithLabel(diagnostics.SpanOfToken(block.Token), "this { is never closed")
+		return block
+	}
+	block.Rbrace = p.curToken.Pos
 	return block
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.parseSignature(lit) || !p.expectPeek(lexer.LBRACE) {
+		return p.badExpression(lit.Token)
+	}
+	lit.Body = p.parseBlockStatement()
+	return lit
+}
 
+// parseSignature parses the parameters and result type of a function
+// literal, up to the opening brace of its body.
+func (p *Parser) parseSignature(lit *ast.FunctionLiteral) bool {
 	if !p.expectPeek(lexer.LPAREN) {
-		return nil
+		return false
+	}
+	lit.Parameters, lit.ParamTypes = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return false
 	}
+	var ok bool
+	lit.ReturnType, ok = p.parseResultType()
+	return ok
+}
 
-	lit.Parameters = p.parseFunctionParameters()
+// parseResultType parses the result type that follows the parameters of a
+// function, if there is one: a type, or a parenthesized list of types for a
+// function that returns several values. It reports false on a syntax error.
+func (p *Parser) parseResultType() (ast.TypeExpr, bool) {
+	switch {
+	case p.peekTokenIs(lexer.LPAREN):
+		p.nextToken()
+		tt := &ast.TupleType{Lparen: p.curToken}
+		for {
+			p.nextToken()
+			elem := p.parseType()
+			if elem == nil {
+				return nil, false
+			}
+			tt.Elems = append(tt.Elems, elem)
+			if !p.peekTokenIs(lexer.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(lexer.RPAREN) {
+			return nil, false
+		}
+		tt.Rparen = p.curToken.Pos
+		if len(tt.Elems) == 1 {
+			return tt.Elems[0], true
+		}
+		return tt, true
+	case p.peekStartsType():
+		p.nextToken()
+		t := p.parseType()
+		return t, t != nil
+	}
+	return nil, true
+}
 
+// parseFuncStatement parses a statement starting with func: a method
+// declaration, or an expression statement starting with a function literal,
+// such as func() { ... }(). The two read alike up to the end of the first
+// parenthesized list; a method name followed by ( reads as a result type
+// that is not followed by the body.
+func (p *Parser) parseFuncStatement() ast.Statement {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.parseSignature(lit) {
+		return nil
+	}
+	if name, ok := lit.ReturnType.(*ast.NamedType); ok && p.peekTokenIs(lexer.LPAREN) {
+		return p.parseMethodStatement(lit, name)
+	}
 	if !p.expectPeek(lexer.LBRACE) {
 		return nil
 	}
-
 	lit.Body = p.parseBlockStatement()
+	return p.completeExpressionStatement(lit.Token, p.continueExpression(lit, LOWEST))
+}
 
-	return lit
+// parseMethodStatement parses the rest of func (r T) Name(params) result
+// { body }, where recv holds the receiver and name the method name.
+func (p *Parser) parseMethodStatement(recv *ast.FunctionLiteral, name *ast.NamedType) ast.Statement {
+	if len(recv.Parameters) != 1 || recv.ParamTypes[0] == nil {
+		p.errorf(diagnostics.UnexpectedToken, recv.Token, "method must have exactly one receiver with a type")
+		return nil
+	}
+	stmt := &ast.MethodStatement{Token: recv.Token, Name: &ast.Identifier{Token: name.Token, Value: name.Name}}
+	fn := &ast.FunctionLiteral{Token: recv.Token}
+	if !p.parseSignature(fn) || !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	fn.Body = p.parseBlockStatement()
+	fn.Parameters = append(recv.Parameters, fn.Parameters...)
+	fn.ParamTypes = append(recv.ParamTypes, fn.ParamTypes...)
+	stmt.Function = fn
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+// parseTypeStatement parses type Name struct { ... }.
+func (p *Parser) parseTypeStatement() ast.Statement {
+	stmt := &ast.TypeStatement{Token: p.curToken}
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(lexer.STRUCT) {
+		return nil
+	}
+	if stmt.Type = p.parseStructType(); stmt.Type == nil {
+		return nil
+	}
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseStructType parses struct { x, y int; name string }. As there is no
+// automatic semicolon insertion, the semicolons between fields are optional.
+func (p *Parser) parseStructType() *ast.StructType {
+	st := &ast.StructType{Token: p.curToken}
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(lexer.RBRACE) {
+		field := &ast.Field{}
+		for {
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			field.Names = append(field.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(lexer.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		p.nextToken()
+		if field.Type = p.parseType(); field.Type == nil {
+			return nil
+		}
+		st.Fields = append(st.Fields, field)
+		if p.peekTokenIs(lexer.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+	st.Rbrace = p.curToken.Pos
+	return st
+}
+
+// parseFunctionParameters parses `a, b int, c bool)`. As in Go, a type
+// applies to the run of untyped names before it, so a and b are both int.
+// Parameters without any annotation get a nil type.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.TypeExpr) {
 	identifiers := []*ast.Identifier{}
+	types := []ast.TypeExpr{}
 
 	if p.peekTokenIs(lexer.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, types
 	}
 
-	p.nextToken()
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	for {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil, nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		types = append(types, nil)
+
+		if p.peekStartsType() {
+			p.nextToken()
+			t := p.parseType()
+			if t == nil {
+				return nil, nil
+			}
+			for i := len(types) - 1; i >= 0 && types[i] == nil; i-- {
+				types[i] = t
+			}
+		}
 
-	for p.peekTokenIs(lexer.COMMA) {
-		p.nextToken()
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
 	if !p.expectPeek(lexer.RPAREN) {
+		return nil, nil
+	}
+	return identifiers, types
+}
+
+// peekStartsType reports whether the next token can begin a type.
+func (p *Parser) peekStartsType() bool {
+	return p.peekTokenIs(lexer.IDENT) || p.peekTokenIs(lexer.FUNC) || p.peekTokenIs(lexer.LBRACKET) ||
+		p.peekTokenIs(lexer.MAP) || p.peekTokenIs(lexer.ASTERISK)
+}
+
+// parseType parses a type annotation starting at the current token.
+func (p *Parser) parseType() ast.TypeExpr {
+	switch p.curToken.Type {
+	case lexer.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case lexer.FUNC:
+		ft := &ast.FuncType{Token: p.curToken}
+		if !p.expectPeek(lexer.LPAREN) {
+			return nil
+		}
+		if !p.peekTokenIs(lexer.RPAREN) {
+			for {
+				p.nextToken()
+				param := p.parseType()
+				if param == nil {
+					return nil
+				}
+				ft.Params = append(ft.Params, param)
+				if !p.peekTokenIs(lexer.COMMA) {
+					break
+				}
+				p.nextToken()
+			}
+		}
+		if !p.expectPeek(lexer.RPAREN) {
+			return nil
+		}
+		ft.Rparen = p.curToken.Pos
+		var ok bool
+		if ft.Result, ok = p.parseResultType(); !ok {
+			return nil
+		}
+		return ft
+	case lexer.ASTERISK:
+		pt := &ast.PointerType{Token: p.curToken}
+		p.nextToken()
+		if pt.Elem = p.parseType(); pt.Elem == nil {
+			return nil
+		}
+		return pt
+	case lexer.LBRACKET:
+		st := &ast.SliceType{Token: p.curToken}
+		if !p.expectPeek(lexer.RBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		st.Elem = p.parseType()
+		if st.Elem == nil {
+			return nil
+		}
+		return st
+	case lexer.MAP:
+		mt := &ast.MapType{Token: p.curToken}
+		if !p.expectPeek(lexer.LBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		if mt.Key = p.parseType(); mt.Key == nil {
+			return nil
+		}
+		if !p.expectPeek(lexer.RBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		if mt.Value = p.parseType(); mt.Value == nil {
+			return nil
+		}
+		return mt
+	default:
+		p.errorf(diagnostics.UnexpectedToken, p.curToken, "expected a type, got %s", describeToken(p.curToken))
 		return nil
 	}
-	return identifiers
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(lexer.RPAREN)
+	if exp.Arguments == nil {
+		return p.badExpression(exp.Token)
+	}
+	exp.Rparen = p.curToken.Pos
 	return exp
 }
 
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(lexer.RBRACKET)
+	if array.Elements == nil {
+		return p.badExpression(array.Token)
+	}
+	array.Rbracket = p.curToken.Pos
+	return array
+}
+
+// parseMapLiteral parses map[K]V{k1: v1, k2: v2}.
+func (p *Parser) parseMapLiteral() ast.Expression {
+	lit := &ast.MapLiteral{Token: p.curToken}
+	mt, ok := p.parseType().(*ast.MapType)
+	if !ok {
+		return p.badExpression(lit.Token)
+	}
+	lit.Type = mt
+	if !p.expectPeek(lexer.LBRACE) {
+		return p.badExpression(lit.Token)
+	}
+	p.exprLev++
+	defer func() { p.exprLev-- }()
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		lit.Keys = append(lit.Keys, p.parseExpression(LOWEST))
+		if !p.expectPeek(lexer.COLON) {
+			return p.badExpression(lit.Token)
+		}
+		p.nextToken()
+		lit.Values = append(lit.Values, p.parseExpression(LOWEST))
+		if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+			return p.badExpression(lit.Token)
+		}
+	}
+	p.nextToken()
+	lit.Rbrace = p.curToken.Pos
+	return lit
+}
+
+// parseSelectorExpression parses the field or method name after the dot in
+// x.name.
+func (p *Parser) parseSelectorExpression(x ast.Expression) ast.Expression {
+	dot := p.curToken
+	if !p.expectPeek(lexer.IDENT) {
+		return p.badExpression(dot)
+	}
+	return &ast.SelectorExpression{Token: dot, X: x, Sel: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+}
+
+// parseStructLiteral parses the braces after a type name, holding either
+// field: value pairs or values for all the fields in order.
+func (p *Parser) parseStructLiteral(left ast.Expression) ast.Expression {
+	lbrace := p.curToken
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		p.errorf(diagnostics.UnexpectedToken, lbrace, "expected a struct type before {, got %s", left.String())
+		return p.badExpression(lbrace)
+	}
+	lit := &ast.StructLiteral{Token: lbrace, Type: &ast.NamedType{Token: name.Token, Name: name.Value}}
+	p.exprLev++
+	defer func() { p.exprLev-- }()
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		keyed := p.peekTokenIs(lexer.COLON)
+		if keyed != (lit.Fields != nil) && len(lit.Values) > 0 {
+			p.errorf(diagnostics.UnexpectedToken, p.curToken, "mixture of field:value and value elements in struct literal")
+			return p.badExpression(lbrace)
+		}
+		if keyed {
+			field, ok := value.(*ast.Identifier)
+			if !ok {
+				p.errorf(diagnostics.UnexpectedToken, p.curToken, "invalid field name %s in struct literal", value.String())
+				return p.badExpression(lbrace)
+			}
+			lit.Fields = append(lit.Fields, field)
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(LOWEST)
+		}
+		lit.Values = append(lit.Values, value)
+		if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+			return p.badExpression(lbrace)
+		}
+	}
+	p.nextToken()
+	lit.Rbrace = p.curToken.Pos
+	return lit
+}
+
+// parseIndexExpression parses the brackets after left, which hold either an
+// index, a[i], or the bounds of a slice expression, a[lo:hi], where either
+// bound may be omitted.
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	lbracket := p.curToken
+	p.exprLev++
+	defer func() { p.exprLev-- }()
+
+	var index ast.Expression
+	if !p.peekTokenIs(lexer.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(lexer.COLON) {
+		p.nextToken()
+		slice := &ast.SliceExpression{Token: lbracket, Left: left, Low: index}
+		if !p.peekTokenIs(lexer.RBRACKET) {
+			p.nextToken()
+			slice.High = p.parseExpression(LOWEST)
+		}
+		if !p.expectPeek(lexer.RBRACKET) {
+			return p.badExpression(lbracket)
+		}
+		slice.Rbracket = p.curToken.Pos
+		return slice
+	}
+
+	if !p.expectPeek(lexer.RBRACKET) {
+		return p.badExpression(lbracket)
+	}
+	return &ast.IndexExpression{Token: lbracket, Left: left, Index: index, Rbracket: p.curToken.Pos}
+}
+
 func (p *Parser) parseExpressionList(end lexer.TokenType) []ast.Expression {
 	list := []ast.Expression{}
+	p.exprLev++
+	defer func() { p.exprLev-- }()
 
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -367,14 +1156,52 @@ func (p *Parser) expectPeek(t lexer.TokenType) bool {
 	return false
 }
 
+// reportedError identifies an error by its position and message.
+type reportedError struct {
+	pos     lexer.Position
+	message string
+}
+
+// errorf records an error diagnostic located at tok and puts the parser in
+// panic mode. Errors found in panic mode are not recorded, as they are
+// almost always consequences of the one that started it, and neither is
+// an error that already was, such as the missing } of each of several
+// blocks left open at the end of the file.
+func (p *Parser) errorf(code diagnostics.Code, tok lexer.Token, format string, args ...interface{}) *diagnostics.Diagnostic {
+	d := diagnostics.Errorf(code, diagnostics.SpanOfToken(tok), format, args...)
+	key := reportedError{tok.Pos, d.Message}
+	if !p.panicking && !p.reported[key] {
+		p.reported[key] = true
+		p.errors = append(p.errors, d)
+	}
+	p.panicking = true
+	return d
+}
+
+// badExpression returns an ast.BadExpression for the tokens from start to
+// the current one.
+func (p *Parser) badExpression(start lexer.Token) *ast.BadExpression {
+	return &ast.BadExpression{Token: start, EndPos: p.curToken.End}
+}
+
 func (p *Parser) peekError(t lexer.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorf(diagnostics.UnexpectedToken, p.peekToken,
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type).
+		WithLabel(diagnostics.SpanOfToken(p.curToken), "after this %s", describeToken(p.curToken))
 }
 
 func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorf(diagnostics.MissingPrefix, p.curToken, "no prefix parse function for %s found", t).
+		WithNote("an expression cannot start with %s", describeToken(p.curToken))
+}
+
+// describeToken returns a short human-readable description of tok for use in
+// diagnostic labels.
+func describeToken(tok lexer.Token) string {
+	if tok.Type == lexer.EOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", tok.Literal)
 }
 
 func (p *Parser) registerPrefix(tokenType lexer.TokenType, fn prefixParseFn) {
@@ -386,6 +1213,10 @@ func (p *Parser) registerInfix(tokenType lexer.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) peekPrecedence() int {
+	if p.peekTokenIs(lexer.LBRACE) && (!p.curTokenIs(lexer.IDENT) || p.exprLev < 0) {
+		// The { opens a block rather than a struct literal.
+		return LOWEST
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
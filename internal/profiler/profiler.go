@@ -6,10 +6,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
 
+	"golite.dev/mvp/internal/amd64"
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/glc"
 	"golite.dev/mvp/internal/lexer"
 	"golite.dev/mvp/internal/optimizer"
 	"golite.dev/mvp/internal/parser"
@@ -62,24 +66,24 @@ func (p *Profiler) Run(sourceFile string, optConfig optimizer.Config) (*Metrics,
 	program := parser.New(l).ParseProgram()
 	optimizer.Optimize(program, optConfig)
 
-	tempGoLiteFile := filepath.Join(p.workDir, "temp.golite")
-	err = os.WriteFile(tempGoLiteFile, []byte(program.String()), 0644)
+	// The optimized program goes to the build as a .glc file, which keeps
+	// it exactly as the optimizer left it, positions included, and spares
+	// parsing it again. The file lives only until the build reads it, so it
+	// records no compiler version.
+	tempGoLiteFile := filepath.Join(p.workDir, "temp.glc")
+	err = os.WriteFile(tempGoLiteFile, glc.Encode(&glc.File{Source: sourceFile, Program: program}), 0644)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write temp golite file: %w", err)
+		return nil, fmt.Errorf("failed to write temp glc file: %w", err)
 	}
 
-	cFile := filepath.Join(p.workDir, "output.c")
 	binaryFile := filepath.Join(p.workDir, "program")
+	steps := p.buildSteps(goliteCompilerPath, tempGoLiteFile, binaryFile, program)
 
 	buildStartTime := time.Now()
-	buildCmd := exec.Command(goliteCompilerPath, "build", "-o", cFile, tempGoLiteFile)
-	if output, err := p.exec.CombinedOutput(buildCmd); err != nil {
-		return nil, fmt.Errorf("failed to compile GoLite to C: %s\n%s", err, string(output))
-	}
-
-	compileCmd := exec.Command("clang", cFile, "-o", binaryFile)
-	if output, err := p.exec.CombinedOutput(compileCmd); err != nil {
-		return nil, fmt.Errorf("failed to compile C to native: %s\n%s", err, string(output))
+	for _, step := range steps {
+		if output, err := p.exec.CombinedOutput(step.cmd); err != nil {
+			return nil, fmt.Errorf("failed to %s: %s\n%s", step.what, err, string(output))
+		}
 	}
 	metrics.BuildTimeMs = float64(time.Since(buildStartTime).Microseconds()) / 1000.0
 
@@ -111,6 +115,53 @@ func (p *Profiler) Run(sourceFile string, optConfig optimizer.Config) (*Metrics,
 	return metrics, nil
 }
 
+// buildStep is one command of a build, with what it does for errors.
+type buildStep struct {
+	what string
+	cmd  *exec.Cmd
+}
+
+// buildSteps returns the commands that build the .glc file glcFile into
+// the executable binaryFile. Programs the x86-64 backend supports are
+// assembled and linked with as and ld when the host runs them; the rest
+// are compiled from C with $CC, or cc if it is unset.
+func (p *Profiler) buildSteps(compiler, glcFile, binaryFile string, program *ast.Program) []buildStep {
+	if nativeBuildAvailable() {
+		if generator := amd64.New(); generator.Generate(program) != "" {
+			asmFile := filepath.Join(p.workDir, "output.s")
+			objFile := filepath.Join(p.workDir, "output.o")
+			return []buildStep{
+				{"compile GoLite to x86-64 assembly", exec.Command(compiler, "build", "--target=amd64", "-o", asmFile, glcFile)},
+				{"assemble", exec.Command("as", "-o", objFile, asmFile)},
+				{"link", exec.Command("ld", "-o", binaryFile, objFile)},
+			}
+		}
+	}
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	cFile := filepath.Join(p.workDir, "output.c")
+	return []buildStep{
+		{"compile GoLite to C", exec.Command(compiler, "build", "-o", cFile, glcFile)},
+		{"compile C to native", exec.Command(cc, cFile, "-o", binaryFile)},
+	}
+}
+
+// nativeBuildAvailable reports whether the host runs the executables of
+// the x86-64 backend and has the tools to build them.
+func nativeBuildAvailable() bool {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		return false
+	}
+	for _, tool := range []string{"as", "ld"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
 type timeCommand struct {
 	Path string
 	Flag string
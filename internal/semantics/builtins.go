@@ -0,0 +1,44 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+// checkBuiltinCall types a call to a builtin. Unlike user functions,
+// builtins may accept several argument types, so each one types its calls
+// itself (see package builtins).
+func (c *Checker) checkBuiltinCall(ce *ast.CallExpression, b *types.Builtin) types.Type {
+	argTypes := make([]types.Type, len(ce.Arguments))
+	for i, arg := range ce.Arguments {
+		argTypes[i] = c.checkValue(arg)
+	}
+	builtin, ok := builtins.Lookup(b.Name)
+	if !ok {
+		return types.Invalid
+	}
+	return builtin.Check(builtinChecker{c}, ce, argTypes)
+}
+
+// builtinChecker gives the type rules of builtins access to the checker.
+type builtinChecker struct {
+	c *Checker
+}
+
+func (bc builtinChecker) NewVar() types.Type {
+	return bc.c.newVar()
+}
+
+func (bc builtinChecker) Unify(a, b types.Type) bool {
+	return bc.c.unify(a, b)
+}
+
+func (bc builtinChecker) Errorf(code diagnostics.Code, node ast.Node, format string, args ...interface{}) *diagnostics.Diagnostic {
+	return bc.c.addError(code, node, format, args...)
+}
+
+func (bc builtinChecker) RequireSequence(arg ast.Expression, t types.Type, builtin string) {
+	bc.c.requireSequence(arg, t, builtin)
+}
@@ -1,29 +1,54 @@
 package semantics
 
 import (
-	"fmt"
-
 	"golite.dev/mvp/internal/ast"
-	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
 )
 
 type Checker struct {
-	errors []string
+	errors []*diagnostics.Diagnostic
 	table  *SymbolTable
+	fn     *funcContext // innermost enclosing function, nil at top level
+	loops  int          // number of loops enclosing the current statement
+
+	sigs      map[*ast.FunctionLiteral]*types.Signature
+	types     map[ast.Expression]types.Type
+	structs   map[string]*types.Struct // the declared types, by name
+	nextVar   int
+	sequences []sequenceUse
+	nils      []nilUse
+}
+
+// funcContext tracks what the checker has seen of the function literal
+// currently being checked.
+type funcContext struct {
+	outer          *funcContext
+	sig            *types.Signature
+	hasValueReturn bool
 }
 
 func New() *Checker {
+	table := NewSymbolTable()
+	for _, b := range builtins.Predeclared() {
+		table.Define(b.Name, &types.Builtin{Name: b.Name})
+	}
 	return &Checker{
-		errors: []string{},
-		table:  NewSymbolTable(),
+		errors: []*diagnostics.Diagnostic{},
+		table:  table,
+		sigs:   map[*ast.FunctionLiteral]*types.Signature{},
+		types:  map[ast.Expression]types.Type{},
+
+		structs: map[string]*types.Struct{},
 	}
 }
 
-func (c *Checker) Errors() []string {
+func (c *Checker) Errors() []*diagnostics.Diagnostic {
 	return c.errors
 }
 
-func (c *Checker) Check(node ast.Node) object.ObjectType {
+func (c *Checker) Check(node ast.Node) types.Type {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -35,16 +60,57 @@ func (c *Checker) Check(node ast.Node) object.ObjectType {
 	case *ast.BlockStatement:
 		return c.checkBlockStatement(node)
 	case *ast.PrintStatement:
-		c.Check(node.Expression)
-		return object.NULL_OBJ // Statements don't have a type
+		c.checkValue(node.Expression)
+		return types.Void // Statements don't have a type
+	case *ast.ReturnStatement:
+		return c.checkReturnStatement(node)
+	case *ast.DeferStatement:
+		return c.checkDeferStatement(node)
+	case *ast.AssignStatement:
+		return c.checkAssignStatement(node)
+	case *ast.IncDecStatement:
+		return c.checkIncDecStatement(node)
+	case *ast.ForStatement:
+		return c.checkForStatement(node)
+	case *ast.BreakStatement:
+		if c.loops == 0 {
+			c.addError(diagnostics.BranchOutsideLoop, node, "break is not in a loop")
+		}
+		return types.Void
+	case *ast.ContinueStatement:
+		if c.loops == 0 {
+			c.addError(diagnostics.BranchOutsideLoop, node, "continue is not in a loop")
+		}
+		return types.Void
+	case *ast.TypeStatement:
+		// Top-level declarations are handled by checkProgram.
+		c.addError(diagnostics.InvalidDeclaration, node, "type declarations are only allowed at the top level")
+		return types.Void
+	case *ast.MethodStatement:
+		c.addError(diagnostics.InvalidDeclaration, node, "methods can only be declared at the top level")
+		return types.Void
 
 	// Expressions
+	case ast.Expression:
+		t := c.checkExpression(node)
+		c.types[node] = t
+		return t
+	}
+	return types.Void
+}
+
+func (c *Checker) checkExpression(node ast.Expression) types.Type {
+	switch node := node.(type) {
 	case *ast.Identifier:
 		return c.checkIdentifier(node)
 	case *ast.IntegerLiteral:
-		return object.INTEGER_OBJ
+		return types.Int
+	case *ast.FloatLiteral:
+		return types.Float
+	case *ast.StringLiteral:
+		return types.String
 	case *ast.Boolean:
-		return object.BOOLEAN_OBJ
+		return types.Bool
 	case *ast.InfixExpression:
 		return c.checkInfixExpression(node)
 	case *ast.PrefixExpression:
@@ -55,169 +121,624 @@ func (c *Checker) Check(node ast.Node) object.ObjectType {
 		return c.checkFunctionLiteral(node)
 	case *ast.CallExpression:
 		return c.checkCallExpression(node)
+	case *ast.ArrayLiteral:
+		return c.checkArrayLiteral(node)
+	case *ast.MapLiteral:
+		return c.checkMapLiteral(node)
+	case *ast.IndexExpression:
+		return c.checkIndexExpression(node)
+	case *ast.SliceExpression:
+		return c.checkSliceExpression(node)
+	case *ast.StructLiteral:
+		return c.checkStructLiteral(node)
+	case *ast.SelectorExpression:
+		return c.checkSelector(node, false)
+	case *ast.TupleExpression:
+		return c.checkTupleExpression(node)
+	case *ast.BadExpression:
+		// The parser has reported the syntax error.
+		return types.Invalid
+	}
+	return types.Void
+}
+
+// checkValue checks an expression whose value is used, such as an operand
+// or the value of a let statement, and reports calls that produce none or
+// several values.
+func (c *Checker) checkValue(expr ast.Expression) types.Type {
+	t := c.checkValues(expr)
+	if tuple, ok := types.Prune(t).(*types.Tuple); ok {
+		c.addError(diagnostics.TypeMismatch, expr, "multiple-value %s (value of type %s) in single-value context", expr.String(), tuple)
+		return types.Invalid
+	}
+	return t
+}
+
+// checkValues checks an expression that may produce several values, as the
+// value of a return statement or of an assignment to several variables may.
+func (c *Checker) checkValues(expr ast.Expression) types.Type {
+	t := c.Check(expr)
+	if types.Prune(t) == types.Void {
+		c.addError(diagnostics.TypeMismatch, expr, "%s (no value) used as value", expr.String())
+		return types.Invalid
 	}
-	return object.NULL_OBJ
+	return t
 }
 
-func (c *Checker) addError(format string, args ...interface{}) {
-	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+// addError records an error diagnostic located at node.
+func (c *Checker) addError(code diagnostics.Code, node ast.Node, format string, args ...interface{}) *diagnostics.Diagnostic {
+	return c.addErrorAt(code, diagnostics.SpanOf(node), format, args...)
 }
 
-func (c *Checker) checkProgram(program *ast.Program) object.ObjectType {
+// addErrorAt records an error diagnostic located at span.
+func (c *Checker) addErrorAt(code diagnostics.Code, span diagnostics.Span, format string, args ...interface{}) *diagnostics.Diagnostic {
+	d := diagnostics.Errorf(code, span, format, args...)
+	c.errors = append(c.errors, d)
+	return d
+}
+
+func (c *Checker) checkProgram(program *ast.Program) types.Type {
+	c.declareTypes(program)
 	for _, stmt := range program.Statements {
-		c.Check(stmt)
+		switch stmt := stmt.(type) {
+		case *ast.TypeStatement:
+			// Declared by declareTypes.
+		case *ast.MethodStatement:
+			c.Check(stmt.Function)
+		default:
+			c.Check(stmt)
+		}
 	}
-	return object.NULL_OBJ
+	c.resolveSequences()
+	c.resolveNils()
+	return types.Void
 }
 
-func (c *Checker) checkBlockStatement(block *ast.BlockStatement) object.ObjectType {
+// checkBlockStatement returns the type of the block's trailing expression,
+// which is the value the block produces, or void if it has none.
+func (c *Checker) checkBlockStatement(block *ast.BlockStatement) types.Type {
 	// Scoping for block statements can be added here if needed
+	result := types.Type(types.Void)
 	for _, stmt := range block.Statements {
-		c.Check(stmt)
+		result = c.Check(stmt)
+		if _, ok := stmt.(*ast.ExpressionStatement); !ok {
+			result = types.Void
+		}
 	}
-	return object.NULL_OBJ
+	return result
 }
 
-func (c *Checker) checkLetStatement(stmt *ast.LetStatement) object.ObjectType {
-	valType := c.Check(stmt.Value)
-	if valType == object.ERROR_OBJ {
-		return object.ERROR_OBJ
+func (c *Checker) checkLetStatement(stmt *ast.LetStatement) types.Type {
+	if len(stmt.Rest) > 0 {
+		return c.checkLetValues(stmt)
+	}
+	name := stmt.Name.Value
+	var declared types.Type
+	if stmt.Type != nil {
+		declared = c.resolveType(stmt.Type)
+	}
+
+	// Define function names before checking their bodies so that they can
+	// call themselves recursively.
+	lit, isFunc := stmt.Value.(*ast.FunctionLiteral)
+	if isFunc {
+		sig := c.signature(lit)
+		if declared != nil {
+			c.unify(sig, declared)
+		}
+		c.table.Define(name, sig)
 	}
-	c.table.Define(stmt.Name.Value, valType)
-	return object.NULL_OBJ
+	valType := c.checkAssignedValue(stmt.Value, stmt)
+
+	switch {
+	case declared != nil:
+		if !c.unify(valType, declared) {
+			c.addError(diagnostics.TypeMismatch, stmt.Value, "cannot use %s (type %s) as %s in variable declaration",
+				stmt.Value.String(), valType, declared).
+				WithLabel(diagnostics.SpanOf(stmt.Type), "declared as %s", declared)
+		}
+		c.table.Define(name, declared)
+	case isFunc:
+		c.table.DefineGeneric(name, valType, c.generalize(valType, name))
+	default:
+		c.table.Define(name, valType)
+	}
+	return types.Void
 }
 
-func (c *Checker) checkIdentifier(ident *ast.Identifier) object.ObjectType {
+func (c *Checker) checkReturnStatement(rs *ast.ReturnStatement) types.Type {
+	var valType types.Type = types.Void
+	if rs.ReturnValue != nil {
+		valType = c.checkValues(rs.ReturnValue)
+	}
+	if c.fn == nil {
+		c.addError(diagnostics.ReturnOutsideFunction, rs, "return statement outside function")
+		return types.Void
+	}
+
+	result := c.fn.sig.Result
+	if rs.ReturnValue == nil {
+		if !c.unify(result, types.Void) {
+			c.addError(diagnostics.MissingReturn, rs, "not enough return values: want %s", result)
+		}
+		return types.Void
+	}
+	c.fn.hasValueReturn = true
+	if !c.checkReturnCount(rs, valType, result) {
+		return types.Void
+	}
+	if !c.unify(valType, result) {
+		c.addError(diagnostics.TypeMismatch, rs.ReturnValue, "cannot use %s (type %s) as %s in return statement",
+			rs.ReturnValue.String(), valType, result)
+	}
+	return types.Void
+}
+
+func (c *Checker) checkAssignStatement(as *ast.AssignStatement) types.Type {
+	if isTuple(as.Target) {
+		return c.checkAssignValues(as)
+	}
+	targetType := c.checkAssignTarget(as.Target)
+	valType := c.checkAssignedValue(as.Value, as)
+	if targetType == types.Invalid || valType == types.Invalid {
+		return types.Void
+	}
+
+	if as.Operator != "=" {
+		// The target decides the type: x += 1.5 does not turn an int x into
+		// a float.
+		want := arithmeticType(as.Operator[:1], targetType, targetType)
+		if !c.unify(targetType, want) || !c.unify(valType, want) {
+			c.addError(diagnostics.UnknownOperator, as, "unknown operator: %s %s %s", targetType, as.Operator, valType)
+		}
+		return types.Void
+	}
+
+	if !c.unify(valType, targetType) {
+		c.addError(diagnostics.TypeMismatch, as.Value, "cannot assign %s to %s (type %s)", valType, as.Target.String(), targetType).
+			WithLabel(diagnostics.SpanOf(as.Target), "declared as %s", targetType)
+	}
+	return types.Void
+}
+
+func (c *Checker) checkIncDecStatement(ids *ast.IncDecStatement) types.Type {
+	targetType := c.checkAssignTarget(ids.Target)
+	// Only numbers can be incremented, so ask for the type of a subtraction.
+	if !c.unify(targetType, arithmeticType("-", targetType, targetType)) {
+		c.addError(diagnostics.UnknownOperator, ids, "unknown operator: %s%s", targetType, ids.Operator)
+	}
+	return types.Void
+}
+
+// checkAssignTarget checks that target denotes an assignable, previously
+// declared variable and returns its type.
+func (c *Checker) checkAssignTarget(target ast.Expression) types.Type {
+	switch target := target.(type) {
+	case *ast.BadExpression:
+		return types.Invalid
+	case *ast.IndexExpression:
+		return c.Check(target)
+	case *ast.SelectorExpression:
+		return c.checkFieldAssignTarget(target)
+	}
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		c.addError(diagnostics.InvalidAssignTarget, target, "cannot assign to %s", target.String())
+		return types.Invalid
+	}
+	if ident.Value == "_" {
+		// Assigning to _ discards a value of any type.
+		return c.newVar()
+	}
 	symbol, ok := c.table.Resolve(ident.Value)
 	if !ok {
-		c.addError("identifier not found: %s", ident.Value)
-		return object.ERROR_OBJ
+		c.addError(diagnostics.UndefinedName, ident, "cannot assign to undeclared name: %s", ident.Value).
+			WithNote("declare it first with `let %s = ...`", ident.Value)
+		return types.Invalid
 	}
+	c.types[ident] = symbol.Type
 	return symbol.Type
 }
 
-func (c *Checker) checkPrefixExpression(node *ast.PrefixExpression) object.ObjectType {
-	rightType := c.Check(node.Right)
-	if rightType == object.ERROR_OBJ {
-		return object.ERROR_OBJ
+// resolveType converts a type annotation to the type it denotes.
+func (c *Checker) resolveType(expr ast.TypeExpr) types.Type {
+	switch t := expr.(type) {
+	case *ast.NamedType:
+		if typ, ok := types.Lookup(t.Name); ok {
+			return typ
+		}
+		if s, ok := c.structs[t.Name]; ok {
+			return s
+		}
+		c.addError(diagnostics.UndefinedType, t, "undefined type: %s", t.Name)
+		return types.Invalid
+	case *ast.TupleType:
+		tuple := &types.Tuple{}
+		for _, e := range t.Elems {
+			tuple.Elems = append(tuple.Elems, c.resolveType(e))
+		}
+		return tuple
+	case *ast.FuncType:
+		sig := &types.Signature{Result: types.Void}
+		for _, p := range t.Params {
+			sig.Params = append(sig.Params, c.resolveType(p))
+		}
+		if t.Result != nil {
+			sig.Result = c.resolveType(t.Result)
+		}
+		return sig
+	case *ast.SliceType:
+		return &types.Slice{Elem: c.resolveType(t.Elem)}
+	case *ast.MapType:
+		key := c.resolveType(t.Key)
+		if key != types.Invalid && !types.Comparable(key) {
+			c.addError(diagnostics.InvalidMapKey, t.Key, "invalid map key type %s", key).
+				WithNote("map keys must be int, float, bool or string")
+			key = types.Invalid
+		}
+		return &types.Map{Key: key, Value: c.resolveType(t.Value)}
+	case *ast.PointerType:
+		elem := c.resolveType(t.Elem)
+		if _, ok := elem.(*types.Struct); !ok && elem != types.Invalid {
+			c.addError(diagnostics.UndefinedType, t, "invalid pointer type %s", t.String()).
+				WithNote("only structs can be pointed to")
+			return types.Invalid
+		}
+		return &types.Pointer{Elem: elem}
+	}
+	return types.Invalid
+}
+
+// signature returns the type of a function literal as declared by its
+// annotations, with a fresh type variable for every parameter or result
+// left unannotated. The result is cached so that annotation errors are
+// reported once.
+func (c *Checker) signature(fl *ast.FunctionLiteral) *types.Signature {
+	if sig, ok := c.sigs[fl]; ok {
+		return sig
+	}
+	sig := &types.Signature{Params: make([]types.Type, len(fl.Parameters))}
+	for i := range fl.Parameters {
+		if i < len(fl.ParamTypes) && fl.ParamTypes[i] != nil {
+			sig.Params[i] = c.resolveType(fl.ParamTypes[i])
+		} else {
+			sig.Params[i] = c.newVar()
+		}
+	}
+	if fl.ReturnType != nil {
+		sig.Result = c.resolveType(fl.ReturnType)
+	} else {
+		sig.Result = c.newVar()
+	}
+	c.sigs[fl] = sig
+	return sig
+}
+
+func (c *Checker) checkForStatement(fs *ast.ForStatement) types.Type {
+	// Variables declared in the init clause are scoped to the loop.
+	originalTable := c.table
+	c.table = NewEnclosedSymbolTable(c.table)
+	defer func() { c.table = originalTable }()
+
+	if fs.Init != nil {
+		c.Check(fs.Init)
+	}
+	if fs.Condition != nil {
+		condType := c.Check(fs.Condition)
+		if !c.unify(condType, types.Bool) {
+			c.addError(diagnostics.NonBoolCondition, fs.Condition, "for condition must be a boolean, got %s", condType)
+		}
+	}
+	if fs.Post != nil {
+		c.Check(fs.Post)
+	}
+
+	c.loops++
+	c.Check(fs.Body)
+	c.loops--
+	return types.Void
+}
+
+func (c *Checker) checkIdentifier(ident *ast.Identifier) types.Type {
+	if ident.Value == "_" {
+		c.addError(diagnostics.UndefinedName, ident, "cannot use _ as value")
+		return types.Invalid
+	}
+	symbol, ok := c.table.Resolve(ident.Value)
+	if !ok && ident.Value == "nil" {
+		return c.checkNil(ident)
+	}
+	if !ok {
+		c.addError(diagnostics.UndefinedName, ident, "identifier not found: %s", ident.Value)
+		return types.Invalid
+	}
+	if b, ok := symbol.Type.(*types.Builtin); ok {
+		d := c.addError(diagnostics.InvalidBuiltinCall, ident, "%s (built-in function) must be called", ident.Value)
+		if builtin, ok := builtins.Lookup(b.Name); ok {
+			d.WithNote("%s", builtin.Signature)
+		}
+		return types.Invalid
+	}
+	return c.instantiate(symbol)
+}
+
+func (c *Checker) checkPrefixExpression(node *ast.PrefixExpression) types.Type {
+	rightType := c.checkValue(node.Right)
+	if rightType == types.Invalid {
+		return types.Invalid
 	}
 
 	switch node.Operator {
 	case "!":
-		if rightType != object.BOOLEAN_OBJ {
-			c.addError("unknown operator: %s%s", node.Operator, rightType)
-			return object.ERROR_OBJ
+		if !c.unify(rightType, types.Bool) {
+			c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s%s", node.Operator, rightType)
+			return types.Invalid
 		}
-		return object.BOOLEAN_OBJ
+		return types.Bool
+	case "&":
+		return c.checkAddressOf(node, rightType)
 	case "-":
-		if rightType != object.INTEGER_OBJ {
-			c.addError("unknown operator: %s%s", node.Operator, rightType)
-			return object.ERROR_OBJ
+		want := arithmeticType(node.Operator, rightType, rightType)
+		if !c.unify(rightType, want) {
+			c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s%s", node.Operator, rightType)
+			return types.Invalid
 		}
-		return object.INTEGER_OBJ
+		return want
 	default:
-		c.addError("unknown operator: %s%s", node.Operator, rightType)
-		return object.ERROR_OBJ
+		c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s%s", node.Operator, rightType)
+		return types.Invalid
 	}
 }
 
-func (c *Checker) checkInfixExpression(node *ast.InfixExpression) object.ObjectType {
-	leftType := c.Check(node.Left)
-	rightType := c.Check(node.Right)
+func (c *Checker) checkInfixExpression(node *ast.InfixExpression) types.Type {
+	leftType := c.checkValue(node.Left)
+	rightType := c.checkValue(node.Right)
 
-	if leftType == object.ERROR_OBJ || rightType == object.ERROR_OBJ {
-		return object.ERROR_OBJ
+	if leftType == types.Invalid || rightType == types.Invalid {
+		return types.Invalid
 	}
 
-	switch {
-	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
-		switch node.Operator {
-		case "+", "-", "*", "/":
-			return object.INTEGER_OBJ
-		case "<", ">", "==", "!=":
-			return object.BOOLEAN_OBJ
-		default:
-			c.addError("unknown operator: %s %s %s", leftType, node.Operator, rightType)
-			return object.ERROR_OBJ
+	switch node.Operator {
+	case "+", "-", "*", "/", "%", "&", "|", "^", "<<", ">>", "&&", "||":
+		want := arithmeticType(node.Operator, leftType, rightType)
+		if !c.unifyOperands(node, leftType, rightType, want) {
+			return types.Invalid
 		}
-	case leftType == object.BOOLEAN_OBJ && rightType == object.BOOLEAN_OBJ:
-		switch node.Operator {
-		case "==", "!=":
-			return object.BOOLEAN_OBJ
-		default:
-			c.addError("unknown operator: %s %s %s", leftType, node.Operator, rightType)
-			return object.ERROR_OBJ
+		return want
+	case "<", ">", "<=", ">=":
+		if !c.unifyOperands(node, leftType, rightType, arithmeticType(node.Operator, leftType, rightType)) {
+			return types.Invalid
 		}
-	case leftType != rightType:
-		c.addError("type mismatch: %s %s %s", leftType, node.Operator, rightType)
-		return object.ERROR_OBJ
+		return types.Bool
+	case "==", "!=":
+		if !c.unifyOperands(node, leftType, rightType, nil) {
+			return types.Invalid
+		}
+		switch types.Prune(leftType).(type) {
+		case *types.Signature, *types.Slice, *types.Map, *types.Struct:
+			c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s %s %s", leftType, node.Operator, rightType)
+			return types.Invalid
+		}
+		return types.Bool
 	default:
-		c.addError("unknown operator: %s %s %s", leftType, node.Operator, rightType)
-		return object.ERROR_OBJ
+		c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s %s %s", leftType, node.Operator, rightType)
+		return types.Invalid
+	}
+}
+
+// arithmeticType returns the type the operands of an arithmetic, logical or
+// ordering operator must share: bool for && and ||, int for the remainder and
+// bitwise operators, float if either operand is a float, string for a
+// concatenation, and int otherwise, which is also what operands of unknown
+// type default to.
+func arithmeticType(operator string, leftType, rightType types.Type) types.Type {
+	left, right := types.Prune(leftType), types.Prune(rightType)
+	switch {
+	case operator == "&&" || operator == "||":
+		return types.Bool
+	case operator == "%" || operator == "&" || operator == "|" || operator == "^" || operator == "<<" || operator == ">>":
+		return types.Int
+	case operator == "+" && (left == types.String || right == types.String):
+		return types.String
+	case left == types.Float || right == types.Float:
+		return types.Float
+	}
+	return types.Int
+}
+
+func isNumeric(t types.Type) bool {
+	t = types.Prune(t)
+	return t == types.Int || t == types.Float
+}
+
+// unifyOperands requires both operands of node to have the same type, and
+// that type to be want unless want is nil.
+func (c *Checker) unifyOperands(node *ast.InfixExpression, leftType, rightType, want types.Type) bool {
+	ok := c.unify(leftType, rightType)
+	if !ok {
+		d := c.addError(diagnostics.TypeMismatch, node, "type mismatch: %s %s %s", leftType, node.Operator, rightType).
+			WithLabel(diagnostics.SpanOf(node.Left), "this is %s", leftType).
+			WithLabel(diagnostics.SpanOf(node.Right), "this is %s", rightType)
+		if isNumeric(leftType) && isNumeric(rightType) {
+			d.WithNote("numbers are not converted implicitly; use int(x) or float(x)")
+		}
+		return false
 	}
+	if want != nil && !c.unify(leftType, want) {
+		c.addError(diagnostics.UnknownOperator, node, "unknown operator: %s %s %s", leftType, node.Operator, rightType)
+		return false
+	}
+	return true
 }
 
-func (c *Checker) checkIfExpression(ie *ast.IfExpression) object.ObjectType {
+// checkIfExpression returns the type of the value the if expression
+// produces: the common type of both branches, or void if there is none.
+func (c *Checker) checkIfExpression(ie *ast.IfExpression) types.Type {
 	condType := c.Check(ie.Condition)
-	if condType != object.BOOLEAN_OBJ {
-		c.addError("if condition must be a boolean, got %s", condType)
+	if !c.unify(condType, types.Bool) {
+		c.addError(diagnostics.NonBoolCondition, ie.Condition, "if condition must be a boolean, got %s", condType)
 	}
 
-	c.Check(ie.Consequence)
-	if ie.Alternative != nil {
-		c.Check(ie.Alternative)
+	consType := c.Check(ie.Consequence)
+	if ie.Alternative == nil {
+		return types.Void
+	}
+	altType := c.Check(ie.Alternative)
+	if types.Prune(consType) == types.Void || types.Prune(altType) == types.Void || !c.unify(consType, altType) {
+		return types.Void
 	}
-	// In a real language, we'd check if consequence/alternative have compatible return types.
-	// For now, if-expressions don't return values.
-	return object.NULL_OBJ
+	return consType
 }
 
-func (c *Checker) checkFunctionLiteral(fl *ast.FunctionLiteral) object.ObjectType {
+func (c *Checker) checkFunctionLiteral(fl *ast.FunctionLiteral) types.Type {
+	sig := c.signature(fl)
+
 	// Create a new scope for the function body
 	enclosedTable := NewEnclosedSymbolTable(c.table)
 	originalTable := c.table
 	c.table = enclosedTable
 	defer func() { c.table = originalTable }() // Restore the original table after checking
 
-	for _, p := range fl.Parameters {
-		// In a typed language, parameters would have types. Here we assume they can be anything
-		// until they are used. A more robust checker would handle this differently.
-		c.table.Define(p.Value, "ANY") // A placeholder type
+	c.fn = &funcContext{outer: c.fn, sig: sig}
+	// break and continue cannot cross a function boundary.
+	outerLoops := c.loops
+	c.loops = 0
+	defer func() {
+		c.fn = c.fn.outer
+		c.loops = outerLoops
+	}()
+
+	for i, p := range fl.Parameters {
+		c.table.Define(p.Value, sig.Params[i])
 	}
 
-	c.Check(fl.Body)
-	return object.FUNCTION_OBJ
+	bodyType := c.Check(fl.Body)
+
+	// A function that returns a value explicitly must do so on every path;
+	// falling off the end would silently yield the last expression instead.
+	// A trailing expression counts as the final return.
+	switch {
+	case isTerminating(fl.Body):
+	case types.Prune(bodyType) != types.Void:
+		if !c.unify(bodyType, sig.Result) {
+			last := fl.Body.Statements[len(fl.Body.Statements)-1]
+			c.addError(diagnostics.TypeMismatch, last, "cannot use %s (type %s) as %s in return statement",
+				last.String(), bodyType, sig.Result)
+		}
+	case c.fn.hasValueReturn || !c.unify(sig.Result, types.Void):
+		span := diagnostics.Span{Start: fl.Body.Rbrace, End: fl.Body.End()}
+		c.addErrorAt(diagnostics.MissingReturn, span, "missing return at end of function").
+			WithLabel(diagnostics.SpanOfToken(fl.Token), "in this function")
+	}
+	c.resolveSequences()
+	c.resolveNils()
+	return sig
+}
+
+// isTerminating reports whether control can never fall off the end of stmt,
+// following Go's notion of a terminating statement.
+func isTerminating(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.BlockStatement:
+		if len(s.Statements) == 0 {
+			return false
+		}
+		return isTerminating(s.Statements[len(s.Statements)-1])
+	case *ast.ExpressionStatement:
+		if isPanic(s.Expression) {
+			return true
+		}
+		ie, ok := s.Expression.(*ast.IfExpression)
+		if !ok || ie.Alternative == nil {
+			return false
+		}
+		return isTerminating(ie.Consequence) && isTerminating(ie.Alternative)
+	case *ast.ForStatement:
+		// An infinite loop only terminates the function if nothing breaks out of it.
+		return s.Condition == nil && !hasBreak(s.Body)
+	}
+	return false
 }
 
-func (c *Checker) checkCallExpression(ce *ast.CallExpression) object.ObjectType {
-	// Check that the function being called is actually a function
-	fnType := c.Check(ce.Function)
-	if fnType != object.FUNCTION_OBJ {
-		c.addError("not a function: %s", ce.Function.String())
-		return object.ERROR_OBJ
+// hasBreak reports whether stmt contains a break that targets the enclosing
+// loop, ignoring nested loops and function literals.
+func hasBreak(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.BreakStatement:
+		return true
+	case *ast.BlockStatement:
+		for _, inner := range s.Statements {
+			if hasBreak(inner) {
+				return true
+			}
+		}
+	case *ast.ExpressionStatement:
+		if ie, ok := s.Expression.(*ast.IfExpression); ok {
+			return hasBreak(ie.Consequence) || (ie.Alternative != nil && hasBreak(ie.Alternative))
+		}
 	}
-	// This is a simplified check. A full check would verify argument types.
-	fn, ok := ce.Function.(*ast.Identifier)
-	if ok {
-		// A more complex check would look up the function literal itself.
-		// For now, we'll just check based on the identifier.
-		sym, ok := c.table.Resolve(fn.Value)
-		if !ok {
-			// This should have been caught by checkIdentifier already.
-			return object.ERROR_OBJ
+	return false
+}
+
+func (c *Checker) checkCallExpression(ce *ast.CallExpression) types.Type {
+	if ident, ok := ce.Function.(*ast.Identifier); ok {
+		if sym, ok := c.table.Resolve(ident.Value); ok {
+			if b, ok := sym.Type.(*types.Builtin); ok {
+				c.types[ident] = b
+				return c.checkBuiltinCall(ce, b)
+			}
 		}
+	}
 
-		// A more advanced checker would resolve the identifier to its FunctionLiteral
-		// and check the arity there. This is a simplification for now.
-		if sym.Type != object.FUNCTION_OBJ {
-			c.addError("not a function: %s", fn.Value)
-			return object.ERROR_OBJ
+	var fnType types.Type
+	if se, ok := ce.Function.(*ast.SelectorExpression); ok {
+		if b, ok := c.qualifiedBuiltin(se); ok {
+			c.types[se] = b
+			return c.checkBuiltinCall(ce, b)
 		}
+		fnType = c.checkSelector(se, true)
+		c.types[se] = fnType
+	} else {
+		fnType = c.Check(ce.Function)
+	}
+	argTypes := make([]types.Type, len(ce.Arguments))
+	for i, arg := range ce.Arguments {
+		argTypes[i] = c.checkValue(arg)
 	}
 
-	for _, arg := range ce.Arguments {
-		c.Check(arg)
+	switch fn := types.Prune(fnType).(type) {
+	case *types.Var:
+		// Calling a value of unknown type, such as a parameter, tells us it
+		// is a function taking these arguments.
+		sig := &types.Signature{Params: argTypes, Result: c.newVar()}
+		if !c.unify(fn, sig) {
+			c.addError(diagnostics.TypeMismatch, ce, "cannot infer a type for %s: it would contain itself", ce.Function.String())
+			return types.Invalid
+		}
+		return sig.Result
+	case *types.Signature:
+		if len(ce.Arguments) != len(fn.Params) {
+			c.addError(diagnostics.WrongArgumentCount, ce, "wrong number of arguments in call to %s: want %d, got %d",
+				ce.Function.String(), len(fn.Params), len(ce.Arguments)).
+				WithNote("%s has type %s", ce.Function.String(), fn)
+			return fn.Result
+		}
+		for i, arg := range ce.Arguments {
+			if !c.unify(argTypes[i], fn.Params[i]) {
+				c.addError(diagnostics.TypeMismatch, arg, "cannot use %s (type %s) as %s in argument to %s",
+					arg.String(), argTypes[i], fn.Params[i], ce.Function.String()).
+					WithNote("%s has type %s", ce.Function.String(), fn)
+			}
+		}
+		return fn.Result
+	default:
+		if fn == types.Invalid {
+			return types.Invalid
+		}
+		c.addError(diagnostics.NotAFunction, ce.Function, "not a function: %s", ce.Function.String()).
+			WithLabel(diagnostics.SpanOf(ce.Function), "this is %s", fn)
+		return types.Invalid
 	}
-	// A full implementation would return the function's return type.
-	return object.NULL_OBJ
 }
@@ -0,0 +1,94 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+// qualifiedBuiltin returns the builtin that se names, if any. Some are
+// called through the name of a package, as in errors.New("..."), unless a
+// let shadows the package name.
+func (c *Checker) qualifiedBuiltin(se *ast.SelectorExpression) (*types.Builtin, bool) {
+	pkg, ok := se.X.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := c.table.Resolve(pkg.Value); ok {
+		return nil, false
+	}
+	b, ok := builtins.Lookup(pkg.Value + "." + se.Sel.Value)
+	if !ok {
+		return nil, false
+	}
+	return &types.Builtin{Name: b.Name}, true
+}
+
+// nilUse is a use of nil whose type was not known when it was checked. As
+// with the operands of len, the choice waits until the end of the
+// enclosing function.
+type nilUse struct {
+	ident *ast.Identifier
+	typ   types.Type
+}
+
+// checkNil types the predeclared nil, the zero value of errors and
+// pointers.
+func (c *Checker) checkNil(ident *ast.Identifier) types.Type {
+	t := c.newVar()
+	c.nils = append(c.nils, nilUse{ident: ident, typ: t})
+	return t
+}
+
+// resolveNils settles the uses of nil collected by checkNil. Those still of
+// unknown type become errors.
+func (c *Checker) resolveNils() {
+	uses := c.nils
+	c.nils = nil
+	for _, use := range uses {
+		if _, ok := types.Prune(use.typ).(*types.Var); ok {
+			c.unify(use.typ, types.Error)
+		}
+		t := types.Prune(use.typ)
+		if _, ok := t.(*types.Pointer); !ok && t != types.Error && t != types.Invalid {
+			c.addError(diagnostics.TypeMismatch, use.ident, "cannot use nil as %s value", t).
+				WithNote("nil is the zero value of errors and pointers only")
+		}
+	}
+}
+
+// checkErrorMethod checks err.Error(), the one method of the error type,
+// which returns the message of a non-nil error.
+func (c *Checker) checkErrorMethod(se *ast.SelectorExpression, called bool) types.Type {
+	if se.Sel.Value != "Error" {
+		c.addError(diagnostics.UndefinedField, se, "%s undefined (type error has no field or method %s)", se.String(), se.Sel.Value)
+		return types.Invalid
+	}
+	if !called {
+		c.addError(diagnostics.TypeMismatch, se, "method %s cannot be used as a value", se.String()).
+			WithNote("call it instead, or wrap it in a function literal")
+		return types.Invalid
+	}
+	return &types.Signature{Result: types.String}
+}
+
+// checkDeferStatement checks defer f(x). The call's result, if any, is
+// discarded. A deferred call at the top level runs when the program ends.
+func (c *Checker) checkDeferStatement(ds *ast.DeferStatement) types.Type {
+	if ds.Call != nil {
+		c.Check(ds.Call)
+	}
+	return types.Void
+}
+
+// isPanic reports whether expr calls the builtin panic, which like a
+// return statement never lets control reach the statement after it.
+func isPanic(expr ast.Expression) bool {
+	ce, ok := expr.(*ast.CallExpression)
+	if !ok {
+		return false
+	}
+	ident, ok := ce.Function.(*ast.Identifier)
+	return ok && ident.Value == "panic"
+}
@@ -0,0 +1,250 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/types"
+)
+
+// This file holds the Hindley–Milner machinery used by the checker.
+// Unannotated parameters and results start out as fresh type variables that
+// unify binds as the body constrains them. Functions bound by let are
+// generalized over the variables left unbound, so that each use can
+// instantiate them afresh.
+
+func (c *Checker) newVar() *types.Var {
+	c.nextVar++
+	return &types.Var{ID: c.nextVar}
+}
+
+// unify makes a and b the same type by binding type variables and reports
+// whether that is possible. A failed unification binds nothing, so the
+// operands can still be described in the resulting error.
+func (c *Checker) unify(a, b types.Type) bool {
+	var bound []*types.Var
+	if unify(a, b, &bound) {
+		return true
+	}
+	for _, v := range bound {
+		v.Instance = nil
+	}
+	return false
+}
+
+func unify(a, b types.Type, bound *[]*types.Var) bool {
+	a, b = types.Prune(a), types.Prune(b)
+	if a == types.Invalid || b == types.Invalid {
+		// The error has been reported where the invalid type came from.
+		return true
+	}
+	if va, ok := a.(*types.Var); ok {
+		return bind(va, b, bound)
+	}
+	if vb, ok := b.(*types.Var); ok {
+		return bind(vb, a, bound)
+	}
+
+	if sa, ok := a.(*types.Slice); ok {
+		sb, ok := b.(*types.Slice)
+		return ok && unify(sa.Elem, sb.Elem, bound)
+	}
+	if ma, ok := a.(*types.Map); ok {
+		mb, ok := b.(*types.Map)
+		return ok && unify(ma.Key, mb.Key, bound) && unify(ma.Value, mb.Value, bound)
+	}
+	if pa, ok := a.(*types.Pointer); ok {
+		pb, ok := b.(*types.Pointer)
+		return ok && unify(pa.Elem, pb.Elem, bound)
+	}
+	if ta, ok := a.(*types.Tuple); ok {
+		tb, ok := b.(*types.Tuple)
+		if !ok || len(ta.Elems) != len(tb.Elems) {
+			return false
+		}
+		for i := range ta.Elems {
+			if !unify(ta.Elems[i], tb.Elems[i], bound) {
+				return false
+			}
+		}
+		return true
+	}
+	sa, ok := a.(*types.Signature)
+	if !ok {
+		return a == b
+	}
+	sb, ok := b.(*types.Signature)
+	if !ok || len(sa.Params) != len(sb.Params) {
+		return false
+	}
+	for i := range sa.Params {
+		if !unify(sa.Params[i], sb.Params[i], bound) {
+			return false
+		}
+	}
+	return unify(sa.Result, sb.Result, bound)
+}
+
+func bind(v *types.Var, t types.Type, bound *[]*types.Var) bool {
+	if v == t {
+		return true
+	}
+	if occurs(v, t) {
+		return false
+	}
+	v.Instance = t
+	*bound = append(*bound, v)
+	return true
+}
+
+// occurs reports whether v appears in t, in which case binding v to t would
+// create an infinite type.
+func occurs(v *types.Var, t types.Type) bool {
+	t = types.Prune(t)
+	if t == v {
+		return true
+	}
+	if s, ok := t.(*types.Slice); ok {
+		return occurs(v, s.Elem)
+	}
+	if m, ok := t.(*types.Map); ok {
+		return occurs(v, m.Key) || occurs(v, m.Value)
+	}
+	if p, ok := t.(*types.Pointer); ok {
+		return occurs(v, p.Elem)
+	}
+	if tuple, ok := t.(*types.Tuple); ok {
+		for _, e := range tuple.Elems {
+			if occurs(v, e) {
+				return true
+			}
+		}
+		return false
+	}
+	if sig, ok := t.(*types.Signature); ok {
+		for _, p := range sig.Params {
+			if occurs(v, p) {
+				return true
+			}
+		}
+		return occurs(v, sig.Result)
+	}
+	return false
+}
+
+// freeVars appends the unbound type variables of t to vars, without
+// duplicates.
+func freeVars(t types.Type, vars []*types.Var) []*types.Var {
+	switch t := types.Prune(t).(type) {
+	case *types.Var:
+		if !containsVar(vars, t) {
+			vars = append(vars, t)
+		}
+	case *types.Signature:
+		for _, p := range t.Params {
+			vars = freeVars(p, vars)
+		}
+		vars = freeVars(t.Result, vars)
+	case *types.Slice:
+		vars = freeVars(t.Elem, vars)
+	case *types.Map:
+		vars = freeVars(t.Key, vars)
+		vars = freeVars(t.Value, vars)
+	case *types.Pointer:
+		vars = freeVars(t.Elem, vars)
+	case *types.Tuple:
+		for _, e := range t.Elems {
+			vars = freeVars(e, vars)
+		}
+	}
+	return vars
+}
+
+func containsVar(vars []*types.Var, v *types.Var) bool {
+	for _, w := range vars {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// generalize returns the type variables of t that no other visible name
+// depends on. The symbol being defined, name, is skipped because during
+// checking it was bound to t itself so that it could recurse.
+func (c *Checker) generalize(t types.Type, name string) []*types.Var {
+	var env []*types.Var
+	for st := c.table; st != nil; st = st.outer {
+		for symName, sym := range st.store {
+			if st == c.table && symName == name {
+				continue
+			}
+			for _, v := range freeVars(sym.Type, nil) {
+				if !containsVar(sym.Generic, v) {
+					env = append(env, v)
+				}
+			}
+		}
+	}
+
+	var generic []*types.Var
+	for _, v := range freeVars(t, nil) {
+		if !containsVar(env, v) {
+			generic = append(generic, v)
+		}
+	}
+	return generic
+}
+
+// instantiate returns the type of sym with its generic type variables
+// replaced by fresh ones.
+func (c *Checker) instantiate(sym Symbol) types.Type {
+	if len(sym.Generic) == 0 {
+		return sym.Type
+	}
+	fresh := make(map[*types.Var]types.Type, len(sym.Generic))
+	for _, v := range sym.Generic {
+		fresh[v] = c.newVar()
+	}
+	return substitute(sym.Type, fresh)
+}
+
+func substitute(t types.Type, m map[*types.Var]types.Type) types.Type {
+	switch t := types.Prune(t).(type) {
+	case *types.Var:
+		if r, ok := m[t]; ok {
+			return r
+		}
+		return t
+	case *types.Signature:
+		sig := &types.Signature{Params: make([]types.Type, len(t.Params)), Result: substitute(t.Result, m)}
+		for i, p := range t.Params {
+			sig.Params[i] = substitute(p, m)
+		}
+		return sig
+	case *types.Slice:
+		return &types.Slice{Elem: substitute(t.Elem, m)}
+	case *types.Map:
+		return &types.Map{Key: substitute(t.Key, m), Value: substitute(t.Value, m)}
+	case *types.Pointer:
+		return &types.Pointer{Elem: substitute(t.Elem, m)}
+	case *types.Tuple:
+		tuple := &types.Tuple{Elems: make([]types.Type, len(t.Elems))}
+		for i, e := range t.Elems {
+			tuple.Elems[i] = substitute(e, m)
+		}
+		return tuple
+	default:
+		return t
+	}
+}
+
+// TypeOf returns the type the checker inferred for expr, with all type
+// variables it could determine replaced by their types, or nil if expr was
+// not checked. Type variables that remain belong to values whose type never
+// mattered, such as an unused parameter.
+func (c *Checker) TypeOf(expr ast.Expression) types.Type {
+	t, ok := c.types[expr]
+	if !ok {
+		return nil
+	}
+	return types.Resolve(t)
+}
@@ -0,0 +1,70 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+func (c *Checker) checkMapLiteral(ml *ast.MapLiteral) types.Type {
+	t := c.resolveType(ml.Type)
+	m, ok := t.(*types.Map)
+	if !ok {
+		for i, key := range ml.Keys {
+			c.checkValue(key)
+			c.checkValue(ml.Values[i])
+		}
+		return types.Invalid
+	}
+
+	seen := map[string]bool{}
+	for i, key := range ml.Keys {
+		keyType := c.checkValue(key)
+		if !c.unify(keyType, m.Key) {
+			c.addError(diagnostics.TypeMismatch, key, "cannot use %s (type %s) as %s key in map literal", key.String(), keyType, m.Key)
+		}
+		if isConstantKey(key) {
+			if seen[key.String()] {
+				c.addError(diagnostics.DuplicateKey, key, "duplicate key %s in map literal", key.String())
+			}
+			seen[key.String()] = true
+		}
+
+		value := ml.Values[i]
+		valType := c.checkValue(value)
+		if !c.unify(valType, m.Value) {
+			c.addError(diagnostics.TypeMismatch, value, "cannot use %s (type %s) as %s value in map literal", value.String(), valType, m.Value)
+		}
+	}
+	return m
+}
+
+// isConstantKey reports whether key is a literal, so that a duplicate of it
+// is certainly a mistake.
+func isConstantKey(key ast.Expression) bool {
+	switch key.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// checkMapIndex checks the key of m[k], of type keyType, and returns the
+// type of the value.
+func (c *Checker) checkMapIndex(ie *ast.IndexExpression, m *types.Map, keyType types.Type) types.Type {
+	if !c.unify(keyType, m.Key) {
+		c.addError(diagnostics.TypeMismatch, ie.Index, "cannot use %s (type %s) as %s in map index", ie.Index.String(), keyType, m.Key)
+	}
+	return m.Value
+}
+
+// isMapIndex reports whether expr, which has been checked, is an index
+// into a map.
+func (c *Checker) isMapIndex(expr ast.Expression) bool {
+	ie, ok := expr.(*ast.IndexExpression)
+	if !ok {
+		return false
+	}
+	_, ok = types.Prune(c.types[ie.Left]).(*types.Map)
+	return ok
+}
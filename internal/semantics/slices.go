@@ -0,0 +1,115 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+// sequenceUse is an operand of len or of a slice expression whose type was
+// not known when it was checked. Both strings and slices would do, so the
+// choice waits until the end of the enclosing function, by which time later
+// uses have usually decided it.
+type sequenceUse struct {
+	expr ast.Expression
+	typ  types.Type
+	op   string // "len" or "slice"
+}
+
+// requireSequence checks that expr, of type t, is a string or a slice, or
+// for len also a map.
+func (c *Checker) requireSequence(expr ast.Expression, t types.Type, op string) {
+	switch types.Prune(t).(type) {
+	case *types.Var:
+		c.sequences = append(c.sequences, sequenceUse{expr: expr, typ: t, op: op})
+		return
+	case *types.Slice:
+		return
+	case *types.Map:
+		if op == "len" {
+			return
+		}
+	}
+	if t := types.Prune(t); t == types.String || t == types.Invalid {
+		return
+	}
+	c.sequenceError(sequenceUse{expr: expr, typ: t, op: op})
+}
+
+// resolveSequences settles the operands collected by requireSequence.
+// Those still of unknown type become strings.
+func (c *Checker) resolveSequences() {
+	uses := c.sequences
+	c.sequences = nil
+	for _, use := range uses {
+		if _, ok := types.Prune(use.typ).(*types.Var); ok {
+			c.unify(use.typ, types.String)
+		}
+		c.requireSequence(use.expr, use.typ, use.op)
+	}
+}
+
+func (c *Checker) sequenceError(use sequenceUse) {
+	if use.op == "len" {
+		c.addError(diagnostics.InvalidBuiltinCall, use.expr, "invalid argument: %s (type %s) for len", use.expr.String(), use.typ)
+		return
+	}
+	c.addError(diagnostics.InvalidIndex, use.expr, "cannot slice %s (type %s)", use.expr.String(), use.typ)
+}
+
+func (c *Checker) checkArrayLiteral(al *ast.ArrayLiteral) types.Type {
+	elem := types.Type(c.newVar())
+	for _, el := range al.Elements {
+		t := c.checkValue(el)
+		if !c.unify(t, elem) {
+			c.addError(diagnostics.TypeMismatch, el, "cannot use %s (type %s) as %s in slice literal", el.String(), t, elem)
+		}
+	}
+	return &types.Slice{Elem: elem}
+}
+
+func (c *Checker) checkIndexExpression(ie *ast.IndexExpression) types.Type {
+	leftType := c.checkValue(ie.Left)
+	indexType := c.checkValue(ie.Index)
+	if _, ok := types.Prune(leftType).(*types.Var); ok && types.Comparable(indexType) && types.Prune(indexType) != types.Int {
+		// Only a map can be indexed by anything but an int.
+		c.unify(leftType, &types.Map{Key: indexType, Value: c.newVar()})
+	}
+	if m, ok := types.Prune(leftType).(*types.Map); ok {
+		return c.checkMapIndex(ie, m, indexType)
+	}
+	c.requireIndex(ie.Index, indexType)
+	if leftType == types.Invalid {
+		return types.Invalid
+	}
+	elem := c.newVar()
+	if !c.unify(leftType, &types.Slice{Elem: elem}) {
+		c.addError(diagnostics.InvalidIndex, ie, "cannot index %s (type %s)", ie.Left.String(), leftType)
+		return types.Invalid
+	}
+	return elem
+}
+
+func (c *Checker) checkSliceExpression(se *ast.SliceExpression) types.Type {
+	leftType := c.checkValue(se.Left)
+	if se.Low != nil {
+		c.checkIndex(se.Low)
+	}
+	if se.High != nil {
+		c.checkIndex(se.High)
+	}
+	c.requireSequence(se.Left, leftType, "slice")
+	return leftType
+}
+
+// checkIndex checks an index or slice bound, which must be an int.
+func (c *Checker) checkIndex(index ast.Expression) {
+	c.requireIndex(index, c.checkValue(index))
+}
+
+// requireIndex checks that index, of type t, is an int.
+func (c *Checker) requireIndex(index ast.Expression, t types.Type) {
+	if !c.unify(t, types.Int) {
+		c.addError(diagnostics.InvalidIndex, index, "invalid argument: index %s (type %s) must be integer", index.String(), t)
+	}
+}
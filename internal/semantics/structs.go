@@ -0,0 +1,255 @@
+package semantics
+
+import (
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+// declareTypes declares the struct types and methods of a program before
+// its statements are checked, so that, as at the top level of a Go package,
+// they can be used before the declarations that introduce them.
+func (c *Checker) declareTypes(program *ast.Program) {
+	var decls []*ast.TypeStatement
+	for _, stmt := range program.Statements {
+		ts, ok := stmt.(*ast.TypeStatement)
+		if !ok {
+			continue
+		}
+		name := ts.Name.Value
+		if _, ok := types.Lookup(name); ok || c.structs[name] != nil {
+			c.addError(diagnostics.InvalidDeclaration, ts.Name, "%s redeclared", name)
+			continue
+		}
+		c.structs[name] = types.NewStruct(name)
+		decls = append(decls, ts)
+	}
+
+	// Fields may refer to any of the types, so they are resolved once all
+	// the names are known.
+	for _, ts := range decls {
+		s := c.structs[ts.Name.Value]
+		for _, field := range ts.Type.Fields {
+			t := c.resolveType(field.Type)
+			for _, name := range field.Names {
+				if f, _ := s.Field(name.Value); f != nil {
+					c.addError(diagnostics.InvalidDeclaration, name, "duplicate field %s in struct %s", name.Value, s.Name)
+					continue
+				}
+				s.Fields = append(s.Fields, &types.Field{Name: name.Value, Type: t})
+			}
+		}
+	}
+	for _, ts := range decls {
+		s := c.structs[ts.Name.Value]
+		if contains(s, s, map[*types.Struct]bool{}) {
+			c.addError(diagnostics.InvalidDeclaration, ts.Name, "invalid recursive type %s", s.Name).
+				WithNote("a struct cannot contain itself; use a pointer such as *%s", s.Name)
+			// Cut the cycle so that nothing later loops over it.
+			for _, f := range s.Fields {
+				f.Type = types.Invalid
+			}
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		if ms, ok := stmt.(*ast.MethodStatement); ok {
+			c.declareMethod(ms)
+		}
+	}
+}
+
+// contains reports whether a value of struct type s holds a value of type
+// target, directly or in a field of a field.
+func contains(s, target *types.Struct, seen map[*types.Struct]bool) bool {
+	if seen[s] {
+		return false
+	}
+	seen[s] = true
+	for _, f := range s.Fields {
+		if inner, ok := f.Type.(*types.Struct); ok && (inner == target || contains(inner, target, seen)) {
+			return true
+		}
+	}
+	return false
+}
+
+// declareMethod adds a method to the method set of its receiver's type.
+// The body is checked later, where the declaration appears.
+func (c *Checker) declareMethod(ms *ast.MethodStatement) {
+	_, recvType := ms.Receiver()
+	sig := c.signature(ms.Function)
+	recv := sig.Params[0]
+	if recv == types.Invalid {
+		return
+	}
+	ptr, pointer := recv.(*types.Pointer)
+	if pointer {
+		recv = ptr.Elem
+	}
+	s, ok := recv.(*types.Struct)
+	if !ok {
+		c.addError(diagnostics.InvalidDeclaration, recvType, "invalid receiver type %s", sig.Params[0]).
+			WithNote("methods can only be declared on struct types and pointers to them")
+		return
+	}
+
+	name := ms.Name.Value
+	if _, ok := s.Methods[name]; ok {
+		c.addError(diagnostics.InvalidDeclaration, ms.Name, "method %s.%s already declared", s.Name, name)
+		return
+	}
+	if f, _ := s.Field(name); f != nil {
+		c.addError(diagnostics.InvalidDeclaration, ms.Name, "field and method with the same name %s", name)
+		return
+	}
+	s.Methods[name] = &types.Method{Name: name, Signature: sig, PointerReceiver: pointer}
+}
+
+func (c *Checker) checkStructLiteral(sl *ast.StructLiteral) types.Type {
+	t := c.resolveType(sl.Type)
+	s, ok := t.(*types.Struct)
+	if !ok {
+		if t != types.Invalid {
+			c.addError(diagnostics.TypeMismatch, sl.Type, "invalid composite literal type %s", t)
+		}
+		for _, value := range sl.Values {
+			c.checkValue(value)
+		}
+		return types.Invalid
+	}
+
+	if sl.Fields == nil && len(sl.Values) > 0 && len(sl.Values) != len(s.Fields) {
+		few := "few"
+		if len(sl.Values) > len(s.Fields) {
+			few = "many"
+		}
+		c.addError(diagnostics.TypeMismatch, sl, "too %s values in struct literal of type %s", few, s.Name)
+	}
+
+	seen := map[string]bool{}
+	for i, value := range sl.Values {
+		valType := c.checkValue(value)
+		var field *types.Field
+		if sl.Fields != nil {
+			name := sl.Fields[i]
+			if field, _ = s.Field(name.Value); field == nil {
+				c.addError(diagnostics.UndefinedField, name, "unknown field %s in struct literal of type %s", name.Value, s.Name)
+				continue
+			}
+			if seen[name.Value] {
+				c.addError(diagnostics.DuplicateKey, name, "duplicate field name %s in struct literal", name.Value)
+			}
+			seen[name.Value] = true
+		} else if i < len(s.Fields) {
+			field = s.Fields[i]
+		} else {
+			continue
+		}
+		if !c.unify(valType, field.Type) {
+			c.addError(diagnostics.TypeMismatch, value, "cannot use %s (type %s) as %s value in struct literal",
+				value.String(), valType, field.Type)
+		}
+	}
+	return s
+}
+
+// checkSelector checks x.f, which selects a field of a struct or of the
+// struct a pointer points to. If called is set, the selector may instead
+// name a method of the struct that is being called, and the result is the
+// signature of the method without its receiver.
+func (c *Checker) checkSelector(se *ast.SelectorExpression, called bool) types.Type {
+	xType := c.checkValue(se.X)
+	if xType == types.Invalid {
+		return types.Invalid
+	}
+
+	t := types.Prune(xType)
+	ptr, pointer := t.(*types.Pointer)
+	if pointer {
+		t = types.Prune(ptr.Elem)
+	}
+	if t == types.Error {
+		return c.checkErrorMethod(se, called)
+	}
+	name := se.Sel.Value
+	s, ok := t.(*types.Struct)
+	if !ok {
+		if _, ok := t.(*types.Var); ok {
+			c.addError(diagnostics.UndefinedField, se, "cannot select %s from %s of unknown type", name, se.X.String()).
+				WithNote("annotate the type of %s", se.X.String())
+			return types.Invalid
+		}
+		c.addError(diagnostics.UndefinedField, se, "%s undefined (type %s has no field or method %s)", se.String(), xType, name)
+		return types.Invalid
+	}
+
+	if f, _ := s.Field(name); f != nil {
+		return f.Type
+	}
+	m, ok := s.Methods[name]
+	if !ok {
+		c.addError(diagnostics.UndefinedField, se, "%s undefined (type %s has no field or method %s)", se.String(), xType, name)
+		return types.Invalid
+	}
+	if !called {
+		c.addError(diagnostics.TypeMismatch, se, "method %s cannot be used as a value", se.String()).
+			WithNote("call it instead, or wrap it in a function literal")
+		return types.Invalid
+	}
+	if m.PointerReceiver && !pointer && !c.addressable(se.X) {
+		c.addError(diagnostics.NotAddressable, se, "cannot call pointer method %s on %s", name, xType).
+			WithNote("%s is not addressable; store it in a variable first", se.X.String())
+	}
+	return &types.Signature{Params: m.Signature.Params[1:], Result: m.Signature.Result}
+}
+
+// checkAddressOf checks &x, which points to a struct variable, to a field or
+// element holding a struct, or to a new struct given by a literal.
+func (c *Checker) checkAddressOf(node *ast.PrefixExpression, t types.Type) types.Type {
+	if _, ok := node.Right.(*ast.StructLiteral); !ok && !c.addressable(node.Right) {
+		c.addError(diagnostics.NotAddressable, node, "cannot take the address of %s", node.Right.String())
+		return types.Invalid
+	}
+	if _, ok := types.Prune(t).(*types.Struct); !ok {
+		c.addError(diagnostics.UnknownOperator, node, "cannot take the address of %s (type %s)", node.Right.String(), t).
+			WithNote("only structs can be pointed to")
+		return types.Invalid
+	}
+	return &types.Pointer{Elem: t}
+}
+
+// addressable reports whether expr denotes a variable, a slice element, or
+// a field of either or of a struct that a pointer points to. Map elements
+// and the results of calls are not addressable, as in Go.
+func (c *Checker) addressable(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.SelectorExpression:
+		if _, ok := types.Prune(c.types[e.X]).(*types.Pointer); ok {
+			return true
+		}
+		return c.addressable(e.X)
+	case *ast.IndexExpression:
+		_, ok := types.Prune(c.types[e.Left]).(*types.Slice)
+		return ok
+	}
+	return false
+}
+
+// checkFieldAssignTarget checks a field used as an assignment target, which
+// must be addressable.
+func (c *Checker) checkFieldAssignTarget(target *ast.SelectorExpression) types.Type {
+	t := c.Check(target)
+	if t == types.Invalid || c.addressable(target) {
+		return t
+	}
+	if c.isMapIndex(target.X) {
+		c.addError(diagnostics.InvalidAssignTarget, target, "cannot assign to struct field %s in map", target.String()).
+			WithNote("map elements are not addressable; assign a whole new value to %s", target.X.String())
+	} else {
+		c.addError(diagnostics.InvalidAssignTarget, target, "cannot assign to %s", target.String())
+	}
+	return types.Invalid
+}
@@ -1,11 +1,12 @@
 package semantics
 
-import "golite.dev/mvp/internal/object"
+import "golite.dev/mvp/internal/types"
 
 type Symbol struct {
-	Name  string
-	Type  object.ObjectType
-	Scope string // "global", "local", etc.
+	Name    string
+	Type    types.Type
+	Generic []*types.Var // type variables instantiated afresh at each use
+	Scope   string       // "global", "local", etc.
 }
 
 type SymbolTable struct {
@@ -24,14 +25,23 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 	return s
 }
 
-func (st *SymbolTable) Define(name string, ty object.ObjectType) Symbol {
-	symbol := Symbol{Name: name, Type: ty}
+func (st *SymbolTable) Define(name string, ty types.Type) Symbol {
+	return st.DefineGeneric(name, ty, nil)
+}
+
+// DefineGeneric defines a name whose type is polymorphic in the type
+// variables listed in generic. The blank identifier _ is never defined, so
+// it can be declared any number of times and never used.
+func (st *SymbolTable) DefineGeneric(name string, ty types.Type, generic []*types.Var) Symbol {
+	symbol := Symbol{Name: name, Type: ty, Generic: generic}
 	if st.outer == nil {
 		symbol.Scope = "global"
 	} else {
 		symbol.Scope = "local"
 	}
-	st.store[name] = symbol
+	if name != "_" {
+		st.store[name] = symbol
+	}
 	return symbol
 }
 
@@ -0,0 +1,999 @@
+package semantics
+
+import (
+	"sort"
+	"strings"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+)
+
+// funcNode is a let-bound function literal in the call graph.
+type funcNode struct {
+	name  *ast.Identifier
+	lit   *ast.FunctionLiteral
+	calls []*ast.CallExpression // calls in lit to let-bound functions
+	flow  *flow
+}
+
+// termination looks for loops and recursion that may never end.
+type termination struct {
+	funcs    []*funcNode
+	callee   map[*ast.CallExpression]*funcNode
+	caller   map[*ast.CallExpression]*funcNode
+	scopes   []map[string]*funcNode // nil entries are names that shadow a function
+	current  *funcNode
+	warnings []*diagnostics.Diagnostic
+}
+
+// CheckTermination analyzes a program that type checks and returns warnings
+// for loops that never end and for recursion that it cannot prove ends.
+//
+// Recursion is found in the call graph of let-bound function literals. A
+// cycle of calls terminates if each function in it has a parameter, or the
+// length of a slice parameter, that every call in the cycle passes on
+// unchanged or moved strictly in one direction, with no cycle of unchanged
+// values, and every cycle passes a function whose base case compares that
+// parameter against a bound in the same direction. Parameters moved by a
+// constant before a call, as in n--, and names let-bound to expressions of
+// them, as in let k = n - 1, are followed to the calls. Cycles that cannot
+// even reach a base case are reported as infinite recursion; other cycles
+// are only reported if the analysis understands every argument they pass,
+// so that the lack of a proof means something.
+func CheckTermination(program *ast.Program) []*diagnostics.Diagnostic {
+	t := &termination{
+		callee: map[*ast.CallExpression]*funcNode{},
+		caller: map[*ast.CallExpression]*funcNode{},
+	}
+	t.push()
+	for _, stmt := range program.Statements {
+		t.walk(stmt)
+	}
+	for _, scc := range t.recursiveComponents() {
+		t.checkCycle(scc)
+	}
+	sort.SliceStable(t.warnings, func(i, j int) bool {
+		return t.warnings[i].Span.Start.Offset < t.warnings[j].Span.Start.Offset
+	})
+	return t.warnings
+}
+
+func (t *termination) push() { t.scopes = append(t.scopes, map[string]*funcNode{}) }
+func (t *termination) pop()  { t.scopes = t.scopes[:len(t.scopes)-1] }
+
+func (t *termination) bind(name string, fn *funcNode) {
+	t.scopes[len(t.scopes)-1][name] = fn
+}
+
+func (t *termination) resolve(name string) *funcNode {
+	for i := len(t.scopes) - 1; i >= 0; i-- {
+		if fn, ok := t.scopes[i][name]; ok {
+			return fn
+		}
+	}
+	return nil
+}
+
+// walk builds the call graph, resolving names the way the checker does: a
+// function can call itself and the functions declared before it.
+func (t *termination) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.LetStatement:
+			if lit, ok := n.Value.(*ast.FunctionLiteral); ok {
+				fn := &funcNode{name: n.Name, lit: lit, flow: analyzeFlow(lit)}
+				t.funcs = append(t.funcs, fn)
+				t.bind(n.Name.Value, fn)
+				outer := t.current
+				t.current = fn
+				t.walk(lit)
+				t.current = outer
+				return false
+			}
+			if n.Value != nil {
+				t.walk(n.Value)
+			}
+			for _, name := range n.Names() {
+				t.bind(name.Value, nil)
+			}
+			return false
+		case *ast.MethodStatement:
+			outer := t.current
+			t.current = nil
+			t.walk(n.Function)
+			t.current = outer
+			return false
+		case *ast.FunctionLiteral:
+			t.push()
+			for _, p := range n.Parameters {
+				t.bind(p.Value, nil)
+			}
+			t.walk(n.Body)
+			t.pop()
+			return false
+		case *ast.BlockStatement:
+			t.push()
+			for _, stmt := range n.Statements {
+				t.walk(stmt)
+			}
+			t.pop()
+			return false
+		case *ast.ForStatement:
+			t.checkLoop(n)
+			t.push()
+			for _, part := range []ast.Node{n.Init, n.Condition, n.Post} {
+				if part != nil {
+					t.walk(part)
+				}
+			}
+			t.walk(n.Body)
+			t.pop()
+			return false
+		case *ast.CallExpression:
+			id, ok := n.Function.(*ast.Identifier)
+			if !ok || t.current == nil {
+				break
+			}
+			if fn := t.resolve(id.Value); fn != nil {
+				t.current.calls = append(t.current.calls, n)
+				t.callee[n] = fn
+				t.caller[n] = t.current
+			}
+		}
+		return true
+	})
+}
+
+// recursiveComponents returns the strongly connected components of the call
+// graph that contain a cycle, in source order.
+func (t *termination) recursiveComponents() [][]*funcNode {
+	index := map[*funcNode]int{}
+	low := map[*funcNode]int{}
+	onStack := map[*funcNode]bool{}
+	var stack []*funcNode
+	var components [][]*funcNode
+
+	var connect func(fn *funcNode)
+	connect = func(fn *funcNode) {
+		index[fn] = len(index) + 1
+		low[fn] = index[fn]
+		stack = append(stack, fn)
+		onStack[fn] = true
+		for _, call := range fn.calls {
+			next := t.callee[call]
+			if index[next] == 0 {
+				connect(next)
+				low[fn] = min(low[fn], low[next])
+			} else if onStack[next] {
+				low[fn] = min(low[fn], index[next])
+			}
+		}
+		if low[fn] != index[fn] {
+			return
+		}
+		var scc []*funcNode
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == fn {
+				break
+			}
+		}
+		components = append(components, scc)
+	}
+	for _, fn := range t.funcs {
+		if index[fn] == 0 {
+			connect(fn)
+		}
+	}
+
+	order := map[*funcNode]int{}
+	for i, fn := range t.funcs {
+		order[fn] = i
+	}
+	var recursive [][]*funcNode
+	for _, scc := range components {
+		sort.Slice(scc, func(i, j int) bool { return order[scc[i]] < order[scc[j]] })
+		if len(scc) > 1 || t.callsItself(scc[0]) {
+			recursive = append(recursive, scc)
+		}
+	}
+	sort.Slice(recursive, func(i, j int) bool { return order[recursive[i][0]] < order[recursive[j][0]] })
+	return recursive
+}
+
+func (t *termination) callsItself(fn *funcNode) bool {
+	for _, call := range fn.calls {
+		if t.callee[call] == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCycle warns about a recursive component unless it terminates.
+func (t *termination) checkCycle(scc []*funcNode) {
+	members := map[*funcNode]bool{}
+	for _, fn := range scc {
+		members[fn] = true
+	}
+
+	if cycle := t.findCycle(scc, func(fn *funcNode) []*ast.CallExpression {
+		calls, _ := t.mustCalls(fn.lit.Body.Statements)
+		return calls
+	}); cycle != nil {
+		first := t.caller[cycle[0]]
+		t.warn(diagnostics.InfiniteRecursion, first, cycle, "infinite recursion in %s", first.name.Value).
+			WithNote("every call follows the cycle %s without reaching a base case", t.describe(cycle))
+		return
+	}
+
+	if t.terminates(scc, members) || t.opaque(scc, members) {
+		return
+	}
+	cycle := t.findCycle(scc, func(fn *funcNode) []*ast.CallExpression { return fn.calls })
+	first := t.caller[cycle[0]]
+	t.warn(diagnostics.UnprovenTermination, first, cycle, "recursion in %s may not terminate", first.name.Value).
+		WithNote("no parameter moves toward a guarded base case on every call in the cycle %s", t.describe(cycle))
+}
+
+func (t *termination) warn(code diagnostics.Code, fn *funcNode, cycle []*ast.CallExpression, format string, args ...interface{}) *diagnostics.Diagnostic {
+	d := diagnostics.Warningf(code, diagnostics.SpanOf(fn.name), format, args...)
+	for _, call := range cycle {
+		d.WithLabel(diagnostics.SpanOf(call), "calls %s", t.callee[call].name.Value)
+	}
+	t.warnings = append(t.warnings, d)
+	return d
+}
+
+// describe formats a cycle of calls as f -> g -> f.
+func (t *termination) describe(cycle []*ast.CallExpression) string {
+	names := []string{t.caller[cycle[0]].name.Value}
+	for _, call := range cycle {
+		names = append(names, t.callee[call].name.Value)
+	}
+	return strings.Join(names, " -> ")
+}
+
+// findCycle returns the calls along a cycle among the functions of scc,
+// following the calls that edges returns for each function, or nil if there
+// is none.
+func (t *termination) findCycle(scc []*funcNode, edges func(*funcNode) []*ast.CallExpression) []*ast.CallExpression {
+	members := map[*funcNode]bool{}
+	for _, fn := range scc {
+		members[fn] = true
+	}
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := map[*funcNode]int{}
+	var path []*funcNode          // functions on the current search path
+	var via []*ast.CallExpression // via[i] calls path[i+1] from path[i]
+	var cycle []*ast.CallExpression
+
+	var visit func(fn *funcNode) bool
+	visit = func(fn *funcNode) bool {
+		state[fn] = active
+		path = append(path, fn)
+		for _, call := range edges(fn) {
+			next := t.callee[call]
+			if !members[next] {
+				continue
+			}
+			switch state[next] {
+			case active:
+				for i, p := range path {
+					if p == next {
+						cycle = append(append([]*ast.CallExpression{}, via[i:]...), call)
+						return true
+					}
+				}
+			case unvisited:
+				via = append(via, call)
+				if visit(next) {
+					return true
+				}
+				via = via[:len(via)-1]
+			}
+		}
+		path = path[:len(path)-1]
+		state[fn] = done
+		return false
+	}
+	for _, fn := range scc {
+		if state[fn] == unvisited && visit(fn) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// mustCalls returns the calls to let-bound functions made on every path
+// through stmts, one per function called, and whether some path returns
+// from the enclosing function. Calls after a statement that may return are
+// not certain to happen, so they are left out.
+func (t *termination) mustCalls(stmts []ast.Statement) ([]*ast.CallExpression, bool) {
+	var calls []*ast.CallExpression
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ReturnStatement:
+			return append(calls, t.evaluatedCalls(s.ReturnValue)...), true
+		case *ast.ExpressionStatement:
+			if isPanic(s.Expression) {
+				return append(calls, t.evaluatedCalls(s.Expression)...), true
+			}
+			ie, ok := s.Expression.(*ast.IfExpression)
+			if !ok {
+				calls = append(calls, t.evaluatedCalls(s.Expression)...)
+				continue
+			}
+			calls = append(calls, t.evaluatedCalls(ie.Condition)...)
+			consequence, returns := t.mustCalls(ie.Consequence.Statements)
+			var alternative []*ast.CallExpression
+			if ie.Alternative != nil {
+				var altReturns bool
+				alternative, altReturns = t.mustCalls(ie.Alternative.Statements)
+				returns = returns || altReturns
+			}
+			calls = append(calls, t.common(consequence, alternative)...)
+			if returns {
+				return calls, true
+			}
+		case *ast.ForStatement:
+			for _, part := range []ast.Node{s.Init, s.Condition} {
+				if part != nil {
+					calls = append(calls, t.evaluatedCalls(part)...)
+				}
+			}
+			if containsReturn(s.Body) {
+				return calls, true
+			}
+		default:
+			calls = append(calls, t.evaluatedCalls(stmt)...)
+		}
+	}
+	return calls, false
+}
+
+// common returns the calls in a to functions that b calls too.
+func (t *termination) common(a, b []*ast.CallExpression) []*ast.CallExpression {
+	var calls []*ast.CallExpression
+	for _, x := range a {
+		for _, y := range b {
+			if t.callee[x] == t.callee[y] {
+				calls = append(calls, x)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// evaluatedCalls returns the calls to let-bound functions that evaluating
+// node always makes, leaving out function literals, the branches of if
+// expressions and the right operands of && and ||.
+func (t *termination) evaluatedCalls(node ast.Node) []*ast.CallExpression {
+	var calls []*ast.CallExpression
+	if node == nil {
+		return nil
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.IfExpression:
+			calls = append(calls, t.evaluatedCalls(n.Condition)...)
+			return false
+		case *ast.InfixExpression:
+			if n.Operator == "&&" || n.Operator == "||" {
+				calls = append(calls, t.evaluatedCalls(n.Left)...)
+				return false
+			}
+		case *ast.CallExpression:
+			if t.callee[n] != nil {
+				calls = append(calls, n)
+			}
+		}
+		return true
+	})
+	return calls
+}
+
+// containsReturn reports whether block has a return statement, or a call
+// to panic, outside nested function literals.
+func containsReturn(block *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(block, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ReturnStatement:
+			found = true
+		case *ast.CallExpression:
+			found = found || isPanic(n)
+		}
+		return !found
+	})
+	return found
+}
+
+// measure is a quantity of a function's arguments that may bound its
+// recursion: the value of an integer parameter or the length of a slice.
+type measure struct {
+	param  int
+	length bool
+}
+
+// terminates reports whether the recursion in scc provably ends, trying
+// every choice of measure for each function in turn.
+func (t *termination) terminates(scc []*funcNode, members map[*funcNode]bool) bool {
+	candidates := make([][]measure, len(scc))
+	combinations := 1
+	for i, fn := range scc {
+		for _, p := range fn.flow.measures {
+			candidates[i] = append(candidates[i], measure{p, false}, measure{p, true})
+		}
+		combinations *= len(candidates[i])
+		if combinations == 0 || combinations > 4096 {
+			return false
+		}
+	}
+
+	choice := map[*funcNode]measure{}
+	var try func(i int) bool
+	try = func(i int) bool {
+		if i == len(scc) {
+			return t.decreases(scc, members, choice, -1) || t.decreases(scc, members, choice, 1)
+		}
+		for _, m := range candidates[i] {
+			choice[scc[i]] = m
+			if try(i + 1) {
+				return true
+			}
+		}
+		return false
+	}
+	return try(0)
+}
+
+// decreases reports whether the measures in choice prove that the recursion
+// in scc ends, moving in direction dir: -1 toward a lower bound, 1 toward an
+// upper one.
+func (t *termination) decreases(scc []*funcNode, members map[*funcNode]bool, choice map[*funcNode]measure, dir int) bool {
+	unitSteps := true
+	unchanged := map[*ast.CallExpression]bool{}
+	for _, fn := range scc {
+		from := choice[fn]
+		param := fn.lit.Parameters[from.param].Value
+		for _, call := range fn.calls {
+			if !members[t.callee[call]] {
+				continue
+			}
+			to := choice[t.callee[call]]
+			if to.param >= len(call.Arguments) || to.length != from.length {
+				return false
+			}
+			step, ok := fn.flow.argStep(call, call.Arguments[to.param], param, from.length)
+			if !ok || step*dir < 0 {
+				return false
+			}
+			if step == 0 {
+				unchanged[call] = true
+			} else if step != dir {
+				unitSteps = false
+			}
+		}
+	}
+	// A value passed on unchanged all the way round a cycle never gets
+	// closer to the bound.
+	if t.findCycle(scc, func(fn *funcNode) []*ast.CallExpression {
+		var calls []*ast.CallExpression
+		for _, call := range fn.calls {
+			if unchanged[call] {
+				calls = append(calls, call)
+			}
+		}
+		return calls
+	}) != nil {
+		return false
+	}
+
+	// Every cycle must pass a base case. An equality test only stops values
+	// that cannot step over it.
+	var unguarded []*funcNode
+	for _, fn := range scc {
+		bound, equal := t.baseCase(fn, choice[fn], dir, members)
+		if !bound && !(equal && unitSteps) {
+			unguarded = append(unguarded, fn)
+		}
+	}
+	return t.findCycle(unguarded, func(fn *funcNode) []*ast.CallExpression { return fn.calls }) == nil
+}
+
+// opaque reports whether a call in scc passes an argument that cannot be
+// related to any measure of its caller, such as (lo + hi) / 2. The measure
+// that bounds the recursion may hide in it, so failing to prove that the
+// recursion ends says little.
+func (t *termination) opaque(scc []*funcNode, members map[*funcNode]bool) bool {
+	for _, fn := range scc {
+		for _, call := range fn.calls {
+			if !members[t.callee[call]] {
+				continue
+			}
+			for _, arg := range call.Arguments {
+				if !fn.flow.understood(fn.lit, call, arg) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// flow is what the top-level statements of a function body show about the
+// arguments of its calls: how far parameters moved by a constant, as in
+// n-- or n -= 2, had moved at each call, and which names are let-bound to
+// expressions of the parameters, as in let k = n - 1.
+type flow struct {
+	measures []int                                    // the parameters that may bound recursion
+	moved    map[*ast.CallExpression]map[string]int64 // by call, how far each parameter had moved
+	aliases  map[string]alias
+}
+
+// alias is a name let-bound to value, which was evaluated once the
+// parameters had moved by moved.
+type alias struct {
+	value ast.Expression
+	moved map[string]int64
+}
+
+func analyzeFlow(lit *ast.FunctionLiteral) *flow {
+	f := &flow{moved: map[*ast.CallExpression]map[string]int64{}, aliases: map[string]alias{}}
+	params := map[string]bool{}
+	for _, p := range lit.Parameters {
+		params[p.Value] = true
+	}
+	moved := map[string]int64{}
+	moves := map[ast.Statement]bool{}
+	for _, stmt := range lit.Body.Statements {
+		if name, k, ok := move(stmt); ok && params[name] {
+			// Copy, since the calls before share the map.
+			next := map[string]int64{name: moved[name] + k}
+			for p, m := range moved {
+				if p != name {
+					next[p] = m
+				}
+			}
+			moved = next
+			moves[stmt] = true
+			continue
+		}
+		if ls, ok := stmt.(*ast.LetStatement); ok && len(ls.Rest) == 0 && ls.Value != nil {
+			f.aliases[ls.Name.Value] = alias{ls.Value, moved}
+		}
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpression); ok {
+				f.moved[call] = moved
+			}
+			return true
+		})
+	}
+
+	writes := assignedNames(lit.Body, moves)
+	for i, p := range lit.Parameters {
+		if writes[p.Value] == 0 {
+			f.measures = append(f.measures, i)
+		}
+	}
+	// A name bound again or assigned to is no alias.
+	for name := range f.aliases {
+		if writes[name] != 1 {
+			delete(f.aliases, name)
+		}
+	}
+	return f
+}
+
+// argStep returns how arg, an argument of call, changes the measure of param
+// from its value on entry, as measureStep does for arguments that use it
+// unmoved.
+func (f *flow) argStep(call *ast.CallExpression, arg ast.Expression, param string, length bool) (int, bool) {
+	moved := f.moved[call]
+	if id, ok := arg.(*ast.Identifier); ok {
+		if a, ok := f.aliases[id.Value]; ok {
+			arg, moved = a.value, a.moved
+		}
+	}
+	if moved[param] == 0 {
+		return measureStep(arg, param, length)
+	}
+	k, ok := offset(arg, param)
+	if !ok || length {
+		return 0, false
+	}
+	switch d := moved[param] + k; {
+	case d < 0:
+		return -step(-d), true
+	case d > 0:
+		return step(d), true
+	}
+	return 0, true
+}
+
+// understood reports whether argStep can relate arg, an argument of call in
+// lit, to some measure of lit.
+func (f *flow) understood(lit *ast.FunctionLiteral, call *ast.CallExpression, arg ast.Expression) bool {
+	for _, p := range f.measures {
+		for _, length := range []bool{false, true} {
+			if _, ok := f.argStep(call, arg, lit.Parameters[p].Value, length); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// move reports whether stmt moves a name by a constant, as n--, n -= 2
+// and n = n + 1 do, and which name by how much.
+func move(stmt ast.Statement) (string, int64, bool) {
+	switch s := stmt.(type) {
+	case *ast.IncDecStatement:
+		if id, ok := s.Target.(*ast.Identifier); ok {
+			if s.Operator == "++" {
+				return id.Value, 1, true
+			}
+			return id.Value, -1, true
+		}
+	case *ast.AssignStatement:
+		id, ok := s.Target.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		lit, isLit := s.Value.(*ast.IntegerLiteral)
+		switch s.Operator {
+		case "=":
+			if k, ok := offset(s.Value, id.Value); ok {
+				return id.Value, k, true
+			}
+		case "+=":
+			if isLit {
+				return id.Value, lit.Value, true
+			}
+		case "-=":
+			if isLit {
+				return id.Value, -lit.Value, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// offset reports whether expr is name plus a constant, as n, n + 1, 1 + n
+// and n - 1 are, and what constant.
+func offset(expr ast.Expression, name string) (int64, bool) {
+	if isName(expr, name) {
+		return 0, true
+	}
+	in, ok := expr.(*ast.InfixExpression)
+	if !ok {
+		return 0, false
+	}
+	left, leftOK := in.Left.(*ast.IntegerLiteral)
+	right, rightOK := in.Right.(*ast.IntegerLiteral)
+	switch {
+	case in.Operator == "+" && isName(in.Left, name) && rightOK:
+		return right.Value, true
+	case in.Operator == "+" && isName(in.Right, name) && leftOK:
+		return left.Value, true
+	case in.Operator == "-" && isName(in.Left, name) && rightOK:
+		return -right.Value, true
+	}
+	return 0, false
+}
+
+// assignedNames counts the times body assigns to or declares each name,
+// leaving out the statements in skip. Names written to are useless as
+// measures.
+func assignedNames(body *ast.BlockStatement, skip map[ast.Statement]bool) map[string]int {
+	names := map[string]int{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if stmt, ok := n.(ast.Statement); ok && skip[stmt] {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.AssignStatement:
+			targets := []ast.Expression{n.Target}
+			if te, ok := n.Target.(*ast.TupleExpression); ok {
+				targets = te.Elements
+			}
+			for _, target := range targets {
+				if id, ok := target.(*ast.Identifier); ok {
+					names[id.Value]++
+				}
+			}
+		case *ast.IncDecStatement:
+			if id, ok := n.Target.(*ast.Identifier); ok {
+				names[id.Value]++
+			}
+		case *ast.LetStatement:
+			for _, name := range n.Names() {
+				names[name.Value]++
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// measureStep returns how an argument changes the measure of param: 0 if
+// it passes the measure on unchanged, -1 or 1 if it moves it by exactly one
+// step, or -2 or 2 if it moves it further, such as by halving it.
+func measureStep(arg ast.Expression, param string, length bool) (int, bool) {
+	if isName(arg, param) {
+		return 0, true
+	}
+	if length {
+		// s[k:] and s[:len(s)-k] shorten s by k.
+		se, ok := arg.(*ast.SliceExpression)
+		if !ok || !isName(se.Left, param) {
+			return 0, false
+		}
+		if k, ok := positiveLiteral(se.Low); ok && se.High == nil {
+			return -step(k), true
+		}
+		if in, ok := se.High.(*ast.InfixExpression); ok && se.Low == nil && in.Operator == "-" && isLen(in.Left, param) {
+			if k, ok := positiveLiteral(in.Right); ok {
+				return -step(k), true
+			}
+		}
+		return 0, false
+	}
+
+	in, ok := arg.(*ast.InfixExpression)
+	if !ok {
+		return 0, false
+	}
+	if in.Operator == "+" && isName(in.Right, param) {
+		if k, ok := positiveLiteral(in.Left); ok {
+			return step(k), true
+		}
+	}
+	if in.Operator == "%" && isName(in.Right, param) {
+		// The remainder is smaller than the divisor in magnitude, so
+		// repeated remainders reach zero exactly.
+		return -1, true
+	}
+	k, ok := positiveLiteral(in.Right)
+	if !ok || !isName(in.Left, param) {
+		return 0, false
+	}
+	switch in.Operator {
+	case "-":
+		return -step(k), true
+	case "+":
+		return step(k), true
+	case "/":
+		// Repeated truncating division reaches zero exactly.
+		if k > 1 {
+			return -1, true
+		}
+	case ">>":
+		return -2, true
+	}
+	return 0, false
+}
+
+func step(k int64) int {
+	if k == 1 {
+		return 1
+	}
+	return 2
+}
+
+func positiveLiteral(expr ast.Expression) (int64, bool) {
+	lit, ok := expr.(*ast.IntegerLiteral)
+	if !ok || lit.Value <= 0 {
+		return 0, false
+	}
+	return lit.Value, true
+}
+
+func isName(expr ast.Expression, name string) bool {
+	id, ok := expr.(*ast.Identifier)
+	return ok && id.Value == name
+}
+
+// isLen reports whether expr is len(name).
+func isLen(expr ast.Expression, name string) bool {
+	call, ok := expr.(*ast.CallExpression)
+	return ok && isName(call.Function, "len") && len(call.Arguments) == 1 && isName(call.Arguments[0], name)
+}
+
+// baseCase looks for an if in fn that guards a base case, a branch that
+// leaves fn without recursing, with a comparison of the measure m. The base
+// case is the consequence, the alternative or, when the consequence leaves
+// fn, the code after the if; the last two run when the condition is false,
+// so its comparisons count negated. baseCase reports whether one compares
+// m against a bound in direction dir and whether one tests m for equality.
+func (t *termination) baseCase(fn *funcNode, m measure, dir int, members map[*funcNode]bool) (bound, equal bool) {
+	param := fn.lit.Parameters[m.param].Value
+	isMeasure := func(expr ast.Expression) bool {
+		if m.length {
+			return isLen(expr, param)
+		}
+		return isName(expr, param)
+	}
+	guard := func(cond ast.Expression, negated bool) {
+		b, e := guards(cond, isMeasure, param, dir, negated)
+		bound, equal = bound || b, equal || e
+	}
+
+	var visit func(stmts []ast.Statement, tail bool)
+	visit = func(stmts []ast.Statement, tail bool) {
+		for i, stmt := range stmts {
+			if fs, ok := stmt.(*ast.ForStatement); ok {
+				// The loop may run again, so nothing in it is the last thing
+				// the function does.
+				visit(fs.Body.Statements, false)
+				continue
+			}
+			es, ok := stmt.(*ast.ExpressionStatement)
+			if !ok {
+				continue
+			}
+			ie, ok := es.Expression.(*ast.IfExpression)
+			if !ok {
+				continue
+			}
+			last := tail && i == len(stmts)-1
+			if t.isBase(ie.Consequence, last, members) {
+				guard(ie.Condition, false)
+			}
+			switch {
+			case ie.Alternative != nil:
+				if t.isBase(ie.Alternative, last, members) {
+					guard(ie.Condition, true)
+				}
+			case last:
+				// Skipping the consequence ends the function.
+				guard(ie.Condition, true)
+			case leaves(ie.Consequence) && t.isBase(&ast.BlockStatement{Statements: stmts[i+1:]}, tail, members):
+				guard(ie.Condition, true)
+			}
+			visit(ie.Consequence.Statements, last)
+			if ie.Alternative != nil {
+				visit(ie.Alternative.Statements, last)
+			}
+		}
+	}
+	visit(fn.lit.Body.Statements, true)
+	return bound, equal
+}
+
+// isBase reports whether block leaves the function without calling any
+// function in members: it returns, panics, or it is the last thing the
+// function does.
+func (t *termination) isBase(block *ast.BlockStatement, tail bool, members map[*funcNode]bool) bool {
+	recurses := false
+	ast.Inspect(block, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpression); ok && members[t.callee[call]] {
+			recurses = true
+		}
+		return !recurses
+	})
+	if recurses {
+		return false
+	}
+	return tail || leaves(block)
+}
+
+// leaves reports whether block ends with a return or a call to panic.
+func leaves(block *ast.BlockStatement) bool {
+	n := len(block.Statements)
+	if n == 0 {
+		return false
+	}
+	switch s := block.Statements[n-1].(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		return isPanic(s.Expression)
+	}
+	return false
+}
+
+// guards reports whether cond, or its negation if negated is set, compares
+// the measure against a bound in direction dir, such as n < 2 for a measure
+// that decreases, and whether it tests the measure for equality, as in
+// n == 0.
+func guards(cond ast.Expression, isMeasure func(ast.Expression) bool, param string, dir int, negated bool) (bound, equal bool) {
+	flipped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "==": "==", "!=": "!="}
+	negations := map[string]string{"<": ">=", "<=": ">", ">": "<=", ">=": "<", "==": "!=", "!=": "=="}
+	ast.Inspect(cond, func(n ast.Node) bool {
+		in, ok := n.(*ast.InfixExpression)
+		if !ok {
+			return true
+		}
+		op, ok := flipped[in.Operator]
+		if !ok {
+			return true
+		}
+		switch {
+		case isMeasure(in.Left) && !mentions(in.Right, param):
+			op = in.Operator
+		case isMeasure(in.Right) && !mentions(in.Left, param):
+		default:
+			return true
+		}
+		if negated {
+			op = negations[op]
+		}
+		switch op {
+		case "==", "!=":
+			equal = true
+		case "<", "<=":
+			bound = bound || dir < 0
+		case ">", ">=":
+			bound = bound || dir > 0
+		}
+		return true
+	})
+	return bound, equal
+}
+
+func mentions(expr ast.Expression, name string) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok && id.Value == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// checkLoop warns about a for loop that has no condition and no way out.
+func (t *termination) checkLoop(fs *ast.ForStatement) {
+	if fs.Condition != nil {
+		if b, ok := fs.Condition.(*ast.Boolean); !ok || !b.Value {
+			return
+		}
+	}
+	if canLeave(fs.Body) {
+		return
+	}
+	t.warnings = append(t.warnings, diagnostics.Warningf(diagnostics.InfiniteLoop, diagnostics.SpanOfToken(fs.Token), "for loop never terminates").
+		WithNote("the loop has no condition and its body has no break or return"))
+}
+
+// canLeave reports whether a loop body has a break out of the loop, a
+// return or a call to panic.
+func canLeave(body *ast.BlockStatement) bool {
+	found := false
+	var visit func(node ast.Node, nested bool)
+	visit = func(node ast.Node, nested bool) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FunctionLiteral:
+				return false
+			case *ast.ReturnStatement:
+				found = true
+			case *ast.BreakStatement:
+				found = found || !nested
+			case *ast.CallExpression:
+				found = found || isPanic(n)
+			case *ast.ForStatement:
+				// A break in an inner loop only leaves that loop.
+				visit(n.Body, true)
+				return false
+			}
+			return !found
+		})
+	}
+	visit(body, false)
+	return found
+}
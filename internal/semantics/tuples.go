@@ -0,0 +1,185 @@
+package semantics
+
+import (
+	"fmt"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/types"
+)
+
+func isTuple(expr ast.Expression) bool {
+	_, ok := expr.(*ast.TupleExpression)
+	return ok
+}
+
+// checkTupleExpression checks a list of values, such as those of return
+// q, r, each of which must be a single value.
+func (c *Checker) checkTupleExpression(te *ast.TupleExpression) types.Type {
+	tuple := &types.Tuple{}
+	for _, el := range te.Elements {
+		t := c.checkValue(el)
+		if t == types.Invalid {
+			return types.Invalid
+		}
+		tuple.Elems = append(tuple.Elems, t)
+	}
+	return tuple
+}
+
+// checkAssignedValue checks the value assigned to a single variable or
+// target, reporting a list of values or a call that returns several as a
+// mismatch in their number.
+func (c *Checker) checkAssignedValue(value ast.Expression, node ast.Node) types.Type {
+	t := c.checkValues(value)
+	if _, ok := types.Prune(t).(*types.Tuple); ok {
+		return c.splitValues(value, t, 1, node)[0]
+	}
+	return t
+}
+
+// checkLetValues checks a let statement that declares a variable for each
+// value of a function call or list of values, as in q, r := divmod(a, b),
+// or v and ok from a map lookup, as in v, ok := m[k].
+func (c *Checker) checkLetValues(stmt *ast.LetStatement) types.Type {
+	names := stmt.Names()
+	valType := c.checkValues(stmt.Value)
+	elems := c.splitValues(stmt.Value, valType, len(names), stmt)
+	for i, name := range names {
+		c.table.Define(name.Value, elems[i])
+	}
+	return types.Void
+}
+
+// checkAssignValues checks an assignment to several targets, as in
+// a, b = b, a. Every value is computed before any target is assigned.
+func (c *Checker) checkAssignValues(as *ast.AssignStatement) types.Type {
+	targets := []ast.Expression{as.Target}
+	if te, ok := as.Target.(*ast.TupleExpression); ok {
+		targets = te.Elements
+	}
+	targetTypes := make([]types.Type, len(targets))
+	for i, target := range targets {
+		targetTypes[i] = c.checkAssignTarget(target)
+	}
+	valType := c.checkValues(as.Value)
+	elems := c.splitValues(as.Value, valType, len(targets), as)
+
+	for i, target := range targets {
+		if targetTypes[i] == types.Invalid || elems[i] == types.Invalid {
+			continue
+		}
+		if !c.unify(elems[i], targetTypes[i]) {
+			var value ast.Node = as.Value
+			if te, ok := as.Value.(*ast.TupleExpression); ok {
+				value = te.Elements[i]
+			}
+			c.addError(diagnostics.TypeMismatch, value, "cannot assign %s to %s (type %s)", elems[i], target.String(), targetTypes[i]).
+				WithLabel(diagnostics.SpanOf(target), "declared as %s", targetTypes[i])
+		}
+	}
+	return types.Void
+}
+
+// splitValues returns the types of the n values that value, of type t,
+// provides to n variables or assignment targets, and reports a mismatch in
+// their number. A map index provides a second value, the found flag.
+func (c *Checker) splitValues(value ast.Expression, t types.Type, n int, node ast.Node) []types.Type {
+	elems := make([]types.Type, n)
+	for i := range elems {
+		elems[i] = types.Invalid
+	}
+	if t == types.Invalid {
+		return elems
+	}
+	if n == 2 && c.isMapIndex(value) {
+		return []types.Type{t, types.Bool}
+	}
+
+	count := 1
+	switch tt := types.Prune(t).(type) {
+	case *types.Tuple:
+		if len(tt.Elems) == n {
+			return tt.Elems
+		}
+		count = len(tt.Elems)
+	case *types.Var:
+		// A call of a function of unknown type returns as many values as
+		// are assigned.
+		if _, ok := value.(*ast.CallExpression); ok && n > 1 {
+			tuple := &types.Tuple{Elems: make([]types.Type, n)}
+			for i := range tuple.Elems {
+				tuple.Elems[i] = c.newVar()
+			}
+			c.unify(tt, tuple)
+			return tuple.Elems
+		}
+	}
+	if count == n {
+		return []types.Type{t}
+	}
+
+	var d *diagnostics.Diagnostic
+	if _, ok := value.(*ast.CallExpression); ok && count > 1 {
+		d = c.addError(diagnostics.AssignmentMismatch, node, "assignment mismatch: %s but %s returns %s",
+			plural(n, "variable"), value.String(), plural(count, "value"))
+	} else {
+		d = c.addError(diagnostics.AssignmentMismatch, node, "assignment mismatch: %s but %s",
+			plural(n, "variable"), plural(count, "value"))
+	}
+	if n == 2 && count == 1 {
+		d.WithNote("only calls of functions with several results produce several values, and a map index expression such as m[k] a value and a found flag")
+	}
+	return elems
+}
+
+// checkReturnCount reports a return statement whose number of values
+// differs from that of the function's result, and returns false if it does.
+func (c *Checker) checkReturnCount(rs *ast.ReturnStatement, valType, result types.Type) bool {
+	have, want := types.Prune(valType), types.Prune(result)
+	_, haveTuple := have.(*types.Tuple)
+	_, wantTuple := want.(*types.Tuple)
+	if !haveTuple && !wantTuple {
+		return true
+	}
+	if _, ok := want.(*types.Var); ok || have == types.Invalid || want == types.Invalid {
+		return true
+	}
+	haveCount, wantCount := valueCount(have), valueCount(want)
+	if haveCount == wantCount {
+		return true
+	}
+	few := "not enough"
+	if haveCount > wantCount {
+		few = "too many"
+	}
+	c.addError(diagnostics.AssignmentMismatch, rs.ReturnValue, "%s return values", few).
+		WithNote("have %s", valueList(have)).
+		WithNote("want %s", valueList(want))
+	return false
+}
+
+// valueCount returns the number of values of type t.
+func valueCount(t types.Type) int {
+	if tuple, ok := t.(*types.Tuple); ok {
+		return len(tuple.Elems)
+	}
+	return 1
+}
+
+// valueList describes the types of the values of type t as a
+// parenthesized list, as Go does in its notes on return statements.
+func valueList(t types.Type) string {
+	if tuple, ok := t.(*types.Tuple); ok {
+		return tuple.String()
+	}
+	return "(" + t.String() + ")"
+}
+
+// plural returns a count of things, such as "1 value" or "2 values".
+func plural(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
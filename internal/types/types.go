@@ -0,0 +1,280 @@
+// Package types defines the static types of GoLite programs as seen by the
+// semantic checker and the code generators.
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Type is a GoLite type.
+type Type interface {
+	String() string
+}
+
+// BasicKind identifies one of the predeclared types.
+type BasicKind int
+
+const (
+	InvalidKind BasicKind = iota
+	IntKind
+	FloatKind
+	BoolKind
+	StringKind
+	ErrorKind
+	VoidKind
+)
+
+// Basic is a predeclared type such as int or bool.
+type Basic struct {
+	kind BasicKind
+	name string
+}
+
+func (b *Basic) Kind() BasicKind { return b.kind }
+func (b *Basic) String() string  { return b.name }
+
+var (
+	// Invalid is the type of expressions that failed to check. It is
+	// compatible with everything so that one mistake is reported only once.
+	Invalid = &Basic{InvalidKind, "invalid type"}
+	Int     = &Basic{IntKind, "int"}
+	Float   = &Basic{FloatKind, "float"}
+	Bool    = &Basic{BoolKind, "bool"}
+	String  = &Basic{StringKind, "string"}
+	// Error is the type of error values, made by errors.New and returned by
+	// recover. Its zero value, nil, means no error.
+	Error = &Basic{ErrorKind, "error"}
+	// Void is the type of statements and of calls to functions that do not
+	// produce a value.
+	Void = &Basic{VoidKind, "void"}
+)
+
+// predeclared maps the type names usable in annotations to their types.
+var predeclared = map[string]Type{
+	"int":    Int,
+	"float":  Float,
+	"bool":   Bool,
+	"string": String,
+	"error":  Error,
+}
+
+// Lookup returns the predeclared type with the given name.
+func Lookup(name string) (Type, bool) {
+	t, ok := predeclared[name]
+	return t, ok
+}
+
+// Signature is the type of a function.
+type Signature struct {
+	Params []Type
+	Result Type // Void if the function produces no value
+}
+
+func (s *Signature) String() string {
+	var out bytes.Buffer
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.String()
+	}
+	out.WriteString("func(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if s.Result != nil && s.Result != Void {
+		out.WriteString(" ")
+		out.WriteString(s.Result.String())
+	}
+	return out.String()
+}
+
+// Slice is the type of a slice with elements of type Elem.
+type Slice struct {
+	Elem Type
+}
+
+func (s *Slice) String() string { return "[]" + s.Elem.String() }
+
+// Map is the type of a map from keys of type Key to values of type Value.
+type Map struct {
+	Key   Type
+	Value Type
+}
+
+func (m *Map) String() string { return "map[" + m.Key.String() + "]" + m.Value.String() }
+
+// Struct is a struct type declared by a type statement. Struct types are
+// nominal: two are identical only if they come from the same declaration.
+type Struct struct {
+	Name    string
+	Fields  []*Field
+	Methods map[string]*Method
+}
+
+// Field is a field of a struct type.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Method is a method of a struct type. Its signature takes the receiver as
+// the first parameter, which is a pointer if PointerReceiver is set.
+type Method struct {
+	Name            string
+	Signature       *Signature
+	PointerReceiver bool
+}
+
+func NewStruct(name string) *Struct {
+	return &Struct{Name: name, Methods: map[string]*Method{}}
+}
+
+func (s *Struct) String() string { return s.Name }
+
+// Field returns the field with the given name and its index, or nil and -1
+// if there is none.
+func (s *Struct) Field(name string) (*Field, int) {
+	for i, f := range s.Fields {
+		if f.Name == name {
+			return f, i
+		}
+	}
+	return nil, -1
+}
+
+// Pointer is the type of a pointer to a value of type Elem.
+type Pointer struct {
+	Elem Type
+}
+
+func (p *Pointer) String() string { return "*" + p.Elem.String() }
+
+// Tuple is the result type of a function that returns several values. It
+// is not the type of any variable: the values are assigned separately.
+type Tuple struct {
+	Elems []Type
+}
+
+func (t *Tuple) String() string {
+	elems := make([]string, len(t.Elems))
+	for i, e := range t.Elems {
+		elems[i] = e.String()
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+// Comparable reports whether values of type t can be compared with == and
+// so used as map keys.
+func Comparable(t Type) bool {
+	switch Prune(t) {
+	case Int, Float, Bool, String:
+		return true
+	}
+	return false
+}
+
+// Builtin is the type of a predeclared function such as len. Builtins may
+// accept arguments of several types, so the checker types each call to
+// them individually and they cannot be used as values.
+type Builtin struct {
+	Name string
+}
+
+func (b *Builtin) String() string { return "built-in function " + b.Name }
+
+// Var is a type variable introduced by type inference for a type that is
+// not known yet. Once inference decides what it stands for, Instance points
+// at that type.
+type Var struct {
+	ID       int
+	Instance Type
+}
+
+func (v *Var) String() string {
+	if v.Instance != nil {
+		return v.Instance.String()
+	}
+	return fmt.Sprintf("T%d", v.ID)
+}
+
+// Prune follows bound type variables until it reaches a type that is not a
+// bound variable.
+func Prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.Instance == nil {
+			return t
+		}
+		t = v.Instance
+	}
+}
+
+// Resolve returns t with every bound type variable replaced by its
+// instance, including inside function signatures, tuples and slice, map
+// and pointer types.
+func Resolve(t Type) Type {
+	switch t := Prune(t).(type) {
+	case *Signature:
+		resolved := &Signature{Params: make([]Type, len(t.Params)), Result: Resolve(t.Result)}
+		for i, p := range t.Params {
+			resolved.Params[i] = Resolve(p)
+		}
+		return resolved
+	case *Slice:
+		return &Slice{Elem: Resolve(t.Elem)}
+	case *Map:
+		return &Map{Key: Resolve(t.Key), Value: Resolve(t.Value)}
+	case *Pointer:
+		return &Pointer{Elem: Resolve(t.Elem)}
+	case *Tuple:
+		resolved := &Tuple{Elems: make([]Type, len(t.Elems))}
+		for i, e := range t.Elems {
+			resolved.Elems[i] = Resolve(e)
+		}
+		return resolved
+	default:
+		return t
+	}
+}
+
+// Identical reports whether a and b are the same type.
+func Identical(a, b Type) bool {
+	a, b = Prune(a), Prune(b)
+	if a == b {
+		return true
+	}
+	if sa, ok := a.(*Slice); ok {
+		sb, ok := b.(*Slice)
+		return ok && Identical(sa.Elem, sb.Elem)
+	}
+	if ma, ok := a.(*Map); ok {
+		mb, ok := b.(*Map)
+		return ok && Identical(ma.Key, mb.Key) && Identical(ma.Value, mb.Value)
+	}
+	if pa, ok := a.(*Pointer); ok {
+		pb, ok := b.(*Pointer)
+		return ok && Identical(pa.Elem, pb.Elem)
+	}
+	if ta, ok := a.(*Tuple); ok {
+		tb, ok := b.(*Tuple)
+		return ok && identicalLists(ta.Elems, tb.Elems)
+	}
+	sa, ok := a.(*Signature)
+	if !ok {
+		return false
+	}
+	sb, ok := b.(*Signature)
+	return ok && identicalLists(sa.Params, sb.Params) && Identical(sa.Result, sb.Result)
+}
+
+func identicalLists(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Identical(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"math/rand"
+
+	"golite.dev/mvp/internal/object"
+)
+
+// natives are the builtins the machine implements itself: those that share
+// or change their arguments, which converting them to objects would lose,
+// and len, which is called too often to convert its argument. The other
+// builtins of the registry run on converted arguments.
+var natives = map[string]native{
+	"len":    nativeLen,
+	"append": nativeAppend,
+	"delete": nativeDelete,
+	"keys":   nativeKeys,
+}
+
+func wrongArgs(want, got int) *object.Error {
+	return newError("wrong number of arguments: want=%d, got=%d", want, got)
+}
+
+func nativeLen(vm *VM, args []value) (value, *object.Error) {
+	if len(args) != 1 {
+		return value{}, wrongArgs(1, len(args))
+	}
+	switch arg := args[0]; arg.kind {
+	case kindString:
+		return intValue(int64(len(arg.str()))), nil
+	case kindSlice:
+		return intValue(int64(len(arg.elems()))), nil
+	case kindMap:
+		return intValue(int64(len(arg.ref.(*mapValue).pairs))), nil
+	}
+	return value{}, newError("argument to `len` not supported, got %s", args[0].kind)
+}
+
+// nativeAppend appends to a slice, growing its array the way the
+// evaluator and the C runtime do.
+func nativeAppend(vm *VM, args []value) (value, *object.Error) {
+	if len(args) == 0 {
+		return value{}, newError("wrong number of arguments: want at least 1, got 0")
+	}
+	if args[0].kind != kindSlice {
+		return value{}, newError("first argument to `append` must be SLICE, got %s", args[0].kind)
+	}
+	s, elems := args[0].elems(), args[1:]
+	if n := len(s) + len(elems); n > cap(s) {
+		newCap := 2 * cap(s)
+		if newCap < n {
+			newCap = n
+		}
+		grown := make([]value, len(s), newCap)
+		copy(grown, s)
+		s = grown
+	}
+	return sliceValue(append(s, elems...)), nil
+}
+
+func nativeDelete(vm *VM, args []value) (value, *object.Error) {
+	if len(args) != 2 {
+		return value{}, wrongArgs(2, len(args))
+	}
+	if args[0].kind != kindMap {
+		return value{}, newError("first argument to `delete` must be MAP, got %s", args[0].kind)
+	}
+	key, err := hashKey(args[1])
+	if err != nil {
+		return value{}, err
+	}
+	delete(args[0].ref.(*mapValue).pairs, key)
+	return null, nil
+}
+
+// nativeKeys returns the keys of a map in random order, as the evaluator
+// does.
+func nativeKeys(vm *VM, args []value) (value, *object.Error) {
+	if len(args) != 1 {
+		return value{}, wrongArgs(1, len(args))
+	}
+	if args[0].kind != kindMap {
+		return value{}, newError("argument to `keys` must be MAP, got %s", args[0].kind)
+	}
+	m := args[0].ref.(*mapValue)
+	keys := make([]value, 0, len(m.pairs))
+	for _, pair := range m.pairs {
+		keys = append(keys, pair.key)
+	}
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	return sliceValue(keys), nil
+}
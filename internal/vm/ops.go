@@ -0,0 +1,294 @@
+package vm
+
+import (
+	"golite.dev/mvp/internal/compiler"
+	"golite.dev/mvp/internal/object"
+)
+
+var operators = map[compiler.Opcode]string{
+	compiler.OpAdd:          "+",
+	compiler.OpSub:          "-",
+	compiler.OpMul:          "*",
+	compiler.OpDiv:          "/",
+	compiler.OpMod:          "%",
+	compiler.OpBitAnd:       "&",
+	compiler.OpBitOr:        "|",
+	compiler.OpBitXor:       "^",
+	compiler.OpShl:          "<<",
+	compiler.OpShr:          ">>",
+	compiler.OpEqual:        "==",
+	compiler.OpNotEqual:     "!=",
+	compiler.OpLess:         "<",
+	compiler.OpLessEqual:    "<=",
+	compiler.OpGreater:      ">",
+	compiler.OpGreaterEqual: ">=",
+}
+
+// binary applies a binary operator to two values.
+func binary(op compiler.Opcode, left, right value) (value, *object.Error) {
+	if op == compiler.OpEqual || op == compiler.OpNotEqual {
+		l, lok := reference(left)
+		r, rok := reference(right)
+		if lok && rok {
+			return boolValue((l == r) == (op == compiler.OpEqual)), nil
+		}
+	}
+	switch {
+	case left.kind == kindInt && right.kind == kindInt:
+		return intBinary(op, left, right)
+	case left.kind == kindFloat && right.kind == kindFloat:
+		return floatBinary(op, left.float(), right.float())
+	case left.kind == kindBool && right.kind == kindBool:
+		switch op {
+		case compiler.OpEqual:
+			return boolValue(left.n == right.n), nil
+		case compiler.OpNotEqual:
+			return boolValue(left.n != right.n), nil
+		}
+	case left.kind == kindString && right.kind == kindString:
+		switch op {
+		case compiler.OpAdd:
+			return stringValue(left.str() + right.str()), nil
+		case compiler.OpEqual:
+			return boolValue(left.str() == right.str()), nil
+		case compiler.OpNotEqual:
+			return boolValue(left.str() != right.str()), nil
+		}
+	case left.kind != right.kind:
+		return value{}, newError("type mismatch: %s %s %s", left.kind, operators[op], right.kind)
+	}
+	return value{}, newError("unknown operator: %s %s %s", left.kind, operators[op], right.kind)
+}
+
+func intBinary(op compiler.Opcode, left, right value) (value, *object.Error) {
+	l, r := left.n, right.n
+	switch op {
+	case compiler.OpAdd:
+		return intValue(l + r), nil
+	case compiler.OpSub:
+		return intValue(l - r), nil
+	case compiler.OpMul:
+		return intValue(l * r), nil
+	case compiler.OpDiv, compiler.OpMod:
+		if r == 0 {
+			return value{}, newError("integer divide by zero")
+		}
+		if op == compiler.OpDiv {
+			return intValue(l / r), nil
+		}
+		return intValue(l % r), nil
+	case compiler.OpBitAnd:
+		return intValue(l & r), nil
+	case compiler.OpBitOr:
+		return intValue(l | r), nil
+	case compiler.OpBitXor:
+		return intValue(l ^ r), nil
+	case compiler.OpShl, compiler.OpShr:
+		if r < 0 {
+			return value{}, newError("negative shift amount: %d", r)
+		}
+		if op == compiler.OpShl {
+			return intValue(l << r), nil
+		}
+		return intValue(l >> r), nil
+	case compiler.OpEqual:
+		return boolValue(l == r), nil
+	case compiler.OpNotEqual:
+		return boolValue(l != r), nil
+	case compiler.OpLess:
+		return boolValue(l < r), nil
+	case compiler.OpLessEqual:
+		return boolValue(l <= r), nil
+	case compiler.OpGreater:
+		return boolValue(l > r), nil
+	case compiler.OpGreaterEqual:
+		return boolValue(l >= r), nil
+	}
+	return value{}, newError("unknown operator: %s %s %s", left.kind, operators[op], right.kind)
+}
+
+func floatBinary(op compiler.Opcode, l, r float64) (value, *object.Error) {
+	switch op {
+	case compiler.OpAdd:
+		return floatValue(l + r), nil
+	case compiler.OpSub:
+		return floatValue(l - r), nil
+	case compiler.OpMul:
+		return floatValue(l * r), nil
+	case compiler.OpDiv:
+		return floatValue(l / r), nil
+	case compiler.OpEqual:
+		return boolValue(l == r), nil
+	case compiler.OpNotEqual:
+		return boolValue(l != r), nil
+	case compiler.OpLess:
+		return boolValue(l < r), nil
+	case compiler.OpLessEqual:
+		return boolValue(l <= r), nil
+	case compiler.OpGreater:
+		return boolValue(l > r), nil
+	case compiler.OpGreaterEqual:
+		return boolValue(l >= r), nil
+	}
+	return value{}, newError("unknown operator: %s %s %s", kindFloat, operators[op], kindFloat)
+}
+
+// reference returns what identifies a pointer or an error. Two of them are
+// equal if they refer to the same struct or error, or are both nil.
+func reference(v value) (interface{}, bool) {
+	switch v.kind {
+	case kindPointer:
+		if s := v.target(); s != nil {
+			return s, true
+		}
+		return nil, true
+	case kindError:
+		if msg := v.ref.(*string); msg != nil {
+			return msg, true
+		}
+		return nil, true
+	case kindNil:
+		return nil, true
+	}
+	return nil, false
+}
+
+// copyValue returns a copy of v if it is a struct, which must not be
+// shared once it is stored somewhere else, and v itself otherwise.
+func copyValue(v value) value {
+	if v.kind == kindStruct {
+		v.ref = v.target().copy()
+	}
+	return v
+}
+
+// structRef returns the struct that v is or points to.
+func structRef(v value) (*structValue, *object.Error) {
+	switch v.kind {
+	case kindStruct:
+		return v.target(), nil
+	case kindPointer:
+		if s := v.target(); s != nil {
+			return s, nil
+		}
+		return nil, newError("invalid memory address or nil pointer dereference")
+	case kindNil:
+		return nil, newError("invalid memory address or nil pointer dereference")
+	}
+	return nil, newError("%s is not a struct", v.kind)
+}
+
+func fieldError(s *structValue, name, sel string) *object.Error {
+	return newError("%s undefined (type %s has no field %s)", sel, s.typ.def.Name, name)
+}
+
+func indexError(i int64, length int) *object.Error {
+	if i < 0 {
+		return newError("index out of range [%d]", i)
+	}
+	return newError("index out of range [%d] with length %d", i, length)
+}
+
+// sliceIndex checks that i can index the slice s.
+func sliceIndex(s, i value) (int64, *object.Error) {
+	if s.kind != kindSlice {
+		return 0, newError("index operator not supported: %s", s.kind)
+	}
+	if i.kind != kindInt {
+		return 0, newError("index must be INTEGER, got %s", i.kind)
+	}
+	if n := len(s.elems()); i.n < 0 || i.n >= int64(n) {
+		return 0, indexError(i.n, n)
+	}
+	return i.n, nil
+}
+
+// index returns left[i] without copying it.
+func index(left, i value) (value, *object.Error) {
+	if left.kind == kindMap {
+		v, _, err := lookup(left.ref.(*mapValue), i)
+		return v, err
+	}
+	n, err := sliceIndex(left, i)
+	if err != nil {
+		return value{}, err
+	}
+	return left.elems()[n], nil
+}
+
+// lookup returns the value m holds for key, or the zero value if the key is
+// missing, and whether it was present.
+func lookup(m *mapValue, key value) (value, bool, *object.Error) {
+	k, err := hashKey(key)
+	if err != nil {
+		return value{}, false, err
+	}
+	pair, ok := m.pairs[k]
+	if !ok {
+		return m.zero, false, nil
+	}
+	return pair.value, true, nil
+}
+
+// setIndex stores v in left[i].
+func setIndex(left, i, v value) *object.Error {
+	if left.kind == kindMap {
+		k, err := hashKey(i)
+		if err != nil {
+			return err
+		}
+		m := left.ref.(*mapValue)
+		if m.pairs == nil {
+			return newError("assignment to entry in nil map")
+		}
+		m.pairs[k] = mapPair{key: i, value: v}
+		return nil
+	}
+	n, err := sliceIndex(left, i)
+	if err != nil {
+		return err
+	}
+	left.elems()[n] = v
+	return nil
+}
+
+// slice evaluates left[low:high]. As in Go, the result of slicing a slice
+// shares its array, and high may reach into the capacity beyond its
+// length.
+func slice(left value, low, high *value) (value, *object.Error) {
+	var length, limit int
+	limitName := "capacity"
+	switch left.kind {
+	case kindSlice:
+		length, limit = len(left.elems()), cap(left.elems())
+	case kindString:
+		length, limit = len(left.str()), len(left.str())
+		limitName = "length"
+	default:
+		return value{}, newError("slice operator not supported: %s", left.kind)
+	}
+
+	bounds := [2]int64{0, int64(length)}
+	for i, bound := range []*value{low, high} {
+		if bound == nil {
+			continue
+		}
+		if bound.kind != kindInt {
+			return value{}, newError("slice index must be INTEGER, got %s", bound.kind)
+		}
+		bounds[i] = bound.n
+	}
+
+	lo, hi := bounds[0], bounds[1]
+	switch {
+	case hi < 0 || hi > int64(limit):
+		return value{}, newError("slice bounds out of range [:%d] with %s %d", hi, limitName, limit)
+	case lo < 0 || lo > hi:
+		return value{}, newError("slice bounds out of range [%d:%d]", lo, hi)
+	}
+
+	if left.kind == kindString {
+		return stringValue(left.str()[lo:hi]), nil
+	}
+	return sliceValue(left.elems()[lo:hi]), nil
+}
@@ -0,0 +1,393 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/compiler"
+	"golite.dev/mvp/internal/object"
+)
+
+type kind uint8
+
+const (
+	kindUndefined kind = iota // a variable not assigned yet
+	kindNull
+	kindInt
+	kindFloat
+	kindBool
+	kindString
+	kindSlice
+	kindMap
+	kindStruct
+	kindPointer
+	kindError
+	kindNil
+	kindTuple
+	kindClosure
+	kindBuiltin
+	kindNative
+	kindCell
+)
+
+// typeNames are the names of the kinds in runtime errors, which are those
+// of the evaluator's object types.
+var typeNames = [...]string{
+	kindUndefined: "UNDEFINED",
+	kindNull:      object.NULL_OBJ,
+	kindInt:       object.INTEGER_OBJ,
+	kindFloat:     object.FLOAT_OBJ,
+	kindBool:      object.BOOLEAN_OBJ,
+	kindString:    object.STRING_OBJ,
+	kindSlice:     object.SLICE_OBJ,
+	kindMap:       object.MAP_OBJ,
+	kindStruct:    object.STRUCT_OBJ,
+	kindPointer:   object.POINTER_OBJ,
+	kindError:     object.ERROR_VALUE_OBJ,
+	kindNil:       object.NIL_OBJ,
+	kindTuple:     object.TUPLE_OBJ,
+	kindClosure:   object.FUNCTION_OBJ,
+	kindBuiltin:   object.BUILTIN_OBJ,
+	kindNative:    object.BUILTIN_OBJ,
+	kindCell:      "CELL",
+}
+
+func (k kind) String() string { return typeNames[k] }
+
+// value is a GoLite value. Numbers and booleans live in n, so arithmetic
+// does not allocate; everything else lives in ref:
+//
+//	kindString   string
+//	kindSlice    []value, sharing its array with other slices as in Go
+//	kindMap      *mapValue
+//	kindStruct   *structValue
+//	kindPointer  *structValue, nil for the nil pointer
+//	kindError    *string, which identifies the error; nil for the nil error
+//	kindTuple    []value
+//	kindClosure  *closure
+//	kindBuiltin  *builtins.Builtin
+//	kindNative   native
+//	kindCell     *cell
+type value struct {
+	kind kind
+	n    int64 // an int, a bool as 0 or 1, or the bits of a float
+	ref  interface{}
+}
+
+var (
+	null       = value{kind: kindNull}
+	nilValue   = value{kind: kindNil}
+	trueValue  = value{kind: kindBool, n: 1}
+	falseValue = value{kind: kindBool}
+)
+
+func intValue(n int64) value         { return value{kind: kindInt, n: n} }
+func floatValue(f float64) value     { return value{kind: kindFloat, n: int64(math.Float64bits(f))} }
+func stringValue(s string) value     { return value{kind: kindString, ref: s} }
+func sliceValue(elems []value) value { return value{kind: kindSlice, ref: elems} }
+
+func boolValue(b bool) value {
+	if b {
+		return trueValue
+	}
+	return falseValue
+}
+
+func (v value) float() float64       { return math.Float64frombits(uint64(v.n)) }
+func (v value) str() string          { return v.ref.(string) }
+func (v value) elems() []value       { return v.ref.([]value) }
+func (v value) truthy() bool         { return v.kind != kindNull && (v.kind != kindBool || v.n != 0) }
+func (v value) isFalse() bool        { return v.kind == kindBool && v.n == 0 }
+func (v value) isTrue() bool         { return v.kind == kindBool && v.n != 0 }
+func (v value) target() *structValue { return v.ref.(*structValue) }
+
+// native is a builtin that works on values directly, rather than on the
+// evaluator's objects.
+type native func(vm *VM, args []value) (value, *object.Error)
+
+// cell holds a variable that closures capture.
+type cell struct {
+	value value
+}
+
+type closure struct {
+	fn   *compiler.Function
+	free []*cell
+}
+
+type structType struct {
+	def     *compiler.StructType
+	fields  map[string]int
+	methods map[string]*method
+	object  *object.StructType
+}
+
+type method struct {
+	closure         *closure
+	pointerReceiver bool
+}
+
+type structValue struct {
+	typ    *structType
+	fields []value
+}
+
+// copy returns a copy of the struct, including the structs in its fields.
+func (s *structValue) copy() *structValue {
+	fields := make([]value, len(s.fields))
+	for i, f := range s.fields {
+		if f.kind == kindStruct {
+			f.ref = f.target().copy()
+		}
+		fields[i] = f
+	}
+	return &structValue{typ: s.typ, fields: fields}
+}
+
+// mapKey identifies a map key by value. Floats are compared as floats, so
+// 0.0 and -0.0 are the same key and NaN is never found.
+type mapKey struct {
+	kind kind
+	n    int64
+	f    float64
+	s    string
+}
+
+type mapPair struct {
+	key   value
+	value value
+}
+
+// mapValue is a reference to a hash table. A nil map has nil pairs.
+type mapValue struct {
+	pairs map[mapKey]mapPair
+	zero  value // the value of keys that are not in the map
+}
+
+func hashKey(v value) (mapKey, *object.Error) {
+	switch v.kind {
+	case kindInt, kindBool:
+		return mapKey{kind: v.kind, n: v.n}, nil
+	case kindFloat:
+		return mapKey{kind: kindFloat, f: v.float()}, nil
+	case kindString:
+		return mapKey{kind: kindString, s: v.str()}, nil
+	}
+	return mapKey{}, newError("unusable as map key: %s", v.kind)
+}
+
+// lessKey orders map keys of the same type: numbers by value, strings
+// bytewise and false before true.
+func lessKey(a, b value) bool {
+	switch a.kind {
+	case kindInt:
+		return a.n < b.n
+	case kindFloat:
+		return a.float() < b.float()
+	case kindString:
+		return a.str() < b.str()
+	case kindBool:
+		return a.n < b.n
+	}
+	return false
+}
+
+// inspect formats v the way the evaluator's Inspect does.
+func inspect(v value) string {
+	switch v.kind {
+	case kindNull:
+		return "null"
+	case kindInt:
+		return strconv.FormatInt(v.n, 10)
+	case kindFloat:
+		return strconv.FormatFloat(v.float(), 'g', -1, 64)
+	case kindBool:
+		return strconv.FormatBool(v.n != 0)
+	case kindString:
+		return v.str()
+	case kindSlice:
+		elems := v.elems()
+		parts := make([]string, len(elems))
+		for i, el := range elems {
+			parts[i] = inspectElement(el)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	case kindMap:
+		m := v.ref.(*mapValue)
+		pairs := make([]mapPair, 0, len(m.pairs))
+		for _, pair := range m.pairs {
+			pairs = append(pairs, pair)
+		}
+		sort.Slice(pairs, func(i, j int) bool { return lessKey(pairs[i].key, pairs[j].key) })
+		parts := make([]string, len(pairs))
+		for i, pair := range pairs {
+			parts[i] = inspect(pair.key) + ":" + inspectElement(pair.value)
+		}
+		return "map[" + strings.Join(parts, " ") + "]"
+	case kindStruct:
+		s := v.target()
+		parts := make([]string, len(s.fields))
+		for i, f := range s.fields {
+			parts[i] = inspectElement(f)
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	case kindPointer:
+		if v.target() == nil {
+			return "<nil>"
+		}
+		return "&" + inspect(value{kind: kindStruct, ref: v.ref})
+	case kindError:
+		if msg := v.ref.(*string); msg != nil {
+			return *msg
+		}
+		return "<nil>"
+	case kindNil:
+		return "<nil>"
+	case kindTuple:
+		elems := v.elems()
+		parts := make([]string, len(elems))
+		for i, el := range elems {
+			parts[i] = inspect(el)
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case kindClosure:
+		return "func literal"
+	case kindBuiltin, kindNative:
+		return "builtin function"
+	}
+	return v.kind.String()
+}
+
+// inspectElement formats a value held in a slice, map or struct, where a
+// pointer shows as an address.
+func inspectElement(v value) string {
+	if v.kind == kindPointer && v.target() != nil {
+		return fmt.Sprintf("%p", v.target())
+	}
+	return inspect(v)
+}
+
+// toObject converts v to an object of the evaluator, for the builtins of
+// the registry.
+func (vm *VM) toObject(v value) object.Object {
+	switch v.kind {
+	case kindInt:
+		return &object.Integer{Value: v.n}
+	case kindFloat:
+		return &object.Float{Value: v.float()}
+	case kindBool:
+		return &object.Boolean{Value: v.n != 0}
+	case kindString:
+		return &object.String{Value: v.str()}
+	case kindSlice:
+		elems := v.elems()
+		objs := make([]object.Object, len(elems), cap(elems))
+		for i, el := range elems {
+			objs[i] = vm.toObject(el)
+		}
+		return &object.Slice{Elements: objs}
+	case kindMap:
+		m := v.ref.(*mapValue)
+		obj := &object.Map{Zero: vm.toObject(m.zero)}
+		if m.pairs != nil {
+			obj.Pairs = make(map[object.HashKey]object.MapPair, len(m.pairs))
+			for _, pair := range m.pairs {
+				key := vm.toObject(pair.key)
+				obj.Pairs[key.(object.Hashable).HashKey()] = object.MapPair{Key: key, Value: vm.toObject(pair.value)}
+			}
+		}
+		return obj
+	case kindStruct:
+		return vm.toStruct(v.target())
+	case kindPointer:
+		if v.target() == nil {
+			return &object.Pointer{}
+		}
+		return &object.Pointer{Target: vm.toStruct(v.target())}
+	case kindError:
+		return &object.ErrorValue{Message: v.ref.(*string)}
+	case kindNil:
+		return &object.Nil{}
+	case kindTuple:
+		elems := v.elems()
+		objs := make([]object.Object, len(elems))
+		for i, el := range elems {
+			objs[i] = vm.toObject(el)
+		}
+		return &object.Tuple{Elements: objs}
+	case kindBuiltin:
+		return &object.Builtin{Fn: v.ref.(*builtins.Builtin).Eval}
+	case kindClosure, kindNative:
+		return &object.Builtin{}
+	}
+	return &object.Null{}
+}
+
+func (vm *VM) toStruct(s *structValue) *object.Struct {
+	obj := &object.Struct{StructType: s.typ.object, Fields: make([]object.Object, len(s.fields))}
+	for i, f := range s.fields {
+		obj.Fields[i] = vm.toObject(f)
+	}
+	return obj
+}
+
+// fromObject converts the result of a builtin of the registry to a value.
+func (vm *VM) fromObject(obj object.Object) value {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return intValue(obj.Value)
+	case *object.Float:
+		return floatValue(obj.Value)
+	case *object.Boolean:
+		return boolValue(obj.Value)
+	case *object.String:
+		return stringValue(obj.Value)
+	case *object.Slice:
+		elems := make([]value, len(obj.Elements), cap(obj.Elements))
+		for i, el := range obj.Elements {
+			elems[i] = vm.fromObject(el)
+		}
+		return sliceValue(elems)
+	case *object.Map:
+		m := &mapValue{zero: vm.fromObject(obj.Zero)}
+		if obj.Pairs != nil {
+			m.pairs = make(map[mapKey]mapPair, len(obj.Pairs))
+			for _, pair := range obj.Pairs {
+				key := vm.fromObject(pair.Key)
+				k, _ := hashKey(key)
+				m.pairs[k] = mapPair{key: key, value: vm.fromObject(pair.Value)}
+			}
+		}
+		return value{kind: kindMap, ref: m}
+	case *object.Struct:
+		return value{kind: kindStruct, ref: vm.fromStruct(obj)}
+	case *object.Pointer:
+		if obj.Target == nil {
+			return value{kind: kindPointer, ref: (*structValue)(nil)}
+		}
+		return value{kind: kindPointer, ref: vm.fromStruct(obj.Target)}
+	case *object.ErrorValue:
+		return value{kind: kindError, ref: obj.Message}
+	case *object.Nil:
+		return nilValue
+	case *object.Tuple:
+		elems := make([]value, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elems[i] = vm.fromObject(el)
+		}
+		return value{kind: kindTuple, ref: elems}
+	}
+	return null
+}
+
+func (vm *VM) fromStruct(obj *object.Struct) *structValue {
+	s := &structValue{typ: vm.objectTypes[obj.StructType], fields: make([]value, len(obj.Fields))}
+	for i, f := range obj.Fields {
+		s.fields[i] = vm.fromObject(f)
+	}
+	return s
+}
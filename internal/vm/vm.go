@@ -0,0 +1,718 @@
+// Package vm runs the bytecode of package compiler on a stack machine. It
+// behaves like the evaluator, including its runtime errors, panics and
+// stack traces, but keeps numbers unboxed and variables in slots.
+package vm
+
+import (
+	"fmt"
+	"os"
+
+	"golite.dev/mvp/internal/builtins"
+	"golite.dev/mvp/internal/compiler"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+)
+
+const initialStackSize = 2048
+
+// maxFrames is the most calls that can be in progress at once. Every call
+// nests a call of run on the Go stack, whose overflow kills the process,
+// so a runaway recursion stops here with a runtime error instead.
+const maxFrames = 10000
+
+// VM is a virtual machine that runs one program.
+type VM struct {
+	bytecode  *compiler.Bytecode
+	constants []value
+	globals   []value
+	builtins  []value
+	types     []*structType
+	// objectTypes maps the struct types given to builtins of the registry
+	// back to their own.
+	objectTypes map[*object.StructType]*structType
+
+	stack  []value
+	sp     int // the stack holds stack[:sp]
+	frames []*frame
+	depth  int // the frames in use
+}
+
+// frame is a call of a closure, or of the main program.
+type frame struct {
+	cl        *closure
+	base      int // the index of the first local in the stack
+	deferred  []deferredCall
+	panic     *object.Error // the panic unwinding through the call while its deferred calls run
+	deferring *frame        // the frame whose deferred calls include this call
+}
+
+// deferredCall is a call postponed by a defer statement, with its function
+// and arguments already evaluated.
+type deferredCall struct {
+	fn   value
+	args []value
+	name string
+	call diagnostics.Span
+}
+
+// New creates a machine that runs bytecode.
+func New(bytecode *compiler.Bytecode) *VM {
+	vm := &VM{
+		bytecode:    bytecode,
+		globals:     make([]value, len(bytecode.Globals)),
+		objectTypes: map[*object.StructType]*structType{},
+		stack:       make([]value, initialStackSize),
+	}
+	for _, def := range bytecode.Types {
+		t := &structType{
+			def:     def,
+			fields:  map[string]int{},
+			methods: map[string]*method{},
+			object:  &object.StructType{Name: def.Name, Decl: def.Decl, Fields: def.Fields, Methods: map[string]*object.Method{}},
+		}
+		for i, name := range def.Fields {
+			t.fields[name] = i
+		}
+		for name, m := range def.Methods {
+			t.methods[name] = &method{closure: &closure{fn: m.Function}, pointerReceiver: m.PointerReceiver}
+		}
+		vm.types = append(vm.types, t)
+		vm.objectTypes[t.object] = t
+	}
+	for _, c := range bytecode.Constants {
+		switch c := c.(type) {
+		case int64:
+			vm.constants = append(vm.constants, intValue(c))
+		case float64:
+			vm.constants = append(vm.constants, floatValue(c))
+		case string:
+			vm.constants = append(vm.constants, stringValue(c))
+		default:
+			// Functions only appear in OpClosure, which reads them from
+			// the bytecode.
+			vm.constants = append(vm.constants, null)
+		}
+	}
+	for _, name := range bytecode.Builtins {
+		if fn, ok := natives[name]; ok {
+			vm.builtins = append(vm.builtins, value{kind: kindNative, ref: fn})
+		} else {
+			b, _ := builtins.Lookup(name)
+			vm.builtins = append(vm.builtins, value{kind: kindBuiltin, ref: b})
+		}
+	}
+	return vm
+}
+
+// Run runs the program and then the calls it deferred. It returns the
+// runtime error or panic that ended it, if any.
+func (vm *VM) Run() *object.Error {
+	_, err := vm.callClosure(&closure{fn: vm.bytecode.Main}, 0, 0, nil)
+	return err
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func (vm *VM) push(v value) {
+	vm.stack[vm.sp] = v
+	vm.sp++
+}
+
+func (vm *VM) pop() value {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+// reserve makes room for n values above the top of the stack.
+func (vm *VM) reserve(n int) {
+	if vm.sp+n <= len(vm.stack) {
+		return
+	}
+	size := 2 * len(vm.stack)
+	for size < vm.sp+n {
+		size *= 2
+	}
+	grown := make([]value, size)
+	copy(grown, vm.stack[:vm.sp])
+	vm.stack = grown
+}
+
+func (vm *VM) pushFrame(cl *closure, base int, deferring *frame) (*frame, *object.Error) {
+	if vm.depth == maxFrames {
+		return nil, newError("stack overflow")
+	}
+	if vm.depth == len(vm.frames) {
+		vm.frames = append(vm.frames, &frame{})
+	}
+	f := vm.frames[vm.depth]
+	vm.depth++
+	f.cl, f.base, f.deferred, f.panic, f.deferring = cl, base, f.deferred[:0], nil, deferring
+	return f, nil
+}
+
+// call calls fn with the argc arguments at stack[base:]. deferring is the
+// frame whose deferred call this is, if it is one.
+func (vm *VM) call(fn value, base, argc int, deferring *frame) (value, *object.Error) {
+	switch fn.kind {
+	case kindClosure:
+		return vm.callClosure(fn.ref.(*closure), base, argc, deferring)
+	case kindNative:
+		return fn.ref.(native)(vm, vm.stack[base:base+argc])
+	case kindBuiltin:
+		args := make([]object.Object, argc)
+		for i, arg := range vm.stack[base : base+argc] {
+			args[i] = vm.toObject(arg)
+		}
+		result := fn.ref.(*builtins.Builtin).Eval(args...)
+		if err, ok := result.(*object.Error); ok {
+			return value{}, err
+		}
+		if result == nil {
+			return null, nil
+		}
+		return vm.fromObject(result), nil
+	}
+	return value{}, newError("not a function: %s", fn.kind)
+}
+
+// callClosure calls cl with the argc arguments at stack[base:], which
+// become its first locals. Once the body has finished, normally or with a
+// panic, the deferred calls run; if one of them recovers from the panic,
+// the closure returns the zero value of its result.
+func (vm *VM) callClosure(cl *closure, base, argc int, deferring *frame) (value, *object.Error) {
+	fn := cl.fn
+	if argc != fn.NumParams {
+		return value{}, newError("wrong number of arguments: want=%d, got=%d", fn.NumParams, argc)
+	}
+	vm.sp = base + argc
+	vm.reserve(fn.NumLocals - argc + fn.MaxStack)
+	for i := base + argc; i < base+fn.NumLocals; i++ {
+		vm.stack[i] = value{}
+	}
+	vm.sp = base + fn.NumLocals
+
+	f, err := vm.pushFrame(cl, base, deferring)
+	if err != nil {
+		return value{}, err
+	}
+	result, err := vm.run(f)
+	if err != nil {
+		f.panic = err
+	}
+	vm.runDeferred(f)
+	vm.depth--
+	if f.panic != nil {
+		return value{}, f.panic
+	}
+	if err != nil {
+		return vm.zero(fn.Result), nil
+	}
+	return result, nil
+}
+
+// runDeferred runs the calls deferred in f, the most recent first. A
+// deferred call that panics replaces the panic unwinding through the
+// frame, if there is one.
+func (vm *VM) runDeferred(f *frame) {
+	for len(f.deferred) > 0 {
+		last := len(f.deferred) - 1
+		d := f.deferred[last]
+		f.deferred = f.deferred[:last]
+
+		top := vm.sp
+		vm.reserve(len(d.args) + 1)
+		vm.push(d.fn)
+		for _, arg := range d.args {
+			vm.push(arg)
+		}
+		_, err := vm.call(d.fn, top+1, len(d.args), f)
+		vm.sp = top
+		if err != nil {
+			if !err.Span.Start.IsValid() {
+				err.Span = d.call
+			}
+			if d.fn.kind == kindClosure {
+				err.Stack = append(err.Stack, object.StackFrame{Function: d.name, Call: d.call})
+			}
+			f.panic = err
+		}
+	}
+}
+
+// fail locates an error that the instruction at ip of f caused, unless it
+// happened further in and has a location already.
+func (vm *VM) fail(f *frame, ip int, err *object.Error) (value, *object.Error) {
+	if !err.Span.Start.IsValid() {
+		err.Span = f.cl.fn.SpanAt(ip)
+	}
+	return value{}, err
+}
+
+// callFailed handles an error that a call made at ip of f returned. An
+// error located inside the function called, rather than in calling it,
+// unwound a call that belongs in its stack trace.
+func (vm *VM) callFailed(f *frame, ip int, fn value, err *object.Error) (value, *object.Error) {
+	if err.Span.Start.IsValid() && fn.kind == kindClosure {
+		err.Stack = append(err.Stack, object.StackFrame{Function: f.cl.fn.Callees[ip], Call: f.cl.fn.SpanAt(ip)})
+	}
+	return vm.fail(f, ip, err)
+}
+
+// zero returns a new zero value of the type z describes.
+func (vm *VM) zero(z *compiler.Zero) value {
+	switch z.Kind {
+	case compiler.ZeroInt:
+		return intValue(0)
+	case compiler.ZeroFloat:
+		return floatValue(0)
+	case compiler.ZeroBool:
+		return falseValue
+	case compiler.ZeroString:
+		return stringValue("")
+	case compiler.ZeroError:
+		return value{kind: kindError, ref: (*string)(nil)}
+	case compiler.ZeroStruct:
+		return value{kind: kindStruct, ref: vm.zeroStruct(vm.types[z.Type])}
+	case compiler.ZeroPointer:
+		return value{kind: kindPointer, ref: (*structValue)(nil)}
+	case compiler.ZeroSlice:
+		return sliceValue(nil)
+	case compiler.ZeroMap:
+		return value{kind: kindMap, ref: &mapValue{zero: vm.zero(z.Elems[0])}}
+	case compiler.ZeroTuple:
+		elems := make([]value, len(z.Elems))
+		for i, e := range z.Elems {
+			elems[i] = vm.zero(e)
+		}
+		return value{kind: kindTuple, ref: elems}
+	}
+	return null
+}
+
+func (vm *VM) zeroStruct(t *structType) *structValue {
+	s := &structValue{typ: t, fields: make([]value, len(t.def.Zeros))}
+	for i, z := range t.def.Zeros {
+		s.fields[i] = vm.zero(z)
+	}
+	return s
+}
+
+// run executes the instructions of the closure called in f until it
+// returns or fails.
+func (vm *VM) run(f *frame) (value, *object.Error) {
+	fn := f.cl.fn
+	ins := fn.Instructions
+	bp := f.base
+	for ip := 0; ; {
+		op := compiler.Opcode(ins[ip])
+		switch op {
+		case compiler.OpConstant:
+			vm.push(vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])])
+			ip += 3
+		case compiler.OpPop:
+			vm.sp--
+			ip++
+		case compiler.OpDup:
+			vm.push(vm.stack[vm.sp-1])
+			ip++
+		case compiler.OpSwap:
+			vm.stack[vm.sp-1], vm.stack[vm.sp-2] = vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			ip++
+		case compiler.OpTrue:
+			vm.push(trueValue)
+			ip++
+		case compiler.OpFalse:
+			vm.push(falseValue)
+			ip++
+		case compiler.OpNull:
+			vm.push(null)
+			ip++
+		case compiler.OpNil:
+			vm.push(nilValue)
+			ip++
+
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpMod,
+			compiler.OpBitAnd, compiler.OpBitOr, compiler.OpBitXor, compiler.OpShl, compiler.OpShr,
+			compiler.OpEqual, compiler.OpNotEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpGreater, compiler.OpGreaterEqual:
+			left, right := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			var result value
+			if left.kind == kindInt && right.kind == kindInt && op <= compiler.OpMul {
+				switch op {
+				case compiler.OpAdd:
+					result = intValue(left.n + right.n)
+				case compiler.OpSub:
+					result = intValue(left.n - right.n)
+				default:
+					result = intValue(left.n * right.n)
+				}
+			} else {
+				var err *object.Error
+				if result, err = binary(op, left, right); err != nil {
+					return vm.fail(f, ip, err)
+				}
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = result
+			ip++
+		case compiler.OpMinus:
+			v := vm.stack[vm.sp-1]
+			switch v.kind {
+			case kindInt:
+				vm.stack[vm.sp-1] = intValue(-v.n)
+			case kindFloat:
+				vm.stack[vm.sp-1] = floatValue(-v.float())
+			default:
+				return vm.fail(f, ip, newError("unknown operator: -%s", v.kind))
+			}
+			ip++
+		case compiler.OpNot:
+			v := vm.stack[vm.sp-1]
+			vm.stack[vm.sp-1] = boolValue(v.kind == kindNull || v.isFalse())
+			ip++
+		case compiler.OpIncrement, compiler.OpDecrement:
+			v := vm.stack[vm.sp-1]
+			switch {
+			case v.kind == kindInt && op == compiler.OpIncrement:
+				vm.stack[vm.sp-1] = intValue(v.n + 1)
+			case v.kind == kindInt:
+				vm.stack[vm.sp-1] = intValue(v.n - 1)
+			default:
+				one := intValue(1)
+				if v.kind == kindFloat {
+					one = floatValue(1)
+				}
+				binop := compiler.OpAdd
+				if op == compiler.OpDecrement {
+					binop = compiler.OpSub
+				}
+				result, err := binary(binop, v, one)
+				if err != nil {
+					return vm.fail(f, ip, err)
+				}
+				vm.stack[vm.sp-1] = result
+			}
+			ip++
+
+		case compiler.OpJump:
+			ip = int(ins[ip+1])<<8 | int(ins[ip+2])
+		case compiler.OpJumpNotTruthy:
+			vm.sp--
+			if !vm.stack[vm.sp].truthy() {
+				ip = int(ins[ip+1])<<8 | int(ins[ip+2])
+			} else {
+				ip += 3
+			}
+		case compiler.OpJumpIfFalse, compiler.OpJumpIfTrue:
+			v := vm.stack[vm.sp-1]
+			if op == compiler.OpJumpIfFalse && v.isFalse() || op == compiler.OpJumpIfTrue && v.isTrue() {
+				ip = int(ins[ip+1])<<8 | int(ins[ip+2])
+			} else {
+				vm.sp--
+				ip += 3
+			}
+
+		case compiler.OpGetGlobal:
+			index := int(ins[ip+1])<<8 | int(ins[ip+2])
+			v := vm.globals[index]
+			if v.kind == kindUndefined {
+				return vm.fail(f, ip, newError("identifier not found: "+vm.bytecode.Globals[index]))
+			}
+			vm.push(v)
+			ip += 3
+		case compiler.OpSetGlobal:
+			vm.globals[int(ins[ip+1])<<8|int(ins[ip+2])] = vm.pop()
+			ip += 3
+		case compiler.OpGetLocal:
+			vm.push(vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))])
+			ip += 3
+		case compiler.OpSetLocal:
+			vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))] = vm.pop()
+			ip += 3
+		case compiler.OpNewCell:
+			vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))] = value{kind: kindCell, ref: &cell{value: vm.pop()}}
+			ip += 3
+		case compiler.OpGetCell:
+			vm.push(vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))].ref.(*cell).value)
+			ip += 3
+		case compiler.OpSetCell:
+			vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))].ref.(*cell).value = vm.pop()
+			ip += 3
+		case compiler.OpLoadCell:
+			vm.push(vm.stack[bp+(int(ins[ip+1])<<8|int(ins[ip+2]))])
+			ip += 3
+		case compiler.OpGetFree:
+			vm.push(f.cl.free[ins[ip+1]].value)
+			ip += 2
+		case compiler.OpSetFree:
+			f.cl.free[ins[ip+1]].value = vm.pop()
+			ip += 2
+		case compiler.OpLoadFree:
+			vm.push(value{kind: kindCell, ref: f.cl.free[ins[ip+1]]})
+			ip += 2
+		case compiler.OpGetBuiltin:
+			vm.push(vm.builtins[ins[ip+1]])
+			ip += 2
+		case compiler.OpClosure:
+			n := int(ins[ip+3])
+			cl := &closure{fn: vm.bytecode.Constants[int(ins[ip+1])<<8|int(ins[ip+2])].(*compiler.Function)}
+			if n > 0 {
+				cl.free = make([]*cell, n)
+				for i, v := range vm.stack[vm.sp-n : vm.sp] {
+					cl.free[i] = v.ref.(*cell)
+				}
+				vm.sp -= n
+			}
+			vm.push(value{kind: kindClosure, ref: cl})
+			ip += 4
+
+		case compiler.OpCall:
+			argc := int(ins[ip+1])
+			slot := vm.sp - argc - 1
+			callee := vm.stack[slot]
+			result, err := vm.call(callee, slot+1, argc, nil)
+			if err != nil {
+				return vm.callFailed(f, ip, callee, err)
+			}
+			vm.sp = slot + 1
+			vm.stack[slot] = result
+			ip += 2
+		case compiler.OpMethod:
+			callee, recv, err := vm.method(vm.stack[vm.sp-1], vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])].str(),
+				vm.constants[int(ins[ip+3])<<8|int(ins[ip+4])].str())
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.stack[vm.sp-1] = callee
+			vm.push(recv)
+			ip += 5
+		case compiler.OpCallMethod:
+			argc := int(ins[ip+1])
+			slot := vm.sp - argc - 2
+			callee := vm.stack[slot]
+			if vm.stack[slot+1].kind == kindUndefined {
+				// A field holding a function rather than a method: there is
+				// no receiver to pass.
+				copy(vm.stack[slot+1:], vm.stack[slot+2:vm.sp])
+				vm.sp--
+			} else {
+				argc++
+			}
+			result, err := vm.call(callee, slot+1, argc, nil)
+			if err != nil {
+				return vm.callFailed(f, ip, callee, err)
+			}
+			vm.sp = slot + 1
+			vm.stack[slot] = result
+			ip += 2
+		case compiler.OpDefer:
+			argc, method := int(ins[ip+1]), int(ins[ip+2])
+			slot := vm.sp - argc - 1 - method
+			d := deferredCall{fn: vm.stack[slot], name: fn.Callees[ip], call: fn.SpanAt(ip)}
+			args := vm.stack[vm.sp-argc : vm.sp]
+			if method == 1 && vm.stack[slot+1].kind != kindUndefined {
+				d.args = append(d.args, vm.stack[slot+1])
+			}
+			d.args = append(d.args, args...)
+			f.deferred = append(f.deferred, d)
+			vm.sp = slot
+			ip += 3
+		case compiler.OpRecover:
+			vm.push(recoverPanic(f))
+			ip++
+		case compiler.OpReturn:
+			return vm.pop(), nil
+
+		case compiler.OpArray:
+			n := int(ins[ip+1])<<8 | int(ins[ip+2])
+			elems := make([]value, n)
+			copy(elems, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			vm.push(sliceValue(elems))
+			ip += 3
+		case compiler.OpMap:
+			zero := vm.bytecode.Zeros[int(ins[ip+1])<<8|int(ins[ip+2])]
+			n := int(ins[ip+3])<<8 | int(ins[ip+4])
+			m := &mapValue{pairs: make(map[mapKey]mapPair, n), zero: vm.zero(zero)}
+			pairs := vm.stack[vm.sp-2*n : vm.sp]
+			for i := 0; i < len(pairs); i += 2 {
+				key, err := hashKey(pairs[i])
+				if err != nil {
+					return vm.fail(f, ip, err)
+				}
+				m.pairs[key] = mapPair{key: pairs[i], value: pairs[i+1]}
+			}
+			vm.sp -= 2 * n
+			vm.push(value{kind: kindMap, ref: m})
+			ip += 5
+		case compiler.OpStruct:
+			vm.push(value{kind: kindStruct, ref: vm.zeroStruct(vm.types[int(ins[ip+1])<<8|int(ins[ip+2])])})
+			ip += 3
+		case compiler.OpInitField:
+			v := vm.pop()
+			vm.stack[vm.sp-1].target().fields[ins[ip+1]] = v
+			ip += 2
+		case compiler.OpIndex:
+			result, err := index(vm.stack[vm.sp-2], vm.stack[vm.sp-1])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = result
+			ip++
+		case compiler.OpIndexOk:
+			left := vm.stack[vm.sp-2]
+			if left.kind != kindMap {
+				return vm.fail(f, ip, newError("assignment mismatch: 2 variables but 1 value"))
+			}
+			result, found, err := lookup(left.ref.(*mapValue), vm.stack[vm.sp-1])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.stack[vm.sp-2] = boolValue(found)
+			vm.stack[vm.sp-1] = copyValue(result)
+			ip++
+		case compiler.OpSetIndex:
+			err := setIndex(vm.stack[vm.sp-2], vm.stack[vm.sp-1], vm.stack[vm.sp-3])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.sp -= 3
+			ip++
+		case compiler.OpSlice:
+			flags := ins[ip+1]
+			var low, high *value
+			if flags&2 != 0 {
+				high = &vm.stack[vm.sp-1]
+				vm.sp--
+			}
+			if flags&1 != 0 {
+				low = &vm.stack[vm.sp-1]
+				vm.sp--
+			}
+			result, err := slice(vm.stack[vm.sp-1], low, high)
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.stack[vm.sp-1] = result
+			ip += 2
+		case compiler.OpField:
+			s, err := structRef(vm.stack[vm.sp-1])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			i, ok := s.typ.fields[vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])].str()]
+			if !ok {
+				return vm.fail(f, ip, fieldError(s, vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])].str(),
+					vm.constants[int(ins[ip+3])<<8|int(ins[ip+4])].str()))
+			}
+			vm.stack[vm.sp-1] = s.fields[i]
+			ip += 5
+		case compiler.OpSetField:
+			s, err := structRef(vm.stack[vm.sp-1])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			i, ok := s.typ.fields[vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])].str()]
+			if !ok {
+				return vm.fail(f, ip, fieldError(s, vm.constants[int(ins[ip+1])<<8|int(ins[ip+2])].str(),
+					vm.constants[int(ins[ip+3])<<8|int(ins[ip+4])].str()))
+			}
+			s.fields[i] = vm.stack[vm.sp-2]
+			vm.sp -= 2
+			ip += 5
+		case compiler.OpAddress:
+			s, err := structRef(vm.stack[vm.sp-1])
+			if err != nil {
+				return vm.fail(f, ip, err)
+			}
+			vm.stack[vm.sp-1] = value{kind: kindPointer, ref: s}
+			ip++
+		case compiler.OpCopy:
+			vm.stack[vm.sp-1] = copyValue(vm.stack[vm.sp-1])
+			ip++
+		case compiler.OpTuple:
+			n := int(ins[ip+1])
+			elems := make([]value, n)
+			copy(elems, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			vm.push(value{kind: kindTuple, ref: elems})
+			ip += 2
+		case compiler.OpUnpack:
+			n := int(ins[ip+1])
+			v := vm.pop()
+			if v.kind != kindTuple || len(v.elems()) != n {
+				count := 1
+				if v.kind == kindTuple {
+					count = len(v.elems())
+				}
+				return vm.fail(f, ip, newError("assignment mismatch: %d variables but %d values", n, count))
+			}
+			elems := v.elems()
+			for i := n - 1; i >= 0; i-- {
+				vm.push(elems[i])
+			}
+			ip += 2
+		case compiler.OpPrint:
+			fmt.Fprintln(os.Stdout, inspect(vm.pop()))
+			ip++
+		default:
+			return vm.fail(f, ip, newError("unknown opcode %d", op))
+		}
+	}
+}
+
+// recoverPanic stops the panic unwinding through the function whose
+// deferred call f is and returns it as an error. It returns nil if f is
+// not a deferred call or there is no panic.
+func recoverPanic(f *frame) value {
+	if f.deferring == nil || f.deferring.panic == nil {
+		return nilValue
+	}
+	err := f.deferring.panic
+	f.deferring.panic = nil
+	if err.Value != nil {
+		return value{kind: kindError, ref: err.Value.Message}
+	}
+	msg := "runtime error: " + err.Message
+	return value{kind: kindError, ref: &msg}
+}
+
+// method returns the method of recv that sel selects and the receiver to
+// pass it: a pointer to the struct for a pointer receiver, a copy of it for
+// a value receiver. A field that holds a function has no receiver.
+func (vm *VM) method(recv value, name, sel string) (value, value, *object.Error) {
+	if recv.kind == kindError {
+		if name != "Error" {
+			return value{}, value{}, newError("%s undefined (type error has no field or method %s)", sel, name)
+		}
+		if recv.ref.(*string) == nil {
+			return value{}, value{}, newError("invalid memory address or nil pointer dereference")
+		}
+		return value{kind: kindNative, ref: native(errorMethod)}, recv, nil
+	}
+	s, err := structRef(recv)
+	if err != nil {
+		return value{}, value{}, err
+	}
+	m, ok := s.typ.methods[name]
+	if !ok {
+		i, ok := s.typ.fields[name]
+		if !ok {
+			return value{}, value{}, newError("%s undefined (type %s has no field or method %s)", sel, s.typ.def.Name, name)
+		}
+		return s.fields[i], value{}, nil
+	}
+	callee := value{kind: kindClosure, ref: m.closure}
+	if m.pointerReceiver {
+		return callee, value{kind: kindPointer, ref: s}, nil
+	}
+	return callee, value{kind: kindStruct, ref: s.copy()}, nil
+}
+
+// errorMethod is the Error method of errors.
+func errorMethod(vm *VM, args []value) (value, *object.Error) {
+	return stringValue(*args[0].ref.(*string)), nil
+}
@@ -0,0 +1,540 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// valType is a WebAssembly value type, by its binary encoding.
+type valType byte
+
+const (
+	i32 valType = 0x7f
+	i64 valType = 0x7e
+	f64 valType = 0x7c
+)
+
+func (t valType) String() string {
+	switch t {
+	case i32:
+		return "i32"
+	case i64:
+		return "i64"
+	case f64:
+		return "f64"
+	}
+	return "?"
+}
+
+type funcType struct {
+	params, results []valType
+}
+
+func (t funcType) String() string {
+	var s strings.Builder
+	s.WriteString("(func")
+	if len(t.params) > 0 {
+		s.WriteString(" (param")
+		for _, p := range t.params {
+			s.WriteString(" " + p.String())
+		}
+		s.WriteString(")")
+	}
+	for _, r := range t.results {
+		s.WriteString(" (result " + r.String() + ")")
+	}
+	s.WriteString(")")
+	return s.String()
+}
+
+// module is a WebAssembly module with one memory, which it exports along
+// with the function that starts the program. Functions are numbered after
+// the imported ones, in order.
+type module struct {
+	types   []funcType
+	imports []*function // functions imported from WASI, which have no body
+	funcs   []*function
+	globals []global
+	pages   int // the size of the memory, in 64 KiB pages
+	data    []segment
+	start   *function
+}
+
+type function struct {
+	name   string
+	typ    int
+	index  int
+	module string  // for imports, the module they come from
+	locals []local // the parameters first
+	params int
+	body   []instr
+}
+
+type local struct {
+	name string
+	typ  valType
+}
+
+type global struct {
+	name string
+	typ  valType
+}
+
+// segment is data that memory starts out holding at an address.
+type segment struct {
+	offset int
+	init   []byte
+}
+
+// typeIndex returns the index of t in the type section, adding it first
+// if needed.
+func (m *module) typeIndex(t funcType) int {
+	for i, u := range m.types {
+		if t.String() == u.String() {
+			return i
+		}
+	}
+	m.types = append(m.types, t)
+	return len(m.types) - 1
+}
+
+// opcode is an instruction, with the kind of immediate it takes.
+type opcode int
+
+const (
+	opUnreachable opcode = iota
+	opBlock
+	opLoop
+	opIf
+	opElse
+	opEnd
+	opBr
+	opBrIf
+	opReturn
+	opCall
+	opDrop
+	opLocalGet
+	opLocalSet
+	opGlobalGet
+	opGlobalSet
+	opI32Store
+	opI64Load
+	opI64Load8U
+	opI64Store
+	opI64Store8
+	opI32Const
+	opI64Const
+	opF64Const
+	opI32Eqz
+	opI32Eq
+	opI32Ne
+	opI32And
+	opI32Or
+	opI32Xor
+	opI64Eq
+	opI64Ne
+	opI64LtS
+	opI64GtS
+	opI64LeS
+	opI64GeS
+	opF64Eq
+	opF64Ne
+	opF64Lt
+	opF64Gt
+	opF64Le
+	opF64Ge
+	opI64Add
+	opI64Sub
+	opI64Mul
+	opI64DivS
+	opI64RemS
+	opI64And
+	opI64Or
+	opI64Xor
+	opI64Shl
+	opI64ShrS
+	opF64Neg
+	opF64Add
+	opF64Sub
+	opF64Mul
+	opF64Div
+	opI32WrapI64
+	opF64ConvertI64S
+	opI64ReinterpretF64
+	opI64TruncSatF64S
+)
+
+type immediate int
+
+const (
+	immNone   immediate = iota
+	immBlock            // a block type
+	immDepth            // the depth of a branch target
+	immFunc             // a function index
+	immLocal            // a local index
+	immGlobal           // a global index
+	immMemory           // alignment and offset
+	immI32
+	immI64
+	immF64
+)
+
+var opcodes = [...]struct {
+	name  string
+	code  []byte
+	imm   immediate
+	align int // the log2 of the natural alignment of memory accesses
+}{
+	opUnreachable:       {"unreachable", []byte{0x00}, immNone, 0},
+	opBlock:             {"block", []byte{0x02}, immBlock, 0},
+	opLoop:              {"loop", []byte{0x03}, immBlock, 0},
+	opIf:                {"if", []byte{0x04}, immBlock, 0},
+	opElse:              {"else", []byte{0x05}, immNone, 0},
+	opEnd:               {"end", []byte{0x0b}, immNone, 0},
+	opBr:                {"br", []byte{0x0c}, immDepth, 0},
+	opBrIf:              {"br_if", []byte{0x0d}, immDepth, 0},
+	opReturn:            {"return", []byte{0x0f}, immNone, 0},
+	opCall:              {"call", []byte{0x10}, immFunc, 0},
+	opDrop:              {"drop", []byte{0x1a}, immNone, 0},
+	opLocalGet:          {"local.get", []byte{0x20}, immLocal, 0},
+	opLocalSet:          {"local.set", []byte{0x21}, immLocal, 0},
+	opGlobalGet:         {"global.get", []byte{0x23}, immGlobal, 0},
+	opGlobalSet:         {"global.set", []byte{0x24}, immGlobal, 0},
+	opI32Store:          {"i32.store", []byte{0x36}, immMemory, 2},
+	opI64Load:           {"i64.load", []byte{0x29}, immMemory, 3},
+	opI64Load8U:         {"i64.load8_u", []byte{0x31}, immMemory, 0},
+	opI64Store:          {"i64.store", []byte{0x37}, immMemory, 3},
+	opI64Store8:         {"i64.store8", []byte{0x3c}, immMemory, 0},
+	opI32Const:          {"i32.const", []byte{0x41}, immI32, 0},
+	opI64Const:          {"i64.const", []byte{0x42}, immI64, 0},
+	opF64Const:          {"f64.const", []byte{0x44}, immF64, 0},
+	opI32Eqz:            {"i32.eqz", []byte{0x45}, immNone, 0},
+	opI32Eq:             {"i32.eq", []byte{0x46}, immNone, 0},
+	opI32Ne:             {"i32.ne", []byte{0x47}, immNone, 0},
+	opI32And:            {"i32.and", []byte{0x71}, immNone, 0},
+	opI32Or:             {"i32.or", []byte{0x72}, immNone, 0},
+	opI32Xor:            {"i32.xor", []byte{0x73}, immNone, 0},
+	opI64Eq:             {"i64.eq", []byte{0x51}, immNone, 0},
+	opI64Ne:             {"i64.ne", []byte{0x52}, immNone, 0},
+	opI64LtS:            {"i64.lt_s", []byte{0x53}, immNone, 0},
+	opI64GtS:            {"i64.gt_s", []byte{0x55}, immNone, 0},
+	opI64LeS:            {"i64.le_s", []byte{0x57}, immNone, 0},
+	opI64GeS:            {"i64.ge_s", []byte{0x59}, immNone, 0},
+	opF64Eq:             {"f64.eq", []byte{0x61}, immNone, 0},
+	opF64Ne:             {"f64.ne", []byte{0x62}, immNone, 0},
+	opF64Lt:             {"f64.lt", []byte{0x63}, immNone, 0},
+	opF64Gt:             {"f64.gt", []byte{0x64}, immNone, 0},
+	opF64Le:             {"f64.le", []byte{0x65}, immNone, 0},
+	opF64Ge:             {"f64.ge", []byte{0x66}, immNone, 0},
+	opI64Add:            {"i64.add", []byte{0x7c}, immNone, 0},
+	opI64Sub:            {"i64.sub", []byte{0x7d}, immNone, 0},
+	opI64Mul:            {"i64.mul", []byte{0x7e}, immNone, 0},
+	opI64DivS:           {"i64.div_s", []byte{0x7f}, immNone, 0},
+	opI64RemS:           {"i64.rem_s", []byte{0x81}, immNone, 0},
+	opI64And:            {"i64.and", []byte{0x83}, immNone, 0},
+	opI64Or:             {"i64.or", []byte{0x84}, immNone, 0},
+	opI64Xor:            {"i64.xor", []byte{0x85}, immNone, 0},
+	opI64Shl:            {"i64.shl", []byte{0x86}, immNone, 0},
+	opI64ShrS:           {"i64.shr_s", []byte{0x87}, immNone, 0},
+	opF64Neg:            {"f64.neg", []byte{0x9a}, immNone, 0},
+	opF64Add:            {"f64.add", []byte{0xa0}, immNone, 0},
+	opF64Sub:            {"f64.sub", []byte{0xa1}, immNone, 0},
+	opF64Mul:            {"f64.mul", []byte{0xa2}, immNone, 0},
+	opF64Div:            {"f64.div", []byte{0xa3}, immNone, 0},
+	opI32WrapI64:        {"i32.wrap_i64", []byte{0xa7}, immNone, 0},
+	opF64ConvertI64S:    {"f64.convert_i64_s", []byte{0xb9}, immNone, 0},
+	opI64ReinterpretF64: {"i64.reinterpret_f64", []byte{0xbd}, immNone, 0},
+	opI64TruncSatF64S:   {"i64.trunc_sat_f64_s", []byte{0xfc, 0x06}, immNone, 0},
+}
+
+// instr is an instruction and its immediate: int holds indexes, depths and
+// integer constants, and block holds the result of a block, loop or if, or
+// 0 if it has none.
+type instr struct {
+	op    opcode
+	int   int64
+	float float64
+	block valType
+}
+
+// text returns the module in the WebAssembly text format. Blocks are
+// written as flat instructions, indented by their nesting.
+func (m *module) text() string {
+	var out strings.Builder
+	out.WriteString("(module\n")
+	for i, t := range m.types {
+		fmt.Fprintf(&out, "  (type (;%d;) %s)\n", i, t)
+	}
+	for _, fn := range m.imports {
+		fmt.Fprintf(&out, "  (import %q %q (func $%s (type %d)))\n", fn.module, fn.name, fn.name, fn.typ)
+	}
+	fmt.Fprintf(&out, "  (memory (export \"memory\") %d)\n", m.pages)
+	for _, g := range m.globals {
+		fmt.Fprintf(&out, "  (global $%s (mut %s) (%s.const 0))\n", g.name, g.typ, g.typ)
+	}
+	for _, fn := range m.funcs {
+		m.funcText(&out, fn)
+	}
+	fmt.Fprintf(&out, "  (export \"_start\" (func $%s))\n", m.start.name)
+	for _, s := range m.data {
+		fmt.Fprintf(&out, "  (data (i32.const %d) \"%s\")\n", s.offset, escape(s.init))
+	}
+	out.WriteString(")\n")
+	return out.String()
+}
+
+func (m *module) funcText(out *strings.Builder, fn *function) {
+	t := m.types[fn.typ]
+	fmt.Fprintf(out, "  (func $%s (type %d)", fn.name, fn.typ)
+	for _, p := range fn.locals[:fn.params] {
+		fmt.Fprintf(out, " (param $%s %s)", p.name, p.typ)
+	}
+	for _, r := range t.results {
+		fmt.Fprintf(out, " (result %s)", r)
+	}
+	out.WriteString("\n")
+	for _, l := range fn.locals[fn.params:] {
+		fmt.Fprintf(out, "    (local $%s %s)\n", l.name, l.typ)
+	}
+	depth := 2
+	for _, in := range fn.body {
+		info := opcodes[in.op]
+		if in.op == opEnd || in.op == opElse {
+			depth--
+		}
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(info.name)
+		switch info.imm {
+		case immBlock:
+			if in.block != 0 {
+				fmt.Fprintf(out, " (result %s)", in.block)
+			}
+		case immDepth, immI32, immI64:
+			fmt.Fprintf(out, " %d", in.int)
+		case immFunc:
+			out.WriteString(" $" + m.function(int(in.int)).name)
+		case immLocal:
+			out.WriteString(" $" + fn.locals[in.int].name)
+		case immGlobal:
+			out.WriteString(" $" + m.globals[in.int].name)
+		case immF64:
+			out.WriteString(" " + floatText(in.float))
+		}
+		out.WriteString("\n")
+		if info.imm == immBlock || in.op == opElse {
+			depth++
+		}
+	}
+	out.WriteString("  )\n")
+}
+
+// function returns the function with the given index.
+func (m *module) function(index int) *function {
+	if index < len(m.imports) {
+		return m.imports[index]
+	}
+	return m.funcs[index-len(m.imports)]
+}
+
+// floatText formats v for the text format: exactly, in the shortest
+// decimal form that reads back as v, and NaNs with their payload.
+func floatText(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	case math.IsNaN(v):
+		bits := math.Float64bits(v)
+		sign := ""
+		if bits>>63 != 0 {
+			sign = "-"
+		}
+		return fmt.Sprintf("%snan:0x%x", sign, bits&(1<<52-1))
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escape quotes data for a string of the text format. Bytes outside
+// printable ASCII are written as two hex digits.
+func escape(data []byte) string {
+	var out strings.Builder
+	for _, b := range data {
+		if b < 0x20 || b >= 0x7f || b == '"' || b == '\\' {
+			fmt.Fprintf(&out, "\\%02x", b)
+		} else {
+			out.WriteByte(b)
+		}
+	}
+	return out.String()
+}
+
+// encode returns the binary encoding of the module.
+func (m *module) encode() []byte {
+	out := []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+	var types []byte
+	types = uleb(types, uint64(len(m.types)))
+	for _, t := range m.types {
+		types = append(types, 0x60)
+		types = vector(types, t.params)
+		types = vector(types, t.results)
+	}
+	out = section(out, 1, types)
+
+	var imports []byte
+	imports = uleb(imports, uint64(len(m.imports)))
+	for _, fn := range m.imports {
+		imports = name(imports, fn.module)
+		imports = name(imports, fn.name)
+		imports = append(imports, 0x00)
+		imports = uleb(imports, uint64(fn.typ))
+	}
+	out = section(out, 2, imports)
+
+	var funcs []byte
+	funcs = uleb(funcs, uint64(len(m.funcs)))
+	for _, fn := range m.funcs {
+		funcs = uleb(funcs, uint64(fn.typ))
+	}
+	out = section(out, 3, funcs)
+
+	memory := []byte{1, 0x00}
+	memory = uleb(memory, uint64(m.pages))
+	out = section(out, 5, memory)
+
+	var globals []byte
+	globals = uleb(globals, uint64(len(m.globals)))
+	for _, g := range m.globals {
+		globals = append(globals, byte(g.typ), 0x01)
+		switch g.typ {
+		case i32:
+			globals = append(globals, 0x41, 0x00)
+		case i64:
+			globals = append(globals, 0x42, 0x00)
+		case f64:
+			globals = append(globals, 0x44, 0, 0, 0, 0, 0, 0, 0, 0)
+		}
+		globals = append(globals, 0x0b)
+	}
+	out = section(out, 6, globals)
+
+	exports := []byte{2}
+	exports = name(exports, "memory")
+	exports = append(exports, 0x02, 0x00)
+	exports = name(exports, "_start")
+	exports = append(exports, 0x00)
+	exports = uleb(exports, uint64(m.start.index))
+	out = section(out, 7, exports)
+
+	var code []byte
+	code = uleb(code, uint64(len(m.funcs)))
+	for _, fn := range m.funcs {
+		body := m.encodeBody(fn)
+		code = uleb(code, uint64(len(body)))
+		code = append(code, body...)
+	}
+	out = section(out, 10, code)
+
+	var data []byte
+	data = uleb(data, uint64(len(m.data)))
+	for _, s := range m.data {
+		data = append(data, 0x00, 0x41)
+		data = sleb(data, int64(s.offset))
+		data = append(data, 0x0b)
+		data = uleb(data, uint64(len(s.init)))
+		data = append(data, s.init...)
+	}
+	return section(out, 11, data)
+}
+
+// encodeBody encodes the locals and code of fn. Locals are declared in
+// runs of the same type.
+func (m *module) encodeBody(fn *function) []byte {
+	type run struct {
+		n   int
+		typ valType
+	}
+	var runs []run
+	for _, l := range fn.locals[fn.params:] {
+		if len(runs) > 0 && runs[len(runs)-1].typ == l.typ {
+			runs[len(runs)-1].n++
+		} else {
+			runs = append(runs, run{1, l.typ})
+		}
+	}
+	var out []byte
+	out = uleb(out, uint64(len(runs)))
+	for _, r := range runs {
+		out = uleb(out, uint64(r.n))
+		out = append(out, byte(r.typ))
+	}
+	for _, in := range fn.body {
+		info := opcodes[in.op]
+		out = append(out, info.code...)
+		switch info.imm {
+		case immBlock:
+			if in.block == 0 {
+				out = append(out, 0x40)
+			} else {
+				out = append(out, byte(in.block))
+			}
+		case immDepth, immFunc, immLocal, immGlobal:
+			out = uleb(out, uint64(in.int))
+		case immMemory:
+			out = uleb(out, uint64(info.align))
+			out = uleb(out, 0)
+		case immI32, immI64:
+			out = sleb(out, in.int)
+		case immF64:
+			out = binary.LittleEndian.AppendUint64(out, math.Float64bits(in.float))
+		}
+	}
+	return append(out, 0x0b)
+}
+
+func section(out []byte, id byte, contents []byte) []byte {
+	out = append(out, id)
+	out = uleb(out, uint64(len(contents)))
+	return append(out, contents...)
+}
+
+func vector(out []byte, types []valType) []byte {
+	out = uleb(out, uint64(len(types)))
+	for _, t := range types {
+		out = append(out, byte(t))
+	}
+	return out
+}
+
+func name(out []byte, s string) []byte {
+	out = uleb(out, uint64(len(s)))
+	return append(out, s...)
+}
+
+// uleb appends v in unsigned LEB128.
+func uleb(out []byte, v uint64) []byte {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+// sleb appends v in signed LEB128.
+func sleb(out []byte, v int64) []byte {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 && b&0x40 == 0 || v == -1 && b&0x40 != 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,405 @@
+// Package wasm generates WebAssembly modules from programs, in both the
+// text format (.wat) and the binary encoding (.wasm), without an external
+// toolchain. The modules follow WASI: they import fd_write and proc_exit
+// from wasi_snapshot_preview1 and export _start and their memory, so any
+// WASI runtime can run them, including a browser with a WASI shim.
+//
+// Programs are lowered to package ir, which brings its own runtime for
+// printing, and then translated statement by statement: ints are i64,
+// floats f64 and bools i32, and strings are i64 addresses in memory.
+// The first 16 bytes of memory hold the arguments and result of fd_write;
+// the runtime's data follows.
+package wasm
+
+import (
+	"fmt"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/ir"
+	"golite.dev/mvp/internal/semantics"
+)
+
+// dataStart is the address of the first data; below it is the scratch
+// memory of fd_write.
+const dataStart = 16
+
+// Generator is the WebAssembly code generator.
+type Generator struct {
+	errors []*diagnostics.Diagnostic
+	module *module
+
+	funcs   map[*ir.Function]*function
+	globals map[*ir.Var]int
+	addrs   map[*ir.Data]int
+	write   *function // the WASI imports
+	exit    *function
+
+	fn     *function // the function being generated
+	locals map[*ir.Var]int
+	labels []label // the blocks around the current instruction, innermost last
+}
+
+// label is what a block is for, which decides where break and continue
+// branch to.
+type label int
+
+const (
+	labelOther label = iota
+	labelBreak
+	labelContinue
+	labelLoop
+)
+
+// New creates a new WebAssembly code generator.
+func New() *Generator {
+	return &Generator{}
+}
+
+// Errors returns the diagnostics for programs the WebAssembly backend
+// cannot lower.
+func (g *Generator) Errors() []*diagnostics.Diagnostic {
+	return g.errors
+}
+
+// Generate takes an AST program and returns an equivalent module in the
+// text format; Binary returns its binary encoding afterwards. The program
+// is type-checked and lowered to package ir first; if either fails,
+// Generate returns "" and Errors reports why.
+func (g *Generator) Generate(program *ast.Program) string {
+	checker := semantics.New()
+	checker.Check(program)
+	if len(checker.Errors()) > 0 {
+		g.errors = append(g.errors, checker.Errors()...)
+		return ""
+	}
+	reserved := append(ir.RuntimeNames(), "fd_write", "proc_exit")
+	prog, errs := ir.Lower(program, checker, "WebAssembly", reserved...)
+	if len(errs) > 0 {
+		g.errors = append(g.errors, errs...)
+		return ""
+	}
+	ir.AddRuntime(prog)
+
+	g.module = &module{}
+	g.funcs = map[*ir.Function]*function{}
+	g.globals = map[*ir.Var]int{}
+	g.addrs = map[*ir.Data]int{}
+	g.write = g.importWASI("fd_write", funcType{[]valType{i32, i32, i32, i32}, []valType{i32}})
+	g.exit = g.importWASI("proc_exit", funcType{[]valType{i32}, nil})
+
+	addr := dataStart
+	for _, d := range prog.Data {
+		g.addrs[d] = addr
+		if len(d.Init) > 0 {
+			g.module.data = append(g.module.data, segment{addr, d.Init})
+		}
+		addr += (d.Size + 7) &^ 7
+	}
+	g.module.pages = (addr + 0xffff) / 0x10000
+
+	for _, v := range prog.Globals {
+		g.globals[v] = len(g.module.globals)
+		g.module.globals = append(g.module.globals, global{v.Name, wasmType(v.Type)})
+	}
+	for _, fn := range prog.Functions {
+		t := funcType{}
+		for _, p := range fn.Params {
+			t.params = append(t.params, wasmType(p.Type))
+		}
+		if fn.Result != ir.Void {
+			t.results = []valType{wasmType(fn.Result)}
+		}
+		f := &function{name: fn.Name, typ: g.module.typeIndex(t), index: len(g.module.imports) + len(g.module.funcs)}
+		g.funcs[fn] = f
+		g.module.funcs = append(g.module.funcs, f)
+	}
+	g.module.start = g.funcs[prog.Main]
+	for _, fn := range prog.Functions {
+		g.function(fn)
+	}
+	return g.module.text()
+}
+
+// Binary returns the binary encoding of the module that Generate made, or
+// nil if it made none.
+func (g *Generator) Binary() []byte {
+	if g.module == nil {
+		return nil
+	}
+	return g.module.encode()
+}
+
+func (g *Generator) importWASI(name string, t funcType) *function {
+	f := &function{name: name, typ: g.module.typeIndex(t), index: len(g.module.imports), module: "wasi_snapshot_preview1"}
+	g.module.imports = append(g.module.imports, f)
+	return f
+}
+
+// wasmType returns the WebAssembly type of values of type t.
+func wasmType(t ir.Type) valType {
+	switch t {
+	case ir.Float:
+		return f64
+	case ir.Bool:
+		return i32
+	default:
+		return i64
+	}
+}
+
+// function generates the body of fn. Variables of different scopes can
+// share a name, so names that repeat get a number.
+func (g *Generator) function(fn *ir.Function) {
+	g.fn, g.locals = g.funcs[fn], map[*ir.Var]int{}
+	used := map[string]bool{}
+	for _, v := range append(append([]*ir.Var{}, fn.Params...), fn.Locals...) {
+		name := v.Name
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s.%d", v.Name, i)
+		}
+		used[name] = true
+		g.locals[v] = len(g.fn.locals)
+		g.fn.locals = append(g.fn.locals, local{name, wasmType(v.Type)})
+	}
+	g.fn.params = len(fn.Params)
+	g.statements(fn.Body)
+	if n := len(fn.Body); fn.Result != ir.Void && (n == 0 || !isReturn(fn.Body[n-1])) {
+		// The body returns on every path, but validation cannot tell.
+		g.op(opUnreachable)
+	}
+}
+
+func isReturn(s ir.Stmt) bool {
+	_, ok := s.(*ir.Return)
+	return ok
+}
+
+func (g *Generator) op(op opcode) {
+	g.fn.body = append(g.fn.body, instr{op: op})
+}
+
+func (g *Generator) imm(op opcode, v int64) {
+	g.fn.body = append(g.fn.body, instr{op: op, int: v})
+}
+
+// begin starts a block, loop or if of the given kind.
+func (g *Generator) begin(op opcode, l label, result valType) {
+	g.fn.body = append(g.fn.body, instr{op: op, block: result})
+	g.labels = append(g.labels, l)
+}
+
+func (g *Generator) end() {
+	g.op(opEnd)
+	g.labels = g.labels[:len(g.labels)-1]
+}
+
+// branch emits a br or br_if to the innermost block of the given kind.
+func (g *Generator) branch(op opcode, l label) {
+	for i := len(g.labels) - 1; i >= 0; i-- {
+		if g.labels[i] == l {
+			g.imm(op, int64(len(g.labels)-1-i))
+			return
+		}
+	}
+}
+
+func (g *Generator) statements(stmts []ir.Stmt) {
+	for _, stmt := range stmts {
+		g.statement(stmt)
+	}
+}
+
+func (g *Generator) statement(stmt ir.Stmt) {
+	switch s := stmt.(type) {
+	case *ir.Assign:
+		g.expression(s.Value)
+		g.store(s.Var)
+	case *ir.Eval:
+		g.expression(s.X)
+		if s.X.Type() != ir.Void {
+			g.op(opDrop)
+		}
+	case *ir.If:
+		g.expression(s.Cond)
+		g.begin(opIf, labelOther, 0)
+		g.statements(s.Then)
+		if len(s.Else) > 0 {
+			g.op(opElse)
+			g.statements(s.Else)
+		}
+		g.end()
+	case *ir.Loop:
+		g.begin(opBlock, labelBreak, 0)
+		g.begin(opLoop, labelLoop, 0)
+		if s.Cond != nil {
+			g.expression(s.Cond)
+			g.op(opI32Eqz)
+			g.branch(opBrIf, labelBreak)
+		}
+		g.begin(opBlock, labelContinue, 0)
+		g.statements(s.Body)
+		g.end()
+		g.statements(s.Post)
+		g.branch(opBr, labelLoop)
+		g.end()
+		g.end()
+	case *ir.Break:
+		g.branch(opBr, labelBreak)
+	case *ir.Continue:
+		g.branch(opBr, labelContinue)
+	case *ir.Return:
+		if s.Value != nil {
+			g.expression(s.Value)
+		}
+		g.op(opReturn)
+	case *ir.Poke:
+		g.address(s.Addr)
+		g.expression(s.Value)
+		if s.Size == 1 {
+			g.op(opI64Store8)
+		} else {
+			g.op(opI64Store)
+		}
+	case *ir.Write:
+		// fd_write takes a list of buffers, here the one at address 0,
+		// and stores the number of bytes written at address 8.
+		g.imm(opI32Const, 0)
+		g.address(s.Addr)
+		g.op(opI32Store)
+		g.imm(opI32Const, 4)
+		g.address(s.Len)
+		g.op(opI32Store)
+		g.imm(opI32Const, int64(s.FD))
+		g.imm(opI32Const, 0)
+		g.imm(opI32Const, 1)
+		g.imm(opI32Const, 8)
+		g.imm(opCall, int64(g.write.index))
+		g.op(opDrop)
+	case *ir.Exit:
+		g.imm(opI32Const, int64(s.Code))
+		g.imm(opCall, int64(g.exit.index))
+		g.op(opUnreachable)
+	}
+}
+
+func (g *Generator) store(v *ir.Var) {
+	if v.Global {
+		g.imm(opGlobalSet, int64(g.globals[v]))
+	} else {
+		g.imm(opLocalSet, int64(g.locals[v]))
+	}
+}
+
+// address computes e, an Int, as a memory address, which is an i32.
+func (g *Generator) address(e ir.Expr) {
+	g.expression(e)
+	g.op(opI32WrapI64)
+}
+
+func (g *Generator) expression(e ir.Expr) {
+	switch e := e.(type) {
+	case *ir.Const:
+		switch e.T {
+		case ir.Float:
+			g.fn.body = append(g.fn.body, instr{op: opF64Const, float: e.Float})
+		case ir.Bool:
+			g.imm(opI32Const, e.Int)
+		default:
+			g.imm(opI64Const, e.Int)
+		}
+	case *ir.Load:
+		if e.Var.Global {
+			g.imm(opGlobalGet, int64(g.globals[e.Var]))
+		} else {
+			g.imm(opLocalGet, int64(g.locals[e.Var]))
+		}
+	case *ir.Unary:
+		switch {
+		case e.Op == ir.Not:
+			g.expression(e.X)
+			g.op(opI32Eqz)
+		case e.X.Type() == ir.Float:
+			g.expression(e.X)
+			g.op(opF64Neg)
+		default:
+			g.imm(opI64Const, 0)
+			g.expression(e.X)
+			g.op(opI64Sub)
+		}
+	case *ir.Binary:
+		g.binary(e)
+	case *ir.Call:
+		for _, arg := range e.Args {
+			g.expression(arg)
+		}
+		g.imm(opCall, int64(g.funcs[e.Func].index))
+	case *ir.Convert:
+		g.expression(e.X)
+		if e.To == ir.Float {
+			g.op(opF64ConvertI64S)
+		} else {
+			g.op(opI64TruncSatF64S)
+		}
+	case *ir.Addr:
+		g.imm(opI64Const, int64(g.addrs[e.Data])+e.Offset)
+	case *ir.Peek:
+		g.address(e.Addr)
+		if e.Size == 1 {
+			g.op(opI64Load8U)
+		} else {
+			g.op(opI64Load)
+		}
+	case *ir.Bits:
+		g.expression(e.X)
+		g.op(opI64ReinterpretF64)
+	}
+}
+
+var intOps = map[ir.Op]opcode{
+	ir.Add: opI64Add, ir.Sub: opI64Sub, ir.Mul: opI64Mul, ir.Div: opI64DivS, ir.Rem: opI64RemS,
+	ir.And: opI64And, ir.Or: opI64Or, ir.Xor: opI64Xor, ir.Shl: opI64Shl, ir.Shr: opI64ShrS,
+	ir.Eq: opI64Eq, ir.Ne: opI64Ne, ir.Lt: opI64LtS, ir.Le: opI64LeS, ir.Gt: opI64GtS, ir.Ge: opI64GeS,
+}
+
+var floatOps = map[ir.Op]opcode{
+	ir.Add: opF64Add, ir.Sub: opF64Sub, ir.Mul: opF64Mul, ir.Div: opF64Div,
+	ir.Eq: opF64Eq, ir.Ne: opF64Ne, ir.Lt: opF64Lt, ir.Le: opF64Le, ir.Gt: opF64Gt, ir.Ge: opF64Ge,
+}
+
+var boolOps = map[ir.Op]opcode{
+	ir.Eq: opI32Eq, ir.Ne: opI32Ne, ir.And: opI32And, ir.Or: opI32Or, ir.Xor: opI32Xor,
+}
+
+// binary emits e. The runtime has already replaced the operators that can
+// stop the program where they might, so the rest map to instructions.
+func (g *Generator) binary(e *ir.Binary) {
+	switch e.Op {
+	case ir.LogAnd:
+		g.expression(e.X)
+		g.begin(opIf, labelOther, i32)
+		g.expression(e.Y)
+		g.op(opElse)
+		g.imm(opI32Const, 0)
+		g.end()
+		return
+	case ir.LogOr:
+		g.expression(e.X)
+		g.begin(opIf, labelOther, i32)
+		g.imm(opI32Const, 1)
+		g.op(opElse)
+		g.expression(e.Y)
+		g.end()
+		return
+	}
+	g.expression(e.X)
+	g.expression(e.Y)
+	switch e.X.Type() {
+	case ir.Float:
+		g.op(floatOps[e.Op])
+	case ir.Bool:
+		g.op(boolOps[e.Op])
+	default:
+		g.op(intOps[e.Op])
+	}
+}
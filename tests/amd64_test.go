@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/amd64"
+	"golite.dev/mvp/internal/diagnostics"
+)
+
+func generateAmd64(t *testing.T, input string) string {
+	t.Helper()
+	generator := amd64.New()
+	code := generator.Generate(parse(input))
+	if len(generator.Errors()) != 0 {
+		t.Fatalf("%q: unexpected codegen errors: %v", input, generator.Errors())
+	}
+	return code
+}
+
+// amd64Linker returns a function that assembles and links a program into
+// an executable in dir and returns its path, or skips the test if the
+// executables cannot be built or run here.
+func amd64Linker(t *testing.T, dir string) func(name, input string) string {
+	t.Helper()
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("x86-64 Linux executables do not run on " + runtime.GOOS + "/" + runtime.GOARCH)
+	}
+	as, err := exec.LookPath("as")
+	if err != nil {
+		t.Skip("as not available")
+	}
+	ld, err := exec.LookPath("ld")
+	if err != nil {
+		t.Skip("ld not available")
+	}
+	return func(name, input string) string {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file+".s", []byte(generateAmd64(t, input)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command(as, "-o", file+".o", file+".s").CombinedOutput(); err != nil {
+			t.Fatalf("%s: as failed: %v\n%s", name, err, out)
+		}
+		if out, err := exec.Command(ld, "-o", file, file+".o").CombinedOutput(); err != nil {
+			t.Fatalf("%s: ld failed: %v\n%s", name, err, out)
+		}
+		return file
+	}
+}
+
+// TestAmd64MatchesEvaluator runs the generated executables and checks that
+// they print what the evaluator prints.
+func TestAmd64MatchesEvaluator(t *testing.T) {
+	link := amd64Linker(t, t.TempDir())
+	for i, input := range nativePrograms {
+		out, err := exec.Command(link(fmt.Sprintf("prog%d", i), input)).Output()
+		if err != nil {
+			t.Errorf("nativePrograms[%d]: running the executable failed: %v", i, err)
+			continue
+		}
+		if expected := evalOutput(input); string(out) != expected {
+			t.Errorf("nativePrograms[%d]: executable output %q differs from evaluator output %q", i, out, expected)
+		}
+	}
+}
+
+func TestAmd64RuntimeErrors(t *testing.T) {
+	link := amd64Linker(t, t.TempDir())
+	for i, tt := range nativeRuntimeErrors {
+		cmd := exec.Command(link(fmt.Sprintf("prog%d", i), tt.input))
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		cmd.Run()
+		if cmd.ProcessState.ExitCode() != 1 || strings.TrimSpace(stderr.String()) != tt.expected {
+			t.Errorf("%q: got exit code %d and %q, want 1 and %q", tt.input, cmd.ProcessState.ExitCode(), stderr.String(), tt.expected)
+		}
+	}
+}
+
+// TestAmd64Golden compares the assembly for a small program with the one
+// in testdata. Run the test with -update to rewrite the file.
+func TestAmd64Golden(t *testing.T) {
+	input := `let fib = func(n) { if n < 2 { return n } return fib(n-1) + fib(n-2) };
+	let total = 0;
+	for let i = 0; i < 10; i++ { if i % 2 == 0 && i > 0 { continue } total += fib(i) }
+	print total; print total > 50; print float(total) / 4.0; print "done";`
+	checkGolden(t, filepath.Join("testdata", "fib.s"), generateAmd64(t, input))
+}
+
+func TestAmd64Unsupported(t *testing.T) {
+	input := `let f = func() { let x = 1; let g = func() { x }; g() }; print f();`
+	expected := "the x86-64 backend does not support closures; x belongs to an enclosing function"
+	generator := amd64.New()
+	if code := generator.Generate(parse(input)); code != "" {
+		t.Errorf("%q: expected no code", input)
+	}
+	errs := generator.Errors()
+	if len(errs) != 1 || errs[0].Code != diagnostics.Unsupported || errs[0].Message != expected {
+		t.Errorf("%q: got %v, want one %s error %q", input, errs, diagnostics.Unsupported, expected)
+	}
+}
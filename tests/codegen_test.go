@@ -1,11 +1,20 @@
 package tests
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"golite.dev/mvp/internal/codegen"
+	"golite.dev/mvp/internal/evaluator"
 	"golite.dev/mvp/internal/lexer"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/optimizer"
 	"golite.dev/mvp/internal/parser"
 )
 
@@ -33,14 +42,14 @@ func TestCCodeGen(t *testing.T) {
 		"#include <stdio.h>",
 		"#include <stdint.h>",
 		"int main() {",
-		"int64_t x = 10;",
-		"int64_t y = 20;",
-		"int64_t z = (x + y);",
+		"int64_t x = INT64_C(10);",
+		"int64_t y = INT64_C(20);",
+		"int64_t z = ((int64_t)((uint64_t)(x) + (uint64_t)(y)));",
 		"printf(\"%lld\\n\", (long long)(z));",
-		"if ((z > 25)) {",
-		"printf(\"%lld\\n\", (long long)(1));",
+		"if ((z > INT64_C(25))) {",
+		"printf(\"%lld\\n\", (long long)(INT64_C(1)));",
 		"} else {",
-		"printf(\"%lld\\n\", (long long)(0));",
+		"printf(\"%lld\\n\", (long long)(INT64_C(0)));",
 		"}",
 		"return 0;",
 	}
@@ -52,3 +61,319 @@ func TestCCodeGen(t *testing.T) {
 		}
 	}
 }
+
+func TestCCodeGenLoops(t *testing.T) {
+	input := `
+	let sum = 0;
+	for let i = 0; i < 10; i++ {
+		if (i > 5) { break; }
+		sum += i;
+		continue;
+	}
+	sum = sum * 2;
+	for true { break; }
+	for { break; }
+	`
+	program := parse(input)
+	cCode := codegen.New().Generate(program)
+
+	expectedSnippets := []string{
+		"    for (int64_t i = INT64_C(0); (i < INT64_C(10)); i = ((int64_t)((uint64_t)(i) + (uint64_t)(1)))) {\n",
+		"        sum = ((int64_t)((uint64_t)(sum) + (uint64_t)(i)));\n",
+		"    sum = ((int64_t)((uint64_t)(sum) * (uint64_t)(INT64_C(2))));\n",
+		"        if ((i > INT64_C(5))) {\n            break;\n        }\n",
+		"        continue;\n",
+		"    while (true) {\n",
+		"    for (; ; ) {\n",
+	}
+
+	for _, snippet := range expectedSnippets {
+		if !strings.Contains(cCode, snippet) {
+			t.Errorf("Generated C code did not contain expected snippet: %q", snippet)
+			t.Logf("Full generated code:\n%s", cCode)
+		}
+	}
+}
+
+func TestCCodeGenFunctions(t *testing.T) {
+	input := `
+	let fib = func(n) {
+		if n < 2 { return n }
+		return fib(n-1) + fib(n-2)
+	};
+	let base = 100;
+	let addBase = func(x) { x + base };
+	let counter = func() {
+		let count = 0;
+		let inc = func() { count++; };
+		inc();
+		count
+	};
+	print fib(10);
+	`
+	program := parse(input)
+	generator := codegen.New()
+	cCode := generator.Generate(program)
+	if len(generator.Errors()) != 0 {
+		t.Fatalf("unexpected codegen errors: %v", generator.Errors())
+	}
+
+	expectedSnippets := []string{
+		"int64_t fib(int64_t n);",
+		"int64_t base;",
+		"int64_t fib(int64_t n) {\n    if ((n < INT64_C(2))) {\n        return n;\n    }\n    return ((int64_t)((uint64_t)(fib(((int64_t)((uint64_t)(n) - (uint64_t)(INT64_C(1)))))) + (uint64_t)(fib(((int64_t)((uint64_t)(n) - (uint64_t)(INT64_C(2))))))));\n}",
+		"int64_t addBase(int64_t x) {\n    return ((int64_t)((uint64_t)(x) + (uint64_t)(base)));\n}",
+		"struct counter__inc_env {\n    int64_t *count;\n};",
+		"static void counter__inc(struct counter__inc_env *env) {\n    (*env->count) = ((int64_t)((uint64_t)((*env->count)) + (uint64_t)(1)));\n}",
+		"    struct counter__inc_env counter__inc_env = { &count };\n    counter__inc(&counter__inc_env);\n    return count;\n",
+		"    base = INT64_C(100);\n",
+		"printf(\"%lld\\n\", (long long)(fib(INT64_C(10))));",
+	}
+
+	for _, snippet := range expectedSnippets {
+		if !strings.Contains(cCode, snippet) {
+			t.Errorf("Generated C code did not contain expected snippet: %q", snippet)
+			t.Logf("Full generated code:\n%s", cCode)
+		}
+	}
+}
+
+func TestCCodeGenInferredTypes(t *testing.T) {
+	input := `
+	let isPositive = func(n) { n > 0 };
+	let flag = isPositive(3);
+	let report = func(b) { if b { print 1; return } print 0; };
+	report(flag);
+	print flag;
+	`
+	generator := codegen.New()
+	cCode := generator.Generate(parse(input))
+	if len(generator.Errors()) != 0 {
+		t.Fatalf("unexpected codegen errors: %v", generator.Errors())
+	}
+
+	expectedSnippets := []string{
+		"bool isPositive(int64_t n) {\n    return (n > INT64_C(0));\n}",
+		"void report(bool b) {",
+		"        return;\n",
+		"    bool flag = isPositive(INT64_C(3));\n",
+		"printf(\"%s\\n\", (flag) ? \"true\" : \"false\");",
+	}
+	for _, snippet := range expectedSnippets {
+		if !strings.Contains(cCode, snippet) {
+			t.Errorf("Generated C code did not contain expected snippet: %q", snippet)
+			t.Logf("Full generated code:\n%s", cCode)
+		}
+	}
+}
+
+// TestCCodeGenNegativeLiterals checks that the negative literals constant
+// folding leaves behind cannot merge with the operator before them.
+func TestCCodeGenNegativeLiterals(t *testing.T) {
+	input := `print -(1 - 3); print -(0.5 - 3.0); print 0 - 9223372036854775807 - 1;`
+	program := optimizer.Optimize(parse(input), optimizer.Config{EnabledPasses: optimizer.ConstantFolding})
+	cCode := codegen.New().Generate(program)
+	for _, snippet := range []string{"(long long)(INT64_C(2))", "(-(-2.5))", "INT64_MIN"} {
+		if !strings.Contains(cCode, snippet) {
+			t.Errorf("Generated C code did not contain expected snippet: %q", snippet)
+			t.Logf("Full generated code:\n%s", cCode)
+		}
+	}
+}
+
+func TestCCodeGenReportsTypeErrors(t *testing.T) {
+	generator := codegen.New()
+	cCode := generator.Generate(parse(`let f = func(x) { x + 1 }; print f(true);`))
+	if cCode != "" || len(generator.Errors()) != 1 {
+		t.Fatalf("expected one type error and no code, got %d errors", len(generator.Errors()))
+	}
+}
+
+func TestCCodeGenUnsupportedFunctionValues(t *testing.T) {
+	input := `let f = func(x) { x }; let g = func(h) { h(1) }; print g(f);`
+	generator := codegen.New()
+	generator.Generate(parse(input))
+
+	if len(generator.Errors()) != 2 {
+		t.Fatalf("expected 2 codegen errors, got %d: %v", len(generator.Errors()), generator.Errors())
+	}
+}
+
+// backendPrograms are programs that every backend must run the way the
+// evaluator does.
+var backendPrograms = []string{
+	`let fib = func(n) { if n < 2 { return n } return fib(n-1) + fib(n-2) }; print fib(15);`,
+	`let sum = 0; for let i = 0; i < 10; i++ { sum += i; } print sum;`,
+	`let makeCounter = func(start) {
+		let count = start;
+		let step = func(by) { count += by; return count; };
+		let twice = func() { step(1); step(1) };
+		twice();
+		count
+	};
+	print makeCounter(10);`,
+	`let k = 3; let scale = func(x) { x * k }; k = 4; print scale(5);`,
+	`let base = 100; if (base > 1) { let add = func(x) { x + base }; print add(5); }`,
+	`print func(a, b) { a * b }(6, 7);`,
+	`let x = 1; let x = 2; print x;`,
+	`let pick = func(c) { if (c) { 1 } else { 2 } }; print pick(true); print pick(false);`,
+	`let id = func(x) { x }; print id(7); print id(false); print 3 > 2;`,
+	`let isEven = func(n) { n / 2 * 2 == n }; print isEven(4); print isEven(7);`,
+	`let label = func(name, n) { if n > 1 { return name + "s" } name }; let s = label("apple", 3); s += "!"; print s; print len(s); print label("pear", 1) == "pear";`,
+	`print "tab\there \"quoted\" back\\slash?? \u00e9";`,
+	`let area = func(r) { 3.14159 * r * r }; print area(2.0); print area(0.001); let x = 100.0; x /= 3.0; print x;`,
+	`print 1e6; print 1234567.0; print 123456.0; print 0.0001; print 0.00001; print 1e21; print -0.5 * 2.0; print 0.1 + 0.2;`,
+	`let calls = 0; let yes = func() { calls++; true }; print false && yes(); print true || yes(); print true && yes(); print calls;`,
+	`let n = 1 << 10 | 5; print n % 7; print -n % 7; print n & 255; print n ^ 1; print -n >> 3; print n <= 1029 && n >= 1029; print 0.5 <= 0.25 || n > 0;`,
+	`print float(7) / 2.0; print int(3.9); print int(-3.9); print 1.0 / 0.0; print -1.0 / 0.0; print 2.5 > 1.0;`,
+	`let sort = func(a) { for let i = 1; i < len(a); i++ { for let j = i; j > 0 && a[j-1] > a[j]; j-- { let t = a[j]; a[j] = a[j-1]; a[j-1] = t; } } a };
+	print sort([9, 3, 7, 1, 8]); let a = [4, 4]; a[1] += 2; a[0]++; print a; print len(a[1:]);`,
+	`let a = append([1, 2], 3); let b = a[:2]; let c = append(b, 9); print a; print c; let d = append(c, 10); d[0] = 0; print a; print d; let e = []; print e; print append(e, true);`,
+	`let grid = [[1, 2], [3]]; grid[1] = append(grid[1], 4); print grid; print [1.5, 0.1 + 0.2]; let s = "hello"; print s[1:3]; print [s, s[3:]]; print s[:1] + s[4:];`,
+	`let counts = map[string]int{"z": 0}; let words = ["b", "a", "c", "a", "b", "a"];
+	for let i = 0; i < len(words); i++ { counts[words[i]] += 1; } counts["z"]++;
+	print counts; delete(counts, "b"); print len(counts); n, ok := counts["a"]; print n; print ok;
+	let sum = func() { let ks = keys(counts); let total = 0; for let i = 0; i < len(ks); i++ { total += counts[ks[i]]; } total };
+	print sum(); m, ok := counts["q"]; print m; print ok;
+	let check = func(k) { v, found := counts[k]; ok = found; v }; print check("c"); print ok;`,
+	`let grid = map[int]map[string]float{1: map[string]float{"x": 0.5}}; grid[2] = map[string]float{}; grid[2]["y"] = 1e21; grid[1]["x"] *= 3.0;
+	print grid; print grid[3]["x"]; print map[bool][]int{true: [1, 2]}; let names = map[float]string{}; names[-0.0] = "zero"; names[0.0] += "!"; print names;`,
+	`let big = map[int]int{}; for let i = 0; i < 1000; i++ { big[i * 7] = i; } for let i = 0; i < 1000; i += 2 { delete(big, i * 7); }
+	for let i = 0; i < 500; i++ { big[i] += 1; } print len(big); print big[7]; print big[14]; print big[0]; print big[6993];`,
+	`type Point struct { x, y int }
+	type Rect struct { min, max Point; name string }
+	func (p *Point) Move(dx, dy int) { p.x += dx; p.y += dy }
+	func (r Rect) Area() int { (r.max.x - r.min.x) * (r.max.y - r.min.y) }
+	let r = Rect{max: Point{3, 4}, name: "box"}; let c = r; c.name = "copy";
+	r.max.Move(1, 1); let p = &r.min; p.Move(-1, -1); p.x *= 5;
+	print r; print c; print r.Area(); print c.Area(); print p;
+	let ps = [r.min, r.max]; ps[1].Move(10, 0); print ps; print map[string]Point{"a": ps[0]}["b"];`,
+	`type Node struct { val int; next *Node }
+	type List struct { head *Node; size int }
+	func (l *List) Push(v int) { l.head = &Node{val: v, next: l.head}; l.size++ }
+	func (l List) Sum() int { let total = 0; let n = l.head; for let i = 0; i < l.size; i++ { total += n.val; n = n.next } total }
+	let l = List{}; for let i = 1; i <= 4; i++ { l.Push(i * i) } print l.Sum(); print l.head.val;
+	let nodes = []; for let i = 0; i < 3; i++ { let n = Node{val: i}; nodes = append(nodes, &n) }
+	let total = 0; for let i = 0; i < 3; i++ { total += nodes[i].val } print total;
+	let counter = Node{}; let bump = func() { counter.val++ }; bump(); bump(); print counter.val;`,
+	`let divmod = func(a, b int) (int, int) { return a / b, a % b }
+	let stats = func(s []int) (int, int, float) { let lo = s[0]; let hi = s[0]; let sum = 0;
+		for let i = 0; i < len(s); i++ { if s[i] < lo { lo = s[i] } if s[i] > hi { hi = s[i] } sum += s[i] }
+		return lo, hi, float(sum) / float(len(s)) }
+	q, r := divmod(47, 6); print q; print r;
+	lo, hi, mean := stats([4, 9, 1, 6]); print lo; print hi; print mean;
+	let s = [1, 2, 3, 4, 5]; for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 { s[i], s[j] = s[j], s[i] } print s;
+	let m = map[string]int{}; m["a"], q = q, 0; v, ok := m["a"]; _, missing := m["b"]; print v; print ok; print missing; print q;`,
+	`let check = func(n int) error { if n < 0 { return errors.New("negative") } nil }
+	print check(1); print check(-1); print check(-1) == check(-1); let err = check(-2); print err.Error();
+	let safeDiv = func(a, b int) (int, error) {
+		defer func() { let r = recover(); if r != nil { print "recovered: " + r.Error() } }()
+		return a / b, nil }
+	q, e := safeDiv(7, 2); print q; print e; q, e = safeDiv(7, 0); print q; print e;
+	type Log struct { n int }
+	func (l *Log) Add(k int) { l.n += k; print l.n }
+	let run = func() int { let log = Log{}; let x = 1; defer func() { print x }(); defer log.Add(5); x = 2
+		for let i = 0; i < 3; i++ { defer func(n int) { print n }(i) } x }
+	print run();
+	let inner = func(n int) int { if n > 2 { panic(err) } n * 2 }
+	let guard = func() string { defer func() { print recover() }(); print inner(1); inner(3); "unreachable" }
+	print guard(); print [1, 2][1] % 2;
+	let twice = func() { defer func() { print recover() }(); defer func() { panic("second") }(); panic("first") }
+	twice(); defer func() { print "done" }();`,
+	`let xs = [3, 1, 2]; println(); println("hi", len(xs), 2.5, true, xs);
+	println(abs(-4), abs(-2.5), min(3, 1, 2), max(3, 7, 5), min("pear", "apple"), max("b", "ab"), min(1.5, -0.5));
+	printf("%d|%5d|%-5d|%+d|%05d|%f|%.2f|%8.3f|%-4s|%4s|%t|%v|%v|%%\n", 42, 42, 42, 42, -42, 3.14159, 2.0, 1.5, "go", "cd", false, xs, 1.5);
+	let e = errors.New("bad"); printf("%s %v\n", e, e); e = nil; printf("%s\n", e);
+	assert(len(xs) == 3); assert(xs[0] == 3, "first");`,
+	`let z = 0.0; printf("%f %f %.2f\n", 1.0/z, 0.0/z, -1.0/z);
+	printf("[%8f][%-8f][%08f][%+f][% f][%08.2f]\n", 1.0/z, 0.0/z, 1.0/z, 0.0/z, 1.0/z, -1.5);
+	printf("[%5s][%-4s][%05s][%2s]\n", "é", "ü", "ab", "日本語"); printf("%s|%3s|\n", "a\x00b", "\xff");
+	let e = errors.New("é"); printf("[%3s]", e); e = nil; printf("[%-6s]\n", e);`,
+}
+
+// TestCCodeGenMatchesEvaluator compiles programs with the system C compiler
+// and checks that the native binary prints what the evaluator prints.
+func TestCCodeGenMatchesEvaluator(t *testing.T) {
+	build := cBuilder(t, t.TempDir())
+	programs := map[string][]string{"backendPrograms": backendPrograms, "nativePrograms": nativePrograms}
+	for _, name := range []string{"backendPrograms", "nativePrograms"} {
+		for i, input := range programs[name] {
+			binFile, ok := build(fmt.Sprintf("%s%d", name, i), input)
+			if !ok {
+				continue
+			}
+			native, err := exec.Command(binFile).Output()
+			if err != nil {
+				t.Errorf("%s[%d]: running binary failed: %v", name, i, err)
+				continue
+			}
+
+			if expected := evalOutput(input); string(native) != expected {
+				t.Errorf("%s[%d]: native output %q differs from evaluator output %q", name, i, native, expected)
+			}
+		}
+	}
+}
+
+func TestCCodeGenRuntimeErrors(t *testing.T) {
+	build := cBuilder(t, t.TempDir())
+	for i, tt := range nativeRuntimeErrors {
+		binFile, ok := build(fmt.Sprintf("prog%d", i), tt.input)
+		if !ok {
+			continue
+		}
+		cmd := exec.Command(binFile)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		cmd.Run()
+		if cmd.ProcessState.ExitCode() != 1 || strings.TrimSpace(stderr.String()) != tt.expected {
+			t.Errorf("%q: got exit code %d and %q, want 1 and %q", tt.input, cmd.ProcessState.ExitCode(), stderr.String(), tt.expected)
+		}
+	}
+}
+
+// cBuilder returns a function that compiles a program with the system C
+// compiler into an executable in dir and returns its path, or reports why
+// it could not. It skips the test if there is no C compiler.
+func cBuilder(t *testing.T, dir string) func(name, input string) (string, bool) {
+	t.Helper()
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler available")
+	}
+	return func(name, input string) (string, bool) {
+		t.Helper()
+		generator := codegen.New()
+		cCode := generator.Generate(parse(input))
+		if len(generator.Errors()) != 0 {
+			t.Errorf("%s: unexpected codegen errors: %v", name, generator.Errors())
+			return "", false
+		}
+
+		cFile := filepath.Join(dir, name+".c")
+		binFile := filepath.Join(dir, name)
+		if err := os.WriteFile(cFile, []byte(cCode), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command(cc, "-o", binFile, cFile).CombinedOutput(); err != nil {
+			t.Errorf("%s: C compilation failed: %v\n%s\n%s", name, err, out, cCode)
+			return "", false
+		}
+		return binFile, true
+	}
+}
+
+// evalOutput runs input through the evaluator and returns what it printed.
+func evalOutput(input string) string {
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	evaluator.Eval(parse(input), object.NewEnvironment())
+
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String()
+}
@@ -0,0 +1,345 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/evaluator"
+	"golite.dev/mvp/internal/lexer"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/parser"
+	"golite.dev/mvp/internal/semantics"
+)
+
+func TestDiagnosticSpans(t *testing.T) {
+	input := "let x = 10;\nlet y = true;\nprint x + y;"
+	program := parse(input)
+
+	checker := semantics.New()
+	checker.Check(program)
+	errors := checker.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(errors))
+	}
+
+	d := errors[0]
+	if d.Code != diagnostics.TypeMismatch {
+		t.Errorf("wrong code. expected=%s, got=%s", diagnostics.TypeMismatch, d.Code)
+	}
+	if d.Span.Start.Line != 3 || d.Span.Start.Column != 7 || d.Span.End.Column != 12 {
+		t.Errorf("wrong span. got=%s-%s", d.Span.Start, d.Span.End)
+	}
+	if len(d.Labels) != 2 {
+		t.Errorf("expected 2 labels, got %d", len(d.Labels))
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	input := "let x 5;"
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+	d := errors[0]
+	if d.Code != diagnostics.UnexpectedToken {
+		t.Errorf("wrong code. expected=%s, got=%s", diagnostics.UnexpectedToken, d.Code)
+	}
+	if d.Span.Start.Column != 7 {
+		t.Errorf("wrong column. expected=7, got=%d", d.Span.Start.Column)
+	}
+}
+
+func TestParserRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   []string // expected parser errors, in order
+		program  string   // the partially valid program
+		checkErr string   // expected first checker error on the partial tree
+	}{
+		{
+			"let x = (1 + 2;\nprint x;",
+			[]string{"expected next token to be ), got ; instead"},
+			"let x = <bad expression>;print x;",
+			"",
+		},
+		{
+			// One missing ) does not cascade into the following lines.
+			"print (1 + 2\nprint 3;\nprint 4;",
+			[]string{"expected next token to be ), got PRINT instead"},
+			"print <bad expression>;print 3;print 4;",
+			"",
+		},
+		{
+			"let = 5;\nlet y = true;\nprint y + 1;",
+			[]string{"expected next token to be IDENT, got = instead"},
+			"<bad statement>let y = true;print (y + 1);",
+			"type mismatch: bool + int",
+		},
+		{
+			"let f = func() {\n  let a = 1 +;\n  return a\n};\nprint f(1);",
+			[]string{"no prefix parse function for ; found"},
+			"let f = func() { let a = (1 + <bad expression>);return a; };print f(1);",
+			"wrong number of arguments in call to f: want 0, got 1",
+		},
+		{
+			// The body of a broken loop header is skipped as a whole.
+			"for i < { print i; }\nprint 2;",
+			[]string{"no prefix parse function for { found"},
+			"<bad statement>print 2;",
+			"",
+		},
+		{
+			"defer f;\nprint 1;",
+			[]string{"expression in defer must be function call"},
+			"defer ;print 1;",
+			"",
+		},
+		{
+			// Only the first error in a statement is reported.
+			"let a = ) + ) + ); let b = 1;",
+			[]string{"no prefix parse function for ) found"},
+			"let a = <bad expression>;let b = 1;",
+			"",
+		},
+		{
+			"let y = (x + ;\nreturn y",
+			[]string{"no prefix parse function for ; found"},
+			"let y = <bad expression>;return y;",
+			"return statement outside function",
+		},
+		{
+			"print g(;\nprint zz;",
+			[]string{"no prefix parse function for ; found"},
+			"print <bad expression>;print zz;",
+			"identifier not found: zz",
+		},
+		{
+			// Broken statements on the same line are reported separately.
+			"let a = ); let b = );",
+			[]string{"no prefix parse function for ) found", "no prefix parse function for ) found"},
+			"let a = <bad expression>;let b = <bad expression>;",
+			"",
+		},
+		{
+			// A block still open at the end of the file is reported once,
+			// however many blocks it is nested in.
+			"let x = true;\nif x { print 1;\nprint 2;",
+			[]string{"expected }, got end of file"},
+			"let x = true;ifx { print 1;print 2; }",
+			"",
+		},
+		{
+			"let f = func(a int) int { for { return a",
+			[]string{"expected }, got end of file"},
+			"let f = func(a int) int { for { return a; } };",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		var messages []string
+		for _, d := range p.Errors() {
+			messages = append(messages, d.Message)
+		}
+		if strings.Join(messages, "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("wrong errors for %q.\nexpected=%q\ngot=%q", tt.input, tt.errors, messages)
+		}
+		if program.String() != tt.program {
+			t.Errorf("wrong program for %q.\nexpected=%q\ngot=%q", tt.input, tt.program, program.String())
+		}
+
+		checker := semantics.New()
+		checker.Check(program)
+		var checkErr string
+		if len(checker.Errors()) > 0 {
+			checkErr = checker.Errors()[0].Message
+		}
+		if checkErr != tt.checkErr {
+			t.Errorf("wrong checker error for %q. expected=%q, got=%q", tt.input, tt.checkErr, checkErr)
+		}
+	}
+}
+
+func TestParserUnclosedBlock(t *testing.T) {
+	p := parser.New(lexer.New("if true {\n  print 1;\n"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected one error, got %v", p.Errors())
+	}
+	d := p.Errors()[0]
+	if d.Span.Start.Line != 3 || len(d.Labels) != 1 || d.Labels[0].Span.Start.String() != "1:9" || d.Labels[0].Message != "this { is never closed" {
+		t.Errorf("wrong diagnostic. got %s at %s with labels %+v", d.Message, d.Span.Start, d.Labels)
+	}
+}
+
+func TestRuntimeErrorSpan(t *testing.T) {
+	input := "let f = func(x) { x };\nprint f(1, 2);"
+	program := parse(input)
+	result := evaluator.Eval(program, object.NewEnvironment())
+
+	err, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected *object.Error, got %T", result)
+	}
+	if err.Span.Start.Line != 2 || err.Span.Start.Column != 7 {
+		t.Errorf("wrong span. got=%s", err.Span.Start)
+	}
+}
+
+func TestIndexOutOfRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; print a[3];", "index out of range [3] with length 3"},
+		{"let a = [1, 2, 3]; let i = -1; a[i] = 0;", "index out of range [-1]"},
+		{"let a = [1, 2, 3]; print a[1:5];", "slice bounds out of range [:5] with capacity 3"},
+		{"let a = [1, 2, 3]; print a[3:2];", "slice bounds out of range [3:2]"},
+		{`print "abc"[:4];`, "slice bounds out of range [:4] with length 3"},
+		{`let m = map[int]map[int]int{}; m[1][2] = 3;`, "assignment to entry in nil map"},
+		{"type N struct { next *N; v int }; let n = N{}; print n.next.v;", "invalid memory address or nil pointer dereference"},
+		{"let a = 7; let b = 0; print a / b;", "integer divide by zero"},
+		{"let e = errors.New(\"x\"); e = nil; print e.Error();", "invalid memory address or nil pointer dereference"},
+		{"let n = 0; assert(n > 0, \"n is positive\");", "assertion failed: n is positive"},
+		{"let f = func(n int) int { return f(n + 1) };\nf(0);", "stack overflow"},
+	}
+
+	for _, tt := range tests {
+		result := evaluator.Eval(parse(tt.input), object.NewEnvironment())
+		err, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("%q: expected *object.Error, got %T", tt.input, result)
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%q: wrong message. expected=%q, got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+}
+
+func TestPanicStackTrace(t *testing.T) {
+	input := "let inner = func() { panic(\"boom\") };\nlet outer = func() { inner() };\nouter();"
+	result := evaluator.Eval(parse(input), object.NewEnvironment())
+
+	err, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected *object.Error, got %T", result)
+	}
+	d := err.Diagnostic()
+	if d.Code != diagnostics.Panic || d.Message != "panic: boom" {
+		t.Errorf("wrong diagnostic. got=%s %q", d.Code, d.Message)
+	}
+	expected := []string{"in inner at 1:22", "in outer at 2:22", "in main at 3:1"}
+	if strings.Join(d.Notes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong stack trace.\nexpected=%q\ngot=%q", expected, d.Notes)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	input := "let f = func(n int) int { return f(n + 1) };\nf(0);"
+	err, ok := evaluator.Eval(parse(input), object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatal("expected a stack overflow")
+	}
+	d := err.Diagnostic()
+	if d.Code != diagnostics.RuntimeError || d.Message != "stack overflow" || d.Span.Start.String() != "1:34" {
+		t.Errorf("wrong diagnostic. got=%s %q at %s", d.Code, d.Message, d.Span.Start)
+	}
+	if len(d.Notes) != 22 || d.Notes[0] != "in f at 1:34" || d.Notes[10] != "... 9979 more calls ..." || d.Notes[21] != "in main at 2:1" {
+		t.Errorf("wrong stack trace. got %d notes: %q", len(d.Notes), d.Notes)
+	}
+
+	input = `let f = func(n int) int { return f(n + 1) };
+	let g = func() { defer func() { print recover(); }(); f(0); };
+	g(); print "after";`
+	if got, expected := evalOutput(input), "runtime error: stack overflow\nafter\n"; got != expected {
+		t.Errorf("wrong output after recovering. expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestRenderDiagnostic(t *testing.T) {
+	input := "let x = 10;\nlet y = true;\nprint x + y;"
+	program := parse(input)
+	checker := semantics.New()
+	checker.Check(program)
+
+	var out bytes.Buffer
+	diagnostics.NewRenderer("test.golite", input).RenderAll(&out, checker.Errors())
+
+	expected := `error[E0101]: type mismatch: int + bool
+ --> test.golite:3:7
+  |
+3 | print x + y;
+  |       ^^^^^
+  |       - this is int
+  |           - this is bool
+`
+	if out.String() != expected {
+		t.Errorf("unexpected rendering.\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+// TestRenderNonASCII checks that carets line up under the code when
+// characters of more than one byte, and tabs after them, come before it.
+func TestRenderNonASCII(t *testing.T) {
+	input := "let s = \"héllo\";\tlet y = s + 1;"
+	program := parse(input)
+	checker := semantics.New()
+	checker.Check(program)
+
+	var out bytes.Buffer
+	diagnostics.NewRenderer("test.golite", input).RenderAll(&out, checker.Errors())
+
+	expected := `error[E0101]: type mismatch: string + int
+ --> test.golite:1:27
+  |
+1 | let s = "héllo";    let y = s + 1;
+  |                             ^^^^^
+  |                             - this is string
+  |                                 - this is int
+`
+	if out.String() != expected {
+		t.Errorf("unexpected rendering.\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	input := "print y;"
+	program := parse(input)
+	checker := semantics.New()
+	checker.Check(program)
+
+	var out bytes.Buffer
+	if err := diagnostics.WriteJSON(&out, "test.golite", checker.Errors()); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded struct {
+		Severity string `json:"severity"`
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		Span     struct {
+			File  string `json:"file"`
+			Start struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"start"`
+		} `json:"span"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out.String())), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if decoded.Severity != "error" || decoded.Code != "E0100" || decoded.Span.File != "test.golite" ||
+		decoded.Span.Start.Line != 1 || decoded.Span.Start.Column != 7 {
+		t.Errorf("unexpected JSON diagnostic: %+v", decoded)
+	}
+}
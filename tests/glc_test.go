@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/evaluator"
+	"golite.dev/mvp/internal/glc"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/optimizer"
+)
+
+// describeTree lists every node of a program with its token and extent, so
+// that two trees compare equal only if they have the same positions too.
+func describeTree(program *ast.Program) string {
+	var out strings.Builder
+	ast.Inspect(program, func(n ast.Node) bool {
+		fmt.Fprintf(&out, "%T %q %v %v\n", n, n.TokenLiteral(), n.Pos(), n.End())
+		return true
+	})
+	return out.String()
+}
+
+func TestGLCRoundTrip(t *testing.T) {
+	for i, input := range backendPrograms {
+		program := optimizer.Optimize(parse(input), optimizer.Config{EnabledPasses: optimizer.AllPasses})
+		data := glc.Encode(&glc.File{Compiler: "golite test", Source: "prog.golite", Program: program})
+
+		f, err := glc.Decode(data)
+		if err != nil {
+			t.Errorf("backendPrograms[%d]: decode failed: %s", i, err)
+			continue
+		}
+		if f.Compiler != "golite test" || f.Source != "prog.golite" {
+			t.Errorf("backendPrograms[%d]: wrong header. got compiler=%q source=%q", i, f.Compiler, f.Source)
+		}
+		if got, want := f.Program.String(), program.String(); got != want {
+			t.Errorf("backendPrograms[%d]: program changed.\nwant=%q\ngot=%q", i, want, got)
+		}
+		if got, want := describeTree(f.Program), describeTree(program); got != want {
+			t.Errorf("backendPrograms[%d]: tree changed.\nwant=%s\ngot=%s", i, want, got)
+		}
+	}
+}
+
+func TestGLCKeepsErrorPositions(t *testing.T) {
+	input := "let a = [1, 2, 3];\n\tlet i = 5;\n\tprint a[i];"
+	f, err := glc.Decode(glc.Encode(&glc.File{Program: parse(input)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := evaluator.Eval(f.Program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("expected *object.Error, got %T", result)
+	}
+	if start := result.Span.Start; start.Line != 3 || start.Column != 8 {
+		t.Errorf("wrong error position. want 3:8, got %d:%d", start.Line, start.Column)
+	}
+}
+
+func TestGLCRejectsBadFiles(t *testing.T) {
+	data := glc.Encode(&glc.File{Program: parse("let x = 1; print x;")})
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+	truncated := data[:len(data)-1]
+	// A well-formed file can still leave out a node the tree needs.
+	valueless := parse("let x = 1;")
+	valueless.Statements[0].(*ast.LetStatement).Value = nil
+
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte("let x = 1;"), "not a .glc file"},
+		{corrupt, "checksum mismatch"},
+		{truncated, "checksum mismatch"},
+		{glc.Encode(&glc.File{Program: valueless}), "corrupt .glc file: missing expression"},
+	}
+	for _, tt := range tests {
+		_, err := glc.Decode(tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestGLCDump(t *testing.T) {
+	f, err := glc.Decode(glc.Encode(&glc.File{Compiler: "golite test", Source: "x.golite", Program: parse("let x = 2;\nprint x + 0.5;")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	glc.Dump(&out, f)
+
+	for _, want := range []string{
+		"compiler: golite test\n",
+		"float   0.5\n",
+		"     2  offset 11\n",
+		"1:1        LetStatement let\n",
+		"2:7          InfixExpression +\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dump does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
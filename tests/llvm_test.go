@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/llvm"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the native backends")
+
+// nativePrograms are the programs of backendPrograms that the native
+// backends (LLVM, WebAssembly and x86-64) support, and more that exercise
+// what they support. The last passes arguments on the stack and keeps more
+// values live than there are registers.
+var nativePrograms = append(pick(backendPrograms, 0, 1, 3, 4, 5, 6, 7, 9, 11, 12, 13, 14, 15, 16),
+	`let x = 0; for { x++; if x > 7 { break } if x % 2 == 0 { continue } print x }
+	for let i = 0; i < 3; i++ { for let j = 0; j < 3; j++ { if j > i { break } print i * 10 + j } }
+	print 1 << 63; print 1 << 64; print -8 >> 70; print 5 >> 1; print -9223372036854775807 - 1; let m = -9223372036854775807 - 1; print m / -1; print m % -1;`,
+	`let collatz = func(n) { if n == 1 { return 0 } if n % 2 == 0 { return collatz(n / 2) + 1 } collatz(3 * n + 1) + 1 };
+	print collatz(27); let outer = func(n) { let sq = func(x) { x * x }; sq(n) + 1 }; print outer(4);
+	let count = func(n) { let total = 0; for let i = 1; i <= n; i++ { if i % 3 == 0 || i % 5 == 0 { total += i } } total }; print count(999);`,
+	`let total = 0.0; let add = func(x float) { total += x }; add(1.5); add(2.25); print total; let done = false; let finish = func() { done = !done }; finish(); print done;
+	let s = "first"; let greet = func() { print s }; greet(); s = ""; greet(); s = "last"; greet();
+	let sign = func(x float) int { if x < 0.0 { return -1 } if x > 0.0 { 1 } else { 0 } }; print sign(-2.5); print sign(0.0); print sign(1e-300);
+	print 1e-7; print 100000.0; print 2.0 / 3.0; print -0.0 * 1.0; print float(1 << 53) + 1.0; print 0.0 / 0.0 == 0.0 / 0.0;`,
+	`let many = func(a, b, c, d, e, f, g, h int, x, y, z, w, u, v, s, t, q, r float) float { float(a - b + c * d - e + f * g - h) + x * y - z / w + u - v + s * t - q + r };
+	print many(1, 2, 3, 4, 5, 6, 7, 8, 1.5, 2.5, 3.5, 4.5, 5.5, 6.5, 7.5, 8.5, 9.5, 10.5);
+	let mix = func(a int, x float, b int, y float, c bool) int { if c { return a + int(x) } b - int(y) }; print mix(1, 2.5, 3, 4.5, true); print mix(1, 2.5, 3, 4.5, false);
+	let id = func(x int) int { x };
+	let pressure = func(n int) int { let a = n + 1; let b = n * 2; let c = n - 3; let d = n * n; let e = a + b; let f = c * d; let g = e - f; let h = g + a; let i = h * 2; let j = i - b; let k = j + c; let l = k * 3;
+		let m = l + id(a) + id(b) + id(c) + id(d) + id(e) + id(f) + id(g) + id(h) + id(i) + id(j) + id(k) + id(l); a + b + c + d + e + f + g + h + i + j + k + l + m };
+	print pressure(5); print pressure(-7); let fp = func(n float) float { let a = n + 1.0; let b = a * 2.0; let c = id(3); a + b + float(c) + n }; print fp(0.25);
+	let big = 123456789012345; print big * 3; print big / 7; print big % 1000; print -big >> 3; print big << 2; let u = 9223372036854775807; print u + 1;
+	print 3.0 < 2.0; print 2.0 <= 2.0; print 0.0 / 0.0 != 0.0 / 0.0; print 1.0 > -1.0; print -0.5 >= 0.0;`,
+)
+
+// nativeRuntimeErrors are programs that the native backends stop with a
+// runtime error, and the error each prints.
+var nativeRuntimeErrors = []struct {
+	input    string
+	expected string
+}{
+	{"let z = 0;\nprint 1;\nprint 7 / z;\nprint 2;", "runtime error at 3:7: integer divide by zero"},
+	{"let f = func(a, b) { a % b }; print f(7, 0);", "runtime error at 1:22: integer divide by zero"},
+	{"let n = -3; let x = 1; x = x << n;", "runtime error at 1:28: negative shift amount: -3"},
+}
+
+func pick(programs []string, indexes ...int) []string {
+	var picked []string
+	for _, i := range indexes {
+		picked = append(picked, programs[i])
+	}
+	return picked
+}
+
+func generateLLVM(t *testing.T, input string) string {
+	t.Helper()
+	generator := llvm.New()
+	code := generator.Generate(parse(input))
+	if len(generator.Errors()) != 0 {
+		t.Fatalf("%q: unexpected codegen errors: %v", input, generator.Errors())
+	}
+	return code
+}
+
+// TestLLVMMatchesEvaluator runs the generated modules with lli and checks
+// that they print what the evaluator prints.
+func TestLLVMMatchesEvaluator(t *testing.T) {
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli not available")
+	}
+
+	dir := t.TempDir()
+	for i, input := range nativePrograms {
+		file := filepath.Join(dir, fmt.Sprintf("prog%d.ll", i))
+		if err := os.WriteFile(file, []byte(generateLLVM(t, input)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(lli, file).Output()
+		if err != nil {
+			t.Errorf("nativePrograms[%d]: lli failed: %v", i, err)
+			continue
+		}
+		if expected := evalOutput(input); string(out) != expected {
+			t.Errorf("nativePrograms[%d]: lli output %q differs from evaluator output %q", i, out, expected)
+		}
+	}
+}
+
+func TestLLVMRuntimeErrors(t *testing.T) {
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli not available")
+	}
+
+	dir := t.TempDir()
+	for i, tt := range nativeRuntimeErrors {
+		file := filepath.Join(dir, fmt.Sprintf("prog%d.ll", i))
+		if err := os.WriteFile(file, []byte(generateLLVM(t, tt.input)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(lli, file)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		cmd.Run()
+		if cmd.ProcessState.ExitCode() != 1 || strings.TrimSpace(stderr.String()) != tt.expected {
+			t.Errorf("%q: got exit code %d and %q, want 1 and %q", tt.input, cmd.ProcessState.ExitCode(), stderr.String(), tt.expected)
+		}
+	}
+}
+
+// TestLLVMCompiles checks that llc accepts the modules, which lli does not
+// fully verify.
+func TestLLVMCompiles(t *testing.T) {
+	llc, err := exec.LookPath("llc")
+	if err != nil {
+		t.Skip("llc not available")
+	}
+
+	dir := t.TempDir()
+	for i, input := range nativePrograms {
+		file := filepath.Join(dir, fmt.Sprintf("prog%d.ll", i))
+		if err := os.WriteFile(file, []byte(generateLLVM(t, input)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command(llc, "-o", file+".s", file).CombinedOutput(); err != nil {
+			t.Errorf("nativePrograms[%d]: llc failed: %v\n%s", i, err, out)
+		}
+	}
+}
+
+// TestLLVMGolden compares the module for a small program with the one in
+// testdata, so that changes to the output show up without LLVM installed.
+// Run the test with -update to rewrite the file.
+func TestLLVMGolden(t *testing.T) {
+	input := `let fib = func(n) { if n < 2 { return n } return fib(n-1) + fib(n-2) };
+	let total = 0;
+	for let i = 0; i < 10; i++ { if i % 2 == 0 && i > 0 { continue } total += fib(i) }
+	print total; print total > 50; print float(total) / 4.0; print "done";`
+	checkGolden(t, filepath.Join("testdata", "fib.ll"), generateLLVM(t, input))
+}
+
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s; run go test -update if the change is intended\ngot:\n%s", path, got)
+	}
+}
+
+func TestLLVMUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = func() { let x = 1; let g = func() { x }; g() }; print f();`, "the LLVM backend does not support closures; x belongs to an enclosing function"},
+		{`let a = [1, 2]; print a;`, "the LLVM backend does not support slices"},
+		{`let s = "a"; print s + "b";`, "the LLVM backend does not support the + operator on string"},
+		{`let f = func(x) { x }; let g = f;`, "the LLVM backend does not support function values; f can only be called"},
+	}
+	for _, tt := range tests {
+		generator := llvm.New()
+		if code := generator.Generate(parse(tt.input)); code != "" {
+			t.Errorf("%q: expected no code", tt.input)
+		}
+		errs := generator.Errors()
+		if len(errs) != 1 || errs[0].Code != diagnostics.Unsupported || errs[0].Message != tt.expected {
+			t.Errorf("%q: got %v, want one %s error %q", tt.input, errs, diagnostics.Unsupported, tt.expected)
+		}
+	}
+}
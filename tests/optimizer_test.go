@@ -36,6 +36,34 @@ func TestConstantFolding(t *testing.T) {
 			"let x = 10 / 0;", // Should not fold division by zero
 			"let x = (10 / 0);",
 		},
+		{
+			"let x = 1.5 * 2.0 + 0.25;",
+			"let x = 3.25;",
+		},
+		{
+			"let x = 3.0 * 2.0;", // Folded floats stay float literals
+			"let x = 6.0;",
+		},
+		{
+			"let x = 17 % 5 + (6 & 3 | 8) + (1 << 4) + (5 ^ 1);",
+			"let x = 32;",
+		},
+		{
+			"let x = 1 % 0;", // Should not fold remainder by zero
+			"let x = (1 % 0);",
+		},
+		{
+			"let x = 1 << -1;", // Should not fold a negative shift
+			"let x = (1 << (-1));",
+		},
+		{
+			"let x = false && f(); let y = true && f();",
+			"let x = false;let y = f();",
+		},
+		{
+			"let x = 1.0 / 0.0;", // Should not fold to infinity
+			"let x = (1.0 / 0.0);",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"fmt"
 	"testing"
 
 	"golite.dev/mvp/internal/ast"
@@ -76,6 +77,177 @@ if (5 < 10) {
 	}
 }
 
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world"`, "hello world"},
+		{`""`, ""},
+		{`"tab\tnewline\nquote\"backslash\\"`, "tab\tnewline\nquote\"backslash\\"},
+		{`"\x41\u00e9"`, "A\u00e9"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != lexer.STRING || tok.Literal != tt.input {
+			t.Fatalf("wrong token for %s: %s %q", tt.input, tok.Type, tok.Literal)
+		}
+
+		program := parse(tt.input)
+		lit, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("expected *ast.StringLiteral for %s", tt.input)
+		}
+		if lit.Value != tt.expected {
+			t.Errorf("wrong value for %s. expected=%q, got=%q", tt.input, tt.expected, lit.Value)
+		}
+	}
+}
+
+func TestOperatorTokens(t *testing.T) {
+	input := "a && b || c <= d >= e % f & g | h ^ i << j >> k < l > map := n : o . type struct"
+	expected := []lexer.TokenType{
+		lexer.IDENT, lexer.AND, lexer.IDENT, lexer.OR, lexer.IDENT, lexer.LT_EQ,
+		lexer.IDENT, lexer.GT_EQ, lexer.IDENT, lexer.PERCENT, lexer.IDENT,
+		lexer.AMPERSAND, lexer.IDENT, lexer.PIPE, lexer.IDENT, lexer.CARET,
+		lexer.IDENT, lexer.SHL, lexer.IDENT, lexer.SHR, lexer.IDENT, lexer.LT,
+		lexer.IDENT, lexer.GT, lexer.MAP, lexer.DEFINE, lexer.IDENT, lexer.COLON,
+		lexer.IDENT, lexer.DOT, lexer.TYPE, lexer.STRUCT, lexer.EOF,
+	}
+
+	l := lexer.New(input)
+	for i, want := range expected {
+		if tok := l.NextToken(); tok.Type != want {
+			t.Fatalf("tokens[%d] - tokentype wrong. expected=%q, got=%q (%q)", i, want, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 1; /* block
+comment */ print x / 2; // trailing
+// at end`
+
+	var types []lexer.TokenType
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		if tok.Comments != nil {
+			t.Errorf("comments attached to %q without NewWithComments", tok.Literal)
+		}
+		types = append(types, tok.Type)
+	}
+	expected := []lexer.TokenType{
+		lexer.LET, lexer.IDENT, lexer.ASSIGN, lexer.INT, lexer.SEMICOLON,
+		lexer.PRINT, lexer.IDENT, lexer.SLASH, lexer.INT, lexer.SEMICOLON,
+	}
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf("wrong tokens. expected=%v, got=%v", expected, types)
+	}
+
+	l = lexer.NewWithComments(input)
+	trivia := map[string][]string{}
+	for {
+		tok := l.NextToken()
+		for _, c := range tok.Comments {
+			trivia[tok.Literal] = append(trivia[tok.Literal], c.Text)
+		}
+		if tok.Type == lexer.EOF {
+			break
+		}
+	}
+	want := map[string][]string{
+		"let":   {"// leading"},
+		"print": {"/* block\ncomment */"},
+		"":      {"// trailing", "// at end"},
+	}
+	if fmt.Sprint(trivia) != fmt.Sprint(want) {
+		t.Errorf("wrong comments. expected=%q, got=%q", want, trivia)
+	}
+
+	l = lexer.NewWithComments("x /* c */")
+	l.NextToken()
+	c := l.NextToken().Comments[0]
+	if c.Pos.String() != "1:3" || c.End.String() != "1:10" {
+		t.Errorf("wrong comment span %s-%s", c.Pos, c.End)
+	}
+
+	p := parser.New(lexer.New("let s = 1; /* never closed"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0].Message != "comment not terminated" {
+		t.Errorf("expected an unterminated comment error, got %v", p.Errors())
+	}
+}
+
+func TestFloatLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"0.5", 0.5},
+		{"1e10", 1e10},
+		{"2.5E-3", 2.5e-3},
+		{"6e+2", 600},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != lexer.FLOAT || tok.Literal != tt.input {
+			t.Fatalf("wrong token for %s: %s %q", tt.input, tok.Type, tok.Literal)
+		}
+
+		program := parse(tt.input)
+		lit, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("expected *ast.FloatLiteral for %s", tt.input)
+		}
+		if lit.Value != tt.expected {
+			t.Errorf("wrong value for %s. expected=%g, got=%g", tt.input, tt.expected, lit.Value)
+		}
+	}
+
+	// A dot or an e that is not followed by digits ends the number.
+	l := lexer.New("1.x 2e")
+	for _, want := range []lexer.Token{
+		{Type: lexer.INT, Literal: "1"},
+		{Type: lexer.DOT, Literal: "."},
+		{Type: lexer.IDENT, Literal: "x"},
+		{Type: lexer.INT, Literal: "2"},
+		{Type: lexer.IDENT, Literal: "e"},
+	} {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Errorf("wrong token. expected=%s %q, got=%s %q", want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = "abc;`, "string literal not terminated"},
+		{"let s = \"abc\nprint s;", "string literal not terminated"},
+		{`let s = "bad \q escape";`, `invalid escape sequence in string literal "bad \q escape"`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if p.Errors()[0].Message != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0].Message)
+		}
+	}
+}
+
 func TestParsingLetStatements(t *testing.T) {
 	input := `
 let x = 5;
@@ -135,6 +307,139 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	return true
 }
 
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return 5;", "return 5;"},
+		{"return x + y", "return (x + y);"},
+		{"return;", "return;"},
+		{"func() { return }", "func() { return; }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		hasInit   bool
+		hasCond   bool
+		hasPost   bool
+		bodyCount int
+	}{
+		{"for { break; }", "for { break; }", false, false, false, 1},
+		{"for x < 10 { continue }", "for (x < 10) { continue; }", false, true, false, 1},
+		{"for (x) { }", "for x {  }", false, true, false, 0},
+		{"for let i = 0; i < 10; i + 1 { print i; }", "for let i = 0; (i < 10); (i + 1) { print i; }", true, true, true, 1},
+		{"for ; x; { }", "for x {  }", false, true, false, 0},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ForStatement. got=%T", program.Statements[0])
+		}
+		if (stmt.Init != nil) != tt.hasInit || (stmt.Condition != nil) != tt.hasCond || (stmt.Post != nil) != tt.hasPost {
+			t.Errorf("wrong clauses for %q. init=%v cond=%v post=%v", tt.input, stmt.Init, stmt.Condition, stmt.Post)
+		}
+		if len(stmt.Body.Statements) != tt.bodyCount {
+			t.Errorf("wrong body length for %q. expected=%d, got=%d", tt.input, tt.bodyCount, len(stmt.Body.Statements))
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	input := `x = 5; x += 1; x -= y * 2; x *= 3; x /= 4; x++; x--;`
+
+	tests := []struct {
+		expectedOperator string
+		expectedValue    string
+	}{
+		{"=", "5"},
+		{"+=", "1"},
+		{"-=", "(y * 2)"},
+		{"*=", "3"},
+		{"/=", "4"},
+		{"++", ""},
+		{"--", ""},
+	}
+
+	program := parse(input)
+	if len(program.Statements) != len(tests) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(tests), len(program.Statements))
+	}
+
+	for i, tt := range tests {
+		switch stmt := program.Statements[i].(type) {
+		case *ast.AssignStatement:
+			if stmt.Operator != tt.expectedOperator || stmt.Value.String() != tt.expectedValue {
+				t.Errorf("tests[%d] - expected x %s %s, got %s", i, tt.expectedOperator, tt.expectedValue, stmt.String())
+			}
+		case *ast.IncDecStatement:
+			if stmt.Operator != tt.expectedOperator {
+				t.Errorf("tests[%d] - expected x%s, got %s", i, tt.expectedOperator, stmt.String())
+			}
+		default:
+			t.Errorf("tests[%d] - unexpected statement type %T", i, stmt)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = func(n int) int { n };", "let f = func(n int) int { n };"},
+		{"let f = func(a, b int, c bool) { a };", "let f = func(a int, b int, c bool) { a };"},
+		{"let f = func(a, b) { a };", "let f = func(a, b) { a };"},
+		{"let x int = 5;", "let x int = 5;"},
+		{"let g func(int, bool) int = f;", "let g func(int, bool) int = f;"},
+		{"let h = func(f func(int)) func() bool { f };", "let h = func(f func(int)) func() bool { f };"},
+		{"let d = func(a, b int) (int, int) { return a / b, a % b };", "let d = func(a int, b int) (int, int) { return (a / b), (a % b); };"},
+		{"let g func(string) (int, bool) = f;", "let g func(string) (int, bool) = f;"},
+		{"q, r := d(7, 2); let x, _ = d(1, 1); a, s[0] = s[0], a;", "q, r := d(7, 2);let x, _ = d(1, 1);a, (s[0]) = (s[0]), a;"},
+		{"let f = func() error { defer g(1); defer func() { recover() }(); nil };", "let f = func() error { defer g(1);defer func() { recover() }();nil };"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parser error on input %q: %s", tt.input, p.Errors()[0])
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := `if (x < y) { x }`
 	l := lexer.New(input)
@@ -183,6 +488,34 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"false", "false"},
 		{"3 > 5 == false", "((3 > 5) == false)"},
 		{"3 < 5 == true", "((3 < 5) == true)"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a <= b && c >= d", "((a <= b) && (c >= d))"},
+		{"a == b || !c", "((a == b) || (!c))"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"a ^ b << c", "(a ^ (b << c))"},
+		{"a >> b * c", "((a >> b) * c)"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a * [1, 2, 3][b * c] * d", "((a * ([1, 2, 3][(b * c)])) * d)"},
+		{"-a[0]", "(-(a[0]))"},
+		{"f(a)[b][c]", "((f(a)[b])[c])"},
+		{"s[1:len(s) - 1]", "(s[1:(len(s) - 1)])"},
+		{"s[:2] + s[i:]", "((s[:2]) + (s[i:]))"},
+		{`map[string]int{"a": 1 + 2, b: c}[k] * 2`, `((map[string]int{"a": (1 + 2), b: c}[k]) * 2)`},
+		{"v, ok := m[k]", "v, ok := (m[k]);"},
+		{"let v, ok = m[f(k)]", "let v, ok = (m[f(k)]);"},
+		{"n := len(m) + 1", "n := (len(m) + 1);"},
+		{"-p.x * 2", "((-p.x) * 2)"},
+		{"a[i].f[j]", "((a[i]).f[j])"},
+		{"p.Dist(q).y", "p.Dist(q).y"},
+		{"Point{x: a + 1, y: f(b)}", "Point{x: (a + 1), y: f(b)}"},
+		{"&Point{1, 2}", "(&Point{1, 2})"},
+		{"if p == (Point{}) { q }", "if(p == Point{}) { q }"},
+		{"for p.x < n { p.x++ }", "for (p.x < n) { p.x++; }"},
+		{"type Point struct { x, y int\n next *Point }", "type Point struct { x, y int; next *Point };"},
+		{"func (p *Point) Move(dx int) { p.x += dx }", "func (p *Point) Move(dx int) { p.x += dx; }"},
+		{"func (x int) int { x }(5)", "func(x int) int { x }(5)"},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -209,3 +542,65 @@ func checkParserErrors(t *testing.T, p *parser.Parser) {
 }
 
 // LINES: 236
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  print x == 10;"
+	tests := []struct {
+		expectedLiteral string
+		line, column    int
+		offset, end     int
+	}{
+		{"let", 1, 1, 0, 3},
+		{"x", 1, 5, 4, 5},
+		{"=", 1, 7, 6, 7},
+		{"5", 1, 9, 8, 9},
+		{";", 1, 10, 9, 10},
+		{"print", 2, 3, 13, 18},
+		{"x", 2, 9, 19, 20},
+		{"==", 2, 11, 21, 23},
+		{"10", 2, 14, 24, 26},
+		{";", 2, 16, 26, 27},
+		{"", 2, 17, 27, 27},
+	}
+
+	l := lexer.New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column || tok.Pos.Offset != tt.offset {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%d:%d@%d, got=%d:%d@%d", i, tok.Literal,
+				tt.line, tt.column, tt.offset, tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+		if tok.End.Offset != tt.end {
+			t.Errorf("tests[%d] - end offset of %q wrong. expected=%d, got=%d", i, tok.Literal, tt.end, tok.End.Offset)
+		}
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := `let add = func(a, b) { a + b };
+print add(1, 2);
+if (x < y) { x } else { y }`
+	program := parse(input)
+
+	tests := []struct {
+		node     ast.Node
+		pos, end int
+	}{
+		{program.Statements[0], 0, 30},
+		{program.Statements[0].(*ast.LetStatement).Value, 10, 30},
+		{program.Statements[1], 32, 47},
+		{program.Statements[1].(*ast.PrintStatement).Expression, 38, 47},
+		{program.Statements[2], 49, 76},
+		{program.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.IfExpression).Condition, 53, 58},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().Offset != tt.pos || tt.node.End().Offset != tt.end {
+			t.Errorf("tests[%d] - span of %q wrong. expected=[%d,%d), got=[%d,%d)", i, tt.node.String(),
+				tt.pos, tt.end, tt.node.Pos().Offset, tt.node.End().Offset)
+		}
+	}
+}
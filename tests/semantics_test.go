@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"golite.dev/mvp/internal/ast"
+	"golite.dev/mvp/internal/diagnostics"
 	"golite.dev/mvp/internal/evaluator"
 	"golite.dev/mvp/internal/lexer"
 	"golite.dev/mvp/internal/object"
@@ -21,7 +23,7 @@ func TestTypeCheckerErrors(t *testing.T) {
 	}{
 		{
 			"let x = 10; let y = true; x + y;",
-			"type mismatch: INTEGER + BOOLEAN",
+			"type mismatch: int + bool",
 		},
 		{
 			"foobar;",
@@ -29,15 +31,15 @@ func TestTypeCheckerErrors(t *testing.T) {
 		},
 		{
 			"5 + true;",
-			"type mismatch: INTEGER + BOOLEAN",
+			"type mismatch: int + bool",
 		},
 		{
 			"-true;",
-			"unknown operator: -BOOLEAN",
+			"unknown operator: -bool",
 		},
 		{
 			"if (10) { 1 }",
-			"if condition must be a boolean, got INTEGER",
+			"if condition must be a boolean, got int",
 		},
 		{
 			"let add = 10; add(1,2);",
@@ -47,6 +49,385 @@ func TestTypeCheckerErrors(t *testing.T) {
 			"let x = 1; let x = 2;",
 			"", // Shadowing is allowed for now
 		},
+		{
+			"return 5;",
+			"return statement outside function",
+		},
+		{
+			"let f = func(x) { if (x > 0) { return 1; } };",
+			"missing return at end of function",
+		},
+		{
+			"let f = func(x) { if (x > 0) { return 1; } else { return 0; } };",
+			"",
+		},
+		{
+			"let fact = func(n) { if (n < 2) { return 1; } return n * fact(n - 1); };",
+			"",
+		},
+		{
+			"break;",
+			"break is not in a loop",
+		},
+		{
+			"for { let f = func() { continue; }; }",
+			"continue is not in a loop",
+		},
+		{
+			"for 1 { }",
+			"for condition must be a boolean, got int",
+		},
+		{
+			"let f = func(x) { for { if (x > 0) { return x; } } };",
+			"",
+		},
+		{
+			"let f = func(x) { for { if (x > 0) { break; } return 1; } };",
+			"missing return at end of function",
+		},
+		{
+			"x = 5;",
+			"cannot assign to undeclared name: x",
+		},
+		{
+			"let x = 5; x = true;",
+			"cannot assign bool to x (type int)",
+		},
+		{
+			"let b = true; b += 1;",
+			"unknown operator: bool += int",
+		},
+		{
+			"let b = false; b++;",
+			"unknown operator: bool++",
+		},
+		{
+			"let x = 1; x += 2; x *= 3; x -= 1; x /= 2; x++; x--;",
+			"",
+		},
+		{
+			"let add = func(a, b int) int { a + b }; add(1);",
+			"wrong number of arguments in call to add: want 2, got 1",
+		},
+		{
+			"let add = func(a, b int) int { a + b }; add(1, true);",
+			"cannot use true (type bool) as int in argument to add",
+		},
+		{
+			"let f = func(n int) bool { return n; };",
+			"cannot use n (type int) as bool in return statement",
+		},
+		{
+			"let f = func(n int) int { n > 0 };",
+			"cannot use (n > 0) (type bool) as int in return statement",
+		},
+		{
+			"let f = func(n int) int { print n; };",
+			"missing return at end of function",
+		},
+		{
+			"let f = func(x number) { x };",
+			"undefined type: number",
+		},
+		{
+			"let x int = true;",
+			"cannot use true (type bool) as int in variable declaration",
+		},
+		{
+			"let apply = func(f func(int) int, x int) int { f(x) }; apply(func(n bool) bool { !n }, 1);",
+			"cannot use func(n bool) bool { (!n) } (type func(bool) bool) as func(int) int in argument to apply",
+		},
+		{
+			"let f = func(n int) int { n }; let b bool = f(1);",
+			"cannot use f(1) (type int) as bool in variable declaration",
+		},
+		{
+			"let fib = func(n int) int { if n < 2 { return n } return fib(n-1) + fib(n-2) }; let x int = fib(10);",
+			"",
+		},
+		{
+			"let id = func(x) { x }; let a int = id(1); let b bool = id(true);",
+			"",
+		},
+		{
+			"let add = func(a, b) { a + b }; add(1, false);",
+			"cannot use false (type bool) as int in argument to add",
+		},
+		{
+			"let f = func(x) { if x { return 1 } return x };",
+			"cannot use x (type bool) as int in return statement",
+		},
+		{
+			"let g = func(x) { x(x) };",
+			"cannot infer a type for x: it would contain itself",
+		},
+		{
+			"let f = func() { print 1; }; let y = f();",
+			"f() (no value) used as value",
+		},
+		{
+			`let s = "n = " + 1;`,
+			"type mismatch: string + int",
+		},
+		{
+			`print "a" * "b";`,
+			"unknown operator: string * string",
+		},
+		{
+			"len(5);",
+			"invalid argument: 5 (type int) for len",
+		},
+		{
+			"let f = len;",
+			"len (built-in function) must be called",
+		},
+		{
+			`let join = func(a, b) { a + " " + b }; let n int = len(join("x", "y"));`,
+			"",
+		},
+		{
+			"let x = 1 + 2.5;",
+			"type mismatch: int + float",
+		},
+		{
+			"let half = func(n int) { n / 2.0 };",
+			"type mismatch: int / float",
+		},
+		{
+			"let f float = 3;",
+			"cannot use 3 (type int) as float in variable declaration",
+		},
+		{
+			"let n int = int(2.5) + 1; let f float = float(n) * 0.5;",
+			"",
+		},
+		{
+			"let ok = 1 < 2 && 3;",
+			"type mismatch: bool && int",
+		},
+		{
+			"let x = 1 || 2;",
+			"unknown operator: int || int",
+		},
+		{
+			"let r = 7.5 % 2.0;",
+			"unknown operator: float % float",
+		},
+		{
+			"let b = true & false;",
+			"unknown operator: bool & bool",
+		},
+		{
+			`let s = "a" <= "b";`,
+			"unknown operator: string <= string",
+		},
+		{
+			"let inRange = func(n, lo, hi) { lo <= n && n < hi }; let mask int = 1 << 4 | 3;",
+			"",
+		},
+		{
+			`let n = int("3");`,
+			"cannot convert \"3\" (type string) to int",
+		},
+		{
+			"let a = [1, 2, 3]; a[0] = a[1] + len(a); let b = append(a[1:], 4, 5); let e []bool = [];",
+			"",
+		},
+		{
+			"let a = [1, true];",
+			"cannot use true (type bool) as int in slice literal",
+		},
+		{
+			"let a = [1, 2]; let x = a[true];",
+			"invalid argument: index true (type bool) must be integer",
+		},
+		{
+			"let n = 5; let x = n[0];",
+			"cannot index n (type int)",
+		},
+		{
+			"let x = 3.5[1:];",
+			"cannot slice 3.5 (type float)",
+		},
+		{
+			`let a = ["x"]; a = append(a, 1);`,
+			"cannot use 1 (type int) as string in argument to append",
+		},
+		{
+			"let a = append(3, 4);",
+			"invalid argument: 3 (type int) is not a slice",
+		},
+		{
+			"let a = [1]; let b = [1]; let same = a == b;",
+			"unknown operator: []int == []int",
+		},
+		{
+			`let m = map[string][]int{"a": [1]}; m["b"] = append(m["a"], 2); v, ok := m["c"]; delete(m, "a"); let n int = len(m) + len(keys(m)) + len(v);`,
+			"",
+		},
+		{
+			"let m = map[[]int]bool{};",
+			"invalid map key type []int",
+		},
+		{
+			`let m = map[string]int{"a": 1, "b": 2, "a": 3};`,
+			`duplicate key "a" in map literal`,
+		},
+		{
+			`let m = map[string]int{"a": true};`,
+			"cannot use true (type bool) as int value in map literal",
+		},
+		{
+			`let m = map[int]string{}; m["x"] = "y";`,
+			`cannot use "x" (type string) as int in map index`,
+		},
+		{
+			"let s = [1, 2]; v, ok := s[0];",
+			"assignment mismatch: 2 variables but 1 value",
+		},
+		{
+			"let m = map[bool]int{}; delete(m, 1);",
+			"cannot use 1 (type int) as bool in argument to delete",
+		},
+		{
+			"let k = keys([1]);",
+			"invalid argument: [1] (type []int) is not a map",
+		},
+		{
+			`type P struct { x, y int }
+			 func (p *P) Move(d int) { p.x += d }
+			 func (p P) Sum() int { p.x + p.y }
+			 let p = P{y: 2}; p.Move(1); let q = &p; q.Move(2); let n int = q.Sum() + p.x;`,
+			"",
+		},
+		{
+			"type P struct { x int }; let p = P{x: 1, z: 2};",
+			"unknown field z in struct literal of type P",
+		},
+		{
+			"type P struct { x, y int }; let p = P{1};",
+			"too few values in struct literal of type P",
+		},
+		{
+			`type P struct { x int }; let p = P{x: "one"};`,
+			`cannot use "one" (type string) as int value in struct literal`,
+		},
+		{
+			"type P struct { x int }; let p = P{}; print p.y;",
+			"p.y undefined (type P has no field or method y)",
+		},
+		{
+			"type T struct { next T };",
+			"invalid recursive type T",
+		},
+		{
+			"type P struct { x int }; func (p *P) Set() { p.x = 1 }; let f = func() P { P{} }; f().Set();",
+			"cannot call pointer method Set on P",
+		},
+		{
+			"type P struct { x int }; let m = map[int]P{}; m[1].x = 2;",
+			"cannot assign to struct field (m[1]).x in map",
+		},
+		{
+			"let n = 1; let p = &n;",
+			"cannot take the address of n (type int)",
+		},
+		{
+			"type P struct { x int }; func (p P) x() int { 1 };",
+			"field and method with the same name x",
+		},
+		{
+			"let d = func(a, b int) (int, int) { return a / b, a % b }; q, r := d(7, 2); let n int = q + r;",
+			"",
+		},
+		{
+			"let d = func(a, b int) (int, int) { return a / b, a % b }; q, r, s := d(7, 2);",
+			"assignment mismatch: 3 variables but d(7, 2) returns 2 values",
+		},
+		{
+			"let d = func(a, b int) (int, int) { return a / b, a % b }; let x = d(7, 2);",
+			"assignment mismatch: 1 variable but d(7, 2) returns 2 values",
+		},
+		{
+			"let d = func(a, b int) (int, int) { return a / b, a % b }; print d(7, 2) + 1;",
+			"multiple-value d(7, 2) (value of type (int, int)) in single-value context",
+		},
+		{
+			"let f = func() (int, bool) { return 1 };",
+			"not enough return values",
+		},
+		{
+			"let f = func() int { return 1, true };",
+			"too many return values",
+		},
+		{
+			`let a = 1; let b = "s"; a, b = b, a;`,
+			"cannot assign string to a (type int)",
+		},
+		{
+			"let _ = 1; print _;",
+			"cannot use _ as value",
+		},
+		{
+			`let check = func(n int) error { if n < 0 { return errors.New("negative") } nil }; let err error = check(1); print err == nil;`,
+			"",
+		},
+		{
+			"let f = func(n int) int { if n > 0 { return n } panic(\"not positive\") };",
+			"",
+		},
+		{
+			"let n int = nil;",
+			"cannot use nil as int value",
+		},
+		{
+			"panic(1);",
+			"cannot use 1 (type int) as string or error in argument to panic",
+		},
+		{
+			`let e = errors.New(1);`,
+			"cannot use 1 (type int) as string in argument to errors.New",
+		},
+		{
+			`let e = errors.New("x"); print e.Message;`,
+			"e.Message undefined (type error has no field or method Message)",
+		},
+		{
+			`printf("%d %s\n", 1);`,
+			"printf format %s reads arg #2, but call has 1 arg",
+		},
+		{
+			`printf("%d\n", "x");`,
+			`printf format %d has arg "x" of wrong type string`,
+		},
+		{
+			`printf("%q\n", 1);`,
+			"printf format %q has unknown verb q",
+		},
+		{
+			`printf("%d\n", 1, 2);`,
+			"printf call needs 1 arg but has 2 args",
+		},
+		{
+			`min(1, "a");`,
+			`cannot use "a" (type string) as int in argument to min`,
+		},
+		{
+			`max(true, false);`,
+			"invalid argument: true (type bool) cannot be ordered",
+		},
+		{
+			`abs("x");`,
+			`invalid argument: "x" (type string) for abs`,
+		},
+		{
+			`assert(1);`,
+			"cannot use 1 (type int) as bool in argument to assert",
+		},
+		{
+			`let p = println;`,
+			"println (built-in function) must be called",
+		},
 	}
 
 	for _, tt := range tests {
@@ -68,9 +449,139 @@ func TestTypeCheckerErrors(t *testing.T) {
 			continue
 		}
 
-		if !strings.Contains(errors[0], tt.expectedError) {
+		if !strings.Contains(errors[0].Message, tt.expectedError) {
 			t.Errorf("wrong error message for input '%s'.\nexpected: %q\ngot:      %q",
-				tt.input, tt.expectedError, errors[0])
+				tt.input, tt.expectedError, errors[0].Message)
+		}
+	}
+}
+
+func TestAssignmentMismatchNote(t *testing.T) {
+	checker := semantics.New()
+	checker.Check(parse("let x = 1; a, b := x;"))
+	errors := checker.Errors()
+	expected := "only calls of functions with several results produce several values, and a map index expression such as m[k] a value and a found flag"
+	if len(errors) != 1 || len(errors[0].Notes) != 1 || errors[0].Notes[0] != expected {
+		t.Errorf("expected one error with the note %q, got %v", expected, errors)
+	}
+}
+
+func TestCheckTermination(t *testing.T) {
+	// Programs whose recursion and loops must not be warned about.
+	silent := []string{
+		"let fact = func(n int) int { if n == 0 { return 1 } n * fact(n - 1) };",
+		"let fib = func(n) { if n < 2 { return n } fib(n - 1) + fib(n - 2) };",
+		"let count = func(i, n int) int { if i >= n { return 0 } 1 + count(i + 1, n) };",
+		"let sum = func(s []int) int { if len(s) == 0 { return 0 } s[0] + sum(s[1:]) };",
+		"let bits = func(n int) int { if n == 0 { return 0 } 1 + bits(n / 2) };",
+		"let fact = func(n int) int { if n == 0 { return 1 } let k = n - 1; return n * fact(k) };",
+		"let down = func(n int) int { if n <= 0 { return 0 } n--; return down(n) };",
+		"let down = func(n int) int { if n <= 0 { return 0 } n -= 2; return 1 + down(n + 1) };",
+		"let gcd = func(a, b int) int { if b == 0 { return a } gcd(b, a % b) };",
+		"let f = func(n int) int { if n < 0 { return 0 } if n > 5 { n = n * 2 } f(n - 1) };",
+		// The base case is the else branch, or the code after an if whose
+		// consequence recurses, so the condition guards it negated.
+		"let g = func(n int) int { if n > 0 { g(n - 1) } else { 0 } };",
+		"let w = func(n int) int { if n > 0 { return w(n - 1); } return 0; };",
+		"let g = func(n int) int { for { if n > 0 { return g(n - 1) } return 0 } };",
+		"let c = func(n int) int { if n > 3 { panic(\"x\") } return c(n + 1) };",
+		// The measure is hi - lo, which the analysis cannot follow.
+		`let search = func(xs []int, x, lo, hi int) int {
+			if lo > hi { return -1 }
+			let mid = (lo + hi) / 2;
+			if xs[mid] == x { return mid }
+			if xs[mid] < x { return search(xs, x, mid + 1, hi) }
+			search(xs, x, lo, mid - 1)
+		};`,
+		`let outer = func(n int) int {
+			let inner = func(m int) int { outer(m - 1) };
+			if n <= 0 { return 0 }
+			inner(n)
+		};`,
+		"let i = 0; for true { i++; if i > 3 { break } }",
+	}
+	// Programs that must be warned about, with the warning.
+	warned := []struct {
+		input   string
+		warning string
+	}{
+		{"let loop = func(n int) int { loop(n) + 1 };", "infinite recursion in loop"},
+		{"let f = func(n int) int { let g = func(m int) int { f(m) }; g(n) };", "infinite recursion in f"},
+		{"let up = func(n int) int { if n > 100 { return 0 } up(n - 1) };", "recursion in up may not terminate"},
+		{"let same = func(n int) int { if n == 0 { return 0 } same(n) };", "recursion in same may not terminate"},
+		{"let skip = func(n int) int { if n == 0 { return 0 } skip(n - 2) };", "recursion in skip may not terminate"},
+		{"let f = func(n int) int { n = n + 1; if n < 0 { return 0 } f(n - 1) };", "recursion in f may not terminate"},
+		{"let f = func(n int) int { if n < 0 { return 0 } n++; f(n) };", "recursion in f may not terminate"},
+		{"let f = func(n int) int { if n < 0 { return 0 } let m = n; f(m) };", "recursion in f may not terminate"},
+		// Skipping the if ends f, but that only happens when n is at least
+		// 5, which counting down never reaches.
+		{"let f = func(n int) { if n < 5 { f(n - 1); } };", "recursion in f may not terminate"},
+		{"let w = func(n int) int { if n < 0 { return w(n - 1); } return 0; };", "recursion in w may not terminate"},
+		{"for { print 1; }", "for loop never terminates"},
+		{"for { for { break } }", "for loop never terminates"},
+	}
+
+	warnings := func(input string) []string {
+		program := parse(input)
+		checker := semantics.New()
+		checker.Check(program)
+		if len(checker.Errors()) != 0 {
+			t.Fatalf("unexpected error for %q: %s", input, checker.Errors()[0].Message)
+		}
+		var messages []string
+		for _, d := range semantics.CheckTermination(program) {
+			if d.Severity != diagnostics.Warning {
+				t.Errorf("%q: expected a warning, got %s", input, d.Severity)
+			}
+			messages = append(messages, d.Message)
+		}
+		return messages
+	}
+	for _, input := range silent {
+		if messages := warnings(input); len(messages) != 0 {
+			t.Errorf("unexpected warnings for %q: %q", input, messages)
+		}
+	}
+	for _, tt := range warned {
+		if messages := warnings(tt.input); len(messages) != 1 || messages[0] != tt.warning {
+			t.Errorf("wrong warnings for %q.\nexpected=%q\ngot=%q", tt.input, tt.warning, messages)
+		}
+	}
+}
+
+func TestInferredSignatures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"func(a, b) { a + b }", "func(int, int) int"},
+		{"func(b) { !b }", "func(bool) bool"},
+		{"func(n) { if n > 0 { return true } false }", "func(int) bool"},
+		{"func(f) { f(1) > 2 }", "func(func(int) int) bool"},
+		{"func(x) { print x + 1; }", "func(int)"},
+		{"func(r) { 3.14 * r * r }", "func(float) float"},
+		{"func(x) { float(x) }", "func(int) float"},
+		{"func(a, b) { a || !b }", "func(bool, bool) bool"},
+		{"func(n) { n % 2 == 0 }", "func(int) bool"},
+		{"func(x, y) { x >= y }", "func(int, int) bool"},
+		{"func(a) { a[0] + 1 }", "func([]int) int"},
+		{"func(a, x) { append(a, x * 2.0) }", "func([]float, float) []float"},
+		{"func(s) { len(s[1:]) }", "func(string) int"},
+		{"func(m) { m[0][1] == true }", "func([][]bool) bool"},
+		{`func(m) { m["k"] + 1.5 }`, "func(map[string]float) float"},
+		{"func(m map[bool]int, k) { delete(m, k); keys(m) }", "func(map[bool]int, bool) []bool"},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+		checker := semantics.New()
+		checker.Check(program)
+		if len(checker.Errors()) > 0 {
+			t.Fatalf("unexpected error for %q: %s", tt.input, checker.Errors()[0].Message)
+		}
+		lit := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if got := checker.TypeOf(lit).String(); got != tt.expected {
+			t.Errorf("wrong type for %q. expected=%q, got=%q", tt.input, tt.expected, got)
 		}
 	}
 }
@@ -103,6 +614,185 @@ func TestEvaluator(t *testing.T) {
 			`if (true) { print 99; }`,
 			"99\n",
 		},
+		{
+			`let fib = func(n) {
+                 if n < 2 { return n }
+                 return fib(n-1) + fib(n-2)
+             }
+             print(fib(10))`,
+			"55\n",
+		},
+		{
+			`let f = func(x) { return x * 2; print 0; };
+             print f(21);`,
+			"42\n",
+		},
+		{
+			`let f = func() { if (true) { if (true) { return 1; } print 0; } return 2; };
+             print f();`,
+			"1\n",
+		},
+		{
+			`for { print 1; break; print 2; }`,
+			"1\n",
+		},
+		{
+			`let f = func(n) { for { if (n > 0) { return n; } } };
+             print f(7);`,
+			"7\n",
+		},
+		{
+			`let countdown = func(n) {
+                 for n > 0 { print n; return countdown(n - 1); }
+                 return 0;
+             };
+             countdown(3);`,
+			"3\n2\n1\n",
+		},
+		{
+			`for let i = 0; true; i { if (i == 0) { print 1; break; } continue; }`,
+			"1\n",
+		},
+		{
+			`let sum = 0;
+             for let i = 1; i < 11; i++ { sum += i; }
+             print sum;`,
+			"55\n",
+		},
+		{
+			`let x = 5;
+             let double = func() { x = x * 2; };
+             double();
+             print x;`,
+			"10\n",
+		},
+		{
+			`let x = 10; x -= 4; x *= 3; x /= 2; x--; print x;`,
+			"8\n",
+		},
+		{
+			`let n = 0; for n < 3 { n++; } print n;`,
+			"3\n",
+		},
+		{
+			`let greet = func(name) { "Hello, " + name + "!" }; print greet("GoLite");`,
+			"Hello, GoLite!\n",
+		},
+		{
+			`let s = "a\tb\n"; s += "c"; print s; print len(s); print s == "a\tb\nc"; print "x" != "x";`,
+			"a\tb\nc\n5\ntrue\nfalse\n",
+		},
+		{
+			`let x = 1.5; x *= 2.0; x++; print x; print x / 4.0; print -x > 0.0;`,
+			"4\n1\nfalse\n",
+		},
+		{
+			`print 1e6; print 0.1 + 0.2; print 1.0 / 0.0; print float(7) / 2.0; print int(-3.9);`,
+			"1e+06\n0.30000000000000004\n+Inf\n3.5\n-3\n",
+		},
+		{
+			`let calls = 0;
+             let yes = func() { calls++; true };
+             print false && yes(); print true || yes(); print true && yes(); print calls;`,
+			"false\ntrue\ntrue\n1\n",
+		},
+		{
+			`print 17 % 5; print -17 % 5; print 12 & 10; print 12 | 10; print 12 ^ 10; print 1 << 5; print -64 >> 2; print 3 <= 3; print 2.5 >= 3.0;`,
+			"2\n-2\n8\n14\n6\n32\n-16\ntrue\nfalse\n",
+		},
+		{
+			`let a = [5, 2, 4, 1, 3];
+             for let i = 1; i < len(a); i++ {
+                 for let j = i; j > 0 && a[j-1] > a[j]; j-- {
+                     let t = a[j]; a[j] = a[j-1]; a[j-1] = t;
+                 }
+             }
+             print a; print a[1:3]; print a[:0]; print len(a[2:]);`,
+			"[1 2 3 4 5]\n[2 3]\n[]\n3\n",
+		},
+		{
+			`let a = append([1, 2], 3);
+             let b = a[:2];
+             let c = append(b, 9);
+             print a; print c;
+             let d = append(c, 10);
+             d[0] = 0;
+             print a; print d;`,
+			"[1 2 9]\n[1 2 9]\n[0 2 9]\n[0 2 9 10]\n",
+		},
+		{
+			`let s = "golite"; print s[2:]; print s[:2] + s[4:]; print len(s[1:1]); print [["a"], ["b", "c"]];`,
+			"lite\ngote\n0\n[[a] [b c]]\n",
+		},
+		{
+			`let counts = map[string]int{};
+             let words = ["b", "a", "c", "a", "b", "a"];
+             for let i = 0; i < len(words); i++ { counts[words[i]]++; }
+             print counts; print len(counts);
+             n, ok := counts["a"]; print n; print ok;
+             n, ok := counts["z"]; print n; print ok;
+             delete(counts, "a"); delete(counts, "z"); print counts;
+             let ks = keys(counts); print len(ks);`,
+			"map[a:3 b:2 c:1]\n3\n3\ntrue\n0\nfalse\nmap[b:2 c:1]\n2\n",
+		},
+		{
+			`let byLen = map[int][]string{};
+             byLen[2] = append(byLen[2], "go");
+             byLen[4] = append(byLen[4], "lite");
+             byLen[2] = append(byLen[2], "is");
+             let alias = byLen; alias[0] = [];
+             print byLen; print byLen[9]; print map[bool]float{true: 0.5, false: -1.0};`,
+			"map[0:[] 2:[go is] 4:[lite]]\n[]\nmap[false:-1 true:0.5]\n",
+		},
+		{
+			`type Point struct { x, y int }
+             func (p *Point) Scale(k int) { p.x *= k; p.y *= k }
+             func (p Point) Swap() Point { Point{p.y, p.x} }
+             let a = Point{1, 2}; let b = a; b.x = 9;
+             a.Scale(3); let s = a.Swap(); s.Scale(2);
+             print a; print b; print s;
+             let ptr = &a; ptr.y = 0; print a; print ptr;`,
+			"{3 6}\n{9 2}\n{12 6}\n{3 0}\n&{3 0}\n",
+		},
+		{
+			`type Box struct { items []int; label string }
+             let boxes = [Box{label: "a"}, Box{[1], "b"}];
+             boxes[0].items = append(boxes[0].items, 5);
+             let first = boxes[0]; first.label = "changed";
+             print boxes; print first;`,
+			"[{[5] a} {[1] b}]\n{[5] changed}\n",
+		},
+		{
+			`let divmod = func(a, b int) (int, int) { return a / b, a % b }
+             q, r := divmod(17, 5); print q; print r;
+             let a = 1; let b = 2; a, b = b, a; print a; print b;
+             let _, rest = divmod(9, 4); print rest;
+             let m = map[string]int{"k": 1}; let v = 0; let ok = true;
+             v, ok = m["x"]; print v; print ok;`,
+			"3\n2\n2\n1\n1\n0\nfalse\n",
+		},
+		{
+			`let safeDiv = func(a, b int) (int, error) {
+                 defer func() { let r = recover(); if r != nil { print "recovered: " + r.Error() } }();
+                 return a / b, nil
+             }
+             q, err := safeDiv(7, 2); print q; print err;
+             q, err = safeDiv(7, 0); print q; print err;
+             let order = func() { for let i = 0; i < 3; i++ { defer func(n int) { print n }(i) } print "body" };
+             order();
+             let e = errors.New("boom"); let same = e;
+             print same == e; print e == errors.New("boom");
+             let guard = func() { defer func() { print recover() }(); panic(e) };
+             guard(); print recover();`,
+			"3\n<nil>\nrecovered: runtime error: integer divide by zero\n0\n<nil>\nbody\n2\n1\n0\ntrue\nfalse\nboom\n<nil>\n",
+		},
+		{
+			`println(); println("a", 1, 2.5, [true]);
+             printf("%d|%5d|%-3s|%.2f|%t|%v|%s|100%%\n", 7, -42, "go", 3.14159, true, map[string]int{"k": 1}, errors.New("e"));
+             print abs(-3) + abs(2); print abs(-1.5); print min(4, 2, 8); print max("b", "ab"); print max(1.5, -2.0);
+             assert(len("ab") == 2, "len");`,
+			"\na 1 2.5 [true]\n7|  -42|go |3.14|true|map[k:1]|e|100%\n5\n1.5\n2\nb\n1.5\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,244 @@
+; ModuleID = 'golite'
+source_filename = "golite"
+
+%golite.string = type { i8*, i64 }
+
+@.str.0 = private unnamed_addr constant [4 x i8] c"done"
+@total = internal global i64 0
+
+@.fmt.error = private unnamed_addr constant [25 x i8] c"runtime error at %d:%d: \00"
+@.fmt.newline = private unnamed_addr constant [2 x i8] c"\0A\00"
+
+define internal void @golite_runtime_error(i32 %line, i32 %column, i8* %message, i64 %arg) noreturn {
+entry:
+  %f = call i32 @fflush(i8* null)
+  %r1 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* getelementptr inbounds ([25 x i8], [25 x i8]* @.fmt.error, i64 0, i64 0), i32 %line, i32 %column)
+  %r2 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* %message, i64 %arg)
+  %r3 = call i32 (i32, i8*, ...) @dprintf(i32 2, i8* getelementptr inbounds ([2 x i8], [2 x i8]* @.fmt.newline, i64 0, i64 0))
+  call void @exit(i32 1)
+  unreachable
+}
+
+@.str.divide = private unnamed_addr constant [23 x i8] c"integer divide by zero\00"
+
+define internal i64 @golite_div(i64 %a, i64 %b, i32 %line, i32 %column) {
+entry:
+  %zero = icmp eq i64 %b, 0
+  br i1 %zero, label %fail, label %nonzero
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([23 x i8], [23 x i8]* @.str.divide, i64 0, i64 0), i64 0)
+  unreachable
+nonzero:
+  %minus = icmp eq i64 %b, -1
+  br i1 %minus, label %negate, label %divide
+negate:
+  %n = sub i64 0, %a
+  ret i64 %n
+divide:
+  %q = sdiv i64 %a, %b
+  ret i64 %q
+}
+
+define internal i64 @golite_mod(i64 %a, i64 %b, i32 %line, i32 %column) {
+entry:
+  %zero = icmp eq i64 %b, 0
+  br i1 %zero, label %fail, label %nonzero
+fail:
+  call void @golite_runtime_error(i32 %line, i32 %column, i8* getelementptr inbounds ([23 x i8], [23 x i8]* @.str.divide, i64 0, i64 0), i64 0)
+  unreachable
+nonzero:
+  %minus = icmp eq i64 %b, -1
+  br i1 %minus, label %done, label %divide
+done:
+  ret i64 0
+divide:
+  %r = srem i64 %a, %b
+  ret i64 %r
+}
+
+@.fmt.int = private unnamed_addr constant [6 x i8] c"%lld\0A\00"
+
+define internal void @golite_print_int(i64 %n) {
+entry:
+  %r = call i32 (i8*, ...) @printf(i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.fmt.int, i64 0, i64 0), i64 %n)
+  ret void
+}
+
+@.str.true = private unnamed_addr constant [5 x i8] c"true\00"
+@.str.false = private unnamed_addr constant [6 x i8] c"false\00"
+
+define internal void @golite_print_bool(i1 %b) {
+entry:
+  %s = select i1 %b, i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.true, i64 0, i64 0), i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.str.false, i64 0, i64 0)
+  %r = call i32 @puts(i8* %s)
+  ret void
+}
+
+@.fmt.e = private unnamed_addr constant [5 x i8] c"%.*e\00"
+@.fmt.g = private unnamed_addr constant [6 x i8] c"%.*g\0A\00"
+@.str.nan = private unnamed_addr constant [4 x i8] c"NaN\00"
+@.str.inf = private unnamed_addr constant [5 x i8] c"+Inf\00"
+@.str.neginf = private unnamed_addr constant [5 x i8] c"-Inf\00"
+
+define internal void @golite_print_float(double %v) {
+entry:
+  %buf = alloca [32 x i8]
+  %digits.addr = alloca i32
+  %p = getelementptr inbounds [32 x i8], [32 x i8]* %buf, i64 0, i64 0
+  %isnan = fcmp uno double %v, %v
+  br i1 %isnan, label %nan, label %notnan
+nan:
+  %r.nan = call i32 @puts(i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.str.nan, i64 0, i64 0))
+  ret void
+notnan:
+  %isinf = fcmp oeq double %v, 0x7FF0000000000000
+  br i1 %isinf, label %inf, label %notinf
+inf:
+  %r.inf = call i32 @puts(i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.inf, i64 0, i64 0))
+  ret void
+notinf:
+  %isneginf = fcmp oeq double %v, 0xFFF0000000000000
+  br i1 %isneginf, label %neginf, label %finite
+neginf:
+  %r.neginf = call i32 @puts(i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.str.neginf, i64 0, i64 0))
+  ret void
+finite:
+  store i32 1, i32* %digits.addr
+  br label %try
+try:
+  %digits = load i32, i32* %digits.addr
+  %precision = sub i32 %digits, 1
+  %n = call i32 (i8*, i64, i8*, ...) @snprintf(i8* %p, i64 32, i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.fmt.e, i64 0, i64 0), i32 %precision, double %v)
+  %back = call double @strtod(i8* %p, i8** null)
+  %same = fcmp oeq double %back, %v
+  %most = icmp sge i32 %digits, 17
+  %found = or i1 %same, %most
+  br i1 %found, label %format, label %next
+next:
+  %more = add i32 %digits, 1
+  store i32 %more, i32* %digits.addr
+  br label %try
+format:
+  %e = call i8* @strchr(i8* %p, i32 101)
+  %ep = getelementptr inbounds i8, i8* %e, i64 1
+  %exp = call i32 @atoi(i8* %ep)
+  %small = icmp slt i32 %exp, -4
+  %large = icmp sge i32 %exp, 6
+  %exponent = or i1 %small, %large
+  br i1 %exponent, label %scientific, label %plain
+scientific:
+  %r.e = call i32 @puts(i8* %p)
+  ret void
+plain:
+  %whole = add i32 %exp, 1
+  %wider = icmp sgt i32 %digits, %whole
+  %width = select i1 %wider, i32 %digits, i32 %whole
+  %r.g = call i32 (i8*, ...) @printf(i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.fmt.g, i64 0, i64 0), i32 %width, double %v)
+  ret void
+}
+
+define internal void @golite_print_string(%golite.string %s) {
+entry:
+  %data = extractvalue %golite.string %s, 0
+  %len = extractvalue %golite.string %s, 1
+  %i.addr = alloca i64
+  store i64 0, i64* %i.addr
+  br label %loop
+loop:
+  %i = load i64, i64* %i.addr
+  %more = icmp slt i64 %i, %len
+  br i1 %more, label %body, label %done
+body:
+  %p = getelementptr inbounds i8, i8* %data, i64 %i
+  %c = load i8, i8* %p
+  %ci = zext i8 %c to i32
+  %r = call i32 @putchar(i32 %ci)
+  %next = add i64 %i, 1
+  store i64 %next, i64* %i.addr
+  br label %loop
+done:
+  %nl = call i32 @putchar(i32 10)
+  ret void
+}
+
+define internal i64 @fib(i64 %0) {
+entry:
+  %n.addr = alloca i64
+  store i64 %0, i64* %n.addr
+  %t.1 = load i64, i64* %n.addr
+  %t.2 = icmp slt i64 %t.1, 2
+  br i1 %t.2, label %if.then.1, label %if.end.1
+if.then.1:
+  %t.3 = load i64, i64* %n.addr
+  ret i64 %t.3
+if.end.1:
+  %t.4 = load i64, i64* %n.addr
+  %t.5 = sub i64 %t.4, 1
+  %t.6 = call i64 @fib(i64 %t.5)
+  %t.7 = load i64, i64* %n.addr
+  %t.8 = sub i64 %t.7, 2
+  %t.9 = call i64 @fib(i64 %t.8)
+  %t.10 = add i64 %t.6, %t.9
+  ret i64 %t.10
+}
+
+define i32 @main() {
+entry:
+  %i.addr = alloca i64
+  store i64 0, i64* @total
+  store i64 0, i64* %i.addr
+  br label %loop.cond.1
+loop.cond.1:
+  %t.1 = load i64, i64* %i.addr
+  %t.2 = icmp slt i64 %t.1, 10
+  br i1 %t.2, label %loop.body.1, label %loop.end.1
+loop.body.1:
+  %t.3 = load i64, i64* %i.addr
+  %t.4 = call i64 @golite_mod(i64 %t.3, i64 2, i32 3, i32 34)
+  %t.5 = icmp eq i64 %t.4, 0
+  br i1 %t.5, label %logic.rhs.2, label %logic.end.2
+logic.rhs.2:
+  %t.6 = load i64, i64* %i.addr
+  %t.7 = icmp sgt i64 %t.6, 0
+  br label %logic.end.2
+logic.end.2:
+  %t.8 = phi i1 [ false, %loop.body.1 ], [ %t.7, %logic.rhs.2 ]
+  br i1 %t.8, label %if.then.3, label %if.end.3
+if.then.3:
+  br label %loop.post.1
+if.end.3:
+  %t.9 = load i64, i64* @total
+  %t.10 = load i64, i64* %i.addr
+  %t.11 = call i64 @fib(i64 %t.10)
+  %t.12 = add i64 %t.9, %t.11
+  store i64 %t.12, i64* @total
+  br label %loop.post.1
+loop.post.1:
+  %t.13 = load i64, i64* %i.addr
+  %t.14 = add i64 %t.13, 1
+  store i64 %t.14, i64* %i.addr
+  br label %loop.cond.1
+loop.end.1:
+  %t.15 = load i64, i64* @total
+  call void @golite_print_int(i64 %t.15)
+  %t.16 = load i64, i64* @total
+  %t.17 = icmp sgt i64 %t.16, 50
+  call void @golite_print_bool(i1 %t.17)
+  %t.18 = load i64, i64* @total
+  %t.19 = sitofp i64 %t.18 to double
+  %t.20 = fdiv double %t.19, 4.000000e+00
+  call void @golite_print_float(double %t.20)
+  call void @golite_print_string(%golite.string { i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.str.0, i64 0, i64 0), i64 4 })
+  ret i32 0
+}
+
+declare double @strtod(i8*, i8**)
+declare i32 @atoi(i8*)
+declare i32 @dprintf(i32, i8*, ...)
+declare i32 @fflush(i8*)
+declare i32 @printf(i8*, ...)
+declare i32 @putchar(i32)
+declare i32 @puts(i8*)
+declare i32 @snprintf(i8*, i64, i8*, ...)
+declare i8* @strchr(i8*, i32)
+declare void @exit(i32) noreturn
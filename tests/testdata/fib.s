@@ -0,0 +1,1997 @@
+	.text
+	.globl _start
+_start:
+	call main
+	movl $231, %eax
+	xorl %edi, %edi
+	syscall
+
+fib:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	cmpq $2, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L1
+	movq %rbx, %rax
+	jmp .L3
+	jmp .L2
+.L1:
+.L2:
+	movq %rbx, %rsi
+	subq $1, %rsi
+	movq %rsi, %rdi
+	call fib
+	movq %rax, %r12
+	movq %rbx, %rsi
+	subq $2, %rsi
+	movq %rsi, %rdi
+	call fib
+	movq %rax, %rdi
+	movq %r12, %rsi
+	addq %rdi, %rsi
+	movq %rsi, %rax
+	jmp .L3
+.L3:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq $0, total(%rip)
+	movq $0, %rbx
+.L4:
+	cmpq $10, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L6
+	movq %rbx, %rax
+	cqto
+	movq $2, %r11
+	idivq %r11
+	movq %rdx, %rsi
+	cmpq $0, %rsi
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	movq %rdi, %rsi
+	cmpq $0, %rsi
+	je .L9
+	cmpq $0, %rbx
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	movq %rdi, %rsi
+.L9:
+	cmpq $0, %rsi
+	je .L7
+	jmp .L5
+	jmp .L8
+.L7:
+.L8:
+	movq total(%rip), %r12
+	movq %rbx, %rdi
+	call fib
+	movq %rax, %rsi
+	movq %r12, %rdi
+	addq %rsi, %rdi
+	movq %rdi, total(%rip)
+.L5:
+	movq %rbx, %rsi
+	addq $1, %rsi
+	movq %rsi, %rbx
+	jmp .L4
+.L6:
+	movq total(%rip), %rsi
+	movq %rsi, %rdi
+	call golite_print_int
+	movq total(%rip), %rsi
+	cmpq $50, %rsi
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	call golite_print_bool
+	movq total(%rip), %rsi
+	xorpd %xmm8, %xmm8
+	cvtsi2sdq %rsi, %xmm8
+	movabsq $4616189618054758400, %r11
+	movq %r11, %xmm9
+	movapd %xmm8, %xmm10
+	divsd %xmm9, %xmm10
+	movapd %xmm10, %xmm0
+	call golite_print_float
+	leaq data.str.0(%rip), %rsi
+	movq %rsi, %rdi
+	call golite_print_string
+	call golite_flush
+	jmp .L10
+.L10:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_flush:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq golite_out_len(%rip), %rsi
+	cmpq $0, %rsi
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	cmpq $0, %rdi
+	je .L11
+	leaq data.golite_out(%rip), %rsi
+	movq golite_out_len(%rip), %rdi
+	movq %rdi, %rdx
+	movq $1, %rdi
+	movl $1, %eax
+	syscall
+	movq $0, golite_out_len(%rip)
+	jmp .L12
+.L11:
+.L12:
+	jmp .L13
+.L13:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_emit:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	movq golite_out_len(%rip), %rsi
+	movq %rsi, %rdi
+	addq %r12, %rdi
+	cmpq $4096, %rdi
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L14
+	call golite_flush
+	jmp .L15
+.L14:
+.L15:
+	cmpq $4096, %r12
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L16
+	movq $1, %rdi
+	movq %rbx, %rsi
+	movq %r12, %rdx
+	movl $1, %eax
+	syscall
+	jmp .L21
+	jmp .L17
+.L16:
+.L17:
+	movq $0, %rsi
+.L18:
+	cmpq %r12, %rsi
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	cmpq $0, %rdi
+	je .L20
+	leaq data.golite_out(%rip), %rdi
+	movq golite_out_len(%rip), %r8
+	movq %r8, %r9
+	addq %rsi, %r9
+	movq %rdi, %r8
+	addq %r9, %r8
+	movq %rbx, %rdi
+	addq %rsi, %rdi
+	movzbq (%rdi), %r11
+	movq %r11, %r9
+	movb %r9b, (%r8)
+	movq %rsi, %rdi
+	addq $1, %rdi
+	movq %rdi, %rsi
+.L19:
+	jmp .L18
+.L20:
+	movq golite_out_len(%rip), %rsi
+	movq %rsi, %rdi
+	addq %r12, %rdi
+	movq %rdi, golite_out_len(%rip)
+	jmp .L21
+.L21:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_emit_byte:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rdi, %rbx
+	movq golite_out_len(%rip), %rsi
+	cmpq $4096, %rsi
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	cmpq $0, %rdi
+	je .L22
+	call golite_flush
+	jmp .L23
+.L22:
+.L23:
+	leaq data.golite_out(%rip), %rsi
+	movq golite_out_len(%rip), %rdi
+	movq %rsi, %r8
+	addq %rdi, %r8
+	movb %bl, (%r8)
+	movq golite_out_len(%rip), %rsi
+	movq %rsi, %rdi
+	addq $1, %rdi
+	movq %rdi, golite_out_len(%rip)
+	jmp .L24
+.L24:
+	leaq -8(%rbp), %rsp
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_format_int:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	leaq data.golite_num(%rip), %rdi
+	movq %rdi, %r8
+	addq $32, %r8
+	movq %r8, %rdi
+	cmpq $0, %rsi
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	movq %r8, %r9
+	movq %r9, %r8
+	xorq $1, %r8
+	cmpq $0, %r8
+	je .L25
+	movq $0, %r8
+	subq %rsi, %r8
+	movq %r8, %rsi
+	jmp .L26
+.L25:
+.L26:
+.L27:
+	movq %rdi, %r8
+	addq $-1, %r8
+	movq %r8, %rdi
+	movq %rsi, %rax
+	cqto
+	movq $10, %r11
+	idivq %r11
+	movq %rdx, %r8
+	movq $48, %r10
+	subq %r8, %r10
+	movb %r10b, (%rdi)
+	movq %rsi, %rax
+	cqto
+	movq $10, %r11
+	idivq %r11
+	movq %rax, %r8
+	movq %r8, %rsi
+	cmpq $0, %rsi
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	cmpq $0, %r8
+	je .L30
+	jmp .L29
+	jmp .L31
+.L30:
+.L31:
+.L28:
+	jmp .L27
+.L29:
+	cmpq $0, %r9
+	je .L32
+	movq %rdi, %rsi
+	addq $-1, %rsi
+	movq %rsi, %rdi
+	movb $45, (%rdi)
+	jmp .L33
+.L32:
+.L33:
+	movq %rdi, %rax
+	jmp .L34
+.L34:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_emit_int:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	movq %rsi, %rdi
+	call golite_format_int
+	movq %rax, %rdi
+	movq %rdi, %rsi
+	leaq data.golite_num(%rip), %rdi
+	movq %rdi, %r8
+	addq $32, %r8
+	movq %r8, %rdi
+	subq %rsi, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_emit
+	jmp .L35
+.L35:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_write_int:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	movq %rsi, %rdi
+	call golite_format_int
+	movq %rax, %rdi
+	movq %rdi, %rsi
+	leaq data.golite_num(%rip), %rdi
+	movq %rdi, %r8
+	addq $32, %r8
+	movq %r8, %rdi
+	subq %rsi, %rdi
+	movq %rdi, %rdx
+	movq $2, %rdi
+	movl $1, %eax
+	syscall
+	jmp .L36
+.L36:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_print_int:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	movq %rsi, %rdi
+	call golite_emit_int
+	movq $10, %rdi
+	call golite_emit_byte
+	jmp .L37
+.L37:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_print_bool:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	cmpq $0, %rsi
+	je .L38
+	leaq data.golite_text.0(%rip), %rsi
+	movq %rsi, %rdi
+	movq $4, %rsi
+	call golite_emit
+	jmp .L39
+.L38:
+	leaq data.golite_text.1(%rip), %rsi
+	movq %rsi, %rdi
+	movq $5, %rsi
+	call golite_emit
+.L39:
+	movq $10, %rdi
+	call golite_emit_byte
+	jmp .L40
+.L40:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_print_string:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rdi, %rsi
+	movq %rsi, %rdi
+	addq $8, %rdi
+	movq (%rsi), %r11
+	movq %r11, %r8
+	movq %r8, %rsi
+	call golite_emit
+	movq $10, %rdi
+	call golite_emit_byte
+	jmp .L41
+.L41:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_error_at:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	call golite_flush
+	leaq data.golite_text.2(%rip), %rsi
+	movq $2, %rdi
+	movq $17, %rdx
+	movl $1, %eax
+	syscall
+	movq %rbx, %rdi
+	call golite_write_int
+	leaq data.golite_text.3(%rip), %rsi
+	movq $2, %rdi
+	movq $1, %rdx
+	movl $1, %eax
+	syscall
+	movq %r12, %rdi
+	call golite_write_int
+	leaq data.golite_text.4(%rip), %rsi
+	movq $2, %rdi
+	movq $2, %rdx
+	movl $1, %eax
+	syscall
+	jmp .L42
+.L42:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_div:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	movq %rdx, %rsi
+	movq %rcx, %rdi
+	cmpq $0, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	cmpq $0, %r8
+	je .L43
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_error_at
+	leaq data.golite_text.5(%rip), %rsi
+	movq $2, %rdi
+	movq $23, %rdx
+	movl $1, %eax
+	syscall
+	movl $231, %eax
+	movl $1, %edi
+	syscall
+	jmp .L44
+.L43:
+.L44:
+	cmpq $-1, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L45
+	movq $0, %rsi
+	subq %rbx, %rsi
+	movq %rsi, %rax
+	jmp .L47
+	jmp .L46
+.L45:
+.L46:
+	movq %rbx, %rax
+	cqto
+	idivq %r12
+	movq %rax, %rsi
+	movq %rsi, %rax
+	jmp .L47
+.L47:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_mod:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	movq %rdx, %rsi
+	movq %rcx, %rdi
+	cmpq $0, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	cmpq $0, %r8
+	je .L48
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_error_at
+	leaq data.golite_text.5(%rip), %rsi
+	movq $2, %rdi
+	movq $23, %rdx
+	movl $1, %eax
+	syscall
+	movl $231, %eax
+	movl $1, %edi
+	syscall
+	jmp .L49
+.L48:
+.L49:
+	cmpq $-1, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L50
+	movq $0, %rax
+	jmp .L52
+	jmp .L51
+.L50:
+.L51:
+	movq %rbx, %rax
+	cqto
+	idivq %r12
+	movq %rdx, %rsi
+	movq %rsi, %rax
+	jmp .L52
+.L52:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_shl:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	movq %rdx, %rsi
+	movq %rcx, %rdi
+	cmpq $0, %r12
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	cmpq $0, %r8
+	je .L53
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_error_at
+	leaq data.golite_text.6(%rip), %rsi
+	movq $2, %rdi
+	movq $23, %rdx
+	movl $1, %eax
+	syscall
+	movq %r12, %rdi
+	call golite_write_int
+	leaq data.golite_text.7(%rip), %rsi
+	movq $2, %rdi
+	movq $1, %rdx
+	movl $1, %eax
+	syscall
+	movl $231, %eax
+	movl $1, %edi
+	syscall
+	jmp .L54
+.L53:
+.L54:
+	cmpq $64, %r12
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L55
+	movq $0, %rax
+	jmp .L57
+	jmp .L56
+.L55:
+.L56:
+	movq %r12, %rcx
+	movq %rbx, %rsi
+	shlq %cl, %rsi
+	movq %rsi, %rax
+	jmp .L57
+.L57:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_shr:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+	movq %rdx, %rsi
+	movq %rcx, %rdi
+	cmpq $0, %r12
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r8
+	cmpq $0, %r8
+	je .L58
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_error_at
+	leaq data.golite_text.6(%rip), %rsi
+	movq $2, %rdi
+	movq $23, %rdx
+	movl $1, %eax
+	syscall
+	movq %r12, %rdi
+	call golite_write_int
+	leaq data.golite_text.7(%rip), %rsi
+	movq $2, %rdi
+	movq $1, %rdx
+	movl $1, %eax
+	syscall
+	movl $231, %eax
+	movl $1, %edi
+	syscall
+	jmp .L59
+.L58:
+.L59:
+	cmpq $64, %r12
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L60
+	movq $63, %r12
+	jmp .L61
+.L60:
+.L61:
+	movq %r12, %rcx
+	movq %rbx, %rsi
+	sarq %cl, %rsi
+	movq %rsi, %rax
+	jmp .L62
+.L62:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_set:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq %rsi, %r11
+	movq %rdi, %rsi
+	movq %r11, %rdi
+	movq $0, %r8
+.L63:
+	cmpq $40, %r8
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r9
+	cmpq $0, %r9
+	je .L65
+	movq %r8, %r9
+	imulq $8, %r9
+	movq %rsi, %r10
+	addq %r9, %r10
+	movq $0, (%r10)
+	movq %r8, %r9
+	addq $1, %r9
+	movq %r9, %r8
+.L64:
+	jmp .L63
+.L65:
+	movq $0, %r8
+	imulq $8, %r8
+	movq %rsi, %r9
+	addq %r8, %r9
+	movq %rdi, %r8
+	movabsq $4294967295, %rax
+	andq %rax, %r8
+	movq %r8, (%r9)
+	movq $1, %r8
+	imulq $8, %r8
+	movq %rsi, %r9
+	addq %r8, %r9
+	movq %rdi, %rsi
+	sarq $32, %rsi
+	movq %rsi, (%r9)
+	jmp .L66
+.L66:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+golite_big_mul:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rsi, %r11
+	movq %rdi, %rsi
+	movq %r11, %rdi
+	movq $0, %r8
+	movq $0, %r9
+.L67:
+	cmpq $40, %r8
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r10
+	cmpq $0, %r10
+	je .L69
+	movq %r8, %r10
+	imulq $8, %r10
+	movq %rsi, %rbx
+	addq %r10, %rbx
+	movq (%rbx), %r11
+	movq %r11, %r10
+	movq %r10, %rbx
+	imulq %rdi, %rbx
+	movq %rbx, %r10
+	addq %r9, %r10
+	movq %r10, %rbx
+	movq %r8, %r10
+	imulq $8, %r10
+	movq %rsi, %r12
+	addq %r10, %r12
+	movq %rbx, %r10
+	movabsq $4294967295, %rax
+	andq %rax, %r10
+	movq %r10, (%r12)
+	movq %rbx, %r10
+	sarq $32, %r10
+	movq %r10, %r9
+	movq %r8, %r10
+	addq $1, %r10
+	movq %r10, %r8
+.L68:
+	jmp .L67
+.L69:
+	jmp .L70
+.L70:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_shl:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+.L71:
+	cmpq $30, %r12
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L73
+	movq %rbx, %rdi
+	movq $1073741824, %rsi
+	call golite_big_mul
+	movq %r12, %rsi
+	addq $-30, %rsi
+	movq %rsi, %r12
+.L72:
+	jmp .L71
+.L73:
+	movq %r12, %rcx
+	movq $1, %rsi
+	shlq %cl, %rsi
+	movq %rbx, %rdi
+	call golite_big_mul
+	jmp .L74
+.L74:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_pow10:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rdi, %rbx
+	movq %rsi, %r12
+.L75:
+	cmpq $9, %r12
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L77
+	movq %rbx, %rdi
+	movq $1000000000, %rsi
+	call golite_big_mul
+	movq %r12, %rsi
+	addq $-9, %rsi
+	movq %rsi, %r12
+.L76:
+	jmp .L75
+.L77:
+	movq $1, %rsi
+.L78:
+	cmpq $0, %r12
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	cmpq $0, %rdi
+	je .L80
+	movq %rsi, %rdi
+	imulq $10, %rdi
+	movq %rdi, %rsi
+	movq %r12, %rdi
+	addq $-1, %rdi
+	movq %rdi, %r12
+.L79:
+	jmp .L78
+.L80:
+	movq %rbx, %rdi
+	call golite_big_mul
+	jmp .L81
+.L81:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_add:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	pushq %r13
+	subq $8, %rsp
+	movq %rdx, %r8
+	movq %rsi, %r11
+	movq %rdi, %rsi
+	movq %r11, %rdi
+	movq $0, %r9
+	movq $0, %r10
+.L82:
+	cmpq $40, %r9
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rbx
+	cmpq $0, %rbx
+	je .L84
+	movq %r9, %rbx
+	imulq $8, %rbx
+	movq %rdi, %r12
+	addq %rbx, %r12
+	movq (%r12), %r11
+	movq %r11, %rbx
+	movq %r9, %r12
+	imulq $8, %r12
+	movq %r8, %r13
+	addq %r12, %r13
+	movq (%r13), %r11
+	movq %r11, %r12
+	movq %rbx, %r13
+	addq %r12, %r13
+	movq %r13, %rbx
+	addq %r10, %rbx
+	movq %rbx, %r12
+	movq %r9, %rbx
+	imulq $8, %rbx
+	movq %rsi, %r13
+	addq %rbx, %r13
+	movq %r12, %rbx
+	movabsq $4294967295, %rax
+	andq %rax, %rbx
+	movq %rbx, (%r13)
+	movq %r12, %rbx
+	sarq $32, %rbx
+	movq %rbx, %r10
+	movq %r9, %rbx
+	addq $1, %rbx
+	movq %rbx, %r9
+.L83:
+	jmp .L82
+.L84:
+	jmp .L85
+.L85:
+	leaq -24(%rbp), %rsp
+	popq %r13
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_sub:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	movq %rsi, %r11
+	movq %rdi, %rsi
+	movq %r11, %rdi
+	movq $0, %r8
+	movq $0, %r9
+.L86:
+	cmpq $40, %r8
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r10
+	cmpq $0, %r10
+	je .L88
+	movq %r8, %r10
+	imulq $8, %r10
+	movq %rsi, %rbx
+	addq %r10, %rbx
+	movq (%rbx), %r11
+	movq %r11, %r10
+	movq %r8, %rbx
+	imulq $8, %rbx
+	movq %rdi, %r12
+	addq %rbx, %r12
+	movq (%r12), %r11
+	movq %r11, %rbx
+	movq %r10, %r12
+	subq %rbx, %r12
+	movq %r12, %r10
+	subq %r9, %r10
+	movq %r10, %rbx
+	movq $0, %r9
+	cmpq $0, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r10
+	cmpq $0, %r10
+	je .L89
+	movq %rbx, %r10
+	movabsq $4294967296, %rax
+	addq %rax, %r10
+	movq %r10, %rbx
+	movq $1, %r9
+	jmp .L90
+.L89:
+.L90:
+	movq %r8, %r10
+	imulq $8, %r10
+	movq %rsi, %r12
+	addq %r10, %r12
+	movq %rbx, (%r12)
+	movq %r8, %r10
+	addq $1, %r10
+	movq %r10, %r8
+.L87:
+	jmp .L86
+.L88:
+	jmp .L91
+.L91:
+	leaq -16(%rbp), %rsp
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_big_cmp:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %r11
+	movq %rdi, %rsi
+	movq %r11, %rdi
+	movq $39, %r8
+.L92:
+	cmpq $0, %r8
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r9
+	cmpq $0, %r9
+	je .L94
+	movq %r8, %r9
+	imulq $8, %r9
+	movq %rsi, %r10
+	addq %r9, %r10
+	movq (%r10), %r11
+	movq %r11, %r9
+	movq %r9, %r10
+	movq %r8, %r9
+	imulq $8, %r9
+	movq %rdi, %rbx
+	addq %r9, %rbx
+	movq (%rbx), %r11
+	movq %r11, %r9
+	movq %r9, %rbx
+	cmpq %rbx, %r10
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r9
+	cmpq $0, %r9
+	je .L95
+	movq $-1, %rax
+	jmp .L99
+	jmp .L96
+.L95:
+.L96:
+	cmpq %rbx, %r10
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r9
+	cmpq $0, %r9
+	je .L97
+	movq $1, %rax
+	jmp .L99
+	jmp .L98
+.L97:
+.L98:
+	movq %r8, %r9
+	addq $-1, %r9
+	movq %r9, %r8
+.L93:
+	jmp .L92
+.L94:
+	movq $0, %rax
+	jmp .L99
+.L99:
+	leaq -8(%rbp), %rsp
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_emit_float:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	pushq %r12
+	pushq %r13
+	pushq %r14
+	pushq %r15
+	subq $24, %rsp
+	movapd %xmm0, %xmm8
+	movq %xmm8, %r11
+	movq %r11, %rsi
+	movq %rsi, %rbx
+	movq %rbx, %rsi
+	sarq $52, %rsi
+	movq %rsi, %rdi
+	andq $2047, %rdi
+	movq %rdi, %r12
+	movq %rbx, %rsi
+	movabsq $4503599627370495, %rax
+	andq %rax, %rsi
+	movq %rsi, %r13
+	cmpq $2047, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L100
+	cmpq $0, %r13
+	setne %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L102
+	leaq data.golite_text.8(%rip), %rsi
+	movq %rsi, %rdi
+	movq $3, %rsi
+	call golite_emit
+	jmp .L185
+	jmp .L103
+.L102:
+.L103:
+	cmpq $0, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L104
+	leaq data.golite_text.10(%rip), %rsi
+	movq %rsi, %rdi
+	movq $4, %rsi
+	call golite_emit
+	jmp .L105
+.L104:
+	leaq data.golite_text.9(%rip), %rsi
+	movq %rsi, %rdi
+	movq $4, %rsi
+	call golite_emit
+.L105:
+	jmp .L185
+	jmp .L101
+.L100:
+.L101:
+	cmpq $0, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L106
+	movq $45, %rdi
+	call golite_emit_byte
+	jmp .L107
+.L106:
+.L107:
+	cmpq $0, %r12
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L110
+	cmpq $0, %r13
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+.L110:
+	cmpq $0, %rdi
+	je .L108
+	movq $48, %rdi
+	call golite_emit_byte
+	jmp .L185
+	jmp .L109
+.L108:
+.L109:
+	movq %r13, %rbx
+	movq $-1074, %r14
+	cmpq $0, %r12
+	setne %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L111
+	movq %r13, %rsi
+	movabsq $4503599627370496, %rax
+	addq %rax, %rsi
+	movq %rsi, %rbx
+	movq %r12, %rsi
+	subq $1075, %rsi
+	movq %rsi, %r14
+	jmp .L112
+.L111:
+.L112:
+	movq %rbx, %rsi
+	andq $1, %rsi
+	cmpq $0, %rsi
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rdi
+	movq %rdi, %r13
+	cmpq $0, %r14
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L113
+	movabsq $4503599627370496, %rax
+	cmpq %rax, %rbx
+	setne %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L115
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rsi, %rdi
+	movq %rbx, %rsi
+	call golite_big_set
+	leaq data.golite_big_r(%rip), %rsi
+	movq %r14, %rdi
+	addq $1, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_shl
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq $2, %rsi
+	call golite_big_set
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq %r14, %rsi
+	call golite_big_shl
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq %r14, %rsi
+	call golite_big_shl
+	jmp .L116
+.L115:
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rsi, %rdi
+	movq %rbx, %rsi
+	call golite_big_set
+	leaq data.golite_big_r(%rip), %rsi
+	movq %r14, %rdi
+	addq $2, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_shl
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq $4, %rsi
+	call golite_big_set
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %r14, %rdi
+	addq $1, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_shl
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq %r14, %rsi
+	call golite_big_shl
+.L116:
+	jmp .L114
+.L113:
+	cmpq $1, %r12
+	setle %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L120
+	jmp .L119
+.L120:
+	movabsq $4503599627370496, %rax
+	cmpq %rax, %rbx
+	setne %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+.L119:
+	cmpq $0, %rdi
+	je .L117
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rbx, %rdi
+	imulq $2, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_set
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_s(%rip), %rsi
+	movq $1, %rdi
+	subq %r14, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_shl
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	jmp .L118
+.L117:
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rbx, %rdi
+	imulq $4, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_set
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+	leaq data.golite_big_s(%rip), %rsi
+	movq $2, %rdi
+	subq %r14, %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_shl
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $2, %rsi
+	call golite_big_set
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $1, %rsi
+	call golite_big_set
+.L118:
+.L114:
+	movq %r14, %rsi
+	addq $52, %rsi
+	xorpd %xmm8, %xmm8
+	cvtsi2sdq %rsi, %xmm8
+	movabsq $4599094494223104510, %r11
+	movq %r11, %xmm9
+	movapd %xmm8, %xmm10
+	mulsd %xmm9, %xmm10
+	cvttsd2siq %xmm10, %r11
+	movq %r11, %rsi
+	movq %rsi, -48(%rbp)
+	cmpq $0, -48(%rbp)
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L121
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq -48(%rbp), %rsi
+	call golite_big_pow10
+	jmp .L122
+.L121:
+	leaq data.golite_big_r(%rip), %rsi
+	movq $0, %rdi
+	subq -48(%rbp), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_pow10
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq $0, %rdi
+	subq -48(%rbp), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_pow10
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq $0, %rdi
+	subq -48(%rbp), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_pow10
+.L122:
+.L123:
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_r(%rip), %rdi
+	leaq data.golite_big_mplus(%rip), %r8
+	movq %r8, %rdx
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_add
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $1, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %r12
+	cmpq $0, %r12
+	je .L129
+	jmp .L128
+.L129:
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $0, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L130
+	movq %r13, %rdi
+.L130:
+	movq %rdi, %r12
+.L128:
+	movq %r12, %rsi
+	xorq $1, %rsi
+	cmpq $0, %rsi
+	je .L126
+	jmp .L125
+	jmp .L127
+.L126:
+.L127:
+	leaq data.golite_big_s(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	movq -48(%rbp), %rsi
+	addq $1, %rsi
+	movq %rsi, -48(%rbp)
+.L124:
+	jmp .L123
+.L125:
+.L131:
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_r(%rip), %rdi
+	leaq data.golite_big_mplus(%rip), %r8
+	movq %r8, %rdx
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_add
+	leaq data.golite_big_high(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $1, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %r12
+	cmpq $0, %r12
+	je .L137
+	jmp .L136
+.L137:
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $0, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L138
+	movq %r13, %rdi
+.L138:
+	movq %rdi, %r12
+.L136:
+	cmpq $0, %r12
+	je .L134
+	jmp .L133
+	jmp .L135
+.L134:
+.L135:
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	movq -48(%rbp), %rsi
+	addq $-1, %rsi
+	movq %rsi, -48(%rbp)
+.L132:
+	jmp .L131
+.L133:
+	movq $0, -56(%rbp)
+.L139:
+	leaq data.golite_big_r(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	leaq data.golite_big_mplus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	leaq data.golite_big_mminus(%rip), %rsi
+	movq %rsi, %rdi
+	movq $10, %rsi
+	call golite_big_mul
+	movq $0, %r14
+.L142:
+	leaq data.golite_big_r(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $0, %r8
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L144
+	leaq data.golite_big_r(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_sub
+	movq %r14, %rsi
+	addq $1, %rsi
+	movq %rsi, %r14
+.L143:
+	jmp .L142
+.L144:
+	leaq data.golite_big_r(%rip), %rsi
+	leaq data.golite_big_mminus(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $-1, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %r15
+	cmpq $0, %r15
+	je .L146
+	jmp .L145
+.L146:
+	leaq data.golite_big_r(%rip), %rsi
+	leaq data.golite_big_mminus(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $0, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L147
+	movq %r13, %rdi
+.L147:
+	movq %rdi, %r15
+.L145:
+	movq %r15, %rbx
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_r(%rip), %rdi
+	leaq data.golite_big_mplus(%rip), %r8
+	movq %r8, %rdx
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_add
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $1, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %r15
+	cmpq $0, %r15
+	je .L149
+	jmp .L148
+.L149:
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	cmpq $0, %r8
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L150
+	movq %r13, %rdi
+.L150:
+	movq %rdi, %r15
+.L148:
+	movq %r15, %r12
+	movq %rbx, %rsi
+	cmpq $0, %rsi
+	je .L153
+	movq %r12, %rsi
+.L153:
+	cmpq $0, %rsi
+	je .L151
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_r(%rip), %rdi
+	leaq data.golite_big_r(%rip), %r8
+	movq %r8, %rdx
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_add
+	leaq data.golite_big_high(%rip), %rsi
+	leaq data.golite_big_s(%rip), %rdi
+	movq %rdi, %r11
+	movq %rsi, %rdi
+	movq %r11, %rsi
+	call golite_big_cmp
+	movq %rax, %r8
+	movq %r8, %r15
+	cmpq $0, %r15
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L157
+	jmp .L156
+.L157:
+	cmpq $0, %r15
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %r8
+	cmpq $0, %r8
+	je .L158
+	movq %r14, %rsi
+	andq $1, %rsi
+	cmpq $1, %rsi
+	sete %r11b
+	movzbq %r11b, %r11
+	movq %r11, %r9
+	movq %r9, %r8
+.L158:
+	movq %r8, %rdi
+.L156:
+	cmpq $0, %rdi
+	je .L154
+	movq %r14, %rsi
+	addq $1, %rsi
+	movq %rsi, %r14
+	jmp .L155
+.L154:
+.L155:
+	jmp .L152
+.L151:
+	cmpq $0, %r12
+	je .L159
+	movq %r14, %rsi
+	addq $1, %rsi
+	movq %rsi, %r14
+	jmp .L160
+.L159:
+.L160:
+.L152:
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	addq -56(%rbp), %rdi
+	movq $48, %rsi
+	addq %r14, %rsi
+	movb %sil, (%rdi)
+	movq -56(%rbp), %rsi
+	addq $1, %rsi
+	movq %rsi, -56(%rbp)
+	movq %rbx, %rsi
+	cmpq $0, %rsi
+	je .L164
+	jmp .L163
+.L164:
+	movq %r12, %rsi
+.L163:
+	cmpq $0, %rsi
+	je .L161
+	jmp .L141
+	jmp .L162
+.L161:
+.L162:
+.L140:
+	jmp .L139
+.L141:
+	movq -48(%rbp), %rsi
+	subq $1, %rsi
+	movq %rsi, %r15
+	cmpq $-4, %r15
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+	cmpq $0, %rdi
+	je .L168
+	jmp .L167
+.L168:
+	cmpq $6, %r15
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	movq %rsi, %rdi
+.L167:
+	cmpq $0, %rdi
+	je .L165
+	leaq data.golite_digits(%rip), %rsi
+	movzbq (%rsi), %r11
+	movq %r11, %rdi
+	call golite_emit_byte
+	cmpq $1, -56(%rbp)
+	setg %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L169
+	movq $46, %rdi
+	call golite_emit_byte
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	addq $1, %rdi
+	movq -56(%rbp), %rsi
+	subq $1, %rsi
+	call golite_emit
+	jmp .L170
+.L169:
+.L170:
+	movq $101, %rdi
+	call golite_emit_byte
+	cmpq $0, %r15
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L171
+	movq $45, %rdi
+	call golite_emit_byte
+	movq $0, %rsi
+	subq %r15, %rsi
+	movq %rsi, %r15
+	jmp .L172
+.L171:
+	movq $43, %rdi
+	call golite_emit_byte
+.L172:
+	cmpq $10, %r15
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L173
+	movq $48, %rdi
+	call golite_emit_byte
+	jmp .L174
+.L173:
+.L174:
+	movq %r15, %rdi
+	call golite_emit_int
+	jmp .L166
+.L165:
+	cmpq $0, -48(%rbp)
+	setle %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L175
+	movq $48, %rdi
+	call golite_emit_byte
+	movq $46, %rdi
+	call golite_emit_byte
+	movq -48(%rbp), %rbx
+.L177:
+	cmpq $0, %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L179
+	movq $48, %rdi
+	call golite_emit_byte
+	movq %rbx, %rsi
+	addq $1, %rsi
+	movq %rsi, %rbx
+.L178:
+	jmp .L177
+.L179:
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	movq -56(%rbp), %rsi
+	call golite_emit
+	jmp .L176
+.L175:
+	movq -48(%rbp), %r11
+	cmpq -56(%rbp), %r11
+	setge %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L180
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	movq -56(%rbp), %rsi
+	call golite_emit
+	movq -56(%rbp), %rbx
+.L182:
+	cmpq -48(%rbp), %rbx
+	setl %r11b
+	movzbq %r11b, %r11
+	movq %r11, %rsi
+	cmpq $0, %rsi
+	je .L184
+	movq $48, %rdi
+	call golite_emit_byte
+	movq %rbx, %rsi
+	addq $1, %rsi
+	movq %rsi, %rbx
+.L183:
+	jmp .L182
+.L184:
+	jmp .L181
+.L180:
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	movq -48(%rbp), %rsi
+	call golite_emit
+	movq $46, %rdi
+	call golite_emit_byte
+	leaq data.golite_digits(%rip), %rsi
+	movq %rsi, %rdi
+	addq -48(%rbp), %rdi
+	movq -56(%rbp), %rsi
+	subq -48(%rbp), %rsi
+	call golite_emit
+.L181:
+.L176:
+.L166:
+	jmp .L185
+.L185:
+	leaq -40(%rbp), %rsp
+	popq %r15
+	popq %r14
+	popq %r13
+	popq %r12
+	popq %rbx
+	popq %rbp
+	ret
+
+golite_print_float:
+	pushq %rbp
+	movq %rsp, %rbp
+	movapd %xmm0, %xmm8
+	movapd %xmm8, %xmm0
+	call golite_emit_float
+	movq $10, %rdi
+	call golite_emit_byte
+	jmp .L186
+.L186:
+	movq %rbp, %rsp
+	popq %rbp
+	ret
+
+	.section .rodata
+	.balign 16
+.Lsign:
+	.quad 0x8000000000000000, 0
+
+	.data
+	.balign 8
+data.golite_text.0:
+	.byte 116, 114, 117, 101
+	.balign 8
+data.golite_text.1:
+	.byte 102, 97, 108, 115, 101
+	.balign 8
+data.golite_text.2:
+	.byte 114, 117, 110, 116, 105, 109, 101, 32, 101, 114, 114, 111, 114, 32, 97, 116
+	.byte 32
+	.balign 8
+data.golite_text.3:
+	.byte 58
+	.balign 8
+data.golite_text.4:
+	.byte 58, 32
+	.balign 8
+data.golite_text.5:
+	.byte 105, 110, 116, 101, 103, 101, 114, 32, 100, 105, 118, 105, 100, 101, 32, 98
+	.byte 121, 32, 122, 101, 114, 111, 10
+	.balign 8
+data.golite_text.6:
+	.byte 110, 101, 103, 97, 116, 105, 118, 101, 32, 115, 104, 105, 102, 116, 32, 97
+	.byte 109, 111, 117, 110, 116, 58, 32
+	.balign 8
+data.golite_text.7:
+	.byte 10
+	.balign 8
+data.golite_text.8:
+	.byte 78, 97, 78
+	.balign 8
+data.golite_text.9:
+	.byte 43, 73, 110, 102
+	.balign 8
+data.golite_text.10:
+	.byte 45, 73, 110, 102
+	.balign 8
+data.str.0:
+	.byte 4, 0, 0, 0, 0, 0, 0, 0, 100, 111, 110, 101
+
+	.bss
+	.balign 8
+data.golite_out:
+	.zero 4096
+	.balign 8
+data.golite_num:
+	.zero 32
+	.balign 8
+data.golite_digits:
+	.zero 32
+	.balign 8
+data.golite_big_r:
+	.zero 320
+	.balign 8
+data.golite_big_s:
+	.zero 320
+	.balign 8
+data.golite_big_mplus:
+	.zero 320
+	.balign 8
+data.golite_big_mminus:
+	.zero 320
+	.balign 8
+data.golite_big_high:
+	.zero 320
+	.balign 8
+total:
+	.zero 8
+	.balign 8
+golite_out_len:
+	.zero 8
+
+	.section .note.GNU-stack,"",@progbits
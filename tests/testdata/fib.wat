@@ -0,0 +1,1572 @@
+(module
+  (type (;0;) (func (param i32 i32 i32 i32) (result i32)))
+  (type (;1;) (func (param i32)))
+  (type (;2;) (func (param i64) (result i64)))
+  (type (;3;) (func))
+  (type (;4;) (func (param i64 i64)))
+  (type (;5;) (func (param i64)))
+  (type (;6;) (func (param i64 i64 i64 i64) (result i64)))
+  (type (;7;) (func (param i64 i64 i64)))
+  (type (;8;) (func (param i64 i64) (result i64)))
+  (type (;9;) (func (param f64)))
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (type 0)))
+  (import "wasi_snapshot_preview1" "proc_exit" (func $proc_exit (type 1)))
+  (memory (export "memory") 1)
+  (global $total (mut i64) (i64.const 0))
+  (global $golite_out_len (mut i64) (i64.const 0))
+  (func $fib (type 2) (param $n i64) (result i64)
+    local.get $n
+    i64.const 2
+    i64.lt_s
+    if
+      local.get $n
+      return
+    end
+    local.get $n
+    i64.const 1
+    i64.sub
+    call $fib
+    local.get $n
+    i64.const 2
+    i64.sub
+    call $fib
+    i64.add
+    return
+  )
+  (func $main (type 3)
+    (local $i i64)
+    i64.const 0
+    global.set $total
+    i64.const 0
+    local.set $i
+    block
+      loop
+        local.get $i
+        i64.const 10
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $i
+          i64.const 2
+          i64.rem_s
+          i64.const 0
+          i64.eq
+          if (result i32)
+            local.get $i
+            i64.const 0
+            i64.gt_s
+          else
+            i32.const 0
+          end
+          if
+            br 1
+          end
+          global.get $total
+          local.get $i
+          call $fib
+          i64.add
+          global.set $total
+        end
+        local.get $i
+        i64.const 1
+        i64.add
+        local.set $i
+        br 0
+      end
+    end
+    global.get $total
+    call $golite_print_int
+    global.get $total
+    i64.const 50
+    i64.gt_s
+    call $golite_print_bool
+    global.get $total
+    f64.convert_i64_s
+    f64.const 4
+    f64.div
+    call $golite_print_float
+    i64.const 5912
+    call $golite_print_string
+    call $golite_flush
+    return
+  )
+  (func $golite_flush (type 3)
+    global.get $golite_out_len
+    i64.const 0
+    i64.gt_s
+    if
+      i32.const 0
+      i64.const 16
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      global.get $golite_out_len
+      i32.wrap_i64
+      i32.store
+      i32.const 1
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      i64.const 0
+      global.set $golite_out_len
+    end
+    return
+  )
+  (func $golite_emit (type 4) (param $a i64) (param $n i64)
+    (local $i i64)
+    global.get $golite_out_len
+    local.get $n
+    i64.add
+    i64.const 4096
+    i64.gt_s
+    if
+      call $golite_flush
+    end
+    local.get $n
+    i64.const 4096
+    i64.gt_s
+    if
+      i32.const 0
+      local.get $a
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      local.get $n
+      i32.wrap_i64
+      i32.store
+      i32.const 1
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      return
+    end
+    i64.const 0
+    local.set $i
+    block
+      loop
+        local.get $i
+        local.get $n
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          i64.const 16
+          global.get $golite_out_len
+          local.get $i
+          i64.add
+          i64.add
+          i32.wrap_i64
+          local.get $a
+          local.get $i
+          i64.add
+          i32.wrap_i64
+          i64.load8_u
+          i64.store8
+          local.get $i
+          i64.const 1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    global.get $golite_out_len
+    local.get $n
+    i64.add
+    global.set $golite_out_len
+    return
+  )
+  (func $golite_emit_byte (type 5) (param $b i64)
+    global.get $golite_out_len
+    i64.const 4096
+    i64.eq
+    if
+      call $golite_flush
+    end
+    i64.const 16
+    global.get $golite_out_len
+    i64.add
+    i32.wrap_i64
+    local.get $b
+    i64.store8
+    global.get $golite_out_len
+    i64.const 1
+    i64.add
+    global.set $golite_out_len
+    return
+  )
+  (func $golite_format_int (type 2) (param $n i64) (result i64)
+    (local $p i64)
+    (local $neg i32)
+    i64.const 4112
+    i64.const 32
+    i64.add
+    local.set $p
+    local.get $n
+    i64.const 0
+    i64.lt_s
+    local.set $neg
+    local.get $neg
+    i32.eqz
+    if
+      i64.const 0
+      local.get $n
+      i64.sub
+      local.set $n
+    end
+    block
+      loop
+        block
+          local.get $p
+          i64.const -1
+          i64.add
+          local.set $p
+          local.get $p
+          i32.wrap_i64
+          i64.const 48
+          local.get $n
+          i64.const 10
+          i64.rem_s
+          i64.sub
+          i64.store8
+          local.get $n
+          i64.const 10
+          i64.div_s
+          local.set $n
+          local.get $n
+          i64.const 0
+          i64.eq
+          if
+            br 3
+          end
+        end
+        br 0
+      end
+    end
+    local.get $neg
+    if
+      local.get $p
+      i64.const -1
+      i64.add
+      local.set $p
+      local.get $p
+      i32.wrap_i64
+      i64.const 45
+      i64.store8
+    end
+    local.get $p
+    return
+  )
+  (func $golite_emit_int (type 5) (param $n i64)
+    (local $p i64)
+    local.get $n
+    call $golite_format_int
+    local.set $p
+    local.get $p
+    i64.const 4112
+    i64.const 32
+    i64.add
+    local.get $p
+    i64.sub
+    call $golite_emit
+    return
+  )
+  (func $golite_write_int (type 5) (param $n i64)
+    (local $p i64)
+    local.get $n
+    call $golite_format_int
+    local.set $p
+    i32.const 0
+    local.get $p
+    i32.wrap_i64
+    i32.store
+    i32.const 4
+    i64.const 4112
+    i64.const 32
+    i64.add
+    local.get $p
+    i64.sub
+    i32.wrap_i64
+    i32.store
+    i32.const 2
+    i32.const 0
+    i32.const 1
+    i32.const 8
+    call $fd_write
+    drop
+    return
+  )
+  (func $golite_print_int (type 5) (param $n i64)
+    local.get $n
+    call $golite_emit_int
+    i64.const 10
+    call $golite_emit_byte
+    return
+  )
+  (func $golite_print_bool (type 1) (param $b i32)
+    local.get $b
+    if
+      i64.const 4176
+      i64.const 4
+      call $golite_emit
+    else
+      i64.const 4184
+      i64.const 5
+      call $golite_emit
+    end
+    i64.const 10
+    call $golite_emit_byte
+    return
+  )
+  (func $golite_print_string (type 5) (param $s i64)
+    local.get $s
+    i64.const 8
+    i64.add
+    local.get $s
+    i32.wrap_i64
+    i64.load
+    call $golite_emit
+    i64.const 10
+    call $golite_emit_byte
+    return
+  )
+  (func $golite_error_at (type 4) (param $line i64) (param $column i64)
+    call $golite_flush
+    i32.const 0
+    i64.const 4192
+    i32.wrap_i64
+    i32.store
+    i32.const 4
+    i64.const 17
+    i32.wrap_i64
+    i32.store
+    i32.const 2
+    i32.const 0
+    i32.const 1
+    i32.const 8
+    call $fd_write
+    drop
+    local.get $line
+    call $golite_write_int
+    i32.const 0
+    i64.const 4216
+    i32.wrap_i64
+    i32.store
+    i32.const 4
+    i64.const 1
+    i32.wrap_i64
+    i32.store
+    i32.const 2
+    i32.const 0
+    i32.const 1
+    i32.const 8
+    call $fd_write
+    drop
+    local.get $column
+    call $golite_write_int
+    i32.const 0
+    i64.const 4224
+    i32.wrap_i64
+    i32.store
+    i32.const 4
+    i64.const 2
+    i32.wrap_i64
+    i32.store
+    i32.const 2
+    i32.const 0
+    i32.const 1
+    i32.const 8
+    call $fd_write
+    drop
+    return
+  )
+  (func $golite_div (type 6) (param $a i64) (param $b i64) (param $line i64) (param $column i64) (result i64)
+    local.get $b
+    i64.const 0
+    i64.eq
+    if
+      local.get $line
+      local.get $column
+      call $golite_error_at
+      i32.const 0
+      i64.const 4232
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 23
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      i32.const 1
+      call $proc_exit
+      unreachable
+    end
+    local.get $b
+    i64.const -1
+    i64.eq
+    if
+      i64.const 0
+      local.get $a
+      i64.sub
+      return
+    end
+    local.get $a
+    local.get $b
+    i64.div_s
+    return
+  )
+  (func $golite_mod (type 6) (param $a i64) (param $b i64) (param $line i64) (param $column i64) (result i64)
+    local.get $b
+    i64.const 0
+    i64.eq
+    if
+      local.get $line
+      local.get $column
+      call $golite_error_at
+      i32.const 0
+      i64.const 4232
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 23
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      i32.const 1
+      call $proc_exit
+      unreachable
+    end
+    local.get $b
+    i64.const -1
+    i64.eq
+    if
+      i64.const 0
+      return
+    end
+    local.get $a
+    local.get $b
+    i64.rem_s
+    return
+  )
+  (func $golite_shl (type 6) (param $a i64) (param $n i64) (param $line i64) (param $column i64) (result i64)
+    local.get $n
+    i64.const 0
+    i64.lt_s
+    if
+      local.get $line
+      local.get $column
+      call $golite_error_at
+      i32.const 0
+      i64.const 4256
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 23
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      local.get $n
+      call $golite_write_int
+      i32.const 0
+      i64.const 4280
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 1
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      i32.const 1
+      call $proc_exit
+      unreachable
+    end
+    local.get $n
+    i64.const 64
+    i64.ge_s
+    if
+      i64.const 0
+      return
+    end
+    local.get $a
+    local.get $n
+    i64.shl
+    return
+  )
+  (func $golite_shr (type 6) (param $a i64) (param $n i64) (param $line i64) (param $column i64) (result i64)
+    local.get $n
+    i64.const 0
+    i64.lt_s
+    if
+      local.get $line
+      local.get $column
+      call $golite_error_at
+      i32.const 0
+      i64.const 4256
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 23
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      local.get $n
+      call $golite_write_int
+      i32.const 0
+      i64.const 4280
+      i32.wrap_i64
+      i32.store
+      i32.const 4
+      i64.const 1
+      i32.wrap_i64
+      i32.store
+      i32.const 2
+      i32.const 0
+      i32.const 1
+      i32.const 8
+      call $fd_write
+      drop
+      i32.const 1
+      call $proc_exit
+      unreachable
+    end
+    local.get $n
+    i64.const 64
+    i64.ge_s
+    if
+      i64.const 63
+      local.set $n
+    end
+    local.get $a
+    local.get $n
+    i64.shr_s
+    return
+  )
+  (func $golite_big_set (type 4) (param $a i64) (param $v i64)
+    (local $i i64)
+    i64.const 0
+    local.set $i
+    block
+      loop
+        local.get $i
+        i64.const 40
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.const 0
+          i64.store
+          local.get $i
+          i64.const 1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    local.get $a
+    i64.const 0
+    i64.const 8
+    i64.mul
+    i64.add
+    i32.wrap_i64
+    local.get $v
+    i64.const 4294967295
+    i64.and
+    i64.store
+    local.get $a
+    i64.const 1
+    i64.const 8
+    i64.mul
+    i64.add
+    i32.wrap_i64
+    local.get $v
+    i64.const 32
+    i64.shr_s
+    i64.store
+    return
+  )
+  (func $golite_big_mul (type 4) (param $a i64) (param $m i64)
+    (local $i i64)
+    (local $t i64)
+    (local $carry i64)
+    i64.const 0
+    local.set $i
+    i64.const 0
+    local.set $carry
+    block
+      loop
+        local.get $i
+        i64.const 40
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          local.get $m
+          i64.mul
+          local.get $carry
+          i64.add
+          local.set $t
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          local.get $t
+          i64.const 4294967295
+          i64.and
+          i64.store
+          local.get $t
+          i64.const 32
+          i64.shr_s
+          local.set $carry
+          local.get $i
+          i64.const 1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    return
+  )
+  (func $golite_big_shl (type 4) (param $a i64) (param $n i64)
+    block
+      loop
+        local.get $n
+        i64.const 30
+        i64.ge_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          i64.const 1073741824
+          call $golite_big_mul
+          local.get $n
+          i64.const -30
+          i64.add
+          local.set $n
+        end
+        br 0
+      end
+    end
+    local.get $a
+    i64.const 1
+    local.get $n
+    i64.shl
+    call $golite_big_mul
+    return
+  )
+  (func $golite_big_pow10 (type 4) (param $a i64) (param $k i64)
+    (local $m i64)
+    block
+      loop
+        local.get $k
+        i64.const 9
+        i64.ge_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          i64.const 1000000000
+          call $golite_big_mul
+          local.get $k
+          i64.const -9
+          i64.add
+          local.set $k
+        end
+        br 0
+      end
+    end
+    i64.const 1
+    local.set $m
+    block
+      loop
+        local.get $k
+        i64.const 0
+        i64.gt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $m
+          i64.const 10
+          i64.mul
+          local.set $m
+          local.get $k
+          i64.const -1
+          i64.add
+          local.set $k
+        end
+        br 0
+      end
+    end
+    local.get $a
+    local.get $m
+    call $golite_big_mul
+    return
+  )
+  (func $golite_big_add (type 7) (param $d i64) (param $a i64) (param $b i64)
+    (local $i i64)
+    (local $t i64)
+    (local $carry i64)
+    i64.const 0
+    local.set $i
+    i64.const 0
+    local.set $carry
+    block
+      loop
+        local.get $i
+        i64.const 40
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          local.get $b
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          i64.add
+          local.get $carry
+          i64.add
+          local.set $t
+          local.get $d
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          local.get $t
+          i64.const 4294967295
+          i64.and
+          i64.store
+          local.get $t
+          i64.const 32
+          i64.shr_s
+          local.set $carry
+          local.get $i
+          i64.const 1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    return
+  )
+  (func $golite_big_sub (type 4) (param $a i64) (param $b i64)
+    (local $i i64)
+    (local $t i64)
+    (local $borrow i64)
+    i64.const 0
+    local.set $i
+    i64.const 0
+    local.set $borrow
+    block
+      loop
+        local.get $i
+        i64.const 40
+        i64.lt_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          local.get $b
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          i64.sub
+          local.get $borrow
+          i64.sub
+          local.set $t
+          i64.const 0
+          local.set $borrow
+          local.get $t
+          i64.const 0
+          i64.lt_s
+          if
+            local.get $t
+            i64.const 4294967296
+            i64.add
+            local.set $t
+            i64.const 1
+            local.set $borrow
+          end
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          local.get $t
+          i64.store
+          local.get $i
+          i64.const 1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    return
+  )
+  (func $golite_big_cmp (type 8) (param $a i64) (param $b i64) (result i64)
+    (local $i i64)
+    (local $x i64)
+    (local $y i64)
+    i64.const 39
+    local.set $i
+    block
+      loop
+        local.get $i
+        i64.const 0
+        i64.ge_s
+        i32.eqz
+        br_if 1
+        block
+          local.get $a
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          local.set $x
+          local.get $b
+          local.get $i
+          i64.const 8
+          i64.mul
+          i64.add
+          i32.wrap_i64
+          i64.load
+          local.set $y
+          local.get $x
+          local.get $y
+          i64.lt_s
+          if
+            i64.const -1
+            return
+          end
+          local.get $x
+          local.get $y
+          i64.gt_s
+          if
+            i64.const 1
+            return
+          end
+          local.get $i
+          i64.const -1
+          i64.add
+          local.set $i
+        end
+        br 0
+      end
+    end
+    i64.const 0
+    return
+  )
+  (func $golite_emit_float (type 9) (param $v f64)
+    (local $bits i64)
+    (local $exp i64)
+    (local $frac i64)
+    (local $f i64)
+    (local $e i64)
+    (local $even i32)
+    (local $k i64)
+    (local $c i64)
+    (local $digit i64)
+    (local $nd i64)
+    (local $low i32)
+    (local $up i32)
+    (local $j i64)
+    local.get $v
+    i64.reinterpret_f64
+    local.set $bits
+    local.get $bits
+    i64.const 52
+    i64.shr_s
+    i64.const 2047
+    i64.and
+    local.set $exp
+    local.get $bits
+    i64.const 4503599627370495
+    i64.and
+    local.set $frac
+    local.get $exp
+    i64.const 2047
+    i64.eq
+    if
+      local.get $frac
+      i64.const 0
+      i64.ne
+      if
+        i64.const 5888
+        i64.const 3
+        call $golite_emit
+        return
+      end
+      local.get $bits
+      i64.const 0
+      i64.lt_s
+      if
+        i64.const 5904
+        i64.const 4
+        call $golite_emit
+      else
+        i64.const 5896
+        i64.const 4
+        call $golite_emit
+      end
+      return
+    end
+    local.get $bits
+    i64.const 0
+    i64.lt_s
+    if
+      i64.const 45
+      call $golite_emit_byte
+    end
+    local.get $exp
+    i64.const 0
+    i64.eq
+    if (result i32)
+      local.get $frac
+      i64.const 0
+      i64.eq
+    else
+      i32.const 0
+    end
+    if
+      i64.const 48
+      call $golite_emit_byte
+      return
+    end
+    local.get $frac
+    local.set $f
+    i64.const -1074
+    local.set $e
+    local.get $exp
+    i64.const 0
+    i64.ne
+    if
+      local.get $frac
+      i64.const 4503599627370496
+      i64.add
+      local.set $f
+      local.get $exp
+      i64.const 1075
+      i64.sub
+      local.set $e
+    end
+    local.get $f
+    i64.const 1
+    i64.and
+    i64.const 0
+    i64.eq
+    local.set $even
+    local.get $e
+    i64.const 0
+    i64.ge_s
+    if
+      local.get $f
+      i64.const 4503599627370496
+      i64.ne
+      if
+        i64.const 4288
+        local.get $f
+        call $golite_big_set
+        i64.const 4288
+        local.get $e
+        i64.const 1
+        i64.add
+        call $golite_big_shl
+        i64.const 4608
+        i64.const 2
+        call $golite_big_set
+        i64.const 4928
+        i64.const 1
+        call $golite_big_set
+        i64.const 4928
+        local.get $e
+        call $golite_big_shl
+        i64.const 5248
+        i64.const 1
+        call $golite_big_set
+        i64.const 5248
+        local.get $e
+        call $golite_big_shl
+      else
+        i64.const 4288
+        local.get $f
+        call $golite_big_set
+        i64.const 4288
+        local.get $e
+        i64.const 2
+        i64.add
+        call $golite_big_shl
+        i64.const 4608
+        i64.const 4
+        call $golite_big_set
+        i64.const 4928
+        i64.const 1
+        call $golite_big_set
+        i64.const 4928
+        local.get $e
+        i64.const 1
+        i64.add
+        call $golite_big_shl
+        i64.const 5248
+        i64.const 1
+        call $golite_big_set
+        i64.const 5248
+        local.get $e
+        call $golite_big_shl
+      end
+    else
+      local.get $exp
+      i64.const 1
+      i64.le_s
+      if (result i32)
+        i32.const 1
+      else
+        local.get $f
+        i64.const 4503599627370496
+        i64.ne
+      end
+      if
+        i64.const 4288
+        local.get $f
+        i64.const 2
+        i64.mul
+        call $golite_big_set
+        i64.const 4608
+        i64.const 1
+        call $golite_big_set
+        i64.const 4608
+        i64.const 1
+        local.get $e
+        i64.sub
+        call $golite_big_shl
+        i64.const 4928
+        i64.const 1
+        call $golite_big_set
+        i64.const 5248
+        i64.const 1
+        call $golite_big_set
+      else
+        i64.const 4288
+        local.get $f
+        i64.const 4
+        i64.mul
+        call $golite_big_set
+        i64.const 4608
+        i64.const 1
+        call $golite_big_set
+        i64.const 4608
+        i64.const 2
+        local.get $e
+        i64.sub
+        call $golite_big_shl
+        i64.const 4928
+        i64.const 2
+        call $golite_big_set
+        i64.const 5248
+        i64.const 1
+        call $golite_big_set
+      end
+    end
+    local.get $e
+    i64.const 52
+    i64.add
+    f64.convert_i64_s
+    f64.const 0.30102999566398114
+    f64.mul
+    i64.trunc_sat_f64_s
+    local.set $k
+    local.get $k
+    i64.const 0
+    i64.ge_s
+    if
+      i64.const 4608
+      local.get $k
+      call $golite_big_pow10
+    else
+      i64.const 4288
+      i64.const 0
+      local.get $k
+      i64.sub
+      call $golite_big_pow10
+      i64.const 4928
+      i64.const 0
+      local.get $k
+      i64.sub
+      call $golite_big_pow10
+      i64.const 5248
+      i64.const 0
+      local.get $k
+      i64.sub
+      call $golite_big_pow10
+    end
+    block
+      loop
+        block
+          i64.const 5568
+          i64.const 4288
+          i64.const 4928
+          call $golite_big_add
+          i64.const 5568
+          i64.const 4608
+          call $golite_big_cmp
+          i64.const 1
+          i64.eq
+          if (result i32)
+            i32.const 1
+          else
+            i64.const 5568
+            i64.const 4608
+            call $golite_big_cmp
+            i64.const 0
+            i64.eq
+            if (result i32)
+              local.get $even
+            else
+              i32.const 0
+            end
+          end
+          i32.eqz
+          if
+            br 3
+          end
+          i64.const 4608
+          i64.const 10
+          call $golite_big_mul
+          local.get $k
+          i64.const 1
+          i64.add
+          local.set $k
+        end
+        br 0
+      end
+    end
+    block
+      loop
+        block
+          i64.const 5568
+          i64.const 4288
+          i64.const 4928
+          call $golite_big_add
+          i64.const 5568
+          i64.const 10
+          call $golite_big_mul
+          i64.const 5568
+          i64.const 4608
+          call $golite_big_cmp
+          i64.const 1
+          i64.eq
+          if (result i32)
+            i32.const 1
+          else
+            i64.const 5568
+            i64.const 4608
+            call $golite_big_cmp
+            i64.const 0
+            i64.eq
+            if (result i32)
+              local.get $even
+            else
+              i32.const 0
+            end
+          end
+          if
+            br 3
+          end
+          i64.const 4288
+          i64.const 10
+          call $golite_big_mul
+          i64.const 4928
+          i64.const 10
+          call $golite_big_mul
+          i64.const 5248
+          i64.const 10
+          call $golite_big_mul
+          local.get $k
+          i64.const -1
+          i64.add
+          local.set $k
+        end
+        br 0
+      end
+    end
+    i64.const 0
+    local.set $nd
+    block
+      loop
+        block
+          i64.const 4288
+          i64.const 10
+          call $golite_big_mul
+          i64.const 4928
+          i64.const 10
+          call $golite_big_mul
+          i64.const 5248
+          i64.const 10
+          call $golite_big_mul
+          i64.const 0
+          local.set $digit
+          block
+            loop
+              i64.const 4288
+              i64.const 4608
+              call $golite_big_cmp
+              i64.const 0
+              i64.ge_s
+              i32.eqz
+              br_if 1
+              block
+                i64.const 4288
+                i64.const 4608
+                call $golite_big_sub
+                local.get $digit
+                i64.const 1
+                i64.add
+                local.set $digit
+              end
+              br 0
+            end
+          end
+          i64.const 4288
+          i64.const 5248
+          call $golite_big_cmp
+          i64.const -1
+          i64.eq
+          if (result i32)
+            i32.const 1
+          else
+            i64.const 4288
+            i64.const 5248
+            call $golite_big_cmp
+            i64.const 0
+            i64.eq
+            if (result i32)
+              local.get $even
+            else
+              i32.const 0
+            end
+          end
+          local.set $low
+          i64.const 5568
+          i64.const 4288
+          i64.const 4928
+          call $golite_big_add
+          i64.const 5568
+          i64.const 4608
+          call $golite_big_cmp
+          i64.const 1
+          i64.eq
+          if (result i32)
+            i32.const 1
+          else
+            i64.const 5568
+            i64.const 4608
+            call $golite_big_cmp
+            i64.const 0
+            i64.eq
+            if (result i32)
+              local.get $even
+            else
+              i32.const 0
+            end
+          end
+          local.set $up
+          local.get $low
+          if (result i32)
+            local.get $up
+          else
+            i32.const 0
+          end
+          if
+            i64.const 5568
+            i64.const 4288
+            i64.const 4288
+            call $golite_big_add
+            i64.const 5568
+            i64.const 4608
+            call $golite_big_cmp
+            local.set $c
+            local.get $c
+            i64.const 0
+            i64.gt_s
+            if (result i32)
+              i32.const 1
+            else
+              local.get $c
+              i64.const 0
+              i64.eq
+              if (result i32)
+                local.get $digit
+                i64.const 1
+                i64.and
+                i64.const 1
+                i64.eq
+              else
+                i32.const 0
+              end
+            end
+            if
+              local.get $digit
+              i64.const 1
+              i64.add
+              local.set $digit
+            end
+          else
+            local.get $up
+            if
+              local.get $digit
+              i64.const 1
+              i64.add
+              local.set $digit
+            end
+          end
+          i64.const 4144
+          local.get $nd
+          i64.add
+          i32.wrap_i64
+          i64.const 48
+          local.get $digit
+          i64.add
+          i64.store8
+          local.get $nd
+          i64.const 1
+          i64.add
+          local.set $nd
+          local.get $low
+          if (result i32)
+            i32.const 1
+          else
+            local.get $up
+          end
+          if
+            br 3
+          end
+        end
+        br 0
+      end
+    end
+    local.get $k
+    i64.const 1
+    i64.sub
+    local.set $c
+    local.get $c
+    i64.const -4
+    i64.lt_s
+    if (result i32)
+      i32.const 1
+    else
+      local.get $c
+      i64.const 6
+      i64.ge_s
+    end
+    if
+      i64.const 4144
+      i32.wrap_i64
+      i64.load8_u
+      call $golite_emit_byte
+      local.get $nd
+      i64.const 1
+      i64.gt_s
+      if
+        i64.const 46
+        call $golite_emit_byte
+        i64.const 4144
+        i64.const 1
+        i64.add
+        local.get $nd
+        i64.const 1
+        i64.sub
+        call $golite_emit
+      end
+      i64.const 101
+      call $golite_emit_byte
+      local.get $c
+      i64.const 0
+      i64.lt_s
+      if
+        i64.const 45
+        call $golite_emit_byte
+        i64.const 0
+        local.get $c
+        i64.sub
+        local.set $c
+      else
+        i64.const 43
+        call $golite_emit_byte
+      end
+      local.get $c
+      i64.const 10
+      i64.lt_s
+      if
+        i64.const 48
+        call $golite_emit_byte
+      end
+      local.get $c
+      call $golite_emit_int
+    else
+      local.get $k
+      i64.const 0
+      i64.le_s
+      if
+        i64.const 48
+        call $golite_emit_byte
+        i64.const 46
+        call $golite_emit_byte
+        local.get $k
+        local.set $j
+        block
+          loop
+            local.get $j
+            i64.const 0
+            i64.lt_s
+            i32.eqz
+            br_if 1
+            block
+              i64.const 48
+              call $golite_emit_byte
+              local.get $j
+              i64.const 1
+              i64.add
+              local.set $j
+            end
+            br 0
+          end
+        end
+        i64.const 4144
+        local.get $nd
+        call $golite_emit
+      else
+        local.get $k
+        local.get $nd
+        i64.ge_s
+        if
+          i64.const 4144
+          local.get $nd
+          call $golite_emit
+          local.get $nd
+          local.set $j
+          block
+            loop
+              local.get $j
+              local.get $k
+              i64.lt_s
+              i32.eqz
+              br_if 1
+              block
+                i64.const 48
+                call $golite_emit_byte
+                local.get $j
+                i64.const 1
+                i64.add
+                local.set $j
+              end
+              br 0
+            end
+          end
+        else
+          i64.const 4144
+          local.get $k
+          call $golite_emit
+          i64.const 46
+          call $golite_emit_byte
+          i64.const 4144
+          local.get $k
+          i64.add
+          local.get $nd
+          local.get $k
+          i64.sub
+          call $golite_emit
+        end
+      end
+    end
+    return
+  )
+  (func $golite_print_float (type 9) (param $v f64)
+    local.get $v
+    call $golite_emit_float
+    i64.const 10
+    call $golite_emit_byte
+    return
+  )
+  (export "_start" (func $main))
+  (data (i32.const 4176) "true")
+  (data (i32.const 4184) "false")
+  (data (i32.const 4192) "runtime error at ")
+  (data (i32.const 4216) ":")
+  (data (i32.const 4224) ": ")
+  (data (i32.const 4232) "integer divide by zero\0a")
+  (data (i32.const 4256) "negative shift amount: ")
+  (data (i32.const 4280) "\0a")
+  (data (i32.const 5888) "NaN")
+  (data (i32.const 5896) "+Inf")
+  (data (i32.const 5904) "-Inf")
+  (data (i32.const 5912) "\04\00\00\00\00\00\00\00done")
+)
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/compiler"
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/object"
+	"golite.dev/mvp/internal/vm"
+)
+
+// runVM compiles input to bytecode and runs it, returning what it printed
+// and the error that ended it.
+func runVM(t *testing.T, input string) (string, *object.Error) {
+	t.Helper()
+	c := compiler.New()
+	bytecode := c.Compile(parse(input))
+	if len(c.Errors()) != 0 {
+		t.Fatalf("%q: unexpected compile errors: %v", input, c.Errors())
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := vm.New(bytecode).Run()
+
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String(), err
+}
+
+func TestVMMatchesEvaluator(t *testing.T) {
+	for i, input := range backendPrograms {
+		out, err := runVM(t, input)
+		if err != nil {
+			t.Errorf("backendPrograms[%d]: unexpected error: %s", i, err.Message)
+			continue
+		}
+		if expected := evalOutput(input); out != expected {
+			t.Errorf("backendPrograms[%d]: vm output %q differs from evaluator output %q", i, out, expected)
+		}
+	}
+}
+
+func TestVMRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; print a[3];", "index out of range [3] with length 3"},
+		{"let a = [1, 2, 3]; let i = -1; a[i] = 0;", "index out of range [-1]"},
+		{"let a = [1, 2, 3]; print a[1:5];", "slice bounds out of range [:5] with capacity 3"},
+		{`print "abc"[:4];`, "slice bounds out of range [:4] with length 3"},
+		{`let m = map[int]map[int]int{}; m[1][2] = 3;`, "assignment to entry in nil map"},
+		{"type N struct { next *N; v int }; let n = N{}; print n.next.v;", "invalid memory address or nil pointer dereference"},
+		{"let a = 7; let b = 0; print a / b;", "integer divide by zero"},
+		{"let e = errors.New(\"x\"); e = nil; print e.Error();", "invalid memory address or nil pointer dereference"},
+		{"let n = 0; assert(n > 0, \"n is positive\");", "assertion failed: n is positive"},
+		{"let f = func(n int) int { return f(n + 1) };\nf(0);", "stack overflow"},
+	}
+
+	for _, tt := range tests {
+		_, err := runVM(t, tt.input)
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%q: wrong message. expected=%q, got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+}
+
+func TestVMPanicStackTrace(t *testing.T) {
+	input := "let inner = func() { panic(\"boom\") };\nlet outer = func() { inner() };\nouter();"
+	_, err := runVM(t, input)
+	if err == nil {
+		t.Fatal("expected a panic")
+	}
+	d := err.Diagnostic()
+	if d.Code != diagnostics.Panic || d.Message != "panic: boom" {
+		t.Errorf("wrong diagnostic. got=%s %q", d.Code, d.Message)
+	}
+	expected := []string{"in inner at 1:22", "in outer at 2:22", "in main at 3:1"}
+	if strings.Join(d.Notes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong stack trace.\nexpected=%q\ngot=%q", expected, d.Notes)
+	}
+}
+
+// TestVMStackOverflow checks that runaway recursion stops with a runtime
+// error, rather than overflowing the stack of the Go program, and that its
+// stack trace leaves out the middle of the calls.
+func TestVMStackOverflow(t *testing.T) {
+	input := "let f = func(n int) int { return f(n + 1) };\nf(0);"
+	_, err := runVM(t, input)
+	if err == nil {
+		t.Fatal("expected a stack overflow")
+	}
+	d := err.Diagnostic()
+	if d.Code != diagnostics.RuntimeError || d.Message != "stack overflow" || d.Span.Start.String() != "1:34" {
+		t.Errorf("wrong diagnostic. got=%s %q at %s", d.Code, d.Message, d.Span.Start)
+	}
+	if len(d.Notes) != 22 || d.Notes[0] != "in f at 1:34" || d.Notes[10] != "... 9979 more calls ..." || d.Notes[21] != "in main at 2:1" {
+		t.Errorf("wrong stack trace. got %d notes: %q", len(d.Notes), d.Notes)
+	}
+}
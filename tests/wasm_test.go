@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golite.dev/mvp/internal/diagnostics"
+	"golite.dev/mvp/internal/wasm"
+)
+
+// wasiRunner runs the WebAssembly module named by its argument with the
+// WASI implementation of Node.js, and exits with the module's exit code.
+const wasiRunner = `import { readFile } from 'node:fs/promises';
+import { WASI } from 'node:wasi';
+const wasi = new WASI({ version: 'preview1', returnOnExit: true });
+const module = await WebAssembly.compile(await readFile(process.argv[2]));
+const instance = await WebAssembly.instantiate(module, wasi.getImportObject());
+process.exitCode = wasi.start(instance);
+`
+
+func generateWasm(t *testing.T, input string) (string, []byte) {
+	t.Helper()
+	generator := wasm.New()
+	text := generator.Generate(parse(input))
+	if len(generator.Errors()) != 0 {
+		t.Fatalf("%q: unexpected codegen errors: %v", input, generator.Errors())
+	}
+	return text, generator.Binary()
+}
+
+// wasiCommand returns a function that makes the command running a module
+// in dir, or skips the test if Node.js is not available.
+func wasiCommand(t *testing.T, dir string) func(module string) *exec.Cmd {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not available")
+	}
+	runner := filepath.Join(dir, "run.mjs")
+	if err := os.WriteFile(runner, []byte(wasiRunner), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return func(module string) *exec.Cmd {
+		return exec.Command(node, "--no-warnings", runner, module)
+	}
+}
+
+// TestWasmMatchesEvaluator runs the generated modules on a WASI runtime
+// and checks that they print what the evaluator prints.
+func TestWasmMatchesEvaluator(t *testing.T) {
+	dir := t.TempDir()
+	command := wasiCommand(t, dir)
+	for i, input := range nativePrograms {
+		file := filepath.Join(dir, fmt.Sprintf("prog%d.wasm", i))
+		_, binary := generateWasm(t, input)
+		if err := os.WriteFile(file, binary, 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := command(file).Output()
+		if err != nil {
+			t.Errorf("nativePrograms[%d]: running the module failed: %v", i, err)
+			continue
+		}
+		if expected := evalOutput(input); string(out) != expected {
+			t.Errorf("nativePrograms[%d]: module output %q differs from evaluator output %q", i, out, expected)
+		}
+	}
+}
+
+func TestWasmRuntimeErrors(t *testing.T) {
+	dir := t.TempDir()
+	command := wasiCommand(t, dir)
+	for i, tt := range nativeRuntimeErrors {
+		file := filepath.Join(dir, fmt.Sprintf("prog%d.wasm", i))
+		_, binary := generateWasm(t, tt.input)
+		if err := os.WriteFile(file, binary, 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := command(file)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		cmd.Run()
+		if cmd.ProcessState.ExitCode() != 1 || strings.TrimSpace(stderr.String()) != tt.expected {
+			t.Errorf("%q: got exit code %d and %q, want 1 and %q", tt.input, cmd.ProcessState.ExitCode(), stderr.String(), tt.expected)
+		}
+	}
+}
+
+// TestWasmFlushesBeforeErrors checks that what a program prints before a
+// runtime error comes out, although the module buffers its output.
+func TestWasmFlushesBeforeErrors(t *testing.T) {
+	dir := t.TempDir()
+	command := wasiCommand(t, dir)
+	file := filepath.Join(dir, "prog.wasm")
+	_, binary := generateWasm(t, nativeRuntimeErrors[0].input)
+	if err := os.WriteFile(file, binary, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := command(file).Output()
+	if string(out) != "1\n" {
+		t.Errorf("got output %q, want %q", out, "1\n")
+	}
+}
+
+// TestWasmGolden compares the text format of the module for a small
+// program with the one in testdata, and checks the header of its binary
+// encoding. Run the test with -update to rewrite the file.
+func TestWasmGolden(t *testing.T) {
+	input := `let fib = func(n) { if n < 2 { return n } return fib(n-1) + fib(n-2) };
+	let total = 0;
+	for let i = 0; i < 10; i++ { if i % 2 == 0 && i > 0 { continue } total += fib(i) }
+	print total; print total > 50; print float(total) / 4.0; print "done";`
+	text, binary := generateWasm(t, input)
+	checkGolden(t, filepath.Join("testdata", "fib.wat"), text)
+	if header := []byte("\x00asm\x01\x00\x00\x00"); !bytes.HasPrefix(binary, header) {
+		t.Errorf("binary starts with %x, want %x", binary[:8], header)
+	}
+}
+
+func TestWasmUnsupported(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = func() { let x = 1; let g = func() { x }; g() }; print f();`, "the WebAssembly backend does not support closures; x belongs to an enclosing function"},
+		{`let a = [1, 2]; print a;`, "the WebAssembly backend does not support slices"},
+	}
+	for _, tt := range tests {
+		generator := wasm.New()
+		if text := generator.Generate(parse(tt.input)); text != "" || generator.Binary() != nil {
+			t.Errorf("%q: expected no module", tt.input)
+		}
+		errs := generator.Errors()
+		if len(errs) != 1 || errs[0].Code != diagnostics.Unsupported || errs[0].Message != tt.expected {
+			t.Errorf("%q: got %v, want one %s error %q", tt.input, errs, diagnostics.Unsupported, tt.expected)
+		}
+	}
+}